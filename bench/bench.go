@@ -0,0 +1,271 @@
+// Package bench guards benchmarks against allocation regressions.
+//
+// Guard records allocs/op and B/op for each benchmark into a history file
+// and fails (or warns) when a benchmark allocates more than the stored
+// baseline allows — an allocation-focused benchstat built into go test.
+//
+// Usage:
+//
+//	func BenchmarkEncode(b *testing.B) {
+//	    msg := newMessage() // setup is not measured
+//
+//	    bench.Guard(b, bench.MaxRegression(5))
+//	    for i := 0; i < b.N; i++ {
+//	        encode(msg)
+//	    }
+//	}
+//
+// The first run records the baseline in testdata/heapcheck-bench.json.
+// Commit the file; later runs compare against it. After an intentional
+// change, refresh the baseline with:
+//
+//	HEAPCHECK_BENCH_UPDATE=1 go test -bench=. ./...
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Environment variables controlling Guard without code changes
+const (
+	HistoryFileEnv = "HEAPCHECK_BENCH_HISTORY"
+	UpdateEnv      = "HEAPCHECK_BENCH_UPDATE"
+)
+
+// DefaultHistoryFile is the history file path relative to the package
+// directory, which is the working directory of go test.
+const DefaultHistoryFile = "testdata/heapcheck-bench.json"
+
+// maxHistory bounds how many past measurements are kept per benchmark
+const maxHistory = 20
+
+// Option configures the guard
+type Option func(*config)
+
+type config struct {
+	historyFile   string
+	maxRegression float64
+	warnOnly      bool
+	update        bool
+}
+
+func defaultConfig() *config {
+	historyFile := os.Getenv(HistoryFileEnv)
+	if historyFile == "" {
+		historyFile = DefaultHistoryFile
+	}
+	return &config{
+		historyFile:   historyFile,
+		maxRegression: 10,
+		update:        os.Getenv(UpdateEnv) != "",
+	}
+}
+
+// HistoryFile sets where measurements are stored.
+// Default is testdata/heapcheck-bench.json or $HEAPCHECK_BENCH_HISTORY.
+func HistoryFile(path string) Option {
+	return func(c *config) {
+		c.historyFile = path
+	}
+}
+
+// MaxRegression sets the allowed growth over the baseline in percent.
+// Default is 10.
+func MaxRegression(pct float64) Option {
+	return func(c *config) {
+		c.maxRegression = pct
+	}
+}
+
+// WarnOnly logs regressions instead of failing the benchmark
+func WarnOnly() Option {
+	return func(c *config) {
+		c.warnOnly = true
+	}
+}
+
+// Update replaces the stored baseline with the current measurement.
+// Default is set when $HEAPCHECK_BENCH_UPDATE is non-empty.
+func Update() Option {
+	return func(c *config) {
+		c.update = true
+	}
+}
+
+// Measurement is the allocation cost of one benchmark run
+type Measurement struct {
+	AllocsPerOp float64   `json:"allocsPerOp"`
+	BytesPerOp  float64   `json:"bytesPerOp"`
+	N           int       `json:"n"`
+	Time        time.Time `json:"time"`
+}
+
+// Record holds the baseline and recent measurements of a benchmark
+type Record struct {
+	Baseline Measurement   `json:"baseline"`
+	History  []Measurement `json:"history,omitempty"`
+}
+
+// History is the content of a history file
+type History struct {
+	Benchmarks map[string]*Record `json:"benchmarks"`
+}
+
+// Guard measures allocations from this call until the benchmark function
+// returns, then compares allocs/op and B/op against the stored baseline.
+// Call it after setup, immediately before the b.N loop.
+//
+// The b.N == 1 probe run that go test performs before scaling N is
+// skipped, since it is dominated by one-time allocations.
+func Guard(b *testing.B, opts ...Option) {
+	b.Helper()
+
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	b.Cleanup(func() {
+		if b.N <= 1 {
+			return
+		}
+
+		var after runtime.MemStats
+		runtime.ReadMemStats(&after)
+
+		m := Measurement{
+			AllocsPerOp: float64(after.Mallocs-before.Mallocs) / float64(b.N),
+			BytesPerOp:  float64(after.TotalAlloc-before.TotalAlloc) / float64(b.N),
+			N:           b.N,
+			Time:        time.Now(),
+		}
+
+		msg, regressed, err := record(cfg, b.Name(), m)
+		if err != nil {
+			b.Errorf("heapcheck: %v", err)
+			return
+		}
+		if !regressed {
+			return
+		}
+		if cfg.warnOnly {
+			b.Logf("heapcheck: %s", msg)
+		} else {
+			b.Errorf("heapcheck: %s", msg)
+		}
+	})
+}
+
+var historyMu sync.Mutex
+
+// record stores m for the named benchmark and reports whether it regressed
+// beyond the allowed percentage.
+func record(cfg *config, name string, m Measurement) (string, bool, error) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	h, err := LoadHistory(cfg.historyFile)
+	if err != nil {
+		return "", false, err
+	}
+
+	rec, ok := h.Benchmarks[name]
+	if !ok || cfg.update {
+		if rec == nil {
+			rec = &Record{}
+		}
+		rec.Baseline = m
+		h.Benchmarks[name] = rec
+	}
+
+	rec.History = append(rec.History, m)
+	if len(rec.History) > maxHistory {
+		rec.History = rec.History[len(rec.History)-maxHistory:]
+	}
+
+	if err := saveHistory(cfg.historyFile, h); err != nil {
+		return "", false, err
+	}
+
+	msg, regressed := compare(rec.Baseline, m, cfg.maxRegression)
+	return msg, regressed, nil
+}
+
+// compare checks allocs/op and B/op against the baseline
+func compare(base, cur Measurement, maxPct float64) (string, bool) {
+	allocsPct, allocsBad := growth(base.AllocsPerOp, cur.AllocsPerOp, maxPct)
+	bytesPct, bytesBad := growth(base.BytesPerOp, cur.BytesPerOp, maxPct)
+
+	if !allocsBad && !bytesBad {
+		return "", false
+	}
+
+	return fmt.Sprintf("allocation regression (max allowed: +%.1f%%)\n"+
+		"  allocs/op: %.1f -> %.1f (%+.1f%%)\n"+
+		"  B/op:      %.0f -> %.0f (%+.1f%%)",
+		maxPct,
+		base.AllocsPerOp, cur.AllocsPerOp, allocsPct,
+		base.BytesPerOp, cur.BytesPerOp, bytesPct), true
+}
+
+// growth returns the percentage change and whether it exceeds maxPct.
+// Fractional allocs/op below one allocation are treated as noise.
+func growth(base, cur, maxPct float64) (float64, bool) {
+	if cur-base < 1 {
+		if base == 0 {
+			return 0, false
+		}
+		return (cur - base) / base * 100, false
+	}
+	if base == 0 {
+		return 100, true
+	}
+	pct := (cur - base) / base * 100
+	return pct, pct > maxPct
+}
+
+// LoadHistory reads a history file. A missing file yields an empty history.
+func LoadHistory(path string) (*History, error) {
+	h := &History{Benchmarks: make(map[string]*Record)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading bench history: %w", err)
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("parsing bench history %s: %w", path, err)
+	}
+	if h.Benchmarks == nil {
+		h.Benchmarks = make(map[string]*Record)
+	}
+	return h, nil
+}
+
+func saveHistory(path string, h *History) error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("writing bench history: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing bench history: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,105 @@
+package bench
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	base := Measurement{AllocsPerOp: 10, BytesPerOp: 1000}
+
+	tests := []struct {
+		name      string
+		cur       Measurement
+		regressed bool
+	}{
+		{"unchanged", Measurement{AllocsPerOp: 10, BytesPerOp: 1000}, false},
+		{"within threshold", Measurement{AllocsPerOp: 10.5, BytesPerOp: 1050}, false},
+		{"allocs regressed", Measurement{AllocsPerOp: 12, BytesPerOp: 1000}, true},
+		{"bytes regressed", Measurement{AllocsPerOp: 10, BytesPerOp: 1200}, true},
+		{"improved", Measurement{AllocsPerOp: 5, BytesPerOp: 500}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, regressed := compare(base, tt.cur, 10)
+			if regressed != tt.regressed {
+				t.Errorf("compare() regressed = %v, want %v", regressed, tt.regressed)
+			}
+		})
+	}
+}
+
+func TestCompareZeroBaseline(t *testing.T) {
+	base := Measurement{}
+	if _, regressed := compare(base, Measurement{AllocsPerOp: 0.01}, 10); regressed {
+		t.Error("sub-allocation noise over a zero baseline should not regress")
+	}
+	if _, regressed := compare(base, Measurement{AllocsPerOp: 2, BytesPerOp: 64}, 10); !regressed {
+		t.Error("new allocations over a zero baseline should regress")
+	}
+}
+
+func TestRecord(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.historyFile = filepath.Join(t.TempDir(), "history.json")
+	cfg.update = false
+
+	// First run establishes the baseline
+	if _, regressed, err := record(cfg, "BenchmarkX", Measurement{AllocsPerOp: 2, BytesPerOp: 64}); err != nil || regressed {
+		t.Fatalf("first record: regressed=%v err=%v", regressed, err)
+	}
+
+	_, regressed, err := record(cfg, "BenchmarkX", Measurement{AllocsPerOp: 4, BytesPerOp: 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regressed {
+		t.Error("expected regression against stored baseline")
+	}
+
+	h, err := LoadHistory(cfg.historyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := h.Benchmarks["BenchmarkX"]
+	if rec == nil || rec.Baseline.AllocsPerOp != 2 || len(rec.History) != 2 {
+		t.Errorf("unexpected history record: %+v", rec)
+	}
+
+	// Update replaces the baseline
+	cfg.update = true
+	if _, regressed, _ := record(cfg, "BenchmarkX", Measurement{AllocsPerOp: 4, BytesPerOp: 128}); regressed {
+		t.Error("update should accept the new measurement as baseline")
+	}
+}
+
+func TestGuard(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	var sink []byte
+
+	result := testing.Benchmark(func(b *testing.B) {
+		Guard(b, HistoryFile(path))
+		for i := 0; i < b.N; i++ {
+			sink = make([]byte, 64)
+		}
+	})
+	_ = sink
+
+	if result.N == 0 {
+		t.Fatal("benchmark did not run")
+	}
+
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Benchmarks) != 1 {
+		t.Fatalf("expected 1 recorded benchmark, got %d", len(h.Benchmarks))
+	}
+	for _, rec := range h.Benchmarks {
+		if rec.Baseline.AllocsPerOp < 0.5 {
+			t.Errorf("expected ~1 alloc/op, got %.2f", rec.Baseline.AllocsPerOp)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/baseline"
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/suppress"
+)
+
+// Where an escape accepted from the web report is recorded
+const (
+	acceptComment  = "comment"
+	acceptBaseline = "baseline"
+)
+
+// acceptor keeps the escapes of the report served last, as the analysis
+// saw them before categories were remapped and paths restyled, so an
+// escape accepted from the report is recorded as suppressions match it
+type acceptor struct {
+	mu       sync.Mutex
+	escapes  []categorizer.CategorizedEscape
+	baseline string // the local --baseline file, "" for none
+
+	// token is random, per process, and posted by the accept forms of the
+	// report only, which other sites cannot read
+	token string
+}
+
+func newAcceptor(cfg *Config) (*acceptor, error) {
+	var token [16]byte
+	if _, err := rand.Read(token[:]); err != nil {
+		return nil, err
+	}
+	a := &acceptor{token: hex.EncodeToString(token[:])}
+	if cfg.Baseline != "" && !baseline.IsRemote(cfg.Baseline) {
+		a.baseline = cfg.Baseline
+	}
+	return a, nil
+}
+
+// targets returns where escapes can be recorded: a suppression comment,
+// and the baseline file if the report applies one
+func (a *acceptor) targets() []string {
+	if a.baseline != "" {
+		return []string{acceptComment, acceptBaseline}
+	}
+	return []string{acceptComment}
+}
+
+// record keeps a copy of the escapes the report lists, in its order
+func (a *acceptor) record(escapes []categorizer.CategorizedEscape) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.escapes = append(a.escapes[:0], escapes...)
+}
+
+// accept records the escape of an accept form of the report, returning
+// a description of what was written
+func (a *acceptor) accept(form url.Values, now time.Time) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	i, err := strconv.Atoi(form.Get("escape"))
+	if err != nil || i < 0 || i >= len(a.escapes) || a.escapes[i].Info.Variable != form.Get("variable") {
+		return "", fmt.Errorf("the escape is no longer in the report; reload it and try again")
+	}
+	e := a.escapes[i]
+	reason := strings.TrimSpace(form.Get("reason"))
+	if reason == "" {
+		return "", fmt.Errorf("accepting an escape needs a reason")
+	}
+	owner, expires := strings.TrimSpace(form.Get("owner")), form.Get("expires")
+
+	switch form.Get("target") {
+	case acceptComment:
+		err := suppress.AddComment(suppress.Suppression{
+			File:       e.Info.File,
+			Line:       e.Info.Line,
+			Categories: []categorizer.Category{e.Category},
+			Owner:      owner,
+			Expires:    expires,
+			Reason:     reason,
+		})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("added a suppression comment above %s:%d", e.Info.File, e.Info.Line), nil
+	case acceptBaseline:
+		if a.baseline == "" {
+			return "", fmt.Errorf("the report applies no local --baseline file to accept escapes into")
+		}
+		if err := suppress.Validate([]suppress.Suppression{{File: a.baseline, Expires: expires}}); err != nil {
+			return "", err
+		}
+		b, err := baseline.Load(a.baseline)
+		if os.IsNotExist(err) {
+			b, err = &baseline.Baseline{Version: baseline.Version}, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		b.Add(baseline.Entry{
+			File:      e.Info.File,
+			Line:      e.Info.Line,
+			Variable:  e.Info.Variable,
+			Category:  e.Category,
+			FirstSeen: e.FirstSeen,
+			Owner:     owner,
+			Expires:   expires,
+			Reason:    strings.Join(strings.Fields(reason), " "),
+		})
+		b.Stamp(now)
+		if err := baseline.Save(a.baseline, b); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("added %s:%d %s to %s", e.Info.File, e.Info.Line, e.Info.Variable, a.baseline), nil
+	default:
+		return "", fmt.Errorf("unknown target %q (want %s)", form.Get("target"), strings.Join(a.targets(), " or "))
+	}
+}
+
+// acceptHandler serves the accept forms of the report: it records the
+// escape posted and sends the browser back to a fresh report, which no
+// longer lists it. The forms write source files, so posts must carry the
+// token of the report, and their host must be served, the --addr of web,
+// or loopback: that keeps out other sites, also under a DNS name rebound
+// to this machine, and other machines.
+func acceptHandler(a *acceptor, mu *sync.Mutex, served string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "accepting an escape needs a POST", http.StatusMethodNotAllowed)
+			return
+		}
+		if !localHost(r.Host, served) {
+			http.Error(w, "accepting an escape needs a request to "+served+" or localhost", http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				http.Error(w, "cross-origin request refused", http.StatusForbidden)
+				return
+			}
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.PostForm.Get("token")), []byte(a.token)) != 1 {
+			http.Error(w, "the form is not from this report; reload it and try again", http.StatusForbidden)
+			return
+		}
+
+		// Not during an analysis, which reads the files written
+		mu.Lock()
+		done, err := a.accept(r.PostForm, time.Now())
+		mu.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "heapcheck: %s\n", done)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+}
+
+// localHost reports whether host, of a request, is the address served or
+// a loopback address or localhost
+func localHost(host, served string) bool {
+	if host == served {
+		return true
+	}
+	name, _, err := net.SplitHostPort(host)
+	if err != nil {
+		name = host
+	}
+	if name == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(name, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/harshakonda/heapcheck/internal/config"
+	"github.com/harshakonda/heapcheck/internal/reporter"
+)
+
+// appliedConfig describes the configuration of the run for its reports:
+// the flags, the config files with their settings and the category rules
+// used, from --ruleset or else the config
+func appliedConfig(cfg *Config, fileCfg *config.Config, nested []config.Nested) *reporter.AppliedConfig {
+	applied := &reporter.AppliedConfig{
+		Flags:    cfg.Flags,
+		Given:    cfg.FlagsGiven,
+		Patterns: cfg.Patterns,
+		Ruleset:  cfg.Ruleset,
+	}
+	if applied.Ruleset == "" {
+		applied.Ruleset = fileCfg.Ruleset
+	}
+	path := cfg.ConfigFile
+	if path == "" {
+		path = config.Find(".")
+	}
+	if path != "" {
+		applied.Files = append(applied.Files, configFile(path))
+	}
+	for _, n := range nested {
+		if path := config.Find(n.Dir); path != "" {
+			applied.Files = append(applied.Files, configFile(path))
+		}
+	}
+	return applied
+}
+
+// configFile reads the settings of a config file, already loaded and
+// validated, as plain YAML values. A file that cannot be read again, or
+// has settings JSON cannot hold, such as keys that are not strings, is
+// listed without them.
+func configFile(path string) reporter.ConfigFile {
+	f := reporter.ConfigFile{Path: path}
+	data, err := os.ReadFile(path)
+	if err != nil || yaml.Unmarshal(data, &f.Settings) != nil {
+		return reporter.ConfigFile{Path: path}
+	}
+	if _, err := json.Marshal(f.Settings); err != nil {
+		f.Settings = nil
+	}
+	return f
+}
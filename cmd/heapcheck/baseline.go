@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/baseline"
+	"github.com/harshakonda/heapcheck/internal/reporter"
+)
+
+// defaultBaselineFile is where `heapcheck baseline` writes by default
+const defaultBaselineFile = "heapcheck-baseline.json"
+
+// runBaseline implements `heapcheck baseline [flags] [packages]`
+func runBaseline(args []string) error {
+	fs := newFlagSet("baseline")
+	file := fs.String("file", defaultBaselineFile, "Baseline file to write; first-seen dates and annotations of existing entries are kept")
+	input := fs.String("input", "", "Read compiler output saved with --save-raw instead of running the compiler (- for stdin)")
+	includeVendor := fs.Bool("include-vendor", false, "Include escapes in vendor/, the module cache and cgo-generated files")
+	packagesFrom := fs.String("packages-from", "", "Analyze the packages listed in this file (one per line)")
+	goListQuery := fs.String("go-list-query", "", "Analyze the packages selected by a go list query, e.g. 'deps(./cmd/api)'")
+	entrypoint := fs.String("entrypoint", "", "Analyze only the packages of this module linked into these main packages, e.g. ./cmd/api (comma-separated)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `heapcheck baseline - write the current escapes to a baseline file
+
+Usage:
+  heapcheck baseline [flags] [packages]
+
+Escapes listed in the baseline are suppressed by heapcheck --baseline, so
+CI only fails on new ones.
+
+Examples:
+  heapcheck baseline ./...
+  heapcheck --baseline=heapcheck-baseline.json ./...
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if err := globals.apply(); err != nil {
+		return err
+	}
+
+	if baseline.IsRemote(*file) {
+		return fmt.Errorf("--file writes a local file, not %s", *file)
+	}
+	if *input == "-" && slices.Contains(fs.Args(), "-") {
+		return fmt.Errorf("--input=- and the - pattern cannot both read stdin")
+	}
+	patterns, err := resolvePatterns(fs.Args(), *packagesFrom, *goListQuery, *entrypoint)
+	if err != nil {
+		return err
+	}
+	cfg := &Config{Patterns: patterns, Input: *input, IncludeVendor: *includeVendor}
+	results, err := loadResults(cfg, &reporter.Stats{})
+	if err != nil {
+		return err
+	}
+
+	b, err := writeBaselineFile(*file, results, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "heapcheck: wrote %d escape(s) to %s\n", len(b.Entries), *file)
+	return nil
+}
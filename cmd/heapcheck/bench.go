@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/harshakonda/heapcheck/bench"
+)
+
+// runBench implements `heapcheck bench [flags] [history files]`
+func runBench(args []string) error {
+	flags := newFlagSet("bench")
+	formatFlag := flags.String("format", "text", "Output format: text, json")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `heapcheck bench - show the allocation history bench.Guard recorded
+
+Usage:
+  heapcheck bench [flags] [history files]
+
+Without files, lists every testdata/heapcheck-bench.json below the current
+directory. For each benchmark it shows the baseline allocs/op and B/op,
+the latest run and the change.
+
+Flags:
+`)
+		flags.PrintDefaults()
+	}
+	parseFlags(flags, args)
+	if err := globals.apply(); err != nil {
+		return err
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		found, err := findBenchHistories(".")
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			return fmt.Errorf("no %s found below the current directory", bench.DefaultHistoryFile)
+		}
+		files = found
+	}
+
+	histories := make(map[string]*bench.History, len(files))
+	for _, file := range files {
+		h, err := bench.LoadHistory(file)
+		if err != nil {
+			return err
+		}
+		histories[file] = h
+	}
+
+	switch *formatFlag {
+	case "text":
+		return writeBenchText(os.Stdout, files, histories)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(histories)
+	default:
+		return fmt.Errorf("unknown format %q for bench (want text or json)", *formatFlag)
+	}
+}
+
+// findBenchHistories returns the history files bench.Guard writes by
+// default, in any package below root
+func findBenchHistories(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != root && (d.Name() == "vendor" || d.Name()[0] == '.') {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && d.Name() == filepath.Base(bench.DefaultHistoryFile) && filepath.Base(filepath.Dir(path)) == "testdata" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// writeBenchText writes one table per history file, benchmarks by name
+func writeBenchText(w io.Writer, files []string, histories map[string]*bench.History) error {
+	for i, file := range files {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s:\n", file)
+
+		h := histories[file]
+		names := make([]string, 0, len(h.Benchmarks))
+		for name := range h.Benchmarks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			fmt.Fprintln(w, "  (no benchmarks recorded)")
+			continue
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(tw, "  BENCHMARK\tALLOCS/OP\tB/OP\tRUNS")
+		for _, name := range names {
+			rec := h.Benchmarks[name]
+			latest := rec.Baseline
+			if n := len(rec.History); n > 0 {
+				latest = rec.History[n-1]
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%d\n", name,
+				benchChange(rec.Baseline.AllocsPerOp, latest.AllocsPerOp, "%.1f"),
+				benchChange(rec.Baseline.BytesPerOp, latest.BytesPerOp, "%.0f"),
+				len(rec.History))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// benchChange renders "base -> latest (+x%)", or just the value when it
+// did not change
+func benchChange(base, latest float64, verb string) string {
+	if base == latest {
+		return fmt.Sprintf(verb, latest)
+	}
+	change := "new"
+	if base != 0 {
+		change = fmt.Sprintf("%+.1f%%", (latest-base)/base*100)
+	}
+	return fmt.Sprintf(verb+" -> "+verb+" (%s)", base, latest, change)
+}
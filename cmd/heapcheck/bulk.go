@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/bulk"
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+// runBulk implements `heapcheck bulk --manifest=repos.yaml [flags]`
+func runBulk(args []string) error {
+	fs := newFlagSet("bulk")
+	manifestFile := fs.String("manifest", "repos.yaml", "Manifest listing the repositories to analyze")
+	formatFlag := fs.String("format", "text", "Output format: text, json")
+	resultsDir := fs.String("results-dir", "", "Also write each repository's full JSON results to <name>.json in this directory, for heapcheck render and diff")
+	noFetch := fs.Bool("no-fetch", false, "Analyze the clones as they are, without cloning or updating them")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `heapcheck bulk - analyze many repositories and combine the results
+
+Usage:
+  heapcheck bulk [flags]
+
+Clones each repository of the manifest, or updates its clone to the
+latest commit, analyzes it with its own .heapcheck.yaml, and reports the
+repositories side by side with their escapes by category combined.
+Repositories that cannot be cloned or analyzed are reported with their
+error; the others are still analyzed.
+
+Manifest:
+  dir: .heapcheck-bulk          # where repositories are cloned
+  repos:
+    - url: https://github.com/org/api
+    - url: git@github.com:org/worker.git
+      ref: release-2.x            # branch or tag (default: the remote's HEAD)
+      name: worker-2x             # default: from the URL
+      packages: [./cmd/...]       # default: ./...
+
+Examples:
+  heapcheck bulk --manifest=repos.yaml
+  heapcheck bulk --format=json --results-dir=results > org.json
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if err := globals.apply(); err != nil {
+		return err
+	}
+	if *formatFlag != "text" && *formatFlag != "json" {
+		return fmt.Errorf("unsupported format %q (supported: text, json)", *formatFlag)
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("bulk takes its packages from the manifest, not arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	m, err := bulk.LoadManifest(*manifestFile)
+	if err != nil {
+		return err
+	}
+	if *resultsDir != "" {
+		if err := os.MkdirAll(*resultsDir, 0o755); err != nil {
+			return err
+		}
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	results := make([]bulk.Result, 0, len(m.Repos))
+	for i, repo := range m.Repos {
+		dir := filepath.Join(m.Dir, repo.Name)
+		fmt.Fprintf(os.Stderr, "heapcheck: [%d/%d] %s\n", i+1, len(m.Repos), repo.Name)
+		res, data, err := analyzeRepo(ctx, self, repo, dir, !*noFetch)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "heapcheck: %s: %v\n", repo.Name, err)
+		}
+		if data != nil && *resultsDir != "" {
+			if err := os.WriteFile(filepath.Join(*resultsDir, repo.Name+".json"), data, 0o644); err != nil {
+				return err
+			}
+		}
+		results = append(results, res)
+	}
+
+	report := bulk.Combine(results)
+	if *formatFlag == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		bulk.WriteText(os.Stdout, report)
+	}
+	if n := report.Failed(); n > 0 {
+		return withExit(exitPartial, fmt.Errorf("%d of %d repo(s) could not be analyzed", n, len(report.Repos)))
+	}
+	return nil
+}
+
+// analyzeRepo syncs the clone of repo in dir, unless fetch is off, and
+// analyzes it with a heapcheck subprocess, which reads the repository's
+// config and go.mod as a run in that directory would. It returns the
+// result and the JSON results of the analysis, or the failed result and
+// the error.
+func analyzeRepo(ctx context.Context, self string, repo bulk.Repo, dir string, fetch bool) (bulk.Result, []byte, error) {
+	var commit string
+	var err error
+	if fetch {
+		commit, err = bulk.Sync(ctx, repo, dir)
+	} else {
+		if commit, err = bulk.Head(ctx, dir); err != nil {
+			err = fmt.Errorf("no clone in %s (run without --no-fetch to clone it): %w", dir, err)
+		}
+	}
+	if err != nil {
+		return bulk.Failed(repo, commit, err), nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, self, append([]string{"--format=json"}, repo.Packages...)...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	// Failed gates and partly analyzed modules still write their results
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && (exit.ExitCode() == exitGate || exit.ExitCode() == exitPartial) {
+		err = nil
+	}
+	if err != nil {
+		err = fmt.Errorf("analysis failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+		return bulk.Failed(repo, commit, err), nil, err
+	}
+	var results categorizer.Results
+	if err := json.Unmarshal(data, &results); err != nil {
+		err = fmt.Errorf("parsing results: %w", err)
+		return bulk.Failed(repo, commit, err), nil, err
+	}
+	return bulk.NewResult(repo, commit, &results), data, nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/harshakonda/heapcheck/internal/logging"
+)
+
+// command is a heapcheck subcommand. run parses the command's own flags
+// from args, which follow the command name.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// commands lists the subcommands in the order help shows them. analyze
+// runs when the first argument is not a command name, so
+// `heapcheck --format=json ./...` keeps working.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"analyze", "Analyze escapes in packages (default)", runAnalyze},
+		{"render", "Render saved JSON results", runRender},
+		{"diff", "Compare two saved JSON results", runDiff},
+		{"baseline", "Write the current escapes to a baseline file", runBaseline},
+		{"init", "Write a starter config with budgets just above the current escapes", runInit},
+		{"explain", "Explain an escape category, with an example and its fix", runExplain},
+		{"ruleset", "Print the built-in category rules, to pin or adapt them", runRuleset},
+		{"bench", "Show the allocation history bench.Guard recorded", runBench},
+		{"history", "Prune the history file of recorded runs", runHistory},
+		{"leaks", "Static goroutine leak detection", runLeaks},
+		{"testreport", "Rank tests by the heap and goroutines guard measured", runTestReport},
+		{"web", "Serve the HTML report, re-analyzing on every reload", runWeb},
+		{"watch", "Re-analyze on every save and print what changed", runWatch},
+		{"bulk", "Analyze the repositories of a manifest and combine the results", runBulk},
+		{"site", "Render saved JSON results as a static multi-page site", runSite},
+		{"precommit", "Report the escapes of staged changes, for a git hook", runPrecommit},
+		{"daemon", "Keep analyses of this module warm for the CLI", runDaemon},
+		{"help", "Show the commands, or a command's flags", runHelp},
+	}
+}
+
+// globalFlags are accepted before the command name and by every command
+type globalFlags struct {
+	debug   bool
+	mod     string
+	gowork  string
+	overlay string
+	version bool
+
+	// concurrency and maxMemory bound the resources of the run
+	concurrency int
+	maxMemory   string
+
+	// profileSelf is the directory --profile-self writes to; profile is
+	// the running profile, stopped when the command returns
+	profileSelf string
+	profile     *selfProfile
+
+	// fs is the flag set registered last, the command's, whose --format
+	// applies to --version
+	fs *flag.FlagSet
+}
+
+var globals globalFlags
+
+// register adds the global flags to fs. Values already parsed before the
+// command name are the defaults, so the command's parse keeps them.
+func (g *globalFlags) register(fs *flag.FlagSet) {
+	g.fs = fs
+	fs.BoolVar(&g.debug, "debug", g.debug, "Log the go command, per-package compile times, cache hits and parse statistics to stderr")
+	fs.StringVar(&g.mod, "mod", g.mod, "Module download mode for the analysis build: readonly, vendor or mod (added to GOFLAGS)")
+	fs.StringVar(&g.gowork, "gowork", g.gowork, "Workspace file for the analysis build, or off (sets GOWORK)")
+	fs.StringVar(&g.overlay, "overlay", g.overlay, "Build with this go build overlay file, to analyze generated code without writing it to the tree (added to GOFLAGS)")
+	fs.BoolVar(&g.version, "version", g.version, "Print version and exit; with --format=json, as JSON")
+	fs.IntVar(&g.concurrency, "concurrency", g.concurrency, "Packages to compile and files to read in parallel (default: one per CPU)")
+	fs.StringVar(&g.maxMemory, "max-memory", g.maxMemory, "Soft limit on heapcheck's memory, e.g. 512MB; compiler output beyond a quarter of it is buffered in a temporary file")
+	fs.StringVar(&g.profileSelf, "profile-self", g.profileSelf, "Write CPU and heap profiles of heapcheck's own run to cpu.pprof and heap.pprof in this directory")
+}
+
+// apply acts on the global flags once a command has parsed its flags:
+// --version prints the version and exits, --profile-self starts profiling
+func (g *globalFlags) apply() error {
+	if g.version {
+		var format string
+		if f := g.fs.Lookup("format"); f != nil {
+			format = f.Value.String()
+		}
+		if err := printVersion(os.Stdout, format); err != nil {
+			return err
+		}
+		os.Exit(0)
+	}
+	if g.debug {
+		logging.Enable(os.Stderr)
+	}
+	if g.profileSelf != "" && g.profile == nil {
+		p, err := startSelfProfile(g.profileSelf)
+		if err != nil {
+			return err
+		}
+		g.profile = p
+	}
+	if err := setLimits(g.concurrency, g.maxMemory); err != nil {
+		return err
+	}
+	return setGoEnv(g.mod, g.gowork, g.overlay)
+}
+
+// finish writes the profiles started by apply
+func (g *globalFlags) finish() error {
+	if g.profile == nil {
+		return nil
+	}
+	p := g.profile
+	g.profile = nil
+	return p.stop()
+}
+
+// newFlagSet returns the flag set of a command, with the global flags
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	globals.register(fs)
+	return fs
+}
+
+// parseFlags parses the flags of a command. Like flag.ExitOnError it
+// exits after --help or an invalid flag, but with exitError rather than
+// the flag package's 2, which heapcheck reserves for failed gates.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	switch err := fs.Parse(args); {
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(exitOK)
+	case err != nil:
+		os.Exit(exitError)
+	}
+}
+
+// dispatch runs the command named by the first argument after any global
+// flags, or analyze with all arguments when there is no command name
+func dispatch(args []string) error {
+	fs := flag.NewFlagSet("heapcheck", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	globals.register(fs)
+
+	// Analysis flags before the packages fail this parse; analyze parses
+	// them along with the global flags
+	if fs.Parse(args) == nil && fs.NArg() > 0 {
+		if cmd := lookupCommand(fs.Arg(0)); cmd != nil {
+			return cmd.run(fs.Args()[1:])
+		}
+	}
+	return runAnalyze(args)
+}
+
+func lookupCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// runHelp implements `heapcheck help [command]`
+func runHelp(args []string) error {
+	if len(args) == 0 {
+		printCommands(os.Stdout)
+		return nil
+	}
+	cmd := lookupCommand(args[0])
+	if cmd == nil || cmd.name == "help" {
+		return fmt.Errorf("unknown command %q; run heapcheck help for the list", args[0])
+	}
+	return cmd.run([]string{"--help"})
+}
+
+// printCommands writes the command list and the global flags
+func printCommands(w io.Writer) {
+	fmt.Fprintf(w, `heapcheck - Go escape analysis made human-readable
+
+Usage:
+  heapcheck [global flags] [command] [flags] [args]
+
+Commands:
+`)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s%s\n", cmd.name, cmd.summary)
+	}
+
+	fmt.Fprintf(w, "\nGlobal flags (before or after the command):\n")
+	fs := flag.NewFlagSet("heapcheck", flag.ContinueOnError)
+	fs.SetOutput(w)
+	var defaults globalFlags
+	defaults.register(fs)
+	fs.PrintDefaults()
+
+	fmt.Fprintf(w, `
+Run "heapcheck <command> --help" for the flags of a command.
+For more information: https://github.com/harshakonda/heapcheck
+`)
+}
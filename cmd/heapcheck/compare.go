@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+	"github.com/harshakonda/heapcheck/internal/sensitivity"
+)
+
+// runCompareFlags implements --compare-flags: it analyzes the packages
+// with and without inlining and reports the escapes that differ
+func runCompareFlags(w io.Writer, cfg *Config) error {
+	inlined, err := analyze(cfg, analysisFlags(cfg.GCFlagsExtra))
+	if err != nil {
+		return err
+	}
+	noInline, err := analyze(cfg, analysisFlags(cfg.GCFlagsExtra)+" -l")
+	if err != nil {
+		return err
+	}
+
+	report := sensitivity.Compare(inlined, noInline)
+
+	if cfg.Format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	sensitivity.WriteText(w, report)
+	return nil
+}
+
+// analyze runs the compiler with gcflags and categorizes its output
+func analyze(cfg *Config, gcflags string) (*categorizer.Results, error) {
+	rawOutput, err := parser.RunCompilerWithFlags(context.Background(), cfg.Patterns, gcflags)
+	if err != nil {
+		return nil, compilerError(fmt.Sprintf("running compiler with -gcflags=%q", gcflags), err)
+	}
+	escapes, err := parser.Parse(rawOutput)
+	if err != nil {
+		return nil, fmt.Errorf("parsing output: %w", err)
+	}
+	if !cfg.IncludeVendor {
+		escapes = parser.SkipThirdParty(escapes)
+	}
+	if cfg.StrictEmpty && len(escapes) == 0 {
+		return nil, errEmpty(cfg)
+	}
+
+	results := categorizer.Categorize(escapes)
+	if cfg.FilterPkg != "" {
+		results = filterByPackage(results, cfg.FilterPkg)
+	}
+	return results, nil
+}
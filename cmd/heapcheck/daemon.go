@@ -0,0 +1,609 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/baseline"
+	"github.com/harshakonda/heapcheck/internal/buildctx"
+	"github.com/harshakonda/heapcheck/internal/logging"
+	"github.com/harshakonda/heapcheck/internal/parser"
+	"github.com/harshakonda/heapcheck/internal/reporter"
+	"github.com/harshakonda/heapcheck/internal/staged"
+	"github.com/harshakonda/heapcheck/internal/typecheck"
+)
+
+// daemonEnv lists the variables that change what the go command builds;
+// the daemon only runs analyses for clients that agree with it on them
+var daemonEnv = []string{"GOFLAGS", "GOWORK", "GOOS", "GOARCH", "CGO_ENABLED", "GOEXPERIMENT", "GOTOOLCHAIN", "GOROOT", "GOMODCACHE"}
+
+// daemonConfig classifies every field of Config: true for those the
+// daemon honors as the CLI would, false for those that keep a run in
+// process when set. Runs that stream to stderr, run a command with the
+// client's environment, create a check run, upload the reports or export
+// them with credentials and commit metadata from the environment, or are
+// set up by another command, stay in process. A field missing here keeps
+// runs in process too, until it is classified.
+var daemonConfig = map[string]bool{
+	"Format":           true,
+	"SummaryOnly":      true,
+	"EscapesOnly":      true,
+	"FilterPkg":        true,
+	"Owner":            true,
+	"OwnersFile":       true,
+	"IncludeVendor":    true,
+	"Where":            true,
+	"CoverProfile":     true,
+	"Benchmarks":       true,
+	"InterfaceParams":  true,
+	"Goroutines":       true,
+	"Handlers":         true,
+	"GCImpact":         true,
+	"Sort":             true,
+	"LargeCopies":      true,
+	"LargeCopyMin":     true,
+	"CategorizerExec":  false,
+	"Ruleset":          true,
+	"Verbose":          true,
+	"Stats":            true,
+	"Color":            true, // resolved by the client for its terminal
+	"Width":            true, // likewise
+	"Limit":            true,
+	"NoLinks":          true,
+	"CompareFlags":     true,
+	"GCFlagsExtra":     true,
+	"ConfigFile":       true,
+	"GateOutput":       true,
+	"SummaryMarkdown":  true,
+	"History":          true,
+	"HistoryKeep":      true,
+	"SaveRaw":          true,
+	"CaptureUnparsed":  true,
+	"Input":            true, // but not - for stdin, see delegate
+	"FailOnTrend":      true,
+	"Patterns":         true,
+	"Baseline":         true, // but not a URL, see delegate
+	"WriteBaseline":    true,
+	"OnlyNewSince":     true,
+	"StrictEmpty":      true,
+	"MaxUncategorized": true,
+	"ExpiryWindow":     true,
+	"PathStyle":        true,
+	"Lang":             true,
+	"JSONEvents":       false,
+	"Tee":              true, // the client writes the file
+	"OTLPEndpoint":     false,
+	"Upload":           false,
+	"GitHubCheck":      false,
+	"Plan":             false,
+	"Sample":           true,
+	"SampledFrom":      true,
+	"Flags":            true,
+	"FlagsGiven":       true,
+	"events":           false,
+	"accept":           false,
+	"watch":            false,
+	"catalog":          true, // loaded again for Lang by the daemon
+	"outcome":          true, // sent back to the client
+	"staged":           true,
+	"failStaged":       true,
+}
+
+// daemonRequest is an analysis the CLI hands to the daemon, run in Dir
+type daemonRequest struct {
+	Dir        string
+	Env        map[string]string
+	Config     *Config
+	Staged     *staged.Changes `json:",omitempty"`
+	FailStaged bool            `json:",omitempty"`
+	Stop       bool            `json:",omitempty"`
+}
+
+// daemonResponse carries the output of the analysis back, with the code
+// the client exits with after Error. Declined is set, with the reason,
+// when the daemon did not run it.
+type daemonResponse struct {
+	Stdout   []byte
+	Stderr   []byte
+	Error    string
+	Code     int `json:",omitempty"`
+	Declined string
+	Outcome  *outcome `json:",omitempty"`
+}
+
+// warm reuses the go command output of earlier analyses in the daemon;
+// nil in a regular run
+var warm *memo
+
+// runDaemon implements `heapcheck daemon [flags]`
+func runDaemon(args []string) error {
+	fs := newFlagSet("daemon")
+	stop := fs.Bool("stop", false, "Stop the daemon serving this module")
+	idle := fs.Duration("idle", time.Hour, "Exit after this long without requests (0: never)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `heapcheck daemon - keep analyses of this module warm
+
+Usage:
+  heapcheck daemon [flags]
+
+Serves the analyses of heapcheck and heapcheck precommit run anywhere in
+this module. The CLI hands them to the daemon when one is running, and
+the daemon reuses the compiler and go list output of earlier runs while
+the module's sources are unchanged. Set HEAPCHECK_DAEMON=off to always
+analyze in the CLI's own process.
+
+Examples:
+  heapcheck daemon &
+  heapcheck daemon --idle=8h
+  heapcheck daemon --stop
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if err := globals.apply(); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("daemon serves the whole module and takes no packages")
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	root, err := daemonRoot(dir)
+	if err != nil {
+		return err
+	}
+	socket, err := daemonSocket(root)
+	if err != nil {
+		return err
+	}
+
+	if *stop {
+		if _, err := callDaemon(socket, &daemonRequest{Stop: true}); err != nil {
+			return fmt.Errorf("no daemon serving %s", root)
+		}
+		fmt.Fprintf(os.Stderr, "heapcheck: stopped the daemon serving %s\n", root)
+		return nil
+	}
+
+	if conn, err := net.DialTimeout("unix", socket, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("a daemon already serves %s (heapcheck daemon --stop stops it)", root)
+	}
+	os.Remove(socket) // left behind by a daemon that did not exit cleanly
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	d := &daemon{root: root, ln: ln, env: goEnvOf(os.Getenv), log: os.Stderr}
+	warm = &memo{}
+	typecheck.Memo = func(args []string, run func() ([]byte, error)) ([]byte, error) {
+		v, err := warm.do("go "+strings.Join(args, " "), func() (any, error) { return run() })
+		out, _ := v.([]byte)
+		return out, err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	if *idle > 0 {
+		d.idle = time.AfterFunc(*idle, func() { ln.Close() })
+		d.idleAfter = *idle
+	}
+
+	fmt.Fprintf(d.log, "heapcheck: daemon serving %s on %s\n", root, socket)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			d.mu.Lock() // let a running analysis finish
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		if err := checkPeer(conn); err != nil {
+			fmt.Fprintf(d.log, "heapcheck: refused a client: %v\n", err)
+			conn.Close()
+			continue
+		}
+		go d.serve(conn)
+	}
+}
+
+// daemon serves analyses of the module at root
+type daemon struct {
+	root string
+	ln   net.Listener
+	env  map[string]string
+
+	idle      *time.Timer
+	idleAfter time.Duration
+
+	// mu runs one analysis at a time. An analysis changes the state of
+	// the whole process while it holds mu: the working directory is its
+	// request's, os.Stdout and os.Stderr are its own files, and the memo
+	// and build context are set up for it. Nothing may run beside it that
+	// reads them, so run starts no goroutine that outlives it, and the
+	// daemon writes its own messages to log and exits only once mu is
+	// free.
+	mu  sync.Mutex
+	log io.Writer
+}
+
+// serve answers one request
+func (d *daemon) serve(conn net.Conn) {
+	defer conn.Close()
+	var req daemonRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	if d.idle != nil {
+		d.idle.Reset(d.idleAfter)
+	}
+	if req.Stop {
+		json.NewEncoder(conn).Encode(daemonResponse{})
+		d.ln.Close()
+		return
+	}
+	json.NewEncoder(conn).Encode(d.analyze(&req))
+}
+
+// analyze runs the analysis of req, unless its environment or directory
+// does not match the daemon's
+func (d *daemon) analyze(req *daemonRequest) daemonResponse {
+	for _, key := range daemonEnv {
+		if req.Env[key] != d.env[key] {
+			return daemonResponse{Declined: fmt.Sprintf("%s is %q for the daemon", key, d.env[key])}
+		}
+	}
+	if rel, err := filepath.Rel(d.root, req.Dir); err != nil || !filepath.IsLocal(rel) {
+		return daemonResponse{Declined: fmt.Sprintf("%s is outside %s", req.Dir, d.root)}
+	}
+	if req.Config == nil {
+		return daemonResponse{Declined: "no analysis to run"}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := os.Chdir(req.Dir); err != nil {
+		return daemonResponse{Declined: err.Error()}
+	}
+	defer os.Chdir(d.root)
+	warm.refresh(d.root)
+
+	// The catalog is not sent with the Config; load it for --lang here,
+	// where a catalog file's relative path resolves as for the client
+	catalog, err := reporter.LoadCatalog(req.Config.Lang)
+	if err != nil {
+		return daemonResponse{Declined: err.Error()}
+	}
+
+	// Warnings go to os.Stderr; collect them for the client. The client
+	// resolved --color and --width for its own terminal, so the daemon's
+	// must not count.
+	errFile, err := os.CreateTemp("", "heapcheck-daemon-*.txt")
+	if err != nil {
+		return daemonResponse{Declined: err.Error()}
+	}
+	defer os.Remove(errFile.Name())
+	defer errFile.Close()
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		return daemonResponse{Declined: err.Error()}
+	}
+	defer null.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = null, errFile
+
+	cfg := req.Config
+	cfg.staged = req.Staged
+	cfg.failStaged = req.FailStaged
+	cfg.catalog = catalog
+	var out strings.Builder
+	err = run(&out, cfg)
+	os.Stdout, os.Stderr = stdout, stderr
+
+	resp := daemonResponse{Stdout: []byte(out.String()), Outcome: cfg.outcome}
+	if _, serr := errFile.Seek(0, io.SeekStart); serr == nil {
+		resp.Stderr, _ = io.ReadAll(errFile)
+	}
+	if err != nil {
+		resp.Error, resp.Code = err.Error(), exitCode(err)
+	}
+	return resp
+}
+
+// delegate runs the analysis of cfg in the daemon serving the current
+// module, if there is one, and reports whether it did. Only runs that set
+// nothing but the fields daemonConfig allows are delegated, and of those
+// not runs that read stdin, download a baseline with a header from the
+// environment, build with an overlay, whose files change between runs,
+// or debug or profile heapcheck itself. Runs the daemon declines stay in
+// process too.
+func delegate(stdout io.Writer, cfg *Config) (bool, error) {
+	if os.Getenv("HEAPCHECK_DAEMON") == "off" || !daemonHonors(cfg) || cfg.Input == "-" || baseline.IsRemote(cfg.Baseline) || buildctx.OverlayFile(os.Getenv("GOFLAGS")) != "" || globals.debug || globals.profileSelf != "" || globals.maxMemory != "" {
+		return false, nil
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return false, nil
+	}
+	root, err := daemonRoot(dir)
+	if err != nil {
+		return false, nil
+	}
+
+	// The daemon's output goes to this terminal
+	color, err := useColor(cfg.Color)
+	if err != nil {
+		return false, nil
+	}
+	c := *cfg
+	c.Color = "never"
+	if color {
+		c.Color = "always"
+	}
+	c.Width = textWidth(cfg.Width)
+
+	socket, err := daemonSocket(root)
+	if err != nil {
+		logging.Logger().Debug("no daemon socket", "err", err)
+		return false, nil
+	}
+	resp, err := callDaemon(socket, &daemonRequest{
+		Dir:        dir,
+		Env:        goEnvOf(os.Getenv),
+		Config:     &c,
+		Staged:     cfg.staged,
+		FailStaged: cfg.failStaged,
+	})
+	if err != nil {
+		return false, nil
+	}
+	if resp.Declined != "" {
+		logging.Logger().Debug("daemon declined the analysis", "reason", resp.Declined)
+		return false, nil
+	}
+	stdout.Write(resp.Stdout)
+	os.Stderr.Write(resp.Stderr)
+	cfg.outcome = resp.Outcome
+	if resp.Error != "" {
+		return true, withExit(resp.Code, errors.New(resp.Error))
+	}
+	return true, nil
+}
+
+// daemonHonors reports whether every field cfg sets is one daemonConfig
+// allows
+func daemonHonors(cfg *Config) bool {
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Field(i).IsZero() && !daemonConfig[v.Type().Field(i).Name] {
+			return false
+		}
+	}
+	return true
+}
+
+// callDaemon sends req to the daemon listening on socket, if it runs as
+// the current user
+func callDaemon(socket string, req *daemonRequest) (*daemonResponse, error) {
+	conn, err := net.DialTimeout("unix", socket, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := checkPeer(conn); err != nil {
+		return nil, fmt.Errorf("daemon on %s: %w", socket, err)
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+	var resp daemonResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// goEnvOf returns the daemonEnv variables per getenv
+func goEnvOf(getenv func(string) string) map[string]string {
+	env := make(map[string]string, len(daemonEnv))
+	for _, key := range daemonEnv {
+		env[key] = getenv(key)
+	}
+	return env
+}
+
+// daemonRoot returns the directory a daemon serves for dir: that of the
+// workspace file the go command uses, else of the nearest go.mod
+func daemonRoot(dir string) (string, error) {
+	switch gowork := os.Getenv("GOWORK"); gowork {
+	case "off":
+	case "":
+		if root, ok := findUp(dir, "go.work"); ok {
+			return root, nil
+		}
+	default:
+		return filepath.Dir(gowork), nil
+	}
+	if root, ok := findUp(dir, "go.mod"); ok {
+		return root, nil
+	}
+	return "", fmt.Errorf("no go.mod in %s or its parents", dir)
+}
+
+// findUp returns the first of dir and its parents that contains name
+func findUp(dir, name string) (string, bool) {
+	for {
+		if fi, err := os.Stat(filepath.Join(dir, name)); err == nil && !fi.IsDir() {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// daemonSocket returns the path of the socket the daemon of root listens
+// on, in daemonDir
+func daemonSocket(root string) (string, error) {
+	dir, err := daemonDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".sock"), nil
+}
+
+// daemonDir returns the directory of the current user's daemon sockets,
+// in the user cache directory, creating it. Only the user may have
+// access to it, so that no other user can listen on a socket in it and
+// answer the CLI with forged analyses.
+func daemonDir() (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cache, "heapcheck", "daemon")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !fi.IsDir() || fi.Mode().Perm() != 0o700 || !ownedByUser(fi) {
+		return "", fmt.Errorf("%s must be a directory of the current user with mode 0700", dir)
+	}
+	return dir, nil
+}
+
+// analysisGCFlags are the compiler flags of an analysis, which print the
+// escape analysis decisions with their flows
+const analysisGCFlags = "-m=2"
+
+// analysisFlags returns the compiler flags of an analysis with the extra
+// flags of --gcflags-extra
+func analysisFlags(extra string) string {
+	if extra == "" {
+		return analysisGCFlags
+	}
+	return analysisGCFlags + " " + extra
+}
+
+// compile runs the compiler with gcflags for patterns. In the daemon, the
+// output of an earlier run is reused while the sources are unchanged.
+func compile(patterns []string, gcflags string, progress io.Writer) (string, []parser.SkippedPackage, error) {
+	if warm == nil || progress != nil {
+		return parser.Compile(context.Background(), patterns, gcflags, progress)
+	}
+	type compiled struct {
+		out     string
+		skipped []parser.SkippedPackage
+	}
+	v, err := warm.do("compile "+gcflags+" "+strings.Join(patterns, " "), func() (any, error) {
+		out, skipped, err := parser.Compile(context.Background(), patterns, gcflags, nil)
+		return compiled{out, skipped}, err
+	})
+	c, _ := v.(compiled)
+	return c.out, c.skipped, err
+}
+
+// memo holds the output of go commands run by the daemon, keyed by the
+// directory and command, while the fingerprint of the sources they read
+// is unchanged. It is only used under daemon.mu, so needs no lock.
+type memo struct {
+	fingerprint string
+	entries     map[string]any
+}
+
+// do returns the output of run for key, running it unless a previous
+// run succeeded
+func (m *memo) do(key string, run func() (any, error)) (any, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return run()
+	}
+	key = dir + "\x00" + key
+	if v, ok := m.entries[key]; ok {
+		logging.Logger().Debug("reusing go command output", "key", key)
+		return v, nil
+	}
+	v, err := run()
+	if err == nil {
+		if m.entries == nil {
+			m.entries = make(map[string]any)
+		}
+		m.entries[key] = v
+	}
+	return v, err
+}
+
+// refresh drops the entries when the sources under root changed
+func (m *memo) refresh(root string) {
+	if fp := sourceFingerprint(root); fp != m.fingerprint {
+		m.fingerprint = fp
+		m.entries = nil
+	}
+}
+
+// sourceExts are the extensions of the files the go command compiles
+var sourceExts = map[string]bool{".go": true, ".s": true, ".c": true, ".h": true, ".cc": true, ".cpp": true, ".m": true, ".syso": true}
+
+// sourceFingerprint summarizes the names, sizes and modification times of
+// the source and module files under root, skipping the directories the
+// go command ignores
+func sourceFingerprint(root string) string {
+	h := sha256.New()
+	filepath.WalkDir(root, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := e.Name()
+		if e.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch name {
+		case "go.mod", "go.sum", "go.work", "go.work.sum":
+		default:
+			if !sourceExts[filepath.Ext(name)] {
+				return nil
+			}
+		}
+		if fi, err := e.Info(); err == nil {
+			fmt.Fprintf(h, "%s %d %d\n", path, fi.Size(), fi.ModTime().UnixNano())
+		}
+		return nil
+	})
+	return hex.EncodeToString(h.Sum(nil))
+}
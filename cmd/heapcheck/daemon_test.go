@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDaemonConfigClassifiesEveryField(t *testing.T) {
+	typ := reflect.TypeOf(Config{})
+	fields := make(map[string]bool)
+	for i := 0; i < typ.NumField(); i++ {
+		name := typ.Field(i).Name
+		fields[name] = true
+		if _, ok := daemonConfig[name]; !ok {
+			t.Errorf("Config.%s is not in daemonConfig: add it, true if the daemon honors it", name)
+		}
+	}
+	for name := range daemonConfig {
+		if !fields[name] {
+			t.Errorf("daemonConfig has %s, which Config does not", name)
+		}
+	}
+}
+
+func TestDaemonHonors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want bool
+	}{
+		{name: "defaults", cfg: Config{Format: "json", Sort: "impact", Patterns: []string{"./..."}}, want: true},
+		{name: "baseline", cfg: Config{Baseline: "heapcheck-baseline.json"}, want: true},
+		{name: "json events", cfg: Config{JSONEvents: true}, want: false},
+		{name: "upload", cfg: Config{Upload: "s3://bucket/reports"}, want: false},
+		{name: "accept", cfg: Config{accept: &acceptor{}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := daemonHonors(&tt.cfg); got != tt.want {
+				t.Errorf("daemonHonors() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// Progress events written with --json-events
+const (
+	eventStart   = "start"   // the analysis started
+	eventPackage = "package" // the compiler output of a package arrived
+	eventDone    = "done"    // the analysis finished, or failed
+)
+
+// progressEvent is one NDJSON line of --json-events output. Counts are
+// heap escapes (moved to heap or escaping), totals are so far in the run.
+type progressEvent struct {
+	Event         string    `json:"event"`
+	Time          time.Time `json:"time"`
+	Patterns      []string  `json:"patterns,omitempty"`
+	Package       string    `json:"package,omitempty"`
+	Escapes       int       `json:"escapes,omitempty"`
+	TotalPackages int       `json:"totalPackages"`
+	TotalEscapes  int       `json:"totalEscapes"`
+	DurationMs    int64     `json:"durationMs,omitempty"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// eventWriter writes progress events, so IDEs and CI wrappers can show
+// live progress and partial results while the compiler runs
+type eventWriter struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	started  time.Time
+	packages int
+	escapes  int
+}
+
+func newEventWriter(w io.Writer) *eventWriter {
+	return &eventWriter{enc: json.NewEncoder(w)}
+}
+
+// start resets the counts and reports the start of an analysis
+func (w *eventWriter) start(patterns []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.started, w.packages, w.escapes = time.Now(), 0, 0
+	w.write(progressEvent{Event: eventStart, Patterns: patterns})
+}
+
+// packageWriter returns a writer for the compiler output that reports
+// each package block as it completes
+func (w *eventWriter) packageWriter(includeVendor bool) *parser.PackageWriter {
+	return parser.NewPackageWriter(func(pkg, output string) {
+		escapes, _ := parser.Parse(output)
+		if !includeVendor {
+			escapes = parser.SkipThirdParty(escapes)
+		}
+		heap := 0
+		for _, e := range escapes {
+			if e.EscapeType == parser.MovedToHeap || e.EscapeType == parser.EscapesToHeap {
+				heap++
+			}
+		}
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.packages++
+		w.escapes += heap
+		w.write(progressEvent{Event: eventPackage, Package: pkg, Escapes: heap})
+	})
+}
+
+// done reports the end of the analysis and its error, if any
+func (w *eventWriter) done(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	e := progressEvent{Event: eventDone, DurationMs: time.Since(w.started).Milliseconds()}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	w.write(e)
+}
+
+// write adds the time and totals to e and writes it as one line. Write
+// errors are ignored: progress must not fail the analysis.
+func (w *eventWriter) write(e progressEvent) {
+	e.Time = time.Now()
+	e.TotalPackages, e.TotalEscapes = w.packages, w.escapes
+	w.enc.Encode(e)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// Exit codes, so CI scripts can tell a regression in the analyzed code
+// from heapcheck failing to run
+const (
+	exitOK      = 0
+	exitError   = 1 // heapcheck failed: invalid flags, unreadable files
+	exitGate    = 2 // the code failed a gate: categories, trend, uncategorized escapes, expired suppressions or staged escapes
+	exitBuild   = 3 // the packages did not build
+	exitPartial = 4 // some or all packages could not be analyzed
+)
+
+// exitErr is an error that exits with code instead of exitError
+type exitErr struct {
+	code int
+	err  error
+}
+
+func (e *exitErr) Error() string { return e.err.Error() }
+func (e *exitErr) Unwrap() error { return e.err }
+
+// withExit marks err to exit with code
+func withExit(code int, err error) error {
+	return &exitErr{code: code, err: err}
+}
+
+// exitCode returns the code to exit with after err
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var e *exitErr
+	if errors.As(err, &e) {
+		return e.code
+	}
+	return exitError
+}
+
+// notAnalyzed counts the skipped packages that failed to build in whole
+// or in part. Packages build constraints exclude are not part of the
+// build and do not count.
+func notAnalyzed(skipped []parser.SkippedPackage) int {
+	n := 0
+	for _, p := range skipped {
+		if !p.Excluded {
+			n++
+		}
+	}
+	return n
+}
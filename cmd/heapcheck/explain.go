@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+// runExplain implements `heapcheck explain [category]`
+func runExplain(args []string) error {
+	fs := newFlagSet("explain")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `heapcheck explain - explain escape categories
+
+Usage:
+  heapcheck explain             List the categories
+  heapcheck explain <category>  Explain a category, with an example and its fix
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if err := globals.apply(); err != nil {
+		return err
+	}
+
+	switch fs.NArg() {
+	case 0:
+		listCategories(os.Stdout)
+		return nil
+	case 1:
+		return explainCategory(os.Stdout, categorizer.Category(fs.Arg(0)))
+	default:
+		return fmt.Errorf("explain takes one category, got %d", fs.NArg())
+	}
+}
+
+// listCategories writes each built-in category with its short
+// suggestion, under its group
+func listCategories(w io.Writer) {
+	for i, g := range categorizer.Groups() {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s:\n", g)
+		for _, cat := range categorizer.Categories() {
+			if categorizer.GroupOf(cat) == g {
+				fmt.Fprintf(w, "  %-20s %s\n", cat, categorizer.GetSuggestion(cat).Short)
+			}
+		}
+	}
+	fmt.Fprintln(w, "\nadvisory:")
+	for _, cat := range categorizer.Advisories() {
+		fmt.Fprintf(w, "  %-20s %s\n", cat, categorizer.GetSuggestion(cat).Short)
+	}
+}
+
+// explainCategory writes a category's suggestion, example and doc link
+func explainCategory(w io.Writer, cat categorizer.Category) error {
+	known := false
+	for _, c := range append(categorizer.Categories(), categorizer.Advisories()...) {
+		known = known || c == cat
+	}
+	if !known {
+		return fmt.Errorf("unknown category %q; run heapcheck explain for the list", cat)
+	}
+
+	s := categorizer.GetSuggestion(cat)
+	fmt.Fprintf(w, "%s: %s\n\n%s\n", cat, s.Short, s.Details)
+	if ex, ok := categorizer.GetExample(cat); ok {
+		fmt.Fprintf(w, "\nEscapes:\n\n%s\n\nInstead:\n\n%s\n", indentCode(ex.Escaping), indentCode(ex.Fixed))
+	}
+	if s.DocLink != "" {
+		fmt.Fprintf(w, "\nSee %s\n", s.DocLink)
+	}
+	return nil
+}
+
+// indentCode indents a tab-indented snippet by four spaces, expanding
+// its tabs so it lines up in any terminal
+func indentCode(code string) string {
+	code = strings.ReplaceAll(code, "\t", "    ")
+	return "    " + strings.ReplaceAll(code, "\n", "\n    ")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/buildctx"
+	"github.com/harshakonda/heapcheck/internal/logging"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// setGoEnv makes every go command heapcheck runs use the given module
+// settings, so the analysis builds the way the project does: mod
+// (readonly, vendor or mod) and overlay, a go build overlay file, are
+// added to GOFLAGS, and gowork sets GOWORK. GOFLAGS and GOWORK already in
+// the environment are inherited as they are.
+func setGoEnv(mod, gowork, overlay string) error {
+	if mod != "" {
+		switch mod {
+		case "readonly", "vendor", "mod":
+		default:
+			return fmt.Errorf("invalid --mod %q (want readonly, vendor or mod)", mod)
+		}
+		if err := setGoFlag("-mod", mod); err != nil {
+			return err
+		}
+	}
+	if overlay != "" {
+		if _, err := buildctx.LoadOverlay(overlay); err != nil {
+			return fmt.Errorf("invalid --overlay: %w", err)
+		}
+		// The go command resolves the file from its own directory
+		abs, err := filepath.Abs(overlay)
+		if err != nil {
+			return err
+		}
+		if strings.ContainsAny(abs, " \t") {
+			return fmt.Errorf("--overlay path %q contains spaces, which GOFLAGS cannot hold", abs)
+		}
+		if err := setGoFlag("-overlay", abs); err != nil {
+			return err
+		}
+	}
+	if gowork != "" {
+		if err := os.Setenv("GOWORK", gowork); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setGoFlag sets a flag in GOFLAGS, replacing any value it has there
+func setGoFlag(name, value string) error {
+	flags := strings.Fields(os.Getenv("GOFLAGS"))
+	kept := flags[:0]
+	for _, f := range flags {
+		if !strings.HasPrefix(f, name+"=") && !strings.HasPrefix(f, "-"+name+"=") {
+			kept = append(kept, f)
+		}
+	}
+	kept = append(kept, name+"="+value)
+	return os.Setenv("GOFLAGS", strings.Join(kept, " "))
+}
+
+// useBuildContext limits the source heapcheck reads, for suggestions,
+// function names and suppressions, to the files the go command compiles
+// in its current configuration, read through its overlay. When go env
+// fails, every file is read from disk.
+func useBuildContext() {
+	m, err := buildctx.FromGoEnv()
+	if err != nil {
+		logging.Logger().Debug("build configuration unknown, reading all source files", "err", err)
+		return
+	}
+	buildctx.Use(m)
+}
+
+// goEnvHint describes the settings the go command ran with, to explain
+// build failures that the project's own build does not have
+func goEnvHint() string {
+	return fmt.Sprintf("heapcheck ran go with GOFLAGS=%q GOWORK=%q; use --mod, --gowork, --overlay or GOFLAGS to match your project's build",
+		os.Getenv("GOFLAGS"), os.Getenv("GOWORK"))
+}
+
+// compilerError wraps an error from running the compiler as a build
+// failure, adding the go environment hint, unless the patterns simply
+// matched no packages
+func compilerError(what string, err error) error {
+	var noPkgs *parser.NoPackagesError
+	if errors.As(err, &noPkgs) {
+		return err
+	}
+	return withExit(exitBuild, fmt.Errorf("%s: %w\n%s", what, err, goEnvHint()))
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/history"
+)
+
+// runHistory implements `heapcheck history <subcommand>`
+func runHistory(args []string) error {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, `heapcheck history - maintain the history file of recorded runs
+
+Usage:
+  heapcheck history prune [flags]
+
+Subcommands:
+  prune    Drop old runs and compact the rest, by the given or configured retention
+`)
+	}
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "-help" {
+		usage()
+		if len(args) == 0 {
+			return fmt.Errorf("history needs a subcommand")
+		}
+		return nil
+	}
+	switch args[0] {
+	case "prune":
+		return runHistoryPrune(args[1:])
+	}
+	usage()
+	return fmt.Errorf("unknown history subcommand %q", args[0])
+}
+
+// runHistoryPrune implements `heapcheck history prune [flags]`
+func runHistoryPrune(args []string) error {
+	fs := newFlagSet("history prune")
+	file := fs.String("file", "", "History file to prune (default: history.file in the config)")
+	keep := fs.String("keep", "", "Keep runs within this retention: an age such as 90d, a number of runs, or both, e.g. 90d,500 (default: history.keep in the config)")
+	compact := fs.String("compact", "", "Thin runs older than this, e.g. 30d, to the last run of each branch and day (default: history.compact in the config)")
+	dryRun := fs.Bool("dry-run", false, "Report what would be dropped without writing the file")
+	configFile := fs.String("config", "", "Config file (default: .heapcheck.yaml in the current directory)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `heapcheck history prune - drop old runs from the history file
+
+Usage:
+  heapcheck history prune [flags]
+
+Runs older than the retention, and the oldest beyond its number of runs,
+are dropped. Runs older than --compact are thinned to one per branch and
+day, which keeps the long-term trend in fewer runs.
+
+Examples:
+  heapcheck history prune --keep=90d
+  heapcheck history prune --keep=500 --compact=30d --dry-run
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if err := globals.apply(); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("history prune takes no arguments, got %q", fs.Arg(0))
+	}
+
+	fileCfg, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	path := *file
+	if path == "" {
+		path = fileCfg.History.File
+	}
+	if path == "" {
+		return fmt.Errorf("no history file to prune (history.file in .heapcheck.yaml or --file)")
+	}
+	if *compact != "" {
+		fileCfg.History.Compact = *compact
+	}
+	retention, compactAge, err := historyRetention(*keep, fileCfg)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+
+	h, err := history.Load(path)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	pruned := h.Prune(retention, now)
+	compacted := 0
+	if compactAge > 0 {
+		compacted = h.Compact(now.Add(-compactAge))
+	}
+
+	verb := "dropped"
+	if *dryRun {
+		verb = "would drop"
+	} else if pruned+compacted > 0 {
+		if err := history.Save(path, h); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "heapcheck: %s %d run(s) past the retention and %d by compaction, %d run(s) left in %s\n", verb, pruned, compacted, len(h.Runs), path)
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/harshakonda/heapcheck/internal/config"
+	"github.com/harshakonda/heapcheck/internal/reporter"
+)
+
+// runInit implements `heapcheck init [flags] [packages]`
+func runInit(args []string) error {
+	fs := newFlagSet("init")
+	file := fs.String("file", config.FileNames[0], "Config file to write")
+	force := fs.Bool("force", false, "Overwrite an existing config file")
+	headroom := fs.Int("headroom", 10, "Set each budget this many percent above the current escapes (at least one escape above)")
+	minPackage := fs.Int("min-package-escapes", 5, "Give packages with at least this many escapes budgets of their own; the escapes of smaller ones count toward the top-level budgets")
+	input := fs.String("input", "", "Read compiler output saved with --save-raw instead of running the compiler (- for stdin)")
+	includeVendor := fs.Bool("include-vendor", false, "Include escapes in vendor/, the module cache and cgo-generated files")
+	packagesFrom := fs.String("packages-from", "", "Analyze the packages listed in this file (one per line)")
+	goListQuery := fs.String("go-list-query", "", "Analyze the packages selected by a go list query, e.g. 'deps(./cmd/api)'")
+	entrypoint := fs.String("entrypoint", "", "Analyze only the packages of this module linked into these main packages, e.g. ./cmd/api (comma-separated)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `heapcheck init - write a starter config from the current escapes
+
+Usage:
+  heapcheck init [flags] [packages]
+
+Analyzes the packages and writes a .heapcheck.yaml whose category budgets
+fail the run once escapes grow past the current counts plus --headroom,
+per category and per package. Lower the budgets as escapes are fixed to
+ratchet them down. Escapes suppressed in the code do not count.
+
+Examples:
+  heapcheck init ./...
+  heapcheck init --headroom=0 --min-package-escapes=20 ./...
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if err := globals.apply(); err != nil {
+		return err
+	}
+
+	switch {
+	case *headroom < 0:
+		return fmt.Errorf("--headroom must not be negative")
+	case *minPackage < 1:
+		return fmt.Errorf("--min-package-escapes must be at least 1")
+	case *input == "-" && slices.Contains(fs.Args(), "-"):
+		return fmt.Errorf("--input=- and the - pattern cannot both read stdin")
+	}
+	if _, err := os.Stat(*file); err == nil && !*force {
+		return fmt.Errorf("%s exists; use --force to overwrite it", *file)
+	}
+	patterns, err := resolvePatterns(fs.Args(), *packagesFrom, *goListQuery, *entrypoint)
+	if err != nil {
+		return err
+	}
+	cfg := &Config{Patterns: patterns, Input: *input, IncludeVendor: *includeVendor}
+	results, err := loadResults(cfg, &reporter.Stats{})
+	if err != nil {
+		return err
+	}
+	if _, err := applySuppressions(cfg, results, nil); err != nil {
+		return err
+	}
+
+	starter := config.NewStarter(results, *headroom, *minPackage)
+	var buf bytes.Buffer
+	if err := starter.Write(&buf); err != nil {
+		return err
+	}
+	if err := os.WriteFile(*file, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "heapcheck: wrote %d category budget(s) and budgets for %d package(s) to %s\n", len(starter.Categories), len(starter.Packages), *file)
+	return nil
+}
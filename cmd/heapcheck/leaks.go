@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/harshakonda/heapcheck/internal/leaks"
+	"github.com/harshakonda/heapcheck/internal/reporter"
+)
+
+// runLeaks implements `heapcheck leaks [flags] [packages]`
+func runLeaks(args []string) error {
+	fs := newFlagSet("leaks")
+	formatFlag := fs.String("format", "text", "Output format: text, json, html, sarif")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `heapcheck leaks - static goroutine leak detection
+
+Usage:
+  heapcheck leaks [flags] [packages]
+
+Detects:
+  leak-time-after-in-loop   time.After called inside a loop
+  leak-missing-done         goroutine loops on select with no ctx.Done()/exit case
+  leak-unclosed-range       goroutine ranges over a channel that is never closed
+  leak-unclosed-select      goroutine waits on a channel that is never closed
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if err := globals.apply(); err != nil {
+		return err
+	}
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	useBuildContext()
+	findings, err := leaks.Analyze(patterns)
+	if err != nil {
+		return fmt.Errorf("analyzing leaks: %w", err)
+	}
+
+	if *formatFlag == "text" {
+		leaks.WriteText(os.Stdout, findings)
+		return nil
+	}
+	rep, err := reporter.New(os.Stdout, *formatFlag)
+	if err != nil {
+		return err
+	}
+	return rep.Report(context.Background(), leaks.Results(findings), reporter.Metadata{Version: Version})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/logging"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// setLimits bounds the resources heapcheck and the go commands it runs
+// use, for constrained CI runners. concurrency caps the packages the go
+// command compiles in parallel (-p in GOFLAGS) and heapcheck's own
+// threads, which read and parse source files. maxMemory, a size such as
+// 512MB, is a soft limit on heapcheck's heap that the garbage collector
+// works to keep under, and a quarter of it is the most compiler output
+// held in memory before the rest is buffered in a temporary file.
+func setLimits(concurrency int, maxMemory string) error {
+	if concurrency < 0 {
+		return fmt.Errorf("invalid --concurrency %d (want a number of workers, or 0 for one per CPU)", concurrency)
+	}
+	if concurrency > 0 {
+		if err := setGoFlag("-p", strconv.Itoa(concurrency)); err != nil {
+			return err
+		}
+		runtime.GOMAXPROCS(concurrency)
+	}
+	if maxMemory != "" {
+		n, err := parseSize(maxMemory)
+		if err != nil {
+			return fmt.Errorf("invalid --max-memory: %w", err)
+		}
+		debug.SetMemoryLimit(n)
+		parser.SetBufferLimit(n / 4)
+		logging.Logger().Debug("limited memory", "bytes", n, "outputBuffer", n/4)
+	}
+	return nil
+}
+
+// sizeUnits are the suffixes parseSize accepts, longest first so that
+// "MiB" is not read as "B"
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// parseSize parses a size in bytes with an optional binary unit, as in
+// 512MB, 2GiB or 1G. Units are case-insensitive.
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	mult := int64(1)
+	for _, u := range sizeUnits {
+		if len(s) > len(u.suffix) && strings.EqualFold(s[len(s)-len(u.suffix):], u.suffix) {
+			s, mult = strings.TrimSpace(s[:len(s)-len(u.suffix)]), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%q is not a size such as 512MB or 2GB", s)
+	}
+	return int64(n * float64(mult)), nil
+}
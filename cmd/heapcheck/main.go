@@ -9,16 +9,49 @@
 //	heapcheck --format=json ./...      # Output as JSON
 //	heapcheck --escapes-only ./...     # Show only heap escapes
 //	heapcheck --filter=pkg/server ./...# Filter by package path
+//	heapcheck leaks ./...              # Static goroutine leak detection
+//	heapcheck diff a.json b.json       # Diff two saved results
+//	heapcheck explain fmt-call         # Explain an escape category
+//	heapcheck help                     # List all commands
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/harshakonda/heapcheck/internal/allow"
+	"github.com/harshakonda/heapcheck/internal/baseline"
+	"github.com/harshakonda/heapcheck/internal/benchcov"
+	"github.com/harshakonda/heapcheck/internal/boxing"
 	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/checkrun"
+	"github.com/harshakonda/heapcheck/internal/config"
+	"github.com/harshakonda/heapcheck/internal/copies"
+	"github.com/harshakonda/heapcheck/internal/coverage"
+	"github.com/harshakonda/heapcheck/internal/gate"
+	"github.com/harshakonda/heapcheck/internal/gcimpact"
+	"github.com/harshakonda/heapcheck/internal/goroutines"
+	"github.com/harshakonda/heapcheck/internal/handlers"
+	"github.com/harshakonda/heapcheck/internal/logging"
+	"github.com/harshakonda/heapcheck/internal/otlp"
+	"github.com/harshakonda/heapcheck/internal/owners"
 	"github.com/harshakonda/heapcheck/internal/parser"
+	"github.com/harshakonda/heapcheck/internal/paths"
+	"github.com/harshakonda/heapcheck/internal/query"
 	"github.com/harshakonda/heapcheck/internal/reporter"
+	"github.com/harshakonda/heapcheck/internal/sample"
+	"github.com/harshakonda/heapcheck/internal/staged"
+	"github.com/harshakonda/heapcheck/internal/suppress"
+	"github.com/harshakonda/heapcheck/internal/upload"
 )
 
 // Version information - set at build time via ldflags
@@ -29,134 +62,1240 @@ var (
 )
 
 func main() {
-	// Define flags
-	formatFlag := flag.String("format", "text", "Output format: text, json, html, sarif")
-	escapesOnly := flag.Bool("escapes-only", false, "Show only variables that escape to heap")
-	filterPkg := flag.String("filter", "", "Filter results by package path prefix")
-	verbose := flag.Bool("v", false, "Verbose output (show all compiler messages)")
-	version := flag.Bool("version", false, "Print version and exit")
-	help := flag.Bool("help", false, "Show help")
-
-	flag.Usage = func() {
+	err := dispatch(os.Args[1:])
+	if perr := globals.finish(); perr != nil && err == nil {
+		err = perr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "heapcheck: %v\n", err)
+	}
+	if lastOutcome != nil {
+		lastOutcome.print(os.Stderr)
+	}
+	if err != nil {
+		os.Exit(exitCode(err))
+	}
+}
+
+// runAnalyze implements `heapcheck [analyze] [flags] [packages]`
+func runAnalyze(args []string) error {
+	fs := newFlagSet("analyze")
+	config := analyzeFlags(fs)
+	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, `heapcheck - Go escape analysis made human-readable
 
 Usage:
-  heapcheck [flags] [packages]
+  heapcheck [analyze] [flags] [packages]
+  heapcheck <command> [flags] [args]  (heapcheck help lists the commands)
 
 Examples:
   heapcheck ./...                     Analyze all packages
   heapcheck ./pkg/server              Analyze specific package
   heapcheck --format=json ./...       Output as JSON
+  heapcheck --format=json --summary-only ./...
+                                      Output only the aggregates as JSON
   heapcheck --escapes-only ./...      Show only heap allocations
   heapcheck --filter=internal ./...   Filter by path
+  heapcheck --owner=@platform-team ./...
+                                      Show escapes in files a team owns
+  heapcheck --where='sink=channel' ./...
+                                      Filter by how values escape
+  heapcheck --interface-params ./...  Find APIs whose parameters cause boxing
+  heapcheck --goroutines ./...        Group goroutine and channel escapes by function
+  heapcheck --handlers ./...          Group escapes by HTTP handler and route
+  heapcheck --gc-impact --sort=bytes ./...
+                                      Rank escapes by estimated bytes allocated
+  heapcheck --sort=position ./...     List escapes by file and line, not by impact
+  heapcheck --large-copies ./...      Find large structs copied by value around escapes
+  heapcheck --cover=coverage.out ./...
+                                      Mark escapes covered by tests
+  heapcheck --benchmarks=bench.txt ./...
+                                      Mark escapes in code benchmarks exercise
+  heapcheck --fail-on-trend=+5%% ./...
+                                      Fail on slow growth vs. recorded runs
+  heapcheck --tee=heapcheck.txt ./...
+                                      Also write the report to a file, e.g. a CI artifact
+  heapcheck --summary-markdown=$GITHUB_STEP_SUMMARY ./...
+                                      Add the gate outcome to the CI job summary
+  heapcheck --compare-flags ./...     Find escapes that depend on inlining
+  heapcheck --mod=vendor ./...        Build from vendor/ like the project does
+  heapcheck --go-list-query='deps(./cmd/api)'
+                                      Analyze one binary's dependencies
+  heapcheck --entrypoint=./cmd/api    Analyze only your packages linked into a binary
+  heapcheck --packages-from=packages.txt
+                                      Analyze packages listed in a file
+  ./affected.sh | heapcheck -        Analyze import paths read from stdin
+  heapcheck --write-baseline=heapcheck-baseline.json ./...
+  heapcheck --baseline=heapcheck-baseline.json ./...
+  heapcheck --baseline=heapcheck-baseline.json --format=delta ./...
+                                      List escapes added or removed since the baseline
+  heapcheck --baseline=heapcheck-baseline.json --format=pr-comment ./...
+                                      Markdown body for a pull request comment bot
+  heapcheck --baseline=https://ci.example.com/artifacts/main/heapcheck.json ./...
+                                      Compare with the main branch's latest artifact
+  heapcheck --write-baseline=heapcheck-baseline.json --only-new-since=30d ./...
+                                      Show escapes that appeared in the last 30 days
+  heapcheck --strict-empty ./...      Fail in CI if nothing was analyzed
+  heapcheck --plan --filter=internal ./...
+                                      List the packages an analysis would compile, without compiling
+  heapcheck --stats ./...             Show phase timings and unrecognized compiler lines
+  heapcheck --sample=10%% ./...        Analyze a stable tenth of the packages for a quick signal
+  heapcheck --json-events ./...       Stream per-package progress as NDJSON on stderr
+  heapcheck --format=sarif --path-style=relative ./...
+                                      Repo-relative paths for code scanning
+  heapcheck --baseline=heapcheck-baseline.json --github-check ./...
+                                      Annotate new escapes on the pull request
+  heapcheck --save-raw=raw.txt ./...  Keep the compiler output for bug reports
+  heapcheck --input=raw.txt --format=html
+                                      Re-render saved compiler output
+  heapcheck --capture-unparsed=unparsed.txt ./...
+                                      Collect compiler lines heapcheck cannot parse
+  heapcheck baseline ./...            Write heapcheck-baseline.json
+  heapcheck explain interface-boxing  Explain a category, with an example
+  heapcheck leaks ./...               Static goroutine leak detection
+  heapcheck diff old.json new.json --format=html
+                                      Side-by-side diff of two JSON results
+  heapcheck web ./...                 Browse the HTML report at localhost:8080
+  heapcheck daemon &                  Keep analyses warm for the editor and git hooks
 
 Flags:
 `)
-		flag.PrintDefaults()
+		fs.PrintDefaults()
 		fmt.Fprintf(os.Stderr, `
 Output Formats:
-  text   Human-readable summary (default)
-  json   Machine-readable JSON
-  html   Visual HTML report
-  sarif  GitHub Code Scanning compatible
+  text        Human-readable summary (default)
+  json        Machine-readable JSON
+  html        Visual HTML report
+  sarif       GitHub Code Scanning compatible
+  matrix      Package × category table of escape counts
+  matrix-csv  The same table as CSV, for spreadsheets
+  delta       JSON Patch of escapes added to and removed from --baseline
 
 For more information: https://github.com/harshakonda/heapcheck
 `)
 	}
 
-	flag.Parse()
+	parseFlags(fs, args)
 
-	if *version {
-		fmt.Printf("heapcheck version %s\n", Version)
-		if Commit != "unknown" {
-			fmt.Printf("  commit: %s\n", Commit)
-		}
-		if Date != "unknown" {
-			fmt.Printf("  built:  %s\n", Date)
-		}
-		os.Exit(0)
+	if err := globals.apply(); err != nil {
+		return err
 	}
-
-	if *help {
-		flag.Usage()
-		os.Exit(0)
+	cfg, err := config()
+	if err != nil {
+		return err
 	}
-
-	// Get package patterns from remaining args
-	patterns := flag.Args()
-	if len(patterns) == 0 {
-		patterns = []string{"./..."}
+	out, closeTee, err := openTee(cfg.Tee)
+	if err != nil {
+		return err
 	}
+	err = analyzeTo(out, cfg)
+	if closeErr := closeTee(); err == nil {
+		err = closeErr
+	}
+	return err
+}
 
-	// Run analysis
-	config := &Config{
-		Format:      *formatFlag,
-		EscapesOnly: *escapesOnly,
-		FilterPkg:   *filterPkg,
-		Verbose:     *verbose,
-		Patterns:    patterns,
+// analyzeTo runs the analysis cfg describes, writing the report to out
+func analyzeTo(out io.Writer, cfg *Config) error {
+	if cfg.Plan {
+		return runPlan(out, cfg)
+	}
+	if ok, err := delegate(out, cfg); ok {
+		finishOutcome(cfg, err)
+		return err
+	}
+	if cfg.JSONEvents {
+		cfg.events = newEventWriter(os.Stderr)
+		cfg.events.start(cfg.Patterns)
+	}
+	err := run(out, cfg)
+	if cfg.events != nil {
+		cfg.events.done(err)
 	}
+	finishOutcome(cfg, err)
+	return err
+}
 
-	if err := run(config); err != nil {
-		fmt.Fprintf(os.Stderr, "heapcheck: %v\n", err)
-		os.Exit(1)
+// langUsage is the help of the --lang flags
+var langUsage = "Language of the category names and suggestions in the HTML report: " + strings.Join(reporter.Languages(), ", ") + ", or the path of a JSON catalog"
+
+// analyzeFlags registers the analysis flags on fs. The returned function
+// builds the Config from them once fs has been parsed.
+func analyzeFlags(fs *flag.FlagSet) func() (*Config, error) {
+	formatFlag := fs.String("format", "text", "Output format: text, json, html, sarif, matrix, matrix-csv, delta, pr-comment")
+	summaryOnly := fs.Bool("summary-only", false, "With --format=json, output only the summary, per-category counts and gate result, without the escapes")
+	escapesOnly := fs.Bool("escapes-only", false, "Show only variables that escape to heap")
+	where := fs.String("where", "", "Filter escapes by flow, e.g. 'sink=channel' or 'sink=interface|closure,category=fmt-call'")
+	categorizerExec := fs.String("categorizer-exec", "", "External command that categorizes escapes (JSON on stdin/stdout)")
+	rulesetFile := fs.String("ruleset", "", "File of category rules tried before the built-in ones (default: ruleset in the config)")
+	interfaceParams := fs.Bool("interface-params", false, "List the interface parameters that boxing escapes are passed to (type-checks the packages)")
+	gcImpact := fs.Bool("gc-impact", false, "Estimate the bytes each escape allocates per call, for the impact order and --sort=bytes (type-checks the packages)")
+	sortFlag := fs.String("sort", categorizer.OrderImpact, "Order escapes by: impact (severity, loops, benchmarks, size and effort combined), bytes (estimated bytes per call, with --gc-impact), position")
+	largeCopies := fs.Bool("large-copies", false, "List large structs and arrays that calls in functions with escapes copy by value (type-checks the packages)")
+	largeCopyMin := fs.Int64("large-copy-min", copies.DefaultMinSize, "Smallest copy in bytes that --large-copies lists")
+	goroutinesFlag := fs.Bool("goroutines", false, "Group goroutine and channel escapes by the function that spawns them")
+	handlersFlag := fs.Bool("handlers", false, "Group the escapes inside HTTP handlers by handler and route (type-checks the packages)")
+	coverFile := fs.String("cover", "", "Mark escapes covered by tests, using a go test -coverprofile file")
+	benchFile := fs.String("benchmarks", "", "Mark escapes in functions benchmarks exercise, using go test -bench output or a list of functions")
+	includeVendor := fs.Bool("include-vendor", false, "Include escapes in vendor/, the module cache and cgo-generated files")
+	filterPkg := fs.String("filter", "", "Filter results by package path prefix")
+	ownerFlag := fs.String("owner", "", "Show only escapes in files owned by this CODEOWNERS owner, e.g. @platform-team")
+	ownersFile := fs.String("owners-file", "", "CODEOWNERS file attributing escapes to owners (default: .github/CODEOWNERS, CODEOWNERS or docs/CODEOWNERS)")
+	verbose := fs.Bool("v", false, "Verbose output (show all compiler messages)")
+	stats := fs.Bool("stats", false, "Report compile, parse and categorize times and how many compiler lines were parsed or unrecognized")
+	color := fs.String("color", "auto", "Color text output: auto, always, never")
+	width := fs.Int("width", 0, "Lay out text output for this many columns (default: the terminal's width, $COLUMNS or 80)")
+	noLinks := fs.Bool("no-links", false, "Omit documentation links from suggestions")
+	limit := fs.Int("limit", 0, "List at most this many escapes in text and HTML details (0: default)")
+	configFile := fs.String("config", "", "Config file (default: .heapcheck.yaml in the current directory)")
+	saveRaw := fs.String("save-raw", "", "Save the unmodified compiler output to this file")
+	captureUnparsed := fs.String("capture-unparsed", "", "Write the compiler lines the parser did not recognize to this file")
+	input := fs.String("input", "", "Read compiler output saved with --save-raw instead of running the compiler (- for stdin)")
+	historyFile := fs.String("history", "", "Record runs on the default branch in this history file (overrides history.file in the config)")
+	historyKeep := fs.String("history-keep", "", "Drop recorded runs beyond this retention: an age such as 90d, a number of runs, or both, e.g. 90d,500 (overrides history.keep in the config)")
+	failOnTrend := fs.String("fail-on-trend", "", "Fail if escapes grew more than this over the rolling average of recorded runs, e.g. +5%")
+	summaryMarkdown := fs.String("summary-markdown", "", "Append a Markdown PASS/FAIL summary of the gates to this file, e.g. $GITHUB_STEP_SUMMARY")
+	gateOutput := fs.String("gate-output", "", "Write the category gate result as JSON to this file")
+	compareFlags := fs.Bool("compare-flags", false, "Compare escapes with and without inlining (-l) and report the differences")
+	gcflagsExtra := fs.String("gcflags-extra", "", "Append these compiler flags to the analysis build's -gcflags, e.g. '-d=escapedebug=2'; lines they print at an escape's position are kept in its flow details")
+	baselineFile := fs.String("baseline", "", "Suppress escapes listed in this baseline file, or downloaded from this http(s) URL (header from $HEAPCHECK_BASELINE_HEADER)")
+	writeBaseline := fs.String("write-baseline", "", "Write all current escapes to this baseline file")
+	strictEmpty := fs.Bool("strict-empty", false, "Fail if the analysis produced no results, e.g. the packages compiled without escape analysis output")
+	onlyNewSince := fs.String("only-new-since", "", "Show only escapes first seen within this window, per the baseline (e.g. 30d)")
+	packagesFrom := fs.String("packages-from", "", "Analyze the packages listed in this file (one per line)")
+	goListQuery := fs.String("go-list-query", "", "Analyze the packages selected by a go list query, e.g. 'deps(./cmd/api)'")
+	entrypoint := fs.String("entrypoint", "", "Analyze only the packages of this module linked into these main packages, e.g. ./cmd/api (comma-separated)")
+	sampleFlag := fs.String("sample", "", "Analyze this percentage of the packages, e.g. 10%, selected by a hash of their import paths so that runs analyze the same ones; totals for all packages are estimated")
+	expiryWindow := fs.String("expiry-window", "14d", "Report suppressions expiring within this window (e.g. 14d, 72h)")
+	pathStyle := fs.String("path-style", "", "Render file paths relative to the current directory, with their module path, or absolute: relative, module, absolute (default: as the compiler prints them)")
+	lang := fs.String("lang", "", langUsage)
+	maxUncategorized := fs.Float64("max-uncategorized-pct", 0, "Fail if more than this percentage of escapes is uncategorized, a sign of compiler output drift or missing rules (0: no limit)")
+	otlpEndpoint := fs.String("otlp-endpoint", "", "Export the run summary as OTLP metrics and a span to this OpenTelemetry collector, e.g. http://localhost:4318 (headers from $OTEL_EXPORTER_OTLP_HEADERS)")
+	uploadDest := fs.String("upload", "", "Upload the JSON and HTML reports with commit metadata to s3://bucket/path, gs://bucket/path or azblob://account/container/path ({repo}, {commit} and {branch} are filled in)")
+	githubCheck := fs.Bool("github-check", false, "Create a GitHub Check Run annotating the reported escapes, e.g. those new since --baseline, on the pull request's head commit (needs $GITHUB_TOKEN)")
+	plan := fs.Bool("plan", false, "Print the packages the analysis would compile, which are cached and which are excluded and why, without compiling")
+	tee := fs.String("tee", "", "Also write the report to this file, in the same format and without terminal colors, e.g. for a CI artifact")
+	jsonEvents := fs.Bool("json-events", false, "Write NDJSON progress events to stderr: start, one per package compiled with escape counts so far, and done, followed by the heapcheck: total=... outcome line")
+
+	return func() (*Config, error) {
+		// Get package patterns from remaining args
+		if *input == "-" && slices.Contains(fs.Args(), "-") {
+			return nil, fmt.Errorf("--input=- and the - pattern cannot both read stdin")
+		}
+		patterns, err := resolvePatterns(fs.Args(), *packagesFrom, *goListQuery, *entrypoint)
+		if err != nil {
+			return nil, err
+		}
+
+		var percent float64
+		var sampledFrom int
+		if *sampleFlag != "" {
+			if percent, err = sample.ParsePercent(*sampleFlag); err != nil {
+				return nil, fmt.Errorf("--sample: %w", err)
+			}
+			switch {
+			case *input != "":
+				return nil, fmt.Errorf("--sample selects the packages to compile and cannot be used with --input")
+			case *writeBaseline != "":
+				return nil, fmt.Errorf("--sample would leave the packages it skips out of the baseline written by --write-baseline")
+			case *failOnTrend != "":
+				return nil, fmt.Errorf("--sample counts the escapes of some packages, which --fail-on-trend cannot compare with runs of all of them")
+			}
+			listed, err := parser.ResolveQuery(strings.Join(patterns, " "))
+			if err != nil {
+				return nil, err
+			}
+			patterns, sampledFrom = sample.Select(listed, percent), len(listed)
+		}
+
+		window, err := parseDuration(*expiryWindow)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --expiry-window: %v", err)
+		}
+
+		catalog, err := reporter.LoadCatalog(*lang)
+		if err != nil {
+			return nil, fmt.Errorf("--lang: %w", err)
+		}
+
+		switch {
+		case !slices.Contains(categorizer.Orders, *sortFlag):
+			return nil, fmt.Errorf("invalid --sort %q (want %s)", *sortFlag, strings.Join(categorizer.Orders, ", "))
+		case *sortFlag == categorizer.OrderBytes && !*gcImpact:
+			return nil, fmt.Errorf("--sort=bytes sorts by the estimates of --gc-impact")
+		}
+
+		if *pathStyle != "" {
+			if _, err := paths.New(*pathStyle, "."); err != nil {
+				return nil, fmt.Errorf("--path-style: %w", err)
+			}
+		}
+
+		var newSince time.Duration
+		if *onlyNewSince != "" {
+			newSince, err = parseDuration(*onlyNewSince)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --only-new-since: %v", err)
+			}
+		}
+
+		if baseline.IsRemote(*writeBaseline) {
+			return nil, fmt.Errorf("--write-baseline writes a local file, not %s", *writeBaseline)
+		}
+
+		if *maxUncategorized < 0 || *maxUncategorized > 100 {
+			return nil, fmt.Errorf("invalid --max-uncategorized-pct %g (want 0 to 100)", *maxUncategorized)
+		}
+
+		if *otlpEndpoint != "" {
+			if _, err := otlp.New(*otlpEndpoint); err != nil {
+				return nil, fmt.Errorf("--otlp-endpoint: %w", err)
+			}
+		}
+
+		if *uploadDest != "" {
+			if _, err := upload.New(*uploadDest, upload.Commit{}); err != nil {
+				return nil, fmt.Errorf("--upload: %w", err)
+			}
+		}
+
+		if *largeCopyMin <= 0 {
+			return nil, fmt.Errorf("invalid --large-copy-min %d (want a size in bytes)", *largeCopyMin)
+		}
+
+		if *summaryOnly && *formatFlag != "json" {
+			return nil, fmt.Errorf("--summary-only needs --format=json")
+		}
+
+		if *gcflagsExtra != "" && *input != "" {
+			return nil, fmt.Errorf("--gcflags-extra changes how the compiler runs and cannot be used with --input")
+		}
+
+		flags := make(map[string]string)
+		fs.VisitAll(func(f *flag.Flag) { flags[f.Name] = f.Value.String() })
+		var given []string
+		fs.Visit(func(f *flag.Flag) { given = append(given, f.Name) })
+
+		return &Config{
+			Format:           *formatFlag,
+			SummaryOnly:      *summaryOnly,
+			EscapesOnly:      *escapesOnly,
+			FilterPkg:        *filterPkg,
+			Owner:            *ownerFlag,
+			OwnersFile:       *ownersFile,
+			IncludeVendor:    *includeVendor,
+			Where:            *where,
+			CoverProfile:     *coverFile,
+			Benchmarks:       *benchFile,
+			InterfaceParams:  *interfaceParams,
+			Goroutines:       *goroutinesFlag,
+			Handlers:         *handlersFlag,
+			GCImpact:         *gcImpact,
+			Sort:             *sortFlag,
+			LargeCopies:      *largeCopies,
+			LargeCopyMin:     *largeCopyMin,
+			CategorizerExec:  *categorizerExec,
+			Ruleset:          *rulesetFile,
+			Verbose:          *verbose,
+			Stats:            *stats,
+			Color:            *color,
+			Width:            *width,
+			Limit:            *limit,
+			NoLinks:          *noLinks,
+			CompareFlags:     *compareFlags,
+			GCFlagsExtra:     strings.TrimSpace(*gcflagsExtra),
+			ConfigFile:       *configFile,
+			GateOutput:       *gateOutput,
+			SummaryMarkdown:  *summaryMarkdown,
+			History:          *historyFile,
+			HistoryKeep:      *historyKeep,
+			SaveRaw:          *saveRaw,
+			CaptureUnparsed:  *captureUnparsed,
+			Input:            *input,
+			FailOnTrend:      *failOnTrend,
+			Patterns:         patterns,
+			Baseline:         *baselineFile,
+			WriteBaseline:    *writeBaseline,
+			OnlyNewSince:     newSince,
+			StrictEmpty:      *strictEmpty,
+			MaxUncategorized: *maxUncategorized,
+			ExpiryWindow:     window,
+			PathStyle:        *pathStyle,
+			Lang:             *lang,
+			catalog:          catalog,
+			JSONEvents:       *jsonEvents,
+			Tee:              *tee,
+			OTLPEndpoint:     *otlpEndpoint,
+			Upload:           *uploadDest,
+			GitHubCheck:      *githubCheck,
+			Plan:             *plan,
+			Sample:           percent,
+			SampledFrom:      sampledFrom,
+			Flags:            flags,
+			FlagsGiven:       given,
+		}, nil
 	}
 }
 
 // Config holds the CLI configuration
 type Config struct {
-	Format      string
-	EscapesOnly bool
-	FilterPkg   string
-	Verbose     bool
-	Patterns    []string
+	Format           string
+	SummaryOnly      bool
+	EscapesOnly      bool
+	FilterPkg        string
+	Owner            string
+	OwnersFile       string
+	IncludeVendor    bool
+	Where            string
+	CoverProfile     string
+	Benchmarks       string
+	InterfaceParams  bool
+	Goroutines       bool
+	Handlers         bool
+	GCImpact         bool
+	Sort             string
+	LargeCopies      bool
+	LargeCopyMin     int64
+	CategorizerExec  string
+	Ruleset          string
+	Verbose          bool
+	Stats            bool
+	Color            string
+	Width            int
+	Limit            int
+	NoLinks          bool
+	CompareFlags     bool
+	GCFlagsExtra     string
+	ConfigFile       string
+	GateOutput       string
+	SummaryMarkdown  string
+	History          string
+	HistoryKeep      string
+	SaveRaw          string
+	CaptureUnparsed  string
+	Input            string
+	FailOnTrend      string
+	Patterns         []string
+	Baseline         string
+	WriteBaseline    string
+	OnlyNewSince     time.Duration
+	StrictEmpty      bool
+	MaxUncategorized float64
+	ExpiryWindow     time.Duration
+	PathStyle        string
+	Lang             string
+	JSONEvents       bool
+	Tee              string
+	OTLPEndpoint     string
+	Upload           string
+	GitHubCheck      bool
+	Plan             bool
+
+	// Sample is the percentage of packages analyzed, 0 for all of them,
+	// and SampledFrom the number of packages the sample was taken from
+	Sample      float64
+	SampledFrom int
+
+	// Flags are the values of all flags, defaults included, and
+	// FlagsGiven the names of those given, for the reports to record
+	Flags      map[string]string
+	FlagsGiven []string
+
+	events  *eventWriter      // set with JSONEvents
+	accept  *acceptor         // set by web, to accept escapes from the report
+	watch   *watcher          // set by watch, to print what changed between analyses
+	catalog *reporter.Catalog // loaded for Lang, nil for English
+	outcome *outcome          // set by run once the gates are evaluated
+
+	// staged limits the report to the staged changes, for precommit,
+	// which fails on any escape left with failStaged
+	staged     *staged.Changes
+	failStaged bool
 }
 
-func run(cfg *Config) error {
-	// Step 1: Run compiler and capture escape analysis output
-	rawOutput, err := parser.RunCompiler(cfg.Patterns)
+func run(w io.Writer, cfg *Config) error {
+	started := time.Now()
+	if cfg.Format == "delta" && cfg.Baseline == "" {
+		return fmt.Errorf("--format=delta needs a baseline to compare with (--baseline)")
+	}
+	fileCfg, err := loadConfig(cfg.ConfigFile)
+	if err != nil {
+		return err
+	}
+	rules, err := fileCfg.Rules()
 	if err != nil {
-		return fmt.Errorf("running compiler: %w", err)
+		return err
 	}
+	nested, err := config.FindNested(".")
+	if err != nil {
+		return err
+	}
+	scopes, err := fileCfg.PackageScopes()
+	if err != nil {
+		return err
+	}
+	nestedScopes, err := config.Scopes(nested)
+	if err != nil {
+		return err
+	}
+	scopes = append(scopes, nestedScopes...)
 
-	// Step 2: Parse the raw output into structured data
-	escapes, err := parser.Parse(rawOutput)
+	// Saved output may come from another configuration; source is read
+	// per the configuration only when this run compiles
+	if cfg.Input == "" {
+		useBuildContext()
+	}
+
+	if cfg.CompareFlags {
+		if cfg.Input != "" {
+			return fmt.Errorf("--compare-flags runs the compiler twice and cannot be used with --input")
+		}
+		return runCompareFlags(w, cfg)
+	}
+
+	// Steps 1-3: Run the compiler, parse and categorize its output
+	var stats reporter.Stats
+	results, err := loadResults(cfg, &stats)
 	if err != nil {
-		return fmt.Errorf("parsing output: %w", err)
+		return err
 	}
+	// Measured before suppressions and filters, against all escapes the
+	// categorizers saw
+	uncategorized := results.UncategorizedPct()
 
-	// Step 3: Categorize and add suggestions
-	results := categorizer.Categorize(escapes)
+	// Escape ages come from the baseline being written, or else the one
+	// being applied. That one is loaded, or downloaded, once, after the
+	// baseline written, which may be the same file.
+	var ages, base *baseline.Baseline
+	if cfg.WriteBaseline != "" {
+		if ages, err = writeBaselineFile(cfg.WriteBaseline, results, started); err != nil {
+			return err
+		}
+	}
+	if cfg.Baseline != "" {
+		if base, err = baseline.Load(cfg.Baseline); err != nil {
+			return err
+		}
+		if ages == nil {
+			ages = base
+		}
+	}
+	if ages == nil && cfg.OnlyNewSince > 0 {
+		return fmt.Errorf("--only-new-since needs escape ages from --baseline or --write-baseline")
+	}
+	if ages != nil {
+		ages.AnnotateAges(results, started)
+	}
+
+	// Step 4: Apply allowed functions, suppressions and filters
+	if _, err := allow.ApplyScoped(cfg.Patterns, fileCfg.AllowFor(nested), results); err != nil {
+		return fmt.Errorf("applying allowed functions: %w", err)
+	}
+	expired, err := applySuppressions(cfg, results, base)
+	if err != nil {
+		return err
+	}
+	var delta *baseline.Delta
+	if base != nil {
+		delta = base.Compare(results)
+	}
+
+	if err := applyOwners(cfg, results); err != nil {
+		return err
+	}
 
-	// Step 4: Apply filters
 	if cfg.EscapesOnly {
 		results = filterEscapesOnly(results)
 	}
+	if cfg.OnlyNewSince > 0 {
+		results = filterNewSince(results, started.Add(-cfg.OnlyNewSince))
+	}
 	if cfg.FilterPkg != "" {
 		results = filterByPackage(results, cfg.FilterPkg)
 	}
+	if cfg.Owner != "" {
+		results = filterByOwner(results, cfg.Owner)
+	}
+	if cfg.Where != "" {
+		q, err := query.Parse(cfg.Where)
+		if err != nil {
+			return fmt.Errorf("invalid --where: %w", err)
+		}
+		results = filterWhere(results, q)
+	}
+	if cfg.staged != nil {
+		results = filterStaged(results, cfg.staged)
+	}
+	if cfg.CoverProfile != "" {
+		profile, err := coverage.ParseProfile(cfg.CoverProfile)
+		if err != nil {
+			return err
+		}
+		profile.Resolve()
+		coverage.Annotate(results, profile)
+	}
+	if cfg.Benchmarks != "" {
+		targets, err := benchcov.Load(cfg.Benchmarks)
+		if err != nil {
+			return err
+		}
+		benchcov.Annotate(results, targets)
+	}
+	if cfg.InterfaceParams {
+		params, err := boxing.Analyze(cfg.Patterns, results.Escapes)
+		if err != nil {
+			return fmt.Errorf("finding interface parameters: %w", err)
+		}
+		results.InterfaceParams = params
+	}
+	if cfg.Goroutines {
+		results.Goroutines = goroutines.Analyze(results.Escapes)
+	}
+	if cfg.Handlers {
+		groups, err := handlers.Analyze(cfg.Patterns, results.Escapes)
+		if err != nil {
+			return fmt.Errorf("finding HTTP handlers: %w", err)
+		}
+		results.Handlers = groups
+	}
+	if cfg.GCImpact {
+		if err := gcimpact.Annotate(cfg.Patterns, results.Escapes); err != nil {
+			return fmt.Errorf("estimating GC impact: %w", err)
+		}
+	}
+	if cfg.LargeCopies {
+		large, err := copies.Analyze(cfg.Patterns, results.Escapes, cfg.LargeCopyMin)
+		if err != nil {
+			return fmt.Errorf("finding large copies: %w", err)
+		}
+		results.LargeCopies = large
+	}
+	// Sorted after the analyses above, whose annotations the impact order
+	// weighs
+	categorizer.SortEscapes(results.Escapes, cfg.Sort)
+
+	if cfg.accept != nil {
+		cfg.accept.record(results.Escapes)
+	}
+
+	// Categories are remapped after the analyses above, which look for
+	// built-in ones, so that gates and reports see the configured names
+	categorizer.Remap(results, fileCfg.Remap)
+	if cfg.watch != nil {
+		cfg.watch.record(results)
+	}
+
+	gateResult := gate.EvaluateScoped(results, rules, scopes)
+	cfg.outcome = newOutcome(results, gateResult)
+	if cfg.GateOutput != "" {
+		if err := gate.WriteFile(cfg.GateOutput, results, gateResult); err != nil {
+			return err
+		}
+	}
+
+	trend, err := checkTrend(cfg, fileCfg, results)
+	if err != nil {
+		return err
+	}
+
+	// Annotations need paths in the repository, whatever the style
+	var annotations []checkrun.Annotation
+	if cfg.GitHubCheck {
+		if annotations, err = checkrun.Annotations(results.Escapes, githubWorkspace()); err != nil {
+			return err
+		}
+	}
+
+	// Step 5: Generate report, with paths in the requested style
+	if cfg.PathStyle != "" {
+		r, err := paths.New(cfg.PathStyle, ".")
+		if err != nil {
+			return err
+		}
+		r.Apply(results)
+	}
+	color, err := useColor(cfg.Color)
+	if err != nil {
+		return err
+	}
+	opts := []reporter.Option{
+		reporter.WithVerbose(cfg.Verbose),
+		reporter.WithColor(color),
+		reporter.WithWidth(textWidth(cfg.Width)),
+		reporter.WithLimit(cfg.Limit),
+		reporter.WithLinks(!cfg.NoLinks),
+		reporter.WithDocLinks(fileCfg.Links),
+		reporter.WithSuggestions(fileCfg.SuggestionOverrides()),
+		reporter.WithSummaryOnly(cfg.SummaryOnly),
+		reporter.WithCatalog(cfg.catalog),
+	}
+	if cfg.accept != nil {
+		opts = append(opts, reporter.WithAccept("/accept", cfg.accept.token, cfg.accept.targets()...))
+	}
+	rep, err := reporter.New(w, cfg.Format, opts...)
+	if err != nil {
+		return err
+	}
+	meta := reporter.Metadata{
+		Version:  Version,
+		Started:  started,
+		Duration: time.Since(started),
+		Gate:     gateResult,
+		Trend:    trend,
+		Delta:    delta,
+		Config:   appliedConfig(cfg, fileCfg, nested),
+	}
+	if cfg.Sample > 0 {
+		meta.Sample = &reporter.Sample{Percent: cfg.Sample, Packages: len(cfg.Patterns), Total: cfg.SampledFrom}
+	}
+	if cfg.Stats {
+		meta.Stats = &stats
+	}
+	if err := rep.Report(context.Background(), results, meta); err != nil {
+		return err
+	}
+	if cfg.SummaryMarkdown != "" {
+		if err := appendMarkdownSummary(cfg.SummaryMarkdown, results, meta); err != nil {
+			return err
+		}
+	}
+	if cfg.OTLPEndpoint != "" {
+		exportOTLP(cfg.OTLPEndpoint, results, meta)
+	}
+	if cfg.Upload != "" {
+		uploadReports(cfg.Upload, results, meta, opts)
+	}
+	if cfg.GitHubCheck {
+		createCheckRun(annotations, results, meta)
+	}
+
+	if expired > 0 {
+		return withExit(exitGate, fmt.Errorf("%d escape(s) resurfaced because their suppression expired", expired))
+	}
+	if gateResult != nil && gateResult.Status == categorizer.GateFail {
+		return withExit(exitGate, fmt.Errorf("category gate failed"))
+	}
+	if cfg.MaxUncategorized > 0 && uncategorized > cfg.MaxUncategorized {
+		return withExit(exitGate, fmt.Errorf("%.1f%% of escapes are uncategorized (max %g%%): the compiler output may have changed with a new Go version, or categorizer rules are missing", uncategorized, cfg.MaxUncategorized))
+	}
+	if cfg.failStaged && len(results.Escapes) > 0 {
+		return withExit(exitGate, fmt.Errorf("%d escape(s) in the staged changes", len(results.Escapes)))
+	}
+	if trend != nil {
+		if trend.Exceeded {
+			return withExit(exitGate, fmt.Errorf("escape trend exceeded: %s", trend))
+		}
+		fmt.Fprintf(os.Stderr, "heapcheck: trend ok: %s\n", trend)
+	}
+	if n := notAnalyzed(results.Summary.Skipped); n > 0 {
+		return withExit(exitPartial, fmt.Errorf("%d package(s) could not be analyzed", n))
+	}
+	return nil
+}
+
+// loadResults runs the compiler, or reads --input, then parses and
+// categorizes the escape analysis output, recording phase times in stats
+func loadResults(cfg *Config, stats *reporter.Stats) (*categorizer.Results, error) {
+	// Step 1: Run compiler and capture escape analysis output
+	compileStarted := time.Now()
+	rawOutput, skipped, err := compilerOutput(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Input == "" {
+		stats.Compile = time.Since(compileStarted)
+	}
+
+	// Step 2: Parse the raw output into structured data
+	parseStarted := time.Now()
+	escapes, outputStats, err := parser.ParseWithStats(context.Background(), rawOutput)
+	if err != nil {
+		return nil, fmt.Errorf("parsing output: %w", err)
+	}
+	stats.Parse, stats.Output = time.Since(parseStarted), outputStats
+	if cfg.CaptureUnparsed != "" {
+		if err := writeUnparsed(cfg.CaptureUnparsed, outputStats.Unparsed); err != nil {
+			return nil, err
+		}
+	}
+	if !cfg.IncludeVendor {
+		escapes = parser.SkipThirdParty(escapes)
+	}
+	if cfg.StrictEmpty && len(escapes) == 0 {
+		return nil, errEmpty(cfg)
+	}
+
+	// Step 3: Categorize and add suggestions
+	if err := useRuleset(cfg); err != nil {
+		return nil, err
+	}
+	categorizers := categorizer.Registered()
+	if cfg.CategorizerExec != "" {
+		c, err := categorizer.NewExecCategorizer(strings.Fields(cfg.CategorizerExec), escapes)
+		if err != nil {
+			return nil, err
+		}
+		categorizers = append(categorizers, c)
+	}
+	categorizeStarted := time.Now()
+	results := categorizer.CategorizeWith(escapes, categorizers...)
+	results.Summary.Skipped = skipped
+	stats.Categorize = time.Since(categorizeStarted)
+	logging.Logger().Debug("categorized escapes", "escapes", len(results.Escapes), "categories", len(results.ByCategory), "duration", stats.Categorize.Round(time.Millisecond))
+
+	return results, nil
+}
 
-	// Step 5: Generate report
-	var rep reporter.Reporter
-	switch cfg.Format {
-	case "json":
-		rep = reporter.NewJSONReporter(os.Stdout)
-	case "html":
-		rep = reporter.NewHTMLReporter(os.Stdout)
-	case "sarif":
-		rep = reporter.NewSARIFReporter(os.Stdout)
+// textWidth resolves --width: unless set, the width of the terminal
+// stdout writes to, else $COLUMNS, else 0 for the reporter's default
+func textWidth(width int) int {
+	if width > 0 {
+		return width
+	}
+	if n := terminalWidth(os.Stdout); n > 0 {
+		return n
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return 0
+}
+
+// useColor resolves --color: auto colors output to a terminal unless
+// NO_COLOR is set
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto", "":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		fi, err := os.Stdout.Stat()
+		return err == nil && fi.Mode()&os.ModeCharDevice != 0, nil
 	default:
-		rep = reporter.NewTextReporter(os.Stdout, cfg.Verbose)
+		return false, fmt.Errorf("invalid --color %q (want auto, always or never)", mode)
+	}
+}
+
+// errEmpty is the --strict-empty failure: the packages compiled (or the
+// input was read) but there was no escape analysis output to report
+func errEmpty(cfg *Config) error {
+	source := strings.Join(cfg.Patterns, " ")
+	if cfg.Input != "" {
+		source = cfg.Input
+	}
+	return withExit(exitPartial, fmt.Errorf("no escape analysis results for %s (--strict-empty)", source))
+}
+
+// compilerOutput returns the escape analysis output: read from --input,
+// or from running the compiler, with the packages cgo failed to build.
+// With --save-raw the output is also saved unmodified, so a run can be
+// reproduced and re-rendered later.
+func compilerOutput(cfg *Config) (string, []parser.SkippedPackage, error) {
+	// With --json-events, package events follow the compiler output
+	var progress io.Writer
+	if cfg.events != nil {
+		pw := cfg.events.packageWriter(cfg.IncludeVendor)
+		defer pw.Close()
+		progress = pw
+	}
+
+	var rawOutput string
+	var skipped []parser.SkippedPackage
+	switch cfg.Input {
+	case "":
+		out, s, err := compile(cfg.Patterns, analysisFlags(cfg.GCFlagsExtra), progress)
+		if err != nil {
+			return "", nil, compilerError("running compiler", err)
+		}
+		rawOutput, skipped = out, s
+	case "-":
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", nil, fmt.Errorf("reading compiler output from stdin: %w", err)
+		}
+		rawOutput = string(data)
+	default:
+		data, err := os.ReadFile(cfg.Input)
+		if err != nil {
+			return "", nil, fmt.Errorf("reading compiler output: %w", err)
+		}
+		rawOutput = string(data)
 	}
 
-	return rep.Report(results)
+	if cfg.Input != "" && progress != nil {
+		io.WriteString(progress, rawOutput)
+	}
+
+	if cfg.SaveRaw != "" {
+		if err := os.WriteFile(cfg.SaveRaw, []byte(rawOutput), 0o644); err != nil {
+			return "", nil, fmt.Errorf("saving compiler output: %w", err)
+		}
+	}
+	return rawOutput, skipped, nil
+}
+
+// appendMarkdownSummary appends the Markdown gate summary to path, which
+// CI job summaries such as $GITHUB_STEP_SUMMARY expect
+func appendMarkdownSummary(path string, results *categorizer.Results, meta reporter.Metadata) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("writing markdown summary: %w", err)
+	}
+	if err := reporter.WriteMarkdownSummary(f, results, meta); err != nil {
+		f.Close()
+		return fmt.Errorf("writing markdown summary: %w", err)
+	}
+	return f.Close()
+}
+
+// exportOTLP sends the run summary to an OpenTelemetry collector. A
+// collector that is down does not fail the run, as the report is
+// already out; the error is only printed.
+func exportOTLP(endpoint string, results *categorizer.Results, meta reporter.Metadata) {
+	exporter, err := otlp.New(endpoint)
+	if err == nil {
+		err = exporter.Export(context.Background(), results, meta)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "heapcheck: %v\n", err)
+	}
+}
+
+// uploadReports renders the JSON and HTML reports and uploads them to
+// dest with a metadata.json naming the commit, for dashboards across
+// repositories. Like exportOTLP it only prints errors.
+func uploadReports(dest string, results *categorizer.Results, meta reporter.Metadata, opts []reporter.Option) {
+	commit := upload.CurrentCommit()
+	var objects []upload.Object
+	for _, r := range []struct{ format, name, contentType string }{
+		{"json", "heapcheck.json", "application/json"},
+		{"html", "heapcheck.html", "text/html; charset=utf-8"},
+	} {
+		var buf bytes.Buffer
+		rep, err := reporter.New(&buf, r.format, opts...)
+		if err == nil {
+			err = rep.Report(context.Background(), results, meta)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "heapcheck: rendering %s for upload: %v\n", r.name, err)
+			return
+		}
+		objects = append(objects, upload.Object{Name: r.name, Body: buf.Bytes(), ContentType: r.contentType})
+	}
+	info, _ := json.MarshalIndent(struct {
+		upload.Commit
+		Version string    `json:"version"`
+		Started time.Time `json:"started"`
+		Escapes int       `json:"escapes"`
+		Stack   int       `json:"stackAllocated"`
+	}{commit, meta.Version, meta.Started.UTC(), results.Summary.HeapAllocated, results.Summary.StackAllocated}, "", "  ")
+	objects = append(objects, upload.Object{Name: "metadata.json", Body: append(info, '\n'), ContentType: "application/json"})
+
+	if err := upload.Upload(context.Background(), dest, commit, objects); err != nil {
+		fmt.Fprintf(os.Stderr, "heapcheck: %v\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "heapcheck: uploaded %d report(s) to %s\n", len(objects), upload.Expand(dest, commit))
+}
+
+// createCheckRun reports the escapes as a GitHub Check Run with one
+// annotation each. Like exportOTLP it only prints errors, and without
+// $GITHUB_TOKEN, e.g. on a fork's pull request, it notes the skip.
+func createCheckRun(annotations []checkrun.Annotation, results *categorizer.Results, meta reporter.Metadata) {
+	client, ok, err := checkrun.FromEnv()
+	if !ok {
+		fmt.Fprintf(os.Stderr, "heapcheck: $%s is not set; skipping the GitHub check run\n", checkrun.TokenEnv)
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "heapcheck: %v\n", err)
+		return
+	}
+	sha, err := checkrun.HeadSHA()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "heapcheck: %v\n", err)
+		return
+	}
+
+	run := checkrun.Run{
+		HeadSHA:     sha,
+		Conclusion:  "success",
+		Title:       "No new escapes",
+		Annotations: annotations,
+	}
+	if n := len(results.Escapes); n > 0 {
+		run.Conclusion = "neutral"
+		run.Title = fmt.Sprintf("%d new escape(s)", n)
+	}
+	if meta.Gate != nil && meta.Gate.Status == categorizer.GateFail {
+		run.Conclusion = "failure"
+	}
+	var summary strings.Builder
+	if meta.Delta != nil {
+		fmt.Fprintf(&summary, "%d escape(s) added and %d removed since the baseline.\n\n", len(meta.Delta.Added), len(meta.Delta.Removed))
+	}
+	reporter.WriteMarkdownSummary(&summary, results, meta)
+	if len(annotations) < len(results.Escapes) {
+		fmt.Fprintf(&summary, "%d escape(s) outside the repository or in generated code are not annotated.\n", len(results.Escapes)-len(annotations))
+	}
+	run.Summary = summary.String()
+	if run.Summary == "" {
+		run.Summary = run.Title
+	}
+
+	url, err := client.Create(context.Background(), run)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "heapcheck: %v\n", err)
+		return
+	}
+	logging.Logger().Debug("created check run", "url", url, "annotations", len(annotations))
+}
+
+// githubWorkspace returns the repository root of a GitHub Actions job,
+// or the working directory elsewhere
+func githubWorkspace() string {
+	if dir := os.Getenv(checkrun.WorkspaceEnv); dir != "" {
+		return dir
+	}
+	return "."
+}
+
+// writeUnparsed writes the unrecognized compiler lines to path, one per
+// line, and notes how many there were on stderr
+func writeUnparsed(path string, lines []string) error {
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return fmt.Errorf("capturing unparsed lines: %w", err)
+	}
+	if len(lines) > 0 {
+		fmt.Fprintf(os.Stderr, "heapcheck: %d unrecognized compiler line(s) written to %s\n", len(lines), path)
+	}
+	return nil
+}
+
+// loadConfig loads the config file at path, or .heapcheck.yaml in the
+// current directory if path is empty. A missing default file yields an
+// empty config.
+func loadConfig(path string) (*config.Config, error) {
+	if path == "" {
+		path = config.Find(".")
+	}
+	if path == "" {
+		return &config.Config{}, nil
+	}
+	return config.Load(path)
+}
+
+// useRuleset has the categorizer use the rules file of --ruleset, or of
+// the config file, or else the built-in rules
+func useRuleset(cfg *Config) error {
+	path := cfg.Ruleset
+	if path == "" {
+		fileCfg, err := loadConfig(cfg.ConfigFile)
+		if err != nil {
+			return err
+		}
+		path = fileCfg.Ruleset
+	}
+	if path == "" {
+		categorizer.SetRuleset(nil)
+		return nil
+	}
+	rs, err := categorizer.LoadRuleset(path)
+	if err != nil {
+		return err
+	}
+	if builtin := categorizer.BuiltinRuleset().Version; !rs.Inherits() && rs.Version > 0 && rs.Version < builtin {
+		fmt.Fprintf(os.Stderr, "heapcheck: %s pins version %d of the category rules; the built-in rules are at version %d (heapcheck ruleset)\n", path, rs.Version, builtin)
+	}
+	categorizer.SetRuleset(rs)
+	return nil
+}
+
+// applyOwners attributes escapes to the owners in the CODEOWNERS file,
+// if there is one. Patterns are relative to the current directory.
+func applyOwners(cfg *Config, results *categorizer.Results) error {
+	path := cfg.OwnersFile
+	if path == "" {
+		path = owners.Find(".")
+	}
+	if path == "" {
+		if cfg.Owner != "" {
+			return fmt.Errorf("--owner needs a CODEOWNERS file; none found, set one with --owners-file")
+		}
+		return nil
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	f, err := owners.Load(path, root)
+	if err != nil {
+		return err
+	}
+	owners.Annotate(results, f)
+	return nil
+}
+
+// applySuppressions hides escapes matched by //heapcheck:ignore comments
+// and the entries of base, if any, and returns how many matched an
+// expired one.
+func applySuppressions(cfg *Config, results *categorizer.Results, base *baseline.Baseline) (int, error) {
+	sups, err := suppress.FromComments(suppress.Files(results))
+	if err != nil {
+		return 0, err
+	}
+
+	if base != nil {
+		sups = append(sups, suppress.FromBaseline(base)...)
+	}
+
+	if err := suppress.Validate(sups); err != nil {
+		return 0, err
+	}
+
+	return suppress.Apply(results, sups, time.Now(), cfg.ExpiryWindow), nil
+}
+
+// writeBaselineFile writes results as a baseline, keeping first-seen
+// dates and owner and expiry annotations from an existing file at path,
+// and returns the baseline written.
+func writeBaselineFile(path string, results *categorizer.Results, now time.Time) (*baseline.Baseline, error) {
+	b := baseline.FromResults(results)
+	if old, err := baseline.Load(path); err == nil {
+		b.Merge(old)
+	}
+	b.Stamp(now)
+	if err := baseline.Save(path, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// resolvePatterns combines positional patterns with those from
+// --packages-from and --go-list-query, defaulting to ./... A "-" pattern
+// is replaced by the import paths read from stdin, one per line. With
+// --entrypoint the result is narrowed to the packages linked into those
+// binaries.
+func resolvePatterns(args []string, packagesFrom, query, entrypoint string) ([]string, error) {
+	var patterns []string
+	stdinRead := false
+	for _, arg := range args {
+		if arg != "-" {
+			patterns = append(patterns, arg)
+			continue
+		}
+		if stdinRead {
+			continue
+		}
+		stdinRead = true
+		pkgs, err := parser.ReadPackages(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("reading packages from stdin: %w", err)
+		}
+		if len(pkgs) == 0 {
+			return nil, fmt.Errorf("no packages listed on stdin")
+		}
+		patterns = append(patterns, pkgs...)
+	}
+
+	if packagesFrom != "" {
+		pkgs, err := parser.ReadPackagesFile(packagesFrom)
+		if err != nil {
+			return nil, err
+		}
+		if len(pkgs) == 0 {
+			return nil, fmt.Errorf("no packages listed in %s", packagesFrom)
+		}
+		patterns = append(patterns, pkgs...)
+	}
+
+	if query != "" {
+		pkgs, err := parser.ResolveQuery(query)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pkgs...)
+	}
+
+	if entrypoint != "" {
+		return narrowToEntrypoints(patterns, entrypoint)
+	}
+	if len(patterns) == 0 {
+		return []string{"./..."}, nil
+	}
+	return dedupe(patterns), nil
+}
+
+// narrowToEntrypoints returns the packages linked into the main packages
+// of entrypoint, a comma-separated list, that patterns also select; all
+// of them without patterns
+func narrowToEntrypoints(patterns []string, entrypoint string) ([]string, error) {
+	linked, err := parser.EntrypointPackages(strings.Split(entrypoint, ","))
+	if err != nil {
+		return nil, fmt.Errorf("--entrypoint: %w", err)
+	}
+	if len(patterns) == 0 {
+		return linked, nil
+	}
+	listed, err := parser.ResolveQuery(strings.Join(patterns, " "))
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]bool, len(listed))
+	for _, pkg := range listed {
+		selected[pkg] = true
+	}
+	var kept []string
+	for _, pkg := range linked {
+		if selected[pkg] {
+			kept = append(kept, pkg)
+		}
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("no package of %s is linked into %s", strings.Join(patterns, " "), entrypoint)
+	}
+	return kept, nil
+}
+
+func dedupe(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	result := items[:0]
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// parseDuration extends time.ParseDuration with a "d" (days) unit
+func parseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
 }
 
 func filterEscapesOnly(results *categorizer.Results) *categorizer.Results {
 	filtered := &categorizer.Results{
-		Summary:    results.Summary,
-		ByCategory: results.ByCategory,
-		Escapes:    make([]categorizer.CategorizedEscape, 0),
+		Summary:      results.Summary,
+		ByCategory:   results.ByCategory,
+		Escapes:      make([]categorizer.CategorizedEscape, 0),
+		Suppressions: results.Suppressions,
 	}
 	for _, e := range results.Escapes {
 		if e.Info.EscapeType == parser.MovedToHeap || e.Info.EscapeType == parser.EscapesToHeap {
@@ -166,20 +1305,62 @@ func filterEscapesOnly(results *categorizer.Results) *categorizer.Results {
 	return filtered
 }
 
+// filterNewSince keeps escapes first seen on or after since
+func filterNewSince(results *categorizer.Results, since time.Time) *categorizer.Results {
+	cutoff := since.Format(baseline.DateLayout)
+	filtered := &categorizer.Results{
+		Summary:      results.Summary,
+		ByCategory:   results.ByCategory,
+		Escapes:      make([]categorizer.CategorizedEscape, 0),
+		Suppressions: results.Suppressions,
+	}
+	for _, e := range results.Escapes {
+		if e.FirstSeen >= cutoff {
+			filtered.Escapes = append(filtered.Escapes, e)
+		}
+	}
+	return filtered
+}
+
 func filterByPackage(results *categorizer.Results, prefix string) *categorizer.Results {
 	filtered := &categorizer.Results{
-		Summary:    results.Summary,
-		ByCategory: results.ByCategory,
-		Escapes:    make([]categorizer.CategorizedEscape, 0),
+		Summary:      results.Summary,
+		ByCategory:   results.ByCategory,
+		Escapes:      make([]categorizer.CategorizedEscape, 0),
+		Suppressions: results.Suppressions,
+	}
+	for _, e := range results.Escapes {
+		if containsPrefix(e.Info.File, prefix) || containsPrefix(e.Info.Package, prefix) {
+			filtered.Escapes = append(filtered.Escapes, e)
+		}
+	}
+	return filtered
+}
+
+func filterByOwner(results *categorizer.Results, owner string) *categorizer.Results {
+	filtered := &categorizer.Results{
+		Summary:      results.Summary,
+		ByCategory:   results.ByCategory,
+		Escapes:      make([]categorizer.CategorizedEscape, 0),
+		Suppressions: results.Suppressions,
 	}
 	for _, e := range results.Escapes {
-		if containsPrefix(e.Info.File, prefix) {
+		if owners.Owned(e, owner) {
 			filtered.Escapes = append(filtered.Escapes, e)
 		}
 	}
 	return filtered
 }
 
+func filterWhere(results *categorizer.Results, q *query.Query) *categorizer.Results {
+	return &categorizer.Results{
+		Summary:      results.Summary,
+		ByCategory:   results.ByCategory,
+		Escapes:      q.Filter(results),
+		Suppressions: results.Suppressions,
+	}
+}
+
 func containsPrefix(path, prefix string) bool {
 	return len(path) >= len(prefix) && path[:len(prefix)] == prefix
 }
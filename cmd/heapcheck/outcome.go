@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+// outcome is the result of an analysis in brief, printed as the last line
+// on stderr whatever the format, for CI systems that only scrape logs
+type outcome struct {
+	Total int    `json:"total"`
+	Heap  int    `json:"heap"`
+	Stack int    `json:"stack"`
+	Gates string `json:"gates"` // a gate status, or "error" when the analysis failed
+}
+
+// lastOutcome is the outcome of the analysis the command ran, which main
+// prints after any error; nil for commands that do not analyze
+var lastOutcome *outcome
+
+// gatesError is the gates of an outcome when the analysis did not finish
+const gatesError = "error"
+
+// newOutcome returns the outcome of results, by the category gates
+func newOutcome(results *categorizer.Results, gateResult *categorizer.GateResult) *outcome {
+	o := &outcome{
+		Total: results.Summary.TotalVariables,
+		Heap:  results.Summary.HeapAllocated,
+		Stack: results.Summary.StackAllocated,
+		Gates: categorizer.GatePass,
+	}
+	if gateResult != nil {
+		o.Gates = gateResult.Status
+	}
+	return o
+}
+
+// finishOutcome sets lastOutcome from the analysis of cfg, which returned
+// err: any gate failing it, not only the category gates, fails the gates
+func finishOutcome(cfg *Config, err error) {
+	o := cfg.outcome
+	if o == nil {
+		o = &outcome{Gates: gatesError}
+	} else if exitCode(err) == exitGate {
+		o.Gates = categorizer.GateFail
+	}
+	lastOutcome = o
+}
+
+// print writes o as a line such as
+// "heapcheck: total=123 heap=45 stack=78 gates=pass"
+func (o *outcome) print(w io.Writer) {
+	fmt.Fprintf(w, "heapcheck: total=%d heap=%d stack=%d gates=%s\n", o.Total, o.Heap, o.Stack, o.Gates)
+}
@@ -0,0 +1,10 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package main
+
+import "io/fs"
+
+// ownedByUser reports true: file owners are not read on this platform
+func ownedByUser(fi fs.FileInfo) bool {
+	return true
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// runPlan implements --plan: it prints the packages the analysis of cfg
+// would compile, which are cached and which are excluded and why,
+// without compiling
+func runPlan(w io.Writer, cfg *Config) error {
+	switch {
+	case cfg.Input != "":
+		return fmt.Errorf("--plan shows what the compiler would do and cannot be used with --input")
+	case cfg.Format != "text" && cfg.Format != "json":
+		return fmt.Errorf("--plan prints text or JSON, not --format=%s", cfg.Format)
+	}
+
+	useBuildContext()
+	plan, err := parser.PlanCompile(context.Background(), cfg.Patterns, analysisFlags(cfg.GCFlagsExtra))
+	if err != nil {
+		return compilerError("planning the analysis", err)
+	}
+
+	// The filters applied to escapes leave whole packages out
+	kept := plan.Packages[:0]
+	for _, p := range plan.Packages {
+		switch {
+		case !cfg.IncludeVendor && parser.IsThirdParty(p.Dir):
+			plan.Excluded = append(plan.Excluded, parser.SkippedPackage{Package: p.Package, Reason: "third-party code, analyzed with --include-vendor"})
+		case cfg.FilterPkg != "" && !containsPrefix(p.Package, cfg.FilterPkg) && !containsPrefix(p.Dir, cfg.FilterPkg):
+			plan.Excluded = append(plan.Excluded, parser.SkippedPackage{Package: p.Package, Reason: fmt.Sprintf("outside --filter=%s", cfg.FilterPkg)})
+		default:
+			kept = append(kept, p)
+		}
+	}
+	plan.Packages = kept
+
+	if cfg.Format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+	writePlan(w, plan)
+	return nil
+}
+
+// writePlan writes the plan as text: the estimated work, then the
+// packages analyzed and those excluded
+func writePlan(w io.Writer, plan *parser.Plan) {
+	packages, files, lines := plan.Compiled()
+	fmt.Fprintf(w, "Plan: %d package(s) to analyze, %d cached\n", len(plan.Packages), len(plan.Packages)-packages)
+	fmt.Fprintf(w, "  Compile: %d package(s), %d file(s), %d line(s)\n", packages, files, lines)
+	fmt.Fprintf(w, "  Dependencies: %d, %d to build without escape analysis\n", plan.Dependencies, plan.StaleDependencies)
+
+	if len(plan.Packages) > 0 {
+		fmt.Fprintf(w, "\nAnalyzed:\n")
+		pw := 0
+		for _, p := range plan.Packages {
+			pw = max(pw, len(p.Package))
+		}
+		for _, p := range plan.Packages {
+			status := "cached"
+			if !p.Cached {
+				status = "compile"
+				if p.Reason != "" {
+					status += " (" + p.Reason + ")"
+				}
+			}
+			fmt.Fprintf(w, "  %-*s  %4d file(s)  %s\n", pw, p.Package, p.Files, status)
+		}
+	}
+
+	if len(plan.Excluded) > 0 {
+		fmt.Fprintf(w, "\nExcluded (%d package(s)):\n", len(plan.Excluded))
+		for _, p := range plan.Excluded {
+			fmt.Fprintf(w, "  %s: %s\n", p.Package, strings.TrimSpace(p.Reason))
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/staged"
+)
+
+// runPrecommit implements `heapcheck precommit [flags]`
+func runPrecommit(args []string) error {
+	fs := newFlagSet("precommit")
+	fail := fs.Bool("fail", false, "Exit non-zero when the staged changes have escapes, blocking the commit")
+	config := analyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `heapcheck precommit - report the escapes of staged changes
+
+Usage:
+  heapcheck precommit [flags]
+
+Analyzes only the packages with staged Go changes and reports the escapes
+in the functions those changes touch, fast enough for a git hook. Takes
+the analysis flags of heapcheck analyze; packages come from the index.
+
+Examples:
+  heapcheck precommit
+  heapcheck precommit --fail --escapes-only
+
+To run it before every commit, add it to .git/hooks/pre-commit:
+  #!/bin/sh
+  exec heapcheck precommit --fail
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if err := globals.apply(); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("precommit analyzes the packages of staged changes and takes no packages")
+	}
+
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+	switch {
+	case cfg.Input != "":
+		return fmt.Errorf("precommit runs the compiler and cannot use --input")
+	case cfg.CompareFlags:
+		return fmt.Errorf("precommit cannot use --compare-flags")
+	}
+
+	useBuildContext()
+	changes, err := staged.Diff(".")
+	if err != nil {
+		return fmt.Errorf("reading staged changes: %w", err)
+	}
+	if len(changes.Files) == 0 {
+		fmt.Fprintln(os.Stderr, "heapcheck: no staged Go changes")
+		return nil
+	}
+	if unstaged, err := staged.Unstaged("."); err == nil {
+		for _, f := range unstaged {
+			if hasFile(changes, f) {
+				fmt.Fprintf(os.Stderr, "heapcheck: %s has unstaged changes; its escapes are of the working tree\n", f)
+			}
+		}
+	}
+	changes.Widen()
+
+	cfg.Patterns = changes.Packages()
+	cfg.staged = changes
+	cfg.failStaged = *fail
+	if cfg.Plan {
+		return runPlan(os.Stdout, cfg)
+	}
+	if ok, err := delegate(os.Stdout, cfg); ok {
+		finishOutcome(cfg, err)
+		return err
+	}
+	if cfg.JSONEvents {
+		cfg.events = newEventWriter(os.Stderr)
+	}
+	err = run(os.Stdout, cfg)
+	finishOutcome(cfg, err)
+	return err
+}
+
+// hasFile reports whether the changes include file
+func hasFile(changes *staged.Changes, file string) bool {
+	for _, f := range changes.Files {
+		if f.Path == file {
+			return true
+		}
+	}
+	return false
+}
+
+// filterStaged keeps the escapes in the lines and functions the staged
+// changes touch
+func filterStaged(results *categorizer.Results, changes *staged.Changes) *categorizer.Results {
+	return &categorizer.Results{
+		Summary:      results.Summary,
+		ByCategory:   results.ByCategory,
+		Escapes:      changes.Filter(results.Escapes),
+		Suppressions: results.Suppressions,
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+)
+
+// selfProfile profiles heapcheck's own run for --profile-self
+type selfProfile struct {
+	dir string
+	cpu *os.File
+}
+
+// startSelfProfile starts a CPU profile written to dir/cpu.pprof, creating
+// dir when needed
+func startSelfProfile(dir string) (*selfProfile, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("--profile-self: %w", err)
+	}
+	f, err := os.Create(filepath.Join(dir, "cpu.pprof"))
+	if err != nil {
+		return nil, fmt.Errorf("--profile-self: %w", err)
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("--profile-self: %w", err)
+	}
+	return &selfProfile{dir: dir, cpu: f}, nil
+}
+
+// stop ends the CPU profile and writes the heap profile, with the
+// allocations of the whole run, to dir/heap.pprof
+func (p *selfProfile) stop() error {
+	pprof.StopCPUProfile()
+	if err := p.cpu.Close(); err != nil {
+		return fmt.Errorf("--profile-self: %w", err)
+	}
+	f, err := os.Create(filepath.Join(p.dir, "heap.pprof"))
+	if err != nil {
+		return fmt.Errorf("--profile-self: %w", err)
+	}
+	runtime.GC()
+	if err := pprof.Lookup("heap").WriteTo(f, 0); err != nil {
+		f.Close()
+		return fmt.Errorf("--profile-self: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("--profile-self: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "heapcheck: wrote profiles of this run to %s\n", p.dir)
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/harshakonda/heapcheck/internal/diff"
+	"github.com/harshakonda/heapcheck/internal/reporter"
+)
+
+// runRender implements `heapcheck render [--diff] [flags] files...`
+func runRender(args []string) error {
+	fs := newFlagSet("render")
+	formatFlag := fs.String("format", "text", "Output format: text, json, html (and sarif, matrix, matrix-csv without --diff)")
+	diffMode := fs.Bool("diff", false, "Compare two result files: old.json new.json")
+	lang := fs.String("lang", "", langUsage)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `heapcheck render - render saved JSON results
+
+Usage:
+  heapcheck render [flags] results.json
+  heapcheck render --diff [flags] old.json new.json
+
+Examples:
+  heapcheck --format=json ./... > new.json
+  heapcheck render --format=html new.json > report.html
+  heapcheck render --diff old.json new.json --format=html > diff.html
+
+heapcheck diff is the same as heapcheck render --diff.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	files := parseInterspersed(fs, args)
+	if err := globals.apply(); err != nil {
+		return err
+	}
+
+	if *diffMode {
+		return renderDiff(files, *formatFlag)
+	}
+
+	if len(files) != 1 {
+		return fmt.Errorf("render needs one result file, got %d", len(files))
+	}
+	results, err := diff.Load(files[0])
+	if err != nil {
+		return err
+	}
+
+	catalog, err := reporter.LoadCatalog(*lang)
+	if err != nil {
+		return fmt.Errorf("--lang: %w", err)
+	}
+	rep, err := reporter.New(os.Stdout, *formatFlag, reporter.WithCatalog(catalog))
+	if err != nil {
+		return err
+	}
+	return rep.Report(context.Background(), results, reporter.Metadata{Version: Version})
+}
+
+// runDiff implements `heapcheck diff [flags] old.json new.json`
+func runDiff(args []string) error {
+	fs := newFlagSet("diff")
+	formatFlag := fs.String("format", "text", "Output format: text, json, html")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `heapcheck diff - compare two saved JSON results
+
+Usage:
+  heapcheck diff [flags] old.json new.json
+
+Examples:
+  heapcheck diff old.json new.json
+  heapcheck diff old.json new.json --format=html > diff.html
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	files := parseInterspersed(fs, args)
+	if err := globals.apply(); err != nil {
+		return err
+	}
+	return renderDiff(files, *formatFlag)
+}
+
+// renderDiff writes the differences between two result files
+func renderDiff(files []string, format string) error {
+	if len(files) != 2 {
+		return fmt.Errorf("diff needs two result files, got %d", len(files))
+	}
+	old, err := diff.Load(files[0])
+	if err != nil {
+		return err
+	}
+	cur, err := diff.Load(files[1])
+	if err != nil {
+		return err
+	}
+
+	d := diff.Compare(old, cur)
+	d.OldFile, d.NewFile = files[0], files[1]
+
+	rep, err := reporter.NewDiffReporter(os.Stdout, format)
+	if err != nil {
+		return err
+	}
+	return rep.ReportDiff(d)
+}
+
+// parseInterspersed parses flags that may follow positional arguments,
+// as in `render --diff old.json new.json --format=html`, and returns the
+// positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		parseFlags(fs, args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
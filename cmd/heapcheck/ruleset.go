@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+// runRuleset implements `heapcheck ruleset`
+func runRuleset(args []string) error {
+	fs := newFlagSet("ruleset")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `heapcheck ruleset - print the built-in category rules
+
+Usage:
+  heapcheck ruleset > .heapcheck-rules.yaml
+
+Prints the rules that categorize escapes by the compiler's text, in the
+format of the ruleset file set in .heapcheck.yaml or with --ruleset. The
+printed rules set inherit: false, so a saved copy pins them: heapcheck
+upgrades then leave categories unchanged until the copy is updated.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if err := globals.apply(); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("ruleset takes no arguments, got %d", fs.NArg())
+	}
+	_, err := os.Stdout.Write(categorizer.BuiltinRules())
+	return err
+}
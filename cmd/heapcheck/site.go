@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/diff"
+	"github.com/harshakonda/heapcheck/internal/history"
+	"github.com/harshakonda/heapcheck/internal/reporter"
+)
+
+// runSite implements `heapcheck site [flags] [name=]results.json...`
+func runSite(args []string) error {
+	fs := newFlagSet("site")
+	out := fs.String("o", "public", "Directory to write the site to")
+	historyFile := fs.String("history", "", "History file for the trend page (default: history.file in the config)")
+	configFile := fs.String("config", "", "Config file (default: .heapcheck.yaml in the current directory)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `heapcheck site - render saved JSON results as a static site
+
+Usage:
+  heapcheck site [flags] [name=]results.json...
+
+Writes an index of the modules and their packages, a summary and the HTML
+report of each module, a page per package and, with a history file, a trend page of the
+recorded runs on the default branch. Each results file is one module,
+named by the common prefix of its packages' import paths unless given as
+name=results.json. The site has no server side, for GitHub Pages.
+
+Examples:
+  heapcheck --format=json ./... > heapcheck.json
+  heapcheck site -o ./public heapcheck.json
+  heapcheck site -o ./public api=api.json worker=worker/heapcheck.json
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	files := parseInterspersed(fs, args)
+	if err := globals.apply(); err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("site needs at least one result file")
+	}
+
+	var modules []reporter.SiteModule
+	for _, arg := range files {
+		name, file, named := strings.Cut(arg, "=")
+		if !named {
+			name, file = "", arg
+		}
+		results, err := diff.Load(file)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			name = moduleName(results, file)
+		}
+		modules = append(modules, reporter.SiteModule{Name: name, Results: results})
+	}
+
+	fileCfg, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	path := *historyFile
+	if path == "" {
+		path = fileCfg.History.File
+	}
+	var runs []history.Run
+	if path != "" {
+		h, err := history.Load(path)
+		if err != nil {
+			return err
+		}
+		branch := fileCfg.History.Branch
+		if branch == "" {
+			branch = history.DefaultBranch()
+		}
+		for _, r := range h.Runs {
+			if r.Branch == branch {
+				runs = append(runs, r)
+			}
+		}
+	}
+
+	if err := reporter.WriteSite(*out, modules, runs, reporter.Metadata{Version: Version}); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "heapcheck: wrote the site of %d module(s) to %s\n", len(modules), filepath.Join(*out, "index.html"))
+	return nil
+}
+
+// moduleName names the module of results by the longest import path
+// prefix its packages share, or by the results file without results
+// carrying import paths
+func moduleName(results *categorizer.Results, file string) string {
+	var prefix []string
+	for _, e := range results.Escapes {
+		if e.Info.Package == "" {
+			continue
+		}
+		parts := strings.Split(e.Info.Package, "/")
+		if prefix == nil {
+			prefix = parts
+			continue
+		}
+		n := 0
+		for n < len(prefix) && n < len(parts) && prefix[n] == parts[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	if len(prefix) > 0 {
+		return strings.Join(prefix, "/")
+	}
+	return strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+}
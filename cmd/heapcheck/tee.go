@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// openTee returns the writer the report goes to: stdout, and with --tee
+// also the file at path, created or truncated. The file gets the report
+// without the colors of a terminal. closeFile closes the file.
+func openTee(path string) (w io.Writer, closeFile func() error, err error) {
+	if path == "" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("--tee: %w", err)
+	}
+	file := &plainWriter{w: f}
+	closeFile = func() error {
+		if file.err != nil {
+			f.Close()
+			return fmt.Errorf("--tee: writing %s: %w", path, file.err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("--tee: %w", err)
+		}
+		return nil
+	}
+	return io.MultiWriter(os.Stdout, file), closeFile, nil
+}
+
+// plainWriter writes to w without ANSI escape sequences, such as the
+// colors of text output, which may be split across writes. Errors writing
+// to w are kept in err rather than returned, so that the report still
+// reaches stdout.
+type plainWriter struct {
+	w   io.Writer
+	err error
+
+	// escape is set after an ESC, and csi after an ESC [, until the final
+	// byte of the sequence
+	escape, csi bool
+}
+
+func (p *plainWriter) Write(b []byte) (int, error) {
+	out := make([]byte, 0, len(b))
+	for _, c := range b {
+		switch {
+		case p.csi:
+			p.csi = c < 0x40 || c > 0x7e
+		case p.escape:
+			p.escape, p.csi = false, c == '['
+		case c == 0x1b:
+			p.escape = true
+		default:
+			out = append(out, c)
+		}
+	}
+	if p.err == nil {
+		_, p.err = p.w.Write(out)
+	}
+	return len(b), nil
+}
@@ -0,0 +1,11 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package main
+
+import "os"
+
+// terminalWidth returns 0: the terminal width is not detected on this
+// platform, so $COLUMNS or the default applies
+func terminalWidth(f *os.File) int {
+	return 0
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+
+	"github.com/harshakonda/heapcheck/internal/testreport"
+)
+
+// runTestReport implements `heapcheck testreport [flags] [packages] [-- go test flags]`
+func runTestReport(args []string) error {
+	var testArgs []string
+	if i := slices.Index(args, "--"); i >= 0 {
+		args, testArgs = args[:i], args[i+1:]
+	}
+
+	fs := newFlagSet("testreport")
+	formatFlag := fs.String("format", "text", "Output format: text, json")
+	top := fs.Int("top", 20, "List this many tests (0: all)")
+	order := fs.String("sort", "heap", "Rank tests by heap, goroutines, mallocs or time")
+	jsonOutput := fs.String("json-output", "", "Also write the go test -json stream to this file")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `heapcheck testreport - rank tests by the heap and goroutines they leave behind
+
+Usage:
+  heapcheck testreport [flags] [packages] [-- go test flags]
+
+Runs go test -json on the packages, with guard recording the growth every
+guard.VerifyNone or Guard.Verify measures, and lists the tests that grew
+the heap most. Only tests verified with guard are measured;
+the others are counted. Tests that run in parallel share the heap, so
+their growth overlaps.
+
+Examples:
+  heapcheck testreport ./...
+  heapcheck testreport --sort=goroutines --top=0 ./... -- -run=Integration -count=1
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if err := globals.apply(); err != nil {
+		return err
+	}
+	if *formatFlag != "text" && *formatFlag != "json" {
+		return fmt.Errorf("unknown format %q for testreport (want text or json)", *formatFlag)
+	}
+	if _, ok := testreport.Sort[*order]; !ok {
+		return fmt.Errorf("unknown --sort %q (want heap, goroutines, mallocs or time)", *order)
+	}
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	dir, err := os.MkdirTemp("", "heapcheck-testreport-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	growthFile := filepath.Join(dir, "growth.json")
+
+	var events bytes.Buffer
+	cmd := exec.Command("go", append(append([]string{"test", "-json"}, testArgs...), patterns...)...)
+	cmd.Stdout = &events
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), testreport.Env+"="+growthFile)
+	testErr := cmd.Run()
+	if testErr != nil && events.Len() == 0 {
+		return fmt.Errorf("go test: %w", testErr)
+	}
+	if *jsonOutput != "" {
+		if err := os.WriteFile(*jsonOutput, events.Bytes(), 0o644); err != nil {
+			return err
+		}
+	}
+
+	// Without a single verification the file was never created
+	growth, err := os.ReadFile(growthFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	report, err := testreport.Build(&events, bytes.NewReader(growth), *order)
+	if err != nil {
+		return err
+	}
+
+	if *formatFlag == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = testreport.WriteText(os.Stdout, report, *top)
+	}
+	if err != nil {
+		return err
+	}
+	if testErr != nil {
+		return fmt.Errorf("go test failed: %w", testErr)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/config"
+	"github.com/harshakonda/heapcheck/internal/history"
+)
+
+// checkTrend implements --fail-on-trend and history recording: it
+// compares the run with the rolling average of recorded runs on the
+// default branch, then records the run if it is on that branch. It
+// returns the trend, or nil when no history is configured or recorded.
+func checkTrend(cfg *Config, fileCfg *config.Config, results *categorizer.Results) (*history.Trend, error) {
+	path := cfg.History
+	if path == "" {
+		path = fileCfg.History.File
+	}
+	if path == "" {
+		if cfg.FailOnTrend != "" {
+			return nil, fmt.Errorf("--fail-on-trend needs a history file (history.file in .heapcheck.yaml or --history)")
+		}
+		return nil, nil
+	}
+	if cfg.Sample > 0 {
+		// The counts of a sample would skew the averages of full runs
+		fmt.Fprintf(os.Stderr, "heapcheck: not recording the sampled run in %s\n", path)
+		return nil, nil
+	}
+
+	h, err := history.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	defaultBranch := fileCfg.History.Branch
+	if defaultBranch == "" {
+		defaultBranch = history.DefaultBranch()
+	}
+	window := fileCfg.History.Window
+	if window == 0 {
+		window = history.DefaultWindow
+	}
+
+	var trend *history.Trend
+	if cfg.FailOnTrend != "" {
+		allowed, err := history.ParseDrift(cfg.FailOnTrend)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --fail-on-trend: %w", err)
+		}
+		trend = h.Check(results.Summary.HeapAllocated, defaultBranch, window, allowed)
+		if trend == nil {
+			fmt.Fprintf(os.Stderr, "heapcheck: no recorded runs on %s in %s yet, skipping trend check\n", defaultBranch, path)
+		}
+	}
+
+	if branch := history.CurrentBranch(); branch == defaultBranch {
+		keep, compact, err := historyRetention(cfg.HistoryKeep, fileCfg)
+		if err != nil {
+			return nil, err
+		}
+		run := history.NewRun(results, branch)
+		h.Add(run, keep)
+		if compact > 0 {
+			h.Compact(run.Time.Add(-compact))
+		}
+		if err := history.Save(path, h); err != nil {
+			return nil, err
+		}
+	}
+	return trend, nil
+}
+
+// historyRetention returns the retention of recorded runs, from keep (a
+// --keep flag) or history.keep in the config, and the age beyond which
+// runs are compacted, 0 if they are not
+func historyRetention(keep string, fileCfg *config.Config) (history.Retention, time.Duration, error) {
+	var r history.Retention
+	if keep == "" {
+		keep = fileCfg.History.Keep
+	}
+	if keep != "" {
+		var err error
+		if r, err = history.ParseRetention(keep); err != nil {
+			return r, 0, fmt.Errorf("history.keep: %w", err)
+		}
+	}
+	var compact time.Duration
+	if fileCfg.History.Compact != "" {
+		var err error
+		if compact, err = history.ParseAge(fileCfg.History.Compact); err != nil {
+			return r, 0, fmt.Errorf("history.compact: %w", err)
+		}
+	}
+	return r, compact, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// versionInfo is what --version prints
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // built from a tree with uncommitted changes
+	Module    string `json:"module,omitempty"`
+	Sum       string `json:"sum,omitempty"` // go.sum hash of the module, for go install builds
+	GoVersion string `json:"goVersion"`
+}
+
+// pseudoVersionRe matches the timestamp and revision of a pseudo-version
+// such as v0.0.0-20260101120000-abcdef123456, which the go command records
+// for builds of an untagged commit
+var pseudoVersionRe = regexp.MustCompile(`\d{14}-[0-9a-f]{12}`)
+
+func init() {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		Version, Commit, Date = fromBuildInfo(info, Version, Commit, Date)
+	}
+}
+
+// fromBuildInfo returns the version, commit and date the go command
+// recorded in the binary, for builds without ldflags such as go install:
+// the module version, when it is a release, and the VCS revision and
+// commit time, when built in a checkout. Values set via ldflags, which
+// leave commit set, are returned as they are.
+func fromBuildInfo(info *debug.BuildInfo, version, commit, date string) (string, string, string) {
+	if commit != "unknown" {
+		return version, commit, date
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" && !strings.Contains(v, "+") && !pseudoVersionRe.MatchString(v) {
+		version = strings.TrimPrefix(v, "v")
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			commit = s.Value
+		case "vcs.time":
+			date = s.Value
+		}
+	}
+	return version, commit, date
+}
+
+// currentVersion returns the version information of this binary
+func currentVersion() versionInfo {
+	v := versionInfo{Version: Version, GoVersion: runtime.Version()}
+	if Commit != "unknown" {
+		v.Commit = Commit
+	}
+	if Date != "unknown" {
+		v.Date = Date
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		v.Module = info.Main.Path
+		v.Sum = info.Main.Sum
+		for _, s := range info.Settings {
+			if s.Key == "vcs.modified" {
+				v.Modified = s.Value == "true"
+			}
+		}
+	}
+	return v
+}
+
+// printVersion writes the version and, when known, the commit and build
+// date, as text or with format json as a JSON object
+func printVersion(w io.Writer, format string) error {
+	v := currentVersion()
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	fmt.Fprintf(w, "heapcheck version %s\n", v.Version)
+	if v.Commit != "" {
+		commit := v.Commit
+		if v.Modified {
+			commit += " (modified)"
+		}
+		fmt.Fprintf(w, "  commit: %s\n", commit)
+	}
+	if v.Date != "" {
+		fmt.Fprintf(w, "  built:  %s\n", v.Date)
+	}
+	fmt.Fprintf(w, "  go:     %s\n", v.GoVersion)
+	return nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/diff"
+)
+
+// runWatch implements `heapcheck watch [flags] [packages]`
+func runWatch(args []string) error {
+	fs := newFlagSet("watch")
+	interval := fs.Duration("interval", time.Second, "How often to check the module's files for changes")
+	config := analyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `heapcheck watch - re-analyze on every save
+
+Usage:
+  heapcheck watch [flags] [packages]
+
+Prints the report once, then analyzes the packages again whenever a Go
+file, go.mod or go.sum under the current directory changes, and prints
+only what changed: per package, the new, fixed and unchanged escape
+counts, with the new escapes detailed. Takes the analysis flags of
+heapcheck analyze, except --format.
+
+Examples:
+  heapcheck watch ./...
+  heapcheck watch --escapes-only ./pkg/server
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if err := globals.apply(); err != nil {
+		return err
+	}
+
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+	switch {
+	case cfg.Format != "text":
+		return fmt.Errorf("watch prints text deltas and cannot use --format=%s", cfg.Format)
+	case cfg.CompareFlags:
+		return fmt.Errorf("watch cannot use --compare-flags")
+	case cfg.Input != "":
+		return fmt.Errorf("watch runs the compiler and cannot use --input")
+	case *interval <= 0:
+		return fmt.Errorf("--interval must be positive")
+	}
+	cfg.watch = &watcher{}
+
+	stamps := sourceStamps(".")
+	if err := run(os.Stdout, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "heapcheck: %v\n", err)
+	}
+	cfg.watch.last = cfg.watch.cur
+	fmt.Fprintln(os.Stderr, "heapcheck: watching for changes, Ctrl-C to stop")
+	for {
+		time.Sleep(*interval)
+		cur := sourceStamps(".")
+		changed := changedFiles(stamps, cur)
+		if len(changed) == 0 {
+			continue
+		}
+		stamps = cur
+
+		fmt.Fprintf(os.Stdout, "\n[%s] %s changed\n", time.Now().Format("15:04:05"), strings.Join(changed, ", "))
+		if err := run(io.Discard, cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "heapcheck: %v\n", err)
+		}
+		cfg.watch.report(os.Stdout)
+	}
+}
+
+// watcher keeps the escapes of the last two analyses of watch, as the
+// report saw them, to print what changed between them
+type watcher struct {
+	last, cur *categorizer.Results
+}
+
+// record keeps the escapes of an analysis as the current ones
+func (w *watcher) record(results *categorizer.Results) {
+	w.cur = &categorizer.Results{Escapes: append([]categorizer.CategorizedEscape(nil), results.Escapes...)}
+}
+
+// report prints the changes since the last reported analysis, package by
+// package, and makes the current one the last. An analysis that failed
+// recorded nothing, and the last one is kept for the next.
+func (w *watcher) report(out io.Writer) {
+	if w.cur == nil || w.cur == w.last {
+		return
+	}
+	last := w.last
+	if last == nil {
+		last = &categorizer.Results{}
+	}
+	w.last = w.cur
+
+	unchanged, packages := 0, 0
+	diffs := diff.ByPackage(last, w.cur)
+	for _, d := range diffs {
+		if len(d.Added) == 0 && len(d.Resolved) == 0 {
+			unchanged += d.Unchanged
+			packages++
+			continue
+		}
+		fmt.Fprintf(out, "  %s: %d new, %d fixed, %d unchanged\n", d.Package, len(d.Added), len(d.Resolved), d.Unchanged)
+		for _, e := range d.Added {
+			fmt.Fprintf(out, "    + %s:%d  %s [%s]\n", e.Info.File, e.Info.Line, e.Info.Variable, e.Category)
+			if e.Suggestion.Short != "" {
+				fmt.Fprintf(out, "        💡 %s\n", e.Suggestion.Short)
+			}
+		}
+		for _, e := range d.Resolved {
+			fmt.Fprintf(out, "    - %s:%d  %s [%s]\n", e.Info.File, e.Info.Line, e.Info.Variable, e.Category)
+		}
+	}
+	switch {
+	case packages == len(diffs):
+		fmt.Fprintf(out, "  no escapes changed, %d unchanged in %d package(s)\n", unchanged, packages)
+	case packages > 0:
+		fmt.Fprintf(out, "  %d escape(s) unchanged in %d other package(s)\n", unchanged, packages)
+	}
+}
+
+// fileStamp identifies a version of a file without reading it
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// sourceStamps returns the stamps of the Go files, go.mod and go.sum
+// under dir, skipping vendor, testdata and hidden directories as the go
+// command does. Files that cannot be read are left out.
+func sourceStamps(dir string) map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") && name != "go.mod" && name != "go.sum" {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			stamps[path] = fileStamp{size: info.Size(), modTime: info.ModTime()}
+		}
+		return nil
+	})
+	return stamps
+}
+
+// changedFiles returns the files added, changed or removed between two
+// sets of stamps, in order
+func changedFiles(old, cur map[string]fileStamp) []string {
+	var changed []string
+	for path, s := range cur {
+		if o, ok := old[path]; !ok || o.size != s.size || !o.modTime.Equal(s.modTime) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range old {
+		if _, ok := cur[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
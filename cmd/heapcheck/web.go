@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// runWeb implements `heapcheck web [flags] [packages]`
+func runWeb(args []string) error {
+	fs := newFlagSet("web")
+	addr := fs.String("addr", "localhost:8080", "Address to serve the report on")
+	config := analyzeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `heapcheck web - serve the HTML report
+
+Usage:
+  heapcheck web [flags] [packages]
+
+Analyzes the packages again on every reload, so the report follows your
+edits. Takes the analysis flags of heapcheck analyze, except --format.
+
+Each escape can be accepted from the report with a reason, which writes a
+//heapcheck:ignore comment above its line or, with a local --baseline
+file, an entry in the baseline. Escapes are accepted from this machine
+only, at the --addr given or localhost.
+
+Examples:
+  heapcheck web ./...
+  heapcheck web --addr=localhost:9000 --escapes-only ./pkg/...
+  heapcheck web --baseline=heapcheck-baseline.json ./...
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if err := globals.apply(); err != nil {
+		return err
+	}
+
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+	switch {
+	case cfg.Format != "text" && cfg.Format != "html":
+		return fmt.Errorf("web serves the HTML report and cannot use --format=%s", cfg.Format)
+	case cfg.CompareFlags:
+		return fmt.Errorf("web serves the HTML report and cannot use --compare-flags")
+	case cfg.Input == "-":
+		return fmt.Errorf("web reads --input on every reload and cannot read it from stdin")
+	}
+	cfg.Format = "html"
+	if cfg.JSONEvents {
+		cfg.events = newEventWriter(os.Stderr)
+	}
+	if cfg.accept, err = newAcceptor(cfg); err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "heapcheck: serving the report at http://%s/\n", ln.Addr())
+
+	// One analysis or acceptance at a time: analyses share the build
+	// cache and files such as --write-baseline, which accepting escapes
+	// writes too
+	var mu sync.Mutex
+	mux := http.NewServeMux()
+	mux.Handle("/", reportHandler(cfg, &mu))
+	mux.Handle("/accept", acceptHandler(cfg.accept, &mu, *addr))
+	return http.Serve(ln, mux)
+}
+
+// reportHandler serves the report of a fresh analysis at /. A failed gate
+// still serves the report; errors without one are served as such.
+func reportHandler(cfg *Config, mu *sync.Mutex) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		var buf bytes.Buffer
+		if cfg.events != nil {
+			cfg.events.start(cfg.Patterns)
+		}
+		err := run(&buf, cfg)
+		if cfg.events != nil {
+			cfg.events.done(err)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "heapcheck: %v\n", err)
+			if buf.Len() == 0 {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+}
@@ -0,0 +1,126 @@
+// Package database demonstrates escape and goroutine leak patterns in
+// database access code built on database/sql.
+//
+// To run without a database server, it queries a minimal in-memory driver
+// over a slice of users. database/sql pools and tracks its connections
+// exactly as it does for a real driver, so the leaks are the same.
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+)
+
+// User is a row of the users table
+type User struct {
+	ID     int64
+	Name   string
+	Email  string
+	Active bool
+}
+
+// Queries the in-memory driver understands
+const (
+	queryUsers    = "SELECT id, name, email, active FROM users"
+	queryUserByID = queryUsers + " WHERE id = ?"
+)
+
+// OpenMemDB returns a database whose users table holds users
+func OpenMemDB(users []User) *sql.DB {
+	return sql.OpenDB(memConnector{users: users})
+}
+
+type memConnector struct {
+	users []User
+}
+
+func (c memConnector) Connect(context.Context) (driver.Conn, error) {
+	return &memConn{users: c.users}, nil
+}
+
+func (c memConnector) Driver() driver.Driver {
+	return memDriver{}
+}
+
+type memDriver struct{}
+
+func (memDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("memdb: use OpenMemDB")
+}
+
+type memConn struct {
+	users []User
+}
+
+func (c *memConn) Prepare(query string) (driver.Stmt, error) {
+	if query != queryUsers && query != queryUserByID {
+		return nil, errors.New("memdb: unsupported query: " + query)
+	}
+	return &memStmt{conn: c, query: query}, nil
+}
+
+func (c *memConn) Close() error {
+	return nil
+}
+
+func (c *memConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("memdb: transactions are not supported")
+}
+
+type memStmt struct {
+	conn  *memConn
+	query string
+}
+
+func (s *memStmt) Close() error {
+	return nil
+}
+
+func (s *memStmt) NumInput() int {
+	return strings.Count(s.query, "?")
+}
+
+func (s *memStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("memdb: the users table is read-only")
+}
+
+func (s *memStmt) Query(args []driver.Value) (driver.Rows, error) {
+	users := s.conn.users
+	if len(args) == 1 {
+		id, _ := args[0].(int64)
+		users = nil
+		for _, u := range s.conn.users {
+			if u.ID == id {
+				users = append(users, u)
+			}
+		}
+	}
+	return &memRows{users: users}, nil
+}
+
+type memRows struct {
+	users []User
+	next  int
+}
+
+func (r *memRows) Columns() []string {
+	return []string{"id", "name", "email", "active"}
+}
+
+func (r *memRows) Close() error {
+	return nil
+}
+
+func (r *memRows) Next(dest []driver.Value) error {
+	if r.next == len(r.users) {
+		return io.EOF
+	}
+	u := r.users[r.next]
+	r.next++
+	dest[0], dest[1], dest[2], dest[3] = u.ID, u.Name, u.Email, u.Active
+	return nil
+}
@@ -0,0 +1,231 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Store reads users from a database
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore returns a store reading from db
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// =============================================================================
+// Pattern: Scan Destinations
+// =============================================================================
+
+// ListUsersBad allocates a *User for every row, and grows the result
+// from empty. Scan takes its destinations as ...any, so whatever they
+// point into escapes.
+func (s *Store) ListUsersBad(ctx context.Context) ([]*User, error) {
+	rows, err := s.db.QueryContext(ctx, queryUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		u := new(User) // ESCAPES - one per row
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Active); err != nil {
+			return nil, err
+		}
+		users = append(users, u) // regrows the slice as rows come in
+	}
+	return users, rows.Err()
+}
+
+// ListUsersGood scans straight into the elements of one slice of values,
+// sized up front when the caller knows how many rows to expect
+func (s *Store) ListUsersGood(ctx context.Context, sizeHint int) ([]User, error) {
+	rows, err := s.db.QueryContext(ctx, queryUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]User, 0, sizeHint)
+	for rows.Next() {
+		users = append(users, User{})
+		u := &users[len(users)-1]
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Active); err != nil {
+			return nil, err
+		}
+	}
+	return users, rows.Err()
+}
+
+// =============================================================================
+// Pattern: Rows Iteration Closures
+// =============================================================================
+
+// UsersBad returns closures that step through the users and close the
+// rows. The closures and everything they capture escape on every call.
+func (s *Store) UsersBad(ctx context.Context) (next func() (User, bool), done func() error, err error) {
+	rows, err := s.db.QueryContext(ctx, queryUsers)
+	if err != nil {
+		return nil, nil, err
+	}
+	var scanErr error            // ESCAPES - captured by both closures
+	next = func() (User, bool) { // ESCAPES - returned
+		var u User // ESCAPES - scanned into, on every row
+		if scanErr != nil || !rows.Next() {
+			return User{}, false
+		}
+		scanErr = rows.Scan(&u.ID, &u.Name, &u.Email, &u.Active)
+		return u, scanErr == nil
+	}
+	done = func() error { // ESCAPES - returned
+		rows.Close()
+		if scanErr != nil {
+			return scanErr
+		}
+		return rows.Err()
+	}
+	return next, done, nil
+}
+
+// CountActiveBad counts through the closures of UsersBad
+func (s *Store) CountActiveBad(ctx context.Context) (int, error) {
+	next, done, err := s.UsersBad(ctx)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for u, ok := next(); ok; u, ok = next() {
+		if u.Active {
+			count++
+		}
+	}
+	return count, done()
+}
+
+// UserRows steps through users like sql.Rows. Scanning into its user
+// moves it to the heap once per query, where UsersBad allocates on every
+// row.
+type UserRows struct {
+	rows *sql.Rows
+	user User
+	err  error
+}
+
+// Users queries the users for iterating with Next and User. Close the
+// result when done.
+func (s *Store) Users(ctx context.Context) (UserRows, error) {
+	rows, err := s.db.QueryContext(ctx, queryUsers)
+	return UserRows{rows: rows}, err
+}
+
+// Next scans the next user, reporting false at the end or on an error
+func (r *UserRows) Next() bool {
+	if r.err != nil || !r.rows.Next() {
+		return false
+	}
+	u := &r.user
+	r.err = r.rows.Scan(&u.ID, &u.Name, &u.Email, &u.Active)
+	return r.err == nil
+}
+
+// User returns the user Next scanned
+func (r *UserRows) User() User {
+	return r.user
+}
+
+// Close closes the rows, returning the first error of the iteration
+func (r *UserRows) Close() error {
+	r.rows.Close()
+	if r.err != nil {
+		return r.err
+	}
+	return r.rows.Err()
+}
+
+// CountActiveGood counts with a UserRows
+func (s *Store) CountActiveGood(ctx context.Context) (int, error) {
+	users, err := s.Users(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer users.Close()
+
+	count := 0
+	for users.Next() {
+		if users.User().Active {
+			count++
+		}
+	}
+	return count, users.Close()
+}
+
+// =============================================================================
+// Pattern: Rows Left Open
+// =============================================================================
+
+// FirstActiveLeaky returns on the first match without closing rows. The
+// connection stays checked out of the pool, and for a cancelable context
+// database/sql keeps a goroutine waiting to close the rows when the
+// context ends, which may be never.
+func (s *Store) FirstActiveLeaky(ctx context.Context) (User, error) {
+	rows, err := s.db.QueryContext(ctx, queryUsers)
+	if err != nil {
+		return User{}, err
+	}
+	var u User
+	for rows.Next() {
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Active); err != nil {
+			return User{}, err // LEAKS - rows are never closed
+		}
+		if u.Active {
+			return u, nil // LEAKS - rows are never closed
+		}
+	}
+	return User{}, rows.Err() // Next closed the rows when it ran out
+}
+
+// FirstActive closes rows on every path
+func (s *Store) FirstActive(ctx context.Context) (User, error) {
+	rows, err := s.db.QueryContext(ctx, queryUsers)
+	if err != nil {
+		return User{}, err
+	}
+	defer rows.Close()
+
+	var u User
+	for rows.Next() {
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Active); err != nil {
+			return User{}, err
+		}
+		if u.Active {
+			return u, nil
+		}
+	}
+	return User{}, rows.Err()
+}
+
+// =============================================================================
+// Pattern: Single-Row Lookups
+// =============================================================================
+
+// UserByIDBad wraps the not-found error with fmt, boxing the ID
+func (s *Store) UserByIDBad(ctx context.Context, id int64) (User, error) {
+	var u User
+	err := s.db.QueryRowContext(ctx, queryUserByID, id).Scan(&u.ID, &u.Name, &u.Email, &u.Active)
+	if err == sql.ErrNoRows {
+		return User{}, fmt.Errorf("user %d: %w", id, err) // boxes id, allocates per miss
+	}
+	return u, err
+}
+
+// UserByID returns sql.ErrNoRows as is for the caller to check; QueryRow
+// closes its rows in Scan
+func (s *Store) UserByID(ctx context.Context, id int64) (User, error) {
+	var u User
+	err := s.db.QueryRowContext(ctx, queryUserByID, id).Scan(&u.ID, &u.Name, &u.Email, &u.Active)
+	return u, err
+}
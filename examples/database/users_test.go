@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harshakonda/heapcheck/guard"
+)
+
+var testUsers = []User{
+	{ID: 1, Name: "ada", Email: "ada@example.com"},
+	{ID: 2, Name: "grace", Email: "grace@example.com", Active: true},
+	{ID: 3, Name: "linus", Email: "linus@example.com", Active: true},
+}
+
+// openStore returns a store over testUsers whose database is closed when
+// the test ends, before the guard registered ahead of it checks for leaks
+func openStore(t *testing.T) *Store {
+	db := OpenMemDB(testUsers)
+	t.Cleanup(func() { db.Close() })
+	return NewStore(db)
+}
+
+// leakRecorder collects the failures of a guard check instead of failing
+// the test, for checking that the leaky patterns are caught
+type leakRecorder struct {
+	errors   []string
+	cleanups []func()
+}
+
+func (r *leakRecorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *leakRecorder) Logf(format string, args ...interface{}) {}
+func (r *leakRecorder) Helper()                                 {}
+func (r *leakRecorder) Cleanup(f func())                        { r.cleanups = append(r.cleanups, f) }
+
+// verify runs the guard check and returns its failures
+func (r *leakRecorder) verify() string {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+	return strings.Join(r.errors, "\n")
+}
+
+func TestListUsers(t *testing.T) {
+	guard.VerifyNone(t)
+	store := openStore(t)
+	ctx := context.Background()
+
+	bad, err := store.ListUsersBad(ctx)
+	if err != nil || len(bad) != 3 || bad[2].Name != "linus" {
+		t.Errorf("ListUsersBad() = %v, %v", bad, err)
+	}
+	good, err := store.ListUsersGood(ctx, len(testUsers))
+	if err != nil || len(good) != 3 || good[2] != testUsers[2] {
+		t.Errorf("ListUsersGood() = %v, %v", good, err)
+	}
+}
+
+func TestCountActive(t *testing.T) {
+	guard.VerifyNone(t)
+	store := openStore(t)
+
+	for _, count := range []func(context.Context) (int, error){store.CountActiveBad, store.CountActiveGood} {
+		ctx, cancel := context.WithCancel(context.Background())
+		n, err := count(ctx)
+		cancel()
+		if n != 2 || err != nil {
+			t.Errorf("CountActive() = %d, %v; want 2", n, err)
+		}
+	}
+}
+
+func TestFirstActive(t *testing.T) {
+	guard.VerifyNone(t)
+	store := openStore(t)
+
+	// A cancelable context, as in a request handler, and rows closed on
+	// return: nothing is left behind
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	u, err := store.FirstActive(ctx)
+	if err != nil || u.Name != "grace" {
+		t.Errorf("FirstActive() = %v, %v; want grace", u, err)
+	}
+}
+
+func TestFirstActiveLeaky(t *testing.T) {
+	rec := &leakRecorder{}
+	guard.VerifyNone(rec, guard.SettleTime(20*time.Millisecond), guard.RetryCount(2))
+	db := OpenMemDB(testUsers)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if u, err := NewStore(db).FirstActiveLeaky(ctx); err != nil || u.Name != "grace" {
+		t.Fatalf("FirstActiveLeaky() = %v, %v; want grace", u, err)
+	}
+	if inUse := db.Stats().InUse; inUse != 1 {
+		t.Errorf("connections in use after FirstActiveLeaky = %d, want the 1 held by its rows", inUse)
+	}
+
+	// The rows wait for the context to end, with the connection
+	if failures := rec.verify(); !strings.Contains(failures, "database/sql.(*Rows).awaitDone") {
+		t.Errorf("guard did not report the rows left open:\n%s", failures)
+	}
+}
+
+func TestDBLeftOpen(t *testing.T) {
+	rec := &leakRecorder{}
+	guard.VerifyNone(rec, guard.SettleTime(20*time.Millisecond), guard.RetryCount(2))
+	db := OpenMemDB(testUsers)
+	defer db.Close() // only after the check, which finds the pool's opener goroutine
+
+	if _, err := NewStore(db).UserByID(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if failures := rec.verify(); !strings.Contains(failures, "database/sql.(*DB).connectionOpener") {
+		t.Errorf("guard did not report the database left open:\n%s", failures)
+	}
+}
+
+func TestUserByID(t *testing.T) {
+	guard.VerifyNone(t)
+	store := openStore(t)
+	ctx := context.Background()
+
+	for _, get := range []func(context.Context, int64) (User, error){store.UserByIDBad, store.UserByID} {
+		if u, err := get(ctx, 3); err != nil || u != testUsers[2] {
+			t.Errorf("UserByID(3) = %v, %v", u, err)
+		}
+		if _, err := get(ctx, 9); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("UserByID(9) error = %v, want sql.ErrNoRows", err)
+		}
+	}
+}
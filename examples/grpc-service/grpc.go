@@ -0,0 +1,83 @@
+// Package grpcservice demonstrates escape analysis in gRPC unary and
+// streaming handlers, interceptors and metadata handling.
+//
+// To build without the google.golang.org/grpc dependency, this file
+// declares the few grpc-go types the handlers use, with the same shapes:
+// the patterns and their escapes are the same against the real packages.
+package grpcservice
+
+import (
+	"context"
+	"strings"
+)
+
+// MD mirrors metadata.MD: header keys, lowercased, to their values
+type MD map[string][]string
+
+// Get returns the values of key, as metadata.MD.Get does
+func (md MD) Get(key string) []string {
+	return md[strings.ToLower(key)]
+}
+
+type mdKey struct{}
+
+// NewIncomingContext attaches md to ctx, as metadata.NewIncomingContext
+func NewIncomingContext(ctx context.Context, md MD) context.Context {
+	return context.WithValue(ctx, mdKey{}, md)
+}
+
+// FromIncomingContext returns the metadata of ctx, as
+// metadata.FromIncomingContext
+func FromIncomingContext(ctx context.Context) (MD, bool) {
+	md, ok := ctx.Value(mdKey{}).(MD)
+	return md, ok
+}
+
+// UnaryServerInfo mirrors grpc.UnaryServerInfo
+type UnaryServerInfo struct {
+	FullMethod string
+}
+
+// UnaryHandler mirrors grpc.UnaryHandler
+type UnaryHandler func(ctx context.Context, req any) (any, error)
+
+// UnaryServerInterceptor mirrors grpc.UnaryServerInterceptor
+type UnaryServerInterceptor func(ctx context.Context, req any, info *UnaryServerInfo, handler UnaryHandler) (any, error)
+
+// ServerStream mirrors grpc.ServerStream. SendMsg serializes the message
+// before it returns, so the caller may reuse it for the next one.
+type ServerStream interface {
+	Context() context.Context
+	SendMsg(m any) error
+	RecvMsg(m any) error
+}
+
+// GetUserRequest, User and Point stand in for generated proto messages
+type GetUserRequest struct {
+	Id int64
+}
+
+// User is a proto message
+type User struct {
+	Id    int64
+	Name  string
+	Email string
+	Tags  []string
+}
+
+// Reset clears the message for reuse, keeping the capacity of Tags, like
+// the Reset of generated messages
+func (u *User) Reset() {
+	*u = User{Tags: u.Tags[:0]}
+}
+
+// Point is a proto message of a client stream
+type Point struct {
+	Latitude  int32
+	Longitude int32
+}
+
+// Summary is the response to a client stream
+type Summary struct {
+	PointCount int32
+}
@@ -0,0 +1,277 @@
+package grpcservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// UserStore looks users up by ID
+type UserStore struct {
+	users []User
+}
+
+// NewUserStore returns a store of n generated users
+func NewUserStore(n int) *UserStore {
+	s := &UserStore{users: make([]User, n)}
+	for i := range s.users {
+		id := int64(i + 1)
+		s.users[i] = User{Id: id, Name: "user" + strconv.FormatInt(id, 10), Tags: []string{"active"}}
+	}
+	return s
+}
+
+// =============================================================================
+// Pattern: Unary Handler Responses
+// =============================================================================
+
+// GetUserBad copies the user into a new response and formats its email
+// with fmt, boxing the ID
+func (s *UserStore) GetUserBad(ctx context.Context, req *GetUserRequest) (*User, error) {
+	for _, u := range s.users {
+		if u.Id == req.Id {
+			resp := &User{ // ESCAPES - returned to the transport
+				Id:    u.Id,
+				Name:  u.Name,
+				Email: fmt.Sprintf("%s+%d@example.com", u.Name, u.Id), // boxes Name and Id
+			}
+			resp.Tags = append(resp.Tags, u.Tags...) // new backing array per call
+			return resp, nil
+		}
+	}
+	return nil, fmt.Errorf("user %d not found", req.Id)
+}
+
+// GetUserGood still allocates the response, which outlives the call, but
+// builds the email without boxing and shares the read-only tags
+func (s *UserStore) GetUserGood(ctx context.Context, req *GetUserRequest) (*User, error) {
+	for i := range s.users {
+		u := &s.users[i]
+		if u.Id == req.Id {
+			return &User{
+				Id:    u.Id,
+				Name:  u.Name,
+				Email: u.Name + "+" + strconv.FormatInt(u.Id, 10) + "@example.com",
+				Tags:  u.Tags, // serialized before the handler's next call, never mutated
+			}, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+// errNotFound is allocated once, not per failed lookup
+var errNotFound = errors.New("user not found")
+
+// =============================================================================
+// Pattern: Proto Message Reuse in Streams
+// =============================================================================
+
+// ListUsersBad allocates a message for every user sent
+func (s *UserStore) ListUsersBad(stream ServerStream) error {
+	for _, u := range s.users {
+		msg := &User{Id: u.Id, Name: u.Name} // ESCAPES - one per message
+		msg.Tags = append(msg.Tags, u.Tags...)
+		if err := stream.SendMsg(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListUsersGood reuses one message: SendMsg has serialized it when it
+// returns, and Reset keeps the Tags capacity for the next user
+func (s *UserStore) ListUsersGood(stream ServerStream) error {
+	msg := new(User) // one allocation per stream
+	for _, u := range s.users {
+		msg.Reset()
+		msg.Id, msg.Name = u.Id, u.Name
+		msg.Tags = append(msg.Tags, u.Tags...)
+		if err := stream.SendMsg(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordRouteBad receives every point into a new message
+func RecordRouteBad(stream ServerStream) (*Summary, error) {
+	summary := &Summary{}
+	for {
+		p := new(Point) // ESCAPES - passed to RecvMsg as interface
+		if err := stream.RecvMsg(p); err != nil {
+			break
+		}
+		summary.PointCount++
+	}
+	return summary, nil
+}
+
+// RecordRouteGood receives into one message, copying out what it keeps
+func RecordRouteGood(stream ServerStream) (*Summary, error) {
+	summary := &Summary{}
+	var p Point // escapes once, not per message
+	for {
+		if err := stream.RecvMsg(&p); err != nil {
+			break
+		}
+		summary.PointCount++
+	}
+	return summary, nil
+}
+
+// =============================================================================
+// Pattern: Interceptor Closures
+// =============================================================================
+
+// LoggingInterceptorBad captures the logger in a closure and formats
+// every call with fmt, boxing the method, duration and error
+func LoggingInterceptorBad(logger *log.Logger) UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *UnaryServerInfo, handler UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logger.Printf("%s took %v, err=%v", info.FullMethod, time.Since(start), err) // boxes all three
+		return resp, err
+	}
+}
+
+// loggingInterceptor holds the logger in a struct, and its method value
+// is created once when the server is built
+type loggingInterceptor struct {
+	logger *log.Logger
+}
+
+// NewLoggingInterceptor returns an interceptor that appends the log line
+// to a pooled buffer with strconv instead of formatting it with fmt
+func NewLoggingInterceptor(logger *log.Logger) UnaryServerInterceptor {
+	l := &loggingInterceptor{logger: logger}
+	return l.intercept
+}
+
+// linePool holds the buffers of log lines across calls
+var linePool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, 128)
+		return &buf
+	},
+}
+
+func (l *loggingInterceptor) intercept(ctx context.Context, req any, info *UnaryServerInfo, handler UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	bufp := linePool.Get().(*[]byte)
+	buf := append((*bufp)[:0], info.FullMethod...)
+	buf = append(buf, " took "...)
+	buf = strconv.AppendInt(buf, int64(time.Since(start)/time.Microsecond), 10)
+	buf = append(buf, "µs"...)
+	if err != nil {
+		buf = append(buf, ", err="...)
+		buf = append(buf, err.Error()...)
+	}
+	l.logger.Writer().Write(append(buf, '\n'))
+	*bufp = buf
+	linePool.Put(bufp)
+	return resp, err
+}
+
+// ChainBad wraps the handler in a new closure for every interceptor on
+// every call
+func ChainBad(interceptors ...UnaryServerInterceptor) UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *UnaryServerInfo, handler UnaryHandler) (any, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			ic, h := interceptors[i], next
+			next = func(ctx context.Context, req any) (any, error) { // ESCAPES - per call and interceptor
+				return ic(ctx, req, info, h)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+// chain calls its interceptors in order, tracking the position in a
+// struct rather than in closures
+type chain struct {
+	interceptors []UnaryServerInterceptor
+	info         *UnaryServerInfo
+	handler      UnaryHandler
+	pos          int
+}
+
+func (c *chain) next(ctx context.Context, req any) (any, error) {
+	if c.pos == len(c.interceptors) {
+		return c.handler(ctx, req)
+	}
+	ic := c.interceptors[c.pos]
+	c.pos++
+	return ic(ctx, req, c.info, c.next)
+}
+
+// ChainGood allocates one chain per call instead of one closure per
+// interceptor; the method value c.next escapes along with it
+func ChainGood(interceptors ...UnaryServerInterceptor) UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *UnaryServerInfo, handler UnaryHandler) (any, error) {
+		c := &chain{interceptors: interceptors, info: info, handler: handler}
+		return c.next(ctx, req)
+	}
+}
+
+// =============================================================================
+// Pattern: Metadata Maps
+// =============================================================================
+
+// WithTenantBad copies the incoming metadata to add a key that
+// downstream handlers read from the context
+func WithTenantBad(ctx context.Context, tenant string) context.Context {
+	md, _ := FromIncomingContext(ctx)
+	out := make(MD, len(md)+1) // ESCAPES - a map per call, held by the context
+	for k, v := range md {
+		out[k] = v
+	}
+	out["x-tenant"] = []string{tenant} // and a slice
+	return NewIncomingContext(ctx, out)
+}
+
+type tenantKey struct{}
+
+// WithTenantGood attaches only the value downstream handlers need, and
+// leaves the metadata shared
+func WithTenantGood(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant) // boxes one string
+}
+
+// Tenant returns the tenant WithTenantBad or WithTenantGood attached
+func Tenant(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+		return tenant
+	}
+	md, _ := FromIncomingContext(ctx)
+	if v := md.Get("x-tenant"); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// ResponseHeaderBad builds the same header map on every call
+func ResponseHeaderBad(version int) MD {
+	return MD{ // ESCAPES - map and slices per call
+		"x-server-version": {fmt.Sprint(version)},
+		"cache-control":    {"no-store"},
+	}
+}
+
+// responseHeader is built once and shared: gRPC only reads the headers
+// passed to SetHeader
+var responseHeader = MD{
+	"x-server-version": {"3"},
+	"cache-control":    {"no-store"},
+}
+
+// ResponseHeaderGood returns the shared header
+func ResponseHeaderGood() MD {
+	return responseHeader
+}
@@ -0,0 +1,156 @@
+package grpcservice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/guard"
+)
+
+// sendStream records the users sent on a server stream
+type sendStream struct {
+	ctx  context.Context
+	sent []int64
+}
+
+func (s *sendStream) Context() context.Context { return s.ctx }
+func (s *sendStream) RecvMsg(m any) error      { return io.EOF }
+
+func (s *sendStream) SendMsg(m any) error {
+	s.sent = append(s.sent, m.(*User).Id)
+	return nil
+}
+
+// recvStream delivers points from a goroutine, as the transport does
+type recvStream struct {
+	ctx    context.Context
+	points chan Point
+}
+
+func newRecvStream(ctx context.Context, n int) *recvStream {
+	s := &recvStream{ctx: ctx, points: make(chan Point)}
+	go func() {
+		defer close(s.points)
+		for i := 0; i < n; i++ {
+			select {
+			case s.points <- Point{Latitude: int32(i), Longitude: int32(-i)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return s
+}
+
+func (s *recvStream) Context() context.Context { return s.ctx }
+func (s *recvStream) SendMsg(m any) error      { return errors.New("client stream") }
+
+func (s *recvStream) RecvMsg(m any) error {
+	p, ok := <-s.points
+	if !ok {
+		return io.EOF
+	}
+	*m.(*Point) = p
+	return nil
+}
+
+func TestGetUser(t *testing.T) {
+	defer guard.VerifyNone(t)
+
+	store := NewUserStore(3)
+	for _, get := range []func(context.Context, *GetUserRequest) (*User, error){store.GetUserBad, store.GetUserGood} {
+		u, err := get(context.Background(), &GetUserRequest{Id: 2})
+		if err != nil || u.Email != "user2+2@example.com" {
+			t.Errorf("GetUser(2) = %+v, %v", u, err)
+		}
+		if _, err := get(context.Background(), &GetUserRequest{Id: 9}); err == nil {
+			t.Error("GetUser(9) found a user")
+		}
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	defer guard.VerifyNone(t)
+
+	store := NewUserStore(5)
+	for _, list := range []func(ServerStream) error{store.ListUsersBad, store.ListUsersGood} {
+		stream := &sendStream{ctx: context.Background()}
+		if err := list(stream); err != nil || len(stream.sent) != 5 || stream.sent[4] != 5 {
+			t.Errorf("ListUsers sent %v, %v", stream.sent, err)
+		}
+	}
+}
+
+func TestListUsersReusesMessage(t *testing.T) {
+	store := NewUserStore(100)
+	stream := &sendStream{ctx: context.Background(), sent: make([]int64, 0, 100)}
+	allocs := func(list func(ServerStream) error) float64 {
+		return testing.AllocsPerRun(10, func() {
+			stream.sent = stream.sent[:0]
+			list(stream)
+		})
+	}
+	bad, good := allocs(store.ListUsersBad), allocs(store.ListUsersGood)
+	if good >= bad || good > 2 {
+		t.Errorf("ListUsersGood allocates %.0f times per stream, ListUsersBad %.0f; want one or two", good, bad)
+	}
+}
+
+func TestRecordRoute(t *testing.T) {
+	defer guard.VerifyNone(t)
+
+	for _, record := range []func(ServerStream) (*Summary, error){RecordRouteBad, RecordRouteGood} {
+		ctx, cancel := context.WithCancel(context.Background())
+		summary, err := record(newRecvStream(ctx, 10))
+		cancel()
+		if err != nil || summary.PointCount != 10 {
+			t.Errorf("RecordRoute = %+v, %v; want 10 points", summary, err)
+		}
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	defer guard.VerifyNone(t)
+
+	var out bytes.Buffer
+	logger := log.New(&out, "", 0)
+	handler := func(ctx context.Context, req any) (any, error) {
+		return Tenant(ctx), nil
+	}
+	info := &UnaryServerInfo{FullMethod: "/users.Users/GetUser"}
+
+	for _, chain := range []func(...UnaryServerInterceptor) UnaryServerInterceptor{ChainBad, ChainGood} {
+		out.Reset()
+		intercept := chain(LoggingInterceptorBad(logger), NewLoggingInterceptor(logger))
+		ctx := WithTenantGood(context.Background(), "acme")
+		resp, err := intercept(ctx, &GetUserRequest{Id: 1}, info, handler)
+		if resp != "acme" || err != nil {
+			t.Errorf("intercepted call = %v, %v; want acme", resp, err)
+		}
+		if n := strings.Count(out.String(), "/users.Users/GetUser took"); n != 2 {
+			t.Errorf("interceptors logged %d lines, want 2:\n%s", n, out.String())
+		}
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	defer guard.VerifyNone(t)
+
+	ctx := NewIncomingContext(context.Background(), MD{"x-request-id": {"r-1"}})
+	for _, with := range []func(context.Context, string) context.Context{WithTenantBad, WithTenantGood} {
+		if got := Tenant(with(ctx, "acme")); got != "acme" {
+			t.Errorf("Tenant() = %q, want acme", got)
+		}
+	}
+	if md, _ := FromIncomingContext(WithTenantBad(ctx, "acme")); md.Get("X-Request-ID")[0] != "r-1" {
+		t.Errorf("WithTenantBad dropped the request ID: %v", md)
+	}
+	if ResponseHeaderBad(3).Get("x-server-version")[0] != ResponseHeaderGood().Get("x-server-version")[0] {
+		t.Error("response headers differ")
+	}
+}
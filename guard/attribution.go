@@ -0,0 +1,219 @@
+package guard
+
+import (
+	"fmt"
+	goruntime "runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harshakonda/heapcheck/runtime"
+	"github.com/harshakonda/heapcheck/runtime/stackparse"
+)
+
+// trackInterval is how often VerifyTestMain samples the goroutines while
+// the tests run
+const trackInterval = 10 * time.Millisecond
+
+// unattributed groups the leaked goroutines no test could be found for
+const unattributed = "(unattributed)"
+
+// DisableTestAttribution stops VerifyTestMain from sampling goroutines
+// while the tests run to attribute leaks to tests. The leak is then
+// reported as one list of goroutines.
+func DisableTestAttribution() Option {
+	return func(c *config) {
+		c.noAttribution = true
+	}
+}
+
+// testTracker samples the goroutines while the tests run, so goroutines
+// leaked by the package can be attributed to the tests that started them
+type testTracker struct {
+	mu       sync.Mutex
+	tests    map[int]string   // goroutine ID → test it runs
+	creators map[int]int      // goroutine ID → the goroutine that started it
+	running  map[int][]string // goroutine ID → tests running when it was first seen
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startTracking samples the goroutines every interval until stopped
+func startTracking(interval time.Duration) *testTracker {
+	tr := &testTracker{
+		tests:    make(map[int]string),
+		creators: make(map[int]int),
+		running:  make(map[int][]string),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(tr.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			tr.sample(captureStacks())
+			select {
+			case <-tr.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return tr
+}
+
+// Stop ends the sampling
+func (tr *testTracker) Stop() {
+	close(tr.stop)
+	<-tr.done
+}
+
+// sample records which goroutines run tests, who started each goroutine,
+// and the tests running when a goroutine first appears
+func (tr *testTracker) sample(goroutines []runtime.GoroutineInfo) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	var running []string
+	for _, g := range goroutines {
+		if name := runningTest(g); name != "" {
+			tr.tests[g.ID] = name
+			running = append(running, name)
+		}
+	}
+	sort.Strings(running)
+	for _, g := range goroutines {
+		if _, seen := tr.creators[g.ID]; seen {
+			continue
+		}
+		tr.creators[g.ID] = g.CreatorID
+		tr.running[g.ID] = running
+	}
+}
+
+// attribute returns the test that started g: the test its go statement
+// is in, the test of a goroutine up its chain of creators, or the only
+// test running when g was first seen. It returns unattributed otherwise.
+func (tr *testTracker) attribute(g runtime.GoroutineInfo) string {
+	if g.CreatedBy != nil {
+		if name := testOf(g.CreatedBy.Function); name != "" {
+			return name
+		}
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	seen := make(map[int]bool)
+	for id := g.CreatorID; id != 0 && !seen[id]; id = tr.creators[id] {
+		seen[id] = true
+		if name, ok := tr.tests[id]; ok {
+			return name
+		}
+	}
+	if running := tr.running[g.ID]; len(running) == 1 {
+		return running[0]
+	}
+	return unattributed
+}
+
+// byTest groups leaked goroutines by the test that started them
+func (tr *testTracker) byTest(leaked []runtime.GoroutineInfo) map[string][]runtime.GoroutineInfo {
+	groups := make(map[string][]runtime.GoroutineInfo)
+	for _, g := range leaked {
+		name := tr.attribute(g)
+		groups[name] = append(groups[name], g)
+	}
+	return groups
+}
+
+// runningTest returns the test g runs: the function testing.tRunner
+// called, or "" when g runs no test
+func runningTest(g runtime.GoroutineInfo) string {
+	for i := 1; i < len(g.Frames); i++ {
+		if g.Frames[i].Function == "testing.tRunner" {
+			return topLevelFunc(g.Frames[i-1].Function)
+		}
+	}
+	return ""
+}
+
+// testOf returns the test fn belongs to when it is a test function or a
+// closure in one, e.g. "TestPool" for "example.com/pool.TestPool.func1"
+func testOf(fn string) string {
+	name := topLevelFunc(fn)
+	for _, prefix := range []string{"Test", "Benchmark", "Fuzz", "Example"} {
+		if strings.HasPrefix(name, prefix) {
+			return name
+		}
+	}
+	return ""
+}
+
+// topLevelFunc returns the top-level function of a qualified function name,
+// e.g. "TestPool" for "example.com/pool.TestPool.func1.2"
+func topLevelFunc(fn string) string {
+	fn = fn[strings.LastIndex(fn, "/")+1:]
+	if i := strings.IndexByte(fn, '.'); i >= 0 {
+		fn = fn[i+1:]
+	}
+	if i := strings.IndexByte(fn, '.'); i >= 0 {
+		fn = fn[:i]
+	}
+	return fn
+}
+
+// formatByTest lists leaked goroutines per test, the tests with the most
+// first, and unattributed goroutines last
+func formatByTest(groups map[string][]runtime.GoroutineInfo) string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := names[i], names[j]
+		if (a == unattributed) != (b == unattributed) {
+			return b == unattributed
+		}
+		if len(groups[a]) != len(groups[b]) {
+			return len(groups[a]) > len(groups[b])
+		}
+		return a < b
+	})
+
+	var sb strings.Builder
+	sb.WriteString("  Leaked by test:\n")
+	for _, name := range names {
+		fmt.Fprintf(&sb, "    %-40s %d goroutine(s)\n", name, len(groups[name]))
+	}
+	for _, name := range names {
+		fmt.Fprintf(&sb, "\n--- %s\n", name)
+		for _, g := range groups[name] {
+			sb.WriteString("\nfingerprint: " + Fingerprint(g) + "\n" + g.Stack + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// leakCounts returns how many goroutines each test leaked
+func leakCounts(groups map[string][]runtime.GoroutineInfo) map[string]int {
+	counts := make(map[string]int, len(groups))
+	for name, gs := range groups {
+		counts[name] = len(gs)
+	}
+	return counts
+}
+
+// captureStacks returns all goroutines
+func captureStacks() []runtime.GoroutineInfo {
+	buf := make([]byte, 1<<20)
+	for {
+		n := goruntime.Stack(buf, true)
+		if n < len(buf) {
+			return stackparse.ParseStacks(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
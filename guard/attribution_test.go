@@ -0,0 +1,106 @@
+package guard
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harshakonda/heapcheck/runtime"
+	"github.com/harshakonda/heapcheck/runtime/stackparse"
+)
+
+func TestTopLevelFunc(t *testing.T) {
+	tests := []struct{ fn, want string }{
+		{"example.com/pool.TestPool", "TestPool"},
+		{"example.com/pool.TestPool.func1.2", "TestPool"},
+		{"example.com/pool.(*Pool).Start.func1", "(*Pool)"},
+		{"main.main", "main"},
+	}
+	for _, tt := range tests {
+		if got := topLevelFunc(tt.fn); got != tt.want {
+			t.Errorf("topLevelFunc(%q) = %q, want %q", tt.fn, got, tt.want)
+		}
+	}
+}
+
+func TestTrackerAttribute(t *testing.T) {
+	tRunner := stackparse.Frame{Function: "testing.tRunner"}
+	running := func(id int, test string) runtime.GoroutineInfo {
+		return runtime.GoroutineInfo{ID: id, Frames: []stackparse.Frame{{Function: "example.com/pool." + test}, tRunner}}
+	}
+	created := func(id, creator int, fn string) runtime.GoroutineInfo {
+		return runtime.GoroutineInfo{ID: id, CreatorID: creator, CreatedBy: &stackparse.Frame{Function: fn}}
+	}
+
+	tr := &testTracker{tests: make(map[int]string), creators: make(map[int]int), running: make(map[int][]string)}
+	tr.sample([]runtime.GoroutineInfo{running(10, "TestPool"), created(11, 10, "example.com/pool.(*Pool).Start")})
+	tr.sample([]runtime.GoroutineInfo{created(12, 11, "example.com/pool.(*Pool).spawn"), created(13, 99, "example.com/pool.init.func1")})
+	tr.sample([]runtime.GoroutineInfo{running(20, "TestA"), running(21, "TestB"), created(14, 98, "example.com/pool.background")})
+
+	tests := []struct {
+		g    runtime.GoroutineInfo
+		want string
+	}{
+		{created(30, 1, "example.com/pool.TestCache.func2"), "TestCache"}, // go statement in the test
+		{created(11, 10, "example.com/pool.(*Pool).Start"), "TestPool"},   // started by the test goroutine
+		{created(12, 11, "example.com/pool.(*Pool).spawn"), "TestPool"},   // up the chain of creators
+		{created(13, 99, "example.com/pool.init.func1"), unattributed},    // no test running
+		{created(14, 98, "example.com/pool.background"), unattributed},    // parallel tests
+	}
+	for _, tt := range tests {
+		if got := tr.attribute(tt.g); got != tt.want {
+			t.Errorf("attribute(goroutine %d) = %q, want %q", tt.g.ID, got, tt.want)
+		}
+	}
+}
+
+// startWorker starts a goroutine that runs until stop is closed
+func startWorker(stop chan struct{}) {
+	go func() {
+		<-stop
+	}()
+}
+
+func TestTrackerLive(t *testing.T) {
+	before := make(map[int]bool)
+	for _, g := range captureStacks() {
+		before[g.ID] = true
+	}
+	tr := startTracking(time.Millisecond)
+	stop := make(chan struct{})
+	defer close(stop)
+	startWorker(stop)
+	tr.Stop()
+
+	var leaked []runtime.GoroutineInfo
+	for _, g := range captureStacks() {
+		if !before[g.ID] && g.CreatedBy != nil && strings.HasSuffix(g.CreatedBy.Function, "startWorker") {
+			leaked = append(leaked, g)
+		}
+	}
+	groups := tr.byTest(leaked)
+	if len(groups["TestTrackerLive"]) != 1 {
+		t.Fatalf("byTest() = %v, want the worker attributed to TestTrackerLive", leakCounts(groups))
+	}
+	out := formatByTest(groups)
+	if !strings.Contains(out, "TestTrackerLive") || !strings.Contains(out, "1 goroutine(s)") || !strings.Contains(out, "\n--- TestTrackerLive\n") {
+		t.Errorf("formatByTest() =\n%s", out)
+	}
+}
+
+func TestFormatByTestOrder(t *testing.T) {
+	g := runtime.GoroutineInfo{Stack: "goroutine 1 [chan receive]:"}
+	out := formatByTest(map[string][]runtime.GoroutineInfo{
+		unattributed: {g, g, g},
+		"TestB":      {g},
+		"TestA":      {g, g},
+	})
+	last := -1
+	for _, name := range []string{"TestA", "TestB", unattributed} {
+		i := strings.Index(out, name)
+		if i < last {
+			t.Errorf("%s listed out of order:\n%s", name, out)
+		}
+		last = i
+	}
+}
@@ -0,0 +1,70 @@
+package guard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/harshakonda/heapcheck/runtime"
+)
+
+// Metric units reported by BenchmarkGuard
+const (
+	HeapBytesPerOpUnit  = "heap-B/op"
+	GoroutinesPerOpUnit = "goroutines/op"
+)
+
+// BenchmarkGuard measures the heap a benchmark retains and the goroutines
+// it leaves running, from this call until the benchmark function returns,
+// and reports both per iteration as the "heap-B/op" and "goroutines/op"
+// metrics. Call it after setup, immediately before the b.N loop, also
+// inside each b.Run sub-benchmark:
+//
+//	func BenchmarkCache(b *testing.B) {
+//	    for _, size := range []int{10, 1000} {
+//	        b.Run(strconv.Itoa(size), func(b *testing.B) {
+//	            c := NewCache(size)
+//
+//	            guard.BenchmarkGuard(b)
+//	            for i := 0; i < b.N; i++ {
+//	                c.Put(i, i)
+//	            }
+//	        })
+//	    }
+//	}
+//
+// Unlike B/op, which counts every allocation, heap-B/op counts the bytes
+// still live after a GC, so it shows leaks and unbounded caches. New
+// goroutines are filtered as in VerifyNone: IgnoreTopFunction,
+// IgnoreContains, ExpectedGoroutines, SettleTime and RetryCount apply.
+func BenchmarkGuard(b *testing.B, opts ...Option) {
+	b.Helper()
+
+	cfg := newConfig(opts)
+
+	snapshot := runtime.TakeSnapshot()
+
+	b.Cleanup(func() {
+		heapPerOp, goroutinesPerOp := perOp(snapshot, cfg, b.N)
+		b.ReportMetric(heapPerOp, HeapBytesPerOpUnit)
+		b.ReportMetric(goroutinesPerOp, GoroutinesPerOpUnit)
+	})
+}
+
+// perOp divides the heap growth and new goroutines since snapshot by n,
+// waiting for goroutines that are still exiting like verifyWithConfig
+func perOp(snapshot *runtime.Snapshot, cfg *config, n int) (heap, goroutines float64) {
+	if n <= 0 {
+		return 0, 0
+	}
+
+	diff := snapshot.CompareWith(cfg.expected)
+	leaked := filterIgnored(diff.LeakedGoroutines, cfg)
+	for i := 1; i < cfg.retryCount && len(leaked) > 0; i++ {
+		time.Sleep(cfg.settleTime)
+		diff = snapshot.CompareWith(cfg.expected)
+		leaked = filterIgnored(diff.LeakedGoroutines, cfg)
+	}
+
+	// The heap can shrink when the snapshot held garbage from setup
+	return float64(max(diff.HeapGrowthBytes, 0)) / float64(n), float64(len(leaked)) / float64(n)
+}
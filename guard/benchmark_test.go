@@ -0,0 +1,65 @@
+package guard_test
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/guard"
+)
+
+// retained keeps what the leaking benchmark allocates alive
+var retained [][]byte
+
+// withBenchtime runs testing.Benchmark with a fixed iteration count
+func withBenchtime(t *testing.T, benchtime string, fn func(b *testing.B)) testing.BenchmarkResult {
+	t.Helper()
+	f := flag.Lookup("test.benchtime")
+	if f == nil {
+		t.Skip("test.benchtime flag not registered")
+	}
+	old := f.Value.String()
+	if err := f.Value.Set(benchtime); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Value.Set(old)
+	return testing.Benchmark(fn)
+}
+
+func TestBenchmarkGuard(t *testing.T) {
+	stop := make(chan struct{})
+	defer func() {
+		close(stop)
+		retained = nil
+	}()
+
+	result := withBenchtime(t, "200x", func(b *testing.B) {
+		guard.BenchmarkGuard(b)
+		for i := 0; i < b.N; i++ {
+			retained = append(retained, make([]byte, 1024))
+			go func() { <-stop }()
+		}
+	})
+
+	if heap := result.Extra[guard.HeapBytesPerOpUnit]; heap < 1024 || heap > 4096 {
+		t.Errorf("%s = %.0f, want about 1024", guard.HeapBytesPerOpUnit, heap)
+	}
+	if g := result.Extra[guard.GoroutinesPerOpUnit]; g != 1 {
+		t.Errorf("%s = %.2f, want 1", guard.GoroutinesPerOpUnit, g)
+	}
+}
+
+func TestBenchmarkGuardNoGrowth(t *testing.T) {
+	result := withBenchtime(t, "200x", func(b *testing.B) {
+		guard.BenchmarkGuard(b)
+		for i := 0; i < b.N; i++ {
+			_ = make([]byte, 64)
+		}
+	})
+
+	if heap := result.Extra[guard.HeapBytesPerOpUnit]; heap > 64 {
+		t.Errorf("%s = %.0f, want ~0 for garbage that is collected", guard.HeapBytesPerOpUnit, heap)
+	}
+	if g := result.Extra[guard.GoroutinesPerOpUnit]; g != 0 {
+		t.Errorf("%s = %.2f, want 0", guard.GoroutinesPerOpUnit, g)
+	}
+}
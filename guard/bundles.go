@@ -0,0 +1,89 @@
+package guard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// httpTestPatterns are the goroutines of httptest servers and of the
+// HTTP clients that talk to them: the accept loop, one goroutine per
+// server connection, and the read and write loops of idle client
+// connections kept alive by the transport
+var httpTestPatterns = []string{
+	"net/http/httptest.(*Server).goServe",
+	"net/http.(*conn).serve",
+	"net/http.(*persistConn).readLoop",
+	"net/http.(*persistConn).writeLoop",
+	"net/http.(*http2serverConn).serve",
+	"net/http.(*http2ClientConn).readLoop",
+}
+
+// sqlPoolPatterns are the goroutines database/sql starts for every open
+// DB, whatever the driver
+var sqlPoolPatterns = []string{
+	"database/sql.(*DB).connectionOpener",
+	"database/sql.(*DB).connectionCleaner",
+	"database/sql.(*DB).connectionResetter",
+}
+
+// sqlDriverPatterns are the background goroutines of popular drivers and
+// their pools, by the name passed to IgnoreSQLDriver
+var sqlDriverPatterns = map[string][]string{
+	"pgx": {
+		"github.com/jackc/pgx/v5/pgxpool.(*Pool).backgroundHealthCheck",
+		"github.com/jackc/pgx/v5/pgxpool.(*Pool).triggerHealthCheck",
+		"github.com/jackc/pgx/v5/pgconn/ctxwatch.(*ContextWatcher).Watch",
+		"github.com/jackc/pgx/v4/pgxpool.(*Pool).backgroundHealthCheck",
+		"github.com/jackc/pgconn/internal/ctxwatch.(*ContextWatcher).Watch",
+		"github.com/jackc/puddle/v2.(*Pool).",
+	},
+	"pq": {
+		"github.com/lib/pq.(*conn).watchCancel",
+		"github.com/lib/pq.(*ListenerConn).listenerConnLoop",
+		"github.com/lib/pq.(*Listener).listenerMain",
+	},
+	"mysql": {
+		"github.com/go-sql-driver/mysql.(*mysqlConn).startWatcher",
+	},
+}
+
+// IgnoreHTTPTestServer ignores the goroutines of httptest servers and
+// the keep-alive connections of clients calling them, for tests that
+// share a server across subtests or leave closing it to a later cleanup.
+// A handler that never returns runs on a server connection goroutine and
+// is ignored too; close the server within the test to catch it.
+func IgnoreHTTPTestServer() Option {
+	return func(c *config) {
+		c.ignoreContains = append(c.ignoreContains, httpTestPatterns...)
+	}
+}
+
+// IgnoreSQLDriver ignores the background goroutines of an open sql.DB
+// and of the named driver's connections and pool: "pgx" (v4 and v5,
+// including pgxpool), "pq" (lib/pq) or "mysql" (go-sql-driver). Queries
+// and transactions left open are still reported. An unknown driver
+// name fails the test, as a typo would otherwise hide nothing.
+//
+//	defer guard.VerifyNone(t, guard.IgnoreSQLDriver("pgx"))
+func IgnoreSQLDriver(driver string) Option {
+	return func(c *config) {
+		patterns, ok := sqlDriverPatterns[driver]
+		if !ok {
+			c.optionErrs = append(c.optionErrs, fmt.Errorf("unknown SQL driver %q (known: %s)", driver, strings.Join(SQLDrivers(), ", ")))
+			return
+		}
+		c.ignoreContains = append(c.ignoreContains, sqlPoolPatterns...)
+		c.ignoreContains = append(c.ignoreContains, patterns...)
+	}
+}
+
+// SQLDrivers returns the driver names IgnoreSQLDriver knows, sorted
+func SQLDrivers() []string {
+	names := make([]string, 0, len(sqlDriverPatterns))
+	for name := range sqlDriverPatterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
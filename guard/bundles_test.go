@@ -0,0 +1,68 @@
+package guard_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/guard"
+)
+
+// serveOnce starts an httptest server and makes one request with a fresh
+// client, leaving the server and the idle connection open
+func serveOnce(t *testing.T) (*httptest.Server, *http.Client) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	client := &http.Client{Transport: &http.Transport{}}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return srv, client
+}
+
+func TestIgnoreHTTPTestServer(t *testing.T) {
+	mock := &mockT{}
+	guard.VerifyNone(mock, guard.RetryCount(1))
+	srv, client := serveOnce(t)
+	mock.runCleanups()
+	if len(mock.errors) == 0 {
+		t.Error("open httptest server not reported without IgnoreHTTPTestServer")
+	}
+	srv.Close()
+	client.CloseIdleConnections()
+
+	mock = &mockT{}
+	guard.VerifyNone(mock, guard.RetryCount(1), guard.IgnoreHTTPTestServer())
+	srv, client = serveOnce(t)
+	mock.runCleanups()
+	if len(mock.errors) != 0 {
+		t.Errorf("open httptest server reported with IgnoreHTTPTestServer: %v", mock.errors)
+	}
+	srv.Close()
+	client.CloseIdleConnections()
+}
+
+func TestIgnoreSQLDriver(t *testing.T) {
+	for _, driver := range guard.SQLDrivers() {
+		mock := &mockT{}
+		guard.VerifyNone(mock, guard.IgnoreSQLDriver(driver))
+		mock.runCleanups()
+		if len(mock.errors) != 0 {
+			t.Errorf("IgnoreSQLDriver(%q) errors = %v", driver, mock.errors)
+		}
+	}
+
+	mock := &mockT{}
+	guard.VerifyNone(mock, guard.IgnoreSQLDriver("postgres"))
+	mock.runCleanups()
+	if len(mock.errors) != 1 || !strings.Contains(mock.errors[0], `unknown SQL driver "postgres"`) {
+		t.Errorf("IgnoreSQLDriver(\"postgres\") errors = %v", mock.errors)
+	}
+}
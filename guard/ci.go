@@ -0,0 +1,97 @@
+package guard
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SettleEnv and RetriesEnv name the environment variables that set the
+// default settle time (e.g. "250ms") and retry count of every check, for
+// the machine they run on. SettleTime and RetryCount options take
+// precedence.
+const (
+	SettleEnv  = "HEAPCHECK_SETTLE"
+	RetriesEnv = "HEAPCHECK_RETRIES"
+)
+
+// The built-in settle time and retry count: on a developer machine, and
+// on CI, whose shared runners schedule goroutines later
+const (
+	defaultSettleTime = 100 * time.Millisecond
+	defaultRetryCount = 3
+	ciSettleTime      = 250 * time.Millisecond
+	ciRetryCount      = 4
+)
+
+// inCI reports whether the tests run on CI, per the CI environment
+// variable that GitHub Actions, GitLab CI, CircleCI, Travis and most other
+// services set
+func inCI(getenv func(string) string) bool {
+	v := getenv("CI")
+	if v == "" {
+		return false
+	}
+	ci, err := strconv.ParseBool(v)
+	return err != nil || ci // any value but a false one, e.g. "woodpecker"
+}
+
+// retryDefaults returns the default settle time and retry count: the
+// built-in ones for a developer machine or CI, overridden by SettleEnv and
+// RetriesEnv. Invalid values are ignored, and described in the error.
+func retryDefaults(getenv func(string) string) (time.Duration, int, error) {
+	settle, retries := defaultSettleTime, defaultRetryCount
+	if inCI(getenv) {
+		settle, retries = ciSettleTime, ciRetryCount
+	}
+
+	var err error
+	if v := getenv(SettleEnv); v != "" {
+		if d, perr := time.ParseDuration(v); perr == nil && d >= 0 {
+			settle = d
+		} else {
+			err = fmt.Errorf("ignoring %s=%q: want a duration such as 250ms", SettleEnv, v)
+		}
+	}
+	if v := getenv(RetriesEnv); v != "" {
+		if n, perr := strconv.Atoi(v); perr == nil && n > 0 {
+			retries = n
+		} else {
+			err = fmt.Errorf("ignoring %s=%q: want a positive number", RetriesEnv, v)
+		}
+	}
+	return settle, retries, err
+}
+
+// warnEnvOnce reports invalid SettleEnv and RetriesEnv values once per
+// test binary rather than on every check
+var warnEnvOnce sync.Once
+
+// envRetryDefaults is retryDefaults for the process environment
+func envRetryDefaults() (time.Duration, int) {
+	settle, retries, err := retryDefaults(os.Getenv)
+	if err != nil {
+		warnEnvOnce.Do(func() {
+			fmt.Fprintf(os.Stderr, "heapcheck: %v\n", err)
+		})
+	}
+	return settle, retries
+}
+
+// OnCI applies opts only when the tests run on CI, as the CI environment
+// variable tells, e.g. to give shared runners a longer settle time than
+// developer machines without slowing local runs:
+//
+//	defer guard.VerifyNone(t, guard.OnCI(guard.SettleTime(time.Second), guard.RetryCount(10)))
+func OnCI(opts ...Option) Option {
+	return func(c *config) {
+		if !inCI(os.Getenv) {
+			return
+		}
+		for _, opt := range opts {
+			opt(c)
+		}
+	}
+}
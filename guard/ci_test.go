@@ -0,0 +1,80 @@
+package guard
+
+import (
+	"testing"
+	"time"
+)
+
+// localDefaults makes defaultConfig use the settle time and retry count
+// of a developer machine, wherever the test runs
+func localDefaults(t *testing.T) {
+	t.Helper()
+	t.Setenv("CI", "")
+	t.Setenv(SettleEnv, "")
+	t.Setenv(RetriesEnv, "")
+}
+
+func TestRetryDefaults(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantSettle  time.Duration
+		wantRetries int
+		wantErr     bool
+	}{
+		{"local", nil, 100 * time.Millisecond, 3, false},
+		{"CI", map[string]string{"CI": "true"}, 250 * time.Millisecond, 4, false},
+		{"CI named", map[string]string{"CI": "woodpecker"}, 250 * time.Millisecond, 4, false},
+		{"CI false", map[string]string{"CI": "false"}, 100 * time.Millisecond, 3, false},
+		{"CI 0", map[string]string{"CI": "0"}, 100 * time.Millisecond, 3, false},
+		{"env", map[string]string{SettleEnv: "1s", RetriesEnv: "10"}, time.Second, 10, false},
+		{"env over CI", map[string]string{"CI": "1", SettleEnv: "500ms"}, 500 * time.Millisecond, 4, false},
+		{"bad settle", map[string]string{"CI": "true", SettleEnv: "soon"}, 250 * time.Millisecond, 4, true},
+		{"negative settle", map[string]string{SettleEnv: "-1s"}, 100 * time.Millisecond, 3, true},
+		{"bad retries", map[string]string{RetriesEnv: "0"}, 100 * time.Millisecond, 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(k string) string { return tt.env[k] }
+			settle, retries, err := retryDefaults(getenv)
+			if settle != tt.wantSettle || retries != tt.wantRetries || (err != nil) != tt.wantErr {
+				t.Errorf("retryDefaults() = %v, %d, %v; want %v, %d, error %v",
+					settle, retries, err, tt.wantSettle, tt.wantRetries, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultConfigFromEnv(t *testing.T) {
+	localDefaults(t)
+	t.Setenv(SettleEnv, "20ms")
+	t.Setenv(RetriesEnv, "7")
+	cfg := defaultConfig()
+	if cfg.settleTime != 20*time.Millisecond || cfg.retryCount != 7 {
+		t.Errorf("defaultConfig() = settle %v, retries %d; want 20ms, 7", cfg.settleTime, cfg.retryCount)
+	}
+
+	// Options take precedence over the environment
+	SettleTime(time.Millisecond)(cfg)
+	if cfg.settleTime != time.Millisecond {
+		t.Errorf("SettleTime did not override %s: %v", SettleEnv, cfg.settleTime)
+	}
+}
+
+func TestOnCI(t *testing.T) {
+	opt := OnCI(SettleTime(time.Second), RetryCount(9))
+
+	localDefaults(t)
+	cfg := defaultConfig()
+	opt(cfg)
+	if cfg.settleTime != 100*time.Millisecond || cfg.retryCount != 3 {
+		t.Errorf("OnCI applied off CI: settle %v, retries %d", cfg.settleTime, cfg.retryCount)
+	}
+
+	t.Setenv("CI", "true")
+	cfg = defaultConfig()
+	opt(cfg)
+	if cfg.settleTime != time.Second || cfg.retryCount != 9 {
+		t.Errorf("OnCI on CI = settle %v, retries %d; want 1s, 9", cfg.settleTime, cfg.retryCount)
+	}
+}
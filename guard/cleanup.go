@@ -0,0 +1,98 @@
+package guard
+
+import "sync"
+
+// AfterCleanup runs the verification after the cleanups registered with
+// Cleanup, instead of as a t.Cleanup function of its own. t.Cleanup
+// functions run last-registered-first, so a fixture set up before
+// VerifyNone, or any cleanup when VerifyNone is deferred, would otherwise
+// release its goroutines only after they were reported as leaks.
+//
+// The testing package has no way to register a cleanup ahead of the
+// others, so register the cleanups that must come first with Cleanup.
+// The verification then also runs after every t.Cleanup function
+// registered after the first Cleanup or VerifyNone of the test.
+func AfterCleanup() Option {
+	return func(c *config) {
+		c.afterCleanup = true
+	}
+}
+
+// Cleanup registers f to run when t and its subtests complete, like
+// t.Cleanup, but before the verification of a VerifyNone or VerifyProcess
+// with AfterCleanup, wherever that is called in the test. Cleanups run
+// last-registered-first, as with t.Cleanup.
+func Cleanup(t TestingT, f func()) {
+	l := cleanupsOf(t)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cleanups = append(l.cleanups, f)
+}
+
+// cleanupList holds the cleanups of a test registered with Cleanup and
+// the verifications run after them, from a single t.Cleanup function
+type cleanupList struct {
+	mu       sync.Mutex
+	cleanups []func()
+	verify   []func()
+}
+
+var (
+	cleanupMu    sync.Mutex
+	cleanupLists = make(map[TestingT]*cleanupList)
+)
+
+// cleanupsOf returns the cleanup list of t, registering the t.Cleanup
+// function that runs it on first use
+func cleanupsOf(t TestingT) *cleanupList {
+	cleanupMu.Lock()
+	defer cleanupMu.Unlock()
+	if l, ok := cleanupLists[t]; ok {
+		return l
+	}
+	l := &cleanupList{}
+	cleanupLists[t] = l
+	t.Cleanup(func() {
+		cleanupMu.Lock()
+		delete(cleanupLists, t)
+		cleanupMu.Unlock()
+		l.run()
+	})
+	return l
+}
+
+// run runs the cleanups, last-registered-first, and then the
+// verifications, in the order they were registered. A cleanup or
+// verification registered while they run runs too.
+func (l *cleanupList) run() {
+	for {
+		l.mu.Lock()
+		var f func()
+		switch {
+		case len(l.cleanups) > 0:
+			f = l.cleanups[len(l.cleanups)-1]
+			l.cleanups = l.cleanups[:len(l.cleanups)-1]
+		case len(l.verify) > 0:
+			f = l.verify[0]
+			l.verify = l.verify[1:]
+		}
+		l.mu.Unlock()
+		if f == nil {
+			return
+		}
+		f()
+	}
+}
+
+// registerVerify registers verify to run when t completes: with last,
+// after the cleanups registered with Cleanup, and otherwise with t.Cleanup
+func registerVerify(t TestingT, verify func(), last bool) {
+	if !last {
+		t.Cleanup(verify)
+		return
+	}
+	l := cleanupsOf(t)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.verify = append(l.verify, verify)
+}
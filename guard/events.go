@@ -0,0 +1,166 @@
+package guard
+
+import (
+	"encoding/json"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harshakonda/heapcheck/runtime"
+)
+
+// EventsFileEnv names the environment variable that enables leak event
+// output when no EventsFile option is given.
+const EventsFileEnv = "HEAPCHECK_EVENTS_FILE"
+
+// LeakEvent is a leak report in the shape of a `go test -json` event.
+// Action is always "output", so dashboards that parse test2json streams
+// show the leak as test output; the extra Leak field carries the details.
+//
+// Events are appended as JSON lines, so the file can be concatenated with
+// the go test -json stream:
+//
+//	HEAPCHECK_EVENTS_FILE=leaks.json go test -json ./... > test.json
+//	cat test.json leaks.json | my-dashboard-ingest
+type LeakEvent struct {
+	Time    time.Time    `json:"Time"`
+	Action  string       `json:"Action"`
+	Package string       `json:"Package,omitempty"`
+	Test    string       `json:"Test,omitempty"`
+	Output  string       `json:"Output"`
+	Leak    *LeakDetails `json:"Leak"`
+}
+
+// LeakDetails describes what a failed verification found
+type LeakDetails struct {
+	Kind               string         `json:"kind"` // goroutine, heap, heap-objects, object, mallocs, cgo, or threads
+	LeakedGoroutines   int            `json:"leakedGoroutines,omitempty"`
+	MaxGoroutines      int            `json:"maxGoroutines"`
+	ByState            map[string]int `json:"byState,omitempty"`
+	HeapGrowthBytes    int64          `json:"heapGrowthBytes"`      // of HeapMetric
+	HeapMetric         string         `json:"heapMetric,omitempty"` // the statistic MaxHeapMB is checked against, empty for HeapAlloc
+	MaxHeapMB          int            `json:"maxHeapMB,omitempty"`
+	HeapGrowthObjects  int64          `json:"heapGrowthObjects,omitempty"`
+	MaxHeapObjects     int            `json:"maxHeapObjects,omitempty"`
+	UncollectedObjects []string       `json:"uncollectedObjects,omitempty"`
+	Mallocs            uint64         `json:"mallocs,omitempty"`
+	MaxMallocs         int            `json:"maxMallocs,omitempty"`
+	CgoCalls           int64          `json:"cgoCalls,omitempty"`
+	MaxCgoCalls        int            `json:"maxCgoCalls,omitempty"`
+	ThreadGrowth       int            `json:"threadGrowth,omitempty"`
+	MaxThreads         int            `json:"maxThreads,omitempty"`
+	WarnOnly           bool           `json:"warnOnly,omitempty"` // logged, the test did not fail
+
+	// Fingerprints are the Fingerprint of each leaked goroutine, for
+	// listing known flaky leaks in a Quarantine file
+	Fingerprints []string `json:"fingerprints,omitempty"`
+
+	// ByTest counts the goroutines VerifyTestMain found leaked per test
+	// that started them
+	ByTest map[string]int `json:"byTest,omitempty"`
+
+	// ByLabel counts the leaked goroutines per pprof label, as
+	// "key=value", to attribute leaks to the component or request that
+	// labeled them
+	ByLabel map[string]int `json:"byLabel,omitempty"`
+}
+
+// EventsFile appends a LeakEvent line to path for every detected leak.
+// Defaults to the HEAPCHECK_EVENTS_FILE environment variable.
+func EventsFile(path string) Option {
+	return func(c *config) {
+		c.eventsFile = path
+	}
+}
+
+var eventsMu sync.Mutex
+
+// writeLeakEvent appends one event to the configured events file.
+// Errors are ignored: event output must never fail the test itself.
+func writeLeakEvent(cfg *config, test string, output string, details *LeakDetails) {
+	if cfg.eventsFile == "" {
+		return
+	}
+
+	event := LeakEvent{
+		Time:    time.Now(),
+		Action:  "output",
+		Package: testPackage(),
+		Test:    test,
+		Output:  output + "\n",
+		Leak:    details,
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	eventsMu.Lock()
+	defer eventsMu.Unlock()
+
+	f, err := os.OpenFile(cfg.eventsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(append(data, '\n'))
+}
+
+// leakDetails builds event details from a verification result
+func leakDetails(kind string, diff *runtime.Diff, leaked []runtime.GoroutineInfo, cfg *config) *LeakDetails {
+	details := &LeakDetails{
+		Kind:              kind,
+		LeakedGoroutines:  len(leaked),
+		MaxGoroutines:     cfg.maxGoroutines,
+		HeapGrowthBytes:   diff.HeapGrowth(cfg.heapMetric),
+		MaxHeapMB:         cfg.maxHeapMB,
+		HeapGrowthObjects: diff.HeapGrowthObjects,
+		MaxHeapObjects:    cfg.maxHeapObjects,
+		Mallocs:           diff.Mallocs,
+		MaxMallocs:        cfg.maxMallocs,
+		CgoCalls:          diff.CgoCalls,
+		MaxCgoCalls:       cfg.maxCgoCalls,
+		ThreadGrowth:      diff.ThreadGrowth,
+		MaxThreads:        cfg.maxThreads,
+		WarnOnly:          cfg.warnOnly,
+	}
+	if cfg.heapMetric != runtime.HeapMetricAlloc {
+		details.HeapMetric = cfg.heapMetric.String()
+	}
+	if len(leaked) > 0 {
+		details.ByState = runtime.CountByState(leaked)
+	}
+	for _, g := range leaked {
+		details.Fingerprints = append(details.Fingerprints, Fingerprint(g))
+		for k, v := range g.Labels {
+			if details.ByLabel == nil {
+				details.ByLabel = make(map[string]int)
+			}
+			details.ByLabel[k+"="+v]++
+		}
+	}
+	for _, obj := range diff.UncollectedObjects {
+		details.UncollectedObjects = append(details.UncollectedObjects, obj.Label)
+	}
+	return details
+}
+
+// testName returns the test name if t provides one (*testing.T does)
+func testName(t TestingT) string {
+	if n, ok := t.(interface{ Name() string }); ok {
+		return n.Name()
+	}
+	return ""
+}
+
+// testPackage returns the import path of the package under test, derived
+// from the test binary's build info ("example.com/pkg.test").
+func testPackage() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	return strings.TrimSuffix(info.Path, ".test")
+}
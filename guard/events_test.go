@@ -0,0 +1,98 @@
+package guard_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harshakonda/heapcheck/guard"
+)
+
+func TestEventsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leaks.json")
+	mock := &mockT{}
+	stop := make(chan struct{})
+	defer close(stop)
+
+	guard.VerifyNone(mock,
+		guard.EventsFile(path),
+		guard.SettleTime(10*time.Millisecond),
+		guard.RetryCount(1),
+	)
+	go func() {
+		<-stop
+	}()
+	mock.runCleanups()
+
+	if len(mock.errors) == 0 {
+		t.Fatal("expected leak to be reported")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading events file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 event line, got %d", len(lines))
+	}
+
+	var event guard.LeakEvent
+	if err := json.Unmarshal([]byte(lines[0]), &event); err != nil {
+		t.Fatalf("invalid event JSON: %v", err)
+	}
+	if event.Action != "output" {
+		t.Errorf("Action = %q, want output", event.Action)
+	}
+	if event.Test != "TestMock" {
+		t.Errorf("Test = %q, want TestMock", event.Test)
+	}
+	if event.Package != "github.com/harshakonda/heapcheck/guard" {
+		t.Errorf("Package = %q", event.Package)
+	}
+	if event.Leak == nil || event.Leak.Kind != "goroutine" || event.Leak.LeakedGoroutines != 1 {
+		t.Errorf("unexpected leak details: %+v", event.Leak)
+	}
+}
+
+func TestEventsFileLabels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leaks.json")
+	mock := &mockT{}
+	stop := make(chan struct{})
+	defer close(stop)
+
+	guard.VerifyNone(mock,
+		guard.EventsFile(path),
+		guard.SettleTime(10*time.Millisecond),
+		guard.RetryCount(1),
+	)
+	pprof.Do(context.Background(), pprof.Labels("component", "billing"), func(context.Context) {
+		go labeledWorker(stop)
+	})
+	mock.runCleanups()
+
+	if len(mock.errors) != 1 || !strings.Contains(mock.errors[0], "labels: component=billing") {
+		t.Fatalf("expected the leak with its labels, got %v", mock.errors)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading events file: %v", err)
+	}
+	var event guard.LeakEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		t.Fatalf("invalid event JSON: %v", err)
+	}
+	if event.Leak == nil || event.Leak.ByLabel["component=billing"] != 1 {
+		t.Errorf("ByLabel = %v, want component=billing: 1", event.Leak)
+	}
+}
+
+// labeledWorker blocks until stop is closed, on a stack of its own
+func labeledWorker(stop chan struct{}) {
+	<-stop
+}
@@ -17,7 +17,7 @@
 //
 //	func TestMyFunction(t *testing.T) {
 //	    defer guard.VerifyNone(t)
-//	    
+//
 //	    // Your test code here
 //	    myFunction()
 //	}
@@ -29,7 +29,7 @@
 //	        guard.MaxGoroutines(10),
 //	        guard.MaxHeapMB(50),
 //	    )
-//	    
+//
 //	    // Your test code here
 //	}
 //
@@ -39,7 +39,7 @@
 //	    defer guard.VerifyNone(t,
 //	        guard.IgnoreTopFunction("github.com/some/pkg.backgroundWorker"),
 //	    )
-//	    
+//
 //	    // Your test code here
 //	}
 //
@@ -48,9 +48,15 @@
 //	func TestMain(m *testing.M) {
 //	    guard.VerifyTestMain(m)
 //	}
+//
+// Rolling Out to a Large Suite:
+//
+// Set HEAPCHECK_WARN_ONLY=1 (or pass guard.WarnOnly()) to log leaks
+// without failing, observe them, then unset it to enforce.
 package guard
 
 import (
+	"fmt"
 	"os"
 	goruntime "runtime"
 	"strings"
@@ -59,6 +65,10 @@ import (
 	"github.com/harshakonda/heapcheck/runtime"
 )
 
+// WarnOnlyEnv names the environment variable that makes every check log
+// leaks instead of failing, as if WarnOnly were given
+const WarnOnlyEnv = "HEAPCHECK_WARN_ONLY"
+
 // TestingT is the interface for *testing.T and *testing.B
 type TestingT interface {
 	Errorf(format string, args ...interface{})
@@ -76,21 +86,80 @@ type TestingM interface {
 type Option func(*config)
 
 type config struct {
-	maxGoroutines     int
-	maxHeapMB         int
-	settleTime        time.Duration
-	retryCount        int
-	ignoreFuncs       []string
-	ignoreContains    []string
+	maxGoroutines  int
+	maxHeapMB      int
+	heapMetric     runtime.HeapMetric
+	maxHeapObjects int
+	maxMallocs     int
+	maxCgoCalls    int
+	maxThreads     int
+	settleTime     time.Duration
+	retryCount     int
+	ignoreFuncs    []string
+	ignoreContains []string
+	creators       []creatorBudget
+	quarantine     []quarantineEntry
+	optionErrs     []error // misused options, reported when verifying
+	expected       *runtime.GoroutineFilter
+	eventsFile     string
+	testReport     string
+	warnOnly       bool
+	noAttribution  bool
+	afterCleanup   bool
+
+	// raceSettle and raceHeap relax the limits in -race builds; race
+	// records that they did, for the failure messages
+	raceSettle float64
+	raceHeap   float64
+	race       bool
 }
 
 func defaultConfig() *config {
+	settle, retries := envRetryDefaults()
 	return &config{
-		maxGoroutines: 0,  // Any growth is a leak
-		maxHeapMB:     0,  // Unlimited
-		settleTime:    100 * time.Millisecond,
-		retryCount:    3,
+		maxGoroutines: 0, // Any growth is a leak
+		maxHeapMB:     0, // Unlimited
+		settleTime:    settle,
+		retryCount:    retries,
+		expected:      runtime.DefaultGoroutineFilter(),
+		eventsFile:    os.Getenv(EventsFileEnv),
+		testReport:    os.Getenv(TestReportEnv),
+		warnOnly:      os.Getenv(WarnOnlyEnv) != "",
+		raceSettle:    runtime.DefaultRaceSettleMultiplier,
+		raceHeap:      runtime.DefaultRaceHeapMultiplier,
+	}
+}
+
+// newConfig applies opts to the defaults, relaxing the result when the
+// test binary was built with -race
+func newConfig(opts []Option) *config {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	cfg.relaxForRace(runtime.RaceEnabled)
+	return cfg
+}
+
+// relaxForRace multiplies the settle time by raceSettle, and the heap,
+// heap object and malloc limits by raceHeap, when race is true
+func (c *config) relaxForRace(race bool) {
+	if !race {
+		return
+	}
+	c.race = true
+	c.settleTime = runtime.ScaleDuration(c.settleTime, c.raceSettle)
+	c.maxHeapMB = runtime.ScaleLimit(c.maxHeapMB, c.raceHeap)
+	c.maxHeapObjects = runtime.ScaleLimit(c.maxHeapObjects, c.raceHeap)
+	c.maxMallocs = runtime.ScaleLimit(c.maxMallocs, c.raceHeap)
+}
+
+// raceNote marks limits relaxed for -race in failure messages
+func (c *config) raceNote() string {
+	if c.race {
+		return ", relaxed for -race"
 	}
+	return ""
 }
 
 // MaxGoroutines sets the maximum allowed goroutine growth.
@@ -109,8 +178,83 @@ func MaxHeapMB(mb int) Option {
 	}
 }
 
+// HeapMetric sets the memory statistic MaxHeapMB is checked against.
+// The default, runtime.HeapMetricAlloc, counts garbage not yet swept and
+// moves with GC timing; runtime.HeapMetricInuse tracks resident heap
+// growth, runtime.HeapMetricSys all memory obtained from the OS, and
+// runtime.HeapMetricLive the objects the last GC found live.
+//
+//	guard.VerifyNone(t, guard.MaxHeapMB(50), guard.HeapMetric(runtime.HeapMetricInuse))
+func HeapMetric(m runtime.HeapMetric) Option {
+	return func(c *config) {
+		c.heapMetric = m
+	}
+}
+
+// MaxHeapObjects sets the maximum allowed growth in live heap objects,
+// catching leaks of many small objects that stay under MaxHeapMB.
+// Default is 0 (unlimited).
+func MaxHeapObjects(n int) Option {
+	return func(c *config) {
+		c.maxHeapObjects = n
+	}
+}
+
+// MaxMallocs sets the maximum number of heap allocations between the
+// snapshot and the check, catching code that churns objects without net
+// heap growth. Default is 0 (unlimited).
+func MaxMallocs(n int) Option {
+	return func(c *config) {
+		c.maxMallocs = n
+	}
+}
+
+// MaxCgoCalls sets the maximum number of calls into C between the
+// snapshot and the check. Memory allocated by C code does not show in
+// heap growth, so bounding the calls guards paths that allocate via C.
+// Default is 0 (unlimited).
+func MaxCgoCalls(n int) Option {
+	return func(c *config) {
+		c.maxCgoCalls = n
+	}
+}
+
+// MaxThreads sets the maximum allowed growth in OS threads, catching
+// threads left behind by goroutines locked to them with
+// runtime.LockOSThread, or started by C libraries, which goroutine counts
+// do not show. The Go runtime keeps some idle threads for reuse, so allow
+// a few. Default is 0 (unlimited).
+//
+//	guard.MaxThreads(4)
+func MaxThreads(n int) Option {
+	return func(c *config) {
+		c.maxThreads = n
+	}
+}
+
+// MaxLeakedByCreator allows up to n leaked goroutines started by a go
+// statement in a function whose name contains creator, such as a
+// package path or a method. They are counted against this budget only,
+// not MaxGoroutines, so a test of a pool that keeps n workers alive can
+// pin that count and still catch any other leak:
+//
+//	guard.MaxLeakedByCreator("pkg/worker", 2)
+//
+// A goroutine is counted against the first budget its creator matches.
+func MaxLeakedByCreator(creator string, n int) Option {
+	return func(c *config) {
+		c.creators = append(c.creators, creatorBudget{creator: creator, max: n})
+	}
+}
+
+// creatorBudget is a MaxLeakedByCreator limit
+type creatorBudget struct {
+	creator string
+	max     int
+}
+
 // SettleTime sets how long to wait for goroutines to settle.
-// Default is 100ms.
+// Default is 100ms, 250ms on CI, or $HEAPCHECK_SETTLE.
 func SettleTime(d time.Duration) Option {
 	return func(c *config) {
 		c.settleTime = d
@@ -118,7 +262,7 @@ func SettleTime(d time.Duration) Option {
 }
 
 // RetryCount sets how many times to retry before reporting a leak.
-// Default is 3.
+// Default is 3, 4 on CI, or $HEAPCHECK_RETRIES.
 func RetryCount(n int) Option {
 	return func(c *config) {
 		c.retryCount = n
@@ -144,6 +288,63 @@ func IgnoreContains(s string) Option {
 	}
 }
 
+// ExpectedGoroutines replaces the filter that decides which new goroutines
+// are expected (runtime, testing, ...) and never reported as leaks.
+//
+//	guard.ExpectedGoroutines(runtime.DefaultGoroutineFilter().Add("mypool.worker"))
+func ExpectedGoroutines(f *runtime.GoroutineFilter) Option {
+	return func(c *config) {
+		c.expected = f
+	}
+}
+
+// RemoveExpected removes patterns from the built-in expected-goroutine
+// filter, for when a broad default pattern masks a real leak:
+//
+//	guard.RemoveExpected("testing.tRunner")
+func RemoveExpected(patterns ...string) Option {
+	return func(c *config) {
+		if c.expected == nil {
+			c.expected = runtime.DefaultGoroutineFilter()
+		}
+		c.expected.Remove(patterns...)
+	}
+}
+
+// DisableExpectedFilter reports every new goroutine, including those
+// matching the built-in runtime and testing patterns.
+// Combine with IgnoreContains to whitelist precisely.
+func DisableExpectedFilter() Option {
+	return func(c *config) {
+		if c.expected == nil {
+			c.expected = runtime.DefaultGoroutineFilter()
+		}
+		c.expected.Disable()
+	}
+}
+
+// RaceMultipliers sets how much a test binary built with -race relaxes
+// the limits: the settle time is multiplied by settle, and the heap, heap
+// object and malloc limits by heap. Race builds schedule goroutines 2-20x
+// slower and allocate more, which would otherwise fail as leaks. 1, 1
+// keeps the limits as they are. Default is 5 and 2.
+func RaceMultipliers(settle, heap float64) Option {
+	return func(c *config) {
+		c.raceSettle = settle
+		c.raceHeap = heap
+	}
+}
+
+// WarnOnly logs leaks via t.Logf instead of failing the test, and keeps
+// VerifyTestMain from changing the exit code. Use it while rolling
+// VerifyNone out across a test suite to see what it would report.
+// Default is set when $HEAPCHECK_WARN_ONLY is non-empty.
+func WarnOnly() Option {
+	return func(c *config) {
+		c.warnOnly = true
+	}
+}
+
 // VerifyNone verifies that no goroutines are leaked when the test completes.
 // This is the primary API, designed to be compatible with goleak.
 //
@@ -166,55 +367,133 @@ func IgnoreContains(s string) Option {
 func VerifyNone(t TestingT, opts ...Option) {
 	t.Helper()
 
-	cfg := defaultConfig()
-	for _, opt := range opts {
-		opt(cfg)
-	}
+	cfg := newConfig(opts)
 
 	snapshot := runtime.TakeSnapshot()
 
 	// Register cleanup to run at end of test
-	t.Cleanup(func() {
+	verify := func() {
 		verifyWithConfig(t, snapshot, cfg)
-	})
+	}
+	registerVerify(t, verify, cfg.afterCleanup)
 }
 
 // verifyWithConfig performs the actual verification
 func verifyWithConfig(t TestingT, snapshot *runtime.Snapshot, cfg *config) {
 	t.Helper()
 
+	for _, err := range cfg.optionErrs {
+		t.Errorf("heapcheck: %v", err)
+	}
+
 	var diff *runtime.Diff
-	var leaked []runtime.GoroutineInfo
+	var leaked, quarantined []runtime.GoroutineInfo
+	var byCreator [][]runtime.GoroutineInfo
+	now := time.Now()
 
 	// Retry loop to allow goroutines to settle
 	for i := 0; i < cfg.retryCount; i++ {
 		goruntime.GC()
 		time.Sleep(cfg.settleTime)
 
-		diff = snapshot.Compare()
-		leaked = filterIgnored(diff.LeakedGoroutines, cfg)
+		diff = snapshot.CompareWith(cfg.expected)
+		leaked, quarantined = splitQuarantined(filterIgnored(diff.LeakedGoroutines, cfg), cfg.quarantine, now)
+		leaked, byCreator = splitByCreator(leaked, cfg.creators)
 
 		// Check if within thresholds
-		goroutineOK := len(leaked) <= cfg.maxGoroutines
-		heapOK := cfg.maxHeapMB == 0 || diff.HeapGrowthBytes <= int64(cfg.maxHeapMB)*1024*1024
-
-		if goroutineOK && heapOK {
+		goroutineOK := len(leaked) <= cfg.maxGoroutines && len(overBudget(byCreator, cfg.creators)) == 0
+		heapOK := cfg.maxHeapMB == 0 || diff.HeapGrowth(cfg.heapMetric) <= int64(cfg.maxHeapMB)*1024*1024
+		heapObjectsOK := cfg.maxHeapObjects == 0 || diff.HeapGrowthObjects <= int64(cfg.maxHeapObjects)
+		objectsOK := len(diff.UncollectedObjects) == 0
+		mallocsOK := cfg.maxMallocs == 0 || diff.Mallocs <= uint64(cfg.maxMallocs)
+		cgoOK := cfg.maxCgoCalls == 0 || diff.CgoCalls <= int64(cfg.maxCgoCalls)
+		threadsOK := cfg.maxThreads == 0 || diff.ThreadGrowth <= cfg.maxThreads
+
+		if goroutineOK && heapOK && heapObjectsOK && objectsOK && mallocsOK && cgoOK && threadsOK {
+			recordGrowth(t, cfg, diff, leaked, false)
+			if len(quarantined) > 0 {
+				t.Logf("%s", formatQuarantined(quarantined, cfg.quarantine, now))
+			}
 			return // No leak detected
 		}
 	}
+	recordGrowth(t, cfg, diff, leaked, true)
+	if len(quarantined) > 0 {
+		t.Logf("%s", formatQuarantined(quarantined, cfg.quarantine, now))
+	}
 
-	// Report failures
+	// Report failures, or only log them in warn-only mode
+	report := t.Errorf
+	if cfg.warnOnly {
+		report = t.Logf
+	}
 	if len(leaked) > cfg.maxGoroutines {
-		t.Errorf("heapcheck: goroutine leak detected\n"+
+		msg := fmt.Sprintf("heapcheck: goroutine leak detected\n"+
+			"  Leaked: %d (max allowed: %d)\n"+
+			"%s%s%s",
+			len(leaked), cfg.maxGoroutines, describe(diff, leaked), formatExpired(leaked, cfg.quarantine, now), formatLeaked(leaked))
+		report("%s", msg)
+		writeLeakEvent(cfg, testName(t), msg, leakDetails("goroutine", diff, leaked, cfg))
+	}
+
+	for _, i := range overBudget(byCreator, cfg.creators) {
+		created := byCreator[i]
+		msg := fmt.Sprintf("heapcheck: goroutine leak detected for creator %s\n"+
 			"  Leaked: %d (max allowed: %d)\n"+
-			"  %s",
-			len(leaked), cfg.maxGoroutines, formatLeaked(leaked))
+			"%s%s%s",
+			cfg.creators[i].creator, len(created), cfg.creators[i].max, describe(diff, created), formatExpired(created, cfg.quarantine, now), formatLeaked(created))
+		report("%s", msg)
+		writeLeakEvent(cfg, testName(t), msg, leakDetails("goroutine", diff, created, cfg))
 	}
 
-	if cfg.maxHeapMB > 0 && diff.HeapGrowthBytes > int64(cfg.maxHeapMB)*1024*1024 {
-		t.Errorf("heapcheck: heap leak detected\n"+
-			"  Growth: %.2f MB (max allowed: %d MB)",
-			float64(diff.HeapGrowthBytes)/1024/1024, cfg.maxHeapMB)
+	if growth := diff.HeapGrowth(cfg.heapMetric); cfg.maxHeapMB > 0 && growth > int64(cfg.maxHeapMB)*1024*1024 {
+		msg := fmt.Sprintf("heapcheck: heap leak detected\n"+
+			"  Growth: %.2f MB of %s (max allowed: %d MB%s)\n"+
+			"%s",
+			float64(growth)/1024/1024, cfg.heapMetric, cfg.maxHeapMB, cfg.raceNote(), describe(diff, leaked))
+		report("%s", msg)
+		writeLeakEvent(cfg, testName(t), msg, leakDetails("heap", diff, leaked, cfg))
+	}
+
+	if cfg.maxHeapObjects > 0 && diff.HeapGrowthObjects > int64(cfg.maxHeapObjects) {
+		msg := fmt.Sprintf("heapcheck: heap object leak detected\n"+
+			"  Growth: %d objects (max allowed: %d%s), %.2f MB\n"+
+			"%s",
+			diff.HeapGrowthObjects, cfg.maxHeapObjects, cfg.raceNote(), float64(diff.HeapGrowthBytes)/1024/1024, describe(diff, leaked))
+		report("%s", msg)
+		writeLeakEvent(cfg, testName(t), msg, leakDetails("heap-objects", diff, leaked, cfg))
+	}
+
+	if len(diff.UncollectedObjects) > 0 {
+		msg := fmt.Sprintf("heapcheck: tracked objects not collected (%d):%s",
+			len(diff.UncollectedObjects), runtime.FormatUncollected(diff.UncollectedObjects))
+		report("%s", msg)
+		writeLeakEvent(cfg, testName(t), msg, leakDetails("object", diff, leaked, cfg))
+	}
+
+	if cfg.maxMallocs > 0 && diff.Mallocs > uint64(cfg.maxMallocs) {
+		msg := fmt.Sprintf("heapcheck: allocation churn detected\n"+
+			"  Mallocs: %d (max allowed: %d%s), frees: %d, heap growth: %.2f MB",
+			diff.Mallocs, cfg.maxMallocs, cfg.raceNote(), diff.Frees, float64(diff.HeapGrowthBytes)/1024/1024)
+		report("%s", msg)
+		writeLeakEvent(cfg, testName(t), msg, leakDetails("mallocs", diff, leaked, cfg))
+	}
+
+	if cfg.maxCgoCalls > 0 && diff.CgoCalls > int64(cfg.maxCgoCalls) {
+		msg := fmt.Sprintf("heapcheck: too many cgo calls\n"+
+			"  Calls: %d (max allowed: %d)",
+			diff.CgoCalls, cfg.maxCgoCalls)
+		report("%s", msg)
+		writeLeakEvent(cfg, testName(t), msg, leakDetails("cgo", diff, leaked, cfg))
+	}
+
+	if cfg.maxThreads > 0 && diff.ThreadGrowth > cfg.maxThreads {
+		msg := fmt.Sprintf("heapcheck: OS thread leak detected\n"+
+			"  Growth: %d threads (max allowed: %d), %d created by the Go runtime\n"+
+			"  Look for goroutines that call runtime.LockOSThread and keep running, and C libraries that start threads",
+			diff.ThreadGrowth, cfg.maxThreads, diff.ThreadsCreated)
+		report("%s", msg)
+		writeLeakEvent(cfg, testName(t), msg, leakDetails("threads", diff, leaked, cfg))
 	}
 }
 
@@ -234,6 +513,50 @@ func filterIgnored(leaked []runtime.GoroutineInfo, cfg *config) []runtime.Gorout
 	return filtered
 }
 
+// splitByCreator takes the goroutines whose creator matches a budget out
+// of leaked, returning the rest and those of each budget
+func splitByCreator(leaked []runtime.GoroutineInfo, budgets []creatorBudget) ([]runtime.GoroutineInfo, [][]runtime.GoroutineInfo) {
+	if len(budgets) == 0 {
+		return leaked, nil
+	}
+	var rest []runtime.GoroutineInfo
+	byCreator := make([][]runtime.GoroutineInfo, len(budgets))
+	for _, g := range leaked {
+		i := creatorBudgetOf(g, budgets)
+		if i < 0 {
+			rest = append(rest, g)
+			continue
+		}
+		byCreator[i] = append(byCreator[i], g)
+	}
+	return rest, byCreator
+}
+
+// creatorBudgetOf returns the index of the first budget matching the
+// creator of g, or -1
+func creatorBudgetOf(g runtime.GoroutineInfo, budgets []creatorBudget) int {
+	if g.CreatedBy == nil {
+		return -1
+	}
+	for i, b := range budgets {
+		if strings.Contains(g.CreatedBy.Function, b.creator) {
+			return i
+		}
+	}
+	return -1
+}
+
+// overBudget returns the indexes of the budgets byCreator exceeds
+func overBudget(byCreator [][]runtime.GoroutineInfo, budgets []creatorBudget) []int {
+	var over []int
+	for i, created := range byCreator {
+		if len(created) > budgets[i].max {
+			over = append(over, i)
+		}
+	}
+	return over
+}
+
 // shouldIgnore checks if a goroutine should be ignored
 func shouldIgnore(g runtime.GoroutineInfo, cfg *config) bool {
 	for _, fn := range cfg.ignoreFuncs {
@@ -249,6 +572,15 @@ func shouldIgnore(g runtime.GoroutineInfo, cfg *config) bool {
 	return false
 }
 
+// describe renders diff like Diff.Format, counting only the leaked
+// goroutines left after the ignore options, indented for error output
+func describe(diff *runtime.Diff, leaked []runtime.GoroutineInfo) string {
+	d := *diff
+	d.LeakedGoroutines = leaked
+	d.ByState = runtime.CountByState(leaked)
+	return "  " + strings.ReplaceAll(d.Format(false), "\n", "\n  ")
+}
+
 // formatLeaked formats leaked goroutines for error output
 func formatLeaked(leaked []runtime.GoroutineInfo) string {
 	if len(leaked) == 0 {
@@ -262,6 +594,13 @@ func formatLeaked(leaked []runtime.GoroutineInfo) string {
 			break
 		}
 		sb.WriteString("\n  ")
+		if labels := g.LabelString(); labels != "" {
+			sb.WriteString("labels: " + labels + "\n  ")
+		}
+		if d := runtime.Diagnose(g); d != nil {
+			sb.WriteString("hint: " + d.String() + "\n  ")
+		}
+		sb.WriteString("fingerprint: " + Fingerprint(g) + "\n  ")
 		sb.WriteString(truncateStack(g.Stack, 5))
 	}
 	return sb.String()
@@ -278,7 +617,9 @@ func truncateStack(stack string, n int) string {
 }
 
 // VerifyTestMain runs tests and checks for leaks at package level.
-// Use in TestMain to check for leaks after all tests complete.
+// Use in TestMain to check for leaks after all tests complete. It then
+// prints the tests whose VerifyNone or Verify checks saw goroutine or
+// heap growth, even within their limits, closest to the limits first.
 //
 //	func TestMain(m *testing.M) {
 //	    guard.VerifyTestMain(m)
@@ -292,29 +633,59 @@ func truncateStack(stack string, n int) string {
 //	    )
 //	}
 func VerifyTestMain(m TestingM, opts ...Option) {
-	cfg := defaultConfig()
-	for _, opt := range opts {
-		opt(cfg)
-	}
+	cfg := newConfig(opts)
 
 	snapshot := runtime.TakeSnapshot()
 
-	// Run tests
+	// Run tests, sampling goroutines to attribute leaks to them
+	var tracker *testTracker
+	if !cfg.noAttribution {
+		tracker = startTracking(trackInterval)
+	}
+	recordedGrowth()
 	exitCode := m.Run()
+	if tracker != nil {
+		tracker.Stop()
+	}
+	writeSummary(os.Stderr, recordedGrowth())
 
 	// Check for leaks
 	goruntime.GC()
 	time.Sleep(cfg.settleTime)
 
-	diff := snapshot.Compare()
-	leaked := filterIgnored(diff.LeakedGoroutines, cfg)
+	for _, err := range cfg.optionErrs {
+		fmt.Fprintf(os.Stderr, "\nheapcheck: %v\n", err)
+		if exitCode == 0 {
+			exitCode = 1
+		}
+	}
 
-	if len(leaked) > cfg.maxGoroutines {
+	now := time.Now()
+	diff := snapshot.CompareWith(cfg.expected)
+	leaked, quarantined := splitQuarantined(filterIgnored(diff.LeakedGoroutines, cfg), cfg.quarantine, now)
+	leaked, byCreator := splitByCreator(leaked, cfg.creators)
+	for _, i := range overBudget(byCreator, cfg.creators) {
+		leaked = append(leaked, byCreator[i]...)
+	}
+	if len(quarantined) > 0 {
+		os.Stderr.WriteString("\n" + formatQuarantined(quarantined, cfg.quarantine, now) + "\n")
+	}
+
+	if len(leaked) > cfg.maxGoroutines || len(overBudget(byCreator, cfg.creators)) > 0 {
 		os.Stderr.WriteString("\nheapcheck: goroutine leak detected after tests\n")
-		for _, g := range leaked {
-			os.Stderr.WriteString("\n" + g.Stack + "\n")
+		os.Stderr.WriteString(describe(diff, leaked) + formatExpired(leaked, cfg.quarantine, now) + "\n")
+		details := leakDetails("goroutine", diff, leaked, cfg)
+		if tracker != nil {
+			groups := tracker.byTest(leaked)
+			os.Stderr.WriteString(formatByTest(groups))
+			details.ByTest = leakCounts(groups)
+		} else {
+			for _, g := range leaked {
+				os.Stderr.WriteString("\nfingerprint: " + Fingerprint(g) + "\n" + g.Stack + "\n")
+			}
 		}
-		if exitCode == 0 {
+		writeLeakEvent(cfg, "", "heapcheck: goroutine leak detected after tests", details)
+		if exitCode == 0 && !cfg.warnOnly {
 			exitCode = 1
 		}
 	}
@@ -327,11 +698,11 @@ func VerifyTestMain(m TestingM, opts ...Option) {
 //
 //	func TestComplex(t *testing.T) {
 //	    g := guard.Check(t)
-//	    
+//
 //	    // Phase 1
 //	    doSomething()
 //	    g.Checkpoint("after phase 1")
-//	    
+//
 //	    // Phase 2
 //	    doSomethingElse()
 //	    g.Verify() // Final check
@@ -339,10 +710,7 @@ func VerifyTestMain(m TestingM, opts ...Option) {
 func Check(t TestingT, opts ...Option) *Guard {
 	t.Helper()
 
-	cfg := defaultConfig()
-	for _, opt := range opts {
-		opt(cfg)
-	}
+	cfg := newConfig(opts)
 
 	return &Guard{
 		t:        t,
@@ -362,7 +730,7 @@ type Guard struct {
 func (g *Guard) Checkpoint(label string) {
 	g.t.Helper()
 
-	diff := g.snapshot.Compare()
+	diff := g.snapshot.CompareWith(g.cfg.expected)
 	g.t.Logf("heapcheck checkpoint [%s]: goroutines=%+d, heap=%+.2f MB",
 		label, diff.GoroutineGrowth, float64(diff.HeapGrowthBytes)/1024/1024)
 }
@@ -380,5 +748,5 @@ func (g *Guard) Reset() {
 
 // Result returns the current diff without failing
 func (g *Guard) Result() *runtime.Diff {
-	return g.snapshot.Compare()
+	return g.snapshot.CompareWith(g.cfg.expected)
 }
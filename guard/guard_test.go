@@ -1,10 +1,19 @@
 package guard_test
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	goruntime "runtime"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/harshakonda/heapcheck/guard"
+	"github.com/harshakonda/heapcheck/runtime"
 )
 
 func TestVerifyNone_NoLeak(t *testing.T) {
@@ -107,3 +116,343 @@ func ExampleVerifyTestMain() {
 	//     guard.VerifyTestMain(m)
 	// }
 }
+
+func TestVerifyNone_ExpectedGoroutines(t *testing.T) {
+	mock := &mockT{}
+	stop := make(chan struct{})
+
+	guard.VerifyNone(mock,
+		guard.ExpectedGoroutines(runtime.DefaultGoroutineFilter().Add("TestVerifyNone_ExpectedGoroutines")),
+		guard.SettleTime(10*time.Millisecond),
+		guard.RetryCount(1),
+	)
+	go func() {
+		<-stop
+	}()
+	mock.runCleanups()
+	close(stop)
+
+	if len(mock.errors) != 0 {
+		t.Errorf("expected goroutine matching the filter to be ignored, got %v", mock.errors)
+	}
+}
+
+func TestVerifyNone_AfterCleanup(t *testing.T) {
+	stop := make(chan struct{})
+	t.Run("fixture", func(t *testing.T) {
+		// Registered before VerifyNone, so with t.Cleanup it would run
+		// after the verification
+		guard.Cleanup(t, func() { close(stop) })
+		guard.VerifyNone(t,
+			guard.AfterCleanup(),
+			guard.SettleTime(10*time.Millisecond),
+			guard.RetryCount(1),
+		)
+		go func() {
+			<-stop
+		}()
+	})
+}
+
+func TestVerifyNone_AfterCleanupOrder(t *testing.T) {
+	mock := &mockT{}
+	stopFixture := make(chan struct{})
+	stopLater := make(chan struct{})
+	var order []string
+
+	guard.Cleanup(mock, func() {
+		order = append(order, "fixture")
+		close(stopFixture)
+	})
+	guard.VerifyNone(mock, guard.AfterCleanup(), guard.SettleTime(10*time.Millisecond), guard.RetryCount(1))
+	mock.Cleanup(func() {
+		order = append(order, "later")
+		close(stopLater)
+	})
+	go func() { <-stopFixture }()
+	go func() { <-stopLater }()
+
+	if len(mock.cleanups) != 2 {
+		t.Fatalf("expected one cleanup for guard and the test's own, got %d", len(mock.cleanups))
+	}
+	mock.runCleanups()
+	if len(mock.errors) != 0 {
+		t.Errorf("expected no failures, got %v", mock.errors)
+	}
+	if want := []string{"later", "fixture"}; !slices.Equal(order, want) {
+		t.Errorf("cleanups ran in order %v, want %v", order, want)
+	}
+}
+
+func TestVerifyNone_WarnOnly(t *testing.T) {
+	mock := &mockT{}
+	stop := make(chan struct{})
+
+	guard.VerifyNone(mock,
+		guard.WarnOnly(),
+		guard.SettleTime(10*time.Millisecond),
+		guard.RetryCount(1),
+	)
+	go func() {
+		<-stop
+	}()
+	mock.runCleanups()
+	close(stop)
+
+	if len(mock.errors) != 0 {
+		t.Errorf("expected no failures in warn-only mode, got %v", mock.errors)
+	}
+	if len(mock.logs) != 1 || !strings.Contains(mock.logs[0], "goroutine leak detected") {
+		t.Fatalf("expected the leak to be logged, got %v", mock.logs)
+	}
+	if !strings.Contains(mock.logs[0], "hint: blocked receiving") {
+		t.Errorf("expected a hint for the blocked receive, got %s", mock.logs[0])
+	}
+}
+
+func TestVerifyNone_WarnOnlyEnv(t *testing.T) {
+	t.Setenv(guard.WarnOnlyEnv, "1")
+	mock := &mockT{}
+	stop := make(chan struct{})
+
+	guard.VerifyNone(mock, guard.SettleTime(10*time.Millisecond), guard.RetryCount(1))
+	go func() {
+		<-stop
+	}()
+	mock.runCleanups()
+	close(stop)
+
+	if len(mock.errors) != 0 || len(mock.logs) != 1 {
+		t.Errorf("with %s set: errors = %v, logs = %v, want the leak logged only", guard.WarnOnlyEnv, mock.errors, mock.logs)
+	}
+}
+
+// startWorkers starts n goroutines that run until stop is closed
+func startWorkers(n int, stop chan struct{}) {
+	for i := 0; i < n; i++ {
+		go func() {
+			<-stop
+		}()
+	}
+}
+
+func TestVerifyNone_MaxLeakedByCreator(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		other   bool   // also leak a goroutine started elsewhere
+		want    string // expected failure, empty for none
+	}{
+		{"within budget", 2, false, ""},
+		{"over budget", 3, false, "goroutine leak detected for creator guard_test.startWorkers\n  Leaked: 3 (max allowed: 2)"},
+		{"other creator", 2, true, "goroutine leak detected\n  Leaked: 1 (max allowed: 0)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockT{}
+			stop := make(chan struct{})
+			defer close(stop)
+
+			guard.VerifyNone(mock,
+				guard.MaxLeakedByCreator("guard_test.startWorkers", 2),
+				guard.SettleTime(10*time.Millisecond),
+				guard.RetryCount(1),
+			)
+			startWorkers(tt.workers, stop)
+			if tt.other {
+				go func() {
+					<-stop
+				}()
+			}
+			mock.runCleanups()
+
+			if tt.want == "" {
+				if len(mock.errors) != 0 {
+					t.Errorf("expected no failures, got %v", mock.errors)
+				}
+				return
+			}
+			if len(mock.errors) != 1 || !strings.Contains(mock.errors[0], tt.want) {
+				t.Errorf("expected one failure containing %q, got %v", tt.want, mock.errors)
+			}
+		})
+	}
+}
+
+func TestVerifyNone_Quarantine(t *testing.T) {
+	verify := func(opts ...guard.Option) *mockT {
+		mock := &mockT{}
+		stop := make(chan struct{})
+		defer close(stop)
+		guard.VerifyNone(mock, append(opts, guard.SettleTime(10*time.Millisecond), guard.RetryCount(1))...)
+		startWorkers(1, stop)
+		mock.runCleanups()
+		return mock
+	}
+
+	// Leak reports print the fingerprint to quarantine
+	mock := verify()
+	m := regexp.MustCompile(`fingerprint: ([0-9a-f]{16})`).FindStringSubmatch(strings.Join(mock.errors, "\n"))
+	if m == nil {
+		t.Fatalf("expected the leak report to print a fingerprint, got %v", mock.errors)
+	}
+	file := filepath.Join(t.TempDir(), "quarantine.txt")
+	write := func(expires string) {
+		entry := m[1] + " expires=" + expires + " owner=@platform -- flaky worker\n"
+		if err := os.WriteFile(file, []byte(entry), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(time.Now().AddDate(0, 1, 0).Format("2006-01-02"))
+	mock = verify(guard.Quarantine(file))
+	if len(mock.errors) != 0 || len(mock.logs) != 1 || !strings.Contains(mock.logs[0], "1 quarantined goroutine leak(s) ignored") {
+		t.Errorf("expected the quarantined leak to be logged only: errors = %v, logs = %v", mock.errors, mock.logs)
+	}
+
+	write("2020-01-01")
+	mock = verify(guard.Quarantine(file))
+	if len(mock.errors) != 1 || !strings.Contains(mock.errors[0], "quarantine expired: "+m[1]+" until 2020-01-01, owner @platform: flaky worker") {
+		t.Errorf("expected the leak to fail with the expired entry, got %v", mock.errors)
+	}
+
+	mock = verify(guard.Quarantine(filepath.Join(t.TempDir(), "missing.txt")))
+	if len(mock.errors) != 2 || !strings.Contains(mock.errors[0], "heapcheck: reading quarantine") {
+		t.Errorf("expected a missing quarantine file to fail the test, got %v", mock.errors)
+	}
+}
+
+var churnSink []byte
+
+func TestVerifyNone_MaxMallocs(t *testing.T) {
+	mock := &mockT{}
+	guard.VerifyNone(mock, guard.MaxMallocs(500), guard.SettleTime(0), guard.RetryCount(1))
+	for i := 0; i < 10000; i++ {
+		churnSink = make([]byte, 64)
+	}
+	mock.runCleanups()
+
+	if len(mock.errors) != 1 || !strings.Contains(mock.errors[0], "allocation churn detected") {
+		t.Errorf("expected allocation churn to be reported, got %v", mock.errors)
+	}
+}
+
+var smallObjects []*int
+
+func TestVerifyNone_MaxHeapObjects(t *testing.T) {
+	mock := &mockT{}
+	guard.VerifyNone(mock, guard.MaxHeapMB(1), guard.MaxHeapObjects(5000), guard.SettleTime(0), guard.RetryCount(1))
+	for i := 0; i < 20000; i++ {
+		smallObjects = append(smallObjects, new(int))
+	}
+	mock.runCleanups()
+	smallObjects = nil
+
+	if len(mock.errors) != 1 || !strings.Contains(mock.errors[0], "heap object leak detected") {
+		t.Errorf("expected only the heap object leak to be reported, got %v", mock.errors)
+	}
+}
+
+func TestVerifyNone_HeapMetric(t *testing.T) {
+	mock := &mockT{}
+	goruntime.GC()
+	guard.VerifyNone(mock, guard.MaxHeapMB(1), guard.HeapMetric(runtime.HeapMetricInuse), guard.SettleTime(0), guard.RetryCount(1))
+	retained = append(retained, make([]byte, 8<<20))
+	mock.runCleanups()
+	retained = nil
+
+	if len(mock.errors) != 1 || !strings.Contains(mock.errors[0], "MB of HeapInuse (max allowed:") {
+		t.Errorf("expected a HeapInuse leak, got %v", mock.errors)
+	}
+}
+
+func TestVerifyNone_WithinMallocsAndCgoCalls(t *testing.T) {
+	mock := &mockT{}
+	guard.VerifyNone(mock, guard.MaxMallocs(100000), guard.MaxCgoCalls(1000), guard.SettleTime(0), guard.RetryCount(1))
+	for i := 0; i < 100; i++ {
+		churnSink = make([]byte, 64)
+	}
+	mock.runCleanups()
+
+	if len(mock.errors) != 0 {
+		t.Errorf("expected no failures within the limits, got %v", mock.errors)
+	}
+}
+
+// lockThreads starts n goroutines locked to their OS threads. release
+// ends them, which ends their threads, and waits for the thread count to
+// drop back, so that later tests do not see the threads go.
+func lockThreads(n int) (release func()) {
+	before := runtime.TakeSnapshot().Threads
+	done := make(chan struct{})
+	var started sync.WaitGroup
+	started.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			goruntime.LockOSThread()
+			started.Done()
+			<-done
+		}()
+	}
+	started.Wait()
+	return func() {
+		close(done)
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if runtime.TakeSnapshot().Threads <= before+n/2 {
+				return
+			}
+		}
+	}
+}
+
+func TestVerifyNone_MaxThreads(t *testing.T) {
+	mock := &mockT{}
+	guard.VerifyNone(mock, guard.MaxThreads(8), guard.MaxGoroutines(64), guard.SettleTime(0), guard.RetryCount(1))
+	release := lockThreads(64)
+	defer release()
+	mock.runCleanups()
+
+	if len(mock.errors) != 1 || !strings.Contains(mock.errors[0], "OS thread leak detected") {
+		t.Errorf("expected the locked threads to be reported, got %v", mock.errors)
+	}
+}
+
+func TestVerifyNone_WithinMaxThreads(t *testing.T) {
+	mock := &mockT{}
+	guard.VerifyNone(mock, guard.MaxThreads(1000), guard.SettleTime(0), guard.RetryCount(1))
+	lockThreads(4)()
+	mock.runCleanups()
+
+	if len(mock.errors) != 0 {
+		t.Errorf("expected no failures within the limit, got %v", mock.errors)
+	}
+}
+
+// mockT records failures instead of failing the enclosing test
+type mockT struct {
+	errors   []string
+	logs     []string
+	cleanups []func()
+}
+
+func (m *mockT) Errorf(format string, args ...interface{}) {
+	m.errors = append(m.errors, fmt.Sprintf(format, args...))
+}
+
+func (m *mockT) Logf(format string, args ...interface{}) {
+	m.logs = append(m.logs, fmt.Sprintf(format, args...))
+}
+
+func (m *mockT) Helper() {}
+
+func (m *mockT) Name() string { return "TestMock" }
+
+func (m *mockT) Cleanup(f func()) {
+	m.cleanups = append(m.cleanups, f)
+}
+
+func (m *mockT) runCleanups() {
+	for i := len(m.cleanups) - 1; i >= 0; i-- {
+		m.cleanups[i]()
+	}
+}
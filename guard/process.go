@@ -0,0 +1,402 @@
+package guard
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/harshakonda/heapcheck/runtime"
+	"github.com/harshakonda/heapcheck/runtime/stackparse"
+)
+
+// Process is a child process a test started, such as the compiled server
+// of an integration test, watched for leaks with the options VerifyNone
+// takes. Its OS threads and resident memory are read from /proc, on
+// Linux. Its goroutines and Go heap can only be read from the child
+// itself: serve net/http/pprof in it and pass PprofURL, or serve expvar
+// and pass ExpvarURL for the heap alone.
+type Process struct {
+	pid    int
+	pprof  string
+	expvar string
+	client *http.Client
+}
+
+// ProcessOption configures a watched process
+type ProcessOption func(*Process)
+
+// PprofURL reads the child's goroutines and heap from its net/http/pprof
+// handlers at base, e.g. "http://127.0.0.1:6060/debug/pprof". The heap is
+// read after the garbage collection the heap profile runs.
+func PprofURL(base string) ProcessOption {
+	return func(p *Process) {
+		p.pprof = strings.TrimSuffix(base, "/")
+	}
+}
+
+// ExpvarURL reads the child's heap from the memstats of its expvar
+// handler, e.g. "http://127.0.0.1:6060/debug/vars", when it does not
+// serve pprof. Without a garbage collection first, the heap includes
+// garbage, so allow for it in MaxHeapMB.
+func ExpvarURL(url string) ProcessOption {
+	return func(p *Process) {
+		p.expvar = url
+	}
+}
+
+// WatchProcess watches the child process pid, e.g. cmd.Process.Pid of an
+// exec.Cmd the test started
+func WatchProcess(pid int, opts ...ProcessOption) *Process {
+	p := &Process{
+		pid: pid,
+		// Without keep-alives no idle connection goroutine of the
+		// child's server outlives a request, to be taken for a leak
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{DisableKeepAlives: true},
+		},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// ProcessSnapshot is the state of a watched process
+type ProcessSnapshot struct {
+	Time time.Time
+
+	// Goroutines are the child's goroutines, nil without PprofURL. The
+	// ones serving the request that read them are left out.
+	Goroutines []runtime.GoroutineInfo
+
+	// HasHeap is set when the heap statistics were read, with PprofURL or
+	// ExpvarURL
+	HasHeap     bool
+	HeapAlloc   uint64
+	HeapInuse   uint64
+	HeapObjects uint64
+	Sys         uint64
+	Mallocs     uint64
+	Frees       uint64
+
+	// Threads and RSS, in bytes, are 0 where /proc is unavailable
+	Threads int
+	RSS     uint64
+}
+
+// Snapshot reads the current state of the process
+func (p *Process) Snapshot() (*ProcessSnapshot, error) {
+	s := &ProcessSnapshot{Time: time.Now()}
+	if err := s.readProc(p.pid); err != nil {
+		return nil, err
+	}
+	switch {
+	case p.pprof != "":
+		if err := p.readPprof(s); err != nil {
+			return nil, err
+		}
+	case p.expvar != "":
+		if err := p.readExpvar(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// readProc reads the threads and resident memory of pid from
+// /proc/<pid>/status. A missing /proc is not an error; a process missing
+// from it has exited.
+func (s *ProcessSnapshot) readProc(pid int) error {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		if _, statErr := os.Stat("/proc/self"); statErr != nil {
+			return nil
+		}
+		return fmt.Errorf("process %d: %w", pid, err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		switch key {
+		case "Threads":
+			s.Threads, _ = strconv.Atoi(fields[0])
+		case "VmRSS":
+			kb, _ := strconv.ParseUint(fields[0], 10, 64)
+			s.RSS = kb * 1024
+		}
+	}
+	return nil
+}
+
+// readPprof reads the heap statistics, after a garbage collection, and
+// the goroutines from the pprof handlers
+func (p *Process) readPprof(s *ProcessSnapshot) error {
+	heap, err := p.get(p.pprof + "/heap?gc=1&debug=1")
+	if err != nil {
+		return err
+	}
+	stats := memStatsComments(heap)
+	s.HasHeap = true
+	s.HeapAlloc, s.HeapInuse, s.HeapObjects = stats["HeapAlloc"], stats["HeapInuse"], stats["HeapObjects"]
+	s.Sys, s.Mallocs, s.Frees = stats["Sys"], stats["Mallocs"], stats["Frees"]
+
+	stacks, err := p.get(p.pprof + "/goroutine?debug=2")
+	if err != nil {
+		return err
+	}
+	for _, g := range stackparse.ParseStacks(stacks) {
+		if !servingProfile(g) {
+			s.Goroutines = append(s.Goroutines, g)
+		}
+	}
+	return nil
+}
+
+// servingFrames are the frames of the child's goroutines serving the
+// profiles: the handler, the background read of its connection, also
+// before it is scheduled, and, after the response was read, the end of
+// the request and the close of the connection
+var servingFrames = []string{
+	"net/http/pprof.",
+	"net/http.(*connReader).backgroundRead",
+	"net/http.(*connReader).startBackgroundRead",
+	"net/http.(*response).finishRequest",
+	"net/http.(*conn).close", // and closeWriteAndWait
+}
+
+// servingProfile reports whether g is a goroutine of the child serving
+// the profiles read for a snapshot
+func servingProfile(g runtime.GoroutineInfo) bool {
+	for _, frame := range servingFrames {
+		if strings.Contains(g.Stack, frame) {
+			return true
+		}
+	}
+	return false
+}
+
+// memStatsComments reads the runtime.MemStats comments, such as
+// "# HeapAlloc = 1234", ending a heap profile in its debug=1 text form
+func memStatsComments(profile []byte) map[string]uint64 {
+	stats := make(map[string]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(profile))
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimPrefix(scanner.Text(), "# "), " = ")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseUint(value, 10, 64); err == nil {
+			stats[key] = n
+		}
+	}
+	return stats
+}
+
+// readExpvar reads the heap statistics from the memstats expvar
+func (p *Process) readExpvar(s *ProcessSnapshot) error {
+	data, err := p.get(p.expvar)
+	if err != nil {
+		return err
+	}
+	var vars struct {
+		Memstats *struct {
+			HeapAlloc, HeapInuse, HeapObjects, Sys, Mallocs, Frees uint64
+		} `json:"memstats"`
+	}
+	if err := json.Unmarshal(data, &vars); err != nil {
+		return fmt.Errorf("reading %s: %w", p.expvar, err)
+	}
+	if vars.Memstats == nil {
+		return fmt.Errorf("reading %s: no memstats", p.expvar)
+	}
+	m := vars.Memstats
+	s.HasHeap = true
+	s.HeapAlloc, s.HeapInuse, s.HeapObjects = m.HeapAlloc, m.HeapInuse, m.HeapObjects
+	s.Sys, s.Mallocs, s.Frees = m.Sys, m.Mallocs, m.Frees
+	return nil
+}
+
+func (p *Process) get(url string) ([]byte, error) {
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("process %d: %w", p.pid, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("process %d: reading %s: %w", p.pid, url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("process %d: %s: %s", p.pid, url, resp.Status)
+	}
+	return data, nil
+}
+
+// Compare returns the growth from s to later as a runtime.Diff, with the
+// goroutines of later that are not in s and that filter does not expect
+// as leaked. The heap growth is zero unless both snapshots have the heap.
+func (s *ProcessSnapshot) Compare(later *ProcessSnapshot, filter *runtime.GoroutineFilter) *runtime.Diff {
+	diff := &runtime.Diff{
+		GoroutineGrowth: len(later.Goroutines) - len(s.Goroutines),
+		ThreadGrowth:    later.Threads - s.Threads,
+		Duration:        later.Time.Sub(s.Time),
+	}
+	if s.HasHeap && later.HasHeap {
+		diff.HeapGrowthBytes = int64(later.HeapAlloc) - int64(s.HeapAlloc)
+		diff.HeapGrowthObjects = int64(later.HeapObjects) - int64(s.HeapObjects)
+		diff.HeapInuseGrowthBytes = int64(later.HeapInuse) - int64(s.HeapInuse)
+		diff.SysGrowthBytes = int64(later.Sys) - int64(s.Sys)
+		diff.Mallocs = later.Mallocs - min(s.Mallocs, later.Mallocs)
+		diff.Frees = later.Frees - min(s.Frees, later.Frees)
+	}
+	before := make(map[int]bool, len(s.Goroutines))
+	for _, g := range s.Goroutines {
+		before[g.ID] = true
+	}
+	for _, g := range later.Goroutines {
+		if !before[g.ID] && !filter.IsExpected(g.Stack) {
+			diff.LeakedGoroutines = append(diff.LeakedGoroutines, g)
+		}
+	}
+	diff.ByState = runtime.CountByState(diff.LeakedGoroutines)
+	return diff
+}
+
+// VerifyProcess verifies that the child process p leaks nothing between
+// now and the end of the test, as VerifyNone does for the test itself.
+// Call it once the child is ready to serve, and after registering the
+// cleanup that stops it, so that it verifies before the child stops:
+//
+//	cmd := exec.Command(serverBinary, "--debug-addr=127.0.0.1:6060")
+//	// start cmd, t.Cleanup(stop), wait until it serves
+//	guard.VerifyProcess(t, guard.WatchProcess(cmd.Process.Pid,
+//	    guard.PprofURL("http://127.0.0.1:6060/debug/pprof")),
+//	    guard.MaxHeapMB(20),
+//	)
+//
+// The goroutine options, MaxHeapMB, MaxHeapObjects, MaxMallocs,
+// MaxThreads, the settle and retry options and WarnOnly apply to the
+// child; MaxCgoCalls and objects tracked with TrackObject, which only
+// the test process can see, do not. Goroutine options need PprofURL, and
+// heap options PprofURL or ExpvarURL.
+func VerifyProcess(t TestingT, p *Process, opts ...Option) {
+	t.Helper()
+
+	cfg := newConfig(opts)
+	if p.pprof == "" && p.expvar == "" && (cfg.maxHeapMB > 0 || cfg.maxHeapObjects > 0 || cfg.maxMallocs > 0) {
+		t.Errorf("heapcheck: process %d: heap limits need the child's heap, from PprofURL or ExpvarURL", p.pid)
+	}
+
+	snapshot, err := p.Snapshot()
+	if err != nil {
+		t.Errorf("heapcheck: %v", err)
+		return
+	}
+	verify := func() {
+		verifyProcess(t, p, snapshot, cfg)
+	}
+	registerVerify(t, verify, cfg.afterCleanup)
+}
+
+// verifyProcess compares the process with snapshot until it is within
+// the limits of cfg or the retries run out, and reports what exceeds them
+func verifyProcess(t TestingT, p *Process, snapshot *ProcessSnapshot, cfg *config) {
+	t.Helper()
+
+	for _, err := range cfg.optionErrs {
+		t.Errorf("heapcheck: %v", err)
+	}
+
+	var diff *runtime.Diff
+	var leaked, quarantined []runtime.GoroutineInfo
+	var byCreator [][]runtime.GoroutineInfo
+	now := time.Now()
+
+	for i := 0; i < cfg.retryCount; i++ {
+		time.Sleep(cfg.settleTime)
+
+		current, err := p.Snapshot()
+		if err != nil {
+			t.Errorf("heapcheck: %v", err)
+			return
+		}
+		diff = snapshot.Compare(current, cfg.expected)
+		leaked, quarantined = splitQuarantined(filterIgnored(diff.LeakedGoroutines, cfg), cfg.quarantine, now)
+		leaked, byCreator = splitByCreator(leaked, cfg.creators)
+
+		goroutineOK := len(leaked) <= cfg.maxGoroutines && len(overBudget(byCreator, cfg.creators)) == 0
+		heapOK := cfg.maxHeapMB == 0 || diff.HeapGrowth(cfg.heapMetric) <= int64(cfg.maxHeapMB)*1024*1024
+		heapObjectsOK := cfg.maxHeapObjects == 0 || diff.HeapGrowthObjects <= int64(cfg.maxHeapObjects)
+		mallocsOK := cfg.maxMallocs == 0 || diff.Mallocs <= uint64(cfg.maxMallocs)
+		threadsOK := cfg.maxThreads == 0 || diff.ThreadGrowth <= cfg.maxThreads
+		if goroutineOK && heapOK && heapObjectsOK && mallocsOK && threadsOK {
+			break
+		}
+	}
+	if diff == nil {
+		return
+	}
+	if len(quarantined) > 0 {
+		t.Logf("%s", formatQuarantined(quarantined, cfg.quarantine, now))
+	}
+
+	report := t.Errorf
+	if cfg.warnOnly {
+		report = t.Logf
+	}
+	fail := func(kind, msg string, leaked []runtime.GoroutineInfo) {
+		report("%s", msg)
+		writeLeakEvent(cfg, testName(t), msg, leakDetails(kind, diff, leaked, cfg))
+	}
+	if len(leaked) > cfg.maxGoroutines {
+		fail("goroutine", fmt.Sprintf("heapcheck: goroutine leak detected in process %d\n"+
+			"  Leaked: %d (max allowed: %d)\n"+
+			"%s%s%s",
+			p.pid, len(leaked), cfg.maxGoroutines, describe(diff, leaked), formatExpired(leaked, cfg.quarantine, now), formatLeaked(leaked)), leaked)
+	}
+	for _, i := range overBudget(byCreator, cfg.creators) {
+		created := byCreator[i]
+		fail("goroutine", fmt.Sprintf("heapcheck: goroutine leak detected in process %d for creator %s\n"+
+			"  Leaked: %d (max allowed: %d)\n"+
+			"%s%s%s",
+			p.pid, cfg.creators[i].creator, len(created), cfg.creators[i].max, describe(diff, created), formatExpired(created, cfg.quarantine, now), formatLeaked(created)), created)
+	}
+	if growth := diff.HeapGrowth(cfg.heapMetric); cfg.maxHeapMB > 0 && growth > int64(cfg.maxHeapMB)*1024*1024 {
+		fail("heap", fmt.Sprintf("heapcheck: heap leak detected in process %d\n"+
+			"  Growth: %.2f MB of %s (max allowed: %d MB)\n"+
+			"%s",
+			p.pid, float64(growth)/1024/1024, cfg.heapMetric, cfg.maxHeapMB, describe(diff, leaked)), leaked)
+	}
+	if cfg.maxHeapObjects > 0 && diff.HeapGrowthObjects > int64(cfg.maxHeapObjects) {
+		fail("heap-objects", fmt.Sprintf("heapcheck: heap object leak detected in process %d\n"+
+			"  Growth: %d objects (max allowed: %d), %.2f MB\n"+
+			"%s",
+			p.pid, diff.HeapGrowthObjects, cfg.maxHeapObjects, float64(diff.HeapGrowthBytes)/1024/1024, describe(diff, leaked)), leaked)
+	}
+	if cfg.maxMallocs > 0 && diff.Mallocs > uint64(cfg.maxMallocs) {
+		fail("mallocs", fmt.Sprintf("heapcheck: allocation churn detected in process %d\n"+
+			"  Mallocs: %d (max allowed: %d), frees: %d, heap growth: %.2f MB",
+			p.pid, diff.Mallocs, cfg.maxMallocs, diff.Frees, float64(diff.HeapGrowthBytes)/1024/1024), leaked)
+	}
+	if cfg.maxThreads > 0 && diff.ThreadGrowth > cfg.maxThreads {
+		fail("threads", fmt.Sprintf("heapcheck: OS thread leak detected in process %d\n"+
+			"  Growth: %d threads (max allowed: %d)\n"+
+			"  Look for goroutines that call runtime.LockOSThread and keep running, and C libraries that start threads",
+			p.pid, diff.ThreadGrowth, cfg.maxThreads), leaked)
+	}
+}
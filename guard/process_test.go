@@ -0,0 +1,202 @@
+package guard_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harshakonda/heapcheck/guard"
+)
+
+// TestHelperProcess is the child of the process tests: it serves pprof,
+// prints its address and then leaks what its stdin asks for, until stdin
+// closes
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("HEAPCHECK_HELPER_PROCESS") != "1" {
+		t.Skip("run as the child of the process tests")
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	go http.Serve(ln, nil)
+	fmt.Println(ln.Addr())
+
+	var retained [][]byte
+	block := make(chan struct{})
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var n int
+		switch cmd := scanner.Text(); {
+		case strings.HasPrefix(cmd, "goroutines "):
+			fmt.Sscan(strings.TrimPrefix(cmd, "goroutines "), &n)
+			for i := 0; i < n; i++ {
+				go func() { <-block }()
+			}
+		case strings.HasPrefix(cmd, "heap "):
+			fmt.Sscan(strings.TrimPrefix(cmd, "heap "), &n)
+			for i := 0; i < n; i++ {
+				b := make([]byte, 1024*1024)
+				for j := range b {
+					b[j] = 1
+				}
+				retained = append(retained, b)
+			}
+		}
+		fmt.Println("ok")
+	}
+	_ = retained
+	os.Exit(0)
+}
+
+// helperProcess starts the child of TestHelperProcess, stopped when the
+// test ends, and returns its pid, pprof address and a function that sends
+// it a command
+func helperProcess(t *testing.T) (int, string, func(string)) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+	cmd.Env = append(os.Environ(), "HEAPCHECK_HELPER_PROCESS=1")
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		stdin.Close()
+		done := make(chan struct{})
+		go func() {
+			cmd.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			cmd.Process.Kill()
+			<-done
+		}
+	})
+
+	lines := bufio.NewScanner(stdout)
+	if !lines.Scan() {
+		t.Fatal("helper process exited before serving")
+	}
+	addr := lines.Text()
+	send := func(command string) {
+		t.Helper()
+		if _, err := io.WriteString(stdin, command+"\n"); err != nil {
+			t.Fatal(err)
+		}
+		if !lines.Scan() {
+			t.Fatalf("helper process exited on %q", command)
+		}
+	}
+	return cmd.Process.Pid, "http://" + addr + "/debug/pprof", send
+}
+
+func TestVerifyProcess(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		opts    []guard.Option
+		want    string
+	}{
+		{
+			name: "no leak",
+			opts: []guard.Option{guard.MaxHeapMB(10)},
+		},
+		{
+			name:    "goroutine leak",
+			command: "goroutines 3",
+			want:    "goroutine leak detected in process",
+		},
+		{
+			name:    "goroutines allowed",
+			command: "goroutines 3",
+			opts:    []guard.Option{guard.MaxGoroutines(3)},
+		},
+		{
+			name:    "heap leak",
+			command: "heap 20",
+			opts:    []guard.Option{guard.MaxHeapMB(5)},
+			want:    "heap leak detected in process",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pid, pprof, send := helperProcess(t)
+
+			mock := &mockT{}
+			opts := append([]guard.Option{guard.SettleTime(10 * time.Millisecond), guard.RetryCount(2)}, tt.opts...)
+			guard.VerifyProcess(mock, guard.WatchProcess(pid, guard.PprofURL(pprof)), opts...)
+			if tt.command != "" {
+				send(tt.command)
+			}
+			mock.runCleanups()
+
+			if tt.want == "" {
+				if len(mock.errors) > 0 {
+					t.Errorf("expected no errors, got: %v", mock.errors)
+				}
+				return
+			}
+			if len(mock.errors) != 1 || !strings.Contains(mock.errors[0], tt.want) {
+				t.Errorf("expected one error containing %q, got: %v", tt.want, mock.errors)
+			}
+		})
+	}
+}
+
+func TestVerifyProcessLeakedStacks(t *testing.T) {
+	pid, pprof, send := helperProcess(t)
+
+	mock := &mockT{}
+	guard.VerifyProcess(mock, guard.WatchProcess(pid, guard.PprofURL(pprof)), guard.SettleTime(10*time.Millisecond), guard.RetryCount(1))
+	send("goroutines 1")
+	mock.runCleanups()
+
+	if len(mock.errors) != 1 || !strings.Contains(mock.errors[0], "TestHelperProcess") {
+		t.Errorf("expected the leaked goroutine's stack, got: %v", mock.errors)
+	}
+}
+
+func TestVerifyProcessWithoutHeap(t *testing.T) {
+	mock := &mockT{}
+	guard.VerifyProcess(mock, guard.WatchProcess(os.Getpid()), guard.MaxHeapMB(10), guard.SettleTime(time.Millisecond))
+	mock.runCleanups()
+
+	if len(mock.errors) != 1 || !strings.Contains(mock.errors[0], "PprofURL or ExpvarURL") {
+		t.Errorf("expected an error asking for the heap, got: %v", mock.errors)
+	}
+}
+
+func TestProcessSnapshotProc(t *testing.T) {
+	if _, err := os.Stat("/proc/self/status"); err != nil {
+		t.Skip("no /proc")
+	}
+	s, err := guard.WatchProcess(os.Getpid()).Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Threads == 0 || s.RSS == 0 {
+		t.Errorf("threads = %d, RSS = %d; want both from /proc", s.Threads, s.RSS)
+	}
+	if s.HasHeap || s.Goroutines != nil {
+		t.Errorf("read the heap or goroutines without PprofURL or ExpvarURL")
+	}
+}
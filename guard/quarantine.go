@@ -0,0 +1,205 @@
+package guard
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/harshakonda/heapcheck/runtime"
+)
+
+// fingerprintFrames is how many of the innermost frames outside the Go
+// runtime identify a leaked goroutine, with its creator
+const fingerprintFrames = 3
+
+// quarantineDateLayout is the format of expiry dates in quarantine files
+const quarantineDateLayout = "2006-01-02"
+
+// Fingerprint identifies a leaked goroutine across test runs: a short hash
+// of the function that started it and of its innermost frames outside the
+// Go runtime, without files, lines or arguments, so that it survives
+// unrelated edits and Go upgrades. Leak reports print it for listing the
+// goroutine in a Quarantine file.
+func Fingerprint(g runtime.GoroutineInfo) string {
+	var funcs []string
+	for _, f := range g.Frames {
+		if len(funcs) == fingerprintFrames {
+			break
+		}
+		if !strings.HasPrefix(f.Function, "runtime.") {
+			funcs = append(funcs, f.Function)
+		}
+	}
+	if len(funcs) == 0 {
+		funcs = append(funcs, g.TopFunction())
+	}
+	if g.CreatedBy != nil {
+		funcs = append(funcs, "created by "+g.CreatedBy.Function)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(funcs, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// quarantineEntry is a line of a quarantine file
+type quarantineEntry struct {
+	fingerprint string
+	expires     time.Time // the last day the entry applies
+	owner       string
+	reason      string
+}
+
+// expired reports whether now is past the end of the entry's expiry day
+func (e quarantineEntry) expired(now time.Time) bool {
+	return !now.Before(e.expires.AddDate(0, 0, 1))
+}
+
+func (e quarantineEntry) String() string {
+	s := e.fingerprint + " until " + e.expires.Format(quarantineDateLayout)
+	if e.owner != "" {
+		s += ", owner " + e.owner
+	}
+	if e.reason != "" {
+		s += ": " + e.reason
+	}
+	return s
+}
+
+// parseQuarantine reads a quarantine file: one fingerprint per line with
+// a required expires= date and optional owner= and "-- reason". Blank
+// lines and lines starting with # are skipped.
+func parseQuarantine(r io.Reader) ([]quarantineEntry, error) {
+	var entries []quarantineEntry
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var e quarantineEntry
+		if i := strings.Index(line, "--"); i >= 0 {
+			e.reason = strings.TrimSpace(line[i+2:])
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("line %d: missing fingerprint", n)
+		}
+		e.fingerprint = fields[0]
+		for _, field := range fields[1:] {
+			key, value, _ := strings.Cut(field, "=")
+			switch key {
+			case "expires":
+				t, err := time.Parse(quarantineDateLayout, value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid expires=%q, want YYYY-MM-DD", n, value)
+				}
+				e.expires = t
+			case "owner":
+				e.owner = value
+			default:
+				return nil, fmt.Errorf("line %d: unknown field %q", n, field)
+			}
+		}
+		if e.expires.IsZero() {
+			return nil, fmt.Errorf("line %d: %s has no expires= date", n, e.fingerprint)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Quarantine ignores leaked goroutines whose Fingerprint is listed in the
+// file at path until the entry expires, for known flaky leaks that are
+// tracked but not yet fixed. Each line holds a fingerprint, its last day
+// and optionally an owner and a reason:
+//
+//	# fingerprint    expiry            owner            reason
+//	3f2a9c0d1e4b5a67 expires=2026-12-31 owner=@platform -- grpc keepalive, see #412
+//
+// Quarantined leaks are logged. Once an entry expires the leak fails the
+// test again, noting the expired entry. A file that cannot be read or
+// parsed fails the test.
+//
+//	defer guard.VerifyNone(t, guard.Quarantine("testdata/heapcheck-quarantine.txt"))
+func Quarantine(path string) Option {
+	return func(c *config) {
+		f, err := os.Open(path)
+		if err != nil {
+			c.optionErrs = append(c.optionErrs, fmt.Errorf("reading quarantine: %w", err))
+			return
+		}
+		defer f.Close()
+		entries, err := parseQuarantine(f)
+		if err != nil {
+			c.optionErrs = append(c.optionErrs, fmt.Errorf("reading quarantine %s: %w", path, err))
+			return
+		}
+		c.quarantine = append(c.quarantine, entries...)
+	}
+}
+
+// quarantineOf returns the entry listing the fingerprint, preferring an
+// unexpired one, and whether there is one
+func quarantineOf(fingerprint string, entries []quarantineEntry, now time.Time) (quarantineEntry, bool) {
+	var found *quarantineEntry
+	for i, e := range entries {
+		if e.fingerprint != fingerprint {
+			continue
+		}
+		if !e.expired(now) {
+			return e, true
+		}
+		if found == nil {
+			found = &entries[i]
+		}
+	}
+	if found == nil {
+		return quarantineEntry{}, false
+	}
+	return *found, true
+}
+
+// splitQuarantined takes the goroutines of unexpired quarantine entries out
+// of leaked, returning the rest and the quarantined ones
+func splitQuarantined(leaked []runtime.GoroutineInfo, entries []quarantineEntry, now time.Time) ([]runtime.GoroutineInfo, []runtime.GoroutineInfo) {
+	if len(entries) == 0 {
+		return leaked, nil
+	}
+	var rest, quarantined []runtime.GoroutineInfo
+	for _, g := range leaked {
+		if e, ok := quarantineOf(Fingerprint(g), entries, now); ok && !e.expired(now) {
+			quarantined = append(quarantined, g)
+			continue
+		}
+		rest = append(rest, g)
+	}
+	return rest, quarantined
+}
+
+// formatExpired notes the expired quarantine entries of leaked goroutines
+func formatExpired(leaked []runtime.GoroutineInfo, entries []quarantineEntry, now time.Time) string {
+	var sb strings.Builder
+	for _, g := range leaked {
+		if e, ok := quarantineOf(Fingerprint(g), entries, now); ok && e.expired(now) {
+			fmt.Fprintf(&sb, "\n  quarantine expired: %s", e)
+		}
+	}
+	return sb.String()
+}
+
+// formatQuarantined formats the quarantined goroutines for the test log,
+// with the entries that let them pass
+func formatQuarantined(quarantined []runtime.GoroutineInfo, entries []quarantineEntry, now time.Time) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "heapcheck: %d quarantined goroutine leak(s) ignored", len(quarantined))
+	for _, g := range quarantined {
+		e, _ := quarantineOf(Fingerprint(g), entries, now)
+		fmt.Fprintf(&sb, "\n  %s in %s", e, g.TopFunction())
+	}
+	return sb.String()
+}
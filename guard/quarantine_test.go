@@ -0,0 +1,114 @@
+package guard
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harshakonda/heapcheck/runtime"
+	"github.com/harshakonda/heapcheck/runtime/stackparse"
+)
+
+func TestFingerprint(t *testing.T) {
+	leak := runtime.GoroutineInfo{
+		ID: 7,
+		Frames: []stackparse.Frame{
+			{Function: "runtime.gopark", File: "/go/src/runtime/proc.go", Line: 398},
+			{Function: "runtime.chanrecv1", File: "/go/src/runtime/chan.go", Line: 442},
+			{Function: "example.com/app.(*Pool).worker", File: "/app/pool.go", Line: 40},
+			{Function: "example.com/app.(*Pool).run", File: "/app/pool.go", Line: 30},
+		},
+		CreatedBy: &stackparse.Frame{Function: "example.com/app.NewPool", File: "/app/pool.go", Line: 20},
+	}
+	fp := Fingerprint(leak)
+	if len(fp) != 16 {
+		t.Errorf("Fingerprint() = %q, want 16 hex digits", fp)
+	}
+
+	// IDs, lines and runtime frames do not change it
+	moved := leak
+	moved.ID = 99
+	moved.Frames = []stackparse.Frame{
+		{Function: "runtime.gopark", File: "/go/src/runtime/proc.go", Line: 402},
+		{Function: "runtime.chanrecv", File: "/go/src/runtime/chan.go", Line: 583},
+		{Function: "runtime.chanrecv1", File: "/go/src/runtime/chan.go", Line: 442},
+		{Function: "example.com/app.(*Pool).worker", File: "/app/pool.go", Line: 44},
+		{Function: "example.com/app.(*Pool).run", File: "/app/pool.go", Line: 31},
+	}
+	if got := Fingerprint(moved); got != fp {
+		t.Errorf("Fingerprint() of the moved goroutine = %s, want %s", got, fp)
+	}
+
+	other := leak
+	other.CreatedBy = &stackparse.Frame{Function: "example.com/app.Restart"}
+	if Fingerprint(other) == fp {
+		t.Error("Fingerprint() is the same for another creator")
+	}
+}
+
+func TestParseQuarantine(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    []string // String() of each entry
+		wantErr string
+	}{
+		{
+			name: "entries",
+			file: "# known leaks\n\n3f2a9c0d1e4b5a67 expires=2026-12-31 owner=@platform -- grpc keepalive, see #412\n0011223344556677 expires=2027-01-15\n",
+			want: []string{"3f2a9c0d1e4b5a67 until 2026-12-31, owner @platform: grpc keepalive, see #412", "0011223344556677 until 2027-01-15"},
+		},
+		{name: "no expiry", file: "3f2a9c0d1e4b5a67 owner=@platform\n", wantErr: "line 1: 3f2a9c0d1e4b5a67 has no expires= date"},
+		{name: "bad date", file: "# leaks\n3f2a9c0d1e4b5a67 expires=31/12/2026\n", wantErr: `line 2: invalid expires="31/12/2026"`},
+		{name: "unknown field", file: "3f2a9c0d1e4b5a67 expires=2026-12-31 until=never\n", wantErr: `unknown field "until=never"`},
+		{name: "reason only", file: "-- flaky\n", wantErr: "line 1: missing fingerprint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := parseQuarantine(strings.NewReader(tt.file))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseQuarantine() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("parseQuarantine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitQuarantined(t *testing.T) {
+	a := runtime.GoroutineInfo{ID: 1, Frames: []stackparse.Frame{{Function: "example.com/app.a"}}}
+	b := runtime.GoroutineInfo{ID: 2, Frames: []stackparse.Frame{{Function: "example.com/app.b"}}}
+	c := runtime.GoroutineInfo{ID: 3, Frames: []stackparse.Frame{{Function: "example.com/app.c"}}}
+	entries, err := parseQuarantine(strings.NewReader(
+		Fingerprint(a) + " expires=2026-03-31\n" + Fingerprint(b) + " expires=2026-03-01 owner=@db\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An entry applies through its expiry day
+	now := time.Date(2026, 3, 1, 23, 59, 0, 0, time.UTC)
+	rest, quarantined := splitQuarantined([]runtime.GoroutineInfo{a, b, c}, entries, now)
+	if len(rest) != 1 || rest[0].ID != 3 || len(quarantined) != 2 {
+		t.Errorf("splitQuarantined() on the expiry day = %v, %v; want c left", rest, quarantined)
+	}
+
+	now = now.Add(time.Minute)
+	rest, quarantined = splitQuarantined([]runtime.GoroutineInfo{a, b, c}, entries, now)
+	if len(rest) != 2 || rest[0].ID != 2 || len(quarantined) != 1 {
+		t.Errorf("splitQuarantined() after b expired = %v, %v; want b and c left", rest, quarantined)
+	}
+	if got, want := formatExpired(rest, entries, now), "quarantine expired: "+Fingerprint(b)+" until 2026-03-01, owner @db"; !strings.Contains(got, want) {
+		t.Errorf("formatExpired() = %q, want %q", got, want)
+	}
+}
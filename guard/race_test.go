@@ -0,0 +1,49 @@
+package guard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/harshakonda/heapcheck/runtime"
+)
+
+func TestRelaxForRace(t *testing.T) {
+	localDefaults(t)
+	cfg := defaultConfig()
+	MaxHeapMB(10)(cfg)
+	MaxHeapObjects(500)(cfg)
+	MaxMallocs(1000)(cfg)
+	cfg.relaxForRace(false)
+	if cfg.settleTime != 100*time.Millisecond || cfg.maxHeapMB != 10 || cfg.maxHeapObjects != 500 || cfg.maxMallocs != 1000 || cfg.raceNote() != "" {
+		t.Errorf("relaxForRace(false) changed the limits: %+v", cfg)
+	}
+
+	cfg.relaxForRace(true)
+	if cfg.settleTime != 500*time.Millisecond || cfg.maxHeapMB != 20 || cfg.maxHeapObjects != 1000 || cfg.maxMallocs != 2000 {
+		t.Errorf("relaxForRace(true) = settle %v, heap %d MB, objects %d, mallocs %d; want 500ms, 20 MB, 1000, 2000",
+			cfg.settleTime, cfg.maxHeapMB, cfg.maxHeapObjects, cfg.maxMallocs)
+	}
+	if cfg.raceNote() == "" {
+		t.Error("raceNote() is empty for relaxed limits")
+	}
+
+	// Unlimited stays unlimited, and custom multipliers apply
+	cfg = defaultConfig()
+	RaceMultipliers(1.5, 3)(cfg)
+	cfg.relaxForRace(true)
+	if cfg.settleTime != 150*time.Millisecond || cfg.maxHeapMB != 0 || cfg.maxMallocs != 0 {
+		t.Errorf("relaxForRace(true) with RaceMultipliers(1.5, 3) = settle %v, heap %d MB, mallocs %d; want 150ms, 0, 0",
+			cfg.settleTime, cfg.maxHeapMB, cfg.maxMallocs)
+	}
+}
+
+func TestNewConfigRace(t *testing.T) {
+	cfg := newConfig([]Option{SettleTime(10 * time.Millisecond)})
+	want := 10 * time.Millisecond
+	if runtime.RaceEnabled {
+		want *= runtime.DefaultRaceSettleMultiplier
+	}
+	if cfg.settleTime != want || cfg.race != runtime.RaceEnabled {
+		t.Errorf("newConfig() settle = %v, race = %v; want %v, %v", cfg.settleTime, cfg.race, want, runtime.RaceEnabled)
+	}
+}
@@ -0,0 +1,112 @@
+package guard
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/harshakonda/heapcheck/runtime"
+)
+
+// summaryLimit is how many tests the VerifyTestMain summary lists
+const summaryLimit = 10
+
+// summaryMinHeap is the heap growth below which a test is not listed:
+// smaller changes are usually GC timing, not the test
+const summaryMinHeap = 64 * 1024
+
+// testGrowth is what one verification of a test found
+type testGrowth struct {
+	name          string
+	goroutines    int
+	maxGoroutines int
+	heapBytes     int64
+	maxHeapMB     int
+	exceeded      bool
+}
+
+// pressure ranks how close a test came to its limits: the larger of its
+// goroutine and heap growth as a fraction of the allowed growth, or of
+// one goroutine or MB when unlimited
+func (g testGrowth) pressure() float64 {
+	gr := float64(g.goroutines) / float64(max(g.maxGoroutines, 1))
+	hp := float64(g.heapBytes) / 1024 / 1024 / float64(max(g.maxHeapMB, 1))
+	return max(gr, hp)
+}
+
+var growth struct {
+	sync.Mutex
+	tests []testGrowth
+}
+
+// recordGrowth remembers the outcome of verifying t for the summary
+// VerifyTestMain prints, and for heapcheck testreport
+func recordGrowth(t TestingT, cfg *config, diff *runtime.Diff, leaked []runtime.GoroutineInfo, exceeded bool) {
+	name := testName(t)
+	if name == "" || diff == nil {
+		return
+	}
+	writeTestReport(cfg, name, diff, leaked, exceeded)
+	growth.Lock()
+	defer growth.Unlock()
+	growth.tests = append(growth.tests, testGrowth{
+		name:          name,
+		goroutines:    len(leaked),
+		maxGoroutines: cfg.maxGoroutines,
+		heapBytes:     diff.HeapGrowth(cfg.heapMetric),
+		maxHeapMB:     cfg.maxHeapMB,
+		exceeded:      exceeded,
+	})
+}
+
+// recordedGrowth returns and clears the recorded outcomes
+func recordedGrowth() []testGrowth {
+	growth.Lock()
+	defer growth.Unlock()
+	tests := growth.tests
+	growth.tests = nil
+	return tests
+}
+
+// writeSummary lists the tests that grew goroutines or the heap, over
+// their limits or not, closest to their limits first, so tests drifting
+// towards a threshold are visible before they fail
+func writeSummary(w io.Writer, tests []testGrowth) {
+	var grew []testGrowth
+	for _, g := range tests {
+		if g.exceeded || g.goroutines > 0 || g.heapBytes >= summaryMinHeap {
+			grew = append(grew, g)
+		}
+	}
+	if len(grew) == 0 {
+		return
+	}
+	sort.SliceStable(grew, func(i, j int) bool {
+		if grew[i].exceeded != grew[j].exceeded {
+			return grew[i].exceeded
+		}
+		return grew[i].pressure() > grew[j].pressure()
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\nheapcheck: %d of %d verified test(s) grew goroutines or heap\n", len(grew), len(tests))
+	for i, g := range grew {
+		if i >= summaryLimit {
+			fmt.Fprintf(&b, "  ... and %d more\n", len(grew)-summaryLimit)
+			break
+		}
+		heapLimit := "no limit"
+		if g.maxHeapMB > 0 {
+			heapLimit = fmt.Sprintf("max %d MB", g.maxHeapMB)
+		}
+		status := ""
+		if g.exceeded {
+			status = "  over limit"
+		}
+		fmt.Fprintf(&b, "  %-40s goroutines %+d (max %d)  heap %+.2f MB (%s)%s\n",
+			g.name, g.goroutines, g.maxGoroutines, float64(g.heapBytes)/1024/1024, heapLimit, status)
+	}
+	io.WriteString(w, b.String())
+}
@@ -0,0 +1,79 @@
+package guard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteSummary(t *testing.T) {
+	tests := []testGrowth{
+		{name: "TestQuiet", heapBytes: 1024},
+		{name: "TestNearHeapLimit", heapBytes: 45 << 20, maxHeapMB: 50},
+		{name: "TestOneGoroutine", goroutines: 1, maxGoroutines: 5, heapBytes: 128 << 10},
+		{name: "TestLeaky", goroutines: 3, exceeded: true},
+	}
+	var buf bytes.Buffer
+	writeSummary(&buf, tests)
+	out := buf.String()
+
+	if !strings.Contains(out, "3 of 4 verified test(s) grew") {
+		t.Errorf("summary missing the count:\n%s", out)
+	}
+	if strings.Contains(out, "TestQuiet") {
+		t.Errorf("summary lists a test within GC noise:\n%s", out)
+	}
+	order := []string{"TestLeaky", "TestNearHeapLimit", "TestOneGoroutine"}
+	last := -1
+	for _, name := range order {
+		i := strings.Index(out, name)
+		if i < last {
+			t.Errorf("%s listed out of order:\n%s", name, out)
+		}
+		last = i
+	}
+	if !strings.Contains(out, "heap +45.00 MB (max 50 MB)") || !strings.Contains(out, "goroutines +3 (max 0)  heap +0.00 MB (no limit)  over limit") {
+		t.Errorf("unexpected summary lines:\n%s", out)
+	}
+}
+
+func TestWriteSummaryNothingGrew(t *testing.T) {
+	var buf bytes.Buffer
+	writeSummary(&buf, []testGrowth{{name: "TestQuiet"}})
+	if buf.Len() != 0 {
+		t.Errorf("expected no summary, got:\n%s", buf.String())
+	}
+}
+
+func TestRecordGrowth(t *testing.T) {
+	recordedGrowth()
+	mock := &namedT{name: "TestMock"}
+	VerifyNone(mock, SettleTime(0), RetryCount(1))
+	mock.runCleanups()
+
+	got := recordedGrowth()
+	if len(got) != 1 || got[0].name != "TestMock" || got[0].exceeded {
+		t.Errorf("recorded = %+v, want one passing TestMock", got)
+	}
+	if len(recordedGrowth()) != 0 {
+		t.Error("recordedGrowth did not clear the records")
+	}
+}
+
+// namedT is a passing TestingT with a name
+type namedT struct {
+	name     string
+	cleanups []func()
+}
+
+func (m *namedT) Errorf(format string, args ...interface{}) {}
+func (m *namedT) Logf(format string, args ...interface{})   {}
+func (m *namedT) Helper()                                   {}
+func (m *namedT) Name() string                              { return m.name }
+func (m *namedT) Cleanup(f func())                          { m.cleanups = append(m.cleanups, f) }
+
+func (m *namedT) runCleanups() {
+	for _, f := range m.cleanups {
+		f()
+	}
+}
@@ -0,0 +1,53 @@
+package guard
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/testreport"
+	"github.com/harshakonda/heapcheck/runtime"
+)
+
+// TestReportEnv names the environment variable `heapcheck testreport`
+// sets to a file. Every verification of a named test appends a JSON line
+// with its growth to it, over its limits or not.
+const TestReportEnv = testreport.Env
+
+var testReportMu sync.Mutex
+
+// writeTestReport appends the growth of one verification to the
+// configured test report file. Like leak events, errors are ignored.
+func writeTestReport(cfg *config, name string, diff *runtime.Diff, leaked []runtime.GoroutineInfo, exceeded bool) {
+	if cfg.testReport == "" {
+		return
+	}
+	g := testreport.Growth{
+		Time:            time.Now(),
+		Package:         testPackage(),
+		Test:            name,
+		Goroutines:      len(leaked),
+		HeapGrowthBytes: diff.HeapGrowth(cfg.heapMetric),
+		HeapObjects:     diff.HeapGrowthObjects,
+		Mallocs:         diff.Mallocs,
+		Exceeded:        exceeded,
+	}
+	if cfg.heapMetric != runtime.HeapMetricAlloc {
+		g.HeapMetric = cfg.heapMetric.String()
+	}
+	data, err := json.Marshal(g)
+	if err != nil {
+		return
+	}
+
+	testReportMu.Lock()
+	defer testReportMu.Unlock()
+
+	f, err := os.OpenFile(cfg.testReport, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(append(data, '\n'))
+}
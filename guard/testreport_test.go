@@ -0,0 +1,52 @@
+package guard_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harshakonda/heapcheck/guard"
+)
+
+func TestTestReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "growth.json")
+	t.Setenv(guard.TestReportEnv, path)
+
+	// Within the limits, the verification is still written
+	mock := &mockT{}
+	goruntime.GC()
+	guard.VerifyNone(mock, guard.SettleTime(10*time.Millisecond), guard.RetryCount(1))
+	retained = append(retained, make([]byte, 4<<20))
+	mock.runCleanups()
+	if len(mock.errors) > 0 {
+		t.Fatalf("unexpected errors: %v", mock.errors)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading test report: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line, got %d:\n%s", len(lines), data)
+	}
+	var g struct {
+		Package         string `json:"package"`
+		Test            string `json:"test"`
+		HeapGrowthBytes int64  `json:"heapGrowthBytes"`
+		Exceeded        bool   `json:"exceeded"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &g); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if g.Test != "TestMock" || g.Package != "github.com/harshakonda/heapcheck/guard" || g.Exceeded {
+		t.Errorf("unexpected growth: %+v", g)
+	}
+	if g.HeapGrowthBytes < 2<<20 {
+		t.Errorf("HeapGrowthBytes = %d, want about the 4 MB retained", g.HeapGrowthBytes)
+	}
+}
@@ -0,0 +1,139 @@
+// Package heapcheck is the library API for Go escape analysis.
+//
+// It runs the same analysis as the heapcheck CLI and returns structured
+// results, for tools that want to build on escape data.
+//
+// Basic Usage:
+//
+//	import "github.com/harshakonda/heapcheck"
+//
+//	results, err := heapcheck.Analyze("./...")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, e := range results.Escapes {
+//	    fmt.Println(e.Info.File, e.Info.Line, e.Category)
+//	}
+//
+// Servers embedding heapcheck can bound an analysis with a context;
+// canceling it stops the compiler:
+//
+//	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+//	defer cancel()
+//	results, err := heapcheck.AnalyzeContext(ctx, "./...")
+//	if errors.Is(err, context.DeadlineExceeded) {
+//	    // the analysis took too long
+//	}
+//
+// Querying Escape Flows:
+//
+// With -m=2 details, each escape carries its flow graph. Select escapes by
+// how they escape, not only by category label:
+//
+//	boxed := results.FlowsInto(heapcheck.SinkInterface)
+//	for _, e := range boxed {
+//	    fmt.Println(e.Info.Variable, heapcheck.SinksFor(e))
+//	}
+//
+//	sent, err := heapcheck.Where(results, "sink=channel")
+//
+// Custom Categories:
+//
+// Register a Categorizer to encode framework-specific knowledge. It is
+// consulted before the built-in rules:
+//
+//	heapcheck.RegisterCategorizer(heapcheck.CategorizerFunc(
+//	    func(e heapcheck.EscapeInfo) (heapcheck.Category, heapcheck.Suggestion, bool) {
+//	        if strings.Contains(e.Reason, "gin.Context") {
+//	            return "gin-context", heapcheck.Suggestion{Short: "Copy values out of gin.Context"}, true
+//	        }
+//	        return "", heapcheck.Suggestion{}, false
+//	    }))
+package heapcheck
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+	"github.com/harshakonda/heapcheck/internal/query"
+)
+
+// Result types, shared with the CLI's JSON output
+type (
+	Results    = categorizer.Results
+	Escape     = categorizer.CategorizedEscape
+	EscapeInfo = parser.EscapeInfo
+	Category   = categorizer.Category
+	Suggestion = categorizer.Suggestion
+	Flow       = parser.Flow
+	FlowStep   = parser.FlowStep
+	Sink       = parser.Sink
+)
+
+// Categorizer types for custom categories
+type (
+	Categorizer     = categorizer.Categorizer
+	CategorizerFunc = categorizer.CategorizerFunc
+)
+
+// Sinks describe how a value escapes
+const (
+	SinkHeap      = parser.SinkHeap
+	SinkReturn    = parser.SinkReturn
+	SinkInterface = parser.SinkInterface
+	SinkClosure   = parser.SinkClosure
+	SinkChannel   = parser.SinkChannel
+	SinkCall      = parser.SinkCall
+	SinkAssign    = parser.SinkAssign
+	SinkSpill     = parser.SinkSpill
+)
+
+// Analyze compiles the packages matching patterns with escape analysis
+// enabled and returns categorized results. Like the CLI, it skips
+// vendored, module cache and cgo-generated files.
+func Analyze(patterns ...string) (*Results, error) {
+	return AnalyzeContext(context.Background(), patterns...)
+}
+
+// AnalyzeContext is Analyze with a context: canceling ctx stops the
+// compiler processes and the parsing, and returns an error wrapping
+// ctx's error.
+func AnalyzeContext(ctx context.Context, patterns ...string) (*Results, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	rawOutput, err := parser.RunCompiler(ctx, patterns)
+	if err != nil {
+		return nil, fmt.Errorf("running compiler: %w", err)
+	}
+	escapes, err := parser.ParseContext(ctx, rawOutput)
+	if err != nil {
+		return nil, fmt.Errorf("parsing output: %w", err)
+	}
+	return categorizer.Categorize(parser.SkipThirdParty(escapes)), nil
+}
+
+// RegisterCategorizer adds a categorizer consulted by Analyze before the
+// built-in rules. Categorizers are tried in registration order.
+func RegisterCategorizer(c Categorizer) {
+	categorizer.Register(c)
+}
+
+// SinksFor returns how e escapes, derived from its flow graph
+func SinksFor(e Escape) []Sink {
+	return e.Info.Sinks()
+}
+
+// Where returns the escapes in results matching a query such as
+// "sink=channel" or "sink=interface|closure,category=fmt-call".
+// Supported keys are sink, category and file (a path prefix).
+func Where(results *Results, expr string) ([]Escape, error) {
+	q, err := query.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return q.Filter(results), nil
+}
@@ -0,0 +1,61 @@
+package heapcheck_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/harshakonda/heapcheck"
+)
+
+func TestAnalyze(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiler run in short mode")
+	}
+
+	results, err := heapcheck.Analyze("./examples/http-server")
+	if err != nil {
+		t.Fatalf("Analyze() error: %v", err)
+	}
+	if len(results.Escapes) == 0 {
+		t.Fatal("Analyze() found no escapes in examples/http-server")
+	}
+
+	closures := results.FlowsInto(heapcheck.SinkClosure)
+	if len(closures) == 0 {
+		t.Error("FlowsInto(closure) found no escapes, want the logging middleware")
+	}
+	for _, e := range closures {
+		found := false
+		for _, s := range heapcheck.SinksFor(e) {
+			if s == heapcheck.SinkClosure {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("SinksFor(%s) = %v, missing closure", e.Info.Variable, heapcheck.SinksFor(e))
+		}
+	}
+
+	matched, err := heapcheck.Where(results, "sink=closure")
+	if err != nil {
+		t.Fatalf("Where() error: %v", err)
+	}
+	if len(matched) != len(closures) {
+		t.Errorf("Where(sink=closure) = %d escapes, FlowsInto = %d", len(matched), len(closures))
+	}
+
+	if _, err := heapcheck.Where(results, "sink=disk"); err == nil {
+		t.Error("Where() expected error for unknown sink")
+	}
+}
+
+func TestAnalyzeContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := heapcheck.AnalyzeContext(ctx, "./examples/http-server")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("AnalyzeContext(canceled) = %v, %v, want context.Canceled", results, err)
+	}
+}
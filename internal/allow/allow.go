@@ -0,0 +1,251 @@
+// Package allow drops escapes that are accepted library behavior: those
+// inside allowed functions, or caused by calls into them.
+//
+// Functions are named as in compiler and profiler output, e.g.
+// "encoding/json.Marshal" or "bytes.(*Buffer).Grow". A pattern may leave
+// out the leading package path ("(*Buffer).Grow", "json.Marshal") and may
+// end in * to match every name with that prefix ("encoding/json.*").
+package allow
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/typecheck"
+)
+
+// Validate checks that every pattern is non-empty and uses * only as a
+// trailing wildcard or in a pointer receiver such as "(*Buffer)"
+func Validate(patterns []string) error {
+	for _, p := range patterns {
+		if strings.TrimSpace(p) == "" {
+			return fmt.Errorf("empty allow pattern")
+		}
+		rest := strings.TrimSuffix(strings.ReplaceAll(p, "(*", "("), "*")
+		if strings.Contains(rest, "*") {
+			return fmt.Errorf("invalid allow pattern %q: only a trailing * is supported", p)
+		}
+	}
+	return nil
+}
+
+// Match reports whether the function name matches pattern. The pattern
+// matches from the start of name or after a "." or "/", so "json.Marshal"
+// matches "encoding/json.Marshal" but not "encoding/myjson.Marshal".
+func Match(pattern, name string) bool {
+	prefix, wildcard := strings.CutSuffix(pattern, "*")
+	for i := 0; i+len(prefix) <= len(name); i++ {
+		if i > 0 && name[i-1] != '.' && name[i-1] != '/' {
+			continue
+		}
+		if !strings.HasPrefix(name[i:], prefix) {
+			continue
+		}
+		if wildcard || i+len(prefix) == len(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAny reports whether name matches one of patterns
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if Match(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply type-checks the packages matching pkgPatterns and removes from
+// results the escapes inside or caused by calls into functions matching
+// allowed, adjusting the counts. It returns how many escapes it removed.
+func Apply(pkgPatterns, allowed []string, results *categorizer.Results) (int, error) {
+	if len(allowed) == 0 {
+		return 0, nil
+	}
+	return ApplyScoped(pkgPatterns, func(string) []string { return allowed }, results)
+}
+
+// ApplyScoped is Apply with the allowed functions of each escape given by
+// its file, e.g. from the configs of the directories around it
+func ApplyScoped(pkgPatterns []string, allowed func(file string) []string, results *categorizer.Results) (int, error) {
+	patterns := make([][]string, len(results.Escapes))
+	some := false
+	for i, e := range results.Escapes {
+		patterns[i] = allowed(e.Info.File)
+		some = some || len(patterns[i]) > 0
+	}
+	if !some {
+		return 0, nil
+	}
+
+	files := make(map[string]bool)
+	for _, e := range results.Escapes {
+		files[abs(e.Info.File)] = true
+		for _, f := range e.Info.Flows {
+			for _, step := range f.Steps {
+				if step.File != "" {
+					files[abs(step.File)] = true
+				}
+			}
+		}
+	}
+	fset := token.NewFileSet()
+	pkgs, err := typecheck.Check(fset, pkgPatterns, files)
+	if err != nil {
+		return 0, err
+	}
+	idx := newIndex(fset, pkgs)
+
+	removed := 0
+	kept := results.Escapes[:0]
+	for i, e := range results.Escapes {
+		if len(patterns[i]) == 0 || !idx.allowed(patterns[i], e) {
+			kept = append(kept, e)
+			continue
+		}
+		removed++
+		results.Summary.HeapAllocated--
+		results.Summary.Allowed++
+		decrement(results.Summary.ByFile, e.Info.File)
+		decrement(results.Summary.ByEscapeType, e.Info.EscapeType.String())
+		if results.ByCategory[e.Category]--; results.ByCategory[e.Category] <= 0 {
+			delete(results.ByCategory, e.Category)
+		}
+	}
+	results.Escapes = kept
+	return removed, nil
+}
+
+// position is a file:line:column key
+type position struct {
+	file      string
+	line, col int
+}
+
+// funcSpan is a top-level function declaration and the lines it covers
+type funcSpan struct {
+	name       string
+	start, end token.Position
+}
+
+// index resolves positions to the functions declared around them and to
+// the functions called there
+type index struct {
+	funcs   map[string][]funcSpan // by file
+	callees map[position]string   // by the call's opening parenthesis
+}
+
+func newIndex(fset *token.FileSet, pkgs []*typecheck.Package) *index {
+	idx := &index{
+		funcs:   make(map[string][]funcSpan),
+		callees: make(map[position]string),
+	}
+	for _, p := range pkgs {
+		for _, f := range p.Files {
+			file := fset.Position(f.Pos()).Filename
+			for _, d := range f.Decls {
+				decl, ok := d.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+				if fn, ok := p.Info.Defs[decl.Name].(*types.Func); ok {
+					idx.funcs[file] = append(idx.funcs[file], funcSpan{
+						name:  funcName(fn),
+						start: fset.Position(decl.Pos()),
+						end:   fset.Position(decl.End()),
+					})
+				}
+			}
+			ast.Inspect(f, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				if fn := typecheck.Callee(p.Info, call.Fun); fn != nil {
+					pos := fset.Position(call.Lparen)
+					idx.callees[position{file, pos.Line, pos.Column}] = funcName(fn)
+				}
+				return true
+			})
+		}
+	}
+	return idx
+}
+
+// allowed reports whether e occurs inside an allowed function, at a call
+// to one (as escapes of inlined calls are reported), or flows into one as
+// a call parameter
+func (idx *index) allowed(patterns []string, e categorizer.CategorizedEscape) bool {
+	file := abs(e.Info.File)
+	for _, fn := range idx.funcs[file] {
+		if typecheck.Contains(fn.start, fn.end, e.Info.Line, e.Info.Column) && matchAny(patterns, fn.name) {
+			return true
+		}
+	}
+	if name, ok := idx.callees[position{file, e.Info.Line, e.Info.Column}]; ok && matchAny(patterns, name) {
+		return true
+	}
+	for _, f := range e.Info.Flows {
+		for _, step := range f.Steps {
+			if step.Reason != "call parameter" || step.File == "" {
+				continue
+			}
+			if name, ok := idx.callees[position{abs(step.File), step.Line, step.Column}]; ok && matchAny(patterns, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// funcName names fn like the compiler does: "encoding/json.Marshal" or
+// "bytes.(*Buffer).Grow"
+func funcName(fn *types.Func) string {
+	prefix := ""
+	if fn.Pkg() != nil {
+		prefix = fn.Pkg().Path() + "."
+	}
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return prefix + fn.Name()
+	}
+	t := recv.Type()
+	ptr := false
+	if p, ok := t.(*types.Pointer); ok {
+		t, ptr = p.Elem(), true
+	}
+	name := t.String()
+	if n, ok := t.(*types.Named); ok {
+		name = n.Obj().Name()
+	}
+	if ptr {
+		name = "(*" + name + ")"
+	}
+	return prefix + name + "." + fn.Name()
+}
+
+func abs(path string) string {
+	if a, err := filepath.Abs(path); err == nil {
+		return a
+	}
+	return path
+}
+
+// decrement lowers a count, deleting it at zero; a nil map, as in
+// results saved before it was counted, is left alone
+func decrement(m map[string]int, key string) {
+	if m == nil {
+		return
+	}
+	if m[key]--; m[key] <= 0 {
+		delete(m, key)
+	}
+}
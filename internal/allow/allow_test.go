@@ -0,0 +1,131 @@
+package allow
+
+import (
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+const sampleFile = "testdata/sample/sample.go"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"encoding/json.Marshal", "encoding/json.Marshal", true},
+		{"json.Marshal", "encoding/json.Marshal", true},
+		{"json.Marshal", "encoding/myjson.Marshal", false},
+		{"json.Marshal", "encoding/json.MarshalIndent", false},
+		{"encoding/json.*", "encoding/json.(*Encoder).Encode", true},
+		{"encoding/json.*", "encoding/jsonx.Marshal", false},
+		{"(*Buffer).Grow", "bytes.(*Buffer).Grow", true},
+		{"bytes.(*Buffer).*", "bytes.(*Buffer).WriteString", true},
+		{"Grow", "bytes.(*Buffer).Grow", true},
+		{"Grow", "example.com/app.Regrow", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate([]string{"(*Buffer).Grow", "encoding/json.*", "fmt.Sprint"}); err != nil {
+		t.Errorf("Validate() error: %v", err)
+	}
+	for _, p := range []string{"", "  ", "encoding/*.Marshal", "*.Grow"} {
+		if err := Validate([]string{p}); err == nil {
+			t.Errorf("Validate(%q) expected error", p)
+		}
+	}
+}
+
+func escape(line, col int, variable string, cat categorizer.Category, steps ...parser.FlowStep) categorizer.CategorizedEscape {
+	e := categorizer.CategorizedEscape{
+		Info: parser.EscapeInfo{
+			File: sampleFile, Line: line, Column: col,
+			EscapeType: parser.EscapesToHeap, Variable: variable,
+		},
+		Category: cat,
+	}
+	if len(steps) > 0 {
+		e.Info.Flows = []parser.Flow{{Dst: "{heap}", Steps: steps}}
+	}
+	return e
+}
+
+func callParam(line, col int) parser.FlowStep {
+	return parser.FlowStep{Reason: "call parameter", File: sampleFile, Line: line, Column: col}
+}
+
+func sampleResults() *categorizer.Results {
+	escapes := []categorizer.CategorizedEscape{
+		escape(15, 26, "e", categorizer.CategorySpill, callParam(15, 25)),               // json.Marshal(e)
+		escape(21, 21, "e.Name", categorizer.CategoryFmtCall, callParam(21, 19)),        // fmt.Sprint(e.Name)
+		escape(27, 8, `"bytes.Buffer.Grow: negative count"`, categorizer.CategorySpill), // inlined b.Grow
+		escape(26, 6, "b", categorizer.CategoryReturnPointer),                           // inside Grow
+	}
+	results := &categorizer.Results{
+		Escapes:    escapes,
+		ByCategory: make(map[categorizer.Category]int),
+	}
+	results.Summary.HeapAllocated = len(escapes)
+	results.Summary.ByFile = map[string]int{sampleFile: len(escapes)}
+	for _, e := range escapes {
+		results.ByCategory[e.Category]++
+	}
+	return results
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		kept    []string
+	}{
+		{"call parameter", []string{"encoding/json.*"}, []string{"e.Name", `"bytes.Buffer.Grow: negative count"`, "b"}},
+		{"inlined call", []string{"(*Buffer).Grow"}, []string{"e", "e.Name", "b"}},
+		{"enclosing function", []string{"sample.Grow"}, []string{"e", "e.Name"}},
+		{"several", []string{"json.Marshal", "fmt.Sprint"}, []string{`"bytes.Buffer.Grow: negative count"`, "b"}},
+		{"none", nil, []string{"e", "e.Name", `"bytes.Buffer.Grow: negative count"`, "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := sampleResults()
+			removed, err := Apply([]string{"./testdata/sample"}, tt.allowed, results)
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if want := 4 - len(tt.kept); removed != want || results.Summary.Allowed != want {
+				t.Errorf("removed = %d, Allowed = %d, want %d", removed, results.Summary.Allowed, want)
+			}
+			if results.Summary.HeapAllocated != len(tt.kept) || results.Summary.ByFile[sampleFile] != len(tt.kept) {
+				t.Errorf("HeapAllocated = %d, ByFile = %d, want %d", results.Summary.HeapAllocated, results.Summary.ByFile[sampleFile], len(tt.kept))
+			}
+			if len(results.Escapes) != len(tt.kept) {
+				t.Fatalf("kept %d escapes, want %d", len(results.Escapes), len(tt.kept))
+			}
+			for i, v := range tt.kept {
+				if got := results.Escapes[i].Info.Variable; got != v {
+					t.Errorf("kept[%d] = %s, want %s", i, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyCategoryCounts(t *testing.T) {
+	results := sampleResults()
+	if _, err := Apply([]string{"./testdata/sample"}, []string{"fmt.*"}, results); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if _, ok := results.ByCategory[categorizer.CategoryFmtCall]; ok {
+		t.Errorf("ByCategory still has fmt-call: %v", results.ByCategory)
+	}
+	if got := results.ByCategory[categorizer.CategorySpill]; got != 2 {
+		t.Errorf("ByCategory[spill] = %d, want 2", got)
+	}
+}
@@ -0,0 +1,29 @@
+package sample
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+type Event struct {
+	Name string
+}
+
+// Encode hands e to encoding/json, which makes it escape
+func Encode(e Event) []byte {
+	data, _ := json.Marshal(e)
+	return data
+}
+
+// Describe boxes its argument for fmt
+func Describe(e Event) string {
+	return fmt.Sprint(e.Name)
+}
+
+// Grow escapes a new buffer through its method
+func Grow() *bytes.Buffer {
+	var b bytes.Buffer
+	b.Grow(64)
+	return &b
+}
@@ -0,0 +1,303 @@
+// Package baseline stores a set of accepted escapes so later runs can
+// tell known escapes from new ones.
+//
+// A baseline file is JSON, written by `heapcheck --write-baseline` and
+// meant to be checked in. Each entry records the date its escape first
+// appeared. Entries may be annotated by hand with an owner and an expiry
+// date, after which the escape resurfaces:
+//
+//	{
+//	  "version": 1,
+//	  "entries": [
+//	    {
+//	      "file": "pkg/server/handler.go",
+//	      "line": 42,
+//	      "variable": "req",
+//	      "category": "interface-boxing",
+//	      "firstSeen": "2024-11-04",
+//	      "owner": "@platform-team",
+//	      "expires": "2025-06-01",
+//	      "reason": "logged once at startup"
+//	    }
+//	  ]
+//	}
+//
+// A baseline may also be loaded from an http or https URL, such as the
+// artifact of the latest main branch build, and from the JSON results of
+// a run, which accept all of their escapes.
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+// HeaderEnv holds a request header sent when loading a baseline from a
+// URL, e.g. "Authorization: Bearer <token>"
+const HeaderEnv = "HEAPCHECK_BASELINE_HEADER"
+
+// fetchTimeout bounds the download of a baseline from a URL
+const fetchTimeout = 30 * time.Second
+
+// Version is the current baseline file format version
+const Version = 1
+
+// DateLayout is the format of firstSeen dates
+const DateLayout = "2006-01-02"
+
+// Entry is a single accepted escape
+type Entry struct {
+	File      string               `json:"file"`
+	Line      int                  `json:"line"`
+	Variable  string               `json:"variable"`
+	Category  categorizer.Category `json:"category"`
+	FirstSeen string               `json:"firstSeen,omitempty"` // YYYY-MM-DD
+	Owner     string               `json:"owner,omitempty"`
+	Expires   string               `json:"expires,omitempty"` // YYYY-MM-DD
+	Reason    string               `json:"reason,omitempty"`
+}
+
+// Key identifies the escape independent of its line number, so entries
+// survive unrelated edits that shift code up or down.
+func (e Entry) Key() string {
+	return e.File + "|" + e.Variable + "|" + string(e.Category)
+}
+
+// Baseline is the content of a baseline file
+type Baseline struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+// KeyOf returns the baseline key of a categorized escape
+func KeyOf(e categorizer.CategorizedEscape) string {
+	return Entry{File: e.Info.File, Variable: e.Info.Variable, Category: e.Category}.Key()
+}
+
+// FromResults creates a baseline accepting every escape in results
+func FromResults(results *categorizer.Results) *Baseline {
+	b := &Baseline{Version: Version, Entries: make([]Entry, 0, len(results.Escapes))}
+	for _, e := range results.Escapes {
+		b.Entries = append(b.Entries, Entry{
+			File:     e.Info.File,
+			Line:     e.Info.Line,
+			Variable: e.Info.Variable,
+			Category: e.Category,
+		})
+	}
+	sort.SliceStable(b.Entries, func(i, j int) bool {
+		if b.Entries[i].File != b.Entries[j].File {
+			return b.Entries[i].File < b.Entries[j].File
+		}
+		return b.Entries[i].Line < b.Entries[j].Line
+	})
+	return b
+}
+
+// Merge carries first-seen dates and owner, expiry and reason
+// annotations from old into b for entries that are still present, so
+// regenerating a baseline keeps escape ages and hand edits.
+func (b *Baseline) Merge(old *Baseline) {
+	annotated := make(map[string]Entry)
+	firstSeen := old.firstSeen()
+	for _, e := range old.Entries {
+		if e.Owner != "" || e.Expires != "" || e.Reason != "" {
+			annotated[e.Key()] = e
+		}
+	}
+	for i, e := range b.Entries {
+		if prev, ok := annotated[e.Key()]; ok {
+			b.Entries[i].Owner = prev.Owner
+			b.Entries[i].Expires = prev.Expires
+			b.Entries[i].Reason = prev.Reason
+		}
+		if date, ok := firstSeen[e.Key()]; ok {
+			b.Entries[i].FirstSeen = date
+		}
+	}
+}
+
+// Add accepts the escape of entry, replacing an entry with the same key
+// and keeping that entry's first-seen date when entry has none. Entries
+// stay sorted by file and line.
+func (b *Baseline) Add(entry Entry) {
+	for i, e := range b.Entries {
+		if e.Key() != entry.Key() {
+			continue
+		}
+		if entry.FirstSeen == "" {
+			entry.FirstSeen = e.FirstSeen
+		}
+		b.Entries[i] = entry
+		return
+	}
+	i := sort.Search(len(b.Entries), func(i int) bool {
+		e := b.Entries[i]
+		return e.File > entry.File || e.File == entry.File && e.Line > entry.Line
+	})
+	b.Entries = append(b.Entries, Entry{})
+	copy(b.Entries[i+1:], b.Entries[i:])
+	b.Entries[i] = entry
+	if b.Version == 0 {
+		b.Version = Version
+	}
+}
+
+// Stamp sets the first-seen date of entries without one to now
+func (b *Baseline) Stamp(now time.Time) {
+	today := now.Format(DateLayout)
+	for i := range b.Entries {
+		if b.Entries[i].FirstSeen == "" {
+			b.Entries[i].FirstSeen = today
+		}
+	}
+}
+
+// AnnotateAges sets the FirstSeen date of each escape in results from the
+// baseline. Escapes the baseline does not list are new, first seen now.
+func (b *Baseline) AnnotateAges(results *categorizer.Results, now time.Time) {
+	firstSeen := b.firstSeen()
+	today := now.Format(DateLayout)
+	for i, e := range results.Escapes {
+		date, ok := firstSeen[KeyOf(e)]
+		if !ok {
+			date = today
+		}
+		results.Escapes[i].FirstSeen = date
+	}
+}
+
+// Delta is how the current escapes differ from a baseline
+type Delta struct {
+	Added   []Entry   // escapes the baseline does not list
+	Removed []Removal // entries no current escape matches, by ascending index
+}
+
+// Removal is a baseline entry and its index in the baseline's entries
+type Removal struct {
+	Index int
+	Entry Entry
+}
+
+// Compare returns the escapes in results that b does not list and the
+// entries of b that match no escape, reported or suppressed. Like
+// baseline suppressions, escapes and entries match by Key.
+func (b *Baseline) Compare(results *categorizer.Results) *Delta {
+	listed := make(map[string]bool, len(b.Entries))
+	for _, e := range b.Entries {
+		listed[e.Key()] = true
+	}
+	present := make(map[string]bool, len(results.Escapes)+len(results.Suppressions))
+	for _, s := range results.Suppressions {
+		present[Entry{File: s.File, Variable: s.Variable, Category: s.Category}.Key()] = true
+	}
+
+	d := &Delta{}
+	added := &categorizer.Results{}
+	for _, e := range results.Escapes {
+		present[KeyOf(e)] = true
+		if !listed[KeyOf(e)] {
+			added.Escapes = append(added.Escapes, e)
+		}
+	}
+	d.Added = FromResults(added).Entries
+	for i, e := range b.Entries {
+		if !present[e.Key()] {
+			d.Removed = append(d.Removed, Removal{Index: i, Entry: e})
+		}
+	}
+	return d
+}
+
+// firstSeen maps entry keys to their earliest first-seen date
+func (b *Baseline) firstSeen() map[string]string {
+	dates := make(map[string]string)
+	for _, e := range b.Entries {
+		if e.FirstSeen == "" {
+			continue
+		}
+		if prev, ok := dates[e.Key()]; !ok || e.FirstSeen < prev {
+			dates[e.Key()] = e.FirstSeen
+		}
+	}
+	return dates
+}
+
+// Load reads a baseline file, or downloads it when path is a URL. JSON
+// results written by --format=json load as a baseline of their escapes.
+func Load(path string) (*Baseline, error) {
+	var data []byte
+	var err error
+	if IsRemote(path) {
+		data, err = fetch(path)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading baseline: %w", err)
+	}
+	var f struct {
+		Baseline
+		Escapes []categorizer.CategorizedEscape `json:"escapes"`
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing baseline %s: %w", path, err)
+	}
+	if f.Entries == nil && f.Escapes != nil {
+		return FromResults(&categorizer.Results{Escapes: f.Escapes}), nil
+	}
+	if f.Version > Version {
+		return nil, fmt.Errorf("baseline %s has version %d, this heapcheck supports up to %d", path, f.Version, Version)
+	}
+	return &f.Baseline, nil
+}
+
+// IsRemote reports whether a baseline path is an http or https URL
+func IsRemote(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// fetch downloads a baseline, with the header from $HEAPCHECK_BASELINE_HEADER
+func fetch(url string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if header := os.Getenv(HeaderEnv); header != "" {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid $%s (want Name: value, e.g. Authorization: Bearer <token>)", HeaderEnv)
+		}
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	client := &http.Client{Timeout: fetchTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("GET %s: %s", req.URL.Redacted(), resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Save writes a baseline file
+func Save(path string, b *Baseline) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing baseline: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package baseline
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func sampleResults() *categorizer.Results {
+	return &categorizer.Results{
+		Escapes: []categorizer.CategorizedEscape{
+			{Info: parser.EscapeInfo{File: "b.go", Line: 3, Variable: "y"}, Category: categorizer.CategoryClosureCapture},
+			{Info: parser.EscapeInfo{File: "a.go", Line: 9, Variable: "x"}, Category: categorizer.CategoryInterfaceBoxing},
+			{Info: parser.EscapeInfo{File: "a.go", Line: 2, Variable: "w"}, Category: categorizer.CategorySliceGrow},
+		},
+	}
+}
+
+func TestFromResults(t *testing.T) {
+	b := FromResults(sampleResults())
+
+	if b.Version != Version {
+		t.Errorf("Version = %d, want %d", b.Version, Version)
+	}
+	want := []string{"a.go|w|slice-grow", "a.go|x|interface-boxing", "b.go|y|closure-capture"}
+	if len(b.Entries) != len(want) {
+		t.Fatalf("Entries = %d, want %d", len(b.Entries), len(want))
+	}
+	for i, key := range want {
+		if got := b.Entries[i].Key(); got != key {
+			t.Errorf("Entries[%d].Key() = %q, want %q", i, got, key)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	old := &Baseline{Entries: []Entry{
+		{File: "a.go", Line: 1, Variable: "x", Category: categorizer.CategoryInterfaceBoxing, Owner: "@a", Expires: "2025-06-01"},
+		{File: "gone.go", Line: 1, Variable: "z", Category: categorizer.CategoryUncategorized, Owner: "@z"},
+	}}
+
+	b := FromResults(sampleResults())
+	b.Merge(old)
+
+	for _, e := range b.Entries {
+		if e.Variable == "x" {
+			if e.Owner != "@a" || e.Expires != "2025-06-01" {
+				t.Errorf("merged entry = %+v, want owner and expiry kept", e)
+			}
+			if e.Line != 9 {
+				t.Errorf("merged entry Line = %d, want current line 9", e.Line)
+			}
+		} else if e.Owner != "" {
+			t.Errorf("entry %s got owner %q", e.Key(), e.Owner)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	b := FromResults(sampleResults())
+	b.Entries[1].FirstSeen = "2024-01-02"
+
+	b.Add(Entry{File: "a.go", Line: 5, Variable: "v", Category: categorizer.CategorySliceGrow, Reason: "bounded"})
+	b.Add(Entry{File: "a.go", Line: 10, Variable: "x", Category: categorizer.CategoryInterfaceBoxing, Owner: "@a", Reason: "once"})
+
+	want := []string{"a.go|w|slice-grow", "a.go|v|slice-grow", "a.go|x|interface-boxing", "b.go|y|closure-capture"}
+	if len(b.Entries) != len(want) {
+		t.Fatalf("Entries = %+v, want keys %v", b.Entries, want)
+	}
+	for i, key := range want {
+		if got := b.Entries[i].Key(); got != key {
+			t.Errorf("Entries[%d].Key() = %q, want %q", i, got, key)
+		}
+	}
+	if e := b.Entries[2]; e.Line != 10 || e.Owner != "@a" || e.Reason != "once" || e.FirstSeen != "2024-01-02" {
+		t.Errorf("replaced entry = %+v, want the new line and annotations and the old first-seen date", e)
+	}
+
+	empty := &Baseline{}
+	empty.Add(Entry{File: "a.go", Line: 1, Variable: "x"})
+	if empty.Version != Version || len(empty.Entries) != 1 {
+		t.Errorf("Add to an empty baseline = %+v", empty)
+	}
+}
+
+func TestFirstSeen(t *testing.T) {
+	old := &Baseline{Entries: []Entry{
+		{File: "a.go", Line: 1, Variable: "x", Category: categorizer.CategoryInterfaceBoxing, FirstSeen: "2024-01-15"},
+		{File: "a.go", Line: 5, Variable: "x", Category: categorizer.CategoryInterfaceBoxing, FirstSeen: "2023-12-01"},
+	}}
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	b := FromResults(sampleResults())
+	b.Merge(old)
+	b.Stamp(now)
+	for _, e := range b.Entries {
+		want := "2024-06-01"
+		if e.Variable == "x" {
+			want = "2023-12-01" // the earliest of duplicate keys
+		}
+		if e.FirstSeen != want {
+			t.Errorf("%s FirstSeen = %q, want %q", e.Key(), e.FirstSeen, want)
+		}
+	}
+
+	results := sampleResults()
+	results.Escapes = append(results.Escapes, categorizer.CategorizedEscape{
+		Info: parser.EscapeInfo{File: "c.go", Line: 1, Variable: "n"}, Category: categorizer.CategorySpill,
+	})
+	old.AnnotateAges(results, now)
+	for _, e := range results.Escapes {
+		want := "2024-06-01"
+		if e.Info.Variable == "x" {
+			want = "2023-12-01"
+		}
+		if e.FirstSeen != want {
+			t.Errorf("%s FirstSeen = %q, want %q", KeyOf(e), e.FirstSeen, want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	b := &Baseline{Entries: []Entry{
+		{File: "a.go", Line: 9, Variable: "x", Category: categorizer.CategoryInterfaceBoxing},
+		{File: "a.go", Line: 20, Variable: "gone", Category: categorizer.CategorySpill},
+		{File: "b.go", Line: 3, Variable: "y", Category: categorizer.CategoryClosureCapture},
+		{File: "c.go", Line: 5, Variable: "z", Category: categorizer.CategorySpill},
+	}}
+	results := sampleResults()
+	// y was suppressed by its baseline entry, so it is still present
+	results.Escapes = results.Escapes[1:]
+	results.Suppressions = []categorizer.SuppressionStatus{
+		{File: "b.go", Line: 3, Variable: "y", Category: categorizer.CategoryClosureCapture, Source: "baseline"},
+	}
+
+	d := b.Compare(results)
+	if len(d.Added) != 1 || d.Added[0].Key() != "a.go|w|slice-grow" || d.Added[0].Line != 2 {
+		t.Errorf("Added = %+v, want a.go:2 w", d.Added)
+	}
+	if len(d.Removed) != 2 || d.Removed[0].Index != 1 || d.Removed[0].Entry.Variable != "gone" || d.Removed[1].Index != 3 {
+		t.Errorf("Removed = %+v, want entries 1 (gone) and 3 (z)", d.Removed)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	b := FromResults(sampleResults())
+	b.Entries[0].Owner = "@core"
+
+	if err := Save(path, b); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(got.Entries) != 3 || got.Entries[0].Owner != "@core" {
+		t.Errorf("Load() = %+v", got)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Load() expected error for missing file")
+	}
+}
+
+func TestLoadRemote(t *testing.T) {
+	b := FromResults(sampleResults())
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := json.Marshal(sampleResults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/baseline.json":
+			w.Write(data)
+		case "/results.json":
+			w.Write(results)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv(HeaderEnv, "Authorization: Bearer secret")
+	got, err := Load(srv.URL + "/baseline.json")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if !reflect.DeepEqual(got, b) {
+		t.Errorf("Load() = %+v, want %+v", got, b)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the header from $%s", auth, HeaderEnv)
+	}
+
+	// The JSON results of a run accept all their escapes
+	got, err = Load(srv.URL + "/results.json")
+	if err != nil {
+		t.Fatalf("Load() of results error: %v", err)
+	}
+	if !reflect.DeepEqual(got, b) {
+		t.Errorf("Load() of results = %+v, want %+v", got, b)
+	}
+
+	if _, err := Load(srv.URL + "/missing.json"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Load() of a missing artifact = %v, want a 404 error", err)
+	}
+
+	t.Setenv(HeaderEnv, "Bearer secret")
+	if _, err := Load(srv.URL + "/baseline.json"); err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("Load() with an invalid header = %v, want an error that does not show its value", err)
+	}
+}
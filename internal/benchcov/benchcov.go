@@ -0,0 +1,312 @@
+// Package benchcov marks the escapes in code that benchmarks exercise,
+// from `go test -bench` output or a list of benchmarked functions, so that
+// optimization work can start where measurement already exists.
+//
+// What a benchmark exercises is found by parsing the sources of the
+// escapes' packages, their test files included, and following the
+// functions and methods each function refers to by name from the
+// benchmark functions. Methods are matched by name alone, which can mark
+// more code as benchmarked than a benchmark runs, but does not miss calls
+// through interfaces; methods of other packages are matched among the
+// packages the calling file imports.
+package benchcov
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/buildctx"
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/typecheck"
+)
+
+// Targets are what a benchmark run measured
+type Targets struct {
+	// Benchmarks are benchmark functions, e.g. "BenchmarkParse"
+	Benchmarks []string
+
+	// Funcs are other benchmarked functions, named as escapes' Func, e.g.
+	// "(*Server).Handle", optionally after the package's import path and
+	// a dot, e.g. "example.com/app.(*Server).Handle"
+	Funcs []string
+}
+
+// resultRe matches a go test -bench result line, e.g.
+// "BenchmarkParse/small-8   	  1000000	      1052 ns/op"
+var resultRe = regexp.MustCompile(`^(Benchmark[^\s/]*?)(?:/\S*?)?(?:-\d+)?\s+\d+\s+[\d.]+ ns/op`)
+
+// Load reads the targets from a file, as Parse does
+func Load(path string) (*Targets, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading benchmarks: %w", err)
+	}
+	defer f.Close()
+	t, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading benchmarks %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Parse reads `go test -bench` output, taking the benchmarks that
+// reported results, or, when r has no result lines, a list with one
+// function per line, where "#" starts a comment. Listed names starting
+// with "Benchmark" are benchmarks; the others are benchmarked functions.
+func Parse(r io.Reader) (*Targets, error) {
+	var results, listed []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := resultRe.FindStringSubmatch(line); m != nil {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				results = append(results, m[1])
+			}
+			continue
+		}
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line != "" {
+			listed = append(listed, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(results) > 0 {
+		return &Targets{Benchmarks: results}, nil
+	}
+
+	t := &Targets{}
+	for _, name := range listed {
+		if strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("%q is neither a benchmark result nor a function name", name)
+		}
+		if strings.HasPrefix(name, "Benchmark") {
+			t.Benchmarks = append(t.Benchmarks, name)
+		} else {
+			t.Funcs = append(t.Funcs, name)
+		}
+	}
+	if len(t.Benchmarks)+len(t.Funcs) == 0 {
+		return nil, fmt.Errorf("no benchmark results or function names")
+	}
+	return t, nil
+}
+
+// Annotate sets Benchmarked on each escape in a function the targets
+// exercise to categorizer.BenchmarkedYes, and on the others in a parsed
+// function to categorizer.BenchmarkedNo, and counts them in the summary.
+// Escapes outside any function or in files that cannot be parsed are
+// left unmarked.
+func Annotate(results *categorizer.Results, t *Targets) {
+	g := newGraph()
+	for _, e := range results.Escapes {
+		dir := filepath.Dir(e.Info.File)
+		if e.Info.Package != "" {
+			g.dirs[e.Info.Package] = dir
+		}
+		g.load(dir)
+	}
+	for dir, p := range g.pkgs {
+		for _, name := range t.Benchmarks {
+			g.visit(dir, name)
+		}
+		for _, name := range t.Funcs {
+			if p.funcs[name] {
+				g.visit(dir, name)
+			} else if path, fn, ok := cutPackage(name); ok && g.dirs[path] == dir && p.funcs[fn] {
+				g.visit(dir, fn)
+			}
+		}
+	}
+
+	for i := range results.Escapes {
+		e := &results.Escapes[i]
+		dir := filepath.Dir(e.Info.File)
+		if p := g.pkgs[dir]; p == nil || !p.funcs[e.Func] {
+			continue
+		}
+		if g.reached[node{dir, e.Func}] {
+			e.Benchmarked = categorizer.BenchmarkedYes
+			results.Summary.BenchmarkedEscapes++
+		} else {
+			e.Benchmarked = categorizer.BenchmarkedNo
+			results.Summary.UnbenchmarkedEscapes++
+		}
+	}
+}
+
+// cutPackage splits a function name after an import path, such as
+// "example.com/app.(*Server).Handle", at the dot ending the path
+func cutPackage(name string) (path, fn string, ok bool) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", "", false
+	}
+	i := slash + 1 + dot
+	return name[:i], name[i+1:], true
+}
+
+// node is a function of the package in a directory
+type node struct {
+	dir, name string
+}
+
+// pkg is the functions declared in the files of a directory, those of an
+// external test package included
+type pkg struct {
+	funcs   map[string]bool     // by name, as escapes' Func
+	methods map[string][]string // method names to funcs
+	refs    map[string][]ref    // funcs to the names their bodies use
+	imports map[string][]string // funcs to the import paths of their file
+}
+
+// ref is a name a function body uses, with the import path it was
+// selected from for names of imported packages
+type ref struct {
+	path, name string
+}
+
+// graph follows the references between the functions of the parsed
+// directories
+type graph struct {
+	fset    *token.FileSet
+	pkgs    map[string]*pkg   // by directory; nil for unreadable ones
+	dirs    map[string]string // import paths to directories
+	reached map[node]bool
+}
+
+func newGraph() *graph {
+	return &graph{
+		fset:    token.NewFileSet(),
+		pkgs:    make(map[string]*pkg),
+		dirs:    make(map[string]string),
+		reached: make(map[node]bool),
+	}
+}
+
+// load parses the Go files of dir, once
+func (g *graph) load(dir string) {
+	if _, ok := g.pkgs[dir]; ok {
+		return
+	}
+	g.pkgs[dir] = nil
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil || len(paths) == 0 {
+		return
+	}
+	p := &pkg{
+		funcs:   make(map[string]bool),
+		methods: make(map[string][]string),
+		refs:    make(map[string][]ref),
+		imports: make(map[string][]string),
+	}
+	for _, path := range paths {
+		if !buildctx.Includes(path) {
+			continue
+		}
+		src, err := buildctx.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		f, err := parser.ParseFile(g.fset, path, src, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		p.add(f)
+	}
+	g.pkgs[dir] = p
+}
+
+// add records the functions of f and the names they use
+func (p *pkg) add(f *ast.File) {
+	imports := make(map[string]string)
+	var paths []string
+	for _, spec := range f.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = path
+		paths = append(paths, path)
+	}
+
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		name := typecheck.FuncName(fd)
+		p.funcs[name] = true
+		p.imports[name] = append(p.imports[name], paths...)
+		if fd.Recv != nil {
+			p.methods[fd.Name.Name] = append(p.methods[fd.Name.Name], name)
+		}
+		if fd.Body == nil {
+			continue
+		}
+		ast.Inspect(fd.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				if x, ok := n.X.(*ast.Ident); ok {
+					if path, ok := imports[x.Name]; ok {
+						p.refs[name] = append(p.refs[name], ref{path, n.Sel.Name})
+						return false
+					}
+				}
+			case *ast.Ident:
+				p.refs[name] = append(p.refs[name], ref{name: n.Name})
+			}
+			return true
+		})
+	}
+}
+
+// visit marks the function name of the package in dir, and what it
+// refers to, as reached
+func (g *graph) visit(dir, name string) {
+	p := g.pkgs[dir]
+	if p == nil || !p.funcs[name] || g.reached[node{dir, name}] {
+		return
+	}
+	g.reached[node{dir, name}] = true
+	for _, r := range p.refs[name] {
+		if r.path != "" {
+			if target, ok := g.dirs[r.path]; ok {
+				g.visit(target, r.name)
+			}
+			continue
+		}
+		g.visit(dir, r.name)
+		g.visitMethods(dir, r.name)
+		for _, path := range p.imports[name] {
+			if target, ok := g.dirs[path]; ok {
+				g.visitMethods(target, r.name)
+			}
+		}
+	}
+}
+
+// visitMethods visits the methods named name of the package in dir
+func (g *graph) visitMethods(dir, name string) {
+	if p := g.pkgs[dir]; p != nil {
+		for _, m := range p.methods[name] {
+			g.visit(dir, m)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package benchcov
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    *Targets
+		wantErr bool
+	}{
+		{
+			name: "bench output",
+			input: `goos: linux
+goarch: amd64
+pkg: example.com/app
+cpu: Intel(R) Xeon(R) Processor
+BenchmarkParse/small-8         	 1000000	      1052 ns/op	     256 B/op	       3 allocs/op
+BenchmarkParse/large-8         	   10000	    104113 ns/op
+BenchmarkGrow                  	     100	        12.5 ns/op
+PASS
+ok  	example.com/app	2.130s
+`,
+			want: &Targets{Benchmarks: []string{"BenchmarkParse", "BenchmarkGrow"}},
+		},
+		{
+			name: "function list",
+			input: `# hot paths
+BenchmarkParse
+(*Server).Handle  # the request path
+example.com/app/store.Get
+`,
+			want: &Targets{
+				Benchmarks: []string{"BenchmarkParse"},
+				Funcs:      []string{"(*Server).Handle", "example.com/app/store.Get"},
+			},
+		},
+		{
+			name:    "output without results",
+			input:   "PASS\nok  \texample.com/app\t0.130s\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			input:   "# nothing\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCutPackage(t *testing.T) {
+	path, fn, ok := cutPackage("example.com/app/store.(*DB).Get")
+	if !ok || path != "example.com/app/store" || fn != "(*DB).Get" {
+		t.Errorf("cutPackage() = %q, %q, %v", path, fn, ok)
+	}
+	if _, _, ok := cutPackage("Parse"); ok {
+		t.Error("cutPackage(Parse) found a package")
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	output, err := parser.RunCompiler(context.Background(), []string{"./testdata/sample"})
+	if err != nil {
+		t.Fatalf("RunCompiler: %v", err)
+	}
+	escapes, err := parser.Parse(output)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		targets Targets
+		want    map[string]string
+	}{
+		{
+			name:    "benchmarks",
+			targets: Targets{Benchmarks: []string{"BenchmarkParse", "BenchmarkGrow"}},
+			want: map[string]string{
+				"Parse":       categorizer.BenchmarkedYes,
+				"split":       categorizer.BenchmarkedYes,
+				"(*Buf).Grow": categorizer.BenchmarkedYes,
+				"Format":      categorizer.BenchmarkedNo,
+			},
+		},
+		{
+			name:    "one benchmark",
+			targets: Targets{Benchmarks: []string{"BenchmarkParse"}},
+			want: map[string]string{
+				"split":       categorizer.BenchmarkedYes,
+				"(*Buf).Grow": categorizer.BenchmarkedNo,
+			},
+		},
+		{
+			name:    "functions",
+			targets: Targets{Funcs: []string{"github.com/harshakonda/heapcheck/internal/benchcov/testdata/sample.Format", "(*Buf).Grow"}},
+			want: map[string]string{
+				"Parse":       categorizer.BenchmarkedNo,
+				"(*Buf).Grow": categorizer.BenchmarkedYes,
+				"Format":      categorizer.BenchmarkedYes,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := categorizer.Categorize(escapes)
+			Annotate(results, &tt.targets)
+
+			got := make(map[string]string)
+			var yes, no int
+			for _, e := range results.Escapes {
+				got[e.Func] = e.Benchmarked
+				switch e.Benchmarked {
+				case categorizer.BenchmarkedYes:
+					yes++
+				case categorizer.BenchmarkedNo:
+					no++
+				}
+			}
+			for fn, want := range tt.want {
+				if got[fn] != want {
+					t.Errorf("%s: Benchmarked = %q, want %q", fn, got[fn], want)
+				}
+			}
+			if s := results.Summary; s.BenchmarkedEscapes != yes || s.UnbenchmarkedEscapes != no {
+				t.Errorf("summary counts %d/%d, want %d/%d", s.BenchmarkedEscapes, s.UnbenchmarkedEscapes, yes, no)
+			}
+		})
+	}
+}
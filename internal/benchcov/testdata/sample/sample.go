@@ -0,0 +1,29 @@
+package sample
+
+import "fmt"
+
+type Buf struct {
+	data []byte
+}
+
+// Grow is only called through a Buf in the benchmark
+func (b *Buf) Grow(n int) *Buf {
+	b.data = append(b.data, make([]byte, n)...)
+	return &Buf{data: b.data}
+}
+
+// Parse is benchmarked directly
+func Parse(s string) *string {
+	return split(s)
+}
+
+// split is reached through Parse
+func split(s string) *string {
+	v := s + "!"
+	return &v
+}
+
+// Format has no benchmark
+func Format(n int) string {
+	return fmt.Sprint(n)
+}
@@ -0,0 +1,16 @@
+package sample
+
+import "testing"
+
+func BenchmarkParse(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Parse("x")
+	}
+}
+
+func BenchmarkGrow(b *testing.B) {
+	buf := &Buf{}
+	for i := 0; i < b.N; i++ {
+		buf.Grow(8)
+	}
+}
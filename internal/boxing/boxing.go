@@ -0,0 +1,222 @@
+// Package boxing finds the interface parameters responsible for boxing
+// escapes. Each boxed value is traced to the call it is passed to, and the
+// callee's signature is resolved with go/types, so the report points at
+// APIs to change rather than at individual call sites.
+package boxing
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	heapparser "github.com/harshakonda/heapcheck/internal/parser"
+	"github.com/harshakonda/heapcheck/internal/typecheck"
+)
+
+// IsBoxing reports whether an escape is a value converted to an interface
+func IsBoxing(e categorizer.CategorizedEscape) bool {
+	return e.Category == categorizer.CategoryInterfaceBoxing ||
+		e.Category == categorizer.CategoryFmtCall ||
+		e.Info.HasSink(heapparser.SinkInterface)
+}
+
+// Analyze type-checks the packages matching patterns and returns the
+// interface parameters that the boxing escapes are passed to, sorted by
+// call sites. Escapes not passed directly as call arguments are skipped.
+func Analyze(patterns []string, escapes []categorizer.CategorizedEscape) ([]categorizer.InterfaceParam, error) {
+	byFile := make(map[string][]categorizer.CategorizedEscape)
+	for _, e := range escapes {
+		if !IsBoxing(e) {
+			continue
+		}
+		if abs, err := filepath.Abs(e.Info.File); err == nil {
+			byFile[abs] = append(byFile[abs], e)
+		}
+	}
+	if len(byFile) == 0 {
+		return nil, nil
+	}
+
+	files := make(map[string]bool, len(byFile))
+	for path := range byFile {
+		files[path] = true
+	}
+	fset := token.NewFileSet()
+	pkgs, err := typecheck.Check(fset, patterns, files)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newCollector(fset)
+	for _, p := range pkgs {
+		for _, f := range p.Files {
+			path := fset.Position(f.Pos()).Filename
+			for _, e := range byFile[path] {
+				c.attribute(f, p.Info, e)
+			}
+		}
+	}
+	return c.sorted(), nil
+}
+
+// paramKey identifies one parameter of one function
+type paramKey struct {
+	fn    string
+	index int
+}
+
+// collector accumulates call sites per interface parameter
+type collector struct {
+	fset   *token.FileSet
+	params map[paramKey]*categorizer.InterfaceParam
+	calls  map[paramKey]map[token.Pos]bool
+}
+
+func newCollector(fset *token.FileSet) *collector {
+	return &collector{
+		fset:   fset,
+		params: make(map[paramKey]*categorizer.InterfaceParam),
+		calls:  make(map[paramKey]map[token.Pos]bool),
+	}
+}
+
+// attribute finds the innermost call argument containing the escape and
+// records it if the matching parameter has an interface type
+func (c *collector) attribute(f *ast.File, info *types.Info, e categorizer.CategorizedEscape) {
+	var (
+		call  *ast.CallExpr
+		index int
+		size  token.Pos
+	)
+	ast.Inspect(f, func(n ast.Node) bool {
+		ce, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		for i, arg := range ce.Args {
+			start, end := c.fset.Position(arg.Pos()), c.fset.Position(arg.End())
+			if !typecheck.Contains(start, end, e.Info.Line, e.Info.Column) {
+				continue
+			}
+			if call == nil || arg.End()-arg.Pos() < size {
+				call, index, size = ce, i, arg.End()-arg.Pos()
+			}
+		}
+		return true
+	})
+	if call == nil {
+		return
+	}
+
+	tv, ok := info.Types[call.Fun]
+	if !ok || tv.IsType() {
+		return // conversion, not a call
+	}
+	sig, ok := tv.Type.Underlying().(*types.Signature)
+	if !ok {
+		return
+	}
+	param, variadic := paramAt(sig, index, call.Ellipsis.IsValid())
+	if param == nil || !isInterface(param.Type()) {
+		return
+	}
+
+	name := types.ExprString(call.Fun)
+	position := ""
+	if fn := typecheck.Callee(info, call.Fun); fn != nil {
+		name = fn.FullName()
+		if pos := c.fset.Position(fn.Pos()); pos.IsValid() {
+			position = fmt.Sprintf("%s:%d", pos.Filename, pos.Line)
+		}
+	}
+
+	key := paramKey{fn: name, index: min(index, sig.Params().Len()-1)}
+	ip := c.params[key]
+	if ip == nil {
+		ip = &categorizer.InterfaceParam{
+			Func:     name,
+			Param:    formatParam(param, variadic),
+			Position: position,
+		}
+		c.params[key] = ip
+		c.calls[key] = make(map[token.Pos]bool)
+	}
+	ip.Escapes++
+	if !c.calls[key][call.Pos()] {
+		c.calls[key][call.Pos()] = true
+		ip.CallSites++
+	}
+}
+
+// sorted returns the collected parameters, most call sites first
+func (c *collector) sorted() []categorizer.InterfaceParam {
+	result := make([]categorizer.InterfaceParam, 0, len(c.params))
+	for _, ip := range c.params {
+		result = append(result, *ip)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.CallSites != b.CallSites {
+			return a.CallSites > b.CallSites
+		}
+		if a.Escapes != b.Escapes {
+			return a.Escapes > b.Escapes
+		}
+		if a.Func != b.Func {
+			return a.Func < b.Func
+		}
+		return a.Param < b.Param
+	})
+	return result
+}
+
+// paramAt returns the parameter receiving argument i, and whether it is
+// the variadic parameter receiving individual elements
+func paramAt(sig *types.Signature, i int, spread bool) (*types.Var, bool) {
+	n := sig.Params().Len()
+	if n == 0 {
+		return nil, false
+	}
+	if sig.Variadic() && i >= n-1 {
+		last := sig.Params().At(n - 1)
+		if spread {
+			return last, false
+		}
+		return last, true
+	}
+	if i >= n {
+		return nil, false
+	}
+	return sig.Params().At(i), false
+}
+
+// isInterface reports whether t (or the element type of a variadic
+// slice) is an interface, excluding type parameters
+func isInterface(t types.Type) bool {
+	if s, ok := t.(*types.Slice); ok {
+		t = s.Elem()
+	}
+	if _, ok := t.(*types.TypeParam); ok {
+		return false
+	}
+	return types.IsInterface(t)
+}
+
+// formatParam renders a parameter as it appears in the signature,
+// e.g. "a ...any"
+func formatParam(v *types.Var, variadic bool) string {
+	qualifier := func(p *types.Package) string { return p.Name() }
+	t := v.Type()
+	typ := types.TypeString(t, qualifier)
+	if s, ok := t.(*types.Slice); ok && variadic {
+		typ = "..." + types.TypeString(s.Elem(), qualifier)
+	}
+	if v.Name() == "" || v.Name() == "_" {
+		return typ
+	}
+	return v.Name() + " " + typ
+}
@@ -0,0 +1,59 @@
+package boxing
+
+import (
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func boxed(line, col int) categorizer.CategorizedEscape {
+	return categorizer.CategorizedEscape{
+		Info:     parser.EscapeInfo{File: "testdata/sample/sample.go", Line: line, Column: col, EscapeType: parser.EscapesToHeap},
+		Category: categorizer.CategoryInterfaceBoxing,
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	escapes := []categorizer.CategorizedEscape{
+		boxed(12, 17), // l.Log("start", n)
+		boxed(13, 16), // l.Log("stop", n, s)
+		boxed(13, 19),
+		boxed(14, 13), // Store("n", n)
+		boxed(15, 14), // fmt.Println(s)
+		{ // not boxing: ignored
+			Info:     parser.EscapeInfo{File: "testdata/sample/sample.go", Line: 11, Column: 10},
+			Category: categorizer.CategoryLeakingParam,
+		},
+	}
+
+	params, err := Analyze([]string{"./testdata/sample"}, escapes)
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	want := []categorizer.InterfaceParam{
+		{Func: "(github.com/harshakonda/heapcheck/internal/boxing/testdata/sample.Logger).Log", Param: "fields ...interface{}", CallSites: 2, Escapes: 3},
+		{Func: "fmt.Println", Param: "a ...any", CallSites: 1, Escapes: 1},
+		{Func: "github.com/harshakonda/heapcheck/internal/boxing/testdata/sample.Store", Param: "v any", CallSites: 1, Escapes: 1},
+	}
+	if len(params) != len(want) {
+		t.Fatalf("params = %+v, want %d entries", params, len(want))
+	}
+	for i, w := range want {
+		got := params[i]
+		if got.Func != w.Func || got.Param != w.Param || got.CallSites != w.CallSites || got.Escapes != w.Escapes {
+			t.Errorf("params[%d] = %+v, want %+v", i, got, w)
+		}
+		if got.Position == "" {
+			t.Errorf("params[%d].Position is empty", i)
+		}
+	}
+}
+
+func TestAnalyzeNoBoxing(t *testing.T) {
+	params, err := Analyze([]string{"./does-not-exist"}, nil)
+	if err != nil || params != nil {
+		t.Errorf("Analyze(no escapes) = %v, %v, want nil, nil", params, err)
+	}
+}
@@ -0,0 +1,16 @@
+package sample
+
+import "fmt"
+
+type Logger struct{}
+
+func (Logger) Log(msg string, fields ...interface{}) {}
+
+func Store(key string, v any) {}
+
+func Run(l Logger, n int, s string) {
+	l.Log("start", n)
+	l.Log("stop", n, s)
+	Store("n", n)
+	fmt.Println(s)
+}
@@ -0,0 +1,156 @@
+// Package buildctx decides which source files belong to the build
+// configuration an analysis compiled, so that files build constraints
+// exclude never contribute source context, function names or
+// suppressions to its report.
+//
+// Packages that read source call Includes, and read files with ReadFile
+// to see them through the build's -overlay. It includes every file until
+// Use is called, as the CLI does for runs that compile the packages
+// itself; output read with --input comes from a configuration heapcheck
+// cannot know.
+package buildctx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Matcher reports which files a build context compiles, caching its
+// answers per file
+type Matcher struct {
+	ctx     build.Context
+	overlay Overlay
+	mu      sync.Mutex
+	files   map[string]bool
+}
+
+// New returns a Matcher for ctx
+func New(ctx build.Context) *Matcher {
+	return &Matcher{ctx: ctx, files: make(map[string]bool)}
+}
+
+// UseOverlay makes m read source through o, as a build with -overlay
+// does. It is meant to be called before m answers.
+func (m *Matcher) UseOverlay(o Overlay) {
+	m.overlay = o
+	m.ctx.OpenFile = o.open
+}
+
+// FromGoEnv returns a Matcher for the configuration the go command
+// builds with: GOOS, GOARCH and CGO_ENABLED as `go env` reports them, and
+// the tags of -tags and the overlay of -overlay in GOFLAGS
+func FromGoEnv() (*Matcher, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("go", "env", "-json", "GOOS", "GOARCH", "CGO_ENABLED", "GOFLAGS")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go env: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	var env struct{ GOOS, GOARCH, CGO_ENABLED, GOFLAGS string }
+	if err := json.Unmarshal(out, &env); err != nil {
+		return nil, fmt.Errorf("decoding go env output: %w", err)
+	}
+	ctx := build.Default
+	ctx.GOOS = env.GOOS
+	ctx.GOARCH = env.GOARCH
+	ctx.CgoEnabled = env.CGO_ENABLED == "1"
+	ctx.BuildTags = Tags(env.GOFLAGS)
+	m := New(ctx)
+	if file := OverlayFile(env.GOFLAGS); file != "" {
+		o, err := LoadOverlay(file)
+		if err != nil {
+			return nil, err
+		}
+		m.UseOverlay(o)
+	}
+	return m, nil
+}
+
+// Tags returns the build tags set by -tags in a GOFLAGS value, e.g.
+// "-mod=mod -tags=integration,linux_only"
+func Tags(goflags string) []string {
+	var tags []string
+	for _, f := range strings.Fields(goflags) {
+		f = strings.TrimPrefix(f, "-")
+		value, ok := strings.CutPrefix(f, "-tags=")
+		if !ok {
+			value, ok = strings.CutPrefix(f, "tags=")
+		}
+		if !ok {
+			continue
+		}
+		tags = nil // the last -tags wins, as with the go command
+		for _, tag := range strings.Split(value, ",") {
+			if tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// Includes reports whether the build compiles the file at path: its name
+// and //go:build line match the context. Files that cannot be read are
+// included, as there is nothing to take from them anyway.
+func (m *Matcher) Includes(path string) bool {
+	if m == nil {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ok, seen := m.files[path]; seen {
+		return ok
+	}
+	if to, ok := m.overlay.replacement(path); ok && to == "" {
+		m.files[path] = false
+		return false
+	}
+	ok, err := m.ctx.MatchFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		ok = true
+	}
+	m.files[path] = ok
+	return ok
+}
+
+var (
+	currentMu sync.RWMutex
+	current   *Matcher
+)
+
+// Use makes Includes answer for m's configuration; nil includes every
+// file again. It is meant to be called before the analysis reads source.
+func Use(m *Matcher) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = m
+}
+
+// Includes reports whether the configuration set with Use compiles the
+// file at path, true for every file without one
+func Includes(path string) bool {
+	currentMu.RLock()
+	m := current
+	currentMu.RUnlock()
+	return m.Includes(path)
+}
+
+// ReadFile reads the source file at path as the configuration set with
+// Use builds it, through its overlay if it has one
+func ReadFile(path string) ([]byte, error) {
+	currentMu.RLock()
+	m := current
+	currentMu.RUnlock()
+	if m == nil {
+		return os.ReadFile(path)
+	}
+	return m.overlay.ReadFile(path)
+}
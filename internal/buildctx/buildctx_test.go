@@ -0,0 +1,72 @@
+package buildctx
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	tests := []struct {
+		goflags string
+		want    []string
+	}{
+		{"", nil},
+		{"-mod=mod", nil},
+		{"-mod=mod -tags=integration,e2e", []string{"integration", "e2e"}},
+		{"--tags=a -tags=b", []string{"b"}},
+	}
+	for _, tt := range tests {
+		if got := Tags(tt.goflags); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tags(%q) = %v, want %v", tt.goflags, got, tt.want)
+		}
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":         "package main\n",
+		"main_windows.go": "package main\n",
+		"integration.go":  "//go:build integration\n\npackage main\n",
+		"ignored.go":      "//go:build ignore\n\npackage main\n",
+		"not_integrat.go": "//go:build !integration\n\npackage main\n",
+		"_underscore.go":  "package main\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := build.Default
+	ctx.GOOS, ctx.GOARCH = "linux", "amd64"
+	ctx.BuildTags = []string{"integration"}
+	m := New(ctx)
+
+	want := map[string]bool{
+		"main.go":         true,
+		"main_windows.go": false,
+		"integration.go":  true,
+		"ignored.go":      false,
+		"not_integrat.go": false,
+		"_underscore.go":  false,
+		"missing.go":      true,
+	}
+	for name, included := range want {
+		if got := m.Includes(filepath.Join(dir, name)); got != included {
+			t.Errorf("Includes(%s) = %v, want %v", name, got, included)
+		}
+	}
+
+	defer Use(nil)
+	if !Includes(filepath.Join(dir, "ignored.go")) {
+		t.Error("Includes() without a configuration excluded a file")
+	}
+	Use(m)
+	if Includes(filepath.Join(dir, "ignored.go")) {
+		t.Error("Includes() with a configuration included an ignored file")
+	}
+}
@@ -0,0 +1,100 @@
+package buildctx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Overlay maps the absolute paths of source files the build replaces to
+// the files that replace them, in the format of the go command's
+// -overlay flag. An empty replacement deletes the file from the build.
+type Overlay map[string]string
+
+// LoadOverlay reads an overlay file: JSON with a "Replace" object from
+// file paths to the paths of their replacements. Relative paths are
+// relative to the current directory, as for the go command.
+func LoadOverlay(path string) (Overlay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading overlay: %w", err)
+	}
+	var raw struct{ Replace map[string]string }
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing overlay %s: %w", path, err)
+	}
+	if raw.Replace == nil {
+		return nil, fmt.Errorf("parsing overlay %s: no Replace object", path)
+	}
+	o := make(Overlay, len(raw.Replace))
+	for from, to := range raw.Replace {
+		abs, err := filepath.Abs(from)
+		if err != nil {
+			return nil, err
+		}
+		if to != "" {
+			if to, err = filepath.Abs(to); err != nil {
+				return nil, err
+			}
+		}
+		o[abs] = to
+	}
+	return o, nil
+}
+
+// OverlayFile returns the file of the last -overlay in a GOFLAGS value,
+// "" without one
+func OverlayFile(goflags string) string {
+	file := ""
+	for _, f := range strings.Fields(goflags) {
+		f = strings.TrimPrefix(f, "-")
+		if value, ok := strings.CutPrefix(f, "-overlay="); ok {
+			file = value
+		} else if value, ok := strings.CutPrefix(f, "overlay="); ok {
+			file = value
+		}
+	}
+	return file
+}
+
+// ReadFile reads the source file at path as the build sees it: the
+// overlay's replacement when it replaces the file
+func (o Overlay) ReadFile(path string) ([]byte, error) {
+	to, ok := o.replacement(path)
+	if !ok {
+		return os.ReadFile(path)
+	}
+	if to == "" {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	return os.ReadFile(to)
+}
+
+// open opens the source file at path as the build sees it, for
+// build.Context.OpenFile
+func (o Overlay) open(path string) (io.ReadCloser, error) {
+	to, ok := o.replacement(path)
+	if !ok {
+		return os.Open(path)
+	}
+	if to == "" {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	return os.Open(to)
+}
+
+// replacement returns the file replacing path, and whether there is one
+func (o Overlay) replacement(path string) (string, bool) {
+	if len(o) == 0 {
+		return "", false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	to, ok := o[abs]
+	return to, ok
+}
@@ -0,0 +1,92 @@
+package buildctx
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOverlayFile(t *testing.T) {
+	tests := []struct {
+		goflags string
+		want    string
+	}{
+		{"", ""},
+		{"-mod=mod -tags=e2e", ""},
+		{"-mod=mod -overlay=/tmp/a.json", "/tmp/a.json"},
+		{"--overlay=a.json -overlay=b.json", "b.json"},
+	}
+	for _, tt := range tests {
+		if got := OverlayFile(tt.goflags); got != tt.want {
+			t.Errorf("OverlayFile(%q) = %q, want %q", tt.goflags, got, tt.want)
+		}
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"app/app.go":      "package app\n",
+		"app/old.go":      "package app\n\nvar Old = 1\n",
+		"gen/app.go":      "package app\n\nvar Generated = 1\n",
+		"gen/windows.go":  "//go:build windows\n\npackage app\n",
+		"gen/overlay.txt": "",
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	overlay := filepath.Join(dir, "overlay.json")
+	data := `{"Replace": {
+		"app/app.go": "gen/app.go",
+		"app/new.go": "gen/windows.go",
+		"app/old.go": ""
+	}}`
+	if err := os.WriteFile(overlay, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	o, err := LoadOverlay(overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src, err := o.ReadFile(filepath.Join(dir, "app", "app.go")); err != nil || string(src) != files["gen/app.go"] {
+		t.Errorf("ReadFile(app.go) = %q, %v, want the replacement", src, err)
+	}
+	if _, err := o.ReadFile("app/old.go"); !os.IsNotExist(err) {
+		t.Errorf("ReadFile(old.go) error = %v, want the deleted file not to exist", err)
+	}
+	if src, err := o.ReadFile("gen/overlay.txt"); err != nil || len(src) != 0 {
+		t.Errorf("ReadFile(overlay.txt) = %q, %v, want the file on disk", src, err)
+	}
+
+	ctx := build.Default
+	ctx.GOOS, ctx.GOARCH = "linux", "amd64"
+	m := New(ctx)
+	m.UseOverlay(o)
+	for name, want := range map[string]bool{"app/app.go": true, "app/old.go": false, "app/new.go": false} {
+		if got := m.Includes(filepath.Join(dir, name)); got != want {
+			t.Errorf("Includes(%s) = %v, want %v", name, got, want)
+		}
+	}
+
+	for _, bad := range []string{`{"Replace": 1}`, `{}`, `not json`} {
+		if err := os.WriteFile(overlay, []byte(bad), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadOverlay(overlay); err == nil {
+			t.Errorf("LoadOverlay(%s) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Package bulk analyzes many repositories in one run and combines their
+// results, for platform teams auditing allocation hygiene across an
+// organization.
+//
+// A manifest lists the repositories:
+//
+//	dir: .heapcheck-bulk          # where repositories are cloned, relative to the manifest
+//	repos:
+//	  - url: https://github.com/org/api
+//	  - url: git@github.com:org/worker.git
+//	    ref: release-2.x            # branch or tag (default: the remote's HEAD)
+//	    name: worker-2x             # clone directory and report name (default: from the URL)
+//	    packages: [./cmd/..., ./internal/...]  # default ./...
+package bulk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/gitcmd"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// DefaultDir is where repositories are cloned when the manifest sets no
+// dir
+const DefaultDir = ".heapcheck-bulk"
+
+// Manifest is the content of a manifest file
+type Manifest struct {
+	Dir   string `yaml:"dir"`
+	Repos []Repo `yaml:"repos"`
+}
+
+// Repo is a repository to analyze
+type Repo struct {
+	URL      string   `yaml:"url"`
+	Ref      string   `yaml:"ref"`
+	Name     string   `yaml:"name"`
+	Packages []string `yaml:"packages"`
+}
+
+// LoadManifest reads and validates a manifest, filling in the defaults:
+// Dir resolved against the manifest's directory, names from the URLs and
+// ./... for the packages
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing manifest %s: %w", path, err)
+	}
+	if len(m.Repos) == 0 {
+		return nil, fmt.Errorf("manifest %s lists no repos", path)
+	}
+	if m.Dir == "" {
+		m.Dir = DefaultDir
+	}
+	if !filepath.IsAbs(m.Dir) {
+		m.Dir = filepath.Join(filepath.Dir(path), m.Dir)
+	}
+	seen := make(map[string]bool)
+	for i := range m.Repos {
+		r := &m.Repos[i]
+		if r.URL == "" {
+			return nil, fmt.Errorf("manifest %s: repos[%d] has no url", path, i)
+		}
+		if r.Name == "" {
+			r.Name = RepoName(r.URL)
+		}
+		if r.Name == "" || r.Name == "." || r.Name == ".." || strings.ContainsAny(r.Name, `/\`) {
+			return nil, fmt.Errorf("manifest %s: repos[%d]: invalid name %q", path, i, r.Name)
+		}
+		if seen[r.Name] {
+			return nil, fmt.Errorf("manifest %s: two repos are named %s; set name on one", path, r.Name)
+		}
+		seen[r.Name] = true
+		if len(r.Packages) == 0 {
+			r.Packages = []string{"./..."}
+		}
+	}
+	return &m, nil
+}
+
+// RepoName returns the name of the repository at url: its last path
+// element without .git
+func RepoName(url string) string {
+	url = strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	if i := strings.LastIndexAny(url, "/:"); i >= 0 {
+		url = url[i+1:]
+	}
+	return url
+}
+
+// Sync clones r into dir, or updates the clone already there to the
+// latest commit of its ref, and returns the commit checked out. Clones
+// are shallow: only the commit analyzed is fetched.
+func Sync(ctx context.Context, r Repo, dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		args := []string{"clone", "--quiet", "--depth=1"}
+		if r.Ref != "" {
+			args = append(args, "--branch", r.Ref)
+		}
+		if _, err := gitcmd.Run(ctx, "", append(args, "--", r.URL, dir)...); err != nil {
+			return "", err
+		}
+	} else {
+		ref := r.Ref
+		if ref == "" {
+			ref = "HEAD"
+		}
+		if _, err := gitcmd.Run(ctx, dir, "fetch", "--quiet", "--depth=1", "origin", ref); err != nil {
+			return "", err
+		}
+		if _, err := gitcmd.Run(ctx, dir, "checkout", "--quiet", "--force", "FETCH_HEAD"); err != nil {
+			return "", err
+		}
+	}
+	return Head(ctx, dir)
+}
+
+// Head returns the commit checked out in the clone in dir
+func Head(ctx context.Context, dir string) (string, error) {
+	return gitcmd.Run(ctx, dir, "rev-parse", "HEAD")
+}
+
+// Result is the outcome of analyzing one repository
+type Result struct {
+	Name           string                       `json:"name"`
+	URL            string                       `json:"url"`
+	Commit         string                       `json:"commit,omitempty"`
+	HeapAllocated  int                          `json:"heapAllocated"`
+	TotalVariables int                          `json:"totalVariables"`
+	ByCategory     map[categorizer.Category]int `json:"byCategory,omitempty"`
+	Error          string                       `json:"error,omitempty"` // set when the repository could not be synced or analyzed
+}
+
+// NewResult summarizes the results of repository r at commit
+func NewResult(r Repo, commit string, results *categorizer.Results) Result {
+	return Result{
+		Name:           r.Name,
+		URL:            r.URL,
+		Commit:         commit,
+		HeapAllocated:  results.Summary.HeapAllocated,
+		TotalVariables: results.Summary.TotalVariables,
+		ByCategory:     results.ByCategory,
+	}
+}
+
+// Failed returns the result of a repository that could not be synced or
+// analyzed
+func Failed(r Repo, commit string, err error) Result {
+	return Result{Name: r.Name, URL: r.URL, Commit: commit, Error: err.Error()}
+}
+
+// Report combines the results of the repositories of a manifest
+type Report struct {
+	Repos         []Result                     `json:"repos"`
+	Analyzed      int                          `json:"analyzed"`
+	HeapAllocated int                          `json:"heapAllocated"`
+	ByCategory    map[categorizer.Category]int `json:"byCategory"`
+}
+
+// Combine adds up the results of the repositories that were analyzed
+func Combine(results []Result) *Report {
+	r := &Report{Repos: results, ByCategory: make(map[categorizer.Category]int)}
+	for _, res := range results {
+		if res.Error != "" {
+			continue
+		}
+		r.Analyzed++
+		r.HeapAllocated += res.HeapAllocated
+		for cat, n := range res.ByCategory {
+			r.ByCategory[cat] += n
+		}
+	}
+	return r
+}
+
+// Failed returns how many repositories could not be analyzed
+func (r *Report) Failed() int {
+	return len(r.Repos) - r.Analyzed
+}
+
+// WriteText writes the report as a table of repositories, most heap
+// escapes first, and the escapes by category across them
+func WriteText(w io.Writer, r *Report) {
+	repos := append([]Result(nil), r.Repos...)
+	sort.SliceStable(repos, func(i, j int) bool {
+		if (repos[i].Error == "") != (repos[j].Error == "") {
+			return repos[i].Error == ""
+		}
+		return repos[i].HeapAllocated > repos[j].HeapAllocated
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPOSITORY\tCOMMIT\tHEAP ESCAPES\tHEAP %\tTOP CATEGORY")
+	for _, res := range repos {
+		if res.Error != "" {
+			fmt.Fprintf(tw, "%s\t%s\t-\t-\terror: %s\n", res.Name, short(res.Commit), parser.FirstLine(res.Error))
+			continue
+		}
+		pct := 0.0
+		if res.TotalVariables > 0 {
+			pct = float64(res.HeapAllocated) / float64(res.TotalVariables) * 100
+		}
+		top := "-"
+		if cats := sortByCount(res.ByCategory); len(cats) > 0 {
+			top = fmt.Sprintf("%s (%d)", cats[0], res.ByCategory[cats[0]])
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%.1f%%\t%s\n", res.Name, short(res.Commit), res.HeapAllocated, pct, top)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%d of %d repo(s) analyzed, %d heap escape(s)\n", r.Analyzed, len(r.Repos), r.HeapAllocated)
+	if len(r.ByCategory) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nBy category across repos:")
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, cat := range sortByCount(r.ByCategory) {
+		fmt.Fprintf(tw, "  %s\t%d\t%s\n", cat, r.ByCategory[cat], reposWith(r.Repos, cat))
+	}
+	tw.Flush()
+}
+
+// reposWith describes how many repositories have escapes of cat
+func reposWith(results []Result, cat categorizer.Category) string {
+	n := 0
+	for _, res := range results {
+		if res.ByCategory[cat] > 0 {
+			n++
+		}
+	}
+	return fmt.Sprintf("in %d repo(s)", n)
+}
+
+// sortByCount returns the categories of counts, largest count first
+func sortByCount(counts map[categorizer.Category]int) []categorizer.Category {
+	cats := make([]categorizer.Category, 0, len(counts))
+	for cat, n := range counts {
+		if n > 0 {
+			cats = append(cats, cat)
+		}
+	}
+	sort.Slice(cats, func(i, j int) bool {
+		if counts[cats[i]] != counts[cats[j]] {
+			return counts[cats[i]] > counts[cats[j]]
+		}
+		return cats[i] < cats[j]
+	})
+	return cats
+}
+
+func short(commit string) string {
+	if commit == "" {
+		return "-"
+	}
+	return commit[:min(len(commit), 7)]
+}
@@ -0,0 +1,157 @@
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/gitcmd"
+)
+
+func TestRepoName(t *testing.T) {
+	tests := []struct {
+		url, want string
+	}{
+		{"https://github.com/org/api", "api"},
+		{"https://github.com/org/api.git", "api"},
+		{"https://github.com/org/api/", "api"},
+		{"git@github.com:org/worker.git", "worker"},
+		{"git@host:worker.git", "worker"},
+		{"/srv/git/tools", "tools"},
+	}
+	for _, tt := range tests {
+		if got := RepoName(tt.url); got != tt.want {
+			t.Errorf("RepoName(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "repos.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("repos:\n  - url: https://github.com/org/api\n  - url: git@github.com:org/api.git\n    name: api-v2\n    ref: v2\n    packages: [./cmd/...]\n")
+	m, err := LoadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Dir != filepath.Join(dir, DefaultDir) {
+		t.Errorf("Dir = %q, want the default next to the manifest", m.Dir)
+	}
+	if r := m.Repos[0]; r.Name != "api" || len(r.Packages) != 1 || r.Packages[0] != "./..." {
+		t.Errorf("Repos[0] = %+v, want the defaults", r)
+	}
+	if r := m.Repos[1]; r.Name != "api-v2" || r.Ref != "v2" || r.Packages[0] != "./cmd/..." {
+		t.Errorf("Repos[1] = %+v", r)
+	}
+
+	for content, want := range map[string]string{
+		"repos: []\n":                          "no repos",
+		"repos:\n  - ref: main\n":              "no url",
+		"repos:\n  - url: a/x\n  - url: b/x\n": "two repos are named x",
+		"repos:\n  - url: a\n    name: ../x\n": "invalid name",
+	} {
+		write(content)
+		if _, err := LoadManifest(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadManifest(%q) error = %v, want %q", content, err, want)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	origin := t.TempDir()
+	commit := func(content string) string {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(origin, "main.go"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{{"add", "."}, {"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "update"}} {
+			if _, err := gitcmd.Run(ctx, origin, args...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		head, err := Head(ctx, origin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return head
+	}
+	if _, err := gitcmd.Run(ctx, origin, "init", "--quiet"); err != nil {
+		t.Fatal(err)
+	}
+	first := commit("package main\n")
+
+	clone := filepath.Join(t.TempDir(), "clones", "app")
+	repo := Repo{URL: "file://" + origin, Name: "app"}
+	if got, err := Sync(ctx, repo, clone); err != nil || got != first {
+		t.Fatalf("Sync() = %q, %v, want the clone at %s", got, err, first)
+	}
+
+	// A second sync fetches the new commit
+	second := commit("package main\n\nfunc main() {}\n")
+	if got, err := Sync(ctx, repo, clone); err != nil || got != second {
+		t.Fatalf("Sync() = %q, %v, want the clone updated to %s", got, err, second)
+	}
+
+	if _, err := Sync(ctx, Repo{URL: "file://" + filepath.Join(origin, "missing")}, filepath.Join(t.TempDir(), "x")); err == nil {
+		t.Error("Sync() of a missing repository succeeded")
+	}
+}
+
+func TestCombine(t *testing.T) {
+	api := Repo{Name: "api", URL: "https://github.com/org/api"}
+	worker := Repo{Name: "worker", URL: "https://github.com/org/worker"}
+	results := []Result{
+		NewResult(api, "0123456789abcdef", &categorizer.Results{
+			Summary:    categorizer.Summary{TotalVariables: 100, HeapAllocated: 10},
+			ByCategory: map[categorizer.Category]int{categorizer.CategoryInterfaceBoxing: 7, categorizer.CategoryFmtCall: 3},
+		}),
+		NewResult(worker, "fedcba9876543210", &categorizer.Results{
+			Summary:    categorizer.Summary{TotalVariables: 40, HeapAllocated: 20},
+			ByCategory: map[categorizer.Category]int{categorizer.CategoryInterfaceBoxing: 5, categorizer.CategorySliceGrow: 15},
+		}),
+		Failed(Repo{Name: "legacy"}, "", errors.New("git clone: exit status 128: repository not found")),
+	}
+
+	r := Combine(results)
+	if r.Analyzed != 2 || r.Failed() != 1 || r.HeapAllocated != 30 {
+		t.Errorf("Combine() = %+v, want 2 analyzed, 1 failed, 30 escapes", r)
+	}
+	if r.ByCategory[categorizer.CategoryInterfaceBoxing] != 12 {
+		t.Errorf("interface-boxing across repos = %d, want 12", r.ByCategory[categorizer.CategoryInterfaceBoxing])
+	}
+
+	var buf bytes.Buffer
+	WriteText(&buf, r)
+	out := buf.String()
+	for _, want := range []string{
+		"worker      fedcba9  20            50.0%   slice-grow (15)",
+		"api         0123456  10            10.0%   interface-boxing (7)",
+		"legacy      -        -             -       error: git clone",
+		"2 of 3 repo(s) analyzed, 30 heap escape(s)",
+		"interface-boxing  12  in 2 repo(s)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteText() missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "worker") > strings.Index(out, "api ") {
+		t.Errorf("WriteText() does not list the most escapes first:\n%s", out)
+	}
+}
@@ -0,0 +1,30 @@
+package categorizer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/bench"
+	"github.com/harshakonda/heapcheck/internal/parser"
+	"github.com/harshakonda/heapcheck/internal/synth"
+)
+
+// BenchmarkCategorize categorizes the escapes of synthetic -m=2 output.
+// Target: the escapes of 100k lines in under 250ms on one core of a
+// current laptop. Allocations are guarded against
+// testdata/heapcheck-bench.json.
+func BenchmarkCategorize(b *testing.B) {
+	for _, lines := range []int{10_000, 100_000} {
+		escapes, err := parser.Parse(synth.CompilerOutput(lines))
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("lines=%d", lines), func(b *testing.B) {
+			b.ReportAllocs()
+			bench.Guard(b)
+			for i := 0; i < b.N; i++ {
+				CategorizeWith(escapes)
+			}
+		})
+	}
+}
@@ -3,7 +3,11 @@
 package categorizer
 
 import (
+	"fmt"
+	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/harshakonda/heapcheck/internal/parser"
 )
@@ -15,6 +19,7 @@ const (
 	CategoryReturnPointer    Category = "return-pointer"
 	CategoryInterfaceBoxing  Category = "interface-boxing"
 	CategoryClosureCapture   Category = "closure-capture"
+	CategoryStoredClosure    Category = "stored-closure"
 	CategoryGoroutineEscape  Category = "goroutine-escape"
 	CategoryChannelSend      Category = "channel-send"
 	CategorySliceGrow        Category = "slice-grow"
@@ -22,7 +27,11 @@ const (
 	CategoryTooLarge         Category = "too-large"
 	CategoryFmtCall          Category = "fmt-call"
 	CategoryReflection       Category = "reflection"
+	CategoryContextValue     Category = "context-value"
+	CategoryPoolMisuse       Category = "pool-misuse"
+	CategoryErrorWrapping    Category = "error-wrapping"
 	CategoryLeakingParam     Category = "leaking-param"
+	CategoryLeakingContent   Category = "leaking-param-content"
 	CategoryStringConversion Category = "string-conversion"
 	CategorySpill            Category = "spill"
 	CategoryAssignment       Category = "assignment"
@@ -30,9 +39,15 @@ const (
 	CategoryMapAllocation    Category = "map-allocation"
 	CategoryNewAllocation    Category = "new-allocation"
 	CategoryCompositeLiteral Category = "composite-literal"
+	CategoryCompilerForced   Category = "compiler-forced"
 	CategoryUncategorized    Category = "uncategorized"
 )
 
+// CategoryLargeValueCopy is an advisory category: it is never assigned to
+// escapes and has no gate, but names the large values copied by value
+// around them, listed in Results.LargeCopies
+const CategoryLargeValueCopy Category = "large-value-copy"
+
 // Suggestion provides optimization advice for a category
 type Suggestion struct {
 	Short   string `json:"short"`
@@ -45,15 +60,223 @@ type CategorizedEscape struct {
 	Info       parser.EscapeInfo `json:"info"`
 	Category   Category          `json:"category"`
 	Suggestion Suggestion        `json:"suggestion"`
+
+	// Func is the function the escape is in, e.g. "(*Server).Handle";
+	// empty when its source is unavailable or outside any function
+	Func string `json:"func,omitempty"`
+
+	// Coverage is CoverageCovered or CoverageUncovered when a coverage
+	// profile was given, empty otherwise
+	Coverage string `json:"coverage,omitempty"`
+
+	// Benchmarked is BenchmarkedYes or BenchmarkedNo when benchmark
+	// results were given, empty otherwise or when the escape's function
+	// is unknown
+	Benchmarked string `json:"benchmarked,omitempty"`
+
+	// FirstSeen is the date (YYYY-MM-DD) the escape first appeared in the
+	// baseline, empty without a baseline
+	FirstSeen string `json:"firstSeen,omitempty"`
+
+	// Impact estimates the bytes allocated, nil unless requested or when
+	// the size is unknown
+	Impact *Impact `json:"impact,omitempty"`
+
+	// Owners are the CODEOWNERS owners of the escape's file, empty without
+	// an owners file or when no rule matches
+	Owners []string `json:"owners,omitempty"`
+
+	// Size is the size in bytes of a too-large object, when the compiler
+	// message states it, as in "make([]byte, 1048576)" or in its size
+	// detail; 0 otherwise
+	Size int64 `json:"size,omitempty"`
+
+	// Call is the variadic call or []interface{} literal the value is
+	// boxed into, or that the escaping argument slice belongs to
+	Call *VariadicCall `json:"call,omitempty"`
+
+	// Cluster names the construct, as file:line and expression, that
+	// causes this escape together with others, such as a composite literal
+	// and its fields; empty for escapes with a cause of their own
+	Cluster string `json:"cluster,omitempty"`
+
+	// Fix is a unified diff of a mechanical rewrite of the source that
+	// avoids the escape, such as pre-allocating a slice; empty when none
+	// is known
+	Fix string `json:"fix,omitempty"`
+
+	// Effort estimates the work of avoiding the escape, from its category
+	// and whether it is part of an exported API
+	Effort Effort `json:"effort,omitempty"`
+
+	// Severity rates how much the escape matters, from its category and
+	// whether it is in an exported API or in internal code
+	Severity Severity `json:"severity,omitempty"`
+
+	// Init is set for escapes at package initialization, in an init
+	// function or a package-level variable's initializer, which allocate
+	// once per process rather than on every call
+	Init bool `json:"init,omitempty"`
+
+	// InLoop is set for escapes in the body of a for or range loop, which
+	// may allocate on every iteration
+	InLoop bool `json:"inLoop,omitempty"`
+
+	// Score estimates the impact of fixing the escape, set when escapes
+	// are sorted by impact; see Score
+	Score float64 `json:"score,omitempty"`
+}
+
+// Impact estimates how many bytes an escape allocates each time its
+// enclosing function is called
+type Impact struct {
+	Size       int64 `json:"size"`              // bytes per allocation
+	Iterations int64 `json:"iterations"`        // allocations per call: the loop iterations, 1 outside loops
+	Assumed    bool  `json:"assumed,omitempty"` // a loop bound is unknown and Iterations is a guess
+	PerCall    int64 `json:"perCall"`           // Size × Iterations
+}
+
+// String describes the impact, e.g. "~4.8KB per call"
+func (i Impact) String() string {
+	s := "~" + FormatBytes(i.PerCall) + " per call"
+	if i.Assumed {
+		s += fmt.Sprintf(" (assuming %d loop iterations)", i.Iterations)
+	}
+	return s
+}
+
+// FormatBytes formats a byte count with one decimal, e.g. "48B", "4.8KB"
+// or "1.2MB"
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	value, suffix := float64(n)/unit, "KB"
+	for _, next := range []string{"MB", "GB", "TB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return fmt.Sprintf("%.1f%s", value, suffix)
+}
+
+// Coverage statuses of an escape's line
+const (
+	CoverageCovered   = "covered"
+	CoverageUncovered = "uncovered"
+)
+
+// Whether benchmarks exercise an escape's function
+const (
+	BenchmarkedYes = "yes"
+	BenchmarkedNo  = "no"
+)
+
+// AgeLabel describes how long ago firstSeen (YYYY-MM-DD) was, e.g.
+// "new this week" or "6 months old". It returns "" for an invalid date.
+func AgeLabel(firstSeen string, now time.Time) string {
+	t, err := time.Parse("2006-01-02", firstSeen)
+	if err != nil {
+		return ""
+	}
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit + " old"
+		}
+		return strconv.Itoa(n) + " " + unit + "s old"
+	}
+	days := int(now.Sub(t).Hours() / 24)
+	switch {
+	case days < 7:
+		return "new this week"
+	case days < 30:
+		return plural(days/7, "week")
+	case days < 365:
+		return plural(days/30, "month")
+	default:
+		return plural(days/365, "year")
+	}
 }
 
 // Summary holds aggregate statistics
 type Summary struct {
-	TotalVariables int            `json:"totalVariables"`
-	StackAllocated int            `json:"stackAllocated"`
-	HeapAllocated  int            `json:"heapAllocated"`
-	Inlined        int            `json:"inlined"`
-	ByFile         map[string]int `json:"byFile"`
+	TotalVariables       int            `json:"totalVariables"`
+	StackAllocated       int            `json:"stackAllocated"`
+	HeapAllocated        int            `json:"heapAllocated"`
+	Inlined              int            `json:"inlined"`
+	Suppressed           int            `json:"suppressed,omitempty"`
+	Allowed              int            `json:"allowed,omitempty"`
+	CoveredEscapes       int            `json:"coveredEscapes,omitempty"`
+	UncoveredEscapes     int            `json:"uncoveredEscapes,omitempty"`
+	BenchmarkedEscapes   int            `json:"benchmarkedEscapes,omitempty"`
+	UnbenchmarkedEscapes int            `json:"unbenchmarkedEscapes,omitempty"`
+	ByFile               map[string]int `json:"byFile"`
+
+	// ByEscapeType counts variables by the compiler's verdict, named as
+	// parser.EscapeType names it: "does-not-escape", "moved-to-heap",
+	// "escapes-to-heap", "leaking-param" and "leaking-param-content".
+	// Suppressed and allowed
+	// escapes are left out, as from HeapAllocated.
+	ByEscapeType map[string]int `json:"byEscapeType,omitempty"`
+
+	// ByOwner counts escapes by CODEOWNERS owner, nil without an owners file
+	ByOwner map[string]int `json:"byOwner,omitempty"`
+
+	// TooLargeBytes totals the known sizes of too-large objects by
+	// package, nil when there are none
+	TooLargeBytes map[string]int64 `json:"tooLargeBytes,omitempty"`
+
+	// Inlining is nil when the output has no inlining decisions
+	Inlining *InliningStats `json:"inlining,omitempty"`
+
+	// Skipped lists the packages cgo failed to build, which were analyzed
+	// without their cgo files or not at all
+	Skipped []parser.SkippedPackage `json:"skipped,omitempty"`
+}
+
+// InliningStats summarizes the compiler's inlining decisions
+type InliningStats struct {
+	Inlinable    int            `json:"inlinable"`             // functions that can be inlined
+	CallsInlined int            `json:"callsInlined"`          // call sites inlined
+	Failed       int            `json:"failed"`                // functions that cannot be inlined
+	FailReasons  map[string]int `json:"failReasons,omitempty"` // failures by reason
+}
+
+// InlineFailureReason reduces the compiler's reason for not inlining a
+// function to its kind, dropping details such as the cost in "function
+// too complex: cost 98 exceeds budget 80"
+func InlineFailureReason(reason string) string {
+	kind, _, _ := strings.Cut(reason, ": ")
+	return kind
+}
+
+// addInlining counts an inlining decision in s
+func (s *Summary) addInlining(e parser.EscapeInfo) {
+	if s.Inlining == nil {
+		s.Inlining = &InliningStats{FailReasons: make(map[string]int)}
+	}
+	switch e.EscapeType {
+	case parser.CanInline:
+		s.Inlining.Inlinable++
+	case parser.InliningCall:
+		s.Inlining.CallsInlined++
+	case parser.CannotInline:
+		s.Inlining.Failed++
+		s.Inlining.FailReasons[InlineFailureReason(e.Reason)]++
+	}
+}
+
+// addTooLarge adds the size of a too-large object in pkg, if known
+func (s *Summary) addTooLarge(pkg string, size int64) {
+	if size <= 0 {
+		return
+	}
+	if s.TooLargeBytes == nil {
+		s.TooLargeBytes = make(map[string]int64)
+	}
+	s.TooLargeBytes[pkg] += size
 }
 
 // Results holds the complete categorization results
@@ -61,6 +284,160 @@ type Results struct {
 	Summary    Summary             `json:"summary"`
 	ByCategory map[Category]int    `json:"byCategory"`
 	Escapes    []CategorizedEscape `json:"escapes"`
+
+	// Suppressions lists suppression comments and baseline entries that
+	// matched escapes in this run, with their expiry status
+	Suppressions []SuppressionStatus `json:"suppressions,omitempty"`
+
+	// InterfaceParams lists the interface parameters that boxing escapes
+	// are passed to, most call sites first
+	InterfaceParams []InterfaceParam `json:"interfaceParams,omitempty"`
+
+	// Goroutines groups the escapes caused by goroutines and channels by
+	// the function that spawns them, most escapes first
+	Goroutines []GoroutineGroup `json:"goroutines,omitempty"`
+
+	// Handlers groups the escapes inside HTTP handlers by handler, most
+	// escapes first
+	Handlers []HandlerGroup `json:"handlers,omitempty"`
+
+	// LargeCopies lists the large values that calls around the escapes
+	// copy, most bytes copied first; advisory, in CategoryLargeValueCopy
+	LargeCopies []LargeCopy `json:"largeCopies,omitempty"`
+}
+
+// InterfaceParam is an interface{}/any (or other interface) parameter
+// that callers box values into
+type InterfaceParam struct {
+	Func      string `json:"func"`      // e.g. "fmt.Println"
+	Param     string `json:"param"`     // e.g. "a ...any"
+	Position  string `json:"position"`  // declaration, file:line
+	CallSites int    `json:"callSites"` // distinct calls boxing into it
+	Escapes   int    `json:"escapes"`
+}
+
+// LargeCopy is a parameter, result or receiver of a function that is a
+// large struct or array passed by value
+type LargeCopy struct {
+	Func      string `json:"func"`      // e.g. "example.com/app.Apply" or "(example.com/app.Config).Describe"
+	Value     string `json:"value"`     // "receiver", "param c" or "result 0", with the index of an unnamed one
+	Type      string `json:"type"`      // e.g. "app.Config"
+	Size      int64  `json:"size"`      // bytes copied per call
+	Position  string `json:"position"`  // declaration, file:line
+	CallSites int    `json:"callSites"` // calls in functions with escapes
+}
+
+// GoroutineGroup collects the goroutine and channel escapes of one
+// function, which usually share a structural fix such as a worker pool
+type GoroutineGroup struct {
+	Func       string           `json:"func"`     // e.g. "ProcessTasks" or "(*Pool).Start"
+	Position   string           `json:"position"` // declaration, file:line
+	Escapes    int              `json:"escapes"`
+	ByCategory map[Category]int `json:"byCategory"`
+}
+
+// HandlerGroup collects the escapes of one HTTP handler, the allocations
+// each request to its routes pays for
+type HandlerGroup struct {
+	Handler    string           `json:"handler"`          // e.g. "listUsers" or "(*Server).getUser"
+	Routes     []string         `json:"routes,omitempty"` // e.g. "GET /users", as registered
+	Position   string           `json:"position"`         // declaration, file:line
+	Escapes    int              `json:"escapes"`
+	ByCategory map[Category]int `json:"byCategory"`
+}
+
+// String describes the handler by its routes, falling back to its name,
+// e.g. "GET /users, /v1/users (listUsers)"
+func (g HandlerGroup) String() string {
+	if len(g.Routes) == 0 {
+		return g.Handler
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(g.Routes, ", "), g.Handler)
+}
+
+// Gate statuses, in increasing severity
+const (
+	GatePass = "pass"
+	GateWarn = "warn"
+	GateFail = "fail"
+)
+
+// CategoryGate is the gate result for one category. Threshold is the
+// limit that decided Status (for a passing category, the nearest limit,
+// or -1 if the rule is off) and Margin is Count minus Threshold:
+// positive by how much the limit was exceeded, otherwise the headroom.
+type CategoryGate struct {
+	Category Category `json:"category"`
+
+	// Dir is the directory of the nested config whose rule this is, for
+	// the escapes below it; empty for the root config
+	Dir string `json:"dir,omitempty"`
+
+	Count     int    `json:"count"`
+	Rule      string `json:"rule"`
+	Status    string `json:"status"`
+	Threshold int    `json:"threshold"`
+	Margin    int    `json:"margin"`
+}
+
+// Name is the category, followed by the directory of the nested config
+// it was gated by, if any, e.g. "interface-boxing (legacy/)"
+func (c CategoryGate) Name() string {
+	if c.Dir == "" {
+		return string(c.Category)
+	}
+	return fmt.Sprintf("%s (%s/)", c.Category, c.Dir)
+}
+
+// GateResult is the overall gate result and its per-category breakdown
+type GateResult struct {
+	Status     string         `json:"status"`
+	Categories []CategoryGate `json:"categories"`
+}
+
+// FlowsInto returns the escapes whose flow reaches sink
+func (r *Results) FlowsInto(sink parser.Sink) []CategorizedEscape {
+	matched := make([]CategorizedEscape, 0)
+	for _, e := range r.Escapes {
+		if e.Info.HasSink(sink) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+// UncategorizedPct returns the percentage of escapes no rule
+// categorized, 0 without escapes
+func (r *Results) UncategorizedPct() float64 {
+	if len(r.Escapes) == 0 {
+		return 0
+	}
+	n := 0
+	for _, e := range r.Escapes {
+		if e.Category == CategoryUncategorized {
+			n++
+		}
+	}
+	return 100 * float64(n) / float64(len(r.Escapes))
+}
+
+// Suppression statuses
+const (
+	SuppressionActive   = "active"
+	SuppressionExpiring = "expiring"
+	SuppressionExpired  = "expired"
+)
+
+// SuppressionStatus describes a suppression that matched an escape
+type SuppressionStatus struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Variable string   `json:"variable"`
+	Category Category `json:"category"`
+	Source   string   `json:"source"` // "comment" or "baseline"
+	Owner    string   `json:"owner,omitempty"`
+	Expires  string   `json:"expires,omitempty"`
+	Status   string   `json:"status"`
 }
 
 // suggestions maps categories to their suggestions
@@ -78,249 +455,260 @@ var suggestions = map[Category]Suggestion{
 	CategoryClosureCapture: {
 		Short:   "Pass variables as parameters instead of capturing",
 		Details: "Variables captured by closures often escape. Pass them as function parameters instead, especially for goroutines.",
+		DocLink: "https://go.dev/doc/faq#closures_and_goroutines",
+	},
+	CategoryStoredClosure: {
+		Short:   "Capture only the state a stored callback needs",
+		Details: "A func literal stored in a struct field or package variable, such as a callback or hook, outlives the call that creates it, so the closure and every variable it captures are heap allocated and kept alive as long as it is stored. Copy the few values the callback uses into locals before creating it, rather than capturing whole structs or request state, or store a method value or a small type implementing an interface.",
+		DocLink: "https://go.dev/ref/spec#Function_literals",
 	},
 	CategoryGoroutineEscape: {
 		Short:   "Consider worker pools for high-frequency goroutines",
 		Details: "Variables passed to goroutines must outlive the creating function and thus escape. For high-throughput scenarios, use worker pools with pre-allocated buffers.",
+		DocLink: "https://go.dev/doc/effective_go#goroutines",
 	},
 	CategoryChannelSend: {
 		Short:   "Buffer channels or use sync.Pool for sent values",
 		Details: "Values sent on channels may escape. For frequently sent large objects, consider using sync.Pool.",
+		DocLink: "https://pkg.go.dev/sync#Pool",
 	},
 	CategorySliceGrow: {
 		Short:   "Pre-allocate slice capacity",
 		Details: "Slices that may grow via append can escape. Pre-allocate with make([]T, 0, expectedCap) when the final size is predictable.",
+		DocLink: "https://go.dev/blog/slices-intro",
 	},
 	CategoryUnknownSize: {
 		Short:   "Use fixed-size arrays when length is known",
 		Details: "make([]T, n) with non-constant n causes heap allocation. If size is known at compile time, use arrays [N]T or pre-allocate.",
+		DocLink: "https://go.dev/doc/effective_go#allocation_make",
 	},
 	CategoryTooLarge: {
 		Short:   "Large allocations go to heap by design",
 		Details: "Very large structs or arrays are placed on heap regardless of escape. Consider if the full size is necessary or if you can use pointers to smaller chunks.",
+		DocLink: "https://go.dev/doc/faq#stack_or_heap",
 	},
 	CategoryFmtCall: {
 		Short:   "Use strconv in hot paths",
 		Details: "fmt.Sprintf and similar cause interface boxing. Use strconv.Itoa, strconv.FormatFloat, etc. for simple conversions in hot paths.",
+		DocLink: "https://pkg.go.dev/strconv",
 	},
 	CategoryReflection: {
 		Short:   "Avoid reflect in hot paths",
 		Details: "Reflection defeats escape analysis. Avoid reflect package in performance-critical code; use code generation or generics instead.",
+		DocLink: "https://go.dev/blog/laws-of-reflection",
+	},
+	CategoryContextValue: {
+		Short:   "Use typed context keys and one pointer value",
+		Details: "context.WithValue boxes its key and value into interfaces and allocates a new context on every call. Use an unexported zero-size key type (type userKey struct{}), which boxes without allocating, store a single pointer to request-scoped data instead of many values, and keep WithValue out of per-item loops.",
+		DocLink: "https://pkg.go.dev/context#WithValue",
+	},
+	CategoryPoolMisuse: {
+		Short:   "Put pointers into sync.Pool and stop using them after Put",
+		Details: "A value from a sync.Pool belongs to the code between its Get and its Put. Keeping it beyond the Put, in a returned value, a struct, a slice or a goroutine, hands the same object to the next Get while it is still in use, a data race, and the pool no longer saves its allocation. Putting a slice or another non-pointer boxes it into an interface, which allocates on every Put. Copy what must outlive the Put or move the Put after the last use, and pool pointers such as *[]byte.",
+		DocLink: "https://pkg.go.dev/sync#Pool",
+	},
+	CategoryErrorWrapping: {
+		Short:   "Return sentinel or typed errors in hot paths",
+		Details: "errors.New and fmt.Errorf allocate on every call, and fmt.Errorf also boxes each argument. Declare package-level sentinel errors (var ErrNotFound = errors.New(\"not found\")), wrap with a small typed error struct instead of formatting, and use errors.Join only on the error path.",
+		DocLink: "https://go.dev/blog/go1.13-errors",
 	},
 	CategoryLeakingParam: {
 		Short:   "Parameter escapes function scope",
 		Details: "This parameter is stored or returned, causing it to escape. Consider if the storage is necessary or if you can restructure to avoid it.",
+		DocLink: "https://go.dev/doc/gc-guide#Eliminating_heap_allocations",
+	},
+	CategoryLeakingContent: {
+		Short:   "Parameter's contents escape: copy what you keep",
+		Details: "The parameter itself stays put, but what it points to escapes: the backing array of a slice, the entries of a map or the target of a pointer is stored, returned or sent elsewhere, so callers cannot keep that data on the stack. Copy the elements you keep (slices.Clone, maps.Clone or a value copy) rather than retaining the caller's slice or map, or take the contents by value.",
+		DocLink: "https://go.dev/doc/gc-guide#Eliminating_heap_allocations",
+	},
+	CategoryCompilerForced: {
+		Short:   "Structural; unlikely fixable: forced by the compiler's analysis",
+		Details: "Escape analysis cannot follow a value into a recursive call, so values passed to recursive functions go to the heap, and a variadic argument slice goes to the heap when the callee keeps it or cannot be seen, as with a function value or interface method. Neither is a pattern this call site can fix: only restructuring the recursion into a loop, or changing the callee, would. These are counted apart so they do not crowd out actionable escapes.",
+		DocLink: "https://go.dev/doc/gc-guide#Escape_analysis",
 	},
 	CategoryStringConversion: {
 		Short:   "String conversion allocates",
 		Details: "Converting []byte to string (or vice versa) allocates. In hot paths, consider using unsafe conversion or reusing buffers.",
+		DocLink: "https://go.dev/doc/gc-guide#Eliminating_heap_allocations",
 	},
 	CategorySpill: {
 		Short:   "Compiler spilled value to heap",
 		Details: "The compiler determined this value may outlive the stack frame. Check if the value is stored in a long-lived data structure.",
+		DocLink: "https://go.dev/doc/gc-guide#Eliminating_heap_allocations",
 	},
 	CategoryAssignment: {
 		Short:   "Value assigned to escaping location",
 		Details: "This value is assigned to a variable that escapes (field, global, etc.). Consider if the assignment is necessary.",
+		DocLink: "https://go.dev/doc/faq#stack_or_heap",
 	},
 	CategoryCallParameter: {
 		Short:   "Value escapes via function call",
 		Details: "This value is passed to a function that causes it to escape. Check if the called function stores the parameter.",
+		DocLink: "https://go.dev/doc/gc-guide#Eliminating_heap_allocations",
 	},
 	CategoryMapAllocation: {
 		Short:   "Maps always allocate on heap",
 		Details: "Maps in Go always escape to heap. Consider using arrays for small fixed-size lookups, or sync.Pool for frequently created maps.",
+		DocLink: "https://go.dev/blog/maps",
 	},
 	CategoryNewAllocation: {
 		Short:   "new() always allocates on heap",
 		Details: "The new() builtin allocates on heap. For small structs, consider stack allocation with var x T followed by &x if needed.",
+		DocLink: "https://go.dev/doc/effective_go#allocation_new",
 	},
 	CategoryCompositeLiteral: {
 		Short:   "Composite literal escapes",
 		Details: "Struct/slice/map literals that escape the function are heap allocated. For hot paths, consider reusing allocations.",
+		DocLink: "https://go.dev/doc/effective_go#composite_literals",
 	},
 	CategoryUncategorized: {
 		Short:   "Review escape flow details",
 		Details: "This escape couldn't be automatically categorized. Check the flow information for details on why the variable escapes.",
+		DocLink: "https://go.dev/doc/gc-guide#Eliminating_heap_allocations",
+	},
+	CategoryLargeValueCopy: {
+		Short:   "Keep large structs behind a pointer on hot paths",
+		Details: "Passing, returning or calling a method on a large struct by value copies all of it on every call. Returning values instead of pointers keeps small structs off the heap, but for structs of hundreds of bytes the copies can cost more than the allocation saved. Pass a pointer the callee does not retain, or fill in a caller-provided value.",
+		DocLink: "https://go.dev/wiki/CodeReviewComments#pass-values",
 	},
 }
 
-// Categorize processes escape info and adds categories and suggestions
+// Categorize processes escape info and adds categories and suggestions,
+// consulting registered categorizers before the built-in rules
 func Categorize(escapes []parser.EscapeInfo) *Results {
+	return CategorizeWith(escapes, Registered()...)
+}
+
+// CategorizeWith is Categorize with an explicit list of custom
+// categorizers instead of the registered ones
+func CategorizeWith(escapes []parser.EscapeInfo, custom ...Categorizer) *Results {
 	results := &Results{
 		Summary: Summary{
-			ByFile: make(map[string]int),
+			ByFile:       make(map[string]int),
+			ByEscapeType: make(map[string]int),
 		},
 		ByCategory: make(map[Category]int),
 		Escapes:    make([]CategorizedEscape, 0, len(escapes)),
 	}
+	src := newSourceCache()
+	src.prefetch(heapFiles(escapes), runtime.GOMAXPROCS(0))
+	src.recursive = recursiveFuncs(escapes)
 
 	for _, e := range escapes {
+		// Inlining failures are about functions, not variables
+		if e.EscapeType == parser.CannotInline {
+			results.Summary.addInlining(e)
+			continue
+		}
 		results.Summary.TotalVariables++
 
 		switch e.EscapeType {
 		case parser.DoesNotEscape:
 			results.Summary.StackAllocated++
-		case parser.MovedToHeap, parser.EscapesToHeap, parser.LeakingParam:
+			results.Summary.ByEscapeType[e.EscapeType.String()]++
+		case parser.MovedToHeap, parser.EscapesToHeap, parser.LeakingParam, parser.LeakingParamContent:
 			results.Summary.HeapAllocated++
+			results.Summary.ByEscapeType[e.EscapeType.String()]++
 			results.Summary.ByFile[e.File]++
 
-			cat := categorize(e)
+			cat, suggestion := classify(e, custom, src)
 			results.ByCategory[cat]++
 
+			var size int64
+			if cat == CategoryTooLarge {
+				size = objectSize(e.Variable)
+				if size == 0 {
+					size = e.Size
+				}
+				results.Summary.addTooLarge(e.PackageOrDir(), size)
+			}
+
+			init := src.atInit(e)
 			results.Escapes = append(results.Escapes, CategorizedEscape{
 				Info:       e,
 				Category:   cat,
-				Suggestion: suggestions[cat],
+				Suggestion: suggestion,
+				Func:       src.funcOf(e),
+				Size:       size,
+				Fix:        src.fix(e),
+				Effort:     src.effort(e, cat),
+				Severity:   src.severity(e, cat, init),
+				Init:       init,
+				InLoop:     src.inLoop(e),
 			})
 		case parser.CanInline, parser.InliningCall:
 			results.Summary.Inlined++
+			results.Summary.addInlining(e)
 		}
 	}
 
+	correlateVariadic(results)
+	assignClusters(results.Escapes)
 	return results
 }
 
-// categorize determines the category based on escape info and flow details
-func categorize(e parser.EscapeInfo) Category {
-	reason := strings.ToLower(e.Reason)
-	flowInfo := strings.ToLower(strings.Join(e.FlowInfo, " "))
-	combined := reason + " " + flowInfo
-	variable := strings.ToLower(e.Variable)
-
-	// === HIGH CONFIDENCE PATTERNS ===
-
-	// Return pointer pattern: "from return &x" or "from &x (address-of)"
-	if strings.Contains(flowInfo, "from return") && strings.Contains(flowInfo, "&") {
-		return CategoryReturnPointer
-	}
-	if strings.Contains(flowInfo, "address-of") && strings.Contains(flowInfo, "return") {
-		return CategoryReturnPointer
-	}
-
-	// Interface conversion: "interface-converted" in flow
-	if strings.Contains(flowInfo, "interface-converted") {
-		return CategoryInterfaceBoxing
-	}
-	if strings.Contains(combined, "interface") {
-		return CategoryInterfaceBoxing
-	}
-
-	// Closure capture
-	if strings.Contains(combined, "closure") || strings.Contains(combined, "captured") {
-		return CategoryClosureCapture
-	}
-
-	// Goroutine escape
-	if strings.Contains(combined, "go func") || strings.Contains(combined, "goroutine") {
-		return CategoryGoroutineEscape
-	}
-
-	// Channel operations
-	if strings.Contains(combined, "chan") || strings.Contains(combined, "channel") {
-		return CategoryChannelSend
-	}
-
-	// Slice/append patterns
-	if strings.Contains(combined, "append") {
-		return CategorySliceGrow
-	}
-	if strings.Contains(flowInfo, "appended") {
-		return CategorySliceGrow
-	}
-
-	// Unknown size at compile time
-	if strings.Contains(combined, "non-constant") {
-		return CategoryUnknownSize
-	}
-
-	// Too large for stack
-	if strings.Contains(combined, "too large") {
-		return CategoryTooLarge
-	}
-
-	// fmt package calls
-	if strings.Contains(combined, "fmt.") {
-		return CategoryFmtCall
-	}
-
-	// Reflection
-	if strings.Contains(combined, "reflect") {
-		return CategoryReflection
-	}
-
-	// === MEDIUM CONFIDENCE PATTERNS ===
-
-	// Leaking param often means it's stored somewhere or returned
-	if e.EscapeType == parser.LeakingParam {
-		// Check if it's leaking to result (return value)
-		if strings.Contains(reason, "to result") {
-			return CategoryReturnPointer
-		}
-		// Leaking param content usually means interface boxing or slice
-		if strings.Contains(reason, "content") {
-			return CategoryInterfaceBoxing
+// heapFiles returns the files with heap escapes, the ones categorizing
+// reads, each once
+func heapFiles(escapes []parser.EscapeInfo) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, e := range escapes {
+		switch e.EscapeType {
+		case parser.MovedToHeap, parser.EscapesToHeap, parser.LeakingParam, parser.LeakingParamContent:
+			if !seen[e.File] && !parser.IsSynthetic(e.File) {
+				seen[e.File] = true
+				files = append(files, e.File)
+			}
 		}
-		return CategoryLeakingParam
-	}
-
-	// String conversion often escapes (string(bytes))
-	if strings.Contains(variable, "string(") {
-		return CategoryStringConversion
-	}
-
-	// Spill to heap (compiler decision)
-	if strings.Contains(flowInfo, "spill") {
-		return CategorySpill
 	}
+	return files
+}
 
-	// Moved to heap without clear reason - check flow
-	if e.EscapeType == parser.MovedToHeap {
-		// Check for assign patterns
-		if strings.Contains(flowInfo, "assign") {
-			// Assigned to field or external variable
-			return CategoryAssignment
+// classify returns the category and suggestion from the first custom
+// categorizer that claims e, falling back to the built-in rules. Values
+// boxed into a printf-style fmt call get a suggestion for their format verb
+// when the source is readable.
+func classify(e parser.EscapeInfo, custom []Categorizer, src *sourceCache) (Category, Suggestion) {
+	for _, c := range custom {
+		cat, suggestion, ok := c.Categorize(e)
+		if !ok {
+			continue
 		}
-		// Check for call parameter
-		if strings.Contains(flowInfo, "call parameter") {
-			return CategoryCallParameter
+		if suggestion == (Suggestion{}) {
+			suggestion = GetSuggestion(cat)
 		}
+		return cat, suggestion
 	}
 
-	// Variadic arguments (... interface{})
-	if strings.Contains(variable, "...") || strings.Contains(reason, "... argument") {
-		return CategoryInterfaceBoxing
-	}
-
-	// === LOWER CONFIDENCE PATTERNS ===
-
-	// Map allocations
-	if strings.Contains(variable, "make(map") || strings.Contains(reason, "make(map") {
-		return CategoryMapAllocation
-	}
-
-	// Slice make (not append)
-	if strings.Contains(variable, "make([]") || strings.Contains(reason, "make([]") {
-		return CategorySliceGrow
+	// Checked first, as pooled values are kept by the same closures,
+	// literals and appends as other values
+	if s, ok := src.poolMisuse(e); ok {
+		return CategoryPoolMisuse, s
 	}
 
-	// New allocations
-	if strings.Contains(variable, "new(") || strings.Contains(reason, "new(") {
-		return CategoryNewAllocation
+	if s, ok := compilerForced(e, src.recursive); ok {
+		return CategoryCompilerForced, s
 	}
 
-	// Composite literals (struct{}{}, []T{}, map[]{})
-	if strings.Contains(variable, "literal") || strings.Contains(reason, "literal") {
-		return CategoryCompositeLiteral
+	if target, ok := src.storedClosure(e); ok {
+		suggestion := suggestions[CategoryStoredClosure]
+		suggestion.Short = fmt.Sprintf("Closure stored in %s: capture only the state the callback needs", target)
+		return CategoryStoredClosure, suggestion
 	}
-
-	// &composite literal
-	if strings.Contains(reason, "&") && !strings.Contains(flowInfo, "return") {
-		return CategoryCompositeLiteral
+	cat := categorize(e)
+	if cat == CategoryFmtCall || cat == CategoryInterfaceBoxing {
+		if s, ok := src.fmtSuggestion(e); ok {
+			return cat, s
+		}
 	}
+	return cat, suggestions[cat]
+}
 
-	// === FALLBACK ===
-	return CategoryUncategorized
+// categorize determines the category from the escape's text, by the
+// rules of the current ruleset
+func categorize(e parser.EscapeInfo) Category {
+	return currentRuleset().Categorize(e)
 }
 
 // GetSuggestion returns the suggestion for a category
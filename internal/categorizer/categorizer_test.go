@@ -1,7 +1,10 @@
 package categorizer
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/harshakonda/heapcheck/internal/parser"
 )
@@ -42,6 +45,26 @@ func TestCategorize(t *testing.T) {
 			},
 			expected: CategoryClosureCapture,
 		},
+		{
+			name: "closure stored in a field",
+			escape: parser.EscapeInfo{
+				EscapeType: parser.EscapesToHeap,
+				Variable:   "func literal",
+				Reason:     "func literal escapes to heap",
+				FlowInfo:   []string{"flow: {heap} ← &{storage for func literal}:", "from func literal (spill) at ./cl.go:11:14", "from s.OnClose = func literal (assign) at ./cl.go:11:12"},
+			},
+			expected: CategoryStoredClosure,
+		},
+		{
+			name: "closure set in a struct literal",
+			escape: parser.EscapeInfo{
+				EscapeType: parser.EscapesToHeap,
+				Variable:   "func literal",
+				Reason:     "func literal escapes to heap",
+				FlowInfo:   []string{"from func literal (spill) at ./cl.go:20:26", "from Server{...} (struct literal element) at ./cl.go:20:16"},
+			},
+			expected: CategoryStoredClosure,
+		},
 		{
 			name: "goroutine escape",
 			escape: parser.EscapeInfo{
@@ -112,6 +135,46 @@ func TestCategorize(t *testing.T) {
 			},
 			expected: CategoryReflection,
 		},
+		{
+			name: "context value",
+			escape: parser.EscapeInfo{
+				EscapeType: parser.EscapesToHeap,
+				Variable:   "user",
+				Reason:     "user escapes to heap",
+				FlowInfo:   []string{"from user (spill)", "from context.WithValue(ctx, \"user\", user) (call parameter)"},
+			},
+			expected: CategoryContextValue,
+		},
+		{
+			name: "inlined errors.New",
+			escape: parser.EscapeInfo{
+				EscapeType: parser.EscapesToHeap,
+				Variable:   "&errors.errorString{...}",
+				Reason:     "&errors.errorString{...} escapes to heap",
+				FlowInfo:   []string{"from &errors.errorString{...} (interface-converted)", "from return ~r0 (return)"},
+			},
+			expected: CategoryErrorWrapping,
+		},
+		{
+			name: "fmt.Errorf argument",
+			escape: parser.EscapeInfo{
+				EscapeType: parser.EscapesToHeap,
+				Variable:   "name",
+				Reason:     "name escapes to heap",
+				FlowInfo:   []string{"from ... argument (slice-literal-element)", "from fmt.errorf(fmt.format, fmt.a...) (call parameter)"},
+			},
+			expected: CategoryErrorWrapping,
+		},
+		{
+			name: "package ending in errors",
+			escape: parser.EscapeInfo{
+				EscapeType: parser.EscapesToHeap,
+				Variable:   "apperrors.Default",
+				Reason:     "apperrors.Default escapes to heap",
+				FlowInfo:   []string{"from apperrors.Default (spill)"},
+			},
+			expected: CategorySpill,
+		},
 		{
 			name: "leaking param to result",
 			escape: parser.EscapeInfo{
@@ -125,12 +188,12 @@ func TestCategorize(t *testing.T) {
 		{
 			name: "leaking param content",
 			escape: parser.EscapeInfo{
-				EscapeType: parser.LeakingParam,
+				EscapeType: parser.LeakingParamContent,
 				Variable:   "data",
 				Reason:     "leaking param content: data",
 				FlowInfo:   []string{},
 			},
-			expected: CategoryInterfaceBoxing,
+			expected: CategoryLeakingContent,
 		},
 		{
 			name: "leaking param generic",
@@ -209,6 +272,8 @@ func TestGetSuggestion(t *testing.T) {
 		CategoryTooLarge,
 		CategoryFmtCall,
 		CategoryReflection,
+		CategoryContextValue,
+		CategoryErrorWrapping,
 		CategoryLeakingParam,
 		CategoryStringConversion,
 		CategorySpill,
@@ -293,3 +358,184 @@ func TestCategorizeCountsCorrectly(t *testing.T) {
 		t.Errorf("expected 2 inlined, got %d", results.Summary.Inlined)
 	}
 }
+
+func TestUncategorizedPct(t *testing.T) {
+	results := &Results{}
+	if got := results.UncategorizedPct(); got != 0 {
+		t.Errorf("UncategorizedPct() without escapes = %v, want 0", got)
+	}
+	results.Escapes = []CategorizedEscape{
+		{Category: CategoryUncategorized},
+		{Category: CategoryReturnPointer},
+		{Category: CategoryInterfaceBoxing},
+		{Category: CategoryFmtCall},
+	}
+	if got := results.UncategorizedPct(); got != 25 {
+		t.Errorf("UncategorizedPct() = %v, want 25", got)
+	}
+}
+
+func TestHandlerGroupString(t *testing.T) {
+	tests := []struct {
+		group HandlerGroup
+		want  string
+	}{
+		{HandlerGroup{Handler: "listUsers", Routes: []string{"GET /users", "/v1/users"}}, "GET /users, /v1/users (listUsers)"},
+		{HandlerGroup{Handler: "(*Server).ServeHTTP"}, "(*Server).ServeHTTP"},
+	}
+	for _, tt := range tests {
+		if got := tt.group.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestInliningStats(t *testing.T) {
+	escapes := []parser.EscapeInfo{
+		{EscapeType: parser.CanInline, Variable: "square"},
+		{EscapeType: parser.InliningCall, Variable: "square"},
+		{EscapeType: parser.InliningCall, Variable: "fmt.Println"},
+		{EscapeType: parser.CannotInline, Variable: "run", Reason: "function too complex: cost 98 exceeds budget 80"},
+		{EscapeType: parser.CannotInline, Variable: "serve", Reason: "function too complex: cost 120 exceeds budget 80"},
+		{EscapeType: parser.CannotInline, Variable: "noop", Reason: "marked go:noinline"},
+		{EscapeType: parser.DoesNotEscape, Variable: "a"},
+	}
+
+	results := Categorize(escapes)
+
+	want := InliningStats{
+		Inlinable:    1,
+		CallsInlined: 2,
+		Failed:       3,
+		FailReasons:  map[string]int{"function too complex": 2, "marked go:noinline": 1},
+	}
+	if got := results.Summary.Inlining; got == nil || !reflect.DeepEqual(*got, want) {
+		t.Errorf("Inlining = %+v, want %+v", got, want)
+	}
+	if results.Summary.TotalVariables != 4 {
+		t.Errorf("TotalVariables = %d, want 4 (inlining failures are not variables)", results.Summary.TotalVariables)
+	}
+
+	if got := Categorize(escapes[6:]).Summary.Inlining; got != nil {
+		t.Errorf("Inlining without inlining output = %+v, want nil", got)
+	}
+}
+
+func TestImpactString(t *testing.T) {
+	tests := []struct {
+		impact Impact
+		want   string
+	}{
+		{Impact{Size: 48, Iterations: 1, PerCall: 48}, "~48B per call"},
+		{Impact{Size: 4915, Iterations: 1, PerCall: 4915}, "~4.8KB per call"},
+		{Impact{Size: 160, Iterations: 10, Assumed: true, PerCall: 1600}, "~1.6KB per call (assuming 10 loop iterations)"},
+		{Impact{Size: 1 << 20, Iterations: 3, PerCall: 3 << 20}, "~3.0MB per call"},
+	}
+	for _, tt := range tests {
+		if got := tt.impact.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.impact, got, tt.want)
+		}
+	}
+}
+
+func TestAgeLabel(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		firstSeen string
+		want      string
+	}{
+		{"2024-06-01", "new this week"},
+		{"2024-05-27", "new this week"},
+		{"2024-05-25", "1 week old"},
+		{"2024-05-10", "3 weeks old"},
+		{"2023-12-01", "6 months old"},
+		{"2022-05-01", "2 years old"},
+		{"", ""},
+		{"yesterday", ""},
+	}
+	for _, tt := range tests {
+		if got := AgeLabel(tt.firstSeen, now); got != tt.want {
+			t.Errorf("AgeLabel(%q) = %q, want %q", tt.firstSeen, got, tt.want)
+		}
+	}
+}
+
+func TestResultsJSONRoundTrip(t *testing.T) {
+	results := &Results{
+		Summary: Summary{
+			TotalVariables: 3,
+			StackAllocated: 1,
+			HeapAllocated:  2,
+			Inlined:        1,
+			Suppressed:     1,
+			ByFile:         map[string]int{"./main.go": 2},
+			ByEscapeType:   map[string]int{"does-not-escape": 1, "moved-to-heap": 1, "escapes-to-heap": 1},
+			ByOwner:        map[string]int{"@team": 2},
+			TooLargeBytes:  map[string]int64{"example.com/app": 1 << 20},
+			Inlining:       &InliningStats{Inlinable: 1, Failed: 1, FailReasons: map[string]int{"too complex": 1}},
+			Skipped:        []parser.SkippedPackage{{Package: "example.com/app/cgo", Reason: "cgo failed", PureGo: true}},
+		},
+		ByCategory: map[Category]int{CategoryReturnPointer: 1, CategoryInterfaceBoxing: 1},
+		Escapes: []CategorizedEscape{
+			{
+				Info: parser.EscapeInfo{
+					Package: "example.com/app", File: "./main.go", Line: 12, Column: 2,
+					Variable: "u", EscapeType: parser.MovedToHeap, Reason: "moved to heap",
+					Flows: []parser.Flow{{Dst: "~r0", Src: "&u"}},
+				},
+				Category:   CategoryReturnPointer,
+				Suggestion: suggestions[CategoryReturnPointer],
+				Func:       "NewUser",
+				Coverage:   CoverageCovered,
+				FirstSeen:  "2026-01-02",
+				Impact:     &Impact{Size: 48, Iterations: 1, PerCall: 48},
+				Owners:     []string{"@team"},
+				Effort:     EffortStructural,
+			},
+			{
+				Info: parser.EscapeInfo{
+					File: "./main.go", Line: 20, Column: 14,
+					Variable: "n", EscapeType: parser.EscapesToHeap, Reason: "escapes to heap",
+				},
+				Category:   CategoryInterfaceBoxing,
+				Suggestion: suggestions[CategoryInterfaceBoxing],
+				Call:       &VariadicCall{Position: "./main.go:20:13", Func: "main", Args: 1},
+				Cluster:    "./main.go:20 fmt.Println(n)",
+				Effort:     EffortModerate,
+			},
+		},
+		Suppressions:    []SuppressionStatus{{File: "./main.go", Line: 30, Variable: "buf", Category: CategorySliceGrow, Source: "comment", Status: "active"}},
+		InterfaceParams: []InterfaceParam{{Func: "fmt.Println", Param: "a ...any", Position: "print.go:1", CallSites: 1, Escapes: 1}},
+		Goroutines:      []GoroutineGroup{{Func: "main", Position: "./main.go:5", Escapes: 1, ByCategory: map[Category]int{CategoryGoroutineEscape: 1}}},
+		Handlers:        []HandlerGroup{{Handler: "listUsers", Routes: []string{"GET /users"}, Position: "./main.go:40", Escapes: 1, ByCategory: map[Category]int{CategoryReturnPointer: 1}}},
+	}
+
+	data, err := json.Marshal(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Results
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, results) {
+		t.Errorf("round trip changed the results:\n got %+v\nwant %+v", got, *results)
+	}
+}
+
+func TestSummaryByEscapeType(t *testing.T) {
+	escapes := []parser.EscapeInfo{
+		{File: "a.go", Line: 1, Column: 2, Variable: "x", EscapeType: parser.MovedToHeap, Reason: "moved to heap"},
+		{File: "a.go", Line: 2, Column: 2, Variable: "y", EscapeType: parser.MovedToHeap, Reason: "moved to heap"},
+		{File: "a.go", Line: 3, Column: 7, Variable: "s", EscapeType: parser.EscapesToHeap, Reason: "escapes to heap"},
+		{File: "a.go", Line: 4, Column: 9, Variable: "p", EscapeType: parser.LeakingParam, Reason: "leaking param"},
+		{File: "a.go", Line: 5, Column: 2, Variable: "z", EscapeType: parser.DoesNotEscape, Reason: "does not escape"},
+		{File: "a.go", Line: 6, Column: 6, Variable: "f", EscapeType: parser.CanInline, Reason: "can inline"},
+		{File: "a.go", Line: 7, Column: 6, Variable: "g", EscapeType: parser.CannotInline, Reason: "cannot inline g: too complex"},
+	}
+	results := Categorize(escapes)
+	want := map[string]int{"moved-to-heap": 2, "escapes-to-heap": 1, "leaking-param": 1, "does-not-escape": 1}
+	if !reflect.DeepEqual(results.Summary.ByEscapeType, want) {
+		t.Errorf("ByEscapeType = %v, want %v", results.Summary.ByEscapeType, want)
+	}
+}
@@ -0,0 +1,154 @@
+package categorizer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strings"
+
+	heapparser "github.com/harshakonda/heapcheck/internal/parser"
+	"github.com/harshakonda/heapcheck/internal/typecheck"
+)
+
+// storedFieldRe matches the flow of a func literal assigned to a field,
+// as in "from s.onclose = func literal (assign)"
+var storedFieldRe = regexp.MustCompile(`from [\w.()*\[\]]+\.\w+ = func literal \(assign\)`)
+
+// isStoredClosure reports whether the flow of e shows a func literal
+// stored in a struct field, by assignment or in a struct literal. It
+// needs no source, unlike sourceCache.storedClosure, which also finds
+// closures stored in package variables.
+func isStoredClosure(variable, flowInfo string) bool {
+	return variable == "func literal" && (storedFieldRe.MatchString(flowInfo) || strings.Contains(flowInfo, "(struct literal element)"))
+}
+
+// storedClosure reads the source of a func literal escape and returns
+// where the closure is stored when it outlives its function as a
+// callback or hook: assigned to a struct field, a package variable or an
+// element of either, appended to one, or set in a struct literal. The
+// target is the expression it is stored in, e.g. "s.OnClose", "Hook" or
+// "Server{OnClose}".
+func (c *sourceCache) storedClosure(e heapparser.EscapeInfo) (string, bool) {
+	if e.Variable != "func literal" {
+		return "", false
+	}
+	f := c.file(e.File)
+	if f == nil {
+		return "", false
+	}
+	p := c.pos(f, e.Line, e.Column)
+	fd := typecheck.EnclosingFunc(f, p)
+	if fd == nil {
+		return "", false
+	}
+
+	target := ""
+	ast.Inspect(fd, func(n ast.Node) bool {
+		if n == nil || target != "" || p < n.Pos() || p >= n.End() {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if len(n.Lhs) != len(n.Rhs) {
+				break
+			}
+			for i, rhs := range n.Rhs {
+				if storesFuncLit(rhs, p) && isStorage(n.Lhs[i], fd) {
+					target = types.ExprString(n.Lhs[i])
+				}
+			}
+		case *ast.CompositeLit:
+			for _, elt := range n.Elts {
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+				key, ok := kv.Key.(*ast.Ident)
+				if ok && storesFuncLit(kv.Value, p) {
+					target = key.Name
+					if n.Type != nil {
+						target = fmt.Sprintf("%s{%s}", types.ExprString(n.Type), key.Name)
+					}
+				}
+			}
+		}
+		return true
+	})
+	return target, target != ""
+}
+
+// storesFuncLit reports whether x is the func literal at p, or an append
+// of it
+func storesFuncLit(x ast.Expr, p token.Pos) bool {
+	switch x := x.(type) {
+	case *ast.FuncLit:
+		return x.Pos() == p
+	case *ast.CallExpr:
+		if fn, ok := x.Fun.(*ast.Ident); ok && fn.Name == "append" {
+			for _, arg := range x.Args[min(1, len(x.Args)):] {
+				if lit, ok := arg.(*ast.FuncLit); ok && lit.Pos() == p {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// isStorage reports whether assigning to x stores a value beyond the
+// function fd: x is a field, a package variable (a name fd does not
+// declare), a pointer dereference, or an element of one of them
+func isStorage(x ast.Expr, fd *ast.FuncDecl) bool {
+	switch x := x.(type) {
+	case *ast.SelectorExpr, *ast.StarExpr:
+		return true
+	case *ast.IndexExpr:
+		return isStorage(x.X, fd)
+	case *ast.ParenExpr:
+		return isStorage(x.X, fd)
+	case *ast.Ident:
+		return x.Name != "_" && !declares(fd, x.Name)
+	}
+	return false
+}
+
+// declares reports whether fd declares name: as a receiver, parameter or
+// result, or anywhere in its body
+func declares(fd *ast.FuncDecl, name string) bool {
+	found := false
+	ast.Inspect(fd, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.Field:
+			for _, id := range n.Names {
+				found = found || id.Name == name
+			}
+		case *ast.ValueSpec:
+			for _, id := range n.Names {
+				found = found || id.Name == name
+			}
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE {
+				for _, lhs := range n.Lhs {
+					if id, ok := lhs.(*ast.Ident); ok {
+						found = found || id.Name == name
+					}
+				}
+			}
+		case *ast.RangeStmt:
+			if n.Tok == token.DEFINE {
+				for _, x := range []ast.Expr{n.Key, n.Value} {
+					if id, ok := x.(*ast.Ident); ok {
+						found = found || id.Name == name
+					}
+				}
+			}
+		}
+		return true
+	})
+	return found
+}
@@ -0,0 +1,77 @@
+package categorizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+const closureSource = `package sample
+
+type Server struct {
+	OnClose  func()
+	hooks    []func(int)
+	handlers map[string]func()
+}
+
+var Hook func() int
+
+func (s *Server) Init(name string, n int) {
+	s.OnClose = func() { println(name) }
+	s.hooks = append(s.hooks, func(i int) { println(i + n) })
+	s.handlers["close"] = func() { println(name) }
+}
+
+func SetHook(v int) {
+	Hook = func() int { return v }
+}
+
+func New(x int) *Server {
+	return &Server{OnClose: func() { println(x) }}
+}
+
+func Local(y int) func() {
+	f := func() { println(y) }
+	var g func()
+	g = func() { println(y) }
+	g()
+	return f
+}
+`
+
+func TestStoredClosure(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sample.go")
+	if err := os.WriteFile(file, []byte(closureSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line, col int
+		variable  string
+		want      string
+	}{
+		{line: 12, col: 14, variable: "func literal", want: "s.OnClose"},
+		{line: 13, col: 28, variable: "func literal", want: "s.hooks"},
+		{line: 14, col: 24, variable: "func literal", want: `s.handlers["close"]`},
+		{line: 18, col: 9, variable: "func literal", want: "Hook"},
+		{line: 22, col: 26, variable: "func literal", want: "Server{OnClose}"},
+		{line: 26, col: 7, variable: "func literal"},
+		{line: 28, col: 6, variable: "func literal"},
+		{line: 12, col: 14, variable: "name"},
+	}
+	src := newSourceCache()
+	for _, tt := range tests {
+		e := parser.EscapeInfo{File: file, Line: tt.line, Column: tt.col, Variable: tt.variable, EscapeType: parser.EscapesToHeap}
+		got, ok := src.storedClosure(e)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("storedClosure(%d:%d %s) = %q, %v, want %q", tt.line, tt.col, tt.variable, got, ok, tt.want)
+		}
+	}
+
+	escape := parser.EscapeInfo{File: file, Line: 18, Column: 9, Variable: "func literal", EscapeType: parser.EscapesToHeap, Reason: "func literal escapes to heap"}
+	cat, suggestion := classify(escape, nil, src)
+	if cat != CategoryStoredClosure || suggestion.Short != "Closure stored in Hook: capture only the state the callback needs" {
+		t.Errorf("classify() = %s, %q, want stored-closure naming Hook", cat, suggestion.Short)
+	}
+}
@@ -0,0 +1,148 @@
+package categorizer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// Cluster is one logical finding: the escapes a single construct causes,
+// such as a composite literal and each of its fields, or a variadic call
+// and the arguments it boxes
+type Cluster struct {
+	Key     string              // the construct, e.g. "./main.go:13 &T{...}"
+	Root    CategorizedEscape   // the construct's own escape, or the first of its escapes
+	Members []CategorizedEscape // the other escapes, in report order
+}
+
+// Size returns the number of escapes in the cluster
+func (c Cluster) Size() int {
+	return 1 + len(c.Members)
+}
+
+// storageForRe matches the location a composite literal's elements are
+// stored in, e.g. "{storage for &T{...}}"
+var storageForRe = regexp.MustCompile(`^\{storage for (.+)\}$`)
+
+// isLiteral reports whether expr is a composite literal as the compiler
+// prints it, e.g. "&T{...}", or a variadic argument slice
+func isLiteral(expr string) bool {
+	return strings.HasSuffix(expr, "{...}") || isArgumentSlice(expr)
+}
+
+// rootOf returns the construct that causes e, as file:line and expression:
+// the escape itself when it is a composite literal or argument slice,
+// otherwise the outermost literal its flow stores it into
+func rootOf(e parser.EscapeInfo) (string, bool) {
+	if isLiteral(e.Variable) {
+		return fmt.Sprintf("%s:%d %s", e.File, e.Line, e.Variable), true
+	}
+	for _, f := range e.Flows {
+		var last *parser.FlowStep
+		for i := range f.Steps {
+			if strings.Contains(f.Steps[i].Reason, "literal") {
+				last = &f.Steps[i]
+			}
+		}
+		if last == nil {
+			continue
+		}
+		// Elements of &T{...} flow through T{...}; the storage names
+		// the literal as it escapes
+		expr := last.Expr
+		if m := storageForRe.FindStringSubmatch(f.Dst); m != nil && isLiteral(m[1]) {
+			expr = m[1]
+		}
+		return fmt.Sprintf("%s:%d %s", last.File, last.Line, expr), true
+	}
+	return "", false
+}
+
+// assignClusters sets the Cluster of escapes that share their construct
+// with other escapes. Escapes without flow details join the cluster of
+// the escapes reported at the same position, unless inlining put several
+// constructs there.
+func assignClusters(escapes []CategorizedEscape) {
+	keys := make([]string, len(escapes))
+	atPosition := make(map[string]string)
+	for i, e := range escapes {
+		key, ok := rootOf(e.Info)
+		if !ok {
+			continue
+		}
+		keys[i] = key
+		pos := positionKey(e.Info)
+		if prev, seen := atPosition[pos]; seen && prev != key {
+			key = ""
+		}
+		atPosition[pos] = key
+	}
+	values := make(map[string]map[string]bool) // cluster -> escaping values
+	for i, e := range escapes {
+		if keys[i] == "" {
+			keys[i] = atPosition[positionKey(e.Info)]
+		}
+		if keys[i] == "" {
+			continue
+		}
+		if values[keys[i]] == nil {
+			values[keys[i]] = make(map[string]bool)
+		}
+		values[keys[i]][positionKey(e.Info)+" "+e.Info.Variable] = true
+	}
+	for i := range escapes {
+		if len(values[keys[i]]) > 1 {
+			escapes[i].Cluster = keys[i]
+		}
+	}
+}
+
+// Clusters groups escapes into findings, in order of first appearance.
+// Escapes without a Cluster are findings of their own.
+func Clusters(escapes []CategorizedEscape) []Cluster {
+	var clusters []Cluster
+	index := make(map[string]int)
+	for _, e := range escapes {
+		if e.Cluster == "" {
+			clusters = append(clusters, Cluster{Root: e})
+			continue
+		}
+		i, ok := index[e.Cluster]
+		if !ok {
+			index[e.Cluster] = len(clusters)
+			clusters = append(clusters, Cluster{Key: e.Cluster, Root: e})
+			continue
+		}
+		c := &clusters[i]
+		if isClusterRoot(e) && !isClusterRoot(c.Root) {
+			c.Members = append([]CategorizedEscape{c.Root}, c.Members...)
+			c.Root = e
+			continue
+		}
+		c.Members = append(c.Members, e)
+	}
+	return clusters
+}
+
+// isClusterRoot reports whether e is the construct its cluster is named for
+func isClusterRoot(e CategorizedEscape) bool {
+	return e.Cluster == fmt.Sprintf("%s:%d %s", e.Info.File, e.Info.Line, e.Info.Variable)
+}
+
+// CountFindings returns the number of findings among escapes, counting
+// each cluster once
+func CountFindings(escapes []CategorizedEscape) int {
+	n := 0
+	seen := make(map[string]bool)
+	for _, e := range escapes {
+		if e.Cluster == "" {
+			n++
+		} else if !seen[e.Cluster] {
+			seen[e.Cluster] = true
+			n++
+		}
+	}
+	return n
+}
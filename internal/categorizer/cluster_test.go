@@ -0,0 +1,127 @@
+package categorizer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// clusterOutput is the -m=2 output of
+//
+//	func f(x, y int, s string) {
+//		z := 3
+//		sink = &T{A: x, B: s, P: &z}
+//		m := map[string]any{"a": x, "b": y}
+//		sink = m
+//		ts = []T{{A: x}, {A: y}}
+//	}
+const clusterOutput = `./main.go:11:6: can inline f with cost 48 as: func(int, int, string) { z := 3; sink = &T{...}; m := map[string]any{...}; sink = m; ts = []T{...} }
+./main.go:13:9: &T{...} escapes to heap in f:
+./main.go:13:9:   flow: {heap} ← &{storage for &T{...}}:
+./main.go:13:9:     from &T{...} (spill) at ./main.go:13:9
+./main.go:13:9:     from &T{...} (interface-converted) at ./main.go:13:9
+./main.go:13:9:     from sink = &T{...} (assign) at ./main.go:13:7
+./main.go:13:15: x escapes to heap in f:
+./main.go:13:15:   flow: {storage for &T{...}} ← &{storage for x}:
+./main.go:13:15:     from x (spill) at ./main.go:13:15
+./main.go:13:15:     from T{...} (struct literal element) at ./main.go:13:11
+./main.go:13:21: s escapes to heap in f:
+./main.go:13:21:   flow: {storage for &T{...}} ← &{storage for s}:
+./main.go:13:21:     from s (spill) at ./main.go:13:21
+./main.go:13:21:     from T{...} (struct literal element) at ./main.go:13:11
+./main.go:12:2: z escapes to heap in f:
+./main.go:12:2:   flow: {storage for &T{...}} ← &z:
+./main.go:12:2:     from &z (address-of) at ./main.go:13:27
+./main.go:12:2:     from T{...} (struct literal element) at ./main.go:13:11
+./main.go:14:27: x escapes to heap in f:
+./main.go:14:27:   flow: {heap} ← &{storage for x}:
+./main.go:14:27:     from x (spill) at ./main.go:14:27
+./main.go:14:27:     from map[string]any{...} (map literal value) at ./main.go:14:21
+./main.go:14:35: y escapes to heap in f:
+./main.go:14:35:   flow: {heap} ← &{storage for y}:
+./main.go:14:35:     from y (spill) at ./main.go:14:35
+./main.go:14:35:     from map[string]any{...} (map literal value) at ./main.go:14:21
+./main.go:16:10: []T{...} escapes to heap in f:
+./main.go:16:10:   flow: {heap} ← &{storage for []T{...}}:
+./main.go:16:10:     from []T{...} (spill) at ./main.go:16:10
+./main.go:16:10:     from ts = []T{...} (assign) at ./main.go:16:5
+./main.go:16:15: x escapes to heap in f:
+./main.go:16:15:   flow: {storage for []T{...}} ← &{storage for x}:
+./main.go:16:15:     from x (spill) at ./main.go:16:15
+./main.go:16:15:     from T{...} (struct literal element) at ./main.go:16:11
+./main.go:16:15:     from []T{...} (slice-literal-element) at ./main.go:16:10
+./main.go:16:23: y escapes to heap in f:
+./main.go:16:23:   flow: {storage for []T{...}} ← &{storage for y}:
+./main.go:16:23:     from y (spill) at ./main.go:16:23
+./main.go:16:23:     from T{...} (struct literal element) at ./main.go:16:19
+./main.go:16:23:     from []T{...} (slice-literal-element) at ./main.go:16:10
+./main.go:14:21: map[string]any{...} escapes to heap in f:
+./main.go:14:21:   flow: m ← &{storage for map[string]any{...}}:
+./main.go:14:21:     from map[string]any{...} (spill) at ./main.go:14:21
+./main.go:14:21:     from m := map[string]any{...} (assign) at ./main.go:14:4
+./main.go:14:21:   flow: {heap} ← m:
+./main.go:14:21:     from m (interface-converted) at ./main.go:15:9
+./main.go:14:21:     from sink = m (assign) at ./main.go:15:7
+./main.go:11:18: parameter s leaks to {storage for s} for f with derefs=0:
+./main.go:11:18:   flow: {storage for s} ← s:
+./main.go:11:18:     from s (interface-converted) at ./main.go:13:21
+./main.go:11:18: leaking param: s
+./main.go:12:2: moved to heap: z
+./main.go:13:9: &T{...} escapes to heap
+./main.go:13:15: x escapes to heap
+./main.go:13:21: s escapes to heap
+./main.go:14:21: map[string]any{...} escapes to heap
+./main.go:14:27: x escapes to heap
+./main.go:14:35: y escapes to heap
+./main.go:16:10: []T{...} escapes to heap
+./main.go:16:15: x escapes to heap
+./main.go:16:23: y escapes to heap
+`
+
+func TestClusters(t *testing.T) {
+	escapes, err := parser.Parse(clusterOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := Categorize(escapes)
+
+	type finding struct {
+		Key, Root string
+		Members   int
+	}
+	var got []finding
+	for _, c := range Clusters(results.Escapes) {
+		got = append(got, finding{c.Key, positionKey(c.Root.Info) + " " + c.Root.Info.Variable, len(c.Members)})
+	}
+	want := []finding{
+		{"./main.go:13 &T{...}", "./main.go:13:9 &T{...}", 7},
+		{"./main.go:14 map[string]any{...}", "./main.go:14:21 map[string]any{...}", 5},
+		{"./main.go:16 []T{...}", "./main.go:16:10 []T{...}", 5},
+		{"", "./main.go:11:18 s", 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Clusters() = %+v, want %+v", got, want)
+	}
+	if n := CountFindings(results.Escapes); n != len(want) {
+		t.Errorf("CountFindings() = %d, want %d", n, len(want))
+	}
+}
+
+func TestClustersSingle(t *testing.T) {
+	// A literal with no escaping elements is not a cluster
+	escapes := []parser.EscapeInfo{
+		{File: "a.go", Line: 3, Column: 9, Variable: "&T{...}", EscapeType: parser.EscapesToHeap, Reason: "&T{...} escapes to heap"},
+		{File: "a.go", Line: 3, Column: 9, Variable: "&T{...}", EscapeType: parser.EscapesToHeap, Reason: "&T{...} escapes to heap"},
+		{File: "a.go", Line: 5, Column: 2, Variable: "v", EscapeType: parser.MovedToHeap, Reason: "moved to heap: v"},
+	}
+	results := Categorize(escapes)
+	for _, e := range results.Escapes {
+		if e.Cluster != "" {
+			t.Errorf("%s: Cluster = %q, want none", e.Info.Variable, e.Cluster)
+		}
+	}
+	if n := CountFindings(results.Escapes); n != 3 {
+		t.Errorf("CountFindings() = %d, want 3", n)
+	}
+}
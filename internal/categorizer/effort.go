@@ -0,0 +1,138 @@
+package categorizer
+
+import (
+	"go/ast"
+	"path/filepath"
+	"strings"
+
+	heapparser "github.com/harshakonda/heapcheck/internal/parser"
+	"github.com/harshakonda/heapcheck/internal/typecheck"
+)
+
+// Effort estimates the work of avoiding an escape, for planning which
+// escapes to take on
+type Effort string
+
+const (
+	EffortTrivial    Effort = "trivial"    // a local rewrite, such as strconv for fmt or a pre-allocated slice
+	EffortModerate   Effort = "moderate"   // a change within a function or package
+	EffortStructural Effort = "structural" // a change of design: concurrency, reflection or an exported API
+)
+
+// efforts lists the effort estimates in report order
+var efforts = []Effort{EffortTrivial, EffortModerate, EffortStructural}
+
+// effortOf maps the built-in categories to their usual effort. Categories
+// left out are EffortModerate.
+var effortOf = map[Category]Effort{
+	CategoryFmtCall:          EffortTrivial,
+	CategoryStringConversion: EffortTrivial,
+	CategorySliceGrow:        EffortTrivial,
+	CategoryGoroutineEscape:  EffortStructural,
+	CategoryChannelSend:      EffortStructural,
+	CategoryReflection:       EffortStructural,
+	CategoryCompilerForced:   EffortStructural,
+}
+
+// apiCategories are the categories whose escapes are part of a function's
+// signature, which callers outside the package depend on when the
+// function is exported
+var apiCategories = map[Category]bool{
+	CategoryReturnPointer:   true,
+	CategoryInterfaceBoxing: true,
+	CategoryLeakingParam:    true,
+	CategoryLeakingContent:  true,
+}
+
+// Efforts returns the effort estimates, in report order
+func Efforts() []Effort {
+	return append([]Effort(nil), efforts...)
+}
+
+// EffortOf returns the usual effort of avoiding an escape of a category.
+// Categories that are not built in are EffortModerate.
+func EffortOf(cat Category) Effort {
+	if e, ok := effortOf[cat]; ok {
+		return e
+	}
+	return EffortModerate
+}
+
+// CountByEffort counts escapes by their effort estimate, falling back to
+// the estimate of their category. Estimates without escapes are left out.
+func CountByEffort(escapes []CategorizedEscape) map[Effort]int {
+	byEffort := make(map[Effort]int)
+	for _, e := range escapes {
+		effort := e.Effort
+		if effort == "" {
+			effort = EffortOf(e.Category)
+		}
+		byEffort[effort]++
+	}
+	return byEffort
+}
+
+// effort estimates the effort of avoiding e of category cat: that of the
+// category, except that escapes in the signature of an exported function
+// of an importable package are structural, as avoiding them breaks its
+// callers
+func (c *sourceCache) effort(e heapparser.EscapeInfo, cat Category) Effort {
+	if !apiCategories[cat] || isInternal(e.PackageOrDir()) || !c.inExportedAPI(e) {
+		return EffortOf(cat)
+	}
+	return EffortStructural
+}
+
+// inExportedAPI reports whether e is in an exported function, or a method
+// of an exported type, of a package other than main. Whether the package
+// is internal is left to the caller.
+func (c *sourceCache) inExportedAPI(e heapparser.EscapeInfo) bool {
+	f := c.file(e.File)
+	if f == nil || f.Name.Name == "main" {
+		return false
+	}
+	fd := typecheck.EnclosingFunc(f, c.pos(f, e.Line, e.Column))
+	return fd != nil && fd.Name.IsExported() && exportedRecv(fd)
+}
+
+// isMain reports whether e is in a main package, which nothing imports
+func (c *sourceCache) isMain(e heapparser.EscapeInfo) bool {
+	f := c.file(e.File)
+	return f != nil && f.Name.Name == "main"
+}
+
+// exportedRecv reports whether fd is a function or a method of an
+// exported type
+func exportedRecv(fd *ast.FuncDecl) bool {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return true
+	}
+	t := fd.Recv.List[0].Type
+	for {
+		switch x := t.(type) {
+		case *ast.StarExpr:
+			t = x.X
+		case *ast.IndexExpr:
+			t = x.X
+		case *ast.IndexListExpr:
+			t = x.X
+		case *ast.ParenExpr:
+			t = x.X
+		case *ast.Ident:
+			return x.IsExported()
+		default:
+			return false
+		}
+	}
+}
+
+// isInternal reports whether the package at an import path or directory
+// is internal, importable from its parent tree alone
+func isInternal(pkg string) bool {
+	for _, dir := range strings.Split(filepath.ToSlash(pkg), "/") {
+		if dir == "internal" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package categorizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+const effortSource = `package sample
+
+type Client struct{ n int }
+
+type conn struct{ n int }
+
+func New() *Client {
+	c := Client{}
+	return &c
+}
+
+func newConn() *conn {
+	c := conn{}
+	return &c
+}
+
+func (c *Client) Clone() *Client {
+	d := *c
+	return &d
+}
+
+func (c *conn) Clone() *conn {
+	d := *c
+	return &d
+}
+`
+
+func TestEffort(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "sample.go")
+	if err := os.WriteFile(file, []byte(effortSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	internal := filepath.Join(dir, "internal", "sample.go")
+	os.MkdirAll(filepath.Dir(internal), 0o755)
+	if err := os.WriteFile(internal, []byte(effortSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		file string
+		pkg  string
+		line int
+		cat  Category
+		want Effort
+	}{
+		{name: "exported function", file: file, pkg: "example.com/sample", line: 8, cat: CategoryReturnPointer, want: EffortStructural},
+		{name: "unexported function", file: file, pkg: "example.com/sample", line: 13, cat: CategoryReturnPointer, want: EffortModerate},
+		{name: "method of exported type", file: file, pkg: "example.com/sample", line: 18, cat: CategoryReturnPointer, want: EffortStructural},
+		{name: "method of unexported type", file: file, pkg: "example.com/sample", line: 23, cat: CategoryReturnPointer, want: EffortModerate},
+		{name: "internal package", file: internal, pkg: "example.com/internal/sample", line: 8, cat: CategoryReturnPointer, want: EffortModerate},
+		{name: "not an API category", file: file, pkg: "example.com/sample", line: 8, cat: CategoryFmtCall, want: EffortTrivial},
+		{name: "structural category", file: file, pkg: "example.com/sample", line: 13, cat: CategoryGoroutineEscape, want: EffortStructural},
+		{name: "custom category", file: file, pkg: "example.com/sample", line: 13, cat: "db-row", want: EffortModerate},
+	}
+	src := newSourceCache()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := parser.EscapeInfo{Package: tt.pkg, File: tt.file, Line: tt.line, Column: 2, Variable: "c"}
+			if got := src.effort(e, tt.cat); got != tt.want {
+				t.Errorf("effort() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountByEffort(t *testing.T) {
+	escapes := []CategorizedEscape{
+		{Category: CategoryFmtCall, Effort: EffortTrivial},
+		{Category: CategoryReturnPointer, Effort: EffortStructural},
+		{Category: CategoryReflection},
+		{Category: CategoryAssignment},
+	}
+	got := CountByEffort(escapes)
+	if got[EffortTrivial] != 1 || got[EffortModerate] != 1 || got[EffortStructural] != 2 {
+		t.Errorf("CountByEffort() = %v, want trivial 1, moderate 1 and structural 2", got)
+	}
+}
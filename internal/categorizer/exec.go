@@ -0,0 +1,87 @@
+package categorizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// ExecAnswer is one element of an exec categorizer's reply. An empty
+// Category leaves the escape to the built-in rules.
+type ExecAnswer struct {
+	Category   Category    `json:"category,omitempty"`
+	Suggestion *Suggestion `json:"suggestion,omitempty"`
+}
+
+// execCategorizer serves answers computed by an external command
+type execCategorizer struct {
+	answers map[string]ExecAnswer
+}
+
+// NewExecCategorizer runs an external command once for all heap escapes.
+// The command reads a JSON array of escapes on stdin and writes a JSON
+// array of ExecAnswer of the same length (null elements are allowed) to
+// stdout. Stderr is passed through.
+func NewExecCategorizer(argv []string, escapes []parser.EscapeInfo) (Categorizer, error) {
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("categorizer exec: empty command")
+	}
+
+	heap := make([]parser.EscapeInfo, 0, len(escapes))
+	for _, e := range escapes {
+		switch e.EscapeType {
+		case parser.MovedToHeap, parser.EscapesToHeap, parser.LeakingParam, parser.LeakingParamContent:
+			heap = append(heap, e)
+		}
+	}
+
+	input, err := json.Marshal(heap)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("categorizer exec %s: %w", argv[0], err)
+	}
+
+	var answers []*ExecAnswer
+	if err := json.Unmarshal(output, &answers); err != nil {
+		return nil, fmt.Errorf("categorizer exec %s: invalid output: %w", argv[0], err)
+	}
+	if len(answers) != len(heap) {
+		return nil, fmt.Errorf("categorizer exec %s: got %d answers for %d escapes", argv[0], len(answers), len(heap))
+	}
+
+	c := &execCategorizer{answers: make(map[string]ExecAnswer)}
+	for i, a := range answers {
+		if a != nil && a.Category != "" {
+			c.answers[execKey(heap[i])] = *a
+		}
+	}
+	return c, nil
+}
+
+// Categorize returns the command's answer for e, if any
+func (c *execCategorizer) Categorize(e parser.EscapeInfo) (Category, Suggestion, bool) {
+	a, ok := c.answers[execKey(e)]
+	if !ok {
+		return "", Suggestion{}, false
+	}
+	var suggestion Suggestion
+	if a.Suggestion != nil {
+		suggestion = *a.Suggestion
+	}
+	return a.Category, suggestion, true
+}
+
+func execKey(e parser.EscapeInfo) string {
+	return fmt.Sprintf("%s:%d:%d|%s|%d", e.File, e.Line, e.Column, e.Variable, e.EscapeType)
+}
@@ -0,0 +1,71 @@
+package categorizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// TestMain lets the test binary act as an exec categorizer when
+// HEAPCHECK_TEST_EXEC_CATEGORIZER is set
+func TestMain(m *testing.M) {
+	if os.Getenv("HEAPCHECK_TEST_EXEC_CATEGORIZER") != "" {
+		var escapes []parser.EscapeInfo
+		if err := json.NewDecoder(os.Stdin).Decode(&escapes); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		answers := make([]*ExecAnswer, len(escapes))
+		for i, e := range escapes {
+			if e.Variable == "ctx" {
+				answers[i] = &ExecAnswer{Category: "framework-context", Suggestion: &Suggestion{Short: "Avoid storing ctx"}}
+			}
+		}
+		json.NewEncoder(os.Stdout).Encode(answers)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestExecCategorizer(t *testing.T) {
+	t.Setenv("HEAPCHECK_TEST_EXEC_CATEGORIZER", "1")
+
+	escapes := []parser.EscapeInfo{
+		{File: "a.go", Line: 1, Variable: "ctx", EscapeType: parser.LeakingParam},
+		{File: "a.go", Line: 2, Variable: "ctx", EscapeType: parser.DoesNotEscape},
+		{File: "a.go", Line: 3, Variable: "buf", EscapeType: parser.MovedToHeap},
+	}
+
+	c, err := NewExecCategorizer([]string{os.Args[0]}, escapes)
+	if err != nil {
+		t.Fatalf("NewExecCategorizer() error: %v", err)
+	}
+
+	results := CategorizeWith(escapes, c)
+	if len(results.Escapes) != 2 {
+		t.Fatalf("Escapes = %d, want 2", len(results.Escapes))
+	}
+	if got := results.Escapes[0]; got.Category != "framework-context" || got.Suggestion.Short != "Avoid storing ctx" {
+		t.Errorf("ctx = %s (%q), want framework-context", got.Category, got.Suggestion.Short)
+	}
+	if got := results.Escapes[1].Category; got == "framework-context" {
+		t.Errorf("buf category = %s, want built-in category", got)
+	}
+}
+
+func TestExecCategorizerErrors(t *testing.T) {
+	escapes := []parser.EscapeInfo{{Variable: "x", EscapeType: parser.MovedToHeap}}
+
+	if _, err := NewExecCategorizer(nil, escapes); err == nil {
+		t.Error("NewExecCategorizer(nil) expected error")
+	}
+	if _, err := NewExecCategorizer([]string{"false"}, escapes); err == nil {
+		t.Error("NewExecCategorizer(false) expected error for failing command")
+	}
+	if _, err := NewExecCategorizer([]string{"echo", "[]"}, escapes); err == nil {
+		t.Error("NewExecCategorizer(echo []) expected error for answer count mismatch")
+	}
+}
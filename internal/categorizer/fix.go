@@ -0,0 +1,547 @@
+package categorizer
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	heapparser "github.com/harshakonda/heapcheck/internal/parser"
+	"github.com/harshakonda/heapcheck/internal/typecheck"
+)
+
+// edit replaces src[start:end] with text
+type edit struct {
+	start, end int
+	text       string
+}
+
+// fix returns a unified diff of a known mechanical rewrite for e,
+// generated from its source: pre-allocating a slice appended to in a
+// range loop, or passing a captured variable to an immediately invoked
+// func literal as a parameter. It returns "" when no rewrite applies.
+func (c *sourceCache) fix(e heapparser.EscapeInfo) string {
+	f := c.file(e.File)
+	if f == nil {
+		return ""
+	}
+	var edits []edit
+	if strings.HasPrefix(e.Variable, "append(") {
+		edits = c.preallocEdits(f, e)
+	} else {
+		edits = c.closureEdits(f, e)
+	}
+	if len(edits) == 0 {
+		return ""
+	}
+	return unifiedDiff(e.File, c.src[e.File], edits)
+}
+
+// pos converts a compiler line:column in f to a token.Pos
+func (c *sourceCache) pos(f *ast.File, line, col int) token.Pos {
+	return typecheck.Pos(c.fset, f, line, col)
+}
+
+// offset returns the byte offset of p in its file
+func (c *sourceCache) offset(p token.Pos) int {
+	return c.fset.Position(p).Offset
+}
+
+// funcOf names the function declaration containing e the way the
+// compiler does, e.g. "Run" or "(*Server).Handle"; "" when its source is
+// unavailable or e is outside any function, as in a package-level var
+func (c *sourceCache) funcOf(e heapparser.EscapeInfo) string {
+	f := c.file(e.File)
+	if f == nil {
+		return ""
+	}
+	fd := typecheck.EnclosingFunc(f, c.pos(f, e.Line, e.Column))
+	if fd == nil {
+		return ""
+	}
+	return typecheck.FuncName(fd)
+}
+
+// preallocEdits rewrites the declaration of a slice that is only grown by
+// "s = append(s, x)" in a range loop into make([]T, 0, cap), where the
+// capacity follows from the ranged-over expression
+func (c *sourceCache) preallocEdits(f *ast.File, e heapparser.EscapeInfo) []edit {
+	p := c.pos(f, e.Line, e.Column)
+	decl := typecheck.EnclosingFunc(f, p)
+	if decl == nil || decl.Body == nil {
+		return nil
+	}
+
+	var (
+		name  string
+		loop  *ast.RangeStmt
+		stack []ast.Node
+	)
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		if name != "" {
+			return false
+		}
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if as, ok := n.(*ast.AssignStmt); ok {
+			if s := appendTarget(as, p); s != "" {
+				for i := len(stack) - 1; i >= 0; i-- {
+					if r, ok := stack[i].(*ast.RangeStmt); ok {
+						name, loop = s, r
+						break
+					}
+				}
+				if name == "" {
+					name = s // appended outside a range loop; nothing to do
+				}
+				return false
+			}
+		}
+		stack = append(stack, n)
+		return true
+	})
+	if loop == nil {
+		return nil
+	}
+
+	stmt, elem := sliceDecl(decl.Body, name, loop)
+	if stmt == nil {
+		return nil
+	}
+	capExpr := rangeLen(decl, loop.X, stmt.Pos())
+	if capExpr == "" {
+		return nil
+	}
+	text := fmt.Sprintf("%s := make([]%s, 0, %s)", name, types.ExprString(elem), capExpr)
+	return []edit{{start: c.offset(stmt.Pos()), end: c.offset(stmt.End()), text: text}}
+}
+
+// appendTarget returns s when as is "s = append(s, x)" with the append
+// call at p, or ""
+func appendTarget(as *ast.AssignStmt, p token.Pos) string {
+	if as.Tok != token.ASSIGN || len(as.Lhs) != 1 || len(as.Rhs) != 1 {
+		return ""
+	}
+	call, ok := as.Rhs[0].(*ast.CallExpr)
+	if !ok || call.Ellipsis.IsValid() || len(call.Args) != 2 {
+		return ""
+	}
+	if fn, ok := call.Fun.(*ast.Ident); !ok || fn.Name != "append" {
+		return ""
+	}
+	// the compiler reports calls at the opening parenthesis
+	if call.Lparen != p && call.Pos() != p {
+		return ""
+	}
+	lhs, ok := as.Lhs[0].(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	if first, ok := call.Args[0].(*ast.Ident); !ok || first.Name != lhs.Name {
+		return ""
+	}
+	return lhs.Name
+}
+
+// sliceDecl finds the last statement before loop, in a block enclosing
+// it, that declares name as an empty slice, and returns it with the
+// slice's element type
+func sliceDecl(body *ast.BlockStmt, name string, loop *ast.RangeStmt) (ast.Stmt, ast.Expr) {
+	var (
+		stmt ast.Stmt
+		elem ast.Expr
+	)
+	ast.Inspect(body, func(n ast.Node) bool {
+		block, ok := n.(*ast.BlockStmt)
+		if !ok {
+			return true
+		}
+		if block.Pos() > loop.Pos() || block.End() < loop.End() {
+			return false
+		}
+		for _, s := range block.List {
+			if s.End() > loop.Pos() {
+				break
+			}
+			if t := emptySliceDecl(s, name); t != nil {
+				stmt, elem = s, t
+			}
+		}
+		return true
+	})
+	return stmt, elem
+}
+
+// emptySliceDecl returns the element type when s is one of
+// "var s []T", "var s = []T{}", "s := []T{}" or "s := make([]T, 0)"
+func emptySliceDecl(s ast.Stmt, name string) ast.Expr {
+	switch s := s.(type) {
+	case *ast.DeclStmt:
+		gd, ok := s.Decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.VAR || gd.Lparen.IsValid() || len(gd.Specs) != 1 {
+			return nil
+		}
+		vs := gd.Specs[0].(*ast.ValueSpec)
+		if len(vs.Names) != 1 || vs.Names[0].Name != name {
+			return nil
+		}
+		switch {
+		case vs.Type != nil && len(vs.Values) == 0:
+			return sliceElem(vs.Type)
+		case vs.Type == nil && len(vs.Values) == 1:
+			return emptySliceValue(vs.Values[0])
+		}
+	case *ast.AssignStmt:
+		if s.Tok != token.DEFINE || len(s.Lhs) != 1 || len(s.Rhs) != 1 {
+			return nil
+		}
+		if id, ok := s.Lhs[0].(*ast.Ident); !ok || id.Name != name {
+			return nil
+		}
+		return emptySliceValue(s.Rhs[0])
+	}
+	return nil
+}
+
+// emptySliceValue returns the element type of "[]T{}" or "make([]T, 0)"
+func emptySliceValue(x ast.Expr) ast.Expr {
+	switch x := x.(type) {
+	case *ast.CompositeLit:
+		if len(x.Elts) == 0 && x.Type != nil {
+			return sliceElem(x.Type)
+		}
+	case *ast.CallExpr:
+		fn, ok := x.Fun.(*ast.Ident)
+		if !ok || fn.Name != "make" || len(x.Args) != 2 {
+			return nil
+		}
+		if lit, ok := x.Args[1].(*ast.BasicLit); ok && lit.Value == "0" {
+			return sliceElem(x.Args[0])
+		}
+	}
+	return nil
+}
+
+// sliceElem returns the element type of a slice type expression
+func sliceElem(t ast.Expr) ast.Expr {
+	if at, ok := t.(*ast.ArrayType); ok && at.Len == nil {
+		return at.Elt
+	}
+	return nil
+}
+
+// rangeLen returns the number of iterations of "range x" as source text,
+// when x is an integer literal or a variable declared before the given
+// position with a type that supports len or is an integer
+func rangeLen(decl *ast.FuncDecl, x ast.Expr, before token.Pos) string {
+	switch x := x.(type) {
+	case *ast.BasicLit:
+		if x.Kind == token.INT {
+			return x.Value
+		}
+	case *ast.Ident:
+		switch t := declaredType(decl, x.Name, before).(type) {
+		case *ast.ArrayType, *ast.MapType:
+			return "len(" + x.Name + ")"
+		case *ast.Ident:
+			switch {
+			case t.Name == "string":
+				return "len(" + x.Name + ")"
+			case integerTypes[t.Name]:
+				return x.Name
+			}
+		}
+	}
+	return ""
+}
+
+var integerTypes = map[string]bool{
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"uintptr": true, "byte": true, "rune": true,
+}
+
+// declaredType returns the type of the last declaration of name in decl
+// before the given position, when it can be read from the syntax alone
+func declaredType(decl *ast.FuncDecl, name string, before token.Pos) ast.Expr {
+	var typ ast.Expr
+	fields := func(fl *ast.FieldList) {
+		if fl == nil {
+			return
+		}
+		for _, field := range fl.List {
+			for _, n := range field.Names {
+				if n.Name == name {
+					typ = field.Type
+				}
+			}
+		}
+	}
+	fields(decl.Recv)
+	fields(decl.Type.Params)
+	fields(decl.Type.Results)
+	if decl.Body == nil {
+		return typ
+	}
+
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		if n == nil || n.Pos() >= before {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			// declarations in a block that has ended are out of scope
+			return n == decl.Body || n.End() > before
+		case *ast.FuncLit:
+			if n.Body.End() > before {
+				fields(n.Type.Params)
+				fields(n.Type.Results)
+			}
+		case *ast.ValueSpec:
+			for i, id := range n.Names {
+				if id.Name != name {
+					continue
+				}
+				switch {
+				case n.Type != nil:
+					typ = n.Type
+				case i < len(n.Values) && len(n.Names) == len(n.Values):
+					typ = valueType(n.Values[i])
+				default:
+					typ = nil
+				}
+			}
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE {
+				return true
+			}
+			for i, lhs := range n.Lhs {
+				if id, ok := lhs.(*ast.Ident); ok && id.Name == name {
+					typ = nil
+					if len(n.Lhs) == len(n.Rhs) {
+						typ = valueType(n.Rhs[i])
+					}
+				}
+			}
+		case *ast.RangeStmt:
+			if n.Tok != token.DEFINE {
+				return true
+			}
+			for _, x := range []ast.Expr{n.Key, n.Value} {
+				if id, ok := x.(*ast.Ident); ok && id.Name == name {
+					typ = nil
+				}
+			}
+		}
+		return true
+	})
+	return typ
+}
+
+// valueType returns the type of an initializer whose type is spelled out
+// in the syntax: composite literals, make, new and basic literals
+func valueType(x ast.Expr) ast.Expr {
+	switch x := x.(type) {
+	case *ast.CompositeLit:
+		return x.Type
+	case *ast.UnaryExpr:
+		if lit, ok := x.X.(*ast.CompositeLit); ok && x.Op == token.AND && lit.Type != nil {
+			return &ast.StarExpr{X: lit.Type}
+		}
+	case *ast.CallExpr:
+		fn, ok := x.Fun.(*ast.Ident)
+		if !ok || len(x.Args) == 0 {
+			return nil
+		}
+		switch fn.Name {
+		case "make":
+			return x.Args[0]
+		case "new":
+			return &ast.StarExpr{X: x.Args[0]}
+		}
+	case *ast.BasicLit:
+		switch x.Kind {
+		case token.INT:
+			return ast.NewIdent("int")
+		case token.FLOAT:
+			return ast.NewIdent("float64")
+		case token.CHAR:
+			return ast.NewIdent("rune")
+		case token.STRING:
+			return ast.NewIdent("string")
+		}
+	}
+	return nil
+}
+
+// closureEdits passes a variable captured by an immediately invoked func
+// literal (including go and defer statements) as a parameter instead,
+// when the literal only reads it
+func (c *sourceCache) closureEdits(f *ast.File, e heapparser.EscapeInfo) []edit {
+	var step *heapparser.FlowStep
+	for i := range e.Flows {
+		for j := range e.Flows[i].Steps {
+			s := &e.Flows[i].Steps[j]
+			if s.Reason == "captured by a closure" && token.IsIdentifier(s.Expr) && step == nil {
+				step = s
+			}
+		}
+	}
+	if step == nil || filepath.Clean(step.File) != filepath.Clean(e.File) {
+		return nil
+	}
+	name := step.Expr
+	p := c.pos(f, step.Line, step.Column)
+	decl := typecheck.EnclosingFunc(f, p)
+	if decl == nil || decl.Body == nil {
+		return nil
+	}
+
+	// innermost call of a func literal around the captured reference
+	var call *ast.CallExpr
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		if n == nil || p < n.Pos() || p >= n.End() {
+			return false
+		}
+		if ce, ok := n.(*ast.CallExpr); ok {
+			if lit, ok := ce.Fun.(*ast.FuncLit); ok && lit.Body.Pos() <= p {
+				call = ce
+			}
+		}
+		return true
+	})
+	if call == nil || call.Ellipsis.IsValid() {
+		return nil
+	}
+	lit := call.Fun.(*ast.FuncLit)
+	params := lit.Type.Params
+	if !sameLine(c.fset, params.Opening, params.Closing) || !sameLine(c.fset, call.Lparen, call.Rparen) {
+		return nil
+	}
+	for _, field := range params.List {
+		for _, n := range field.Names {
+			if n.Name == name {
+				return nil
+			}
+		}
+	}
+
+	typ := declaredType(decl, name, lit.Pos())
+	if typ == nil || !readOnly(lit.Body, name, typ) {
+		return nil
+	}
+
+	param := name + " " + types.ExprString(typ)
+	if len(params.List) > 0 {
+		param = ", " + param
+	}
+	arg := name
+	if len(call.Args) > 0 {
+		arg = ", " + arg
+	}
+	return []edit{
+		{start: c.offset(params.Closing), end: c.offset(params.Closing), text: param},
+		{start: c.offset(call.Rparen), end: c.offset(call.Rparen), text: arg},
+	}
+}
+
+// readOnly reports whether body only reads name: it is never assigned,
+// incremented or has its address taken. Method calls and field accesses
+// are only allowed through pointers, since they may take the address of
+// a value implicitly.
+func readOnly(body *ast.BlockStmt, name string, typ ast.Expr) bool {
+	is := func(x ast.Expr) bool {
+		id, ok := ast.Unparen(x).(*ast.Ident)
+		return ok && id.Name == name
+	}
+	_, pointer := typ.(*ast.StarExpr)
+	ok := true
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				if is(lhs) {
+					ok = false
+				}
+			}
+		case *ast.IncDecStmt:
+			ok = ok && !is(n.X)
+		case *ast.RangeStmt:
+			ok = ok && !is(n.Key) && !is(n.Value)
+		case *ast.UnaryExpr:
+			ok = ok && !(n.Op == token.AND && is(n.X))
+		case *ast.SelectorExpr:
+			ok = ok && (pointer || !is(n.X))
+		}
+		return ok
+	})
+	return ok
+}
+
+// sameLine reports whether a and b are on the same line
+func sameLine(fset *token.FileSet, a, b token.Pos) bool {
+	return fset.Position(a).Line == fset.Position(b).Line
+}
+
+// unifiedDiff renders edits of src as a unified diff with one hunk per
+// run of changed lines and no context lines
+func unifiedDiff(path string, src []byte, edits []edit) string {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	lineStart := func(off int) int { return bytes.LastIndexByte(src[:off], '\n') + 1 }
+	lineEnd := func(off int) int {
+		if i := bytes.IndexByte(src[off:], '\n'); i >= 0 {
+			return off + i
+		}
+		return len(src)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", path, path)
+	delta := 0
+	for i := 0; i < len(edits); {
+		start, end := lineStart(edits[i].start), lineEnd(edits[i].end)
+		j := i + 1
+		for j < len(edits) && edits[j].start <= end {
+			end = max(end, lineEnd(edits[j].end))
+			j++
+		}
+
+		var text strings.Builder
+		prev := start
+		for _, ed := range edits[i:j] {
+			text.Write(src[prev:ed.start])
+			text.WriteString(ed.text)
+			prev = ed.end
+		}
+		text.Write(src[prev:end])
+
+		oldLines := strings.Split(string(src[start:end]), "\n")
+		newLines := strings.Split(text.String(), "\n")
+		line := bytes.Count(src[:start], []byte{'\n'}) + 1
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(line, len(oldLines)), hunkRange(line+delta, len(newLines)))
+		for _, l := range oldLines {
+			b.WriteString("-" + l + "\n")
+		}
+		for _, l := range newLines {
+			b.WriteString("+" + l + "\n")
+		}
+		delta += len(newLines) - len(oldLines)
+		i = j
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// hunkRange formats the line range of a hunk header
+func hunkRange(line, n int) string {
+	if n == 1 {
+		return strconv.Itoa(line)
+	}
+	return fmt.Sprintf("%d,%d", line, n)
+}
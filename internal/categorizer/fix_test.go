@@ -0,0 +1,157 @@
+package categorizer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+const fixSource = `package sample
+
+import "sync"
+
+type Item struct{ ID int }
+
+func IDs(items []Item) []int {
+	var ids []int
+	for _, it := range items {
+		ids = append(ids, it.ID)
+	}
+	return ids
+}
+
+func Squares(n int) []int {
+	out := []int{}
+	for i := range n {
+		out = append(out, i*i)
+	}
+	return out
+}
+
+func Lookup(m map[string]int, key func() string) []int {
+	var vals []int
+	for range key() {
+		vals = append(vals, m[key()])
+	}
+	return vals
+}
+
+func Run(n int, wg *sync.WaitGroup) {
+	total := 0
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			total += i
+		}()
+	}
+}
+
+func Spawn(count int, out chan int) {
+	go func(twice bool) {
+		out <- count * 2
+	}(true)
+}
+`
+
+func TestFix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sample.go")
+	if err := os.WriteFile(path, []byte(fixSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	captured := func(expr string, line, col int) []parser.Flow {
+		return []parser.Flow{{Dst: "{heap}", Steps: []parser.FlowStep{
+			{Expr: expr, Reason: "captured by a closure", File: path, Line: line, Column: col},
+		}}}
+	}
+
+	tests := []struct {
+		name string
+		e    parser.EscapeInfo
+		want []string // lines of the diff after the header; nil: no fix
+	}{
+		{
+			name: "range over slice",
+			e:    parser.EscapeInfo{File: path, Line: 10, Column: 15, Variable: "append(ids, it.ID)"},
+			want: []string{"@@ -8 +8 @@", "-\tvar ids []int", "+\tids := make([]int, 0, len(items))"},
+		},
+		{
+			name: "range over int",
+			e:    parser.EscapeInfo{File: path, Line: 18, Column: 15, Variable: "append(out, i * i)"},
+			want: []string{"@@ -16 +16 @@", "-\tout := []int{}", "+\tout := make([]int, 0, n)"},
+		},
+		{
+			name: "unknown length",
+			e:    parser.EscapeInfo{File: path, Line: 26, Column: 16, Variable: "append(vals, m[key()])"},
+		},
+		{
+			name: "read-only capture",
+			e:    parser.EscapeInfo{File: path, Line: 31, Column: 31, Variable: "wg", Flows: captured("wg", 36, 10)},
+			want: []string{
+				"@@ -35 +35 @@", "-\t\tgo func() {", "+\t\tgo func(wg *sync.WaitGroup) {",
+				"@@ -38 +38 @@", "-\t\t}()", "+\t\t}(wg)",
+			},
+		},
+		{
+			name: "assigned capture",
+			e:    parser.EscapeInfo{File: path, Line: 32, Column: 2, Variable: "total", Flows: captured("total", 37, 4)},
+		},
+		{
+			name: "existing parameters",
+			e:    parser.EscapeInfo{File: path, Line: 42, Column: 23, Variable: "out", Flows: captured("out", 44, 3)},
+			want: []string{
+				"@@ -43 +43 @@", "-\tgo func(twice bool) {", "+\tgo func(twice bool, out chan int) {",
+				"@@ -45 +45 @@", "-\t}(true)", "+\t}(true, out)",
+			},
+		},
+		{
+			name: "missing file",
+			e:    parser.EscapeInfo{File: "missing.go", Line: 1, Column: 1, Variable: "append(x, y)"},
+		},
+	}
+
+	src := newSourceCache()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := src.fix(tt.e)
+			if tt.want == nil {
+				if got != "" {
+					t.Errorf("fix() = %q, want none", got)
+				}
+				return
+			}
+			want := strings.Join(append([]string{"--- " + path, "+++ " + path}, tt.want...), "\n")
+			if got != want {
+				t.Errorf("fix() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestFuncOf(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sample.go")
+	if err := os.WriteFile(file, []byte(effortSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line int
+		want string
+	}{
+		{line: 3, want: ""},
+		{line: 8, want: "New"},
+		{line: 18, want: "(*Client).Clone"},
+		{line: 23, want: "(*conn).Clone"},
+	}
+	src := newSourceCache()
+	for _, tt := range tests {
+		if got := src.funcOf(parser.EscapeInfo{File: file, Line: tt.line, Column: 2}); got != tt.want {
+			t.Errorf("funcOf(line %d) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+	if got := src.funcOf(parser.EscapeInfo{File: filepath.Join(t.TempDir(), "missing.go"), Line: 1, Column: 1}); got != "" {
+		t.Errorf("funcOf(missing file) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,296 @@
+package categorizer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/harshakonda/heapcheck/internal/buildctx"
+	heapparser "github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// printfFuncs maps fmt functions to the index of their format argument
+var printfFuncs = map[string]int{
+	"Sprintf": 0, "Printf": 0, "Errorf": 0, "Appendf": 1, "Fprintf": 1,
+}
+
+// sourceCache parses each source file at most once per run
+type sourceCache struct {
+	fset  *token.FileSet
+	files map[string]*ast.File // nil for files that failed to parse
+	src   map[string][]byte    // content of the parsed files
+
+	// recursive names the functions the compiler reported as recursive,
+	// for classifying the escapes their recursion forces
+	recursive map[string]bool
+}
+
+func newSourceCache() *sourceCache {
+	return &sourceCache{fset: token.NewFileSet(), files: make(map[string]*ast.File), src: make(map[string][]byte)}
+}
+
+// file returns the parsed file at path, nil when it does not parse or is
+// not part of the build configuration
+func (c *sourceCache) file(path string) *ast.File {
+	if f, ok := c.files[path]; ok {
+		return f
+	}
+	f, src := parseSource(c.fset, path)
+	c.files[path] = f
+	if src != nil {
+		c.src[path] = src
+	}
+	return f
+}
+
+// prefetch reads and parses the files at paths with up to workers
+// goroutines, so the categorization that follows finds them parsed
+func (c *sourceCache) prefetch(paths []string, workers int) {
+	if workers < 2 || len(paths) < 2 {
+		return
+	}
+	type parsed struct {
+		path string
+		f    *ast.File
+		src  []byte
+	}
+	todo := make(chan string)
+	done := make(chan parsed)
+	var wg sync.WaitGroup
+	for i := 0; i < min(workers, len(paths)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range todo {
+				f, src := parseSource(c.fset, path)
+				done <- parsed{path, f, src}
+			}
+		}()
+	}
+	go func() {
+		for _, path := range paths {
+			todo <- path
+		}
+		close(todo)
+		wg.Wait()
+		close(done)
+	}()
+	for p := range done {
+		c.files[p.path] = p.f
+		if p.src != nil {
+			c.src[p.path] = p.src
+		}
+	}
+}
+
+// parseSource reads and parses the file at path into fset, which is safe
+// for concurrent use. It returns a nil file for files that are not part
+// of the build configuration, cannot be read or do not parse, and a nil
+// source for the first two.
+func parseSource(fset *token.FileSet, path string) (*ast.File, []byte) {
+	if !buildctx.Includes(path) {
+		return nil, nil
+	}
+	src, err := buildctx.ReadFile(path)
+	if err != nil {
+		return nil, nil
+	}
+	f, err := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, src
+	}
+	return f, src
+}
+
+// fmtSuggestion reads the source of a boxing escape and, when it is an
+// argument of a printf-style call with a literal format string, suggests
+// the strconv replacement for the verb that formats it
+func (c *sourceCache) fmtSuggestion(e heapparser.EscapeInfo) (Suggestion, bool) {
+	f := c.file(e.File)
+	if f == nil {
+		return Suggestion{}, false
+	}
+
+	var (
+		decl *ast.FuncDecl
+		call *ast.CallExpr
+		arg  ast.Expr
+		verb string
+	)
+	ast.Inspect(f, func(n ast.Node) bool {
+		if call != nil {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			decl = n
+		case *ast.CallExpr:
+			sel, ok := n.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "fmt" {
+				return true
+			}
+			fmtIndex, ok := printfFuncs[sel.Sel.Name]
+			if !ok || len(n.Args) <= fmtIndex {
+				return true
+			}
+			lit, ok := n.Args[fmtIndex].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			format, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				return true
+			}
+			for i, a := range n.Args[fmtIndex+1:] {
+				pos := c.fset.Position(a.Pos())
+				if pos.Line == e.Line && pos.Column == e.Column {
+					verbs := formatVerbs(format)
+					if i < len(verbs) {
+						call, arg, verb = n, a, verbs[i]
+					}
+					return false
+				}
+			}
+		}
+		return true
+	})
+	if call == nil {
+		return Suggestion{}, false
+	}
+
+	expr := types.ExprString(arg)
+	stringArg := isStringExpr(decl, arg)
+	return verbSuggestion(verb, expr, stringArg)
+}
+
+// formatVerbs returns the verbs of a format string in argument order,
+// e.g. "%d", "%.2f". It returns nil for formats it does not understand
+// (explicit argument indexes or * widths).
+func formatVerbs(format string) []string {
+	var verbs []string
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		start := i
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0123456789.", format[i]) >= 0 {
+			i++
+		}
+		if i >= len(format) {
+			break
+		}
+		switch format[i] {
+		case '%':
+			continue
+		case '[', '*':
+			return nil
+		}
+		verbs = append(verbs, format[start:i+1])
+	}
+	return verbs
+}
+
+// verbSuggestion returns the strconv replacement for one verb
+func verbSuggestion(verb, expr string, stringArg bool) (Suggestion, bool) {
+	flags, c := verb[1:len(verb)-1], verb[len(verb)-1]
+	prec := "-1"
+	if _, p, ok := strings.Cut(flags, "."); ok && p != "" {
+		prec = p
+	}
+
+	var short, details string
+	switch {
+	case c == 'd' && flags == "":
+		short = fmt.Sprintf("Replace %s with strconv.Itoa(%s)", verb, expr)
+		details = fmt.Sprintf("strconv.Itoa formats an int without boxing it; use strconv.FormatInt(int64(%s), 10) for other integer types.", expr)
+	case c == 'x' && flags == "":
+		short = fmt.Sprintf("Replace %s with strconv.FormatInt(int64(%s), 16)", verb, expr)
+		details = "strconv.FormatInt formats integers in any base without boxing; use strconv.FormatUint for unsigned values."
+	case c == 'f' || c == 'e' || c == 'g':
+		short = fmt.Sprintf("Replace %s with strconv.FormatFloat(%s, '%c', %s, 64)", verb, expr, c, prec)
+		details = "strconv.FormatFloat takes the format, precision and bit size explicitly and does not box the value."
+	case c == 't' && flags == "":
+		short = fmt.Sprintf("Replace %s with strconv.FormatBool(%s)", verb, expr)
+		details = "strconv.FormatBool returns \"true\" or \"false\" without boxing the value."
+	case c == 'q' && flags == "":
+		short = fmt.Sprintf("Replace %s with strconv.Quote(%s)", verb, expr)
+		details = "strconv.Quote produces the same Go-quoted string without boxing the value."
+	case (c == 's' || c == 'v') && flags == "" && stringArg:
+		short = fmt.Sprintf("Replace %s with direct concatenation of %s", verb, expr)
+		details = "The argument is already a string: concatenate it with + (or use a strings.Builder) instead of formatting it."
+	default:
+		return Suggestion{}, false
+	}
+	return Suggestion{Short: short, Details: details, DocLink: "https://pkg.go.dev/strconv"}, true
+}
+
+// isStringExpr reports whether expr is known to be a string: a string
+// literal, a String() call, or an identifier declared as a string in the
+// enclosing function
+func isStringExpr(decl *ast.FuncDecl, expr ast.Expr) bool {
+	switch x := ast.Unparen(expr).(type) {
+	case *ast.BasicLit:
+		return x.Kind == token.STRING
+	case *ast.CallExpr:
+		sel, ok := x.Fun.(*ast.SelectorExpr)
+		return ok && sel.Sel.Name == "String" && len(x.Args) == 0
+	case *ast.BinaryExpr:
+		return x.Op == token.ADD && (isStringExpr(decl, x.X) || isStringExpr(decl, x.Y))
+	case *ast.Ident:
+		return decl != nil && declaredString(decl, x.Name)
+	}
+	return false
+}
+
+// declaredString reports whether name is a parameter, var declaration or
+// short variable declaration of type string in decl
+func declaredString(decl *ast.FuncDecl, name string) bool {
+	isString := func(t ast.Expr) bool {
+		id, ok := t.(*ast.Ident)
+		return ok && id.Name == "string"
+	}
+	for _, field := range decl.Type.Params.List {
+		for _, n := range field.Names {
+			if n.Name == name {
+				return isString(field.Type)
+			}
+		}
+	}
+
+	if decl.Body == nil {
+		return false
+	}
+	found := false
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ValueSpec:
+			for _, id := range n.Names {
+				if id.Name == name && n.Type != nil && isString(n.Type) {
+					found = true
+				}
+			}
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE || len(n.Lhs) != len(n.Rhs) {
+				return true
+			}
+			for i, lhs := range n.Lhs {
+				if id, ok := lhs.(*ast.Ident); ok && id.Name == name {
+					if lit, ok := n.Rhs[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+						found = true
+					}
+				}
+			}
+		}
+		return !found
+	})
+	return found
+}
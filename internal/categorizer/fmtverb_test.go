@@ -0,0 +1,134 @@
+package categorizer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func TestFormatVerbs(t *testing.T) {
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"id=%d name=%s", []string{"%d", "%s"}},
+		{"%.2f%% done, %-8v|%x", []string{"%.2f", "%-8v", "%x"}},
+		{"no verbs", nil},
+		{"%[2]d %[1]d", nil},
+		{"%*d", nil},
+	}
+	for _, tt := range tests {
+		if got := formatVerbs(tt.format); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("formatVerbs(%q) = %v, want %v", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestVerbSuggestion(t *testing.T) {
+	tests := []struct {
+		verb      string
+		stringArg bool
+		want      string // empty: no specific suggestion
+	}{
+		{"%d", false, "Replace %d with strconv.Itoa(x)"},
+		{"%x", false, "Replace %x with strconv.FormatInt(int64(x), 16)"},
+		{"%f", false, "Replace %f with strconv.FormatFloat(x, 'f', -1, 64)"},
+		{"%.3g", false, "Replace %.3g with strconv.FormatFloat(x, 'g', 3, 64)"},
+		{"%t", false, "Replace %t with strconv.FormatBool(x)"},
+		{"%q", false, "Replace %q with strconv.Quote(x)"},
+		{"%v", true, "Replace %v with direct concatenation of x"},
+		{"%s", true, "Replace %s with direct concatenation of x"},
+		{"%v", false, ""},
+		{"%05d", false, ""},
+	}
+	for _, tt := range tests {
+		s, ok := verbSuggestion(tt.verb, "x", tt.stringArg)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("verbSuggestion(%q) = %q, want none", tt.verb, s.Short)
+			}
+			continue
+		}
+		if !ok || s.Short != tt.want {
+			t.Errorf("verbSuggestion(%q, %v) = %q, want %q", tt.verb, tt.stringArg, s.Short, tt.want)
+		}
+	}
+}
+
+const fmtSource = `package sample
+
+import "fmt"
+
+func Describe(id int, name string, ratio float64) string {
+	label := "item"
+	return fmt.Sprintf("%s %d %v %.1f", label, id, name, ratio)
+}
+`
+
+func TestFmtSuggestion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sample.go")
+	if err := os.WriteFile(path, []byte(fmtSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		col  int
+		want string
+	}{
+		{38, "Replace %s with direct concatenation of label"},
+		{45, "Replace %d with strconv.Itoa(id)"},
+		{49, "Replace %v with direct concatenation of name"},
+		{55, "Replace %.1f with strconv.FormatFloat(ratio, 'f', 1, 64)"},
+	}
+	src := newSourceCache()
+	for _, tt := range tests {
+		e := parser.EscapeInfo{File: path, Line: 7, Column: tt.col}
+		s, ok := src.fmtSuggestion(e)
+		if !ok || s.Short != tt.want {
+			t.Errorf("fmtSuggestion(col %d) = %q, want %q", tt.col, s.Short, tt.want)
+		}
+	}
+
+	if _, ok := src.fmtSuggestion(parser.EscapeInfo{File: path, Line: 6, Column: 2}); ok {
+		t.Error("fmtSuggestion outside a fmt call returned a suggestion")
+	}
+	if _, ok := src.fmtSuggestion(parser.EscapeInfo{File: "missing.go", Line: 1, Column: 1}); ok {
+		t.Error("fmtSuggestion for a missing file returned a suggestion")
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.go")
+	bad := filepath.Join(dir, "bad.go")
+	missing := filepath.Join(dir, "missing.go")
+	if err := os.WriteFile(good, []byte("package p\n\nfunc f() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bad, []byte("package p\n\nfunc {\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newSourceCache()
+	c.prefetch([]string{good, bad, missing}, 4)
+	for _, path := range []string{good, bad, missing} {
+		if _, ok := c.files[path]; !ok {
+			t.Errorf("%s not prefetched", filepath.Base(path))
+		}
+	}
+	if c.files[good] == nil {
+		t.Error("good.go did not parse")
+	}
+	if c.files[bad] != nil || c.src[bad] == nil {
+		t.Error("bad.go: want no file and its source")
+	}
+	if c.src[missing] != nil {
+		t.Error("missing.go: want no source")
+	}
+	if got := c.file(good); got != c.files[good] {
+		t.Error("file() parsed good.go again")
+	}
+}
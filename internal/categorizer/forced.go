@@ -0,0 +1,88 @@
+package categorizer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// recursiveCycleRe matches the inlining failures that name a recursive
+// function at a call site, which the parser keeps with the escape there:
+// "cannot inline walk into main: repeated recursive cycle"
+var recursiveCycleRe = regexp.MustCompile(`cannot inline (\S+) into \S+: .*recursive`)
+
+// recursiveFuncs returns the functions the compiler reports as recursive,
+// by their name without package or receiver
+func recursiveFuncs(escapes []parser.EscapeInfo) map[string]bool {
+	funcs := make(map[string]bool)
+	for _, e := range escapes {
+		if e.EscapeType == parser.CannotInline && strings.Contains(e.Reason, "recursive") {
+			funcs[baseName(e.Variable)] = true
+		}
+		for _, line := range e.FlowInfo {
+			if m := recursiveCycleRe.FindStringSubmatch(line); m != nil {
+				funcs[baseName(m[1])] = true
+			}
+		}
+	}
+	return funcs
+}
+
+// baseName strips the package and receiver from a function name, as in
+// "(*Tree).walk" or "pkg.walk", and the call from an expression such as
+// "t.walk(n.left)"
+func baseName(name string) string {
+	if strings.HasPrefix(name, "(") {
+		_, name, _ = strings.Cut(name, ").")
+	}
+	name, _, _ = strings.Cut(name, "(")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// compilerForced reports whether the compiler's analysis, not the code,
+// sends e to the heap, with the suggestion saying why: a flow to the heap
+// that ends in a call parameter of a recursive function, or, for the
+// argument slice of a variadic call, of any callee. Without -m=2 flows
+// there is nothing to tell by.
+func compilerForced(e parser.EscapeInfo, recursive map[string]bool) (Suggestion, bool) {
+	caller := ""
+	if m := inFuncRe.FindStringSubmatch(e.Reason); m != nil {
+		caller = baseName(m[1])
+	}
+	for _, f := range e.Flows {
+		if f.Dst != "{heap}" || len(f.Steps) == 0 {
+			continue
+		}
+		last := f.Steps[len(f.Steps)-1]
+		if last.Reason != "call parameter" {
+			continue
+		}
+		callee := baseName(last.Expr)
+		suggestion := suggestions[CategoryCompilerForced]
+		switch {
+		case recursive[callee] || callee == caller:
+			suggestion.Short = fmt.Sprintf("Structural; unlikely fixable: passed into recursive %s, which escape analysis cannot follow", callee)
+			return suggestion, true
+		case e.Variable == "... argument" && !assigns(f):
+			suggestion.Short = fmt.Sprintf("Structural; unlikely fixable here: %s may keep its variadic arguments", callee)
+			return suggestion, true
+		}
+	}
+	return Suggestion{}, false
+}
+
+// assigns reports whether a flow stores the value on its way, as an
+// inlined callee storing its variadic slice does
+func assigns(f parser.Flow) bool {
+	for _, step := range f.Steps {
+		if strings.HasPrefix(step.Reason, "assign") {
+			return true
+		}
+	}
+	return false
+}
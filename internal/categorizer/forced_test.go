@@ -0,0 +1,86 @@
+package categorizer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// forcedOutput is the -m=2 output of a recursive chain(&u, n-1) inlined
+// into main on line 37, logf(a, b) through a func variable and l.Log(a,
+// b) through an interface on lines 46 and 47, and an address returned
+// from a function on line 32
+const forcedOutput = `./main.go:37:32: inlining call to chain
+./main.go:37:32: cannot inline chain into main: repeated recursive cycle
+./main.go:37:32: u escapes to heap in main:
+./main.go:37:32:   flow: {heap} ← &u:
+./main.go:37:32:     from &u (address-of) at ./main.go:37:32
+./main.go:37:32:     from chain(&u, n - 1) (call parameter) at ./main.go:37:32
+./main.go:37:32: moved to heap: u
+./main.go:46:6: ... argument escapes to heap in call:
+./main.go:46:6:   flow: {heap} ← &{storage for ... argument}:
+./main.go:46:6:     from ... argument (spill) at ./main.go:46:6
+./main.go:46:6:     from logf(... argument...) (call parameter) at ./main.go:46:6
+./main.go:47:7: ... argument escapes to heap in call:
+./main.go:47:7:   flow: {heap} ← &{storage for ... argument}:
+./main.go:47:7:     from ... argument (spill) at ./main.go:47:7
+./main.go:47:7:     from l.Log(... argument...) (call parameter) at ./main.go:47:7
+./main.go:46:6: ... argument escapes to heap
+./main.go:47:7: ... argument escapes to heap
+./main.go:30:2: x escapes to heap in build:
+./main.go:30:2:   flow: ~r0 ← &x:
+./main.go:30:2:     from &x (address-of) at ./main.go:32:9
+./main.go:30:2:     from return &x (return) at ./main.go:32:2
+./main.go:30:2: moved to heap: x
+`
+
+func TestCompilerForced(t *testing.T) {
+	escapes, err := parser.Parse(forcedOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := Categorize(escapes)
+
+	want := map[int]struct {
+		cat   Category
+		short string
+	}{
+		37: {CategoryCompilerForced, "recursive chain"},
+		46: {CategoryCompilerForced, "logf may keep its variadic arguments"},
+		47: {CategoryCompilerForced, "Log may keep its variadic arguments"},
+		30: {CategoryReturnPointer, ""},
+	}
+	seen := make(map[int]bool)
+	for _, e := range results.Escapes {
+		w, ok := want[e.Info.Line]
+		if !ok || len(e.Info.Flows) == 0 {
+			continue
+		}
+		seen[e.Info.Line] = true
+		if e.Category != w.cat {
+			t.Errorf("line %d: Category = %s, want %s", e.Info.Line, e.Category, w.cat)
+		}
+		if !strings.Contains(e.Suggestion.Short, w.short) {
+			t.Errorf("line %d: Suggestion = %q, want it to mention %q", e.Info.Line, e.Suggestion.Short, w.short)
+		}
+		if w.cat == CategoryCompilerForced && (e.Severity != SeverityLow || e.Effort != EffortStructural) {
+			t.Errorf("line %d: Severity, Effort = %s, %s, want low, structural", e.Info.Line, e.Severity, e.Effort)
+		}
+	}
+	if len(seen) != len(want) {
+		t.Errorf("found escapes with flows on lines %v, want %d", seen, len(want))
+	}
+}
+
+func TestRecursiveFuncs(t *testing.T) {
+	escapes := []parser.EscapeInfo{
+		{EscapeType: parser.CannotInline, Variable: "(*Tree).walk", Reason: "recursive"},
+		{EscapeType: parser.CannotInline, Variable: "parse", Reason: "function too complex: cost 98 exceeds budget 80"},
+		{EscapeType: parser.EscapesToHeap, FlowInfo: []string{"./main.go:37:32: cannot inline pkg.chain into main: repeated recursive cycle"}},
+	}
+	got := recursiveFuncs(escapes)
+	if !got["walk"] || !got["chain"] || got["parse"] || len(got) != 2 {
+		t.Errorf("recursiveFuncs = %v, want walk and chain", got)
+	}
+}
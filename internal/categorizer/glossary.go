@@ -0,0 +1,161 @@
+package categorizer
+
+// categories lists the built-in categories, most actionable first
+var categories = []Category{
+	CategoryReturnPointer,
+	CategoryInterfaceBoxing,
+	CategoryClosureCapture,
+	CategoryStoredClosure,
+	CategoryGoroutineEscape,
+	CategoryChannelSend,
+	CategorySliceGrow,
+	CategoryUnknownSize,
+	CategoryTooLarge,
+	CategoryFmtCall,
+	CategoryReflection,
+	CategoryContextValue,
+	CategoryPoolMisuse,
+	CategoryErrorWrapping,
+	CategoryLeakingParam,
+	CategoryLeakingContent,
+	CategoryStringConversion,
+	CategorySpill,
+	CategoryAssignment,
+	CategoryCallParameter,
+	CategoryMapAllocation,
+	CategoryNewAllocation,
+	CategoryCompositeLiteral,
+	CategoryCompilerForced,
+	CategoryUncategorized,
+}
+
+// Categories returns the built-in categories
+func Categories() []Category {
+	return append([]Category(nil), categories...)
+}
+
+// advisories lists the advisory categories, which name findings other
+// than escapes
+var advisories = []Category{CategoryLargeValueCopy}
+
+// Advisories returns the advisory categories
+func Advisories() []Category {
+	return append([]Category(nil), advisories...)
+}
+
+// Example is a minimal snippet that escapes for a category's reason, and
+// the same code rewritten to stay on the stack (or allocate less)
+type Example struct {
+	Escaping string
+	Fixed    string
+}
+
+// examples maps categories to their examples
+var examples = map[Category]Example{
+	CategoryReturnPointer: {
+		Escaping: "func newPoint() *Point {\n\tp := Point{X: 1, Y: 2}\n\treturn &p // moved to heap: p\n}",
+		Fixed:    "func newPoint() Point {\n\treturn Point{X: 1, Y: 2}\n}",
+	},
+	CategoryInterfaceBoxing: {
+		Escaping: "func sum(values []any) (n int) {\n\tfor _, v := range values {\n\t\tn += v.(int)\n\t}\n\treturn n\n}\n\nsum([]any{x, y}) // x escapes to heap",
+		Fixed:    "func sum[T int | int64](values []T) (n T) {\n\tfor _, v := range values {\n\t\tn += v\n\t}\n\treturn n\n}",
+	},
+	CategoryClosureCapture: {
+		Escaping: "for _, job := range jobs {\n\tgo func() {\n\t\tprocess(job) // job captured by a closure\n\t}()\n}",
+		Fixed:    "for _, job := range jobs {\n\tgo func(j Job) {\n\t\tprocess(j)\n\t}(job)\n}",
+	},
+	CategoryStoredClosure: {
+		Escaping: "func (s *Server) Start(cfg *Config) {\n\ts.onClose = func() {\n\t\tlog.Println(cfg.Name) // keeps all of cfg alive\n\t}\n}",
+		Fixed:    "func (s *Server) Start(cfg *Config) {\n\tname := cfg.Name\n\ts.onClose = func() {\n\t\tlog.Println(name) // captures only the name\n\t}\n}",
+	},
+	CategoryGoroutineEscape: {
+		Escaping: "for req := range requests {\n\tbuf := make([]byte, 4096)\n\tgo handle(req, buf) // buf escapes\n}",
+		Fixed:    "for i := 0; i < workers; i++ {\n\tgo func() {\n\t\tbuf := make([]byte, 4096) // one buffer per worker\n\t\tfor req := range requests {\n\t\t\thandle(req, buf)\n\t\t}\n\t}()\n}",
+	},
+	CategoryChannelSend: {
+		Escaping: "msg := &Message{Body: body}\nch <- msg // msg escapes",
+		Fixed:    "msg := pool.Get().(*Message)\nmsg.Body = body\nch <- msg // the receiver returns msg to the pool",
+	},
+	CategorySliceGrow: {
+		Escaping: "var ids []int\nfor _, u := range users {\n\tids = append(ids, u.ID)\n}",
+		Fixed:    "ids := make([]int, 0, len(users))\nfor _, u := range users {\n\tids = append(ids, u.ID)\n}",
+	},
+	CategoryUnknownSize: {
+		Escaping: "buf := make([]byte, n) // non-constant size",
+		Fixed:    "var arr [512]byte\nbuf := arr[:n] // when n is known to be at most 512",
+	},
+	CategoryTooLarge: {
+		Escaping: "var table [1 << 20]int64 // too large for the stack",
+		Fixed:    "table := make([]int64, 1<<20) // allocate once and reuse",
+	},
+	CategoryFmtCall: {
+		Escaping: "key := fmt.Sprintf(\"%d\", id) // id escapes to heap",
+		Fixed:    "key := strconv.Itoa(id)",
+	},
+	CategoryReflection: {
+		Escaping: "v := reflect.ValueOf(cfg) // cfg escapes\nname := v.FieldByName(\"Name\").String()",
+		Fixed:    "name := cfg.Name",
+	},
+	CategoryContextValue: {
+		Escaping: "ctx = context.WithValue(ctx, \"user\", user) // \"user\" and user escape",
+		Fixed:    "type userKey struct{}\n\nctx = context.WithValue(ctx, userKey{}, &req.User) // zero-size key, pointer value",
+	},
+	CategoryPoolMisuse: {
+		Escaping: "buf := bufPool.Get().(*bytes.Buffer)\ndefer bufPool.Put(buf)\nbuf.Reset()\nrender(buf, page)\nreturn &Page{Body: buf} // &Page{...} escapes, still holding buf after Put",
+		Fixed:    "buf := bufPool.Get().(*bytes.Buffer)\ndefer bufPool.Put(buf)\nbuf.Reset()\nrender(buf, page)\nreturn &Page{Body: bytes.Clone(buf.Bytes())} // a copy outlives the Put",
+	},
+	CategoryErrorWrapping: {
+		Escaping: "if n < 0 {\n\treturn fmt.Errorf(\"negative count %d\", n) // allocates per call\n}",
+		Fixed:    "var ErrNegative = errors.New(\"negative count\")\n\nif n < 0 {\n\treturn ErrNegative\n}",
+	},
+	CategoryLeakingParam: {
+		Escaping: "func (c *Cache) Put(key string, v *Value) {\n\tc.items[key] = v // leaking param: v\n}",
+		Fixed:    "func (c *Cache) Put(key string, v Value) {\n\tc.items[key] = v // store a copy\n}",
+	},
+	CategoryLeakingContent: {
+		Escaping: "func (r *Recorder) Record(samples []float64) {\n\tr.last = samples // leaking param content: samples\n}",
+		Fixed:    "func (r *Recorder) Record(samples []float64) {\n\tr.last = append(r.last[:0], samples...) // copy into a reused buffer\n}",
+	},
+	CategoryCompilerForced: {
+		Escaping: "func prepend(list *Node, n int) *Node {\n\tif n == 0 {\n\t\treturn list\n\t}\n\tnode := Node{Next: list, Val: n}\n\treturn prepend(&node, n-1) // recursive: &node goes to the heap\n}",
+		Fixed:    "func prepend(list *Node, n int) *Node {\n\tnodes := make([]Node, n) // a loop, and one allocation for the chain\n\tfor i := range nodes {\n\t\tnodes[i] = Node{Next: list, Val: n - i}\n\t\tlist = &nodes[i]\n\t}\n\treturn list\n}",
+	},
+	CategoryStringConversion: {
+		Escaping: "s := string(data) // allocates a copy\nreturn strings.HasPrefix(s, \"GET\")",
+		Fixed:    "return bytes.HasPrefix(data, []byte(\"GET\"))",
+	},
+	CategorySpill: {
+		Escaping: "func keys(m map[string]int) []string {\n\tks := make([]string, 0, len(m))\n\tfor k := range m {\n\t\tks = append(ks, k)\n\t}\n\treturn ks // spilled to the heap\n}",
+		Fixed:    "func keys(m map[string]int, ks []string) []string {\n\tfor k := range m {\n\t\tks = append(ks, k) // caller supplies the buffer\n\t}\n\treturn ks\n}",
+	},
+	CategoryAssignment: {
+		Escaping: "var last *Request\n\nfunc handle(r Request) {\n\tlast = &r // r escapes via the global\n}",
+		Fixed:    "var last Request\n\nfunc handle(r Request) {\n\tlast = r\n}",
+	},
+	CategoryCallParameter: {
+		Escaping: "b := &bytes.Buffer{}\nregister(b) // register stores b",
+		Fixed:    "var b bytes.Buffer\nuse(&b) // use does not retain b",
+	},
+	CategoryMapAllocation: {
+		Escaping: "seen := map[int]bool{} // allocated per call",
+		Fixed:    "var seen [64]bool // small, dense keys",
+	},
+	CategoryNewAllocation: {
+		Escaping: "p := new(Point)\nstore(p)",
+		Fixed:    "var p Point\ncompute(&p) // compute does not retain p",
+	},
+	CategoryCompositeLiteral: {
+		Escaping: "return &Config{Timeout: t} // &Config{...} escapes to heap",
+		Fixed:    "return Config{Timeout: t}",
+	},
+	CategoryLargeValueCopy: {
+		Escaping: "type Frame struct{ Pixels [4096]byte }\n\nfunc render() Frame // copies 4KB per call\n\nfor i := range frames {\n\tframes[i] = render()\n}",
+		Fixed:    "func render(f *Frame) // fills in the caller's frame\n\nfor i := range frames {\n\trender(&frames[i])\n}",
+	},
+}
+
+// GetExample returns the example for a category, if it has one
+func GetExample(cat Category) (Example, bool) {
+	ex, ok := examples[cat]
+	return ex, ok
+}
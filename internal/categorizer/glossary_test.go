@@ -0,0 +1,50 @@
+package categorizer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCategories(t *testing.T) {
+	cats := append(Categories(), Advisories()...)
+	if len(cats) != len(suggestions) {
+		t.Errorf("Categories() and Advisories() have %d categories, suggestions has %d", len(cats), len(suggestions))
+	}
+	for _, cat := range cats {
+		s, ok := suggestions[cat]
+		if !ok {
+			t.Errorf("%s has no suggestion", cat)
+		}
+		if !strings.HasPrefix(s.DocLink, "https://") {
+			t.Errorf("%s DocLink = %q, want an https link", cat, s.DocLink)
+		}
+		if _, ok := GetExample(cat); !ok && cat != CategoryUncategorized {
+			t.Errorf("%s has no example", cat)
+		}
+	}
+}
+
+func TestGroups(t *testing.T) {
+	for _, cat := range Categories() {
+		if _, ok := groupOf[cat]; !ok {
+			t.Errorf("%s has no group", cat)
+		}
+	}
+
+	byGroup := CountByGroup(map[Category]int{
+		CategoryReturnPointer:   3,
+		CategoryInterfaceBoxing: 2,
+		CategoryGoroutineEscape: 1,
+		CategoryFmtCall:         0,
+		"alloc/slice":           4,
+	})
+	want := map[Group]int{GroupAPIDesign: 5, GroupConcurrency: 1, GroupOther: 4}
+	if len(byGroup) != len(want) {
+		t.Errorf("CountByGroup() = %v, want %v", byGroup, want)
+	}
+	for g, n := range want {
+		if byGroup[g] != n {
+			t.Errorf("CountByGroup()[%s] = %d, want %d", g, byGroup[g], n)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package categorizer
+
+// Group is a bucket of related categories, for reports that summarize
+// escapes at a coarser level than their causes
+type Group string
+
+const (
+	GroupAPIDesign   Group = "api-design"   // signatures: pointers returned, interfaces taken, parameters leaked
+	GroupConcurrency Group = "concurrency"  // goroutines, channels, closures and contexts
+	GroupStdlibUsage Group = "stdlib-usage" // fmt, reflect, errors, sync.Pool and string conversions
+	GroupSize        Group = "size"         // allocations the compiler cannot size or keep on the stack
+	GroupOther       Group = "other"        // uncategorized escapes and categories of your own
+)
+
+// groups lists the groups in report order
+var groups = []Group{GroupAPIDesign, GroupConcurrency, GroupStdlibUsage, GroupSize, GroupOther}
+
+// groupOf maps the built-in categories to their groups
+var groupOf = map[Category]Group{
+	CategoryReturnPointer:    GroupAPIDesign,
+	CategoryInterfaceBoxing:  GroupAPIDesign,
+	CategoryLeakingParam:     GroupAPIDesign,
+	CategoryLeakingContent:   GroupAPIDesign,
+	CategoryCallParameter:    GroupAPIDesign,
+	CategoryAssignment:       GroupAPIDesign,
+	CategorySpill:            GroupAPIDesign,
+	CategoryClosureCapture:   GroupConcurrency,
+	CategoryStoredClosure:    GroupConcurrency,
+	CategoryGoroutineEscape:  GroupConcurrency,
+	CategoryChannelSend:      GroupConcurrency,
+	CategoryContextValue:     GroupConcurrency,
+	CategoryFmtCall:          GroupStdlibUsage,
+	CategoryReflection:       GroupStdlibUsage,
+	CategoryErrorWrapping:    GroupStdlibUsage,
+	CategoryStringConversion: GroupStdlibUsage,
+	CategoryPoolMisuse:       GroupStdlibUsage,
+	CategorySliceGrow:        GroupSize,
+	CategoryUnknownSize:      GroupSize,
+	CategoryTooLarge:         GroupSize,
+	CategoryMapAllocation:    GroupSize,
+	CategoryNewAllocation:    GroupSize,
+	CategoryCompositeLiteral: GroupSize,
+	CategoryCompilerForced:   GroupSize,
+	CategoryUncategorized:    GroupOther,
+}
+
+// Groups returns the category groups, in report order
+func Groups() []Group {
+	return append([]Group(nil), groups...)
+}
+
+// GroupOf returns the group of a category. Categories that are not
+// built in, from remapping or an external categorizer, are GroupOther.
+func GroupOf(cat Category) Group {
+	if g, ok := groupOf[cat]; ok {
+		return g
+	}
+	return GroupOther
+}
+
+// CountByGroup sums counts by category into counts by group. Groups
+// without escapes are left out.
+func CountByGroup(byCategory map[Category]int) map[Group]int {
+	byGroup := make(map[Group]int)
+	for cat, n := range byCategory {
+		if n > 0 {
+			byGroup[GroupOf(cat)] += n
+		}
+	}
+	return byGroup
+}
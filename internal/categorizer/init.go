@@ -0,0 +1,46 @@
+package categorizer
+
+import (
+	"go/ast"
+	"go/token"
+
+	heapparser "github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// atInit reports whether e happens at package initialization: in an init
+// function, or in the initializer of a package-level variable, func
+// literals in it included. Such escapes allocate once per process rather
+// than once per call.
+func (c *sourceCache) atInit(e heapparser.EscapeInfo) bool {
+	f := c.file(e.File)
+	if f == nil {
+		return false
+	}
+	p := c.pos(f, e.Line, e.Column)
+	if p == token.NoPos {
+		return false
+	}
+	for _, d := range f.Decls {
+		if p < d.Pos() || d.End() <= p {
+			continue
+		}
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			return d.Recv == nil && d.Name.Name == "init"
+		case *ast.GenDecl:
+			return d.Tok == token.VAR
+		}
+	}
+	return false
+}
+
+// CountInit counts the escapes at package initialization
+func CountInit(escapes []CategorizedEscape) int {
+	n := 0
+	for _, e := range escapes {
+		if e.Init {
+			n++
+		}
+	}
+	return n
+}
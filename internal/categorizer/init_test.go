@@ -0,0 +1,75 @@
+package categorizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+const initSource = `package sample
+
+var registry = map[string]int{"a": 1}
+
+var handlers = []func() *int{
+	func() *int { return new(int) },
+}
+
+func init() {
+	registry["b"] = len(make([]byte, 10))
+}
+
+func Lookup(key string) *int {
+	v := registry[key]
+	return &v
+}
+
+type T struct{}
+
+func (T) init() *int { return new(int) }
+`
+
+func TestCategorizeInit(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sample.go")
+	if err := os.WriteFile(file, []byte(initSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		line     int
+		column   int
+		variable string
+		want     bool
+	}{
+		{name: "package-level var", line: 3, column: 16, variable: `map[string]int{...}`, want: true},
+		{name: "func literal in a var", line: 6, column: 23, variable: "new(int)", want: true},
+		{name: "init function", line: 10, column: 25, variable: "make([]byte, 10)", want: true},
+		{name: "function", line: 14, column: 2, variable: "v", want: false},
+		{name: "method named init", line: 20, column: 25, variable: "new(int)", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := parser.EscapeInfo{
+				Package:    "example.com/sample",
+				File:       file,
+				Line:       tt.line,
+				Column:     tt.column,
+				Variable:   tt.variable,
+				EscapeType: parser.EscapesToHeap,
+				Reason:     tt.variable + " escapes to heap",
+			}
+			got := CategorizeWith([]parser.EscapeInfo{e}).Escapes[0]
+			if got.Init != tt.want {
+				t.Errorf("Init = %v, want %v", got.Init, tt.want)
+			}
+			if tt.want && got.Severity != SeverityLow {
+				t.Errorf("Severity = %s, want %s at initialization", got.Severity, SeverityLow)
+			}
+			if n := CountInit([]CategorizedEscape{got}); n != map[bool]int{true: 1}[tt.want] {
+				t.Errorf("CountInit() = %d", n)
+			}
+		})
+	}
+}
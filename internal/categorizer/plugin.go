@@ -0,0 +1,42 @@
+package categorizer
+
+import (
+	"sync"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// Categorizer assigns a category to an escape. It returns false to leave
+// the escape to the next categorizer and finally the built-in rules. An
+// empty Suggestion is replaced by the category's default one.
+type Categorizer interface {
+	Categorize(e parser.EscapeInfo) (Category, Suggestion, bool)
+}
+
+// CategorizerFunc adapts a function to the Categorizer interface
+type CategorizerFunc func(e parser.EscapeInfo) (Category, Suggestion, bool)
+
+// Categorize calls f(e)
+func (f CategorizerFunc) Categorize(e parser.EscapeInfo) (Category, Suggestion, bool) {
+	return f(e)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []Categorizer
+)
+
+// Register adds a categorizer consulted by Categorize before the
+// built-in rules. Categorizers are tried in registration order.
+func Register(c Categorizer) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, c)
+}
+
+// Registered returns the registered categorizers
+func Registered() []Categorizer {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]Categorizer(nil), registry...)
+}
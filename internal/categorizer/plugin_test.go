@@ -0,0 +1,70 @@
+package categorizer
+
+import (
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func TestCategorizeWithCustom(t *testing.T) {
+	escapes := []parser.EscapeInfo{
+		{File: "h.go", Line: 1, Variable: "c", EscapeType: parser.EscapesToHeap, Reason: "c escapes to heap via gin.Context"},
+		{File: "h.go", Line: 2, Variable: "fmt.Sprintf(...)", EscapeType: parser.EscapesToHeap, Reason: "fmt.Sprintf"},
+		{File: "h.go", Line: 3, Variable: "y", EscapeType: parser.EscapesToHeap, Reason: "reflect.ValueOf"},
+	}
+
+	gin := CategorizerFunc(func(e parser.EscapeInfo) (Category, Suggestion, bool) {
+		if e.Line == 1 {
+			return "gin-context", Suggestion{Short: "Copy values out of gin.Context"}, true
+		}
+		return "", Suggestion{}, false
+	})
+	// Claims line 1 too, but registered later
+	shadowed := CategorizerFunc(func(e parser.EscapeInfo) (Category, Suggestion, bool) {
+		if e.Line <= 2 {
+			return CategoryReflection, Suggestion{}, true
+		}
+		return "", Suggestion{}, false
+	})
+
+	results := CategorizeWith(escapes, gin, shadowed)
+
+	want := []struct {
+		cat   Category
+		short string
+	}{
+		{"gin-context", "Copy values out of gin.Context"},
+		{CategoryReflection, suggestions[CategoryReflection].Short},
+		{CategoryReflection, suggestions[CategoryReflection].Short},
+	}
+	for i, w := range want {
+		got := results.Escapes[i]
+		if got.Category != w.cat || got.Suggestion.Short != w.short {
+			t.Errorf("escape %d = %s (%q), want %s (%q)", i, got.Category, got.Suggestion.Short, w.cat, w.short)
+		}
+	}
+	if results.ByCategory["gin-context"] != 1 {
+		t.Errorf("ByCategory[gin-context] = %d, want 1", results.ByCategory["gin-context"])
+	}
+}
+
+func TestRegister(t *testing.T) {
+	saved := Registered()
+	defer func() {
+		registryMu.Lock()
+		registry = saved
+		registryMu.Unlock()
+	}()
+
+	Register(CategorizerFunc(func(e parser.EscapeInfo) (Category, Suggestion, bool) {
+		return "custom", Suggestion{}, true
+	}))
+
+	results := Categorize([]parser.EscapeInfo{{Variable: "x", EscapeType: parser.MovedToHeap}})
+	if results.Escapes[0].Category != "custom" {
+		t.Errorf("Category = %s, want custom", results.Escapes[0].Category)
+	}
+	if results.Escapes[0].Suggestion != GetSuggestion("custom") {
+		t.Errorf("Suggestion = %+v, want default for unknown category", results.Escapes[0].Suggestion)
+	}
+}
@@ -0,0 +1,262 @@
+package categorizer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	heapparser "github.com/harshakonda/heapcheck/internal/parser"
+	"github.com/harshakonda/heapcheck/internal/typecheck"
+)
+
+// poolMisuse returns the suggestion for an escape that defeats a
+// sync.Pool: a non-pointer boxed by Put, found from the flow or the
+// source, or an allocation that keeps a value obtained from Get beyond
+// the Put that returns it, found from the source
+func (c *sourceCache) poolMisuse(e heapparser.EscapeInfo) (Suggestion, bool) {
+	suggestion := suggestions[CategoryPoolMisuse]
+	pool, ok := boxedByPut(e)
+	if !ok {
+		pool, ok = c.putArg(e)
+	}
+	if ok {
+		suggestion.Short = fmt.Sprintf("%s.Put boxes %s on every call: pool a pointer, such as *[]byte", pool, e.Variable)
+		return suggestion, true
+	}
+	if v, pool, ok := c.keptAfterPut(e); ok {
+		suggestion.Short = fmt.Sprintf("%s from %s.Get is kept after %s.Put: copy what outlives the Put, or Put it later", v, pool, pool)
+		return suggestion, true
+	}
+	return Suggestion{}, false
+}
+
+// putCall starts the call in the flow of a value passed to sync.Pool's
+// Put, as in "from (*sync.Pool).Put(bufPool, b[:0]) (call parameter)"
+const putCall = "(*sync.Pool).Put("
+
+// boxedByPut reports whether e is the argument of a sync.Pool Put that
+// the compiler boxes into the interface Put takes, allocating on every
+// call, and returns the pool. A pointer is boxed without allocating, so
+// the escapes of pointers put into a pool are those of their own
+// allocation, with another variable.
+func boxedByPut(e heapparser.EscapeInfo) (string, bool) {
+	if e.EscapeType != heapparser.EscapesToHeap || strings.HasPrefix(e.Variable, "&") || strings.HasPrefix(e.Variable, "new(") {
+		return "", false
+	}
+	for _, line := range e.FlowInfo {
+		_, call, ok := strings.Cut(line, putCall)
+		if !ok {
+			continue
+		}
+		pool, arg, ok := strings.Cut(call, ", ")
+		if ok && strings.HasPrefix(arg, e.Variable+") (call parameter)") {
+			return pool, true
+		}
+	}
+	return "", false
+}
+
+// putArg reads the source of e and reports whether it is in the argument
+// of a pool's Put, for escapes reported without their flow, such as
+// "b[:0] escapes to heap" at the bracket of pool.Put(b[:0]). The compiler
+// reports no escape for the arguments Put boxes without allocating.
+func (c *sourceCache) putArg(e heapparser.EscapeInfo) (string, bool) {
+	if e.EscapeType != heapparser.EscapesToHeap || strings.HasPrefix(e.Variable, "&") || strings.HasPrefix(e.Variable, "new(") {
+		return "", false
+	}
+	f := c.file(e.File)
+	if f == nil || !importsSync(f) {
+		return "", false
+	}
+	p := c.pos(f, e.Line, e.Column)
+	fd := typecheck.EnclosingFunc(f, p)
+	if fd == nil {
+		return "", false
+	}
+	pool, found := "", false
+	ast.Inspect(fd, func(n ast.Node) bool {
+		if found || n == nil || p < n.Pos() || p >= n.End() {
+			return false
+		}
+		if call, ok := n.(*ast.CallExpr); ok && len(call.Args) == 1 && call.Args[0].Pos() <= p && p < call.Args[0].End() {
+			pool, found = poolCall(call, "Put", 1)
+		}
+		return !found
+	})
+	return pool, found
+}
+
+// importsSync reports whether f imports the sync package
+func importsSync(f *ast.File) bool {
+	for _, imp := range f.Imports {
+		if imp.Path.Value == `"sync"` {
+			return true
+		}
+	}
+	return false
+}
+
+// keptAfterPut reads the source of e and reports whether the allocation
+// it escapes for holds a variable assigned from a pool's Get, as in
+// v := pool.Get().(*T), in a function that also passes v to the same
+// pool's Put: the allocation outlives the function, and so the Put
+// that lets the next Get hand out v again. The allocation holds v when
+// it is v itself, a func literal that captures v, or a composite literal
+// or append with v as an element. It returns v and the pool.
+func (c *sourceCache) keptAfterPut(e heapparser.EscapeInfo) (string, string, bool) {
+	f := c.file(e.File)
+	if f == nil {
+		return "", "", false
+	}
+	p := c.pos(f, e.Line, e.Column)
+	fd := typecheck.EnclosingFunc(f, p)
+	if fd == nil || fd.Body == nil {
+		return "", "", false
+	}
+	pooled := pooledVars(fd)
+	if len(pooled) == 0 {
+		return "", "", false
+	}
+
+	// The allocation is the outermost expression starting at p, or the
+	// append whose parenthesis is at p. Other calls starting at p, such
+	// as an immediately invoked func literal, allocate their results, not
+	// themselves, and are looked into.
+	var alloc ast.Node
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		if alloc != nil || n == nil || p < n.Pos() || p >= n.End() {
+			return false
+		}
+		if call, ok := n.(*ast.CallExpr); ok {
+			if fn, ok := call.Fun.(*ast.Ident); ok && fn.Name == "append" && (call.Lparen == p || call.Pos() == p) {
+				alloc = n
+			}
+		} else if _, ok := n.(ast.Expr); ok && n.Pos() == p {
+			alloc = n
+		}
+		return alloc == nil
+	})
+	if alloc == nil {
+		return "", "", false
+	}
+	for v, pool := range pooled {
+		if holds(alloc, v) {
+			return v, pool, true
+		}
+	}
+	return "", "", false
+}
+
+// pooledVars returns the variables fd assigns from a pool's Get with a
+// type assertion and passes to the same pool's Put, mapped to the pool
+func pooledVars(fd *ast.FuncDecl) map[string]string {
+	got := make(map[string]string)
+	put := make(map[string]string)
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if len(n.Rhs) != 1 {
+				break
+			}
+			id, ok := n.Lhs[0].(*ast.Ident)
+			if !ok {
+				break
+			}
+			if assert, ok := n.Rhs[0].(*ast.TypeAssertExpr); ok {
+				if pool, ok := poolCall(assert.X, "Get", 0); ok {
+					got[id.Name] = pool
+				}
+			}
+		case *ast.CallExpr:
+			if pool, ok := poolCall(n, "Put", 1); ok {
+				if id, ok := n.Args[0].(*ast.Ident); ok {
+					put[id.Name] = pool
+				}
+			}
+		}
+		return true
+	})
+	for v, pool := range got {
+		if put[v] != pool {
+			delete(got, v)
+		}
+	}
+	return got
+}
+
+// poolCall reports whether x calls method on a pool with args arguments,
+// returning the pool expression, e.g. "bufPool" or "s.pool"
+func poolCall(x ast.Expr, method string, args int) (string, bool) {
+	call, ok := x.(*ast.CallExpr)
+	if !ok || len(call.Args) != args {
+		return "", false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != method {
+		return "", false
+	}
+	return types.ExprString(sel.X), true
+}
+
+// holds reports whether the allocation at n keeps v alive
+func holds(n ast.Node, v string) bool {
+	switch n := n.(type) {
+	case *ast.Ident:
+		return n.Name == v
+	case *ast.FuncLit:
+		return mentions(n.Body, v)
+	case *ast.UnaryExpr:
+		return n.Op == token.AND && holds(n.X, v)
+	case *ast.CompositeLit:
+		for _, elt := range n.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				elt = kv.Value
+			}
+			if refers(elt, v) {
+				return true
+			}
+		}
+	case *ast.CallExpr:
+		if fn, ok := n.Fun.(*ast.Ident); ok && fn.Name == "append" {
+			for _, arg := range n.Args[min(1, len(n.Args)):] {
+				if refers(arg, v) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// refers reports whether x is v or shares its memory: what it points to,
+// a slice of it, or its Bytes
+func refers(x ast.Expr, v string) bool {
+	switch x := x.(type) {
+	case *ast.Ident:
+		return x.Name == v
+	case *ast.ParenExpr:
+		return refers(x.X, v)
+	case *ast.StarExpr:
+		return refers(x.X, v)
+	case *ast.SliceExpr:
+		return refers(x.X, v)
+	case *ast.CallExpr:
+		sel, ok := x.Fun.(*ast.SelectorExpr)
+		return ok && sel.Sel.Name == "Bytes" && refers(sel.X, v)
+	}
+	return false
+}
+
+// mentions reports whether n uses the name v
+func mentions(n ast.Node, v string) bool {
+	found := false
+	ast.Inspect(n, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == v {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,114 @@
+package categorizer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+const poolSource = `package sample
+
+import (
+	"bytes"
+	"sync"
+)
+
+var bufPool sync.Pool
+
+type Server struct {
+	pool  sync.Pool
+	items []*bytes.Buffer
+}
+
+type Result struct{ Buf *bytes.Buffer }
+
+func PutSlice(b []byte) {
+	bufPool.Put(b[:0])
+}
+
+func Render(s string) *Result {
+	buf := bufPool.Get().(*bytes.Buffer)
+	defer bufPool.Put(buf)
+	buf.WriteString(s)
+	return &Result{Buf: buf}
+}
+
+func Async(s string) {
+	buf := bufPool.Get().(*bytes.Buffer)
+	go func() {
+		buf.WriteString(s)
+	}()
+	bufPool.Put(buf)
+}
+
+func (srv *Server) Handle() {
+	b := srv.pool.Get().(*bytes.Buffer)
+	srv.items = append(srv.items, b)
+	srv.pool.Put(b)
+}
+
+func Copy(s string) (string, *Result) {
+	buf := bufPool.Get().(*bytes.Buffer)
+	buf.WriteString(s)
+	out := buf.String()
+	r := &Result{Buf: new(bytes.Buffer)}
+	bufPool.Put(buf)
+	return out, r
+}
+
+func Handoff() *Result {
+	buf := bufPool.Get().(*bytes.Buffer)
+	return &Result{Buf: buf}
+}
+`
+
+func TestPoolMisuse(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sample.go")
+	if err := os.WriteFile(file, []byte(poolSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	putFlow := []string{
+		"./sample.go:18:15:   flow: {heap} ← &{storage for b[:0]}:",
+		"./sample.go:18:15:     from (*sync.Pool).Put(bufPool, b[:0]) (call parameter) at ./sample.go:18:13",
+	}
+	tests := []struct {
+		name      string
+		line, col int
+		variable  string
+		flow      []string
+		want      string // start of the suggestion, "" for none
+	}{
+		{name: "put boxes", line: 18, col: 15, variable: "b[:0]", flow: putFlow, want: "bufPool.Put boxes b[:0]"},
+		{name: "put boxes without flow", line: 18, col: 15, variable: "b[:0]", want: "bufPool.Put boxes b[:0]"},
+		{name: "returned after put", line: 25, col: 9, variable: "&Result{...}", want: "buf from bufPool.Get is kept after bufPool.Put"},
+		{name: "goroutine after put", line: 30, col: 5, variable: "func literal", want: "buf from bufPool.Get"},
+		{name: "appended after put", line: 38, col: 20, variable: "append(srv.items, b)", want: "b from srv.pool.Get is kept after srv.pool.Put"},
+		{name: "copied before put", line: 45, col: 19, variable: "string(bytes.b.buf[bytes.b.off:])"},
+		{name: "other value", line: 46, col: 7, variable: "&Result{...}"},
+		{name: "handed off without put", line: 53, col: 9, variable: "&Result{...}"},
+	}
+	src := newSourceCache()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := parser.EscapeInfo{File: file, Line: tt.line, Column: tt.col, Variable: tt.variable, EscapeType: parser.EscapesToHeap, FlowInfo: tt.flow}
+			s, ok := src.poolMisuse(e)
+			if ok != (tt.want != "") || !strings.HasPrefix(s.Short, tt.want) {
+				t.Errorf("poolMisuse() = %q, %v, want %q", s.Short, ok, tt.want)
+			}
+			if ok {
+				if cat, _ := classify(e, nil, src); cat != CategoryPoolMisuse {
+					t.Errorf("classify() = %s, want %s", cat, CategoryPoolMisuse)
+				}
+			}
+		})
+	}
+
+	// The flow is enough without the source
+	e := parser.EscapeInfo{File: "missing.go", Line: 18, Column: 15, Variable: "b[:0]", EscapeType: parser.EscapesToHeap, FlowInfo: putFlow}
+	if cat, _ := classify(e, nil, newSourceCache()); cat != CategoryPoolMisuse {
+		t.Errorf("classify() without source = %s, want %s", cat, CategoryPoolMisuse)
+	}
+}
@@ -0,0 +1,156 @@
+package categorizer
+
+import (
+	"go/ast"
+	"go/token"
+	"math"
+	"sort"
+
+	heapparser "github.com/harshakonda/heapcheck/internal/parser"
+	"github.com/harshakonda/heapcheck/internal/typecheck"
+)
+
+// Orders escapes can be sorted in, by SortEscapes
+const (
+	OrderImpact   = "impact"   // highest estimated fix impact first, see Score
+	OrderBytes    = "bytes"    // most estimated bytes per call first, see Impact
+	OrderPosition = "position" // by file, line and column
+)
+
+// Orders lists the orders SortEscapes accepts, the default first
+var Orders = []string{OrderImpact, OrderBytes, OrderPosition}
+
+// loopIterations is the iterations assumed for an escape in a loop whose
+// bound is unknown, as GC impact estimates assume
+const loopIterations = 10
+
+// severityWeight, hotWeight and effortCost are the factors of Score
+var (
+	severityWeight = map[Severity]float64{SeverityLow: 1, SeverityMedium: 2, SeverityHigh: 3}
+	hotWeight      = map[string]float64{BenchmarkedYes: 4, BenchmarkedNo: 1, "": 2}
+	effortCost     = map[Effort]float64{EffortTrivial: 1, EffortModerate: 2, EffortStructural: 4}
+)
+
+// Score estimates the impact of fixing e, as a product of heuristics:
+//
+//   - its severity, 1 to 3 from low to high
+//   - how often it allocates per call: the loop iterations of its GC
+//     impact, or 10 in a loop, or 1
+//   - how hot its function is: 4 when benchmarks exercise it, 1 when they
+//     do not, 2 without benchmark results
+//   - its size: 1, plus 1 for each doubling above 64 bytes, when known
+//   - how often it is called: a hundredth at package initialization or in
+//     func main, which run once, and half for leaking parameters, which
+//     only make their callers' arguments escape
+//
+// divided by its effort, 1, 2 or 4 from trivial to structural, so that the
+// highest scores are the most allocation avoided for the least work
+func Score(e CategorizedEscape) float64 {
+	severity := e.Severity
+	if severity == "" {
+		severity = SeverityOf(e.Category)
+	}
+	score := severityWeight[severity]
+
+	switch {
+	case e.Impact != nil:
+		score *= float64(e.Impact.Iterations)
+	case e.InLoop:
+		score *= loopIterations
+	}
+
+	score *= hotWeight[e.Benchmarked]
+
+	size := e.Size
+	if e.Impact != nil {
+		size = e.Impact.Size
+	}
+	if size > 64 {
+		score *= 1 + math.Log2(float64(size)/64)
+	}
+
+	switch {
+	case e.Init || e.Func == "main":
+		score /= 100
+	case e.Info.EscapeType == heapparser.LeakingParam || e.Info.EscapeType == heapparser.LeakingParamContent:
+		score /= 2
+	}
+
+	effort := e.Effort
+	if effort == "" {
+		effort = EffortOf(e.Category)
+	}
+	if cost := effortCost[effort]; cost > 0 {
+		score /= cost
+	}
+	return math.Round(score*100) / 100
+}
+
+// SortEscapes sorts escapes in order, one of Orders. Impact order sets
+// each escape's Score; bytes order puts escapes without a GC impact
+// estimate last. Escapes that compare equal keep their order.
+func SortEscapes(escapes []CategorizedEscape, order string) {
+	switch order {
+	case OrderImpact:
+		for i := range escapes {
+			escapes[i].Score = Score(escapes[i])
+		}
+		sort.SliceStable(escapes, func(i, j int) bool {
+			return escapes[i].Score > escapes[j].Score
+		})
+	case OrderBytes:
+		sort.SliceStable(escapes, func(i, j int) bool {
+			return perCall(escapes[i]) > perCall(escapes[j])
+		})
+	case OrderPosition:
+		sort.SliceStable(escapes, func(i, j int) bool {
+			a, b := escapes[i].Info, escapes[j].Info
+			if a.File != b.File {
+				return a.File < b.File
+			}
+			if a.Line != b.Line {
+				return a.Line < b.Line
+			}
+			return a.Column < b.Column
+		})
+	}
+}
+
+func perCall(e CategorizedEscape) int64 {
+	if e.Impact == nil {
+		return -1
+	}
+	return e.Impact.PerCall
+}
+
+// inLoop reports whether e is in the body of a for or range loop of the
+// function it is in, func literals inside it included
+func (c *sourceCache) inLoop(e heapparser.EscapeInfo) bool {
+	f := c.file(e.File)
+	if f == nil {
+		return false
+	}
+	p := c.pos(f, e.Line, e.Column)
+	fd := typecheck.EnclosingFunc(f, p)
+	if p == token.NoPos || fd == nil || fd.Body == nil {
+		return false
+	}
+	found := false
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		if found || n == nil || p < n.Pos() || n.End() <= p {
+			return false
+		}
+		var body *ast.BlockStmt
+		switch n := n.(type) {
+		case *ast.ForStmt:
+			body = n.Body
+		case *ast.RangeStmt:
+			body = n.Body
+		}
+		if body != nil && body.Pos() <= p && p < body.End() {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,148 @@
+package categorizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+const loopSource = `package sample
+
+func Collect(items []string) []*string {
+	var out []*string
+	for _, item := range items {
+		s := item
+		out = append(out, &s)
+	}
+	for i := 0; i < len(items); i++ {
+		go func() { _ = new(int) }()
+	}
+	return out
+}
+`
+
+func TestCategorizeInLoop(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sample.go")
+	if err := os.WriteFile(file, []byte(loopSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		line     int
+		column   int
+		variable string
+		want     bool
+	}{
+		{name: "before the loops", line: 4, column: 6, variable: "out", want: false},
+		{name: "range loop", line: 6, column: 3, variable: "s", want: true},
+		{name: "func literal in a for loop", line: 10, column: 20, variable: "new(int)", want: true},
+		{name: "range expression", line: 5, column: 23, variable: "items", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := parser.EscapeInfo{
+				Package:    "example.com/sample",
+				File:       file,
+				Line:       tt.line,
+				Column:     tt.column,
+				Variable:   tt.variable,
+				EscapeType: parser.MovedToHeap,
+				Reason:     "moved to heap: " + tt.variable,
+			}
+			if got := CategorizeWith([]parser.EscapeInfo{e}).Escapes[0].InLoop; got != tt.want {
+				t.Errorf("InLoop = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	base := CategorizedEscape{
+		Info:     parser.EscapeInfo{EscapeType: parser.EscapesToHeap},
+		Category: CategoryFmtCall,
+		Severity: SeverityMedium,
+		Effort:   EffortTrivial,
+	}
+	with := func(f func(e *CategorizedEscape)) CategorizedEscape {
+		e := base
+		f(&e)
+		return e
+	}
+
+	tests := []struct {
+		name string
+		e    CategorizedEscape
+		want float64
+	}{
+		{name: "base", e: base, want: 4},
+		{name: "high severity", e: with(func(e *CategorizedEscape) { e.Severity = SeverityHigh }), want: 6},
+		{name: "in a loop", e: with(func(e *CategorizedEscape) { e.InLoop = true }), want: 40},
+		{name: "estimated iterations", e: with(func(e *CategorizedEscape) {
+			e.InLoop = true
+			e.Impact = &Impact{Size: 16, Iterations: 3, PerCall: 48}
+		}), want: 12},
+		{name: "benchmarked", e: with(func(e *CategorizedEscape) { e.Benchmarked = BenchmarkedYes }), want: 8},
+		{name: "not benchmarked", e: with(func(e *CategorizedEscape) { e.Benchmarked = BenchmarkedNo }), want: 2},
+		{name: "large", e: with(func(e *CategorizedEscape) { e.Size = 256 }), want: 12},
+		{name: "at initialization", e: with(func(e *CategorizedEscape) { e.Init = true }), want: 0.04},
+		{name: "in main", e: with(func(e *CategorizedEscape) { e.Func = "main" }), want: 0.04},
+		{name: "leaking param", e: with(func(e *CategorizedEscape) { e.Info.EscapeType = parser.LeakingParam }), want: 2},
+		{name: "structural", e: with(func(e *CategorizedEscape) { e.Effort = EffortStructural }), want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Score(tt.e); got != tt.want {
+				t.Errorf("Score() = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortEscapes(t *testing.T) {
+	escape := func(file string, line int, cat Category, inLoop bool, impact *Impact) CategorizedEscape {
+		return CategorizedEscape{
+			Info:     parser.EscapeInfo{File: file, Line: line, EscapeType: parser.EscapesToHeap},
+			Category: cat,
+			InLoop:   inLoop,
+			Impact:   impact,
+		}
+	}
+	escapes := []CategorizedEscape{
+		escape("b.go", 3, CategoryCompositeLiteral, false, &Impact{Size: 8, Iterations: 1, PerCall: 8}),
+		escape("a.go", 9, CategoryFmtCall, true, nil),
+		escape("a.go", 2, CategoryFmtCall, false, &Impact{Size: 4096, Iterations: 1, PerCall: 4096}),
+	}
+	lines := func(escapes []CategorizedEscape) []string {
+		var got []string
+		for _, e := range escapes {
+			got = append(got, fmt.Sprintf("%s:%d", e.Info.File, e.Info.Line))
+		}
+		return got
+	}
+
+	tests := []struct {
+		order string
+		want  []string
+	}{
+		{OrderImpact, []string{"a.go:9", "a.go:2", "b.go:3"}},
+		{OrderBytes, []string{"a.go:2", "b.go:3", "a.go:9"}},
+		{OrderPosition, []string{"a.go:2", "a.go:9", "b.go:3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			sorted := append([]CategorizedEscape(nil), escapes...)
+			SortEscapes(sorted, tt.order)
+			if got := lines(sorted); !slices.Equal(got, tt.want) {
+				t.Errorf("SortEscapes(%s) = %v, want %v", tt.order, got, tt.want)
+			}
+			if scored := sorted[0].Score > 0; scored != (tt.order == OrderImpact) {
+				t.Errorf("Score set = %v with %s order", scored, tt.order)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package categorizer
+
+// Remap reports the escapes of the categories in mapping under the
+// category they map to: merged into another category, e.g. fmt-call as
+// interface-boxing, or renamed to match a team's own taxonomy. Mappings
+// are not chained, and escapes keep their suggestions, effort estimates
+// and severities.
+func Remap(results *Results, mapping map[Category]Category) {
+	if len(mapping) == 0 {
+		return
+	}
+	for i := range results.Escapes {
+		if to, ok := mapping[results.Escapes[i].Category]; ok {
+			results.Escapes[i].Category = to
+		}
+	}
+	results.ByCategory = remapCounts(results.ByCategory, mapping)
+	for i := range results.Goroutines {
+		results.Goroutines[i].ByCategory = remapCounts(results.Goroutines[i].ByCategory, mapping)
+	}
+}
+
+// remapCounts returns counts by remapped category, summing the counts of
+// categories mapped to the same one
+func remapCounts(counts map[Category]int, mapping map[Category]Category) map[Category]int {
+	if counts == nil {
+		return nil
+	}
+	remapped := make(map[Category]int, len(counts))
+	for cat, n := range counts {
+		if to, ok := mapping[cat]; ok {
+			cat = to
+		}
+		remapped[cat] += n
+	}
+	return remapped
+}
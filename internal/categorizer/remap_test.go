@@ -0,0 +1,43 @@
+package categorizer
+
+import "testing"
+
+func TestRemap(t *testing.T) {
+	results := &Results{
+		ByCategory: map[Category]int{CategoryFmtCall: 2, CategoryInterfaceBoxing: 1, CategorySliceGrow: 1},
+		Escapes: []CategorizedEscape{
+			{Category: CategoryFmtCall, Suggestion: Suggestion{Short: "Replace %d with strconv.Itoa(id)"}},
+			{Category: CategoryFmtCall},
+			{Category: CategoryInterfaceBoxing},
+			{Category: CategorySliceGrow},
+		},
+		Goroutines: []GoroutineGroup{
+			{Func: "Serve", ByCategory: map[Category]int{CategoryFmtCall: 1}},
+		},
+	}
+	Remap(results, map[Category]Category{
+		CategoryFmtCall:   CategoryInterfaceBoxing,
+		CategorySliceGrow: "alloc/slice",
+	})
+
+	want := map[Category]int{CategoryInterfaceBoxing: 3, "alloc/slice": 1}
+	if len(results.ByCategory) != len(want) {
+		t.Errorf("ByCategory = %v, want %v", results.ByCategory, want)
+	}
+	for cat, n := range want {
+		if results.ByCategory[cat] != n {
+			t.Errorf("ByCategory[%s] = %d, want %d", cat, results.ByCategory[cat], n)
+		}
+	}
+	for i, cat := range []Category{CategoryInterfaceBoxing, CategoryInterfaceBoxing, CategoryInterfaceBoxing, "alloc/slice"} {
+		if got := results.Escapes[i].Category; got != cat {
+			t.Errorf("Escapes[%d].Category = %s, want %s", i, got, cat)
+		}
+	}
+	if got := results.Escapes[0].Suggestion.Short; got != "Replace %d with strconv.Itoa(id)" {
+		t.Errorf("Suggestion = %q, want it kept", got)
+	}
+	if got := results.Goroutines[0].ByCategory[CategoryInterfaceBoxing]; got != 1 {
+		t.Errorf("Goroutines[0].ByCategory = %v, want interface-boxing: 1", results.Goroutines[0].ByCategory)
+	}
+}
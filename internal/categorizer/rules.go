@@ -0,0 +1,298 @@
+package categorizer
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// builtinRules is the ruleset categorize uses unless SetRuleset replaces
+// it; see rules.yaml for its format
+//
+//go:embed rules.yaml
+var builtinRules []byte
+
+// Ruleset is an ordered list of rules that categorize escapes by the
+// compiler's text. The first rule that matches an escape decides its
+// category; escapes no rule matches are uncategorized.
+type Ruleset struct {
+	// Version is the revision of the built-in rules, increased whenever
+	// they change; in a copy, the revision it was copied from
+	Version int `yaml:"version"`
+
+	// Inherit has the built-in rules tried after these, so that a file
+	// need only hold additions and fixes. Unset is true; false replaces
+	// the built-in rules, pinning a copy of them.
+	Inherit *bool `yaml:"inherit"`
+
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule assigns its category to the escapes that meet all of its
+// conditions. Text conditions match lowercase text.
+type Rule struct {
+	Category Category `yaml:"category"`
+
+	// EscapeType limits the rule to escapes with one of these verdicts of
+	// the compiler, named as parser.EscapeType names them
+	EscapeType stringList `yaml:"escapeType"`
+
+	Reason   Matcher `yaml:"reason"`   // the compiler's message
+	Flow     Matcher `yaml:"flow"`     // the flow lines, joined by spaces
+	Text     Matcher `yaml:"text"`     // the reason and the flow, joined by a space
+	Variable Matcher `yaml:"variable"` // the escaping expression
+
+	// Check names a test that text cannot express, one of ruleChecks
+	Check string `yaml:"check"`
+
+	escapeTypes []parser.EscapeType
+}
+
+// Matcher tests a text. An empty Matcher matches any text.
+type Matcher struct {
+	Contains stringList `yaml:"contains"` // any of these
+	All      stringList `yaml:"all"`      // every one of these
+	Not      stringList `yaml:"not"`      // none of these
+	Match    string     `yaml:"match"`    // a regular expression
+
+	re *regexp.Regexp
+}
+
+// stringList is a list of strings that YAML can also give as one string
+type stringList []string
+
+// UnmarshalYAML accepts a string for a list of one
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var s string
+		if err := node.Decode(&s); err != nil {
+			return err
+		}
+		*l = stringList{s}
+		return nil
+	}
+	return node.Decode((*[]string)(l))
+}
+
+// ruleInput is an escape with the lowercase texts rules match
+type ruleInput struct {
+	e        parser.EscapeInfo
+	reason   string
+	flow     string
+	text     string
+	variable string
+}
+
+// ruleChecks are the tests rules can name as their Check
+var ruleChecks = []string{"variadic-call", "stored-closure"}
+
+// check runs the test named by a rule's Check
+func (in *ruleInput) check(name string) bool {
+	switch name {
+	case "variadic-call":
+		// An argument boxed into a ...interface{} parameter or
+		// []interface{} literal, or the argument slice itself
+		_, ok := variadicCall(in.e)
+		return ok
+	case "stored-closure":
+		// A func literal stored in a struct field
+		return isStoredClosure(in.variable, in.flow)
+	}
+	return false
+}
+
+var (
+	builtinRuleset = mustParseRuleset(builtinRules)
+	activeRuleset  atomic.Pointer[Ruleset]
+)
+
+func mustParseRuleset(data []byte) *Ruleset {
+	rs, err := ParseRuleset(data)
+	if err != nil {
+		panic("categorizer: built-in rules: " + err.Error())
+	}
+	return rs
+}
+
+// BuiltinRules returns the built-in ruleset as YAML, for a copy to pin
+func BuiltinRules() []byte {
+	return bytes.Clone(builtinRules)
+}
+
+// BuiltinRuleset returns the built-in ruleset
+func BuiltinRuleset() *Ruleset {
+	return builtinRuleset
+}
+
+// SetRuleset has Categorize use rs instead of the built-in rules, after
+// the registered categorizers and the checks that read the source, as
+// before. A nil rs restores the built-in rules.
+func SetRuleset(rs *Ruleset) {
+	activeRuleset.Store(rs)
+}
+
+// currentRuleset returns the ruleset SetRuleset set, or the built-in one
+func currentRuleset() *Ruleset {
+	if rs := activeRuleset.Load(); rs != nil {
+		return rs
+	}
+	return builtinRuleset
+}
+
+// ParseRuleset parses and validates a ruleset. Unknown fields are errors,
+// so that a misspelled condition does not match every escape.
+func ParseRuleset(data []byte) (*Ruleset, error) {
+	var rs Ruleset
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&rs); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	for i := range rs.Rules {
+		if err := rs.Rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return &rs, nil
+}
+
+// LoadRuleset reads a ruleset file. Unless the file sets inherit: false,
+// the built-in rules follow its own.
+func LoadRuleset(path string) (*Ruleset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading ruleset: %w", err)
+	}
+	rs, err := ParseRuleset(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ruleset %s: %w", path, err)
+	}
+	if rs.Inherits() {
+		rs.Rules = append(rs.Rules, builtinRuleset.Rules...)
+	}
+	return rs, nil
+}
+
+// Inherits reports whether the built-in rules follow the ruleset's own
+func (rs *Ruleset) Inherits() bool {
+	return rs.Inherit == nil || *rs.Inherit
+}
+
+// Categorize returns the category of the first rule e meets, or
+// CategoryUncategorized
+func (rs *Ruleset) Categorize(e parser.EscapeInfo) Category {
+	flow := strings.ToLower(strings.Join(e.FlowInfo, " "))
+	reason := strings.ToLower(e.Reason)
+	in := ruleInput{
+		e:        e,
+		reason:   reason,
+		flow:     flow,
+		text:     reason + " " + flow,
+		variable: strings.ToLower(e.Variable),
+	}
+	for i := range rs.Rules {
+		if rs.Rules[i].matches(&in) {
+			return rs.Rules[i].Category
+		}
+	}
+	return CategoryUncategorized
+}
+
+// compile validates r and prepares its conditions
+func (r *Rule) compile() error {
+	if r.Category == "" {
+		return fmt.Errorf("no category")
+	}
+	r.escapeTypes = r.escapeTypes[:0]
+	for _, name := range r.EscapeType {
+		t, err := parser.ParseEscapeType(name)
+		if err != nil {
+			return err
+		}
+		r.escapeTypes = append(r.escapeTypes, t)
+	}
+	if r.Check != "" && !slices.Contains(ruleChecks, r.Check) {
+		return fmt.Errorf("unknown check %q", r.Check)
+	}
+	empty := len(r.escapeTypes) == 0 && r.Check == ""
+	for _, m := range []*Matcher{&r.Reason, &r.Flow, &r.Text, &r.Variable} {
+		if err := m.compile(); err != nil {
+			return err
+		}
+		empty = empty && m.empty()
+	}
+	if empty {
+		return fmt.Errorf("%s: no conditions, so it would match every escape", r.Category)
+	}
+	return nil
+}
+
+func (r *Rule) matches(in *ruleInput) bool {
+	if len(r.escapeTypes) > 0 && !slices.Contains(r.escapeTypes, in.e.EscapeType) {
+		return false
+	}
+	if !r.Reason.matches(in.reason) || !r.Flow.matches(in.flow) ||
+		!r.Text.matches(in.text) || !r.Variable.matches(in.variable) {
+		return false
+	}
+	return r.Check == "" || in.check(r.Check)
+}
+
+// compile lowercases the strings of m, as the texts it matches are, and
+// compiles its regular expression
+func (m *Matcher) compile() error {
+	for _, l := range []stringList{m.Contains, m.All, m.Not} {
+		for i, s := range l {
+			l[i] = strings.ToLower(s)
+		}
+	}
+	if m.Match != "" {
+		re, err := regexp.Compile(m.Match)
+		if err != nil {
+			return err
+		}
+		m.re = re
+	}
+	return nil
+}
+
+func (m *Matcher) empty() bool {
+	return len(m.Contains) == 0 && len(m.All) == 0 && len(m.Not) == 0 && m.Match == ""
+}
+
+func (m *Matcher) matches(s string) bool {
+	if len(m.Contains) > 0 {
+		found := false
+		for _, sub := range m.Contains {
+			if strings.Contains(s, sub) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, sub := range m.All {
+		if !strings.Contains(s, sub) {
+			return false
+		}
+	}
+	for _, sub := range m.Not {
+		if strings.Contains(s, sub) {
+			return false
+		}
+	}
+	return m.re == nil || m.re.MatchString(s)
+}
@@ -0,0 +1,139 @@
+package categorizer
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func TestBuiltinRuleset(t *testing.T) {
+	rs := BuiltinRuleset()
+	if rs.Version < 1 || rs.Inherits() {
+		t.Errorf("built-in ruleset version %d, inherits %v; want a version and inherit: false", rs.Version, rs.Inherits())
+	}
+	for i, r := range rs.Rules {
+		if !slices.Contains(categories, r.Category) {
+			t.Errorf("rule %d: unknown category %q", i+1, r.Category)
+		}
+	}
+}
+
+func TestLoadRuleset(t *testing.T) {
+	boxed := parser.EscapeInfo{
+		EscapeType: parser.EscapesToHeap,
+		Variable:   "c",
+		Reason:     "c escapes to heap",
+		FlowInfo:   []string{"flow: interface-converted", "from gin.Context.Set(c) (call parameter)"},
+	}
+	literal := parser.EscapeInfo{
+		EscapeType: parser.EscapesToHeap,
+		Variable:   "&Config{}",
+		Reason:     "&Config{} escapes to heap",
+	}
+
+	tests := []struct {
+		name        string
+		file        string
+		wantBoxed   Category
+		wantLiteral Category
+	}{
+		{
+			name: "added rule first",
+			file: `
+rules:
+  - category: gin-context
+    flow: {contains: GIN.Context}
+`,
+			wantBoxed:   "gin-context",
+			wantLiteral: CategoryCompositeLiteral,
+		},
+		{
+			name: "pinned",
+			file: `
+version: 1
+inherit: false
+rules:
+  - category: interface-boxing
+    text: {all: [interface, call parameter]}
+`,
+			wantBoxed:   CategoryInterfaceBoxing,
+			wantLiteral: CategoryUncategorized,
+		},
+		{
+			name: "escape type and regular expression",
+			file: `
+inherit: false
+rules:
+  - category: config-literal
+    escapeType: [escapes-to-heap]
+    variable: {match: '^&config\{'}
+    flow: {not: interface}
+`,
+			wantBoxed:   CategoryUncategorized,
+			wantLiteral: "config-literal",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.yaml")
+			if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			rs, err := LoadRuleset(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rs.Categorize(boxed); got != tt.wantBoxed {
+				t.Errorf("boxed value = %s, want %s", got, tt.wantBoxed)
+			}
+			if got := rs.Categorize(literal); got != tt.wantLiteral {
+				t.Errorf("literal = %s, want %s", got, tt.wantLiteral)
+			}
+		})
+	}
+}
+
+func TestParseRulesetErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{"unknown field", "rules:\n  - category: x\n    reasons: {contains: y}\n", "field reasons not found"},
+		{"no conditions", "rules:\n  - category: x\n", "no conditions"},
+		{"no category", "rules:\n  - text: {contains: y}\n", "no category"},
+		{"escape type", "rules:\n  - category: x\n    escapeType: on-heap\n", `unknown escape type "on-heap"`},
+		{"check", "rules:\n  - category: x\n    check: generic\n", `unknown check "generic"`},
+		{"regular expression", "rules:\n  - category: x\n    reason: {match: '('}\n", "missing closing )"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRuleset([]byte(tt.file))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseRuleset() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetRuleset(t *testing.T) {
+	defer SetRuleset(nil)
+	rs, err := ParseRuleset([]byte("inherit: false\nrules:\n  - category: heap\n    escapeType: escapes-to-heap\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := parser.EscapeInfo{File: "main.go", Line: 1, Variable: "x", EscapeType: parser.EscapesToHeap, Reason: "x escapes to heap", FlowInfo: []string{"flow: interface-converted"}}
+
+	SetRuleset(rs)
+	if got := CategorizeWith([]parser.EscapeInfo{e}).Escapes[0].Category; got != "heap" {
+		t.Errorf("with the ruleset, category = %s, want heap", got)
+	}
+	SetRuleset(nil)
+	if got := CategorizeWith([]parser.EscapeInfo{e}).Escapes[0].Category; got != CategoryInterfaceBoxing {
+		t.Errorf("with the built-in rules restored, category = %s, want %s", got, CategoryInterfaceBoxing)
+	}
+}
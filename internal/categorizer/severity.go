@@ -0,0 +1,89 @@
+package categorizer
+
+import heapparser "github.com/harshakonda/heapcheck/internal/parser"
+
+// Severity ranks how much an escape matters to the users of the code it
+// is in, for the level it is reported at
+type Severity string
+
+const (
+	SeverityLow    Severity = "low"    // inherent to the construct, or in code only its own module calls
+	SeverityMedium Severity = "medium" // a pattern with a known fix
+	SeverityHigh   Severity = "high"   // in an exported API, paid for by every consumer
+)
+
+// severities lists the severities in increasing order
+var severities = []Severity{SeverityLow, SeverityMedium, SeverityHigh}
+
+// lowSeverity are the categories whose escapes are inherent to the
+// construct or need the flow details to act on, rather than a pattern
+// with a known fix. Categories left out are SeverityMedium.
+var lowSeverity = map[Category]bool{
+	CategoryTooLarge:         true,
+	CategoryLeakingParam:     true,
+	CategoryLeakingContent:   true,
+	CategorySpill:            true,
+	CategoryAssignment:       true,
+	CategoryCallParameter:    true,
+	CategoryMapAllocation:    true,
+	CategoryUncategorized:    true,
+	CategoryCompositeLiteral: true,
+	CategoryCompilerForced:   true,
+}
+
+// Severities returns the severities, in increasing order
+func Severities() []Severity {
+	return append([]Severity(nil), severities...)
+}
+
+// SeverityOf returns the usual severity of an escape of a category.
+// Categories that are not built in are SeverityMedium.
+func SeverityOf(cat Category) Severity {
+	if lowSeverity[cat] {
+		return SeverityLow
+	}
+	return SeverityMedium
+}
+
+// CountBySeverity counts escapes by their severity, falling back to the
+// severity of their category. Severities without escapes are left out.
+func CountBySeverity(escapes []CategorizedEscape) map[Severity]int {
+	bySeverity := make(map[Severity]int)
+	for _, e := range escapes {
+		severity := e.Severity
+		if severity == "" {
+			severity = SeverityOf(e.Category)
+		}
+		bySeverity[severity]++
+	}
+	return bySeverity
+}
+
+// severity rates e of category cat: the severity of the category, one
+// level up in an exported function of an importable package, whose
+// allocations every consumer pays for, and one level down in an internal
+// or main package, which only its own module calls. Escapes at package
+// initialization, which allocate once, are low.
+func (c *sourceCache) severity(e heapparser.EscapeInfo, cat Category, init bool) Severity {
+	severity := SeverityOf(cat)
+	switch {
+	case init:
+		return SeverityLow
+	case isInternal(e.PackageOrDir()) || c.isMain(e):
+		return shiftSeverity(severity, -1)
+	case c.inExportedAPI(e):
+		return shiftSeverity(severity, 1)
+	}
+	return severity
+}
+
+// shiftSeverity moves s by delta levels, within the known severities
+func shiftSeverity(s Severity, delta int) Severity {
+	for i, known := range severities {
+		if known == s {
+			i = max(0, min(len(severities)-1, i+delta))
+			return severities[i]
+		}
+	}
+	return s
+}
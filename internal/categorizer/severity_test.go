@@ -0,0 +1,70 @@
+package categorizer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func TestSeverity(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "sample.go")
+	internal := filepath.Join(dir, "internal", "sample.go")
+	cmd := filepath.Join(dir, "cmd", "main.go")
+	sources := map[string]string{
+		file:     effortSource,
+		internal: effortSource,
+		cmd:      strings.Replace(effortSource, "package sample", "package main", 1),
+	}
+	for path, src := range sources {
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		file string
+		pkg  string
+		line int
+		cat  Category
+		want Severity
+	}{
+		{name: "exported function", file: file, pkg: "example.com/sample", line: 8, cat: CategoryReturnPointer, want: SeverityHigh},
+		{name: "unexported function", file: file, pkg: "example.com/sample", line: 13, cat: CategoryReturnPointer, want: SeverityMedium},
+		{name: "method of exported type", file: file, pkg: "example.com/sample", line: 18, cat: CategoryReturnPointer, want: SeverityHigh},
+		{name: "method of unexported type", file: file, pkg: "example.com/sample", line: 23, cat: CategoryReturnPointer, want: SeverityMedium},
+		{name: "low category in exported function", file: file, pkg: "example.com/sample", line: 8, cat: CategorySpill, want: SeverityMedium},
+		{name: "internal package", file: internal, pkg: "example.com/internal/sample", line: 8, cat: CategoryReturnPointer, want: SeverityLow},
+		{name: "low category in internal package", file: internal, pkg: "example.com/internal/sample", line: 8, cat: CategorySpill, want: SeverityLow},
+		{name: "main package", file: cmd, pkg: "example.com/cmd", line: 8, cat: CategoryReturnPointer, want: SeverityLow},
+		{name: "source unavailable", file: filepath.Join(dir, "missing.go"), pkg: "example.com/sample", line: 8, cat: CategoryReturnPointer, want: SeverityMedium},
+		{name: "custom category", file: file, pkg: "example.com/sample", line: 8, cat: "db-row", want: SeverityHigh},
+	}
+	src := newSourceCache()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := parser.EscapeInfo{Package: tt.pkg, File: tt.file, Line: tt.line, Column: 2, Variable: "c"}
+			if got := src.severity(e, tt.cat, false); got != tt.want {
+				t.Errorf("severity() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountBySeverity(t *testing.T) {
+	escapes := []CategorizedEscape{
+		{Category: CategoryFmtCall, Severity: SeverityHigh},
+		{Category: CategorySpill, Severity: SeverityMedium},
+		{Category: CategoryReflection},
+		{Category: CategoryAssignment},
+	}
+	got := CountBySeverity(escapes)
+	if got[SeverityLow] != 1 || got[SeverityMedium] != 2 || got[SeverityHigh] != 1 {
+		t.Errorf("CountBySeverity() = %v, want low 1, medium 2 and high 1", got)
+	}
+}
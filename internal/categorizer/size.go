@@ -0,0 +1,91 @@
+package categorizer
+
+import (
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// sizes are the type sizes of the gc compiler for the target architecture
+var sizes = types.SizesFor("gc", build.Default.GOARCH)
+
+// objectSize returns the size in bytes of the object an escaping
+// expression allocates, when the compiler message spells it out with
+// constant lengths of predeclared types, as in "make([]byte, 1048576)",
+// "new([4096]int64)" or "&[65536]byte{...}". It returns 0 otherwise,
+// e.g. for variables or named types, which need type information.
+func objectSize(expr string) int64 {
+	// The compiler elides the elements of composite literals
+	expr = strings.Replace(expr, "{...}", "{}", 1)
+	x, err := parser.ParseExpr(expr)
+	if err != nil {
+		return 0
+	}
+	if u, ok := x.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		x = u.X
+	}
+
+	switch x := x.(type) {
+	case *ast.CompositeLit:
+		return typeSize(x.Type)
+	case *ast.CallExpr:
+		fn, ok := x.Fun.(*ast.Ident)
+		if !ok || len(x.Args) == 0 {
+			return 0
+		}
+		switch {
+		case fn.Name == "new" && len(x.Args) == 1:
+			return typeSize(x.Args[0])
+		case fn.Name == "make" && len(x.Args) >= 2:
+			slice, ok := x.Args[0].(*ast.ArrayType)
+			if !ok || slice.Len != nil {
+				return 0
+			}
+			// The backing array has the capacity, if given
+			n := constInt(x.Args[len(x.Args)-1])
+			return n * typeSize(slice.Elt)
+		}
+	}
+	return 0
+}
+
+// typeSize returns the size of a type expression built from predeclared
+// types, or 0 if it is not one
+func typeSize(t ast.Expr) int64 {
+	ptr := sizes.Sizeof(types.Typ[types.UnsafePointer])
+	switch t := t.(type) {
+	case *ast.ParenExpr:
+		return typeSize(t.X)
+	case *ast.Ident:
+		if obj, ok := types.Universe.Lookup(t.Name).(*types.TypeName); ok {
+			return sizes.Sizeof(obj.Type())
+		}
+	case *ast.StarExpr, *ast.MapType, *ast.ChanType, *ast.FuncType:
+		return ptr
+	case *ast.InterfaceType:
+		return 2 * ptr
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return 3 * ptr // slice header
+		}
+		return constInt(t.Len) * typeSize(t.Elt)
+	}
+	return 0
+}
+
+// constInt returns the value of an integer literal, or 0
+func constInt(x ast.Expr) int64 {
+	lit, ok := x.(*ast.BasicLit)
+	if !ok || lit.Kind != token.INT {
+		return 0
+	}
+	n, err := strconv.ParseInt(lit.Value, 0, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,65 @@
+package categorizer
+
+import (
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func TestObjectSize(t *testing.T) {
+	tests := []struct {
+		expr string
+		want int64
+	}{
+		{"make([]byte, 1048576)", 1 << 20},
+		{"make([]int64, 10, 4096)", 8 * 4096},
+		{"make([]string, 1000)", 16 * 1000},
+		{"new([4096]int64)", 8 * 4096},
+		{"&[65536]byte{...}", 1 << 16},
+		{"[2][1024]uint32{}", 2 * 1024 * 4},
+		{"make([]*Node, 100)", 8 * 100},
+		{"make([]Node, 100)", 0},
+		{"make([]byte, n)", 0},
+		{"buf", 0},
+		{"make(map[string]int, 100)", 0},
+	}
+	for _, tt := range tests {
+		if got := objectSize(tt.expr); got != tt.want {
+			t.Errorf("objectSize(%q) = %d, want %d", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCategorizeTooLargeBytes(t *testing.T) {
+	tooLarge := func(pkg, variable string) parser.EscapeInfo {
+		return parser.EscapeInfo{
+			Package:    pkg,
+			File:       "./a.go",
+			Variable:   variable,
+			EscapeType: parser.EscapesToHeap,
+			FlowInfo:   []string{"from " + variable + " (too large for stack) at ./a.go:8:11"},
+		}
+	}
+	results := Categorize([]parser.EscapeInfo{
+		tooLarge("example.com/app", "make([]byte, 1048576)"),
+		tooLarge("example.com/app", "make([]byte, 65536)"),
+		tooLarge("example.com/app", "b"),
+		tooLarge("example.com/lib", "new([1024]int64)"),
+	})
+
+	if results.ByCategory[CategoryTooLarge] != 4 {
+		t.Fatalf("ByCategory = %v, want 4 too-large escapes", results.ByCategory)
+	}
+	if got := results.Escapes[0].Size; got != 1<<20 {
+		t.Errorf("Size = %d, want %d", got, 1<<20)
+	}
+	if got := results.Escapes[2].Size; got != 0 {
+		t.Errorf("Size of a variable = %d, want 0 (not in the message)", got)
+	}
+	want := map[string]int64{"example.com/app": 1<<20 + 1<<16, "example.com/lib": 8 * 1024}
+	for pkg, n := range want {
+		if got := results.Summary.TooLargeBytes[pkg]; got != n {
+			t.Errorf("TooLargeBytes[%s] = %d, want %d", pkg, got, n)
+		}
+	}
+}
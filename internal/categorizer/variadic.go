@@ -0,0 +1,132 @@
+package categorizer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// VariadicCall is the call that boxed an argument into the slice of a
+// variadic ...interface{} parameter, as in fmt.Println(a, b), or into an
+// []interface{} literal
+type VariadicCall struct {
+	Position string `json:"position"`       // the call or literal, file:line:column
+	Func     string `json:"func,omitempty"` // the function making the call
+	Args     int    `json:"args"`           // escaping arguments boxed there
+}
+
+// String describes the call, e.g. "3 boxed argument(s) at ./main.go:22:13 in main"
+func (c VariadicCall) String() string {
+	s := fmt.Sprintf("%d boxed argument(s) at %s", c.Args, c.Position)
+	if c.Func != "" {
+		s += " in " + c.Func
+	}
+	return s
+}
+
+// inFuncRe matches the function named at the end of an -m=2 escape line,
+// e.g. "x escapes to heap in main:"
+var inFuncRe = regexp.MustCompile(` in (\S+):$`)
+
+// isArgumentSlice reports whether expr is the slice a call's variadic
+// arguments are collected in, or an []interface{} literal
+func isArgumentSlice(expr string) bool {
+	return expr == "... argument" || strings.HasPrefix(expr, "[]interface {}{") || strings.HasPrefix(expr, "[]any{")
+}
+
+// variadicCall returns the position of the call or literal whose
+// argument slice e is, or is boxed into
+func variadicCall(e parser.EscapeInfo) (string, bool) {
+	if isArgumentSlice(e.Variable) {
+		return fmt.Sprintf("%s:%d:%d", e.File, e.Line, e.Column), true
+	}
+	// Pointers such as &T{} passed to ...*T are not boxed
+	if strings.HasPrefix(e.Variable, "&") {
+		return "", false
+	}
+	for _, f := range e.Flows {
+		for _, step := range f.Steps {
+			if step.Reason == "slice-literal-element" && isArgumentSlice(step.Expr) {
+				return fmt.Sprintf("%s:%d:%d", step.File, step.Line, step.Column), true
+			}
+		}
+	}
+	return "", false
+}
+
+// correlateVariadic attributes boxed variadic arguments to their call,
+// with the number of arguments boxed there. The compiler reports each
+// escape twice with -m=2, with and without its flow; the copies without
+// a flow are dropped for these, as they cannot be told apart from other
+// escapes and would otherwise be counted again, uncategorized.
+func correlateVariadic(results *Results) {
+	calls := make(map[string]*VariadicCall)
+	args := make(map[string]map[string]bool)         // call -> argument positions
+	correlated := make(map[string]parser.EscapeType) // escapes with flows, by position
+	for i := range results.Escapes {
+		e := &results.Escapes[i]
+		call, ok := variadicCall(e.Info)
+		if !ok {
+			continue
+		}
+		c := calls[call]
+		if c == nil {
+			c = &VariadicCall{Position: call}
+			calls[call] = c
+			args[call] = make(map[string]bool)
+		}
+		if m := inFuncRe.FindStringSubmatch(e.Info.Reason); m != nil && c.Func == "" {
+			c.Func = m[1]
+		}
+		pos := positionKey(e.Info)
+		if !isArgumentSlice(e.Info.Variable) {
+			args[call][pos] = true
+		}
+		if len(e.Info.Flows) > 0 {
+			correlated[pos] = e.Info.EscapeType
+		}
+		e.Call = c
+	}
+	if len(calls) == 0 {
+		return
+	}
+	for call, c := range calls {
+		c.Args = len(args[call])
+	}
+
+	kept := results.Escapes[:0]
+	for _, e := range results.Escapes {
+		if t, ok := correlated[positionKey(e.Info)]; ok && len(e.Info.Flows) == 0 && t == e.Info.EscapeType {
+			results.Summary.TotalVariables--
+			results.Summary.HeapAllocated--
+			decrement(results.Summary.ByFile, e.Info.File)
+			decrement(results.Summary.ByEscapeType, e.Info.EscapeType.String())
+			decrementCategory(results.ByCategory, e.Category)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	results.Escapes = kept
+}
+
+func positionKey(e parser.EscapeInfo) string {
+	return fmt.Sprintf("%s:%d:%d", e.File, e.Line, e.Column)
+}
+
+// decrement lowers a count, deleting it at zero; a nil map is left alone
+func decrement(counts map[string]int, key string) {
+	if counts == nil {
+		return
+	}
+	if counts[key]--; counts[key] <= 0 {
+		delete(counts, key)
+	}
+}
+
+func decrementCategory(counts map[Category]int, cat Category) {
+	if counts[cat]--; counts[cat] <= 0 {
+		delete(counts, cat)
+	}
+}
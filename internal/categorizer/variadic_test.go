@@ -0,0 +1,118 @@
+package categorizer
+
+import (
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// variadicOutput is the -m=2 output of fmt.Println(x, y, t) on line 22
+// and store(x, y) on line 25, where store keeps its ...any argument
+const variadicOutput = `./main.go:22:14: 1 escapes to heap in main:
+./main.go:22:14:   flow: {storage for ... argument} ← &{storage for 1}:
+./main.go:22:14:     from 1 (spill) at ./main.go:22:14
+./main.go:22:14:     from ... argument (slice-literal-element) at ./main.go:22:13
+./main.go:22:14:   flow: fmt.a ← &{storage for ... argument}:
+./main.go:22:14:     from ... argument (spill) at ./main.go:22:13
+./main.go:22:14:     from fmt.a := ... argument (assign-pair) at ./main.go:22:13
+./main.go:22:14:   flow: {heap} ← *fmt.a:
+./main.go:22:14:     from fmt.Fprintln(os.Stdout, fmt.a...) (call parameter) at ./main.go:22:13
+./main.go:22:17: "s" escapes to heap in main:
+./main.go:22:17:   flow: {storage for ... argument} ← &{storage for "s"}:
+./main.go:22:17:     from "s" (spill) at ./main.go:22:17
+./main.go:22:17:     from ... argument (slice-literal-element) at ./main.go:22:13
+./main.go:22:17:   flow: fmt.a ← &{storage for ... argument}:
+./main.go:22:17:     from ... argument (spill) at ./main.go:22:13
+./main.go:22:17:     from fmt.a := ... argument (assign-pair) at ./main.go:22:13
+./main.go:22:17:   flow: {heap} ← *fmt.a:
+./main.go:22:17:     from fmt.Fprintln(os.Stdout, fmt.a...) (call parameter) at ./main.go:22:13
+./main.go:22:20: t escapes to heap in main:
+./main.go:22:20:   flow: {storage for ... argument} ← &{storage for t}:
+./main.go:22:20:     from t (spill) at ./main.go:22:20
+./main.go:22:20:     from ... argument (slice-literal-element) at ./main.go:22:13
+./main.go:22:20:   flow: fmt.a ← &{storage for ... argument}:
+./main.go:22:20:     from ... argument (spill) at ./main.go:22:13
+./main.go:22:20:     from fmt.a := ... argument (assign-pair) at ./main.go:22:13
+./main.go:22:20:   flow: {heap} ← *fmt.a:
+./main.go:22:20:     from fmt.Fprintln(os.Stdout, fmt.a...) (call parameter) at ./main.go:22:13
+./main.go:25:7: ... argument escapes to heap in main:
+./main.go:25:7:   flow: vals ← &{storage for ... argument}:
+./main.go:25:7:     from ... argument (spill) at ./main.go:25:7
+./main.go:25:7:     from vals := ... argument (assign-pair) at ./main.go:25:7
+./main.go:25:7:   flow: {heap} ← vals:
+./main.go:25:7:     from global = vals (assign) at ./main.go:25:7
+./main.go:25:8: 1 escapes to heap in main:
+./main.go:25:8:   flow: {storage for ... argument} ← &{storage for 1}:
+./main.go:25:8:     from 1 (spill) at ./main.go:25:8
+./main.go:25:8:     from ... argument (slice-literal-element) at ./main.go:25:7
+./main.go:25:11: "s" escapes to heap in main:
+./main.go:25:11:   flow: {storage for ... argument} ← &{storage for "s"}:
+./main.go:25:11:     from "s" (spill) at ./main.go:25:11
+./main.go:25:11:     from ... argument (slice-literal-element) at ./main.go:25:7
+./main.go:22:13: ... argument does not escape
+./main.go:22:14: 1 escapes to heap
+./main.go:22:17: "s" escapes to heap
+./main.go:22:20: t escapes to heap
+./main.go:25:7: ... argument escapes to heap
+./main.go:25:8: 1 escapes to heap
+./main.go:25:11: "s" escapes to heap
+./main.go:30:9: x escapes to heap
+`
+
+func TestCorrelateVariadic(t *testing.T) {
+	escapes, err := parser.Parse(variadicOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := Categorize(escapes)
+
+	want := map[string]struct {
+		call string
+		args int
+		cat  Category
+	}{
+		"./main.go:22:14": {"./main.go:22:13", 3, CategoryFmtCall},
+		"./main.go:22:17": {"./main.go:22:13", 3, CategoryFmtCall},
+		"./main.go:22:20": {"./main.go:22:13", 3, CategoryFmtCall},
+		"./main.go:25:7":  {"./main.go:25:7", 2, CategoryInterfaceBoxing},
+		"./main.go:25:8":  {"./main.go:25:7", 2, CategoryInterfaceBoxing},
+		"./main.go:25:11": {"./main.go:25:7", 2, CategoryInterfaceBoxing},
+	}
+	if len(results.Escapes) != len(want)+1 {
+		t.Fatalf("got %d escapes, want %d", len(results.Escapes), len(want)+1)
+	}
+	for _, e := range results.Escapes {
+		pos := positionKey(e.Info)
+		w, ok := want[pos]
+		if !ok {
+			if e.Call != nil {
+				t.Errorf("%s: Call = %v, want none", pos, e.Call)
+			}
+			continue
+		}
+		if e.Call == nil {
+			t.Errorf("%s: no Call, want %s", pos, w.call)
+			continue
+		}
+		if e.Call.Position != w.call || e.Call.Args != w.args || e.Call.Func != "main" {
+			t.Errorf("%s: Call = %+v, want %s with %d args in main", pos, *e.Call, w.call, w.args)
+		}
+		if e.Category != w.cat {
+			t.Errorf("%s: Category = %v, want %v", pos, e.Category, w.cat)
+		}
+	}
+	if results.Summary.HeapAllocated != 7 {
+		t.Errorf("HeapAllocated = %d, want 7", results.Summary.HeapAllocated)
+	}
+	// Only the unrelated escape on line 30 is left uncategorized
+	if results.ByCategory[CategoryUncategorized] != 1 {
+		t.Errorf("ByCategory[uncategorized] = %d, want 1", results.ByCategory[CategoryUncategorized])
+	}
+}
+
+func TestVariadicCallString(t *testing.T) {
+	c := VariadicCall{Position: "./main.go:22:13", Func: "main", Args: 3}
+	if got, want := c.String(), "3 boxed argument(s) at ./main.go:22:13 in main"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
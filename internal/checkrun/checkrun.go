@@ -0,0 +1,254 @@
+// Package checkrun reports escapes as a GitHub Check Run, with an inline
+// annotation on the pull request for each escape. Unlike SARIF uploads,
+// check runs need no Code Scanning license, only a token allowed to
+// write checks.
+//
+// The repository, head commit and API URL come from the environment of
+// a GitHub Actions job.
+package checkrun
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// Environment of a GitHub Actions job
+const (
+	TokenEnv      = "GITHUB_TOKEN"
+	RepositoryEnv = "GITHUB_REPOSITORY" // owner/name
+	APIURLEnv     = "GITHUB_API_URL"
+	SHAEnv        = "GITHUB_SHA"
+	EventPathEnv  = "GITHUB_EVENT_PATH"
+	WorkspaceEnv  = "GITHUB_WORKSPACE"
+)
+
+// Name is the name of the check run
+const Name = "heapcheck"
+
+// maxAnnotations is how many annotations the API accepts per request;
+// more are added by updating the run
+const maxAnnotations = 50
+
+// Client creates check runs in one repository
+type Client struct {
+	API    string // e.g. https://api.github.com
+	Repo   string // owner/name
+	Token  string
+	Client *http.Client
+}
+
+// FromEnv returns a client for the repository of the job, authenticated
+// with $GITHUB_TOKEN. ok is false when the token is not set.
+func FromEnv() (c *Client, ok bool, err error) {
+	token := os.Getenv(TokenEnv)
+	if token == "" {
+		return nil, false, nil
+	}
+	repo := os.Getenv(RepositoryEnv)
+	if owner, name, found := strings.Cut(repo, "/"); !found || owner == "" || name == "" {
+		return nil, true, fmt.Errorf("$%s = %q, want owner/name", RepositoryEnv, repo)
+	}
+	api := os.Getenv(APIURLEnv)
+	if api == "" {
+		api = "https://api.github.com"
+	}
+	return &Client{
+		API:    strings.TrimSuffix(api, "/"),
+		Repo:   repo,
+		Token:  token,
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}, true, nil
+}
+
+// HeadSHA returns the commit to attach the check run to: the head of the
+// pull request that triggered the job, or else $GITHUB_SHA. On pull
+// request events $GITHUB_SHA is a merge commit, whose checks the pull
+// request does not show.
+func HeadSHA() (string, error) {
+	if path := os.Getenv(EventPathEnv); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("reading the event payload: %w", err)
+		}
+		var event struct {
+			PullRequest struct {
+				Head struct {
+					SHA string `json:"sha"`
+				} `json:"head"`
+			} `json:"pull_request"`
+		}
+		if err := json.Unmarshal(data, &event); err != nil {
+			return "", fmt.Errorf("reading the event payload: %w", err)
+		}
+		if sha := event.PullRequest.Head.SHA; sha != "" {
+			return sha, nil
+		}
+	}
+	if sha := os.Getenv(SHAEnv); sha != "" {
+		return sha, nil
+	}
+	return "", fmt.Errorf("no commit to attach the check run to ($%s is not set)", SHAEnv)
+}
+
+// Run is a completed check run
+type Run struct {
+	HeadSHA     string
+	Conclusion  string // success, neutral or failure
+	Title       string
+	Summary     string // Markdown
+	Annotations []Annotation
+}
+
+// Annotation marks an escape in a file of the repository
+type Annotation struct {
+	Path        string `json:"path"` // relative to the repository root
+	StartLine   int    `json:"start_line"`
+	EndLine     int    `json:"end_line"`
+	StartColumn int    `json:"start_column,omitempty"`
+	EndColumn   int    `json:"end_column,omitempty"`
+	Level       string `json:"annotation_level"` // notice, warning or failure
+	Title       string `json:"title,omitempty"`
+	Message     string `json:"message"`
+	RawDetails  string `json:"raw_details,omitempty"`
+}
+
+// annotationLevels are the annotation levels of the severities; escapes
+// without one are warnings
+var annotationLevels = map[categorizer.Severity]string{
+	categorizer.SeverityLow:    "notice",
+	categorizer.SeverityMedium: "warning",
+	categorizer.SeverityHigh:   "failure",
+}
+
+// Annotations returns an annotation for each escape in a file under
+// root. Relative file paths, as the compiler prints them, are resolved
+// against the working directory.
+func Annotations(escapes []categorizer.CategorizedEscape, root string) ([]Annotation, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	annotations := make([]Annotation, 0, len(escapes))
+	for _, e := range escapes {
+		if e.Info.File == "" || parser.IsSynthetic(e.Info.File) {
+			continue
+		}
+		abs, err := filepath.Abs(e.Info.File)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		a := Annotation{
+			Path:       filepath.ToSlash(rel),
+			StartLine:  e.Info.Line,
+			EndLine:    e.Info.Line,
+			Level:      "warning",
+			Title:      string(e.Category),
+			Message:    fmt.Sprintf("%s escapes to heap: %s", e.Info.Variable, e.Suggestion.Short),
+			RawDetails: e.Fix,
+		}
+		if level, ok := annotationLevels[e.Severity]; ok {
+			a.Level = level
+		}
+		if e.Info.Column > 0 {
+			a.StartColumn, a.EndColumn = e.Info.Column, e.Info.Column
+		}
+		annotations = append(annotations, a)
+	}
+	return annotations, nil
+}
+
+// checkRun is the request body of the checks API
+type checkRun struct {
+	Name        string  `json:"name,omitempty"`
+	HeadSHA     string  `json:"head_sha,omitempty"`
+	Status      string  `json:"status,omitempty"`
+	Conclusion  string  `json:"conclusion,omitempty"`
+	CompletedAt string  `json:"completed_at,omitempty"`
+	Output      *output `json:"output"`
+}
+
+type output struct {
+	Title       string       `json:"title"`
+	Summary     string       `json:"summary"`
+	Annotations []Annotation `json:"annotations,omitempty"`
+}
+
+// Create creates run as a completed check run and returns its URL. The
+// annotations beyond the first batch are added by updating it.
+func (c *Client) Create(ctx context.Context, run Run) (string, error) {
+	batch := func(i int) []Annotation {
+		return run.Annotations[i:min(i+maxAnnotations, len(run.Annotations))]
+	}
+	out := func(i int) *output {
+		return &output{Title: run.Title, Summary: run.Summary, Annotations: batch(i)}
+	}
+
+	var created struct {
+		ID      int64  `json:"id"`
+		HTMLURL string `json:"html_url"`
+	}
+	err := c.do(ctx, http.MethodPost, "/repos/"+c.Repo+"/check-runs", checkRun{
+		Name:        Name,
+		HeadSHA:     run.HeadSHA,
+		Status:      "completed",
+		Conclusion:  run.Conclusion,
+		CompletedAt: time.Now().UTC().Format(time.RFC3339),
+		Output:      out(0),
+	}, &created)
+	if err != nil {
+		return "", err
+	}
+	for i := maxAnnotations; i < len(run.Annotations); i += maxAnnotations {
+		path := fmt.Sprintf("/repos/%s/check-runs/%d", c.Repo, created.ID)
+		if err := c.do(ctx, http.MethodPatch, path, checkRun{Output: out(i)}, nil); err != nil {
+			return created.HTMLURL, err
+		}
+	}
+	return created.HTMLURL, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.API+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("creating check run: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("creating check run: %s %s: %s: %s", method, req.URL.Path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("creating check run: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package checkrun
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func TestCreate(t *testing.T) {
+	type request struct {
+		method, path, auth string
+		body               checkRun
+	}
+	var requests []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body checkRun
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		requests = append(requests, request{r.Method, r.URL.Path, r.Header.Get("Authorization"), body})
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]any{"id": 7, "html_url": "https://github.com/o/r/runs/7"})
+		}
+	}))
+	defer srv.Close()
+
+	run := Run{HeadSHA: "abc123", Conclusion: "neutral", Title: "120 new escape(s)", Summary: "summary"}
+	for i := 1; i <= 120; i++ {
+		run.Annotations = append(run.Annotations, Annotation{Path: "main.go", StartLine: i, EndLine: i, Level: "warning", Message: "m"})
+	}
+	c := &Client{API: srv.URL, Repo: "o/r", Token: "secret", Client: srv.Client()}
+	url, err := c.Create(context.Background(), run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "https://github.com/o/r/runs/7" {
+		t.Errorf("url = %q", url)
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want a create and two updates", len(requests))
+	}
+	create := requests[0]
+	if create.method != http.MethodPost || create.path != "/repos/o/r/check-runs" {
+		t.Errorf("first request = %s %s, want POST /repos/o/r/check-runs", create.method, create.path)
+	}
+	if create.body.Name != Name || create.body.HeadSHA != "abc123" || create.body.Status != "completed" || create.body.Conclusion != "neutral" {
+		t.Errorf("create body = %+v", create.body)
+	}
+	var lines []int
+	for i, r := range requests {
+		if r.auth != "Bearer secret" {
+			t.Errorf("request %d Authorization = %q", i, r.auth)
+		}
+		if i > 0 && (r.method != http.MethodPatch || r.path != "/repos/o/r/check-runs/7") {
+			t.Errorf("request %d = %s %s, want PATCH /repos/o/r/check-runs/7", i, r.method, r.path)
+		}
+		if r.body.Output.Title != run.Title || r.body.Output.Summary != run.Summary {
+			t.Errorf("request %d output = %q, %q, want the run's title and summary", i, r.body.Output.Title, r.body.Output.Summary)
+		}
+		for _, a := range r.body.Output.Annotations {
+			lines = append(lines, a.StartLine)
+		}
+		if n := len(r.body.Output.Annotations); n > maxAnnotations {
+			t.Errorf("request %d has %d annotations, want at most %d", i, n, maxAnnotations)
+		}
+	}
+	if len(lines) != 120 || lines[0] != 1 || lines[119] != 120 {
+		t.Errorf("annotated lines = %v, want 1 to 120 once each", lines)
+	}
+}
+
+func TestCreateError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Resource not accessible by integration"}`, http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	c := &Client{API: srv.URL, Repo: "o/r", Token: "secret", Client: srv.Client()}
+	if _, err := c.Create(context.Background(), Run{HeadSHA: "abc123"}); err == nil {
+		t.Fatal("Create succeeded, want the API error")
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv(TokenEnv, "")
+	if _, ok, err := FromEnv(); ok || err != nil {
+		t.Errorf("without a token: ok = %v, err = %v, want false, nil", ok, err)
+	}
+
+	t.Setenv(TokenEnv, "secret")
+	t.Setenv(RepositoryEnv, "repo")
+	if _, ok, err := FromEnv(); !ok || err == nil {
+		t.Errorf("with repository %q: ok = %v, err = %v, want an error", "repo", ok, err)
+	}
+
+	t.Setenv(RepositoryEnv, "o/r")
+	t.Setenv(APIURLEnv, "")
+	c, ok, err := FromEnv()
+	if !ok || err != nil {
+		t.Fatalf("ok = %v, err = %v", ok, err)
+	}
+	if c.API != "https://api.github.com" || c.Repo != "o/r" || c.Token != "secret" {
+		t.Errorf("client = %+v", c)
+	}
+}
+
+func TestHeadSHA(t *testing.T) {
+	dir := t.TempDir()
+	pr := filepath.Join(dir, "pr.json")
+	os.WriteFile(pr, []byte(`{"pull_request":{"head":{"sha":"head456"}}}`), 0o644)
+	push := filepath.Join(dir, "push.json")
+	os.WriteFile(push, []byte(`{"ref":"refs/heads/main"}`), 0o644)
+
+	tests := []struct {
+		name, event, sha string
+		want             string
+		wantErr          bool
+	}{
+		{name: "pull request", event: pr, sha: "merge123", want: "head456"},
+		{name: "push", event: push, sha: "push789", want: "push789"},
+		{name: "no event", sha: "push789", want: "push789"},
+		{name: "nothing", wantErr: true},
+		{name: "missing event", event: filepath.Join(dir, "missing.json"), sha: "push789", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EventPathEnv, tt.event)
+			t.Setenv(SHAEnv, tt.sha)
+			got, err := HeadSHA()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("HeadSHA() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	root := t.TempDir()
+	escape := func(file string, line, col int, severity categorizer.Severity) categorizer.CategorizedEscape {
+		return categorizer.CategorizedEscape{
+			Info:       parser.EscapeInfo{File: file, Line: line, Column: col, Variable: "buf"},
+			Category:   categorizer.CategoryFmtCall,
+			Suggestion: categorizer.Suggestion{Short: "use strconv"},
+			Fix:        "--- a\n+++ b\n",
+			Severity:   severity,
+		}
+	}
+	escapes := []categorizer.CategorizedEscape{
+		escape(filepath.Join(root, "pkg", "a.go"), 12, 5, ""),
+		escape(filepath.Join(root, "b.go"), 3, 0, categorizer.SeverityHigh),
+		escape(filepath.Join(root, "internal", "c.go"), 7, 0, categorizer.SeverityLow),
+		escape("<autogenerated>", 1, 0, ""),
+		escape(filepath.Join(filepath.Dir(root), "elsewhere.go"), 1, 0, ""),
+	}
+	got, err := Annotations(escapes, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Annotation{
+		{Path: "pkg/a.go", StartLine: 12, EndLine: 12, StartColumn: 5, EndColumn: 5, Level: "warning", Title: "fmt-call", Message: "buf escapes to heap: use strconv", RawDetails: "--- a\n+++ b\n"},
+		{Path: "b.go", StartLine: 3, EndLine: 3, Level: "failure", Title: "fmt-call", Message: "buf escapes to heap: use strconv", RawDetails: "--- a\n+++ b\n"},
+		{Path: "internal/c.go", StartLine: 7, EndLine: 7, Level: "notice", Title: "fmt-call", Message: "buf escapes to heap: use strconv", RawDetails: "--- a\n+++ b\n"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Annotations() =\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -0,0 +1,325 @@
+// Package config loads the .heapcheck.yaml configuration file.
+//
+// Example:
+//
+//	categories:
+//	  interface-boxing: fail>10   # fail when more than 10 escapes
+//	  fmt-call: warn              # warn on any escape
+//	  slice-grow: warn>5, fail>20
+//	packages:
+//	  internal/server:             # rules for the escapes below this directory
+//	    interface-boxing: fail>4
+//	history:
+//	  file: .heapcheck-history.json  # escape counts of past runs
+//	  branch: main                   # only runs on this branch are recorded
+//	  window: 10                     # runs in the rolling average
+//	  keep: 90d                      # drop older runs; or a number of runs, or both: 90d,500
+//	  compact: 30d                   # keep one run per day of runs older than this
+//	links:
+//	  interface-boxing: https://wiki.example.com/go/boxing  # replaces the default doc link
+//	allow:
+//	  - (*Buffer).Grow     # escapes inside or caused by calls to these functions
+//	  - encoding/json.*
+//	remap:
+//	  fmt-call: interface-boxing  # report fmt-call escapes as interface-boxing
+//	  slice-grow: alloc/slice     # or under a name from your own taxonomy
+//	suggestions:
+//	  interface-boxing: Use generics, see the perf guide  # replaces the short text
+//	  alloc/slice:
+//	    short: Preallocate with make([]T, 0, n)
+//	    details: Size slices from the request's item count.
+//	ruleset: .heapcheck-rules.yaml  # category rules tried before the built-in ones
+//
+// Config files in subdirectories apply to the escapes below them: their
+// category rules replace the ones above for that subtree, and their
+// allowed functions add to them. A legacy/.heapcheck.yaml can so give old
+// code looser budgets than the rest of the module.
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/harshakonda/heapcheck/internal/allow"
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/gate"
+)
+
+// FileNames are the config file names looked up by Find, in order
+var FileNames = []string{".heapcheck.yaml", ".heapcheck.yml"}
+
+// Config is the content of a config file
+type Config struct {
+	// Categories maps a category to its gate rule, e.g. "fail>10"
+	Categories map[categorizer.Category]string `yaml:"categories"`
+
+	// Packages maps a directory, relative to the current directory, to
+	// category rules for the escapes below it, as a config file in that
+	// directory would set them
+	Packages map[string]map[categorizer.Category]string `yaml:"packages"`
+
+	// History configures the run history used by --fail-on-trend
+	History History `yaml:"history"`
+
+	// Links maps a category to the documentation link shown with its
+	// suggestion, replacing the default one
+	Links map[categorizer.Category]string `yaml:"links"`
+
+	// Allow lists functions whose escapes are accepted, e.g.
+	// "(*Buffer).Grow" or "encoding/json.*"
+	Allow []string `yaml:"allow"`
+
+	// Remap maps a category to the category its escapes are reported as
+	Remap map[categorizer.Category]categorizer.Category `yaml:"remap"`
+
+	// Suggestions replaces the suggestion text of a category, by the name
+	// it is reported as
+	Suggestions map[categorizer.Category]Suggestion `yaml:"suggestions"`
+
+	// Ruleset is a file of category rules, relative to the current
+	// directory, tried before the built-in rules or, when it sets
+	// inherit: false, instead of them
+	Ruleset string `yaml:"ruleset"`
+}
+
+// Suggestion is the text of a category's suggestion. A plain string sets
+// Short.
+type Suggestion struct {
+	Short   string `yaml:"short"`
+	Details string `yaml:"details"`
+}
+
+// UnmarshalYAML accepts a string for Short, or a mapping
+func (s *Suggestion) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&s.Short)
+	}
+	type plain Suggestion
+	return node.Decode((*plain)(s))
+}
+
+// SuggestionOverrides returns the suggestions as categorizer suggestions
+func (c *Config) SuggestionOverrides() map[categorizer.Category]categorizer.Suggestion {
+	if len(c.Suggestions) == 0 {
+		return nil
+	}
+	overrides := make(map[categorizer.Category]categorizer.Suggestion, len(c.Suggestions))
+	for cat, s := range c.Suggestions {
+		overrides[cat] = categorizer.Suggestion{Short: s.Short, Details: s.Details}
+	}
+	return overrides
+}
+
+// History configures where past runs are recorded
+type History struct {
+	File   string `yaml:"file"`
+	Branch string `yaml:"branch"` // default: origin/HEAD, or main
+	Window int    `yaml:"window"` // default: history.DefaultWindow
+
+	// Keep is the retention of recorded runs, e.g. "90d", "500" runs or
+	// both, "90d,500" (default: the newest 200 runs)
+	Keep string `yaml:"keep"`
+
+	// Compact is the age beyond which runs are thinned to one per branch
+	// and day, e.g. "30d"; empty keeps every run
+	Compact string `yaml:"compact"`
+}
+
+// Load reads and validates a config file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Find returns the config file in dir, or "" if there is none
+func Find(dir string) string {
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Nested is a config file in a subdirectory, for the escapes below Dir
+type Nested struct {
+	Dir    string
+	Config *Config
+}
+
+// FindNested loads the config files in the subdirectories of dir, with
+// Dir joined to dir. It skips the directories "./..." patterns skip:
+// testdata, vendor and names starting with . or _.
+func FindNested(dir string) ([]Nested, error) {
+	var nested []Nested
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil // e.g. an unreadable directory
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		if path != dir && (name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			return filepath.SkipDir
+		}
+		if path == dir {
+			return nil
+		}
+		file := Find(path)
+		if file == "" {
+			return nil
+		}
+		cfg, err := Load(file)
+		if err != nil {
+			return err
+		}
+		if err := cfg.validateNested(); err != nil {
+			return fmt.Errorf("config %s: %w", file, err)
+		}
+		nested = append(nested, Nested{Dir: path, Config: cfg})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("finding nested configs: %w", err)
+	}
+	return nested, nil
+}
+
+// validateNested checks that a nested config sets only what can apply to
+// a subtree: category rules and allowed functions
+func (c *Config) validateNested() error {
+	switch {
+	case c.History != History{}:
+		return fmt.Errorf("history applies to the whole run; set it in the top-level config")
+	case len(c.Links) > 0, len(c.Remap) > 0, len(c.Suggestions) > 0, len(c.Packages) > 0:
+		return fmt.Errorf("only categories and allow can be set for a subdirectory")
+	}
+	return nil
+}
+
+// AllowFor returns the allowed functions of a file: those of c and of the
+// nested configs of the directories around it
+func (c *Config) AllowFor(nested []Nested) func(file string) []string {
+	return func(file string) []string {
+		allowed := c.Allow
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return allowed
+		}
+		for _, n := range nested {
+			if len(n.Config.Allow) == 0 {
+				continue
+			}
+			if dir, err := filepath.Abs(n.Dir); err == nil && strings.HasPrefix(abs, dir+string(filepath.Separator)) {
+				allowed = append(slices.Clip(allowed), n.Config.Allow...)
+			}
+		}
+		return allowed
+	}
+}
+
+// Scopes returns the category rules of the nested configs that set any
+func Scopes(nested []Nested) ([]gate.Scope, error) {
+	var scopes []gate.Scope
+	for _, n := range nested {
+		rules, err := n.Config.Rules()
+		if err != nil {
+			return nil, err
+		}
+		if len(rules) > 0 {
+			scopes = append(scopes, gate.Scope{Dir: n.Dir, Rules: rules})
+		}
+	}
+	return scopes, nil
+}
+
+// Validate checks that every category rule parses, every link is an
+// absolute URL, every allow pattern is well formed, categories are
+// remapped to valid names and suggestions have text
+func (c *Config) Validate() error {
+	if c.History.Window < 0 {
+		return fmt.Errorf("history.window must not be negative")
+	}
+	for cat, link := range c.Links {
+		if u, err := url.Parse(link); err != nil || !u.IsAbs() {
+			return fmt.Errorf("links.%s: %q is not an absolute URL", cat, link)
+		}
+	}
+	if err := allow.Validate(c.Allow); err != nil {
+		return fmt.Errorf("allow: %w", err)
+	}
+	for from, to := range c.Remap {
+		if to == "" || strings.ContainsAny(string(to), " \t") {
+			return fmt.Errorf("remap.%s: %q is not a category name", from, to)
+		}
+	}
+	for cat, s := range c.Suggestions {
+		if s.Short == "" && s.Details == "" {
+			return fmt.Errorf("suggestions.%s: no short or details text", cat)
+		}
+	}
+	if _, err := c.PackageScopes(); err != nil {
+		return err
+	}
+	_, err := c.Rules()
+	return err
+}
+
+// PackageScopes returns the category rules of the packages, sorted by
+// directory
+func (c *Config) PackageScopes() ([]gate.Scope, error) {
+	dirs := make([]string, 0, len(c.Packages))
+	for dir := range c.Packages {
+		dirs = append(dirs, dir)
+	}
+	slices.Sort(dirs)
+	scopes := make([]gate.Scope, 0, len(dirs))
+	for _, dir := range dirs {
+		rules, err := parseRules(c.Packages[dir])
+		if err != nil {
+			return nil, fmt.Errorf("packages.%s: %w", dir, err)
+		}
+		scopes = append(scopes, gate.Scope{Dir: filepath.FromSlash(dir), Rules: rules})
+	}
+	return scopes, nil
+}
+
+// Rules parses the category gate rules
+func (c *Config) Rules() (map[categorizer.Category]gate.Rule, error) {
+	return parseRules(c.Categories)
+}
+
+func parseRules(specs map[categorizer.Category]string) (map[categorizer.Category]gate.Rule, error) {
+	rules := make(map[categorizer.Category]gate.Rule, len(specs))
+	for cat, spec := range specs {
+		rule, err := gate.ParseRule(spec)
+		if err != nil {
+			return nil, fmt.Errorf("category %s: %w", cat, err)
+		}
+		rules[cat] = rule
+	}
+	return rules, nil
+}
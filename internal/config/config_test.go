@@ -0,0 +1,204 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, ".heapcheck.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), "categories:\n  interface-boxing: fail>10\n  fmt-call: warn\n")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	rules, err := cfg.Rules()
+	if err != nil {
+		t.Fatalf("Rules() error: %v", err)
+	}
+	if got := rules[categorizer.CategoryInterfaceBoxing].String(); got != "fail>10" {
+		t.Errorf("interface-boxing rule = %q, want fail>10", got)
+	}
+	if got := rules[categorizer.CategoryFmtCall].String(); got != "warn" {
+		t.Errorf("fmt-call rule = %q, want warn", got)
+	}
+}
+
+func TestLoadHistory(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), "history:\n  file: runs.json\n  branch: trunk\n  window: 5\n  keep: 90d,500\n  compact: 30d\n")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	want := History{File: "runs.json", Branch: "trunk", Window: 5, Keep: "90d,500", Compact: "30d"}
+	if cfg.History != want {
+		t.Errorf("History = %+v, want %+v", cfg.History, want)
+	}
+}
+
+func TestLoadLinks(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), "links:\n  fmt-call: https://wiki.example.com/go/fmt\n")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if got := cfg.Links[categorizer.CategoryFmtCall]; got != "https://wiki.example.com/go/fmt" {
+		t.Errorf("fmt-call link = %q, want https://wiki.example.com/go/fmt", got)
+	}
+}
+
+func TestLoadAllow(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), "allow:\n  - (*Buffer).Grow\n  - encoding/json.*\n")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(cfg.Allow) != 2 || cfg.Allow[0] != "(*Buffer).Grow" || cfg.Allow[1] != "encoding/json.*" {
+		t.Errorf("Allow = %q, want [(*Buffer).Grow encoding/json.*]", cfg.Allow)
+	}
+}
+
+func TestLoadRemap(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), `remap:
+  fmt-call: interface-boxing
+  slice-grow: alloc/slice
+suggestions:
+  interface-boxing: Use generics
+  alloc/slice:
+    short: Preallocate
+    details: Size slices from the item count.
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if got := cfg.Remap[categorizer.CategoryFmtCall]; got != categorizer.CategoryInterfaceBoxing {
+		t.Errorf("fmt-call remapped to %q, want interface-boxing", got)
+	}
+	if got := cfg.Remap[categorizer.CategorySliceGrow]; got != "alloc/slice" {
+		t.Errorf("slice-grow remapped to %q, want alloc/slice", got)
+	}
+	overrides := cfg.SuggestionOverrides()
+	if got := overrides[categorizer.CategoryInterfaceBoxing]; got != (categorizer.Suggestion{Short: "Use generics"}) {
+		t.Errorf("interface-boxing suggestion = %+v, want short text only", got)
+	}
+	want := categorizer.Suggestion{Short: "Preallocate", Details: "Size slices from the item count."}
+	if got := overrides["alloc/slice"]; got != want {
+		t.Errorf("alloc/slice suggestion = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []string{
+		"categories:\n  fmt-call: error\n",
+		"categories: [\n",
+		"history:\n  window: -1\n",
+		"links:\n  fmt-call: wiki/fmt\n",
+		"allow:\n  - encoding/*.Marshal\n",
+		"remap:\n  fmt-call: \"\"\n",
+		"remap:\n  fmt-call: fmt call\n",
+		"suggestions:\n  fmt-call: {}\n",
+	}
+	for _, content := range tests {
+		path := writeConfig(t, t.TempDir(), content)
+		if _, err := Load(path); err == nil {
+			t.Errorf("Load(%q) expected error", content)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	if got := Find(dir); got != "" {
+		t.Errorf("Find() in empty dir = %q, want empty", got)
+	}
+
+	path := writeConfig(t, dir, "")
+	if got := Find(dir); got != path {
+		t.Errorf("Find() = %q, want %q", got, path)
+	}
+}
+
+func TestFindNested(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"legacy/v1", "testdata", ".git", "new"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(t, dir, "categories:\n  interface-boxing: fail\nallow:\n  - json.*\n")
+	writeConfig(t, filepath.Join(dir, "legacy"), "categories:\n  interface-boxing: fail>50\nallow:\n  - fmt.*\n")
+	writeConfig(t, filepath.Join(dir, "legacy/v1"), "allow:\n  - reflect.*\n")
+	writeConfig(t, filepath.Join(dir, "testdata"), "categories:\n  fmt-call: fail\n")
+	writeConfig(t, filepath.Join(dir, ".git"), "categories:\n  fmt-call: fail\n")
+
+	nested, err := FindNested(dir)
+	if err != nil {
+		t.Fatalf("FindNested() error: %v", err)
+	}
+	if len(nested) != 2 || nested[0].Dir != filepath.Join(dir, "legacy") || nested[1].Dir != filepath.Join(dir, "legacy", "v1") {
+		t.Fatalf("FindNested() = %+v", nested)
+	}
+
+	scopes, err := Scopes(nested)
+	if err != nil {
+		t.Fatalf("Scopes() error: %v", err)
+	}
+	if len(scopes) != 1 || scopes[0].Rules[categorizer.CategoryInterfaceBoxing].String() != "fail>50" {
+		t.Errorf("Scopes() = %+v", scopes)
+	}
+
+	root := &Config{Allow: []string{"json.*"}}
+	allowFor := root.AllowFor(nested)
+	tests := []struct {
+		file string
+		want int
+	}{
+		{"main.go", 1},
+		{"legacy/old.go", 2},
+		{"legacy/v1/older.go", 3},
+		{"legacyish/new.go", 1},
+	}
+	for _, tt := range tests {
+		if got := allowFor(filepath.Join(dir, tt.file)); len(got) != tt.want {
+			t.Errorf("AllowFor(%s) = %v, want %d patterns", tt.file, got, tt.want)
+		}
+	}
+	if len(root.Allow) != 1 {
+		t.Errorf("AllowFor modified the root patterns: %v", root.Allow)
+	}
+}
+
+func TestFindNestedInvalid(t *testing.T) {
+	for _, content := range []string{
+		"history:\n  file: runs.json\n",
+		"links:\n  fmt-call: https://wiki.example.com/go/fmt\n",
+		"remap:\n  fmt-call: interface-boxing\n",
+		"categories:\n  fmt-call: error\n",
+	} {
+		dir := t.TempDir()
+		if err := os.Mkdir(filepath.Join(dir, "legacy"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		writeConfig(t, filepath.Join(dir, "legacy"), content)
+		if _, err := FindNested(dir); err == nil {
+			t.Errorf("FindNested() with %q expected error", content)
+		}
+	}
+}
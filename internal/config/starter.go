@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/gate"
+)
+
+// Budget is a category's rule in a starter config
+type Budget struct {
+	Category categorizer.Category
+	Count    int // escapes the analysis found
+	Limit    int // the fail threshold: Count plus the headroom
+}
+
+// Starter is the config heapcheck init writes, with budgets just above
+// the escapes of an analysis, for teams to lower as escapes are fixed
+type Starter struct {
+	Headroom   int // percent above the counts
+	Categories []Budget
+	Packages   map[string][]Budget // by directory, with forward slashes
+}
+
+// NewStarter sets budgets headroom percent, and at least one escape,
+// above the escapes of results. Packages, by the directory of their
+// files, with at least minPackage escapes get budgets of their own; the
+// escapes of smaller ones, of the package in the current directory and
+// of files outside it count toward the top-level budgets.
+func NewStarter(results *categorizer.Results, headroom, minPackage int) *Starter {
+	off, _ := gate.ParseRule("off")
+	byDir := make(map[string]map[categorizer.Category]gate.Rule)
+	counts := make(map[string]int)
+	for _, e := range results.Escapes {
+		dir, ok := packageDir(e.Info.File)
+		if !ok {
+			continue
+		}
+		if byDir[dir] == nil {
+			byDir[dir] = make(map[categorizer.Category]gate.Rule)
+		}
+		byDir[dir][e.Category] = off
+		counts[dir]++
+	}
+	var scopes []gate.Scope
+	for dir, rules := range byDir {
+		if counts[dir] >= minPackage {
+			scopes = append(scopes, gate.Scope{Dir: filepath.FromSlash(dir), Rules: rules})
+		}
+	}
+	rules := make(map[categorizer.Category]gate.Rule)
+	for cat, n := range results.ByCategory {
+		if n > 0 {
+			rules[cat] = off
+		}
+	}
+
+	s := &Starter{Headroom: headroom, Packages: make(map[string][]Budget)}
+	gr := gate.EvaluateScoped(results, rules, scopes)
+	if gr == nil {
+		return s
+	}
+	for _, g := range gr.Categories {
+		b := Budget{Category: g.Category, Count: g.Count, Limit: g.Count + max(1, (g.Count*headroom+99)/100)}
+		if g.Dir == "" {
+			s.Categories = append(s.Categories, b)
+		} else {
+			dir := filepath.ToSlash(g.Dir)
+			s.Packages[dir] = append(s.Packages[dir], b)
+		}
+	}
+	return s
+}
+
+// packageDir returns the directory of file relative to the current
+// directory, or false for the current directory and those outside it
+func packageDir(file string) (string, bool) {
+	dir := filepath.Dir(file)
+	if filepath.IsAbs(dir) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", false
+		}
+		if dir, err = filepath.Rel(wd, dir); err != nil {
+			return "", false
+		}
+	}
+	if dir == "." || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(dir), true
+}
+
+// Write writes s as a config file, with the current count of each budget
+// in a comment
+func (s *Starter) Write(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Written by heapcheck init. Each budget fails the run when a category's\n")
+	fmt.Fprintf(&b, "# escapes grow more than %d%% above the count found then; lower the budgets\n", s.Headroom)
+	fmt.Fprintf(&b, "# as escapes are fixed to keep them from coming back.\n")
+	if len(s.Categories) == 0 {
+		b.WriteString("categories: {}\n")
+	} else {
+		b.WriteString("categories:\n")
+		writeBudgets(&b, "  ", s.Categories)
+	}
+	if len(s.Packages) > 0 {
+		b.WriteString("packages:\n")
+		dirs := make([]string, 0, len(s.Packages))
+		for dir := range s.Packages {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+		for _, dir := range dirs {
+			fmt.Fprintf(&b, "  %s:\n", yamlKey(dir))
+			writeBudgets(&b, "    ", s.Packages[dir])
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeBudgets(b *strings.Builder, indent string, budgets []Budget) {
+	for _, budget := range budgets {
+		fmt.Fprintf(b, "%s%s: fail>%d  # %d now\n", indent, yamlKey(string(budget.Category)), budget.Limit, budget.Count)
+	}
+}
+
+// yamlKey quotes s unless it is safe as a plain YAML key
+func yamlKey(s string) string {
+	for _, r := range s {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || strings.ContainsRune("._/-", r)) {
+			return fmt.Sprintf("%q", s)
+		}
+	}
+	return s
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func TestStarter(t *testing.T) {
+	escape := func(file string, cat categorizer.Category) categorizer.CategorizedEscape {
+		return categorizer.CategorizedEscape{Info: parser.EscapeInfo{File: file}, Category: cat}
+	}
+	results := &categorizer.Results{}
+	for i := 0; i < 20; i++ {
+		results.Escapes = append(results.Escapes, escape("server/handler.go", categorizer.CategoryInterfaceBoxing))
+	}
+	results.Escapes = append(results.Escapes,
+		escape("server/handler.go", categorizer.CategoryFmtCall),
+		escape("server/sub/sub.go", categorizer.CategoryInterfaceBoxing), // counts toward server
+		escape("util/util.go", categorizer.CategoryInterfaceBoxing),      // too few for its own budgets
+		escape("main.go", categorizer.CategoryFmtCall),
+	)
+	results.ByCategory = map[categorizer.Category]int{
+		categorizer.CategoryInterfaceBoxing: 22,
+		categorizer.CategoryFmtCall:         2,
+	}
+
+	s := NewStarter(results, 10, 5)
+	want := []Budget{
+		{Category: categorizer.CategoryFmtCall, Count: 1, Limit: 2},
+		{Category: categorizer.CategoryInterfaceBoxing, Count: 1, Limit: 2},
+	}
+	if len(s.Categories) != 2 || s.Categories[0] != want[0] || s.Categories[1] != want[1] {
+		t.Errorf("Categories = %+v, want %+v", s.Categories, want)
+	}
+	server := []Budget{
+		{Category: categorizer.CategoryFmtCall, Count: 1, Limit: 2},
+		{Category: categorizer.CategoryInterfaceBoxing, Count: 21, Limit: 24},
+	}
+	if got := s.Packages["server"]; len(s.Packages) != 1 || len(got) != 2 || got[0] != server[0] || got[1] != server[1] {
+		t.Errorf("Packages = %+v, want server budgets %+v", s.Packages, server)
+	}
+
+	// The written config loads, with the budgets as rules
+	var buf bytes.Buffer
+	if err := s.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "interface-boxing: fail>24  # 21 now") {
+		t.Errorf("written config missing the server budget:\n%s", buf.String())
+	}
+	path := writeConfig(t, t.TempDir(), buf.String())
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() of the written config: %v", err)
+	}
+	scopes, err := cfg.PackageScopes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scopes) != 1 || scopes[0].Dir != "server" || scopes[0].Rules[categorizer.CategoryInterfaceBoxing].String() != "fail>24" {
+		t.Errorf("PackageScopes() = %+v", scopes)
+	}
+}
+
+func TestStarterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewStarter(&categorizer.Results{}, 10, 5).Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(writeConfig(t, t.TempDir(), buf.String())); err != nil {
+		t.Errorf("Load() of an empty starter config: %v\n%s", err, buf.String())
+	}
+}
+
+func TestPackagesConfig(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), "packages:\n  internal/server:\n    fmt-call: fail>x\n")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "packages.internal/server") {
+		t.Errorf("Load() error = %v, want the invalid package rule", err)
+	}
+
+	// Only the top-level config sets packages
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "legacy"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, filepath.Join(dir, "legacy"), "packages:\n  v1:\n    fmt-call: fail\n")
+	if _, err := FindNested(dir); err == nil {
+		t.Error("FindNested() accepted packages in a nested config")
+	}
+}
@@ -0,0 +1,211 @@
+// Package copies finds large structs and arrays copied by value in the
+// functions that escapes were reported in: the mirror image of the
+// return-pointer advice. Returning or passing a value instead of a pointer
+// keeps it off the heap, but for a value of hundreds of bytes the copy on
+// every call can cost more than the allocation it saves.
+package copies
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/typecheck"
+)
+
+// DefaultMinSize is the smallest copy reported, in bytes: four cache
+// lines, well past the size the return-pointer advice returns by value
+const DefaultMinSize = 256
+
+// Analyze type-checks the packages matching patterns and returns the
+// parameters, results and receivers of at least minSize bytes that calls
+// copy by value, within the functions that have escapes. The most bytes
+// copied, size times call sites, come first.
+func Analyze(patterns []string, escapes []categorizer.CategorizedEscape, minSize int64) ([]categorizer.LargeCopy, error) {
+	lines := make(map[string]map[int]bool)
+	for _, e := range escapes {
+		abs, err := filepath.Abs(e.Info.File)
+		if err != nil {
+			continue
+		}
+		if lines[abs] == nil {
+			lines[abs] = make(map[int]bool)
+		}
+		lines[abs][e.Info.Line] = true
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	files := make(map[string]bool, len(lines))
+	for path := range lines {
+		files[path] = true
+	}
+	fset := token.NewFileSet()
+	pkgs, err := typecheck.Check(fset, patterns, files)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &collector{
+		fset:    fset,
+		sizes:   types.SizesFor("gc", build.Default.GOARCH),
+		minSize: minSize,
+		copies:  make(map[copyKey]*categorizer.LargeCopy),
+	}
+	for _, p := range pkgs {
+		for _, f := range p.Files {
+			escaped := lines[fset.Position(f.Pos()).Filename]
+			for _, decl := range f.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if ok && fd.Body != nil && c.hasEscape(fd, escaped) {
+					c.inspect(fd.Body, p.Info)
+				}
+			}
+		}
+	}
+	return c.sorted(), nil
+}
+
+// copyKey identifies one value of one function: the receiver (-1), a
+// parameter or a result
+type copyKey struct {
+	fn     string
+	result bool
+	index  int
+}
+
+// collector accumulates the call sites per copied value
+type collector struct {
+	fset    *token.FileSet
+	sizes   types.Sizes
+	minSize int64
+	copies  map[copyKey]*categorizer.LargeCopy
+}
+
+// hasEscape reports whether an escape was reported on a line of fd
+func (c *collector) hasEscape(fd *ast.FuncDecl, escaped map[int]bool) bool {
+	first, last := c.fset.Position(fd.Pos()).Line, c.fset.Position(fd.End()).Line
+	for line := range escaped {
+		if line >= first && line <= last {
+			return true
+		}
+	}
+	return false
+}
+
+// inspect records the large values copied by the calls in body
+func (c *collector) inspect(body *ast.BlockStmt, info *types.Info) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		tv, ok := info.Types[call.Fun]
+		if !ok || tv.IsType() {
+			return true // conversion, not a call
+		}
+		sig, ok := tv.Type.Underlying().(*types.Signature)
+		if !ok {
+			return true
+		}
+
+		fn := typecheck.Callee(info, call.Fun)
+		name, position := types.ExprString(call.Fun), ""
+		if fn != nil {
+			name = fn.FullName()
+			if pos := c.fset.Position(fn.Pos()); pos.IsValid() {
+				position = fmt.Sprintf("%s:%d", pos.Filename, pos.Line)
+			}
+			// A method value's signature drops the receiver; the method's
+			// own keeps it
+			if recv := fn.Type().(*types.Signature).Recv(); recv != nil && isMethodCall(info, call.Fun) {
+				c.record(copyKey{fn: name, index: -1}, "receiver", recv.Type(), position)
+			}
+		}
+
+		params := sig.Params()
+		for i := 0; i < params.Len(); i++ {
+			if sig.Variadic() && i == params.Len()-1 {
+				break // a slice
+			}
+			c.record(copyKey{fn: name, index: i}, describe("param", params.At(i), i), params.At(i).Type(), position)
+		}
+		results := sig.Results()
+		for i := 0; i < results.Len(); i++ {
+			c.record(copyKey{fn: name, result: true, index: i}, describe("result", results.At(i), i), results.At(i).Type(), position)
+		}
+		return true
+	})
+}
+
+// record counts a call site copying a value of type t, if it is a large
+// struct or array
+func (c *collector) record(key copyKey, value string, t types.Type, position string) {
+	switch t.Underlying().(type) {
+	case *types.Struct, *types.Array:
+	default:
+		return // pointers, interfaces, slices and type parameters are not copied whole
+	}
+	size := c.sizes.Sizeof(t)
+	if size < c.minSize {
+		return
+	}
+
+	lc := c.copies[key]
+	if lc == nil {
+		lc = &categorizer.LargeCopy{
+			Func:     key.fn,
+			Value:    value,
+			Type:     types.TypeString(t, func(p *types.Package) string { return p.Name() }),
+			Size:     size,
+			Position: position,
+		}
+		c.copies[key] = lc
+	}
+	lc.CallSites++
+}
+
+// sorted returns the collected copies, most bytes copied first
+func (c *collector) sorted() []categorizer.LargeCopy {
+	result := make([]categorizer.LargeCopy, 0, len(c.copies))
+	for _, lc := range c.copies {
+		result = append(result, *lc)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Size*int64(a.CallSites) != b.Size*int64(b.CallSites) {
+			return a.Size*int64(a.CallSites) > b.Size*int64(b.CallSites)
+		}
+		if a.Func != b.Func {
+			return a.Func < b.Func
+		}
+		return a.Value < b.Value
+	})
+	return result
+}
+
+// describe names a parameter or result, e.g. "param cfg" or "result 0"
+// for an unnamed one
+func describe(kind string, v *types.Var, i int) string {
+	if v.Name() == "" || v.Name() == "_" {
+		return fmt.Sprintf("%s %d", kind, i)
+	}
+	return kind + " " + v.Name()
+}
+
+// isMethodCall reports whether fun calls a method on a value, x.M(),
+// rather than a method expression T.M(x) or a function
+func isMethodCall(info *types.Info, fun ast.Expr) bool {
+	sel, ok := ast.Unparen(fun).(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	s, ok := info.Selections[sel]
+	return ok && s.Kind() == types.MethodVal
+}
@@ -0,0 +1,53 @@
+package copies
+
+import (
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func TestAnalyze(t *testing.T) {
+	escapes := []categorizer.CategorizedEscape{
+		{ // sink = &Small{A: n}: Use has escapes, Quiet has none
+			Info:     parser.EscapeInfo{File: "testdata/sample/sample.go", Line: 31, Column: 10, EscapeType: parser.EscapesToHeap},
+			Category: categorizer.CategoryCompositeLiteral,
+		},
+	}
+
+	copies, err := Analyze([]string{"./testdata/sample"}, escapes, DefaultMinSize)
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	const pkg = "github.com/harshakonda/heapcheck/internal/copies/testdata/sample"
+	want := []categorizer.LargeCopy{
+		{Func: pkg + ".Apply", Value: "param c", Type: "sample.Config", Size: 528, CallSites: 2},
+		{Func: pkg + ".NewConfig", Value: "result 0", Type: "sample.Config", Size: 528, CallSites: 2},
+		{Func: "(" + pkg + ".Config).Describe", Value: "receiver", Type: "sample.Config", Size: 528, CallSites: 1},
+	}
+	if len(copies) != len(want) {
+		t.Fatalf("copies = %+v, want %d entries", copies, len(want))
+	}
+	for i, w := range want {
+		got := copies[i]
+		got.Position = ""
+		if got != w {
+			t.Errorf("copies[%d] = %+v, want %+v", i, got, w)
+		}
+		if copies[i].Position == "" {
+			t.Errorf("copies[%d].Position is empty", i)
+		}
+	}
+
+	if copies, err := Analyze([]string{"./testdata/sample"}, escapes, 1024); err != nil || len(copies) != 0 {
+		t.Errorf("Analyze(min 1KB) = %+v, %v; want none", copies, err)
+	}
+}
+
+func TestAnalyzeNoEscapes(t *testing.T) {
+	copies, err := Analyze([]string{"./does-not-exist"}, nil, DefaultMinSize)
+	if err != nil || copies != nil {
+		t.Errorf("Analyze(no escapes) = %v, %v, want nil, nil", copies, err)
+	}
+}
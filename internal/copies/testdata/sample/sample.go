@@ -0,0 +1,35 @@
+package sample
+
+type Config struct {
+	Name string
+	Buf  [512]byte
+}
+
+type Small struct{ A, B int }
+
+func NewConfig(name string) Config { return Config{Name: name} }
+
+func Apply(c Config, s Small) int { return len(c.Name) + s.A }
+
+func (c Config) Describe() string { return c.Name }
+
+func (c *Config) Ptr() string { return c.Name }
+
+func Sum(cs ...Config) int { return len(cs) }
+
+var sink *Small
+
+func Use(names []string) int {
+	n := 0
+	for _, name := range names {
+		c := NewConfig(name)
+		n += Apply(c, Small{})
+		_ = c.Describe()
+		_ = c.Ptr()
+		n += Sum(c)
+		sink = &Small{A: n}
+	}
+	return n + Apply(NewConfig(""), Small{})
+}
+
+func Quiet() Config { return NewConfig("quiet") }
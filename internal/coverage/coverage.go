@@ -0,0 +1,184 @@
+// Package coverage maps escapes onto a `go test -coverprofile` profile,
+// marking each escape as covered or uncovered by tests.
+package coverage
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+// Block is one profile line: a source range, its statement count and how
+// often it ran
+type Block struct {
+	StartLine, StartCol int
+	EndLine, EndCol     int
+	NumStmt             int
+	Count               int
+}
+
+// Profile is a parsed coverage profile
+type Profile struct {
+	Mode string
+
+	// Files maps profile file names (import path + file name) to blocks
+	Files map[string][]Block
+
+	// dirs maps profile file names to files on disk, once resolved
+	dirs map[string]string
+}
+
+// github.com/x/y/file.go:10.2,12.16 1 1
+var blockRe = regexp.MustCompile(`^(.+):(\d+)\.(\d+),(\d+)\.(\d+) (\d+) (\d+)$`)
+
+// ParseProfile reads a coverage profile
+func ParseProfile(path string) (*Profile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading coverage profile: %w", err)
+	}
+	defer f.Close()
+
+	p := &Profile{Files: make(map[string][]Block)}
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if mode, ok := strings.CutPrefix(line, "mode: "); ok {
+			p.Mode = mode
+			continue
+		}
+
+		m := blockRe.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("%s:%d: invalid coverage line %q", path, lineNum, line)
+		}
+		var n [6]int
+		for i := range n {
+			n[i], _ = strconv.Atoi(m[i+2])
+		}
+		p.Files[m[1]] = append(p.Files[m[1]], Block{
+			StartLine: n[0], StartCol: n[1],
+			EndLine: n[2], EndCol: n[3],
+			NumStmt: n[4], Count: n[5],
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading coverage profile %s: %w", path, err)
+	}
+	if p.Mode == "" {
+		return nil, fmt.Errorf("%s: not a coverage profile (missing mode line)", path)
+	}
+	return p, nil
+}
+
+// Resolve maps profile file names to absolute paths by asking go list
+// for the directory of each package. Files whose package cannot be
+// listed are matched by path suffix instead.
+func (p *Profile) Resolve() {
+	pkgs := make(map[string]bool)
+	for name := range p.Files {
+		pkgs[path.Dir(name)] = true
+	}
+	args := []string{"list", "-e", "-f", "{{.ImportPath}}\t{{.Dir}}"}
+	for pkg := range pkgs {
+		args = append(args, pkg)
+	}
+
+	dirs := make(map[string]string)
+	var stdout bytes.Buffer
+	cmd := exec.Command("go", args...)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err == nil {
+		for _, line := range strings.Split(stdout.String(), "\n") {
+			pkg, dir, ok := strings.Cut(line, "\t")
+			if ok && dir != "" {
+				dirs[pkg] = dir
+			}
+		}
+	}
+
+	p.dirs = make(map[string]string)
+	for name := range p.Files {
+		if dir, ok := dirs[path.Dir(name)]; ok {
+			p.dirs[filepath.Join(dir, path.Base(name))] = name
+		}
+	}
+}
+
+// blocks returns the profile blocks of a source file, looked up by
+// absolute path, then by path suffix
+func (p *Profile) blocks(file string) []Block {
+	if abs, err := filepath.Abs(file); err == nil {
+		if name, ok := p.dirs[abs]; ok {
+			return p.Files[name]
+		}
+	}
+	suffix := "/" + strings.TrimPrefix(filepath.ToSlash(filepath.Clean(file)), "./")
+	for name, blocks := range p.Files {
+		if strings.HasSuffix("/"+name, suffix) {
+			return blocks
+		}
+	}
+	return nil
+}
+
+// Status returns CoverageCovered or CoverageUncovered for a source line,
+// or "" if the profile has no statement there. A line outside any block,
+// such as a function signature reporting a leaking parameter, takes the
+// status of the block starting on the next line.
+func (p *Profile) Status(file string, line int) string {
+	blocks := p.blocks(file)
+	found, covered := false, false
+	for _, b := range blocks {
+		if line >= b.StartLine && line <= b.EndLine {
+			found = true
+			covered = covered || b.Count > 0
+		}
+	}
+	if !found {
+		for _, b := range blocks {
+			if b.StartLine == line+1 {
+				found = true
+				covered = covered || b.Count > 0
+			}
+		}
+	}
+
+	switch {
+	case !found:
+		return ""
+	case covered:
+		return categorizer.CoverageCovered
+	default:
+		return categorizer.CoverageUncovered
+	}
+}
+
+// Annotate sets the coverage status of each escape and the covered and
+// uncovered counts in the summary
+func Annotate(results *categorizer.Results, p *Profile) {
+	for i := range results.Escapes {
+		e := &results.Escapes[i]
+		e.Coverage = p.Status(e.Info.File, e.Info.Line)
+		switch e.Coverage {
+		case categorizer.CoverageCovered:
+			results.Summary.CoveredEscapes++
+		case categorizer.CoverageUncovered:
+			results.Summary.UncoveredEscapes++
+		}
+	}
+}
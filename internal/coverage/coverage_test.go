@@ -0,0 +1,115 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+const sampleProfile = `mode: set
+example.com/app/server/server.go:10.2,12.16 2 1
+example.com/app/server/server.go:15.2,20.3 4 0
+example.com/app/server/server.go:18.3,18.20 1 1
+`
+
+func writeProfile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "coverage.out")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseProfile(t *testing.T) {
+	p, err := ParseProfile(writeProfile(t, sampleProfile))
+	if err != nil {
+		t.Fatalf("ParseProfile: %v", err)
+	}
+	if p.Mode != "set" {
+		t.Errorf("Mode = %q, want %q", p.Mode, "set")
+	}
+	blocks := p.Files["example.com/app/server/server.go"]
+	if len(blocks) != 3 {
+		t.Fatalf("blocks = %d, want 3", len(blocks))
+	}
+	want := Block{StartLine: 10, StartCol: 2, EndLine: 12, EndCol: 16, NumStmt: 2, Count: 1}
+	if blocks[0] != want {
+		t.Errorf("blocks[0] = %+v, want %+v", blocks[0], want)
+	}
+}
+
+func TestParseProfileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"missing mode", "example.com/a.go:1.1,2.2 1 1\n"},
+		{"bad line", "mode: set\nnot a block\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseProfile(writeProfile(t, tt.content)); err == nil {
+				t.Error("ParseProfile() = nil error, want error")
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	p, err := ParseProfile(writeProfile(t, sampleProfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file string
+		line int
+		want string
+	}{
+		{"server/server.go", 11, categorizer.CoverageCovered},
+		{"./server/server.go", 16, categorizer.CoverageUncovered},
+		{"server/server.go", 18, categorizer.CoverageCovered},   // nested block ran
+		{"server/server.go", 9, categorizer.CoverageCovered},    // signature line above a block
+		{"server/server.go", 14, categorizer.CoverageUncovered}, // signature line above an unexecuted block
+		{"server/server.go", 30, ""},
+		{"client/client.go", 11, ""},
+	}
+	for _, tt := range tests {
+		if got := p.Status(tt.file, tt.line); got != tt.want {
+			t.Errorf("Status(%q, %d) = %q, want %q", tt.file, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	p, err := ParseProfile(writeProfile(t, sampleProfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := &categorizer.Results{
+		Escapes: []categorizer.CategorizedEscape{
+			{Info: parser.EscapeInfo{File: "server/server.go", Line: 11}},
+			{Info: parser.EscapeInfo{File: "server/server.go", Line: 16}},
+			{Info: parser.EscapeInfo{File: "server/other.go", Line: 11}},
+		},
+	}
+
+	Annotate(results, p)
+
+	if results.Summary.CoveredEscapes != 1 {
+		t.Errorf("CoveredEscapes = %d, want 1", results.Summary.CoveredEscapes)
+	}
+	if results.Summary.UncoveredEscapes != 1 {
+		t.Errorf("UncoveredEscapes = %d, want 1", results.Summary.UncoveredEscapes)
+	}
+	want := []string{categorizer.CoverageCovered, categorizer.CoverageUncovered, ""}
+	for i, w := range want {
+		if got := results.Escapes[i].Coverage; got != w {
+			t.Errorf("Escapes[%d].Coverage = %q, want %q", i, got, w)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Package diff compares two heapcheck JSON result files, listing new and
+// resolved escapes and the change in each category.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/harshakonda/heapcheck/internal/baseline"
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+// CategoryDelta is the escape count of a category in both runs
+type CategoryDelta struct {
+	Category categorizer.Category `json:"category"`
+	Old      int                  `json:"old"`
+	New      int                  `json:"new"`
+}
+
+// Delta returns New - Old
+func (c CategoryDelta) Delta() int {
+	return c.New - c.Old
+}
+
+// Diff is the difference between two runs
+type Diff struct {
+	OldFile    string                          `json:"oldFile"`
+	NewFile    string                          `json:"newFile"`
+	OldTotal   int                             `json:"oldTotal"`
+	NewTotal   int                             `json:"newTotal"`
+	Added      []categorizer.CategorizedEscape `json:"added"`
+	Resolved   []categorizer.CategorizedEscape `json:"resolved"`
+	Unchanged  int                             `json:"unchanged"`
+	Categories []CategoryDelta                 `json:"categories"`
+}
+
+// Load reads a results file written by --format=json
+func Load(path string) (*categorizer.Results, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading results: %w", err)
+	}
+	var results categorizer.Results
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("parsing results %s: %w", path, err)
+	}
+	return &results, nil
+}
+
+// Compare matches escapes between runs by file, variable and category,
+// so escapes that only moved to another line count as unchanged
+func Compare(old, cur *categorizer.Results) *Diff {
+	d := &Diff{
+		OldTotal: len(old.Escapes),
+		NewTotal: len(cur.Escapes),
+		Added:    make([]categorizer.CategorizedEscape, 0),
+		Resolved: make([]categorizer.CategorizedEscape, 0),
+	}
+
+	remaining := make(map[string]int, len(old.Escapes))
+	for _, e := range old.Escapes {
+		remaining[baseline.KeyOf(e)]++
+	}
+	for _, e := range cur.Escapes {
+		key := baseline.KeyOf(e)
+		if remaining[key] > 0 {
+			remaining[key]--
+			d.Unchanged++
+		} else {
+			d.Added = append(d.Added, e)
+		}
+	}
+	for i := len(old.Escapes) - 1; i >= 0; i-- {
+		e := old.Escapes[i]
+		key := baseline.KeyOf(e)
+		if remaining[key] > 0 {
+			remaining[key]--
+			d.Resolved = append(d.Resolved, e)
+		}
+	}
+	categorizer.SortEscapes(d.Added, categorizer.OrderPosition)
+	categorizer.SortEscapes(d.Resolved, categorizer.OrderPosition)
+
+	d.Categories = categoryDeltas(old.Escapes, cur.Escapes)
+	return d
+}
+
+// PackageDiff is the difference between two runs in one package
+type PackageDiff struct {
+	Package string `json:"package"`
+	*Diff
+}
+
+// ByPackage compares two runs package by package, by the import path or
+// directory of each escape, for the packages with escapes in either run
+// in order of their names
+func ByPackage(old, cur *categorizer.Results) []PackageDiff {
+	byPkg := make(map[string][2]*categorizer.Results)
+	add := func(escapes []categorizer.CategorizedEscape, run int) {
+		for _, e := range escapes {
+			pkg := e.Info.PackageOrDir()
+			runs := byPkg[pkg]
+			if runs[0] == nil {
+				runs = [2]*categorizer.Results{{}, {}}
+				byPkg[pkg] = runs
+			}
+			runs[run].Escapes = append(runs[run].Escapes, e)
+		}
+	}
+	add(old.Escapes, 0)
+	add(cur.Escapes, 1)
+
+	diffs := make([]PackageDiff, 0, len(byPkg))
+	for pkg, runs := range byPkg {
+		diffs = append(diffs, PackageDiff{Package: pkg, Diff: Compare(runs[0], runs[1])})
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Package < diffs[j].Package })
+	return diffs
+}
+
+// categoryDeltas counts escapes per category in both runs, largest
+// change first
+func categoryDeltas(old, cur []categorizer.CategorizedEscape) []CategoryDelta {
+	byCat := make(map[categorizer.Category]*CategoryDelta)
+	get := func(cat categorizer.Category) *CategoryDelta {
+		if byCat[cat] == nil {
+			byCat[cat] = &CategoryDelta{Category: cat}
+		}
+		return byCat[cat]
+	}
+	for _, e := range old {
+		get(e.Category).Old++
+	}
+	for _, e := range cur {
+		get(e.Category).New++
+	}
+
+	deltas := make([]CategoryDelta, 0, len(byCat))
+	for _, c := range byCat {
+		deltas = append(deltas, *c)
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		di, dj := abs(deltas[i].Delta()), abs(deltas[j].Delta())
+		if di != dj {
+			return di > dj
+		}
+		return deltas[i].Category < deltas[j].Category
+	})
+	return deltas
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,124 @@
+package diff
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func escape(file string, line int, variable string, cat categorizer.Category) categorizer.CategorizedEscape {
+	return categorizer.CategorizedEscape{
+		Info:     parser.EscapeInfo{File: file, Line: line, Variable: variable, EscapeType: parser.EscapesToHeap},
+		Category: cat,
+	}
+}
+
+func TestCompare(t *testing.T) {
+	old := &categorizer.Results{Escapes: []categorizer.CategorizedEscape{
+		escape("a.go", 10, "x", categorizer.CategoryReturnPointer),
+		escape("a.go", 20, "y", categorizer.CategoryInterfaceBoxing),
+		escape("b.go", 5, "buf", categorizer.CategorySliceGrow),
+	}}
+	cur := &categorizer.Results{Escapes: []categorizer.CategorizedEscape{
+		escape("a.go", 12, "x", categorizer.CategoryReturnPointer), // moved down two lines
+		escape("b.go", 5, "buf", categorizer.CategorySliceGrow),
+		escape("b.go", 9, "buf", categorizer.CategorySliceGrow), // second escape with the same key
+		escape("c.go", 1, "z", categorizer.CategoryFmtCall),
+	}}
+
+	d := Compare(old, cur)
+
+	if d.Unchanged != 2 {
+		t.Errorf("Unchanged = %d, want 2", d.Unchanged)
+	}
+	if len(d.Added) != 2 {
+		t.Errorf("Added = %+v, want 2 escapes", d.Added)
+	}
+	if len(d.Resolved) != 1 || d.Resolved[0].Info.Variable != "y" {
+		t.Errorf("Resolved = %+v, want y", d.Resolved)
+	}
+	if d.OldTotal != 3 || d.NewTotal != 4 {
+		t.Errorf("totals = %d, %d, want 3, 4", d.OldTotal, d.NewTotal)
+	}
+
+	deltas := make(map[categorizer.Category]int)
+	for _, c := range d.Categories {
+		deltas[c.Category] = c.Delta()
+	}
+	want := map[categorizer.Category]int{
+		categorizer.CategoryReturnPointer:   0,
+		categorizer.CategoryInterfaceBoxing: -1,
+		categorizer.CategorySliceGrow:       1,
+		categorizer.CategoryFmtCall:         1,
+	}
+	for cat, delta := range want {
+		if deltas[cat] != delta {
+			t.Errorf("%s delta = %d, want %d", cat, deltas[cat], delta)
+		}
+	}
+	if d.Categories[len(d.Categories)-1].Category != categorizer.CategoryReturnPointer {
+		t.Errorf("unchanged category should sort last, got %+v", d.Categories)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.json")
+	results := categorizer.Categorize([]parser.EscapeInfo{
+		{File: "a.go", Line: 1, Variable: "x", EscapeType: parser.MovedToHeap, Reason: "moved to heap: x"},
+	})
+	data, _ := json.Marshal(results)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(got.Escapes) != 1 || got.Escapes[0].Info.Variable != "x" {
+		t.Errorf("Load() = %+v", got.Escapes)
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Load() expected error for invalid JSON")
+	}
+}
+
+func TestByPackage(t *testing.T) {
+	old := &categorizer.Results{Escapes: []categorizer.CategorizedEscape{
+		escape("a/a.go", 10, "x", categorizer.CategoryReturnPointer),
+		escape("b/b.go", 5, "buf", categorizer.CategorySliceGrow),
+	}}
+	cur := &categorizer.Results{Escapes: []categorizer.CategorizedEscape{
+		escape("a/a.go", 10, "x", categorizer.CategoryReturnPointer),
+		escape("c/c.go", 1, "z", categorizer.CategoryFmtCall),
+	}}
+	cur.Escapes[1].Info.Package = "example.com/c"
+
+	diffs := ByPackage(old, cur)
+
+	want := []struct {
+		pkg                        string
+		added, resolved, unchanged int
+	}{
+		{"a", 0, 0, 1},
+		{"b", 0, 1, 0},
+		{"example.com/c", 1, 0, 0},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("ByPackage() = %d packages, want %d", len(diffs), len(want))
+	}
+	for i, w := range want {
+		d := diffs[i]
+		if d.Package != w.pkg || len(d.Added) != w.added || len(d.Resolved) != w.resolved || d.Unchanged != w.unchanged {
+			t.Errorf("diffs[%d] = %s +%d -%d =%d, want %s +%d -%d =%d", i, d.Package, len(d.Added), len(d.Resolved), d.Unchanged, w.pkg, w.added, w.resolved, w.unchanged)
+		}
+	}
+}
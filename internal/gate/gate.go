@@ -0,0 +1,255 @@
+// Package gate evaluates per-category escape thresholds into a
+// pass/warn/fail result.
+//
+// A rule is a comma-separated list of actions, each optionally followed
+// by a threshold the escape count must exceed:
+//
+//	fail>10         fail when there are more than 10 escapes
+//	warn            warn on any escape
+//	warn>5, fail>20 warn above 5, fail above 20
+//	off             never warn or fail
+package gate
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+// disabled marks an action without a threshold
+const disabled = -1
+
+// Rule holds the warn and fail thresholds of a category
+type Rule struct {
+	Warn int
+	Fail int
+}
+
+// ParseRule parses a rule such as "warn>5, fail>20"
+func ParseRule(spec string) (Rule, error) {
+	rule := Rule{Warn: disabled, Fail: disabled}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		action, threshold, hasThreshold := strings.Cut(part, ">")
+		action = strings.TrimSpace(action)
+
+		limit := 0
+		if hasThreshold {
+			n, err := strconv.Atoi(strings.TrimSpace(threshold))
+			if err != nil || n < 0 {
+				return Rule{}, fmt.Errorf("invalid threshold in %q", part)
+			}
+			limit = n
+		}
+
+		switch action {
+		case categorizer.GateWarn:
+			rule.Warn = limit
+		case categorizer.GateFail:
+			rule.Fail = limit
+		case "off":
+			if hasThreshold {
+				return Rule{}, fmt.Errorf("invalid rule %q: off takes no threshold", part)
+			}
+		default:
+			return Rule{}, fmt.Errorf("invalid rule %q, want warn, fail or off with an optional >N", part)
+		}
+	}
+	return rule, nil
+}
+
+// Status returns the gate status for count escapes
+func (r Rule) Status(count int) string {
+	switch {
+	case r.Fail != disabled && count > r.Fail:
+		return categorizer.GateFail
+	case r.Warn != disabled && count > r.Warn:
+		return categorizer.GateWarn
+	default:
+		return categorizer.GatePass
+	}
+}
+
+// Threshold returns the limit that decides the status of count escapes:
+// the exceeded limit, else the nearest one, or -1 if the rule is off
+func (r Rule) Threshold(count int) int {
+	switch {
+	case r.Fail != disabled && count > r.Fail:
+		return r.Fail
+	case r.Warn != disabled:
+		return r.Warn
+	default:
+		return r.Fail
+	}
+}
+
+// String formats the rule in its config syntax
+func (r Rule) String() string {
+	var parts []string
+	if r.Warn != disabled {
+		parts = append(parts, action(categorizer.GateWarn, r.Warn))
+	}
+	if r.Fail != disabled {
+		parts = append(parts, action(categorizer.GateFail, r.Fail))
+	}
+	if len(parts) == 0 {
+		return "off"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func action(name string, limit int) string {
+	if limit == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s>%d", name, limit)
+}
+
+// Evaluate applies rules to the category counts of results. It returns
+// nil when there are no rules.
+func Evaluate(results *categorizer.Results, rules map[categorizer.Category]Rule) *categorizer.GateResult {
+	return EvaluateScoped(results, rules, nil)
+}
+
+// Scope holds the rules of a nested config, for the escapes in the
+// directory tree below Dir
+type Scope struct {
+	Dir   string
+	Rules map[categorizer.Category]Rule
+}
+
+// EvaluateScoped is Evaluate with the rules of nested configs. An escape
+// counts toward the deepest scope around its file with a rule for its
+// category, or else toward rules, so a scope's budget covers its own
+// subtree and the categories it leaves out fall through to the configs
+// above it. It returns nil when there are no rules.
+func EvaluateScoped(results *categorizer.Results, rules map[categorizer.Category]Rule, scopes []Scope) *categorizer.GateResult {
+	counts := make([]map[categorizer.Category]int, len(scopes))
+	rootCounts := results.ByCategory
+	if len(scopes) > 0 {
+		rootCounts = maps.Clone(results.ByCategory)
+		dirs := make([]string, len(scopes))
+		for i, s := range scopes {
+			counts[i] = make(map[categorizer.Category]int)
+			dirs[i], _ = filepath.Abs(s.Dir)
+		}
+		for _, e := range results.Escapes {
+			if i := innermost(scopes, dirs, e); i >= 0 {
+				counts[i][e.Category]++
+				rootCounts[e.Category]--
+			}
+		}
+	}
+
+	gr := &categorizer.GateResult{Status: categorizer.GatePass}
+	gr.Categories = appendGates(gr.Categories, "", rules, rootCounts)
+	for i, s := range scopes {
+		gr.Categories = appendGates(gr.Categories, s.Dir, s.Rules, counts[i])
+	}
+	if len(gr.Categories) == 0 && len(rules) == 0 {
+		return nil
+	}
+	for _, c := range gr.Categories {
+		if severity(c.Status) > severity(gr.Status) {
+			gr.Status = c.Status
+		}
+	}
+	return gr
+}
+
+// innermost returns the index of the deepest scope whose directory
+// contains e's file and that has a rule for e's category, or -1
+func innermost(scopes []Scope, dirs []string, e categorizer.CategorizedEscape) int {
+	file, err := filepath.Abs(e.Info.File)
+	if err != nil {
+		return -1
+	}
+	best := -1
+	for i, s := range scopes {
+		if _, ok := s.Rules[e.Category]; !ok || !strings.HasPrefix(file, dirs[i]+string(filepath.Separator)) {
+			continue
+		}
+		if best < 0 || len(dirs[i]) > len(dirs[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+// appendGates appends the gates of rules applied to counts, sorted by
+// category
+func appendGates(gates []categorizer.CategoryGate, dir string, rules map[categorizer.Category]Rule, counts map[categorizer.Category]int) []categorizer.CategoryGate {
+	cats := make([]categorizer.Category, 0, len(rules))
+	for cat := range rules {
+		cats = append(cats, cat)
+	}
+	sort.Slice(cats, func(i, j int) bool { return cats[i] < cats[j] })
+
+	for _, cat := range cats {
+		rule := rules[cat]
+		count := counts[cat]
+		threshold := rule.Threshold(count)
+		margin := 0
+		if threshold != disabled {
+			margin = count - threshold
+		}
+		gates = append(gates, categorizer.CategoryGate{
+			Category:  cat,
+			Dir:       dir,
+			Count:     count,
+			Rule:      rule.String(),
+			Status:    rule.Status(count),
+			Threshold: threshold,
+			Margin:    margin,
+		})
+	}
+	return gates
+}
+
+func severity(status string) int {
+	switch status {
+	case categorizer.GateFail:
+		return 2
+	case categorizer.GateWarn:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// File is the content of a --gate-output file
+type File struct {
+	Status        string                     `json:"status"`
+	HeapAllocated int                        `json:"heapAllocated"`
+	Categories    []categorizer.CategoryGate `json:"categories"`
+}
+
+// WriteFile writes the gate result gr for results to path. Without gate
+// rules (gr is nil) the result is a pass with no categories.
+func WriteFile(path string, results *categorizer.Results, gr *categorizer.GateResult) error {
+	f := File{
+		Status:        categorizer.GatePass,
+		HeapAllocated: results.Summary.HeapAllocated,
+		Categories:    []categorizer.CategoryGate{},
+	}
+	if gr != nil {
+		f.Status = gr.Status
+		f.Categories = gr.Categories
+	}
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing gate output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package gate
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Rule
+		wantErr bool
+	}{
+		{"fail>10", Rule{Warn: disabled, Fail: 10}, false},
+		{"warn", Rule{Warn: 0, Fail: disabled}, false},
+		{"warn>5, fail>20", Rule{Warn: 5, Fail: 20}, false},
+		{"fail", Rule{Warn: disabled, Fail: 0}, false},
+		{"off", Rule{Warn: disabled, Fail: disabled}, false},
+		{"fail>x", Rule{}, true},
+		{"fail>-1", Rule{}, true},
+		{"error>3", Rule{}, true},
+		{"off>3", Rule{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRule(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRule(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRule(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestRuleStatus(t *testing.T) {
+	rule := Rule{Warn: 5, Fail: 20}
+
+	tests := []struct {
+		count int
+		want  string
+	}{
+		{0, categorizer.GatePass},
+		{5, categorizer.GatePass},
+		{6, categorizer.GateWarn},
+		{20, categorizer.GateWarn},
+		{21, categorizer.GateFail},
+	}
+
+	for _, tt := range tests {
+		if got := rule.Status(tt.count); got != tt.want {
+			t.Errorf("Status(%d) = %q, want %q", tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestRuleString(t *testing.T) {
+	for _, spec := range []string{"fail>10", "warn", "warn>5, fail>20", "off"} {
+		rule, err := ParseRule(spec)
+		if err != nil {
+			t.Fatalf("ParseRule(%q) error: %v", spec, err)
+		}
+		if got := rule.String(); got != spec {
+			t.Errorf("ParseRule(%q).String() = %q", spec, got)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	results := &categorizer.Results{
+		ByCategory: map[categorizer.Category]int{
+			categorizer.CategoryInterfaceBoxing: 12,
+			categorizer.CategoryFmtCall:         1,
+		},
+	}
+
+	if got := Evaluate(results, nil); got != nil {
+		t.Errorf("Evaluate() with no rules = %+v, want nil", got)
+	}
+
+	gr := Evaluate(results, map[categorizer.Category]Rule{
+		categorizer.CategoryInterfaceBoxing: {Warn: disabled, Fail: 10},
+		categorizer.CategoryFmtCall:         {Warn: 0, Fail: disabled},
+		categorizer.CategorySliceGrow:       {Warn: 0, Fail: 0},
+	})
+
+	if gr.Status != categorizer.GateFail {
+		t.Errorf("Status = %q, want fail", gr.Status)
+	}
+	want := map[categorizer.Category]string{
+		categorizer.CategoryInterfaceBoxing: categorizer.GateFail,
+		categorizer.CategoryFmtCall:         categorizer.GateWarn,
+		categorizer.CategorySliceGrow:       categorizer.GatePass,
+	}
+	if len(gr.Categories) != len(want) {
+		t.Fatalf("Categories = %d, want %d", len(gr.Categories), len(want))
+	}
+	for _, c := range gr.Categories {
+		if c.Status != want[c.Category] {
+			t.Errorf("%s status = %q, want %q", c.Category, c.Status, want[c.Category])
+		}
+	}
+}
+
+func TestEvaluateMargin(t *testing.T) {
+	results := &categorizer.Results{
+		ByCategory: map[categorizer.Category]int{
+			categorizer.CategoryInterfaceBoxing: 12,
+			categorizer.CategoryFmtCall:         3,
+		},
+	}
+
+	gr := Evaluate(results, map[categorizer.Category]Rule{
+		categorizer.CategoryInterfaceBoxing: {Warn: 5, Fail: 20},
+		categorizer.CategoryFmtCall:         {Warn: disabled, Fail: 10},
+		categorizer.CategorySliceGrow:       {Warn: disabled, Fail: disabled},
+	})
+
+	want := map[categorizer.Category][2]int{
+		categorizer.CategoryInterfaceBoxing: {5, 7},
+		categorizer.CategoryFmtCall:         {10, -7},
+		categorizer.CategorySliceGrow:       {-1, 0},
+	}
+	for _, c := range gr.Categories {
+		if got := [2]int{c.Threshold, c.Margin}; got != want[c.Category] {
+			t.Errorf("%s threshold, margin = %v, want %v", c.Category, got, want[c.Category])
+		}
+	}
+}
+
+func TestEvaluateScoped(t *testing.T) {
+	dir := t.TempDir()
+	escape := func(file string, cat categorizer.Category) categorizer.CategorizedEscape {
+		return categorizer.CategorizedEscape{Info: parser.EscapeInfo{File: filepath.Join(dir, file)}, Category: cat}
+	}
+	results := &categorizer.Results{
+		ByCategory: map[categorizer.Category]int{
+			categorizer.CategoryInterfaceBoxing: 4,
+			categorizer.CategoryFmtCall:         2,
+		},
+		Escapes: []categorizer.CategorizedEscape{
+			escape("main.go", categorizer.CategoryInterfaceBoxing),
+			escape("legacy/old.go", categorizer.CategoryInterfaceBoxing),
+			escape("legacy/old.go", categorizer.CategoryInterfaceBoxing),
+			escape("legacy/v1/older.go", categorizer.CategoryInterfaceBoxing),
+			escape("legacy/old.go", categorizer.CategoryFmtCall),
+			escape("legacyish/new.go", categorizer.CategoryFmtCall),
+		},
+	}
+	rules := map[categorizer.Category]Rule{
+		categorizer.CategoryInterfaceBoxing: {Warn: disabled, Fail: 0},
+		categorizer.CategoryFmtCall:         {Warn: disabled, Fail: 1},
+	}
+	legacy := filepath.Join(dir, "legacy")
+	scopes := []Scope{
+		{Dir: legacy, Rules: map[categorizer.Category]Rule{categorizer.CategoryInterfaceBoxing: {Warn: 2, Fail: 10}}},
+		{Dir: filepath.Join(legacy, "v1"), Rules: map[categorizer.Category]Rule{categorizer.CategoryInterfaceBoxing: {Warn: disabled, Fail: disabled}}},
+	}
+
+	gr := EvaluateScoped(results, rules, scopes)
+	type key struct {
+		dir string
+		cat categorizer.Category
+	}
+	got := make(map[key]categorizer.CategoryGate)
+	for _, c := range gr.Categories {
+		got[key{c.Dir, c.Category}] = c
+	}
+	want := []struct {
+		key    key
+		count  int
+		status string
+	}{
+		// legacy's escapes count toward its own boxing budget, but fall
+		// through to the root's fmt-call rule
+		{key{"", categorizer.CategoryInterfaceBoxing}, 1, categorizer.GateFail},
+		{key{"", categorizer.CategoryFmtCall}, 2, categorizer.GateFail},
+		{key{legacy, categorizer.CategoryInterfaceBoxing}, 2, categorizer.GatePass},
+		{key{filepath.Join(legacy, "v1"), categorizer.CategoryInterfaceBoxing}, 1, categorizer.GatePass},
+	}
+	if len(gr.Categories) != len(want) {
+		t.Fatalf("Categories = %+v, want %d", gr.Categories, len(want))
+	}
+	for _, w := range want {
+		c := got[w.key]
+		if c.Count != w.count || c.Status != w.status {
+			t.Errorf("%v: count, status = %d, %q, want %d, %q", w.key, c.Count, c.Status, w.count, w.status)
+		}
+	}
+	if gr.Status != categorizer.GateFail {
+		t.Errorf("Status = %q, want fail", gr.Status)
+	}
+	if results.ByCategory[categorizer.CategoryInterfaceBoxing] != 4 {
+		t.Error("EvaluateScoped modified the category counts")
+	}
+
+	// Nested rules alone gate the run
+	if gr := EvaluateScoped(results, nil, scopes[:1]); gr == nil || gr.Status != categorizer.GateWarn || gr.Categories[0].Count != 3 {
+		t.Errorf("EvaluateScoped() with nested rules only = %+v", gr)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gate.json")
+	results := &categorizer.Results{
+		Summary: categorizer.Summary{HeapAllocated: 4},
+	}
+	gr := &categorizer.GateResult{
+		Status: categorizer.GateWarn,
+		Categories: []categorizer.CategoryGate{
+			{Category: categorizer.CategoryFmtCall, Count: 4, Rule: "warn", Status: categorizer.GateWarn, Margin: 4},
+		},
+	}
+
+	if err := WriteFile(path, results, gr); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatalf("invalid gate JSON: %v", err)
+	}
+	if f.Status != categorizer.GateWarn || f.HeapAllocated != 4 || len(f.Categories) != 1 {
+		t.Errorf("gate file = %+v", f)
+	}
+
+	// Without rules the gate passes
+	if err := WriteFile(path, &categorizer.Results{}, nil); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if err := json.Unmarshal(data, &f); err != nil || f.Status != categorizer.GatePass {
+		t.Errorf("gate file without rules = %s", data)
+	}
+}
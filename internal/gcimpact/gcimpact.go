@@ -0,0 +1,376 @@
+// Package gcimpact estimates how many bytes each heap escape allocates
+// per call of its enclosing function, from the size of the escaping type
+// and the loops around the allocation, so reports can rank escapes by GC
+// pressure rather than by how often a category occurs.
+package gcimpact
+
+import (
+	"go/ast"
+	"go/build"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+	"github.com/harshakonda/heapcheck/internal/typecheck"
+)
+
+// assumedIterations is the iteration count assumed for a loop whose bound
+// is not a constant
+const assumedIterations = 10
+
+// Annotate type-checks the packages matching patterns and sets the Impact
+// of each heap escape whose allocation size can be determined
+func Annotate(patterns []string, escapes []categorizer.CategorizedEscape) error {
+	byFile := make(map[string][]int)
+	for i, e := range escapes {
+		if !allocates(e.Info.EscapeType) {
+			continue
+		}
+		if abs, err := filepath.Abs(e.Info.File); err == nil {
+			byFile[abs] = append(byFile[abs], i)
+		}
+	}
+	if len(byFile) == 0 {
+		return nil
+	}
+
+	files := make(map[string]bool, len(byFile))
+	for path := range byFile {
+		files[path] = true
+	}
+	fset := token.NewFileSet()
+	pkgs, err := typecheck.Check(fset, patterns, files)
+	if err != nil {
+		return err
+	}
+
+	e := estimator{fset: fset, sizes: types.SizesFor("gc", build.Default.GOARCH)}
+	for _, p := range pkgs {
+		for _, f := range p.Files {
+			path := fset.Position(f.Pos()).Filename
+			for _, i := range byFile[path] {
+				escapes[i].Impact = e.estimate(f, p.Info, escapes[i].Info)
+			}
+		}
+	}
+	return nil
+}
+
+// Sort orders escapes by estimated bytes per call, largest first, with
+// escapes of unknown size last in their original order
+func Sort(escapes []categorizer.CategorizedEscape) {
+	categorizer.SortEscapes(escapes, categorizer.OrderBytes)
+}
+
+// allocates reports whether an escape type is a heap allocation; leaking
+// parameters only make their callers' arguments escape
+func allocates(t parser.EscapeType) bool {
+	return t == parser.MovedToHeap || t == parser.EscapesToHeap
+}
+
+type estimator struct {
+	fset  *token.FileSet
+	sizes types.Sizes
+}
+
+// estimate returns the impact of the escape in f, or nil when the
+// allocation or its size cannot be identified
+func (e estimator) estimate(f *ast.File, info *types.Info, esc parser.EscapeInfo) *categorizer.Impact {
+	var node ast.Node
+	var size int64
+	if esc.EscapeType == parser.MovedToHeap {
+		node, size = e.movedVar(f, info, esc)
+	} else {
+		node, size = e.escapingExpr(f, info, esc)
+	}
+	if node != nil && esc.Size > 0 {
+		// The compiler's size accounts for padding the estimate may miss
+		size = esc.Size
+	}
+	if node == nil || size <= 0 {
+		return nil
+	}
+
+	iterations, assumed := e.loopIterations(f, info, node.Pos())
+	return &categorizer.Impact{
+		Size:       size,
+		Iterations: iterations,
+		Assumed:    assumed,
+		PerCall:    size * iterations,
+	}
+}
+
+// movedVar finds the variable declared at the escape's position and
+// returns its size
+func (e estimator) movedVar(f *ast.File, info *types.Info, esc parser.EscapeInfo) (ast.Node, int64) {
+	var found *ast.Ident
+	ast.Inspect(f, func(n ast.Node) bool {
+		if found != nil {
+			return false
+		}
+		id, ok := n.(*ast.Ident)
+		if ok && id.Name == esc.Variable && e.at(id, esc) && info.Defs[id] != nil {
+			found = id
+		}
+		return true
+	})
+	if found == nil {
+		return nil, 0
+	}
+	return found, e.sizes.Sizeof(info.Defs[found].Type())
+}
+
+// escapingExpr finds the expression at the escape's position, preferring
+// the one the compiler named over the outermost, and returns the size it
+// allocates
+func (e estimator) escapingExpr(f *ast.File, info *types.Info, esc parser.EscapeInfo) (ast.Node, int64) {
+	var found ast.Expr
+	ast.Inspect(f, func(n ast.Node) bool {
+		x, ok := n.(ast.Expr)
+		if !ok || !e.at(x, esc) {
+			return true
+		}
+		if found == nil || exprString(x) == esc.Variable {
+			found = x
+		}
+		return true
+	})
+	if found == nil {
+		return nil, 0
+	}
+	return found, e.allocated(info, found)
+}
+
+// allocated returns the bytes allocated when x escapes, 0 if unknown
+func (e estimator) allocated(info *types.Info, x ast.Expr) int64 {
+	switch x := ast.Unparen(x).(type) {
+	case *ast.UnaryExpr:
+		// &T{...}
+		if lit, ok := ast.Unparen(x.X).(*ast.CompositeLit); x.Op == token.AND && ok {
+			if tv, ok := info.Types[lit]; ok {
+				return e.sizes.Sizeof(tv.Type)
+			}
+		}
+		return 0
+	case *ast.CompositeLit:
+		// The backing array of []T{...}
+		if tv, ok := info.Types[x]; ok {
+			if s, ok := tv.Type.Underlying().(*types.Slice); ok {
+				return int64(len(x.Elts)) * e.sizes.Sizeof(s.Elem())
+			}
+		}
+		return 0
+	case *ast.CallExpr:
+		if n, ok := e.builtinAlloc(info, x); ok {
+			return n
+		}
+	case *ast.FuncLit:
+		return 0 // closure sizes depend on the captured variables
+	case *ast.Ident:
+		// "x escapes to heap" at x's declaration: x itself is moved
+		if obj := info.Defs[x]; obj != nil {
+			return e.sizes.Sizeof(obj.Type())
+		}
+	}
+
+	// A value converted to an interface is copied to the heap, unless it
+	// is pointer-shaped and stored in the interface directly
+	tv, ok := info.Types[x]
+	if !ok || tv.Type == nil || types.IsInterface(tv.Type) || pointerShaped(tv.Type) {
+		return 0
+	}
+	return e.sizes.Sizeof(tv.Type)
+}
+
+// builtinAlloc returns the bytes allocated by new(T) or by make([]T, n)
+// with a constant length or capacity
+func (e estimator) builtinAlloc(info *types.Info, call *ast.CallExpr) (int64, bool) {
+	id, ok := ast.Unparen(call.Fun).(*ast.Ident)
+	if !ok {
+		return 0, false
+	}
+	if _, ok := info.Uses[id].(*types.Builtin); !ok {
+		return 0, false
+	}
+	tv, ok := info.Types[call]
+	if !ok {
+		return 0, true
+	}
+	switch id.Name {
+	case "new":
+		if p, ok := tv.Type.Underlying().(*types.Pointer); ok {
+			return e.sizes.Sizeof(p.Elem()), true
+		}
+	case "make":
+		s, ok := tv.Type.Underlying().(*types.Slice)
+		if !ok || len(call.Args) < 2 {
+			return 0, true // maps and channels grow on their own
+		}
+		n, ok := constInt(info, call.Args[len(call.Args)-1])
+		if !ok {
+			return 0, true
+		}
+		return n * e.sizes.Sizeof(s.Elem()), true
+	}
+	return 0, true // append and the like allocate an unknown amount
+}
+
+// loopIterations multiplies the iteration counts of the loops around pos
+// in its innermost function, guessing assumedIterations for loops
+// without a constant bound
+func (e estimator) loopIterations(f *ast.File, info *types.Info, pos token.Pos) (int64, bool) {
+	body := innermostFunc(f, pos)
+	if body == nil {
+		return 1, false
+	}
+
+	iterations, assumed := int64(1), false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil || n.Pos() > pos || pos >= n.End() {
+			return false
+		}
+		var (
+			loopBody *ast.BlockStmt
+			count    int64
+			known    bool
+		)
+		switch loop := n.(type) {
+		case *ast.FuncLit:
+			return false // a nested function contains pos only if it is innermost
+		case *ast.ForStmt:
+			loopBody = loop.Body
+			count, known = forCount(info, loop)
+		case *ast.RangeStmt:
+			loopBody = loop.Body
+			count, known = rangeCount(info, loop)
+		default:
+			return true
+		}
+		if pos < loopBody.Pos() || pos >= loopBody.End() {
+			return true
+		}
+		if !known {
+			count, assumed = assumedIterations, true
+		}
+		iterations *= max(count, 1)
+		return true
+	})
+	return iterations, assumed
+}
+
+// innermostFunc returns the body of the innermost function declaration
+// or literal containing pos
+func innermostFunc(f *ast.File, pos token.Pos) *ast.BlockStmt {
+	var body *ast.BlockStmt
+	ast.Inspect(f, func(n ast.Node) bool {
+		if n == nil || n.Pos() > pos || pos >= n.End() {
+			return false
+		}
+		switch fn := n.(type) {
+		case *ast.FuncDecl:
+			body = fn.Body
+		case *ast.FuncLit:
+			body = fn.Body
+		}
+		return true
+	})
+	if body != nil && (pos < body.Pos() || pos >= body.End()) {
+		return nil
+	}
+	return body
+}
+
+// forCount returns the iterations of `for i := a; i < b; i++` with
+// constant a and b
+func forCount(info *types.Info, loop *ast.ForStmt) (int64, bool) {
+	init, ok := loop.Init.(*ast.AssignStmt)
+	if !ok || len(init.Lhs) != 1 || len(init.Rhs) != 1 {
+		return 0, false
+	}
+	cond, ok := loop.Cond.(*ast.BinaryExpr)
+	if !ok {
+		return 0, false
+	}
+	v, ok1 := init.Lhs[0].(*ast.Ident)
+	c, ok2 := cond.X.(*ast.Ident)
+	if !ok1 || !ok2 || v.Name != c.Name {
+		return 0, false
+	}
+	if inc, ok := loop.Post.(*ast.IncDecStmt); !ok || inc.Tok != token.INC {
+		return 0, false
+	}
+	from, ok1 := constInt(info, init.Rhs[0])
+	to, ok2 := constInt(info, cond.Y)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	switch cond.Op {
+	case token.LSS:
+		return to - from, true
+	case token.LEQ:
+		return to - from + 1, true
+	}
+	return 0, false
+}
+
+// rangeCount returns the iterations of a range over an array, a pointer
+// to an array or a constant integer
+func rangeCount(info *types.Info, loop *ast.RangeStmt) (int64, bool) {
+	if n, ok := constInt(info, loop.X); ok {
+		return n, true
+	}
+	tv, ok := info.Types[loop.X]
+	if !ok {
+		return 0, false
+	}
+	t := tv.Type.Underlying()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem().Underlying()
+	}
+	if a, ok := t.(*types.Array); ok {
+		return a.Len(), true
+	}
+	return 0, false
+}
+
+// constInt returns the value of a constant integer expression
+func constInt(info *types.Info, x ast.Expr) (int64, bool) {
+	tv, ok := info.Types[x]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.Int {
+		return 0, false
+	}
+	return constant.Int64Val(tv.Value)
+}
+
+// pointerShaped reports whether values of t are stored in an interface
+// without allocating
+func pointerShaped(t types.Type) bool {
+	switch u := t.Underlying().(type) {
+	case *types.Pointer, *types.Map, *types.Chan, *types.Signature:
+		return true
+	case *types.Basic:
+		return u.Kind() == types.UnsafePointer
+	}
+	return false
+}
+
+// at reports whether the compiler reports n at the escape's position:
+// the start of n, or the opening parenthesis of a call
+func (e estimator) at(n ast.Node, esc parser.EscapeInfo) bool {
+	pos := n.Pos()
+	if call, ok := n.(*ast.CallExpr); ok {
+		pos = call.Lparen
+	}
+	p := e.fset.Position(pos)
+	return p.Line == esc.Line && p.Column == esc.Column
+}
+
+// exprString renders x like the compiler's escape messages, which elide
+// composite literal contents as "..." rather than "…"
+func exprString(x ast.Expr) string {
+	return strings.ReplaceAll(types.ExprString(x), "…", "...")
+}
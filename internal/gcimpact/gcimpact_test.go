@@ -0,0 +1,63 @@
+package gcimpact
+
+import (
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func escape(typ parser.EscapeType, line, col int, variable string) categorizer.CategorizedEscape {
+	return categorizer.CategorizedEscape{
+		Info: parser.EscapeInfo{File: "testdata/sample/sample.go", Line: line, Column: col, EscapeType: typ, Variable: variable},
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	escapes := []categorizer.CategorizedEscape{
+		escape(parser.EscapesToHeap, 14, 9, "&Request{...}"),
+		escape(parser.MovedToHeap, 20, 3, "r"),
+		escape(parser.EscapesToHeap, 21, 15, "append"),
+		escape(parser.EscapesToHeap, 29, 24, "new([64]byte)"),
+		escape(parser.EscapesToHeap, 35, 13, "make([]byte, 0, 4096)"),
+		escape(parser.EscapesToHeap, 39, 14, "id"),
+		escape(parser.LeakingParam, 13, 17, "id"),
+	}
+	if err := Annotate([]string{"./testdata/sample"}, escapes); err != nil {
+		t.Fatalf("Annotate: %v", err)
+	}
+
+	// Request is an int64, [8]string and []byte: 8 + 128 + 24 bytes
+	want := []*categorizer.Impact{
+		{Size: 160, Iterations: 1, PerCall: 160},
+		{Size: 160, Iterations: 10, Assumed: true, PerCall: 1600},
+		nil, // append growth is unknown
+		{Size: 64, Iterations: 4, PerCall: 256},
+		{Size: 4096, Iterations: 1, PerCall: 4096},
+		{Size: 8, Iterations: 1, PerCall: 8},
+		nil, // leaking params do not allocate
+	}
+	for i, w := range want {
+		got := escapes[i].Impact
+		switch {
+		case got == nil && w == nil:
+		case got == nil || w == nil || *got != *w:
+			t.Errorf("%s: Impact = %+v, want %+v", escapes[i].Info.Variable, got, w)
+		}
+	}
+
+	Sort(escapes)
+	order := []string{"make([]byte, 0, 4096)", "r", "new([64]byte)", "&Request{...}", "id", "append", "id"}
+	for i, v := range order {
+		if escapes[i].Info.Variable != v {
+			t.Errorf("sorted[%d] = %s, want %s", i, escapes[i].Info.Variable, v)
+		}
+	}
+}
+
+func TestAnnotateNoAllocations(t *testing.T) {
+	escapes := []categorizer.CategorizedEscape{escape(parser.DoesNotEscape, 13, 17, "id")}
+	if err := Annotate([]string{"./does-not-exist"}, escapes); err != nil {
+		t.Errorf("Annotate(no allocations) = %v, want nil", err)
+	}
+}
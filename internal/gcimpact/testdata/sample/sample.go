@@ -0,0 +1,40 @@
+// Package sample allocates in loops of known and unknown length, for
+// estimating GC impact
+package sample
+
+import "fmt"
+
+type Request struct {
+	ID      int64
+	Headers [8]string
+	Body    []byte
+}
+
+func NewRequest(id int64) *Request {
+	return &Request{ID: id}
+}
+
+func Batch(n int) []*Request {
+	var out []*Request
+	for i := 0; i < n; i++ {
+		r := Request{ID: int64(i)}
+		out = append(out, &r)
+	}
+	return out
+}
+
+func Fixed() []*[64]byte {
+	var out []*[64]byte
+	for i := 0; i < 4; i++ {
+		out = append(out, new([64]byte))
+	}
+	return out
+}
+
+func Buffer() []byte {
+	return make([]byte, 0, 4096)
+}
+
+func Log(id int64) {
+	fmt.Println(id)
+}
@@ -0,0 +1,46 @@
+// Package gitcmd runs git for the packages that read the state of a
+// repository, such as its head commit and branch, or clone one.
+package gitcmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Run runs git with args in dir, the current directory for "", and
+// returns its output without surrounding space. The error carries what
+// git printed to stderr.
+func Run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Output returns the output of git with args in the current directory, as
+// Run does, or "" when git fails, e.g. outside a repository
+func Output(args ...string) string {
+	out, err := Run(context.Background(), "", args...)
+	if err != nil {
+		return ""
+	}
+	return out
+}
+
+// Branch returns the branch checked out in the current directory, or ""
+// outside a repository or on a detached HEAD
+func Branch() string {
+	branch := Output("rev-parse", "--abbrev-ref", "HEAD")
+	if branch == "HEAD" {
+		return ""
+	}
+	return branch
+}
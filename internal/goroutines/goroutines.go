@@ -0,0 +1,137 @@
+// Package goroutines groups the escapes caused by goroutines and channels
+// by the function that spawns the goroutines or sends on the channels.
+// Such escapes usually need one structural fix per function, such as a
+// worker pool with per-worker buffers, rather than a fix per variable.
+package goroutines
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+
+	"github.com/harshakonda/heapcheck/internal/buildctx"
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	heapparser "github.com/harshakonda/heapcheck/internal/parser"
+	"github.com/harshakonda/heapcheck/internal/typecheck"
+)
+
+// IsConcurrent reports whether an escape is caused by a goroutine or a
+// channel send, judging by its category and flows alone
+func IsConcurrent(e categorizer.CategorizedEscape) bool {
+	return e.Category == categorizer.CategoryGoroutineEscape ||
+		e.Category == categorizer.CategoryChannelSend ||
+		e.Info.HasSink(heapparser.SinkChannel)
+}
+
+// Analyze groups the concurrency-related escapes by their enclosing
+// top-level function, most escapes first. Besides IsConcurrent escapes,
+// these are values captured by a closure started with `go func`, and
+// values passed as arguments in a go statement, found by parsing the
+// escapes' source files. Escapes in files that cannot be parsed, or that
+// the build configuration excludes, are grouped by file, with an empty Func.
+func Analyze(escapes []categorizer.CategorizedEscape) []categorizer.GoroutineGroup {
+	fset := token.NewFileSet()
+	files := make(map[string]*ast.File)
+	groups := make(map[string]*categorizer.GoroutineGroup)
+
+	for _, e := range escapes {
+		path := e.Info.File
+		f, ok := files[path]
+		if !ok {
+			if buildctx.Includes(path) {
+				if src, err := buildctx.ReadFile(path); err == nil {
+					f, _ = parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+				}
+			}
+			files[path] = f
+		}
+
+		if f == nil {
+			if IsConcurrent(e) {
+				add(groups, "", path, e)
+			}
+			continue
+		}
+		if !IsConcurrent(e) && !spawned(fset, f, e) {
+			continue
+		}
+
+		name, position := "init", path
+		if decl := typecheck.EnclosingFunc(f, typecheck.Pos(fset, f, e.Info.Line, e.Info.Column)); decl != nil {
+			name = typecheck.FuncName(decl)
+			position = fmt.Sprintf("%s:%d", path, fset.Position(decl.Pos()).Line)
+		}
+		add(groups, name, position, e)
+	}
+	return sorted(groups)
+}
+
+// add counts e in the group of the function declared at position
+func add(groups map[string]*categorizer.GoroutineGroup, name, position string, e categorizer.CategorizedEscape) {
+	g := groups[position]
+	if g == nil {
+		g = &categorizer.GoroutineGroup{
+			Func:       name,
+			Position:   position,
+			ByCategory: make(map[categorizer.Category]int),
+		}
+		groups[position] = g
+	}
+	g.Escapes++
+	g.ByCategory[e.Category]++
+}
+
+// spawned reports whether e is captured by a closure started in a go
+// statement, or escapes as an argument of a go statement
+func spawned(fset *token.FileSet, f *ast.File, e categorizer.CategorizedEscape) bool {
+	found := false
+	ast.Inspect(f, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		g, ok := n.(*ast.GoStmt)
+		if !ok {
+			return true
+		}
+		start, end := fset.Position(g.Pos()), fset.Position(g.End())
+		for _, flow := range e.Info.Flows {
+			for _, step := range flow.Steps {
+				if step.Reason == "captured by a closure" && typecheck.Contains(start, end, step.Line, step.Column) {
+					found = true
+					return false
+				}
+			}
+		}
+		if typecheck.Contains(start, end, e.Info.Line, e.Info.Column) {
+			// Allocations inside the goroutine's body are not caused
+			// by starting it
+			if lit, ok := g.Call.Fun.(*ast.FuncLit); ok {
+				bodyStart, bodyEnd := fset.Position(lit.Body.Pos()), fset.Position(lit.Body.End())
+				if typecheck.Contains(bodyStart, bodyEnd, e.Info.Line, e.Info.Column) {
+					return true
+				}
+			}
+			found = true
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// sorted returns the groups, most escapes first
+func sorted(groups map[string]*categorizer.GoroutineGroup) []categorizer.GoroutineGroup {
+	result := make([]categorizer.GoroutineGroup, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Escapes != result[j].Escapes {
+			return result[i].Escapes > result[j].Escapes
+		}
+		return result[i].Position < result[j].Position
+	})
+	return result
+}
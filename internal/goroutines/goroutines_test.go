@@ -0,0 +1,59 @@
+package goroutines
+
+import (
+	"context"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func TestAnalyze(t *testing.T) {
+	output, err := parser.RunCompiler(context.Background(), []string{"./testdata/sample"})
+	if err != nil {
+		t.Fatalf("RunCompiler: %v", err)
+	}
+	escapes, err := parser.Parse(output)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	results := categorizer.Categorize(escapes)
+
+	groups := Analyze(results.Escapes)
+	byFunc := make(map[string]categorizer.GoroutineGroup)
+	for _, g := range groups {
+		byFunc[g.Func] = g
+	}
+
+	// Process: wg captured by the go func literal, and the literal itself
+	if g := byFunc["Process"]; g.Escapes < 2 || g.ByCategory[categorizer.CategoryClosureCapture] != 1 || g.Position != "testdata/sample/sample.go:12" {
+		t.Errorf("Process group = %+v, want the capture and the literal at testdata/sample/sample.go:12", g)
+	}
+	// Serve: buf passed to handle in a go statement
+	if g := byFunc["Serve"]; g.Escapes < 1 {
+		t.Errorf("Serve group = %+v, want buf's escape", g)
+	}
+	// (*Pool).Submit: &Job{...} sent on a channel
+	if g := byFunc["(*Pool).Submit"]; g.Escapes != 1 {
+		t.Errorf("(*Pool).Submit group = %+v, want 1 escape", g)
+	}
+	if _, ok := byFunc["Format"]; ok {
+		t.Errorf("Format has no goroutine or channel escapes, got group %+v", byFunc["Format"])
+	}
+	for i := 1; i < len(groups); i++ {
+		if groups[i].Escapes > groups[i-1].Escapes {
+			t.Errorf("groups not sorted by escapes: %+v", groups)
+		}
+	}
+}
+
+func TestAnalyzeUnreadable(t *testing.T) {
+	escapes := []categorizer.CategorizedEscape{
+		{Info: parser.EscapeInfo{File: "testdata/missing.go", Line: 3, Column: 2}, Category: categorizer.CategoryChannelSend},
+		{Info: parser.EscapeInfo{File: "testdata/missing.go", Line: 5, Column: 2}, Category: categorizer.CategoryClosureCapture},
+	}
+	groups := Analyze(escapes)
+	if len(groups) != 1 || groups[0].Func != "" || groups[0].Position != "testdata/missing.go" || groups[0].Escapes != 1 {
+		t.Errorf("Analyze(unreadable file) = %+v, want one channel-send escape grouped by file", groups)
+	}
+}
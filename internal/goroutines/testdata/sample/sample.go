@@ -0,0 +1,38 @@
+// Package sample spawns goroutines and sends on channels for the tests.
+package sample
+
+import "sync"
+
+type Job struct{ ID int }
+
+type Pool struct{ jobs chan *Job }
+
+func handle(req int, buf []byte) { _ = buf[req] }
+
+func Process(jobs []Job) {
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = job.ID
+		}()
+	}
+	wg.Wait()
+}
+
+func Serve(requests chan int, n int) {
+	for req := range requests {
+		buf := make([]byte, n)
+		go handle(req, buf)
+	}
+}
+
+func (p *Pool) Submit(id int) {
+	p.jobs <- &Job{ID: id}
+}
+
+func Format(id int) []int {
+	ids := []int{}
+	return append(ids, id)
+}
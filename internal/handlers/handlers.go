@@ -0,0 +1,266 @@
+// Package handlers groups escapes by the HTTP handler they occur in, so a
+// web service sees its allocations per endpoint, the way it thinks about
+// per-request cost. Handlers are found by their signatures with go/types:
+// net/http handler functions and ServeHTTP methods, which chi and
+// gorilla/mux use too, and the handler types of gin, echo, fiber and
+// httprouter. Routes come from the calls registering the handlers.
+package handlers
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/typecheck"
+)
+
+// signatures are the parameter types of handlers, as go/types prints them
+var signatures = [][]string{
+	{"net/http.ResponseWriter", "*net/http.Request"},
+	{"net/http.ResponseWriter", "*net/http.Request", "github.com/julienschmidt/httprouter.Params"},
+	{"*github.com/gin-gonic/gin.Context"},
+	{"github.com/labstack/echo/v4.Context"},
+	{"github.com/labstack/echo.Context"},
+	{"*github.com/gofiber/fiber/v2.Ctx"},
+}
+
+// methods are the router methods whose name is the HTTP method of the
+// route they register
+var methods = map[string]string{
+	"GET": "GET", "Get": "GET",
+	"POST": "POST", "Post": "POST",
+	"PUT": "PUT", "Put": "PUT",
+	"PATCH": "PATCH", "Patch": "PATCH",
+	"DELETE": "DELETE", "Delete": "DELETE",
+	"HEAD": "HEAD", "Head": "HEAD",
+	"OPTIONS": "OPTIONS", "Options": "OPTIONS",
+}
+
+// IsHandler reports whether sig is the signature of an HTTP handler
+func IsHandler(sig *types.Signature) bool {
+	params := sig.Params()
+	for _, want := range signatures {
+		if params.Len() != len(want) {
+			continue
+		}
+		match := true
+		for i, typ := range want {
+			if params.At(i).Type().String() != typ {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// handler is a handler function or function literal
+type handler struct {
+	name     string
+	position string
+	file     string
+	start    token.Position
+	end      token.Position
+	routes   []string
+	group    *categorizer.HandlerGroup
+}
+
+// Analyze type-checks the packages matching patterns and groups the
+// escapes inside HTTP handlers by handler, most escapes first. Escapes in
+// functions that handlers call are not attributed to them.
+func Analyze(patterns []string, escapes []categorizer.CategorizedEscape) ([]categorizer.HandlerGroup, error) {
+	byFile := make(map[string][]categorizer.CategorizedEscape)
+	for _, e := range escapes {
+		if abs, err := filepath.Abs(e.Info.File); err == nil {
+			byFile[abs] = append(byFile[abs], e)
+		}
+	}
+	if len(byFile) == 0 {
+		return nil, nil
+	}
+
+	files := make(map[string]bool, len(byFile))
+	for path := range byFile {
+		files[path] = true
+	}
+	fset := token.NewFileSet()
+	pkgs, err := typecheck.Check(fset, patterns, files)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*handler
+	for _, p := range pkgs {
+		found := find(fset, p)
+		for _, f := range p.Files {
+			addRoutes(f, p.Info, found)
+		}
+		for _, h := range found {
+			all = append(all, h)
+		}
+	}
+
+	for path, escapes := range byFile {
+		for _, e := range escapes {
+			if h := innermost(all, path, e.Info.Line, e.Info.Column); h != nil {
+				add(h, e)
+			}
+		}
+	}
+	return sorted(all), nil
+}
+
+// find returns the handlers declared in a package, by the object of
+// handler functions and the node of handler function literals
+func find(fset *token.FileSet, p *typecheck.Package) map[any]*handler {
+	found := make(map[any]*handler)
+	for _, f := range p.Files {
+		for _, d := range f.Decls {
+			decl, ok := d.(*ast.FuncDecl)
+			if !ok || decl.Body == nil {
+				continue
+			}
+			name := typecheck.FuncName(decl)
+			if obj, ok := p.Info.Defs[decl.Name].(*types.Func); ok && IsHandler(obj.Type().(*types.Signature)) {
+				found[obj] = newHandler(fset, name, decl)
+			}
+			literals := 0
+			ast.Inspect(decl.Body, func(n ast.Node) bool {
+				lit, ok := n.(*ast.FuncLit)
+				if !ok {
+					return true
+				}
+				literals++
+				if sig, ok := p.Info.Types[lit].Type.(*types.Signature); ok && IsHandler(sig) {
+					found[lit] = newHandler(fset, fmt.Sprintf("%s.func%d", name, literals), lit)
+				}
+				return true
+			})
+		}
+	}
+	return found
+}
+
+func newHandler(fset *token.FileSet, name string, n ast.Node) *handler {
+	start, end := fset.Position(n.Pos()), fset.Position(n.End())
+	return &handler{
+		name:     name,
+		position: fmt.Sprintf("%s:%d", start.Filename, start.Line),
+		file:     start.Filename,
+		start:    start,
+		end:      end,
+	}
+}
+
+// addRoutes records the routes of calls registering handlers, such as
+// mux.HandleFunc("GET /users", listUsers) or r.GET("/users", listUsers):
+// a string constant followed by a handler among the arguments
+func addRoutes(f *ast.File, info *types.Info, found map[any]*handler) {
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		route := ""
+		for _, arg := range call.Args {
+			if tv, ok := info.Types[arg]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+				route = constant.StringVal(tv.Value)
+				continue
+			}
+			if route == "" {
+				continue
+			}
+			if h := handlerOf(arg, info, found); h != nil {
+				if sel, ok := call.Fun.(*ast.SelectorExpr); ok && methods[sel.Sel.Name] != "" {
+					route = methods[sel.Sel.Name] + " " + route
+				}
+				h.routes = append(h.routes, route)
+				break
+			}
+		}
+		return true
+	})
+}
+
+// handlerOf returns the handler an argument refers to: a function or
+// method value, a function literal, either converted, as in
+// http.HandlerFunc(listUsers), or a value with a ServeHTTP method
+func handlerOf(arg ast.Expr, info *types.Info, found map[any]*handler) *handler {
+	var h *handler
+	switch a := ast.Unparen(arg).(type) {
+	case *ast.FuncLit:
+		h = found[a]
+	case *ast.Ident:
+		h = found[info.Uses[a]]
+	case *ast.SelectorExpr:
+		h = found[info.Uses[a.Sel]]
+	case *ast.CallExpr:
+		if tv, ok := info.Types[a.Fun]; ok && tv.IsType() && len(a.Args) == 1 {
+			h = handlerOf(a.Args[0], info, found)
+		}
+	}
+	if h != nil {
+		return h
+	}
+	// A value whose ServeHTTP method is the handler
+	if tv, ok := info.Types[arg]; ok && tv.Type != nil {
+		if obj, _, _ := types.LookupFieldOrMethod(tv.Type, true, nil, "ServeHTTP"); obj != nil {
+			return found[obj]
+		}
+	}
+	return nil
+}
+
+// innermost returns the handler most closely enclosing line:col of path
+func innermost(all []*handler, path string, line, col int) *handler {
+	var best *handler
+	for _, h := range all {
+		// A function literal escaping as a value is not inside itself
+		if h.file != path || !typecheck.Contains(h.start, h.end, line, col) || (line == h.start.Line && col == h.start.Column) {
+			continue
+		}
+		if best == nil || typecheck.Contains(best.start, best.end, h.start.Line, h.start.Column) {
+			best = h
+		}
+	}
+	return best
+}
+
+// add counts e in the group of handler h
+func add(h *handler, e categorizer.CategorizedEscape) {
+	if h.group == nil {
+		h.group = &categorizer.HandlerGroup{
+			Handler:    h.name,
+			Routes:     h.routes,
+			Position:   h.position,
+			ByCategory: make(map[categorizer.Category]int),
+		}
+	}
+	h.group.Escapes++
+	h.group.ByCategory[e.Category]++
+}
+
+// sorted returns the groups of handlers with escapes, most escapes first
+func sorted(all []*handler) []categorizer.HandlerGroup {
+	var result []categorizer.HandlerGroup
+	for _, h := range all {
+		if h.group != nil {
+			result = append(result, *h.group)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Escapes != result[j].Escapes {
+			return result[i].Escapes > result[j].Escapes
+		}
+		return result[i].Position < result[j].Position
+	})
+	return result
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func escape(line, col int, cat categorizer.Category) categorizer.CategorizedEscape {
+	return categorizer.CategorizedEscape{
+		Info:     parser.EscapeInfo{File: "testdata/sample/sample.go", Line: line, Column: col, EscapeType: parser.EscapesToHeap},
+		Category: cat,
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	escapes := []categorizer.CategorizedEscape{
+		escape(10, 16, categorizer.CategoryLeakingParam),     // listUsers: w
+		escape(11, 17, categorizer.CategoryCompositeLiteral), // []user{...}
+		escape(12, 28, categorizer.CategoryInterfaceBoxing),  // users
+		escape(18, 26, categorizer.CategoryStringConversion), // ServeHTTP
+		escape(25, 28, categorizer.CategoryClosureCapture),   // the literal itself, escaping in Routes
+		escape(26, 30, categorizer.CategoryMapAllocation),    // in the /health literal
+		escape(32, 9, categorizer.CategoryReturnPointer),     // newUser: no handler
+	}
+
+	groups, err := Analyze([]string{"./testdata/sample"}, escapes)
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	want := []categorizer.HandlerGroup{
+		{Handler: "listUsers", Routes: []string{"GET /users", "/v1/users"}, Escapes: 3, ByCategory: map[categorizer.Category]int{
+			categorizer.CategoryLeakingParam: 1, categorizer.CategoryCompositeLiteral: 1, categorizer.CategoryInterfaceBoxing: 1,
+		}},
+		{Handler: "(*Server).ServeHTTP", Routes: []string{"/admin/"}, Escapes: 1, ByCategory: map[categorizer.Category]int{
+			categorizer.CategoryStringConversion: 1,
+		}},
+		{Handler: "Routes.func1", Routes: []string{"/health"}, Escapes: 1, ByCategory: map[categorizer.Category]int{
+			categorizer.CategoryMapAllocation: 1,
+		}},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("groups = %+v, want %d", groups, len(want))
+	}
+	for i, w := range want {
+		got := groups[i]
+		if got.Position == "" {
+			t.Errorf("groups[%d].Position is empty", i)
+		}
+		got.Position = ""
+		if !reflect.DeepEqual(got, w) {
+			t.Errorf("groups[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestAnalyzeNoEscapes(t *testing.T) {
+	groups, err := Analyze([]string{"./does-not-exist"}, nil)
+	if err != nil || groups != nil {
+		t.Errorf("Analyze(no escapes) = %v, %v, want nil, nil", groups, err)
+	}
+}
@@ -0,0 +1,33 @@
+package sample
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type user struct{ Name string }
+
+func listUsers(w http.ResponseWriter, r *http.Request) {
+	users := []user{{Name: r.URL.Query().Get("name")}}
+	json.NewEncoder(w).Encode(users)
+}
+
+type Server struct{ prefix string }
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(s.prefix + r.URL.Path))
+}
+
+func Routes(mux *http.ServeMux, s *Server) {
+	mux.HandleFunc("GET /users", listUsers)
+	mux.Handle("/v1/users", http.HandlerFunc(listUsers))
+	mux.Handle("/admin/", s)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		status := map[string]string{"status": "ok"}
+		json.NewEncoder(w).Encode(status)
+	})
+}
+
+func newUser(name string) *user {
+	return &user{Name: name}
+}
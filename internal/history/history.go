@@ -0,0 +1,175 @@
+// Package history records escape counts of past runs and detects slow
+// regressions against their rolling average.
+//
+// Runs on the default branch are appended to a JSON history file that is
+// kept in the repository or a CI cache. A run then compares its escape
+// count to the average of the last N recorded runs, which catches drift
+// that per-PR diffs miss.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/gitcmd"
+)
+
+// DefaultWindow is how many past runs the rolling average covers
+const DefaultWindow = 10
+
+// maxRuns bounds how many runs the file keeps, unless a Retention sets
+// another bound
+const maxRuns = 200
+
+// Run is the escape count of one recorded run
+type Run struct {
+	Time          time.Time                    `json:"time"`
+	Commit        string                       `json:"commit,omitempty"`
+	Branch        string                       `json:"branch,omitempty"`
+	HeapAllocated int                          `json:"heapAllocated"`
+	ByCategory    map[categorizer.Category]int `json:"byCategory,omitempty"`
+}
+
+// History is the content of a history file, oldest run first
+type History struct {
+	Runs []Run `json:"runs"`
+}
+
+// Load reads a history file. A missing file yields an empty history.
+func Load(path string) (*History, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &History{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+
+	var h History
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("parsing history %s: %w", path, err)
+	}
+	return &h, nil
+}
+
+// Save writes the history file
+func Save(path string, h *History) error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing history: %w", err)
+	}
+	return nil
+}
+
+// Add appends a run, then drops the runs keep does not retain at the
+// time of the run. It returns how many runs it dropped.
+func (h *History) Add(run Run, keep Retention) int {
+	h.Runs = append(h.Runs, run)
+	return h.Prune(keep, run.Time)
+}
+
+// RollingAverage returns the average escape count of the last n runs on
+// branch and how many runs it covers
+func (h *History) RollingAverage(branch string, n int) (float64, int) {
+	sum, count := 0, 0
+	for i := len(h.Runs) - 1; i >= 0 && count < n; i-- {
+		if h.Runs[i].Branch != branch {
+			continue
+		}
+		sum += h.Runs[i].HeapAllocated
+		count++
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return float64(sum) / float64(count), count
+}
+
+// NewRun captures the current results and git state
+func NewRun(results *categorizer.Results, branch string) Run {
+	byCategory := make(map[categorizer.Category]int, len(results.ByCategory))
+	for cat, n := range results.ByCategory {
+		byCategory[cat] = n
+	}
+	return Run{
+		Time:          time.Now().UTC(),
+		Commit:        gitcmd.Output("rev-parse", "HEAD"),
+		Branch:        branch,
+		HeapAllocated: results.Summary.HeapAllocated,
+		ByCategory:    byCategory,
+	}
+}
+
+// ParseDrift parses an allowed growth such as "+5%" or "5" into percent
+func ParseDrift(s string) (float64, error) {
+	v := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s), "+"), "%")
+	pct, err := strconv.ParseFloat(v, 64)
+	if err != nil || pct < 0 {
+		return 0, fmt.Errorf("invalid drift %q (want e.g. +5%%)", s)
+	}
+	return pct, nil
+}
+
+// Trend is the comparison of a run against the rolling average
+type Trend struct {
+	Current  int     `json:"current"`
+	Average  float64 `json:"average"`
+	Runs     int     `json:"runs"`
+	Change   float64 `json:"change"` // percent over the average
+	Allowed  float64 `json:"allowed"`
+	Exceeded bool    `json:"exceeded"`
+}
+
+// Check compares current to the rolling average of the last window runs
+// on branch. It returns nil if there are no runs to compare with.
+func (h *History) Check(current int, branch string, window int, allowed float64) *Trend {
+	avg, runs := h.RollingAverage(branch, window)
+	if runs == 0 {
+		return nil
+	}
+
+	change := 0.0
+	switch {
+	case avg > 0:
+		change = (float64(current) - avg) / avg * 100
+	case current > 0:
+		change = 100
+	}
+	return &Trend{
+		Current:  current,
+		Average:  avg,
+		Runs:     runs,
+		Change:   change,
+		Allowed:  allowed,
+		Exceeded: change > allowed,
+	}
+}
+
+// String describes the trend for the CLI
+func (t *Trend) String() string {
+	return fmt.Sprintf("%d escapes vs rolling average %.1f over %d runs (%+.1f%%, allowed +%g%%)",
+		t.Current, t.Average, t.Runs, t.Change, t.Allowed)
+}
+
+// CurrentBranch returns the checked-out git branch, or "" outside a repo
+// or on a detached HEAD
+func CurrentBranch() string {
+	return gitcmd.Branch()
+}
+
+// DefaultBranch returns the branch origin/HEAD points to, or "main"
+func DefaultBranch() string {
+	if ref := gitcmd.Output("symbolic-ref", "--short", "refs/remotes/origin/HEAD"); ref != "" {
+		return strings.TrimPrefix(ref, "origin/")
+	}
+	return "main"
+}
@@ -0,0 +1,123 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+func TestLoadSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+
+	h, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load(missing) error: %v", err)
+	}
+	if len(h.Runs) != 0 {
+		t.Fatalf("Load(missing) = %d runs, want 0", len(h.Runs))
+	}
+
+	h.Add(Run{Branch: "main", HeapAllocated: 12, ByCategory: map[categorizer.Category]int{categorizer.CategoryFmtCall: 12}}, Retention{})
+	if err := Save(path, h); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(loaded.Runs) != 1 || loaded.Runs[0].HeapAllocated != 12 || loaded.Runs[0].ByCategory[categorizer.CategoryFmtCall] != 12 {
+		t.Errorf("Load() = %+v, want the saved run", loaded.Runs)
+	}
+}
+
+func TestAddBounded(t *testing.T) {
+	h := &History{}
+	for i := 0; i < maxRuns+5; i++ {
+		h.Add(Run{HeapAllocated: i}, Retention{})
+	}
+	if len(h.Runs) != maxRuns {
+		t.Fatalf("Runs = %d, want %d", len(h.Runs), maxRuns)
+	}
+	if h.Runs[0].HeapAllocated != 5 {
+		t.Errorf("oldest run = %d, want 5", h.Runs[0].HeapAllocated)
+	}
+}
+
+func TestRollingAverage(t *testing.T) {
+	h := &History{Runs: []Run{
+		{Branch: "main", HeapAllocated: 100},
+		{Branch: "main", HeapAllocated: 10},
+		{Branch: "feature", HeapAllocated: 500},
+		{Branch: "main", HeapAllocated: 20},
+	}}
+
+	tests := []struct {
+		branch   string
+		n        int
+		wantAvg  float64
+		wantRuns int
+	}{
+		{"main", 2, 15, 2},
+		{"main", 10, 130.0 / 3, 3},
+		{"feature", 10, 500, 1},
+		{"release", 10, 0, 0},
+	}
+	for _, tt := range tests {
+		avg, runs := h.RollingAverage(tt.branch, tt.n)
+		if avg != tt.wantAvg || runs != tt.wantRuns {
+			t.Errorf("RollingAverage(%q, %d) = %v, %d, want %v, %d", tt.branch, tt.n, avg, runs, tt.wantAvg, tt.wantRuns)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	h := &History{Runs: []Run{
+		{Branch: "main", HeapAllocated: 100},
+		{Branch: "main", HeapAllocated: 100},
+	}}
+
+	tests := []struct {
+		current  int
+		allowed  float64
+		exceeded bool
+	}{
+		{105, 5, false},
+		{106, 5, true},
+		{90, 0, false},
+	}
+	for _, tt := range tests {
+		trend := h.Check(tt.current, "main", 10, tt.allowed)
+		if trend == nil {
+			t.Fatalf("Check(%d) = nil", tt.current)
+		}
+		if trend.Exceeded != tt.exceeded {
+			t.Errorf("Check(%d, +%g%%).Exceeded = %v, want %v (change %+.1f%%)", tt.current, tt.allowed, trend.Exceeded, tt.exceeded, trend.Change)
+		}
+	}
+
+	if trend := h.Check(100, "release", 10, 5); trend != nil {
+		t.Errorf("Check() without runs = %+v, want nil", trend)
+	}
+}
+
+func TestParseDrift(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"+5%", 5, false},
+		{"5", 5, false},
+		{"2.5%", 2.5, false},
+		{"-5%", 0, true},
+		{"five", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDrift(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseDrift(%q) = %v, %v, want %v (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package history
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retention bounds the runs a history file keeps, so that files of
+// long-lived repositories do not grow without bound
+type Retention struct {
+	MaxAge  time.Duration // runs older than this are dropped; 0 keeps runs of any age
+	MaxRuns int           // only the newest runs are kept; 0 is the default of 200
+}
+
+// ParseRetention parses a retention such as "90d" (an age, also "12w" or
+// "720h"), "500" (a number of runs) or both, comma-separated: "90d,500"
+func ParseRetention(s string) (Retention, error) {
+	var r Retention
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if n, err := strconv.Atoi(part); err == nil {
+			if n <= 0 || r.MaxRuns != 0 {
+				return Retention{}, fmt.Errorf("invalid retention %q (want e.g. 90d or 500 runs)", s)
+			}
+			r.MaxRuns = n
+			continue
+		}
+		age, err := ParseAge(part)
+		if err != nil || r.MaxAge != 0 {
+			return Retention{}, fmt.Errorf("invalid retention %q (want e.g. 90d or 500 runs)", s)
+		}
+		r.MaxAge = age
+	}
+	return r, nil
+}
+
+// ParseAge parses a positive age in days ("90d"), weeks ("12w") or as a
+// Go duration ("720h")
+func ParseAge(s string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	switch {
+	case strings.HasSuffix(s, "d"), strings.HasSuffix(s, "w"):
+		n, perr := strconv.Atoi(s[:len(s)-1])
+		d, err = time.Duration(n)*24*time.Hour, perr
+		if strings.HasSuffix(s, "w") {
+			d *= 7
+		}
+	default:
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid age %q (want e.g. 90d, 12w or 720h)", s)
+	}
+	return d, nil
+}
+
+// maxRuns returns the number of runs the retention keeps
+func (r Retention) maxRuns() int {
+	if r.MaxRuns > 0 {
+		return r.MaxRuns
+	}
+	return maxRuns
+}
+
+// Prune drops the runs keep does not retain at now: those older than its
+// age, then the oldest beyond its number of runs. It returns how many runs
+// it dropped.
+func (h *History) Prune(keep Retention, now time.Time) int {
+	before := len(h.Runs)
+	if keep.MaxAge > 0 {
+		cutoff := now.Add(-keep.MaxAge)
+		kept := h.Runs[:0]
+		for _, run := range h.Runs {
+			if !run.Time.Before(cutoff) {
+				kept = append(kept, run)
+			}
+		}
+		h.Runs = kept
+	}
+	if n := keep.maxRuns(); len(h.Runs) > n {
+		h.Runs = append([]Run(nil), h.Runs[len(h.Runs)-n:]...)
+	}
+	return before - len(h.Runs)
+}
+
+// Compact thins the runs recorded before cutoff to the last run of each
+// branch and day (UTC), keeping the long-term trend in fewer runs. Newer
+// runs, which rolling averages cover, are kept as they are. It returns
+// how many runs it dropped.
+func (h *History) Compact(cutoff time.Time) int {
+	type key struct {
+		branch string
+		day    string
+	}
+	last := make(map[key]int) // index of the last run of each branch and day
+	for i, run := range h.Runs {
+		if run.Time.Before(cutoff) {
+			last[key{run.Branch, run.Time.UTC().Format(time.DateOnly)}] = i
+		}
+	}
+
+	before := len(h.Runs)
+	kept := h.Runs[:0]
+	for i, run := range h.Runs {
+		if !run.Time.Before(cutoff) || last[key{run.Branch, run.Time.UTC().Format(time.DateOnly)}] == i {
+			kept = append(kept, run)
+		}
+	}
+	h.Runs = kept
+	return before - len(h.Runs)
+}
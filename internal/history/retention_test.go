@@ -0,0 +1,85 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRetention(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		in      string
+		want    Retention
+		wantErr bool
+	}{
+		{in: "90d", want: Retention{MaxAge: 90 * day}},
+		{in: "12w", want: Retention{MaxAge: 84 * day}},
+		{in: "720h", want: Retention{MaxAge: 30 * day}},
+		{in: "500", want: Retention{MaxRuns: 500}},
+		{in: "90d, 500", want: Retention{MaxAge: 90 * day, MaxRuns: 500}},
+		{in: "0", wantErr: true},
+		{in: "-5d", wantErr: true},
+		{in: "90d,30d", wantErr: true},
+		{in: "10,20", wantErr: true},
+		{in: "soon", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRetention(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRetention(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRetention(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	h := &History{}
+	for i := 9; i >= 0; i-- {
+		h.Runs = append(h.Runs, Run{Time: now.AddDate(0, 0, -i*10), HeapAllocated: i})
+	}
+
+	if n := h.Prune(Retention{MaxAge: 45 * 24 * time.Hour}, now); n != 5 || len(h.Runs) != 5 || h.Runs[0].HeapAllocated != 4 {
+		t.Fatalf("Prune(45d) = %d, runs %+v, want the 5 runs of the last 45 days", n, h.Runs)
+	}
+	if n := h.Prune(Retention{MaxRuns: 2}, now); n != 3 || len(h.Runs) != 2 || h.Runs[0].HeapAllocated != 1 {
+		t.Fatalf("Prune(2) = %d, runs %+v, want the newest 2 runs", n, h.Runs)
+	}
+	if n := h.Prune(Retention{}, now); n != 0 {
+		t.Errorf("Prune() dropped %d runs below the default bound", n)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	day := func(d, hour int) time.Time { return time.Date(2024, 6, d, hour, 0, 0, 0, time.UTC) }
+	h := &History{Runs: []Run{
+		{Time: day(1, 9), Branch: "main", HeapAllocated: 1},
+		{Time: day(1, 10), Branch: "release", HeapAllocated: 2},
+		{Time: day(1, 17), Branch: "main", HeapAllocated: 3},
+		{Time: day(2, 9), Branch: "main", HeapAllocated: 4},
+		{Time: day(2, 11), Branch: "main", HeapAllocated: 5},
+		{Time: day(3, 9), Branch: "main", HeapAllocated: 6},
+		{Time: day(3, 10), Branch: "main", HeapAllocated: 7},
+	}}
+
+	if n := h.Compact(day(3, 0)); n != 2 {
+		t.Errorf("Compact() dropped %d runs, want 2", n)
+	}
+	var got []int
+	for _, run := range h.Runs {
+		got = append(got, run.HeapAllocated)
+	}
+	want := []int{2, 3, 5, 6, 7}
+	if len(got) != len(want) {
+		t.Fatalf("runs = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("runs = %v, want %v: the last run of each branch and day before the cutoff, then every run", got, want)
+		}
+	}
+}
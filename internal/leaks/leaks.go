@@ -0,0 +1,450 @@
+// Package leaks performs static detection of common goroutine leak
+// patterns by inspecting the AST of the analyzed packages.
+//
+// It complements the runtime guard package: guard catches leaks that
+// happen in tests, leaks catches well-known leak shapes at compile time,
+// including code paths no test exercises.
+package leaks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/buildctx"
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// Kind identifies a leak pattern
+type Kind string
+
+const (
+	KindTimeAfterInLoop  Kind = "leak-time-after-in-loop"
+	KindMissingDone      Kind = "leak-missing-done"
+	KindUnclosedRange    Kind = "leak-unclosed-range"
+	KindUnclosedSelector Kind = "leak-unclosed-select"
+)
+
+// Finding is a single suspected leak
+type Finding struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Kind    Kind   `json:"kind"`
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+}
+
+// suggestions maps leak kinds to their suggestions
+var suggestions = map[Kind]categorizer.Suggestion{
+	KindTimeAfterInLoop: {
+		Short:   "Reuse a time.Timer instead of time.After in loops",
+		Details: "Each time.After call allocates a timer that is not released until it fires (before Go 1.23). In a loop this accumulates timers; create one time.NewTimer outside the loop and Reset it.",
+		DocLink: "https://pkg.go.dev/time#After",
+	},
+	KindMissingDone: {
+		Short:   "Add a ctx.Done() case to the select",
+		Details: "This goroutine loops forever over a select with no cancellation case, so it can never exit. Add `case <-ctx.Done(): return` (or a done channel) so owners can stop it.",
+		DocLink: "https://go.dev/blog/pipelines",
+	},
+	KindUnclosedRange: {
+		Short:   "Close the channel when producers finish",
+		Details: "The goroutine ranges over a channel that is never closed in the creating function, so the range never terminates. Close the channel after the last send, typically with defer close(ch) in the producer.",
+		DocLink: "https://go.dev/blog/pipelines",
+	},
+	KindUnclosedSelector: {
+		Short:   "Close the channel or add an exit case",
+		Details: "The goroutine only exits when a channel created in this function is closed or receives a value, but nothing closes it. Close it when done, or add a ctx.Done() case.",
+		DocLink: "https://go.dev/blog/pipelines",
+	},
+}
+
+// GetSuggestion returns the suggestion for a leak kind
+func GetSuggestion(k Kind) categorizer.Suggestion {
+	return suggestions[k]
+}
+
+// Package is a Go package to analyze, as reported by go list
+type Package struct {
+	ImportPath string
+	Dir        string
+	GoFiles    []string
+}
+
+// ListPackages resolves patterns to packages with `go list -json`
+func ListPackages(patterns []string) ([]Package, error) {
+	args := append([]string{"list", "-e", "-json"}, patterns...)
+	cmd := exec.Command("go", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go list failed: %w\n%s", err, stderr.String())
+	}
+
+	var pkgs []Package
+	dec := json.NewDecoder(&stdout)
+	for {
+		var p Package
+		if err := dec.Decode(&p); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("decoding go list output: %w", err)
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
+
+// Analyze lists the packages matching patterns and inspects their files
+func Analyze(patterns []string) ([]Finding, error) {
+	pkgs, err := ListPackages(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	cwd, _ := os.Getwd()
+	var findings []Finding
+	for _, p := range pkgs {
+		for _, name := range p.GoFiles {
+			path := filepath.Join(p.Dir, name)
+			src, err := buildctx.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %w", path, err)
+			}
+			display := path
+			if rel, err := filepath.Rel(cwd, path); err == nil && !filepath.IsAbs(rel) && rel[0] != '.' {
+				display = rel
+			}
+			fs, err := AnalyzeSource(display, src)
+			if err != nil {
+				return nil, err
+			}
+			findings = append(findings, fs...)
+		}
+	}
+	return findings, nil
+}
+
+// AnalyzeSource inspects a single Go source file
+func AnalyzeSource(filename string, src []byte) ([]Finding, error) {
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filename, err)
+	}
+
+	c := &checker{fset: fset}
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+			c.checkFunc(fn.Body)
+		}
+	}
+	return c.findings, nil
+}
+
+type checker struct {
+	fset     *token.FileSet
+	findings []Finding
+}
+
+func (c *checker) report(node ast.Node, kind Kind, subject, msg string) {
+	pos := c.fset.Position(node.Pos())
+	c.findings = append(c.findings, Finding{
+		File:    pos.Filename,
+		Line:    pos.Line,
+		Column:  pos.Column,
+		Kind:    kind,
+		Subject: subject,
+		Message: msg,
+	})
+}
+
+// checkFunc inspects one function body. Channel closes are tracked per
+// enclosing function declaration, including its nested func literals.
+func (c *checker) checkFunc(body *ast.BlockStmt) {
+	made := localChannels(body)
+	closed := closedChannels(body)
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ForStmt:
+			c.checkTimeAfter(n.Body)
+		case *ast.RangeStmt:
+			c.checkTimeAfter(n.Body)
+		case *ast.GoStmt:
+			lit, ok := n.Call.Fun.(*ast.FuncLit)
+			if !ok {
+				return true
+			}
+			c.checkGoroutine(lit.Body, made, closed)
+		}
+		return true
+	})
+}
+
+// checkTimeAfter flags time.After calls directly inside a loop body
+func (c *checker) checkTimeAfter(body *ast.BlockStmt) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit, *ast.ForStmt, *ast.RangeStmt:
+			// Nested loops are reported on their own; closures run elsewhere
+			return false
+		case *ast.CallExpr:
+			if isPkgCall(n, "time", "After") {
+				c.report(n, KindTimeAfterInLoop, "time.After",
+					"time.After called in a loop allocates a new timer every iteration")
+			}
+		}
+		return true
+	})
+}
+
+// checkGoroutine inspects the body of a `go func() { ... }()` literal
+func (c *checker) checkGoroutine(body *ast.BlockStmt, made, closed map[string]bool) {
+	for _, stmt := range body.List {
+		switch s := stmt.(type) {
+		case *ast.RangeStmt:
+			name := identName(s.X)
+			if made[name] && !closed[name] {
+				c.report(s, KindUnclosedRange, name,
+					fmt.Sprintf("goroutine ranges over channel %s, which is never closed", name))
+			}
+		case *ast.ForStmt:
+			if s.Cond != nil {
+				continue
+			}
+			c.checkForeverSelect(s, made, closed)
+		}
+	}
+}
+
+// checkForeverSelect flags `for { select { ... } }` loops that have no way
+// to observe cancellation.
+func (c *checker) checkForeverSelect(loop *ast.ForStmt, made, closed map[string]bool) {
+	for _, stmt := range loop.Body.List {
+		sel, ok := stmt.(*ast.SelectStmt)
+		if !ok {
+			continue
+		}
+
+		hasDone := false
+		hasExit := false
+		var waitsOn []string
+		for _, cc := range sel.Body.List {
+			clause := cc.(*ast.CommClause)
+			if clause.Comm == nil {
+				// default case: the loop spins rather than blocks
+				hasExit = true
+				continue
+			}
+			if ch := recvChannel(clause.Comm); ch != nil {
+				if isDoneCall(ch) {
+					hasDone = true
+				}
+				if name := identName(ch); name != "" {
+					waitsOn = append(waitsOn, name)
+				}
+			}
+			if exits(clause.Body) {
+				hasExit = true
+			}
+		}
+
+		if hasDone {
+			continue
+		}
+		if !hasExit {
+			c.report(sel, KindMissingDone, "select",
+				"goroutine loops forever on a select without a ctx.Done() or return case")
+			continue
+		}
+		for _, name := range waitsOn {
+			if made[name] && !closed[name] {
+				c.report(sel, KindUnclosedSelector, name,
+					fmt.Sprintf("goroutine waits on channel %s, which is never closed", name))
+				break
+			}
+		}
+	}
+}
+
+// localChannels returns names assigned from make(chan ...) in body
+func localChannels(body *ast.BlockStmt) map[string]bool {
+	made := make(map[string]bool)
+	ast.Inspect(body, func(n ast.Node) bool {
+		assign, ok := n.(*ast.AssignStmt)
+		if !ok {
+			return true
+		}
+		for i, rhs := range assign.Rhs {
+			call, ok := rhs.(*ast.CallExpr)
+			if !ok || identName(call.Fun) != "make" || len(call.Args) == 0 {
+				continue
+			}
+			if _, ok := call.Args[0].(*ast.ChanType); ok && i < len(assign.Lhs) {
+				made[identName(assign.Lhs[i])] = true
+			}
+		}
+		return true
+	})
+	return made
+}
+
+// closedChannels returns channel names passed to close() in body, and
+// channels that leave the function (returned or passed to another call),
+// since those may be closed elsewhere.
+func closedChannels(body *ast.BlockStmt) map[string]bool {
+	closed := make(map[string]bool)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			for _, arg := range n.Args {
+				if name := identName(arg); name != "" {
+					closed[name] = true
+				}
+			}
+		case *ast.ReturnStmt:
+			for _, r := range n.Results {
+				if name := identName(r); name != "" {
+					closed[name] = true
+				}
+			}
+		}
+		return true
+	})
+	return closed
+}
+
+// recvChannel returns the channel expression of a receive comm clause
+func recvChannel(stmt ast.Stmt) ast.Expr {
+	var expr ast.Expr
+	switch s := stmt.(type) {
+	case *ast.ExprStmt:
+		expr = s.X
+	case *ast.AssignStmt:
+		if len(s.Rhs) == 1 {
+			expr = s.Rhs[0]
+		}
+	}
+	if u, ok := expr.(*ast.UnaryExpr); ok && u.Op == token.ARROW {
+		return u.X
+	}
+	return nil
+}
+
+// isDoneCall matches ctx.Done() and similar cancellation channels
+func isDoneCall(expr ast.Expr) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "Done"
+}
+
+// exits reports whether stmts contain a return, goto, or labeled break
+func exits(stmts []ast.Stmt) bool {
+	found := false
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.ReturnStmt:
+				found = true
+			case *ast.BranchStmt:
+				if n.Tok == token.GOTO || (n.Tok == token.BREAK && n.Label != nil) {
+					found = true
+				}
+			case *ast.CallExpr:
+				if name := identName(n.Fun); name == "panic" {
+					found = true
+				}
+				if isPkgCall(n, "runtime", "Goexit") || isPkgCall(n, "os", "Exit") {
+					found = true
+				}
+			}
+			return !found
+		})
+	}
+	return found
+}
+
+func isPkgCall(call *ast.CallExpr, pkg, fn string) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != fn {
+		return false
+	}
+	return identName(sel.X) == pkg
+}
+
+func identName(expr ast.Expr) string {
+	if id, ok := expr.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// Results converts findings into categorizer results so the standard
+// JSON, HTML, and SARIF reporters can render them.
+func Results(findings []Finding) *categorizer.Results {
+	results := &categorizer.Results{
+		Summary: categorizer.Summary{
+			TotalVariables: len(findings),
+			HeapAllocated:  len(findings),
+			ByFile:         make(map[string]int),
+		},
+		ByCategory: make(map[categorizer.Category]int),
+		Escapes:    make([]categorizer.CategorizedEscape, 0, len(findings)),
+	}
+
+	for _, f := range findings {
+		cat := categorizer.Category(f.Kind)
+		results.Summary.ByFile[f.File]++
+		results.ByCategory[cat]++
+		results.Escapes = append(results.Escapes, categorizer.CategorizedEscape{
+			Info: parser.EscapeInfo{
+				File:     f.File,
+				Line:     f.Line,
+				Column:   f.Column,
+				Variable: f.Subject,
+				Reason:   f.Message,
+			},
+			Category:   cat,
+			Suggestion: suggestions[f.Kind],
+		})
+	}
+	return results
+}
+
+// WriteText prints findings in a human-readable form
+func WriteText(w io.Writer, findings []Finding) {
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "🔍 heapcheck - Static Goroutine Leak Report")
+	fmt.Fprintln(w, strings.Repeat("─", 50))
+	fmt.Fprintln(w, "")
+
+	if len(findings) == 0 {
+		fmt.Fprintln(w, "✅ No goroutine leak patterns found.")
+		return
+	}
+
+	fmt.Fprintf(w, "Found %d potential leak(s):\n", len(findings))
+	for _, f := range findings {
+		fmt.Fprintln(w, "")
+		fmt.Fprintf(w, "📍 %s:%d:%d\n", f.File, f.Line, f.Column)
+		fmt.Fprintf(w, "   Pattern:  %s\n", f.Kind)
+		fmt.Fprintf(w, "   %s\n", f.Message)
+		fmt.Fprintf(w, "   💡 %s\n", suggestions[f.Kind].Short)
+	}
+}
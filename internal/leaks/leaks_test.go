@@ -0,0 +1,166 @@
+package leaks
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+func TestAnalyzeSource(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []Kind
+	}{
+		{
+			name: "time.After in loop",
+			src: `package p
+import "time"
+func f(ch chan int) {
+	for {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			return
+		}
+	}
+}`,
+			want: []Kind{KindTimeAfterInLoop},
+		},
+		{
+			name: "goroutine select without done",
+			src: `package p
+func f(in chan int) {
+	go func() {
+		for {
+			select {
+			case v := <-in:
+				_ = v
+			}
+		}
+	}()
+}`,
+			want: []Kind{KindMissingDone},
+		},
+		{
+			name: "goroutine select with ctx.Done",
+			src: `package p
+import "context"
+func f(ctx context.Context, in chan int) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v := <-in:
+				_ = v
+			}
+		}
+	}()
+}`,
+			want: nil,
+		},
+		{
+			name: "range over unclosed channel",
+			src: `package p
+func f() {
+	ch := make(chan int)
+	go func() {
+		for v := range ch {
+			_ = v
+		}
+	}()
+	ch <- 1
+}`,
+			want: []Kind{KindUnclosedRange},
+		},
+		{
+			name: "range over closed channel",
+			src: `package p
+func f() {
+	ch := make(chan int)
+	go func() {
+		for v := range ch {
+			_ = v
+		}
+	}()
+	ch <- 1
+	close(ch)
+}`,
+			want: nil,
+		},
+		{
+			name: "select exits only via unclosed channel",
+			src: `package p
+func f(in chan int) {
+	quit := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-quit:
+				return
+			case v := <-in:
+				_ = v
+			}
+		}
+	}()
+}`,
+			want: []Kind{KindUnclosedSelector},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, err := AnalyzeSource("p.go", []byte(tt.src))
+			if err != nil {
+				t.Fatalf("AnalyzeSource() error = %v", err)
+			}
+			if len(findings) != len(tt.want) {
+				t.Fatalf("got %d findings %+v, want %v", len(findings), findings, tt.want)
+			}
+			for i, f := range findings {
+				if f.Kind != tt.want[i] {
+					t.Errorf("findings[%d].Kind = %s, want %s", i, f.Kind, tt.want[i])
+				}
+				if f.File != "p.go" || f.Line == 0 {
+					t.Errorf("findings[%d] has bad position %s:%d", i, f.File, f.Line)
+				}
+			}
+		})
+	}
+}
+
+func TestResults(t *testing.T) {
+	findings := []Finding{
+		{File: "a.go", Line: 3, Kind: KindMissingDone, Subject: "select"},
+		{File: "a.go", Line: 9, Kind: KindTimeAfterInLoop, Subject: "time.After"},
+	}
+
+	results := Results(findings)
+	if len(results.Escapes) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(results.Escapes))
+	}
+	if results.Summary.ByFile["a.go"] != 2 {
+		t.Errorf("ByFile[a.go] = %d, want 2", results.Summary.ByFile["a.go"])
+	}
+	if results.ByCategory[categorizer.Category(KindMissingDone)] != 1 {
+		t.Errorf("missing category count for %s", KindMissingDone)
+	}
+	if results.Escapes[0].Suggestion.Short == "" {
+		t.Error("expected suggestion to be attached")
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	WriteText(&buf, []Finding{{File: "a.go", Line: 3, Column: 2, Kind: KindMissingDone, Message: "loops forever"}})
+
+	output := buf.String()
+	for _, check := range []string{"a.go:3:2", "leak-missing-done", "loops forever", "💡"} {
+		if !strings.Contains(output, check) {
+			t.Errorf("text output missing: %s", check)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Package logging provides the debug logger shared by heapcheck's
+// packages. It discards everything until Enable is called, as the CLI
+// does for --debug, so logging costs nothing in normal runs.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+var logger = slog.New(discardHandler{})
+
+// Logger returns the debug logger
+func Logger() *slog.Logger {
+	return logger
+}
+
+// Enable writes debug records to w as text. It is meant to be called
+// once at startup, before any analysis runs.
+func Enable(w io.Writer) {
+	logger = slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+// Enabled reports whether debug records are written, to skip work that
+// only feeds the log
+func Enabled() bool {
+	return logger.Enabled(context.Background(), slog.LevelDebug)
+}
+
+// discardHandler drops every record
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,32 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEnable(t *testing.T) {
+	saved := logger
+	defer func() { logger = saved }()
+
+	if Enabled() {
+		t.Fatal("Enabled() = true before Enable")
+	}
+	Logger().Debug("dropped")
+
+	var buf bytes.Buffer
+	Enable(&buf)
+	if !Enabled() {
+		t.Fatal("Enabled() = false after Enable")
+	}
+	Logger().Debug("running go", "args", "list -export")
+
+	out := buf.String()
+	if strings.Contains(out, "dropped") {
+		t.Errorf("record logged before Enable was written: %q", out)
+	}
+	if !strings.Contains(out, `level=DEBUG msg="running go" args="list -export"`) {
+		t.Errorf("debug output = %q, want the running go record", out)
+	}
+}
@@ -0,0 +1,233 @@
+// Package otlp exports the summary of a run to an OpenTelemetry collector
+// over OTLP/HTTP, as gauge metrics and one span covering the analysis.
+//
+// Requests use the JSON encoding of OTLP, which collectors accept on the
+// same /v1/metrics and /v1/traces paths as protobuf, so no OpenTelemetry
+// SDK is needed.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/reporter"
+)
+
+// HeadersEnv holds extra request headers as key=value pairs separated by
+// commas, e.g. an API key for a hosted collector, per the OpenTelemetry
+// exporter conventions
+const HeadersEnv = "OTEL_EXPORTER_OTLP_HEADERS"
+
+// ServiceNameEnv overrides the service.name resource attribute, which
+// defaults to heapcheck
+const ServiceNameEnv = "OTEL_SERVICE_NAME"
+
+// scopeName is the instrumentation scope of the exported data
+const scopeName = "github.com/harshakonda/heapcheck"
+
+// Exporter sends run summaries to a collector
+type Exporter struct {
+	Endpoint string            // base URL, e.g. http://localhost:4318
+	Headers  map[string]string // sent with every request
+	Service  string            // service.name resource attribute
+	Client   *http.Client
+}
+
+// New returns an exporter for the collector at endpoint, an http or
+// https base URL to which /v1/metrics and /v1/traces are appended, with
+// headers and service name from the environment
+func New(endpoint string) (*Exporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q (want an http or https URL, e.g. http://localhost:4318)", endpoint)
+	}
+	headers, err := ParseHeaders(os.Getenv(HeadersEnv))
+	if err != nil {
+		return nil, fmt.Errorf("$%s: %w", HeadersEnv, err)
+	}
+	service := os.Getenv(ServiceNameEnv)
+	if service == "" {
+		service = "heapcheck"
+	}
+	return &Exporter{
+		Endpoint: strings.TrimSuffix(endpoint, "/"),
+		Headers:  headers,
+		Service:  service,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// ParseHeaders parses key=value pairs separated by commas, with
+// URL-encoded values
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid header %q (want key=value)", pair)
+		}
+		if decoded, err := url.QueryUnescape(value); err == nil {
+			value = decoded
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+// Export sends the metrics and the span of a run
+func (e *Exporter) Export(ctx context.Context, results *categorizer.Results, meta reporter.Metadata) error {
+	metrics, err := json.Marshal(e.Metrics(results, meta))
+	if err != nil {
+		return err
+	}
+	if err := e.post(ctx, "/v1/metrics", metrics); err != nil {
+		return err
+	}
+	traces, err := json.Marshal(e.Traces(results, meta))
+	if err != nil {
+		return err
+	}
+	return e.post(ctx, "/v1/traces", traces)
+}
+
+func (e *Exporter) post(ctx context.Context, path string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("exporting to %s: %w", req.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("exporting to %s: %s: %s", req.URL, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Metrics builds the metrics request of a run: the escape, stack
+// allocation, finding and suppression counts, and escapes by category
+func (e *Exporter) Metrics(results *categorizer.Results, meta reporter.Metadata) MetricsRequest {
+	now := unixNano(meta.Started.Add(meta.Duration))
+	gauge := func(name, unit, description string, points ...DataPoint) Metric {
+		for i := range points {
+			points[i].Time = now
+		}
+		return Metric{Name: name, Unit: unit, Description: description, Gauge: &Gauge{DataPoints: points}}
+	}
+	point := func(n int, attrs ...KeyValue) DataPoint {
+		return DataPoint{AsInt: strconv.Itoa(n), Attributes: attrs}
+	}
+
+	var byCategory []DataPoint
+	cats := make([]string, 0, len(results.ByCategory))
+	for cat := range results.ByCategory {
+		cats = append(cats, string(cat))
+	}
+	sort.Strings(cats)
+	for _, cat := range cats {
+		n := results.ByCategory[categorizer.Category(cat)]
+		byCategory = append(byCategory, point(n,
+			stringAttr("category", cat),
+			stringAttr("group", string(categorizer.GroupOf(categorizer.Category(cat))))))
+	}
+
+	metrics := []Metric{
+		gauge("heapcheck.escapes", "{escape}", "Values that escape to the heap", point(len(results.Escapes))),
+		gauge("heapcheck.stack_allocated", "{variable}", "Variables kept on the stack", point(results.Summary.StackAllocated)),
+		gauge("heapcheck.findings", "{finding}", "Escapes grouped by the construct causing them", point(categorizer.CountFindings(results.Escapes))),
+		gauge("heapcheck.suppressed", "{escape}", "Escapes suppressed by comments or baselines", point(results.Summary.Suppressed)),
+	}
+	if len(byCategory) > 0 {
+		metrics = append(metrics, gauge("heapcheck.escapes.by_category", "{escape}", "Escapes by category", byCategory...))
+	}
+	return MetricsRequest{ResourceMetrics: []ResourceMetrics{{
+		Resource:     e.resource(meta),
+		ScopeMetrics: []ScopeMetrics{{Scope: scope(meta), Metrics: metrics}},
+	}}}
+}
+
+// Traces builds the traces request of a run: one span from the start to
+// the end of the analysis, an error when the category gate failed
+func (e *Exporter) Traces(results *categorizer.Results, meta reporter.Metadata) TracesRequest {
+	span := Span{
+		TraceID: randomHex(16),
+		SpanID:  randomHex(8),
+		Name:    "heapcheck analysis",
+		Kind:    spanKindInternal,
+		Start:   unixNano(meta.Started),
+		End:     unixNano(meta.Started.Add(meta.Duration)),
+		Attributes: []KeyValue{
+			intAttr("heapcheck.escapes", len(results.Escapes)),
+			intAttr("heapcheck.findings", categorizer.CountFindings(results.Escapes)),
+		},
+		Status: Status{Code: statusOK},
+	}
+	if meta.Gate != nil {
+		span.Attributes = append(span.Attributes, stringAttr("heapcheck.gate.status", meta.Gate.Status))
+		if meta.Gate.Status == categorizer.GateFail {
+			span.Status = Status{Code: statusError, Message: "category gate failed"}
+		}
+	}
+	return TracesRequest{ResourceSpans: []ResourceSpans{{
+		Resource:   e.resource(meta),
+		ScopeSpans: []ScopeSpans{{Scope: scope(meta), Spans: []Span{span}}},
+	}}}
+}
+
+func (e *Exporter) resource(meta reporter.Metadata) Resource {
+	attrs := []KeyValue{stringAttr("service.name", e.Service)}
+	if meta.Version != "" {
+		attrs = append(attrs, stringAttr("service.version", meta.Version))
+	}
+	return Resource{Attributes: attrs}
+}
+
+func scope(meta reporter.Metadata) Scope {
+	return Scope{Name: scopeName, Version: meta.Version}
+}
+
+// unixNano formats a time as OTLP JSON encodes 64-bit integers, as a
+// decimal string
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// randomHex returns n random bytes, hex-encoded as OTLP JSON encodes
+// trace and span IDs
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func stringAttr(key, value string) KeyValue {
+	return KeyValue{Key: key, Value: AnyValue{StringValue: &value}}
+}
+
+func intAttr(key string, n int) KeyValue {
+	s := strconv.Itoa(n)
+	return KeyValue{Key: key, Value: AnyValue{IntValue: &s}}
+}
@@ -0,0 +1,137 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/reporter"
+)
+
+func TestExport(t *testing.T) {
+	bodies := make(map[string]string)
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies[r.URL.Path] = string(data)
+		auth = r.Header.Get("Authorization")
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv(HeadersEnv, "Authorization=Bearer%20secret")
+	t.Setenv(ServiceNameEnv, "")
+	exporter, err := New(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := &categorizer.Results{
+		Escapes: []categorizer.CategorizedEscape{
+			{Category: categorizer.CategoryFmtCall},
+			{Category: categorizer.CategoryFmtCall},
+			{Category: categorizer.CategoryReturnPointer},
+		},
+		ByCategory: map[categorizer.Category]int{categorizer.CategoryFmtCall: 2, categorizer.CategoryReturnPointer: 1},
+	}
+	meta := reporter.Metadata{
+		Version:  "1.2.3",
+		Started:  time.Unix(1700000000, 0),
+		Duration: 2 * time.Second,
+		Gate:     &categorizer.GateResult{Status: categorizer.GateFail},
+	}
+	if err := exporter.Export(context.Background(), results, meta); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the header from $%s", auth, HeadersEnv)
+	}
+
+	var metrics MetricsRequest
+	if err := json.Unmarshal([]byte(bodies["/v1/metrics"]), &metrics); err != nil {
+		t.Fatalf("invalid metrics request: %v\n%s", err, bodies["/v1/metrics"])
+	}
+	rm := metrics.ResourceMetrics[0]
+	if got := *rm.Resource.Attributes[0].Value.StringValue; got != "heapcheck" {
+		t.Errorf("service.name = %q, want heapcheck", got)
+	}
+	values := make(map[string][]string)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		for _, p := range m.Gauge.DataPoints {
+			if p.Time != "1700000002000000000" {
+				t.Errorf("%s time = %s, want the end of the run", m.Name, p.Time)
+			}
+			values[m.Name] = append(values[m.Name], p.AsInt)
+		}
+	}
+	want := map[string][]string{
+		"heapcheck.escapes":             {"3"},
+		"heapcheck.stack_allocated":     {"0"},
+		"heapcheck.findings":            {"3"},
+		"heapcheck.suppressed":          {"0"},
+		"heapcheck.escapes.by_category": {"2", "1"},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("metric values = %v, want %v", values, want)
+	}
+
+	var traces TracesRequest
+	if err := json.Unmarshal([]byte(bodies["/v1/traces"]), &traces); err != nil {
+		t.Fatalf("invalid traces request: %v\n%s", err, bodies["/v1/traces"])
+	}
+	span := traces.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if len(span.TraceID) != 32 || len(span.SpanID) != 16 {
+		t.Errorf("trace ID %q, span ID %q: want 16 and 8 hex-encoded bytes", span.TraceID, span.SpanID)
+	}
+	if span.Start != "1700000000000000000" || span.End != "1700000002000000000" {
+		t.Errorf("span = %s to %s, want the run's start and end", span.Start, span.End)
+	}
+	if span.Status.Code != statusError {
+		t.Errorf("span status = %+v, want an error for the failed gate", span.Status)
+	}
+}
+
+func TestExportError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unsupported", http.StatusUnsupportedMediaType)
+	}))
+	defer srv.Close()
+
+	exporter, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exporter.Export(context.Background(), &categorizer.Results{}, reporter.Metadata{Started: time.Now()})
+	if err == nil || !strings.Contains(err.Error(), "415") || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("Export() error = %v, want the collector's status and message", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, endpoint := range []string{"localhost:4318", "grpc://collector:4317", "http://"} {
+		if _, err := New(endpoint); err == nil {
+			t.Errorf("New(%q) succeeded, want an error", endpoint)
+		}
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	got, err := ParseHeaders("api-key=abc, x-tenant = team%2Fa,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"api-key": "abc", "x-tenant": "team/a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseHeaders() = %v, want %v", got, want)
+	}
+	if _, err := ParseHeaders("novalue"); err == nil {
+		t.Error("ParseHeaders(\"novalue\") succeeded, want an error")
+	}
+}
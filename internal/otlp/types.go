@@ -0,0 +1,106 @@
+package otlp
+
+// The OTLP messages heapcheck sends, in their JSON encoding: field names
+// in lowerCamelCase, 64-bit integers as strings, IDs in hex
+
+// MetricsRequest is an ExportMetricsServiceRequest
+type MetricsRequest struct {
+	ResourceMetrics []ResourceMetrics `json:"resourceMetrics"`
+}
+
+// ResourceMetrics are the metrics of one resource
+type ResourceMetrics struct {
+	Resource     Resource       `json:"resource"`
+	ScopeMetrics []ScopeMetrics `json:"scopeMetrics"`
+}
+
+// ScopeMetrics are the metrics of one instrumentation scope
+type ScopeMetrics struct {
+	Scope   Scope    `json:"scope"`
+	Metrics []Metric `json:"metrics"`
+}
+
+// Metric is a named gauge
+type Metric struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Unit        string `json:"unit,omitempty"`
+	Gauge       *Gauge `json:"gauge,omitempty"`
+}
+
+// Gauge holds the current values of a metric
+type Gauge struct {
+	DataPoints []DataPoint `json:"dataPoints"`
+}
+
+// DataPoint is an integer value with its attributes
+type DataPoint struct {
+	Attributes []KeyValue `json:"attributes,omitempty"`
+	Time       string     `json:"timeUnixNano"`
+	AsInt      string     `json:"asInt"`
+}
+
+// TracesRequest is an ExportTraceServiceRequest
+type TracesRequest struct {
+	ResourceSpans []ResourceSpans `json:"resourceSpans"`
+}
+
+// ResourceSpans are the spans of one resource
+type ResourceSpans struct {
+	Resource   Resource     `json:"resource"`
+	ScopeSpans []ScopeSpans `json:"scopeSpans"`
+}
+
+// ScopeSpans are the spans of one instrumentation scope
+type ScopeSpans struct {
+	Scope Scope  `json:"scope"`
+	Spans []Span `json:"spans"`
+}
+
+// Span kinds and status codes used
+const (
+	spanKindInternal = 1
+	statusOK         = 1
+	statusError      = 2
+)
+
+// Span is a timed operation
+type Span struct {
+	TraceID    string     `json:"traceId"`
+	SpanID     string     `json:"spanId"`
+	Name       string     `json:"name"`
+	Kind       int        `json:"kind"`
+	Start      string     `json:"startTimeUnixNano"`
+	End        string     `json:"endTimeUnixNano"`
+	Attributes []KeyValue `json:"attributes,omitempty"`
+	Status     Status     `json:"status"`
+}
+
+// Status is the outcome of a span
+type Status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// Resource describes what produced the data
+type Resource struct {
+	Attributes []KeyValue `json:"attributes"`
+}
+
+// Scope is the instrumentation scope
+type Scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// KeyValue is an attribute
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue is a string or integer attribute value
+type AnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
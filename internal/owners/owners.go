@@ -0,0 +1,190 @@
+// Package owners reads CODEOWNERS files and attributes escapes to the
+// teams that own their files, so allocation debt can be routed to them.
+package owners
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+// Unowned is the ByOwner key for escapes in files no rule assigns
+const Unowned = "(unowned)"
+
+// FileNames are the CODEOWNERS locations looked up by Find, in the order
+// GitHub uses them
+var FileNames = []string{
+	filepath.Join(".github", "CODEOWNERS"),
+	"CODEOWNERS",
+	filepath.Join("docs", "CODEOWNERS"),
+}
+
+// Rule assigns the files matching a pattern to owners
+type Rule struct {
+	Pattern string
+	Owners  []string // empty when the rule removes ownership
+	re      *regexp.Regexp
+}
+
+// File is a parsed CODEOWNERS file. Paths are matched relative to Root.
+type File struct {
+	Root  string
+	Rules []Rule
+}
+
+// Find returns the CODEOWNERS file in dir, or "" if there is none
+func Find(dir string) string {
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Load reads a CODEOWNERS file whose patterns are relative to root
+func Load(path, root string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading owners file: %w", err)
+	}
+	defer f.Close()
+
+	owners, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("owners file %s: %w", path, err)
+	}
+	owners.Root = root
+	return owners, nil
+}
+
+// Parse reads CODEOWNERS rules: a gitignore-style pattern followed by
+// owners, one rule per line, with # comments
+func Parse(r io.Reader) (*File, error) {
+	f := &File{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		re, err := compile(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		rule := Rule{Pattern: fields[0], re: re}
+		if len(fields) > 1 {
+			rule.Owners = fields[1:]
+		}
+		f.Rules = append(f.Rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// compile turns a CODEOWNERS pattern into a regexp over slash-separated
+// paths relative to the root. As in gitignore, a pattern without an inner
+// slash matches at any depth, and a pattern matching a directory matches
+// everything below it.
+func compile(pattern string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, "!") || strings.ContainsAny(pattern, "[]") {
+		return nil, fmt.Errorf("unsupported pattern %q: negation and character ranges are not supported", pattern)
+	}
+	dirOnly := strings.HasSuffix(pattern, "/")
+	p := strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case p[i] == '*':
+			b.WriteString("[^/]*")
+		case p[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(b.String())
+}
+
+// Owners returns the owners of path under the last matching rule, or nil
+func (f *File) Owners(path string) []string {
+	rel := path
+	if f.Root != "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			if r, err := filepath.Rel(f.Root, abs); err == nil {
+				rel = r
+			}
+		}
+	}
+	rel = strings.TrimPrefix(filepath.ToSlash(rel), "./")
+
+	for i := len(f.Rules) - 1; i >= 0; i-- {
+		if f.Rules[i].re.MatchString(rel) {
+			return f.Rules[i].Owners
+		}
+	}
+	return nil
+}
+
+// Annotate sets the owners of each escape and counts escapes by owner. An
+// escape with several owners counts once for each.
+func Annotate(results *categorizer.Results, f *File) {
+	results.Summary.ByOwner = make(map[string]int)
+	for i := range results.Escapes {
+		e := &results.Escapes[i]
+		e.Owners = f.Owners(e.Info.File)
+		if len(e.Owners) == 0 {
+			results.Summary.ByOwner[Unowned]++
+			continue
+		}
+		for _, o := range e.Owners {
+			results.Summary.ByOwner[o]++
+		}
+	}
+}
+
+// Owned reports whether owner is one of the escape's owners, ignoring case
+// as GitHub does. Unowned matches escapes without owners.
+func Owned(e categorizer.CategorizedEscape, owner string) bool {
+	if owner == Unowned {
+		return len(e.Owners) == 0
+	}
+	for _, o := range e.Owners {
+		if strings.EqualFold(o, owner) {
+			return true
+		}
+	}
+	return false
+}
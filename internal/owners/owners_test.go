@@ -0,0 +1,106 @@
+package owners
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+const codeowners = `# Default owners
+*                       @org/core
+
+/internal/api/          @org/api-team
+*.pb.go                 @org/proto
+docs/**                 @org/docs
+/internal/api/legacy/   # ownership removed
+cmd/*/main.go           @org/cli alice@example.com  # several owners
+`
+
+func TestOwners(t *testing.T) {
+	f, err := Parse(strings.NewReader(codeowners))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"main.go", []string{"@org/core"}},
+		{"./internal/api/server.go", []string{"@org/api-team"}},
+		{"internal/api/v1/handler.go", []string{"@org/api-team"}},
+		{"internal/apiclient/client.go", []string{"@org/core"}},
+		{"internal/api/types.pb.go", []string{"@org/proto"}},
+		{"docs/examples/alloc.go", []string{"@org/docs"}},
+		{"internal/api/legacy/old.go", nil},
+		{"cmd/heapcheck/main.go", []string{"@org/cli", "alice@example.com"}},
+		{"cmd/heapcheck/run.go", []string{"@org/core"}},
+	}
+	for _, tt := range tests {
+		if got := f.Owners(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Owners(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseUnsupported(t *testing.T) {
+	for _, content := range []string{"!vendor/ @org/core\n", "*.[ch] @org/c\n"} {
+		if _, err := Parse(strings.NewReader(content)); err == nil {
+			t.Errorf("Parse(%q) expected error", content)
+		}
+	}
+}
+
+func TestLoadRoot(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, ".github", "CODEOWNERS")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("/pkg/ @org/pkg\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := Find(root); got != path {
+		t.Fatalf("Find() = %q, want %q", got, path)
+	}
+	f, err := Load(path, root)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := f.Owners(filepath.Join(root, "pkg", "a.go")); !reflect.DeepEqual(got, []string{"@org/pkg"}) {
+		t.Errorf("Owners(absolute path) = %q, want [@org/pkg]", got)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	f, err := Parse(strings.NewReader(codeowners))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	results := &categorizer.Results{Escapes: []categorizer.CategorizedEscape{
+		{Info: parser.EscapeInfo{File: "./internal/api/server.go"}},
+		{Info: parser.EscapeInfo{File: "./internal/api/server.go"}},
+		{Info: parser.EscapeInfo{File: "./cmd/heapcheck/main.go"}},
+		{Info: parser.EscapeInfo{File: "./internal/api/legacy/old.go"}},
+	}}
+	Annotate(results, f)
+
+	want := map[string]int{"@org/api-team": 2, "@org/cli": 1, "alice@example.com": 1, Unowned: 1}
+	if !reflect.DeepEqual(results.Summary.ByOwner, want) {
+		t.Errorf("ByOwner = %v, want %v", results.Summary.ByOwner, want)
+	}
+	if !Owned(results.Escapes[0], "@ORG/api-team") {
+		t.Error("Owned(@ORG/api-team) = false, want true (case-insensitive)")
+	}
+	if Owned(results.Escapes[0], "@org/cli") {
+		t.Error("Owned(@org/cli) = true, want false")
+	}
+	if !Owned(results.Escapes[3], Unowned) {
+		t.Errorf("Owned(%s) = false for an unowned escape", Unowned)
+	}
+}
@@ -0,0 +1,28 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/bench"
+	"github.com/harshakonda/heapcheck/internal/synth"
+)
+
+// BenchmarkParse parses synthetic -m=2 output. Target: 100k lines in
+// under 1s on one core of a current laptop. Allocations are guarded
+// against testdata/heapcheck-bench.json.
+func BenchmarkParse(b *testing.B) {
+	for _, lines := range []int{10_000, 100_000} {
+		output := synth.CompilerOutput(lines)
+		b.Run(fmt.Sprintf("lines=%d", lines), func(b *testing.B) {
+			b.SetBytes(int64(len(output)))
+			b.ReportAllocs()
+			bench.Guard(b)
+			for i := 0; i < b.N; i++ {
+				if _, err := Parse(output); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/logging"
+)
+
+// buildAction is the subset of a go command -debug-actiongraph entry used
+// for debug logging. A build action without a command was a cache hit.
+type buildAction struct {
+	Mode      string
+	Package   string
+	Cmd       []string
+	TimeStart time.Time
+	TimeDone  time.Time
+}
+
+// actionGraphFile returns a temporary file for the go command's action
+// graph when debug logging is enabled, or "" otherwise. The graph records
+// per-package compile times and cache hits; -debug-actiongraph is a
+// debugging flag of the go command, so it is only passed for --debug.
+func actionGraphFile() string {
+	if !logging.Enabled() {
+		return ""
+	}
+	f, err := os.CreateTemp("", "heapcheck-actiongraph-*.json")
+	if err != nil {
+		logging.Logger().Debug("no action graph", "err", err)
+		return ""
+	}
+	f.Close()
+	return f.Name()
+}
+
+// logActionGraph logs how long each compiled package took, and how many
+// packages came from the build cache
+func logActionGraph(path string) {
+	log := logging.Logger()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Debug("reading action graph", "err", err)
+		return
+	}
+	var actions []buildAction
+	if err := json.Unmarshal(data, &actions); err != nil {
+		log.Debug("parsing action graph", "err", err)
+		return
+	}
+
+	var compiled, cached int
+	for _, a := range actions {
+		if a.Mode != "build" || a.Package == "" {
+			continue
+		}
+		if a.Cmd == nil {
+			cached++ // the go command replays cached compiler output
+			continue
+		}
+		compiled++
+		log.Debug("compiled package", "package", a.Package, "duration", a.TimeDone.Sub(a.TimeStart).Round(time.Millisecond))
+	}
+	log.Debug("build summary", "compiled", compiled, "cacheHits", cached)
+}
@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Sink describes how a value escapes
+type Sink string
+
+const (
+	SinkHeap      Sink = "heap"      // stored in a heap location
+	SinkReturn    Sink = "return"    // returned to the caller
+	SinkInterface Sink = "interface" // converted to an interface
+	SinkClosure   Sink = "closure"   // captured by a closure
+	SinkChannel   Sink = "channel"   // sent on a channel
+	SinkCall      Sink = "call"      // passed to a function that leaks it
+	SinkAssign    Sink = "assign"    // assigned to an escaping location
+	SinkSpill     Sink = "spill"     // spilled by the compiler
+)
+
+// Sinks lists all sink kinds
+var Sinks = []Sink{SinkHeap, SinkReturn, SinkInterface, SinkClosure, SinkChannel, SinkCall, SinkAssign, SinkSpill}
+
+// FlowStep is one "from" line of a flow: how Expr moves the value
+type FlowStep struct {
+	Expr   string `json:"expr"`
+	Reason string `json:"reason"` // e.g. "call parameter", "interface-converted"
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// Flow is one "flow:" edge of the -m=2 escape graph, from Src to Dst
+// through Steps
+type Flow struct {
+	Dst   string     `json:"dst"` // e.g. "{heap}", "~r0", "resp"
+	Src   string     `json:"src"` // e.g. "&x", "&{storage for user}"
+	Steps []FlowStep `json:"steps,omitempty"`
+}
+
+var (
+	// {heap} ← &{storage for resp}:   (older compilers print "=")
+	flowEdgeRe = regexp.MustCompile(`^(.+?) (?:←|=) (.+?):?$`)
+
+	// resp (interface-converted) at ./server.go:41:28
+	fromStepRe = regexp.MustCompile(`^(.*) \(([^()]+)\) at (.+?):(\d+)(?::(\d+))?$`)
+)
+
+// parseFlowEdge parses the text after "flow: "
+func parseFlowEdge(text string) Flow {
+	if m := flowEdgeRe.FindStringSubmatch(text); m != nil {
+		return Flow{Dst: m[1], Src: m[2]}
+	}
+	return Flow{Dst: strings.TrimSuffix(text, ":")}
+}
+
+// parseFlowStep parses the text after "from "
+func parseFlowStep(text string) FlowStep {
+	m := fromStepRe.FindStringSubmatch(text)
+	if m == nil {
+		return FlowStep{Expr: text}
+	}
+	line, _ := strconv.Atoi(m[4])
+	col, _ := strconv.Atoi(m[5])
+	return FlowStep{Expr: m[1], Reason: m[2], File: m[3], Line: line, Column: col}
+}
+
+// stepSinks maps flow step reasons to the sink they represent
+var stepSinks = map[string]Sink{
+	"interface-converted":   SinkInterface,
+	"captured by a closure": SinkClosure,
+	"send":                  SinkChannel,
+	"call parameter":        SinkCall,
+	"assign":                SinkAssign,
+	"assign-pair":           SinkAssign,
+	"spill":                 SinkSpill,
+	"return":                SinkReturn,
+}
+
+// Sinks returns how the value escapes, derived from its flows, in order
+// of first appearance. It is empty without -m=2 flow details.
+func (e EscapeInfo) Sinks() []Sink {
+	var sinks []Sink
+	seen := make(map[Sink]bool)
+	add := func(s Sink) {
+		if !seen[s] {
+			seen[s] = true
+			sinks = append(sinks, s)
+		}
+	}
+
+	for _, f := range e.Flows {
+		switch {
+		case f.Dst == "{heap}":
+			add(SinkHeap)
+		case strings.HasPrefix(f.Dst, "~r"):
+			add(SinkReturn)
+		}
+		for _, step := range f.Steps {
+			if s, ok := stepSinks[step.Reason]; ok {
+				add(s)
+			}
+		}
+	}
+	return sinks
+}
+
+// HasSink reports whether the value escapes through sink
+func (e EscapeInfo) HasSink(sink Sink) bool {
+	for _, s := range e.Sinks() {
+		if s == sink {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+const flowOutput = `./server.go:41:28: resp escapes to heap in HandleUser:
+./server.go:41:28:   flow: {heap} ← &{storage for resp}:
+./server.go:41:28:     from resp (spill) at ./server.go:41:28
+./server.go:41:28:     from (*json.Encoder).Encode(~r0, resp) (call parameter) at ./server.go:41:27
+./server.go:85:27: func literal escapes to heap in withLogging:
+./server.go:85:27:   flow: ~r0 = &{storage for func literal}:
+./server.go:85:27:     from http.HandlerFunc(func literal) (interface-converted) at ./server.go:85:26
+./server.go:85:27:     from return http.HandlerFunc(func literal) (return) at ./server.go:85:3
+./server.go:83:27: parameter logger leaks to {storage for func literal} for withLogging with derefs=0:
+./server.go:83:27:   flow: {storage for func literal} ← logger:
+./server.go:83:27:     from logger (captured by a closure) at ./server.go:87:4
+./server.go:83:27: leaking param: logger
+`
+
+func TestParseFlows(t *testing.T) {
+	escapes, err := Parse(flowOutput)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if len(escapes) != 3 {
+		t.Fatalf("Parse() = %d escapes, want 3", len(escapes))
+	}
+
+	resp := escapes[0]
+	if len(resp.Flows) != 1 {
+		t.Fatalf("resp flows = %d, want 1", len(resp.Flows))
+	}
+	flow := resp.Flows[0]
+	if flow.Dst != "{heap}" || flow.Src != "&{storage for resp}" {
+		t.Errorf("flow = %q ← %q, want {heap} ← &{storage for resp}", flow.Dst, flow.Src)
+	}
+	want := FlowStep{Expr: "(*json.Encoder).Encode(~r0, resp)", Reason: "call parameter", File: "./server.go", Line: 41, Column: 27}
+	if len(flow.Steps) != 2 || flow.Steps[1] != want {
+		t.Errorf("steps = %+v, want second step %+v", flow.Steps, want)
+	}
+
+	closure := escapes[1]
+	if len(closure.Flows) != 1 || closure.Flows[0].Dst != "~r0" {
+		t.Errorf("closure flows = %+v, want one flow to ~r0", closure.Flows)
+	}
+	if len(closure.FlowInfo) != 3 {
+		t.Errorf("closure FlowInfo = %d lines, want 3", len(closure.FlowInfo))
+	}
+
+	// The flow printed before "leaking param: logger" belongs to it
+	logger := escapes[2]
+	if logger.EscapeType != LeakingParam || len(logger.Flows) != 1 || logger.Flows[0].Src != "logger" {
+		t.Errorf("logger = %v with flows %+v, want leaking param with the logger flow", logger.EscapeType, logger.Flows)
+	}
+}
+
+func TestSinks(t *testing.T) {
+	escapes, err := Parse(flowOutput)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		e    EscapeInfo
+		want []Sink
+	}{
+		{"heap via call", escapes[0], []Sink{SinkHeap, SinkSpill, SinkCall}},
+		{"returned closure", escapes[1], []Sink{SinkReturn, SinkInterface}},
+		{"captured param", escapes[2], []Sink{SinkClosure}},
+		{"no flows", EscapeInfo{Variable: "x"}, nil},
+	}
+
+	for _, tt := range tests {
+		if got := tt.e.Sinks(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Sinks() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if !escapes[2].HasSink(SinkClosure) || escapes[1].HasSink(SinkClosure) {
+		t.Error("HasSink(closure) mismatch")
+	}
+}
+
+func TestParseFlowStepWithoutColumn(t *testing.T) {
+	got := parseFlowStep("x (assign) at ./a.go:7")
+	want := FlowStep{Expr: "x", Reason: "assign", File: "./a.go", Line: 7}
+	if got != want {
+		t.Errorf("parseFlowStep() = %+v, want %+v", got, want)
+	}
+}
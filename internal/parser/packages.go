@@ -0,0 +1,142 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ReadPackagesFile reads package patterns from a file, one per line.
+// Blank lines and lines starting with # are ignored.
+func ReadPackagesFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading packages file: %w", err)
+	}
+	defer f.Close()
+
+	patterns, err := ReadPackages(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading packages file %s: %w", path, err)
+	}
+	return patterns, nil
+}
+
+// ReadPackages reads package patterns from r, one per line, skipping
+// blank lines and # comments like ReadPackagesFile
+func ReadPackages(r io.Reader) ([]string, error) {
+	var patterns []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return patterns, nil
+}
+
+// ParseQuery splits a package query into its function and arguments.
+// Supported forms are "deps(patterns...)" for the dependency closure of
+// the given packages and plain patterns, which are listed as-is.
+func ParseQuery(query string) (fn string, args []string, err error) {
+	query = strings.TrimSpace(query)
+	open := strings.IndexByte(query, '(')
+	if open < 0 {
+		return "", strings.Fields(query), nil
+	}
+	if !strings.HasSuffix(query, ")") {
+		return "", nil, fmt.Errorf("invalid query %q: missing closing parenthesis", query)
+	}
+
+	fn = strings.TrimSpace(query[:open])
+	args = strings.FieldsFunc(query[open+1:len(query)-1], func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	if fn != "deps" {
+		return "", nil, fmt.Errorf("invalid query %q: unknown function %q (supported: deps)", query, fn)
+	}
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("invalid query %q: deps needs at least one package", query)
+	}
+	return fn, args, nil
+}
+
+// ResolveQuery runs `go list` for a package query and returns the import
+// paths it selects. Standard library packages are left out of deps(),
+// since their escapes are not actionable.
+func ResolveQuery(query string) ([]string, error) {
+	fn, args, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	listArgs := []string{"list"}
+	if fn == "deps" {
+		listArgs = append(listArgs, "-deps", "-f", "{{if not .Standard}}{{.ImportPath}}{{end}}")
+	}
+	listArgs = append(listArgs, args...)
+
+	cmd := exec.Command("go", listArgs...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go list failed: %w\n%s", err, stderr.String())
+	}
+
+	var pkgs []string
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			pkgs = append(pkgs, line)
+		}
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("query %q matched no packages", query)
+	}
+	return pkgs, nil
+}
+
+// EntrypointPackages returns the packages of your own modules linked into
+// the binaries of the given main packages: their dependency closure
+// without the standard library, third-party modules, and packages such
+// as tools or test helpers that the binaries do not import
+func EntrypointPackages(entrypoints []string) ([]string, error) {
+	args := append([]string{"list", "-deps", "-f", "{{.ImportPath}} {{.Name}} {{.DepOnly}} {{.Standard}} {{with .Module}}{{.Main}}{{end}}"}, entrypoints...)
+	cmd := exec.Command("go", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go list failed: %w\n%s", err, stderr.String())
+	}
+
+	var pkgs []string
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		path, name, depOnly, standard := fields[0], fields[1], fields[2] == "true", fields[3] == "true"
+		if !depOnly && name != "main" {
+			return nil, fmt.Errorf("entrypoint %s is package %s, not a main package", path, name)
+		}
+		// Outside module mode there is no module to tell yours apart
+		if standard || (len(fields) == 5 && fields[4] != "true") {
+			continue
+		}
+		pkgs = append(pkgs, path)
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("entrypoint %s matched no packages", strings.Join(entrypoints, ","))
+	}
+	return pkgs, nil
+}
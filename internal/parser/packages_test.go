@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadPackagesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "packages.txt")
+	content := "# api binary\n./cmd/api\n\n  example.com/internal/db  \n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadPackagesFile(path)
+	if err != nil {
+		t.Fatalf("ReadPackagesFile() error: %v", err)
+	}
+	want := []string{"./cmd/api", "example.com/internal/db"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadPackagesFile() = %v, want %v", got, want)
+	}
+}
+
+func TestReadPackages(t *testing.T) {
+	got, err := ReadPackages(strings.NewReader("example.com/a\r\n# skipped\n\texample.com/b"))
+	if err != nil {
+		t.Fatalf("ReadPackages() error: %v", err)
+	}
+	want := []string{"example.com/a", "example.com/b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadPackages() = %v, want %v", got, want)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		wantFn   string
+		wantArgs []string
+		wantErr  bool
+	}{
+		{"deps(./cmd/api)", "deps", []string{"./cmd/api"}, false},
+		{" deps( ./cmd/api, ./cmd/worker ) ", "deps", []string{"./cmd/api", "./cmd/worker"}, false},
+		{"./pkg/...", "", []string{"./pkg/..."}, false},
+		{"deps()", "", nil, true},
+		{"deps(./cmd/api", "", nil, true},
+		{"rdeps(./cmd/api)", "", nil, true},
+	}
+
+	for _, tt := range tests {
+		fn, args, err := ParseQuery(tt.query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseQuery(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if fn != tt.wantFn || !reflect.DeepEqual(args, tt.wantArgs) {
+			t.Errorf("ParseQuery(%q) = %q, %v, want %q, %v", tt.query, fn, args, tt.wantFn, tt.wantArgs)
+		}
+	}
+}
+
+func TestResolveQueryDeps(t *testing.T) {
+	pkgs, err := ResolveQuery("deps(github.com/harshakonda/heapcheck/internal/reporter)")
+	if err != nil {
+		t.Fatalf("ResolveQuery() error: %v", err)
+	}
+
+	found := make(map[string]bool)
+	for _, p := range pkgs {
+		found[p] = true
+	}
+	for _, want := range []string{
+		"github.com/harshakonda/heapcheck/internal/reporter",
+		"github.com/harshakonda/heapcheck/internal/categorizer",
+		"github.com/harshakonda/heapcheck/internal/parser",
+	} {
+		if !found[want] {
+			t.Errorf("ResolveQuery() missing %s in %v", want, pkgs)
+		}
+	}
+	if found["fmt"] {
+		t.Error("ResolveQuery() should leave out standard library packages")
+	}
+	if found["github.com/harshakonda/heapcheck/guard"] {
+		t.Error("ResolveQuery() should only return the dependency closure")
+	}
+}
+
+func TestEntrypointPackages(t *testing.T) {
+	pkgs, err := EntrypointPackages([]string{"github.com/harshakonda/heapcheck/cmd/heapcheck"})
+	if err != nil {
+		t.Fatalf("EntrypointPackages() error: %v", err)
+	}
+
+	found := make(map[string]bool)
+	for _, p := range pkgs {
+		found[p] = true
+	}
+	for _, want := range []string{
+		"github.com/harshakonda/heapcheck/cmd/heapcheck",
+		"github.com/harshakonda/heapcheck/internal/reporter",
+		"github.com/harshakonda/heapcheck/internal/parser",
+	} {
+		if !found[want] {
+			t.Errorf("EntrypointPackages() missing %s in %v", want, pkgs)
+		}
+	}
+	for _, unwanted := range []string{"fmt", "gopkg.in/yaml.v3", "github.com/harshakonda/heapcheck/guard"} {
+		if found[unwanted] {
+			t.Errorf("EntrypointPackages() includes %s, which is not a package of this module linked into heapcheck", unwanted)
+		}
+	}
+
+	_, err = EntrypointPackages([]string{"github.com/harshakonda/heapcheck/internal/reporter"})
+	if err == nil || !strings.Contains(err.Error(), "not a main package") {
+		t.Errorf("EntrypointPackages() of a library = %v, want a not a main package error", err)
+	}
+}
@@ -5,11 +5,22 @@ package parser
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"path"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/logging"
 )
 
 // EscapeType represents the type of escape analysis result
@@ -23,6 +34,15 @@ const (
 	LeakingParam             // "leaking param: x"
 	CanInline                // "can inline foo"
 	InliningCall             // "inlining call to foo"
+	CannotInline             // "cannot inline foo: reason"
+
+	// LeakingParamContent is "leaking param content: x": what x points
+	// to, such as the backing array of a slice or the entries of a map,
+	// escapes, while x itself does not. It comes last so that the numbers
+	// older versions saved keep their meaning.
+	LeakingParamContent
+
+	lastEscapeType = LeakingParamContent
 )
 
 func (e EscapeType) String() string {
@@ -39,13 +59,57 @@ func (e EscapeType) String() string {
 		return "can-inline"
 	case InliningCall:
 		return "inlining-call"
+	case CannotInline:
+		return "cannot-inline"
+	case LeakingParamContent:
+		return "leaking-param-content"
 	default:
 		return "unknown"
 	}
 }
 
+// ParseEscapeType returns the escape type named s, as String names it
+func ParseEscapeType(s string) (EscapeType, error) {
+	for t := Unknown; t <= lastEscapeType; t++ {
+		if t.String() == s {
+			return t, nil
+		}
+	}
+	return Unknown, fmt.Errorf("unknown escape type %q", s)
+}
+
+// MarshalJSON writes the escape type by name, e.g. "moved-to-heap", so
+// saved results do not depend on the order of the constants
+func (e EscapeType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.String())
+}
+
+// UnmarshalJSON reads an escape type by name, or as the number results
+// saved by older versions hold
+func (e *EscapeType) UnmarshalJSON(data []byte) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err == nil {
+		if n < int(Unknown) || n > int(lastEscapeType) {
+			return fmt.Errorf("unknown escape type %d", n)
+		}
+		*e = EscapeType(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("escape type: %w", err)
+	}
+	t, err := ParseEscapeType(s)
+	if err != nil {
+		return err
+	}
+	*e = t
+	return nil
+}
+
 // EscapeInfo represents a single escape analysis result
 type EscapeInfo struct {
+	Package    string     `json:"package,omitempty"` // import path from the "# pkg" header
 	File       string     `json:"file"`
 	Line       int        `json:"line"`
 	Column     int        `json:"column"`
@@ -53,153 +117,611 @@ type EscapeInfo struct {
 	EscapeType EscapeType `json:"escapeType"`
 	Reason     string     `json:"reason"`
 	FlowInfo   []string   `json:"flowInfo,omitempty"` // Additional flow details from -m=2
+	Flows      []Flow     `json:"flows,omitempty"`    // FlowInfo as a structured graph
+
+	// Size and Align are the heap object's size and alignment in bytes,
+	// which newer compilers append to some escape lines, e.g.
+	// "x escapes to heap (size 64, align 8)"; 0 when not stated
+	Size  int64 `json:"size,omitempty"`
+	Align int64 `json:"align,omitempty"`
+
+	// InlinedFrom lists the calls inlined at the escape's position,
+	// outermost first. The compiler reports escapes in inlined code at
+	// the call site in the caller, which File and Line keep; the last
+	// frame is the function the escaping code was written in.
+	InlinedFrom []InlineFrame `json:"inlinedFrom,omitempty"`
+}
+
+// InlineFrame is a function inlined at the position of an escape
+type InlineFrame struct {
+	Func string `json:"func"` // as the compiler names it, e.g. "sub.(*T).Clone"
+
+	// File, Line and Column are where Func is declared, when its "can
+	// inline" line is in the same output; empty otherwise
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+// String returns the function name and its declaration, if known
+func (f InlineFrame) String() string {
+	if f.File == "" {
+		return f.Func
+	}
+	return fmt.Sprintf("%s (%s:%d)", f.Func, f.File, f.Line)
+}
+
+// InlineStack returns the calls inlined at the escape's position, e.g.
+// "wrap → newT (./t.go:7)", or "" when the escape is not in inlined code
+func (e EscapeInfo) InlineStack() string {
+	frames := make([]string, len(e.InlinedFrom))
+	for i, f := range e.InlinedFrom {
+		frames[i] = f.String()
+	}
+	return strings.Join(frames, " → ")
 }
 
+// PackageOrDir returns the import path of the escape's package, or the
+// directory of its file for output without package headers
+func (e EscapeInfo) PackageOrDir() string {
+	if e.Package != "" {
+		return e.Package
+	}
+	return filepath.Dir(e.File)
+}
+
+// AutogeneratedFile is the position label the compiler uses for
+// generated code such as method wrappers and equality functions, which has
+// a line but no column (e.g. "<autogenerated>:1: leaking param: p")
+const AutogeneratedFile = "<autogenerated>"
+
+// IsSynthetic reports whether file is a compiler label such as
+// "<autogenerated>" rather than a source file
+func IsSynthetic(file string) bool {
+	return strings.HasPrefix(file, "<") && strings.HasSuffix(file, ">")
+}
+
+// pos matches the position prefix of a compiler line: file, line and an
+// optional column, which is missing for synthetic files
+const pos = `^(.+?):(\d+)(?::(\d+))?:`
+
 // Patterns for matching escape analysis output
 var (
 	// ./file.go:10:2: moved to heap: x
-	movedToHeapRe = regexp.MustCompile(`^(.+):(\d+):(\d+): moved to heap: (.+)$`)
+	movedToHeapRe = regexp.MustCompile(pos + ` moved to heap: (.+)$`)
 
 	// ./file.go:10:2: x escapes to heap
-	escapesToHeapRe = regexp.MustCompile(`^(.+):(\d+):(\d+): (.+) escapes to heap`)
+	escapesToHeapRe = regexp.MustCompile(pos + ` (.+) escapes to heap`)
+
+	// ./file.go:10:2: x escapes to heap (size 64, align 8):
+	// The detail newer compilers append to escape lines, which is also
+	// written "(size=64 align=8)"
+	heapDetailRe = regexp.MustCompile(` \(((?:size|align)[ =]\d+(?:,? (?:size|align)[ =]\d+)?)\)`)
+	heapFieldRe  = regexp.MustCompile(`(size|align)[ =](\d+)`)
 
 	// ./file.go:10:2: x does not escape
-	doesNotEscapeRe = regexp.MustCompile(`^(.+):(\d+):(\d+): (.+) does not escape$`)
+	doesNotEscapeRe = regexp.MustCompile(pos + ` (.+) does not escape$`)
 
 	// ./file.go:10:2: leaking param: x
-	leakingParamRe = regexp.MustCompile(`^(.+):(\d+):(\d+): leaking param: (.+)`)
+	leakingParamRe = regexp.MustCompile(pos + ` leaking param: (.+)`)
+
+	// ./file.go:10:2: leaking param content: x
+	leakingParamContentRe = regexp.MustCompile(pos + ` leaking param content: (.+)`)
+
+	// ./file.go:10:2: can inline foo with cost 4 as: func() { ... }
+	canInlineRe = regexp.MustCompile(pos + ` can inline (\S+)(?: with cost .*)?$`)
 
-	// ./file.go:10:2: can inline foo
-	canInlineRe = regexp.MustCompile(`^(.+):(\d+):(\d+): can inline (.+)$`)
+	// ./file.go:10:2: cannot inline foo: function too complex: cost 98 exceeds budget 80
+	cannotInlineRe = regexp.MustCompile(pos + ` cannot inline (\S+): (.+)$`)
 
 	// ./file.go:10:2: inlining call to foo
-	inliningCallRe = regexp.MustCompile(`^(.+):(\d+):(\d+): inlining call to (.+)$`)
+	inliningCallRe = regexp.MustCompile(pos + ` inlining call to (.+)$`)
 
 	// ./file.go:10:2:   flow: ~r0 = &x:
-	flowRe = regexp.MustCompile(`^(.+):(\d+):(\d+):\s+flow: (.+)$`)
+	flowRe = regexp.MustCompile(pos + `\s+flow: (.+)$`)
 
 	// ./file.go:10:2:     from &x (address-of) at ./file.go:10:9
-	fromRe = regexp.MustCompile(`^(.+):(\d+):(\d+):\s+from (.+)$`)
+	fromRe = regexp.MustCompile(pos + `\s+from (.+)$`)
+
+	// Any other line with a position, such as the output of extra -d
+	// debug flags, which is kept with the escape at its position
+	positionedRe = regexp.MustCompile(pos)
+
+	// Lines the parser knows but has no use for, which are not counted
+	// as unrecognized
+	ignoredRes = []*regexp.Regexp{
+		// ./file.go:10:2: parameter x leaks to {heap} with derefs=0:
+		regexp.MustCompile(pos + ` parameter \S+ leaks to .+:$`),
+		// ./file.go:10:2: f capturing by ref: x (addr=false assign=false width=8)
+		regexp.MustCompile(pos + ` \S+ capturing by (?:ref|value): `),
+	}
 )
 
-// RunCompiler executes `go build` with escape analysis flags and returns the output
-func RunCompiler(patterns []string) (string, error) {
-	// Build the command
+// RunCompiler compiles the packages with escape analysis flags and returns the output
+func RunCompiler(ctx context.Context, patterns []string) (string, error) {
 	// -gcflags="-m=2" gives detailed escape analysis
-	// -l disables inlining for clearer escape info (optional, we include both)
-	args := []string{"build", "-gcflags=-m=2", "-o", "/dev/null"}
+	return RunCompilerWithFlags(ctx, patterns, "-m=2")
+}
+
+// waitDelay is how long a canceled go command gets to stop its compiler
+// processes after an interrupt before it is killed
+const waitDelay = 5 * time.Second
+
+// RunCompilerWithFlags compiles the packages with the given -gcflags
+// value, e.g. "-m=2 -l" to disable inlining, and returns the compiler
+// output.
+//
+// It runs `go list -export` rather than `go build`: that compiles each
+// package to export data, printing the same diagnostics, but skips
+// linking main packages, which dominates analysis time for large binaries.
+//
+// Canceling ctx interrupts the go command, which stops the compilers it
+// started, and returns ctx's error.
+func RunCompilerWithFlags(ctx context.Context, patterns []string, gcflags string) (string, error) {
+	return RunCompilerStream(ctx, patterns, gcflags, nil)
+}
+
+// RunCompilerStream is like RunCompilerWithFlags, and also copies the
+// compiler output to progress as the go command prints it, e.g. to a
+// PackageWriter. A nil progress is ignored.
+func RunCompilerStream(ctx context.Context, patterns []string, gcflags string, progress io.Writer) (string, error) {
+	output, _, err := Compile(ctx, patterns, gcflags, progress)
+	return output, err
+}
+
+// Compile is like RunCompilerStream, and also returns the packages the
+// analysis left out: those that failed to compile, with the Go files of
+// cgo packages analyzed on their own where possible (see notAnalyzed),
+// and those build constraints exclude.
+func Compile(ctx context.Context, patterns []string, gcflags string, progress io.Writer) (string, []SkippedPackage, error) {
+	output, listed, err := compile(ctx, patterns, gcflags, progress, false)
+	if err != nil {
+		return "", nil, err
+	}
+	output, skipped, err := notAnalyzed(ctx, output, listed, gcflags, progress)
+	if err != nil {
+		return "", nil, err
+	}
+	return output, append(skipped, excludedPackages(ctx, patterns, listed)...), nil
+}
+
+// listedPackage is the part of `go list -json` output compile reads
+type listedPackage struct {
+	ImportPath string
+	Dir        string
+	Export     string // empty if the package failed to compile
+	CgoFiles   []string
+	Deps       []string
+	Error      *struct{ Err string }
+}
+
+// compile runs the go command for RunCompilerStream and returns the
+// compiler output and the packages it listed. With pureGo it builds
+// with CGO_ENABLED=0 and -e, so that packages with no Go files left
+// are reported in their Error rather than stopping the go command.
+func compile(ctx context.Context, patterns []string, gcflags string, progress io.Writer, pureGo bool) (string, []listedPackage, error) {
+	// Build the command
+	args := []string{"list", "-export", "-json=ImportPath,Dir,Export,CgoFiles,Deps,Error", "-gcflags=" + gcflags}
+	if pureGo {
+		args = append(args, "-e")
+	}
+	if graph := actionGraphFile(); graph != "" {
+		defer os.Remove(graph)
+		defer logActionGraph(graph)
+		args = append(args, "-debug-actiongraph="+graph)
+	}
 	args = append(args, patterns...)
 
-	cmd := exec.Command("go", args...)
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = waitDelay
+	if pureGo {
+		cmd.Env = append(os.Environ(), "CGO_ENABLED=0")
+	}
 
 	// Escape analysis output goes to stderr
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+	stderr := newSpillBuffer()
+	defer stderr.Close()
+	cmd.Stderr = stderr
+	if progress != nil {
+		cmd.Stderr = io.MultiWriter(stderr, progress)
+	}
 
-	// We don't care about stdout for this
+	// stdout lists the packages and whether they compiled
 	var stdout bytes.Buffer
 	cmd.Stdout = &stdout
 
+	log := logging.Logger()
+	log.Debug("running go", "args", strings.Join(args, " "), "GOFLAGS", os.Getenv("GOFLAGS"), "GOWORK", os.Getenv("GOWORK"), "pureGo", pureGo)
+	started := time.Now()
+
 	// Run the command - it may return non-zero if there are build errors
 	err := cmd.Run()
+	log.Debug("go finished", "duration", time.Since(started).Round(time.Millisecond), "outputBytes", stderr.Len(), "err", err)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return "", nil, ctxErr
+	}
 
 	// If there's output in stderr, we got escape analysis data
 	// Even if cmd failed (build errors), we might have partial data
-	output := stderr.String()
+	output, readErr := stderr.String()
+	if readErr != nil {
+		return "", nil, fmt.Errorf("buffering compiler output: %w", readErr)
+	}
+	listed := decodeListed(stdout.Bytes())
 
 	// If we have no output and an error, something went wrong
 	if output == "" && err != nil {
-		return "", fmt.Errorf("go build failed: %w", err)
+		return "", nil, fmt.Errorf("go list -export failed: %w", err)
 	}
 
-	return output, nil
+	// Without a "# pkg" header nothing was compiled: either the patterns
+	// matched no Go packages, or the go command failed on the module
+	// setup, e.g. a missing go.sum entry or inconsistent vendoring
+	if !strings.Contains("\n"+output, "\n# ") {
+		if unmatched := unmatchedPatterns(output); len(unmatched) > 0 {
+			return "", nil, &NoPackagesError{Patterns: unmatched}
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("go list -export failed: %w\n%s", err, strings.TrimSpace(output))
+		}
+	}
+
+	return output, listed, nil
+}
+
+// NoPackagesError reports package patterns that matched no Go packages,
+// so that an empty run is not mistaken for a clean one
+type NoPackagesError struct {
+	Patterns []string
+}
+
+func (e *NoPackagesError) Error() string {
+	return "no Go packages matched " + strings.Join(e.Patterns, " ")
+}
+
+// noPackagesPatterns match the go command's messages for patterns that
+// resolve to no Go packages
+var noPackagesPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^go: warning: "(.+)" matched no packages$`),
+	regexp.MustCompile(`^pattern (.+?): .*no such file or directory$`),
+	regexp.MustCompile(`^no Go files in (.+)$`),
+	regexp.MustCompile(`^package (\S+) is not in std`),
+}
+
+// unmatchedPatterns returns the patterns the go command reported as
+// matching no Go packages
+func unmatchedPatterns(output string) []string {
+	var patterns []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		for _, re := range noPackagesPatterns {
+			if m := re.FindStringSubmatch(line); m != nil {
+				patterns = append(patterns, m[1])
+				break
+			}
+		}
+	}
+	return patterns
 }
 
-// Parse parses the raw compiler output into structured EscapeInfo slice
+// Parse parses the raw compiler output into structured EscapeInfo slice.
+//
+// Flow lines repeat the position of the escape they describe, and are
+// attached by that position rather than to the most recent escape:
+// the go command interleaves the output of packages compiled in parallel, which
+// can separate a flow block from its parent line. Unrecognized lines at the
+// position of an escape, such as those of extra compiler debug flags, are
+// passed through to its FlowInfo as they are.
 func Parse(output string) ([]EscapeInfo, error) {
+	return ParseContext(context.Background(), output)
+}
+
+// checkEvery is how many lines ParseContext parses between checks for
+// cancellation
+const checkEvery = 1024
+
+// ParseContext is Parse, stopping with ctx's error when ctx is canceled
+func ParseContext(ctx context.Context, output string) ([]EscapeInfo, error) {
+	results, _, err := ParseWithStats(ctx, output)
+	return results, err
+}
+
+// Stats describes the compiler output read by a parse
+type Stats struct {
+	Lines        int      // non-empty lines
+	Unrecognized int      // lines matching no known pattern
+	Packages     int      // package headers
+	Unparsed     []string // the unrecognized lines, in order
+}
+
+// ParseWithStats is ParseContext, also returning statistics about the
+// output. Many unrecognized lines suggest the compiler's output format
+// changed and the parser needs updating.
+func ParseWithStats(ctx context.Context, output string) ([]EscapeInfo, Stats, error) {
 	var results []EscapeInfo
+	var stats Stats
 
-	scanner := bufio.NewScanner(strings.NewReader(output))
-	var currentEscape *EscapeInfo
+	// byPos maps a position to the latest escape reported there; pending
+	// holds flow lines seen before their escape, as with the "parameter x
+	// leaks to ..." header that precedes "leaking param: x"
+	byPos := make(map[string]int)
+	pending := make(map[string][]string)
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	// unknown holds the indexes in stats.Unparsed of unrecognized lines
+	// by position, and passed those attached to an escape after all
+	unknown := make(map[string][]int)
+	passed := make(map[int]bool)
+
+	// pkg is the import path from the last "# example.com/pkg" header
+	var pkg string
+
+	// Results per package, for the debug log
+	byPackage := make(map[string]int)
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Split(scanLines)
+	for n := 0; scanner.Scan(); n++ {
+		if n%checkEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, Stats{}, err
+			}
+		}
+		line := cleanLine(scanner.Text())
 
 		// Skip empty lines
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
+		stats.Lines++
 
-		// Try to match each pattern
-		if info := parseMovedToHeap(line); info != nil {
-			if currentEscape != nil {
-				results = append(results, *currentEscape)
+		if header, ok := strings.CutPrefix(line, "# "); ok {
+			pkg = packageFromHeader(header)
+			if _, ok := byPackage[pkg]; !ok {
+				byPackage[pkg] = 0 // logged even without results
 			}
-			currentEscape = info
 			continue
 		}
 
-		if info := parseEscapesToHeap(line); info != nil {
-			if currentEscape != nil {
-				results = append(results, *currentEscape)
+		if info := parseLine(line); info != nil {
+			info.Package = pkg
+			results = append(results, *info)
+			byPackage[pkg]++
+			if !hasFlows(info.EscapeType) {
+				continue
 			}
-			currentEscape = info
+			key := positionKey(info.File, info.Line, info.Column)
+			byPos[key] = len(results) - 1
+			for _, flowLine := range pending[key] {
+				addFlowLine(&results[len(results)-1], flowLine)
+			}
+			delete(pending, key)
+			for _, i := range unknown[key] {
+				addFlowLine(&results[len(results)-1], stats.Unparsed[i])
+				passed[i] = true
+			}
+			delete(unknown, key)
 			continue
 		}
 
-		if info := parseDoesNotEscape(line); info != nil {
-			if currentEscape != nil {
-				results = append(results, *currentEscape)
+		// Flow/from lines add details to the escape at their position
+		m := flowRe.FindStringSubmatch(line)
+		if m == nil {
+			m = fromRe.FindStringSubmatch(line)
+		}
+		if m == nil {
+			if ignored(line) {
+				continue
 			}
-			currentEscape = info
+			if m := positionedRe.FindStringSubmatch(line); m != nil && m[3] != "" {
+				lineNum, _ := strconv.Atoi(m[2])
+				colNum, _ := strconv.Atoi(m[3])
+				key := positionKey(m[1], lineNum, colNum)
+				if i, ok := byPos[key]; ok {
+					addFlowLine(&results[i], line)
+					continue
+				}
+				unknown[key] = append(unknown[key], len(stats.Unparsed))
+			}
+			stats.Unparsed = append(stats.Unparsed, line)
 			continue
 		}
+		lineNum, _ := strconv.Atoi(m[2])
+		colNum, _ := strconv.Atoi(m[3])
+		key := positionKey(m[1], lineNum, colNum)
+		if i, ok := byPos[key]; ok {
+			addFlowLine(&results[i], line)
+		} else {
+			pending[key] = append(pending[key], line)
+		}
+	}
 
-		if info := parseLeakingParam(line); info != nil {
-			if currentEscape != nil {
-				results = append(results, *currentEscape)
+	if err := scanner.Err(); err != nil {
+		return nil, Stats{}, fmt.Errorf("scanning output: %w", err)
+	}
+	stats.Packages = len(byPackage)
+	if len(passed) > 0 {
+		unparsed := stats.Unparsed[:0]
+		for i, line := range stats.Unparsed {
+			if !passed[i] {
+				unparsed = append(unparsed, line)
 			}
-			currentEscape = info
-			continue
 		}
+		stats.Unparsed = unparsed
+	}
+	stats.Unrecognized = len(stats.Unparsed)
+	annotateInlined(results)
 
-		if info := parseCanInline(line); info != nil {
-			if currentEscape != nil {
-				results = append(results, *currentEscape)
-			}
-			currentEscape = info
-			continue
+	if log := logging.Logger(); logging.Enabled() {
+		pkgs := make([]string, 0, len(byPackage))
+		for p := range byPackage {
+			pkgs = append(pkgs, p)
+		}
+		sort.Strings(pkgs)
+		for _, p := range pkgs {
+			log.Debug("package results", "package", p, "results", byPackage[p])
 		}
+		log.Debug("parsed compiler output", "lines", stats.Lines, "results", len(results), "unrecognized", stats.Unrecognized, "packages", stats.Packages)
+	}
+	return results, stats, nil
+}
 
-		if info := parseInliningCall(line); info != nil {
-			if currentEscape != nil {
-				results = append(results, *currentEscape)
+// annotateInlined sets InlinedFrom of the escapes at the position of an
+// "inlining call to" line, looking up each inlined function's declaration
+// in the "can inline" lines. Calls into other packages name the function
+// by package name, e.g. "sub.New", which is matched against the last
+// element of the import paths.
+func annotateInlined(results []EscapeInfo) {
+	calls := make(map[string][]string)
+	decls := make(map[string]InlineFrame)
+	for _, r := range results {
+		switch r.EscapeType {
+		case InliningCall:
+			// Test variants repeat a package's output, and a function
+			// is never inlined into itself
+			key := positionKey(r.File, r.Line, r.Column)
+			if !slices.Contains(calls[key], r.Variable) {
+				calls[key] = append(calls[key], r.Variable)
 			}
-			currentEscape = info
-			continue
+		case CanInline:
+			frame := InlineFrame{Func: r.Variable, File: r.File, Line: r.Line, Column: r.Column}
+			decls[r.Package+"\x00"+r.Variable] = frame
+			decls["\x00"+path.Base(r.Package)+"."+r.Variable] = frame
 		}
+	}
+	if len(calls) == 0 {
+		return
+	}
 
-		// Check for flow/from lines (additional details for current escape)
-		if currentEscape != nil {
-			if flowRe.MatchString(line) || fromRe.MatchString(line) {
-				currentEscape.FlowInfo = append(currentEscape.FlowInfo, strings.TrimSpace(line))
+	for i := range results {
+		r := &results[i]
+		switch r.EscapeType {
+		case InliningCall, CanInline, CannotInline:
+			continue
+		}
+		funcs := calls[positionKey(r.File, r.Line, r.Column)]
+		if len(funcs) == 0 {
+			continue
+		}
+		r.InlinedFrom = make([]InlineFrame, len(funcs))
+		for j, fn := range funcs {
+			frame, ok := decls[r.Package+"\x00"+fn]
+			if !ok {
+				frame, ok = decls["\x00"+fn]
+			}
+			if !ok {
+				frame = InlineFrame{Func: fn}
 			}
+			frame.Func = fn
+			r.InlinedFrom[j] = frame
 		}
 	}
+}
+
+// scanLines is bufio.ScanLines, also ending lines at a lone "\r", which
+// some tools converting line endings leave in place of "\r\n"
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	i := bytes.IndexAny(data, "\r\n")
+	switch {
+	case i < 0 && atEOF:
+		return len(data), data, nil
+	case i < 0:
+		return 0, nil, nil
+	case data[i] == '\n':
+		return i + 1, data[:i], nil
+	case i+1 < len(data) && data[i+1] == '\n':
+		return i + 2, data[:i], nil
+	case i+1 < len(data) || atEOF:
+		return i + 1, data[:i], nil
+	}
+	// A "\r" ending the buffer may start a "\r\n"
+	return 0, nil, nil
+}
+
+// cleanLine removes what Windows tools and localized toolchains add to
+// compiler output: a UTF-8 byte order mark, which editors and PowerShell
+// redirection write at the start of a file, carriage returns left by
+// "\r\n" line endings, and byte sequences that are not valid UTF-8, which
+// are replaced with U+FFFD so that reports stay valid JSON and HTML
+func cleanLine(line string) string {
+	line = strings.TrimPrefix(line, "\ufeff")
+	line = strings.TrimRight(line, "\r")
+	return strings.ToValidUTF8(line, "\ufffd")
+}
 
-	// Don't forget the last one
-	if currentEscape != nil {
-		results = append(results, *currentEscape)
+// ignored reports whether line is known compiler output the parser skips
+func ignored(line string) bool {
+	for _, re := range ignoredRes {
+		if re.MatchString(line) {
+			return true
+		}
 	}
+	return false
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scanning output: %w", err)
+// packageFromHeader returns the import path of a package header such as
+// "example.com/pkg" or "example.com/pkg [example.com/pkg.test]"
+func packageFromHeader(header string) string {
+	if fields := strings.Fields(header); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// parseLine parses a line reporting an escape analysis or inlining result
+func parseLine(line string) *EscapeInfo {
+	for _, parse := range []func(string) *EscapeInfo{
+		parseMovedToHeap,
+		parseEscapesToHeap,
+		parseDoesNotEscape,
+		parseLeakingParam,
+		parseLeakingParamContent,
+		parseCanInline,
+		parseInliningCall,
+		parseCannotInline,
+	} {
+		if info := parse(line); info != nil {
+			return info
+		}
 	}
+	return nil
+}
 
-	return results, nil
+// hasFlows reports whether -m=2 prints flow details for results of type t
+func hasFlows(t EscapeType) bool {
+	return t == MovedToHeap || t == EscapesToHeap || t == LeakingParam || t == LeakingParamContent
+}
+
+func positionKey(file string, line, col int) string {
+	return file + ":" + strconv.Itoa(line) + ":" + strconv.Itoa(col)
+}
+
+// addFlowLine adds a flow or from line to e, or any other line as it is
+func addFlowLine(e *EscapeInfo, line string) {
+	if m := flowRe.FindStringSubmatch(line); m != nil {
+		e.FlowInfo = append(e.FlowInfo, strings.TrimSpace(line))
+		e.Flows = append(e.Flows, parseFlowEdge(m[4]))
+	} else if m := fromRe.FindStringSubmatch(line); m != nil {
+		e.FlowInfo = append(e.FlowInfo, strings.TrimSpace(line))
+		if n := len(e.Flows); n > 0 {
+			flow := &e.Flows[n-1]
+			flow.Steps = append(flow.Steps, parseFlowStep(m[4]))
+		}
+	} else {
+		e.FlowInfo = append(e.FlowInfo, strings.TrimSpace(line))
+	}
 }
 
 func parseMovedToHeap(line string) *EscapeInfo {
+	line, size, align := heapDetail(line)
 	matches := movedToHeapRe.FindStringSubmatch(line)
 	if matches == nil {
 		return nil
@@ -213,10 +735,13 @@ func parseMovedToHeap(line string) *EscapeInfo {
 		Variable:   matches[4],
 		EscapeType: MovedToHeap,
 		Reason:     line,
+		Size:       size,
+		Align:      align,
 	}
 }
 
 func parseEscapesToHeap(line string) *EscapeInfo {
+	line, size, align := heapDetail(line)
 	matches := escapesToHeapRe.FindStringSubmatch(line)
 	if matches == nil {
 		return nil
@@ -230,9 +755,30 @@ func parseEscapesToHeap(line string) *EscapeInfo {
 		Variable:   matches[4],
 		EscapeType: EscapesToHeap,
 		Reason:     line,
+		Size:       size,
+		Align:      align,
 	}
 }
 
+// heapDetail removes the size and alignment detail from an escape line
+// and returns the line without it, and the size and alignment
+func heapDetail(line string) (string, int64, int64) {
+	loc := heapDetailRe.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return line, 0, 0
+	}
+	var size, align int64
+	for _, m := range heapFieldRe.FindAllStringSubmatch(line[loc[2]:loc[3]], -1) {
+		n, _ := strconv.ParseInt(m[2], 10, 64)
+		if m[1] == "size" {
+			size = n
+		} else {
+			align = n
+		}
+	}
+	return line[:loc[0]] + line[loc[1]:], size, align
+}
+
 func parseDoesNotEscape(line string) *EscapeInfo {
 	matches := doesNotEscapeRe.FindStringSubmatch(line)
 	if matches == nil {
@@ -267,6 +813,23 @@ func parseLeakingParam(line string) *EscapeInfo {
 	}
 }
 
+func parseLeakingParamContent(line string) *EscapeInfo {
+	matches := leakingParamContentRe.FindStringSubmatch(line)
+	if matches == nil {
+		return nil
+	}
+	lineNum, _ := strconv.Atoi(matches[2])
+	colNum, _ := strconv.Atoi(matches[3])
+	return &EscapeInfo{
+		File:       matches[1],
+		Line:       lineNum,
+		Column:     colNum,
+		Variable:   matches[4],
+		EscapeType: LeakingParamContent,
+		Reason:     line,
+	}
+}
+
 func parseCanInline(line string) *EscapeInfo {
 	matches := canInlineRe.FindStringSubmatch(line)
 	if matches == nil {
@@ -284,6 +847,25 @@ func parseCanInline(line string) *EscapeInfo {
 	}
 }
 
+// parseCannotInline parses an inlining failure; Reason holds the
+// compiler's reason rather than the whole line
+func parseCannotInline(line string) *EscapeInfo {
+	matches := cannotInlineRe.FindStringSubmatch(line)
+	if matches == nil {
+		return nil
+	}
+	lineNum, _ := strconv.Atoi(matches[2])
+	colNum, _ := strconv.Atoi(matches[3])
+	return &EscapeInfo{
+		File:       matches[1],
+		Line:       lineNum,
+		Column:     colNum,
+		Variable:   matches[4],
+		EscapeType: CannotInline,
+		Reason:     matches[5],
+	}
+}
+
 func parseInliningCall(line string) *EscapeInfo {
 	matches := inliningCallRe.FindStringSubmatch(line)
 	if matches == nil {
@@ -1,6 +1,12 @@
 package parser
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
 	"testing"
 )
 
@@ -72,6 +78,43 @@ func TestParseEscapesToHeap(t *testing.T) {
 	}
 }
 
+func TestParseHeapDetail(t *testing.T) {
+	tests := []struct {
+		input    string
+		variable string
+		reason   string
+		size     int64
+		align    int64
+	}{
+		{"./main.go:8:14: &T{} escapes to heap (size 64, align 8)", "&T{}", "./main.go:8:14: &T{} escapes to heap", 64, 8},
+		{"./main.go:8:14: &T{} escapes to heap (size=24 align=8) in F:", "&T{}", "./main.go:8:14: &T{} escapes to heap in F:", 24, 8},
+		{"./main.go:10:2: moved to heap: x (size 16)", "x", "./main.go:10:2: moved to heap: x", 16, 0},
+		{"./main.go:10:2: moved to heap: x", "x", "./main.go:10:2: moved to heap: x", 0, 0},
+		{"./main.go:8:14: f(x) (value) escapes to heap", "f(x) (value)", "./main.go:8:14: f(x) (value) escapes to heap", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			results, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("Parse() got %d results, want 1", len(results))
+			}
+			r := results[0]
+			if r.Variable != tt.variable {
+				t.Errorf("Variable = %q, want %q", r.Variable, tt.variable)
+			}
+			if r.Reason != tt.reason {
+				t.Errorf("Reason = %q, want %q", r.Reason, tt.reason)
+			}
+			if r.Size != tt.size || r.Align != tt.align {
+				t.Errorf("Size, Align = %d, %d, want %d, %d", r.Size, r.Align, tt.size, tt.align)
+			}
+		})
+	}
+}
+
 func TestParseDoesNotEscape(t *testing.T) {
 	input := "./main.go:11:13: x does not escape"
 	results, err := Parse(input)
@@ -102,23 +145,49 @@ func TestParseLeakingParam(t *testing.T) {
 	}
 }
 
+func TestParseLeakingParamContent(t *testing.T) {
+	input := "./main.go:20:13: leaking param content: samples"
+	results, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Parse() got %d results, want 1", len(results))
+	}
+	r := results[0]
+	if r.EscapeType != LeakingParamContent {
+		t.Errorf("EscapeType = %v, want LeakingParamContent", r.EscapeType)
+	}
+	if r.Variable != "samples" {
+		t.Errorf("Variable = %v, want samples", r.Variable)
+	}
+}
+
 func TestParseInlining(t *testing.T) {
-	input := `./main.go:15:6: can inline square
-./main.go:35:10: inlining call to foo`
+	input := `./main.go:15:6: can inline square with cost 4 as: func(int) int { return x * x }
+./main.go:35:10: inlining call to foo
+./main.go:40:6: cannot inline (*Server).Run: function too complex: cost 98 exceeds budget 80`
 
 	results, err := Parse(input)
 	if err != nil {
 		t.Fatalf("Parse() error = %v", err)
 	}
-	if len(results) != 2 {
-		t.Fatalf("Parse() got %d results, want 2", len(results))
+	if len(results) != 3 {
+		t.Fatalf("Parse() got %d results, want 3", len(results))
 	}
-	if results[0].EscapeType != CanInline {
-		t.Errorf("results[0].EscapeType = %v, want CanInline", results[0].EscapeType)
+	if results[0].EscapeType != CanInline || results[0].Variable != "square" {
+		t.Errorf("results[0] = %v %q, want CanInline square", results[0].EscapeType, results[0].Variable)
 	}
 	if results[1].EscapeType != InliningCall {
 		t.Errorf("results[1].EscapeType = %v, want InliningCall", results[1].EscapeType)
 	}
+	failed := results[2]
+	if failed.EscapeType != CannotInline || failed.Variable != "(*Server).Run" {
+		t.Errorf("results[2] = %v %q, want CannotInline (*Server).Run", failed.EscapeType, failed.Variable)
+	}
+	if want := "function too complex: cost 98 exceeds budget 80"; failed.Reason != want {
+		t.Errorf("results[2].Reason = %q, want %q", failed.Reason, want)
+	}
 }
 
 func TestParseMultipleLines(t *testing.T) {
@@ -190,6 +259,8 @@ func TestEscapeTypeString(t *testing.T) {
 		{LeakingParam, "leaking-param"},
 		{CanInline, "can-inline"},
 		{InliningCall, "inlining-call"},
+		{CannotInline, "cannot-inline"},
+		{LeakingParamContent, "leaking-param-content"},
 		{Unknown, "unknown"},
 	}
 
@@ -201,3 +272,387 @@ func TestEscapeTypeString(t *testing.T) {
 		})
 	}
 }
+
+func TestEscapeTypeJSON(t *testing.T) {
+	for et := Unknown; et <= lastEscapeType; et++ {
+		data, err := json.Marshal(et)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `"` + et.String() + `"`; string(data) != want {
+			t.Errorf("Marshal(%v) = %s, want %s", et, data, want)
+		}
+		var got EscapeType
+		if err := json.Unmarshal(data, &got); err != nil || got != et {
+			t.Errorf("Unmarshal(%s) = %v, %v, want %v", data, got, err, et)
+		}
+	}
+
+	tests := []struct {
+		data    string
+		want    EscapeType
+		wantErr bool
+	}{
+		{`"leaking-param"`, LeakingParam, false},
+		{`"leaking-param-content"`, LeakingParamContent, false},
+		{`1`, MovedToHeap, false}, // saved by older versions
+		{`7`, CannotInline, false},
+		{`"escaped"`, Unknown, true},
+		{`42`, Unknown, true},
+		{`true`, Unknown, true},
+	}
+	for _, tt := range tests {
+		var got EscapeType
+		err := json.Unmarshal([]byte(tt.data), &got)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, %v, want %v (error %v)", tt.data, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestEscapeInfoJSONRoundTrip(t *testing.T) {
+	info := EscapeInfo{
+		Package:    "example.com/app",
+		File:       "./main.go",
+		Line:       12,
+		Column:     2,
+		Variable:   "u",
+		EscapeType: MovedToHeap,
+		Reason:     "moved to heap",
+		FlowInfo:   []string{"flow: ~r0 ← &u:"},
+		Flows: []Flow{{Dst: "~r0", Src: "&u", Steps: []FlowStep{
+			{Expr: "&u", Reason: "spill", File: "./main.go", Line: 13, Column: 9},
+		}}},
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`"escapeType":"moved-to-heap"`)) {
+		t.Errorf("escapeType not written by name: %s", data)
+	}
+	var got EscapeInfo
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, info) {
+		t.Errorf("round trip = %+v, want %+v", got, info)
+	}
+}
+
+func TestParseColumnless(t *testing.T) {
+	data, err := os.ReadFile("testdata/autogenerated.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := Parse(string(data))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := []struct {
+		file       string
+		line, col  int
+		escapeType EscapeType
+		flows      int
+	}{
+		{"internal/baseline/baseline.go", 122, 20, EscapesToHeap, 0},
+		{AutogeneratedFile, 1, 0, EscapesToHeap, 2},
+		{AutogeneratedFile, 1, 0, InliningCall, 0},
+		{AutogeneratedFile, 1, 0, LeakingParam, 0},
+		{"internal/baseline/baseline.go", 130, 6, CanInline, 0},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("Parse() got %d results, want %d", len(results), len(want))
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.File != w.file || r.Line != w.line || r.Column != w.col || r.EscapeType != w.escapeType {
+			t.Errorf("results[%d] = %s:%d:%d %v, want %s:%d:%d %v", i, r.File, r.Line, r.Column, r.EscapeType, w.file, w.line, w.col, w.escapeType)
+		}
+		if len(r.Flows) != w.flows {
+			t.Errorf("results[%d] has %d flows, want %d", i, len(r.Flows), w.flows)
+		}
+	}
+
+	step := results[1].Flows[0].Steps[0]
+	if step.File != AutogeneratedFile || step.Line != 1 || step.Column != 0 {
+		t.Errorf("step position = %s:%d:%d, want %s:1:0", step.File, step.Line, step.Column, AutogeneratedFile)
+	}
+}
+
+func TestIsSynthetic(t *testing.T) {
+	tests := map[string]bool{
+		AutogeneratedFile: true,
+		"./main.go":       false,
+		"pkg/<x>.go":      false,
+	}
+	for file, want := range tests {
+		if got := IsSynthetic(file); got != want {
+			t.Errorf("IsSynthetic(%q) = %v, want %v", file, got, want)
+		}
+	}
+}
+
+func TestParseContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := ParseContext(ctx, "./main.go:12:2: moved to heap: z\n"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ParseContext(canceled) error = %v, want context.Canceled", err)
+	}
+}
+
+func TestUnmatchedPatterns(t *testing.T) {
+	output := `go: warning: "./docs/..." matched no packages
+pattern ./nonexist/...: lstat ./nonexist/: no such file or directory
+no Go files in /src/mod/testdata
+package fmtx is not in std (/usr/local/go/src/fmtx)
+go: downloading example.com/dep v1.0.0
+`
+	got := unmatchedPatterns(output)
+	want := []string{"./docs/...", "./nonexist/...", "/src/mod/testdata", "fmtx"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unmatchedPatterns() = %q, want %q", got, want)
+	}
+
+	err := &NoPackagesError{Patterns: want[:1]}
+	if got, want := err.Error(), "no Go packages matched ./docs/..."; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestParseInterleaved(t *testing.T) {
+	// Two packages compiled in parallel: b.go's escape is reported between
+	// a.go's header and its flow lines
+	input := `./a/a.go:10:2: x escapes to heap in A:
+./b/b.go:5:9: y escapes to heap in B:
+./a/a.go:10:2:   flow: {heap} ← &{storage for x}:
+./b/b.go:5:9:   flow: ~r0 ← &{storage for y}:
+./a/a.go:10:2:     from x (spill) at ./a/a.go:10:2
+./b/b.go:5:9:     from return &y (return) at ./b/b.go:6:2
+./a/a.go:10:2:     from sink(x) (call parameter) at ./a/a.go:11:6
+`
+	results, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Parse() got %d results, want 2", len(results))
+	}
+
+	x, y := results[0], results[1]
+	if len(x.FlowInfo) != 3 || len(x.Flows) != 1 || len(x.Flows[0].Steps) != 2 {
+		t.Errorf("x: %d flow lines, flows %+v, want 3 lines in one flow with 2 steps", len(x.FlowInfo), x.Flows)
+	}
+	if len(y.FlowInfo) != 2 || len(y.Flows) != 1 || y.Flows[0].Dst != "~r0" {
+		t.Errorf("y: %d flow lines, flows %+v, want 2 lines in one flow to ~r0", len(y.FlowInfo), y.Flows)
+	}
+}
+
+func TestParsePackageHeaders(t *testing.T) {
+	input := `# example.com/app/server
+./server/handler.go:12:2: moved to heap: req
+# example.com/app/store [example.com/app/store.test]
+./store/store.go:8:6: leaking param: db
+`
+	results, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Parse() got %d results, want 2", len(results))
+	}
+	if got := results[0].Package; got != "example.com/app/server" {
+		t.Errorf("results[0].Package = %q, want example.com/app/server", got)
+	}
+	if got := results[1].Package; got != "example.com/app/store" {
+		t.Errorf("results[1].Package = %q, want example.com/app/store", got)
+	}
+}
+
+func TestParseWindowsAndLocalizedOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantVar []string
+	}{
+		{
+			name:    "CRLF line endings",
+			input:   "# example.com/app\r\n./main.go:12:2: moved to heap: z\r\n./main.go:14:2: y does not escape\r\n",
+			wantVar: []string{"z", "y"},
+		},
+		{
+			name:    "lone carriage returns",
+			input:   "# example.com/app\r./main.go:12:2: moved to heap: z\r./main.go:14:2: y does not escape",
+			wantVar: []string{"z", "y"},
+		},
+		{
+			name:    "byte order mark",
+			input:   "\ufeff# example.com/app\n./main.go:12:2: moved to heap: z\n",
+			wantVar: []string{"z"},
+		},
+		{
+			name:    "byte order mark without header",
+			input:   "\ufeff./main.go:12:2: moved to heap: z\r\n",
+			wantVar: []string{"z"},
+		},
+		{
+			name:    "invalid UTF-8",
+			input:   "# example.com/app\n./main.go:12:2: moved to heap: caf\xe9\n",
+			wantVar: []string{"caf\ufffd"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, stats, err := ParseWithStats(context.Background(), tt.input)
+			if err != nil {
+				t.Fatalf("ParseWithStats() error = %v", err)
+			}
+			var vars []string
+			for _, r := range results {
+				vars = append(vars, r.Variable)
+				if r.File != "./main.go" {
+					t.Errorf("File = %q, want ./main.go", r.File)
+				}
+			}
+			if !reflect.DeepEqual(vars, tt.wantVar) {
+				t.Errorf("variables = %q, want %q", vars, tt.wantVar)
+			}
+			if stats.Unrecognized != 0 {
+				t.Errorf("unrecognized lines: %q", stats.Unparsed)
+			}
+		})
+	}
+}
+
+func TestParseInlinedFrom(t *testing.T) {
+	input := `# example.com/app/sub
+sub/sub.go:7:6: can inline (*T).Clone with cost 9 as: method(t *T) func() *T { c := *t; return &c }
+sub/sub.go:7:26: moved to heap: c
+# example.com/app
+./t.go:7:6: can inline newT with cost 8 as: func() *T { t := &T{}; return t }
+./t.go:12:6: can inline wrap with cost 10 as: func() *T { return newT() }
+./t.go:13:13: inlining call to newT
+./t.go:17:11: inlining call to wrap
+./t.go:17:11: inlining call to newT
+./t.go:18:16: inlining call to sub.(*T).Clone
+./t.go:19:9: inlining call to strings.Clone
+./t.go:8:7: &T{} escapes to heap
+./t.go:17:11: &T{} escapes to heap
+./t.go:18:16: moved to heap: sub.c
+./t.go:19:9: ... argument does not escape
+# example.com/app [example.com/app.test]
+./t.go:17:11: inlining call to wrap
+./t.go:17:11: inlining call to newT
+./t.go:17:11: &T{} escapes to heap
+`
+	results, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	got := make(map[string]string)
+	for _, r := range results {
+		switch r.EscapeType {
+		case CanInline, InliningCall:
+			if r.InlinedFrom != nil {
+				t.Errorf("%s: InlinedFrom = %v, want none on inlining results", r.Reason, r.InlinedFrom)
+			}
+		default:
+			got[r.Reason] = r.InlineStack()
+		}
+	}
+	want := map[string]string{
+		"sub/sub.go:7:26: moved to heap: c":         "",
+		"./t.go:8:7: &T{} escapes to heap":          "",
+		"./t.go:17:11: &T{} escapes to heap":        "wrap (./t.go:12) → newT (./t.go:7)",
+		"./t.go:18:16: moved to heap: sub.c":        "sub.(*T).Clone (sub/sub.go:7)",
+		"./t.go:19:9: ... argument does not escape": "strings.Clone",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("inline stacks = %q, want %q", got, want)
+	}
+
+	var frame InlineFrame
+	for _, r := range results {
+		if r.Reason == "./t.go:18:16: moved to heap: sub.c" {
+			frame = r.InlinedFrom[0]
+		}
+	}
+	if want := (InlineFrame{Func: "sub.(*T).Clone", File: "sub/sub.go", Line: 7, Column: 6}); frame != want {
+		t.Errorf("frame = %+v, want %+v", frame, want)
+	}
+}
+
+func TestParsePassesThroughUnrecognized(t *testing.T) {
+	output := `# example.com/app
+./main.go:7:2: debug: before the escape
+./main.go:7:2: moved to heap: u
+./main.go:7:2: debug: after the escape
+./main.go:9:3: debug: no escape here
+`
+	results, stats, err := ParseWithStats(context.Background(), output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	want := []string{"./main.go:7:2: debug: before the escape", "./main.go:7:2: debug: after the escape"}
+	if !reflect.DeepEqual(results[0].FlowInfo, want) {
+		t.Errorf("FlowInfo = %q, want %q", results[0].FlowInfo, want)
+	}
+	if len(results[0].Flows) != 0 {
+		t.Errorf("Flows = %+v, want none", results[0].Flows)
+	}
+	if stats.Unrecognized != 1 || !reflect.DeepEqual(stats.Unparsed, []string{"./main.go:9:3: debug: no escape here"}) {
+		t.Errorf("Unrecognized = %d, Unparsed = %q, want only the line without an escape", stats.Unrecognized, stats.Unparsed)
+	}
+}
+
+func TestPackageOrDir(t *testing.T) {
+	tests := []struct {
+		e    EscapeInfo
+		want string
+	}{
+		{EscapeInfo{Package: "example.com/app/server", File: "./server/handler.go"}, "example.com/app/server"},
+		{EscapeInfo{File: "./server/handler.go"}, "server"},
+	}
+	for _, tt := range tests {
+		if got := tt.e.PackageOrDir(); got != tt.want {
+			t.Errorf("PackageOrDir(%+v) = %q, want %q", tt.e, got, tt.want)
+		}
+	}
+}
+
+func TestParseWithStats(t *testing.T) {
+	output := `# example.com/app
+./main.go:10:6: can inline square with cost 4 as: func(int) int { return x * x }
+./main.go:12:2: parameter x leaks to {heap} with derefs=0:
+./main.go:12:2:   flow: {heap} = x:
+./main.go:12:2:     from return x (return) at ./main.go:13:2
+./main.go:12:2: leaking param: x
+./main.go:15:3: run.func1 capturing by ref: n (addr=false assign=true width=8)
+./main.go:20:2: x shadows a new diagnostic
+
+# example.com/app/util
+./util/util.go:5:2: moved to heap: buf
+`
+	results, stats, err := ParseWithStats(context.Background(), output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Errorf("got %d results, want 3", len(results))
+	}
+	want := Stats{
+		Lines:        10,
+		Unrecognized: 1,
+		Packages:     2,
+		Unparsed:     []string{"./main.go:20:2: x shadows a new diagnostic"},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("Stats = %+v, want %+v", stats, want)
+	}
+}
@@ -0,0 +1,151 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/buildctx"
+	"github.com/harshakonda/heapcheck/internal/logging"
+)
+
+// Plan is what compiling some patterns with escape analysis output would
+// do, found without compiling: the packages analyzed, whether the build
+// cache holds their compiler output, and the packages left out
+type Plan struct {
+	Packages []PlannedPackage `json:"packages"`
+	Excluded []SkippedPackage `json:"excluded,omitempty"`
+
+	// Dependencies are the other packages the analyzed ones import,
+	// compiled without escape analysis; Stale of them are not cached
+	Dependencies      int `json:"dependencies"`
+	StaleDependencies int `json:"staleDependencies"`
+}
+
+// PlannedPackage is a package a compile would analyze
+type PlannedPackage struct {
+	Package string `json:"package"`
+	Dir     string `json:"dir"`
+	Files   int    `json:"files"` // Go and cgo files compiled
+	Lines   int    `json:"lines"`
+
+	// Cached is set when the build cache holds the package's compiler
+	// output for these flags, which is replayed rather than compiled;
+	// otherwise Reason says why it is compiled
+	Cached bool   `json:"cached"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Compiled returns the packages that are not cached, and their files and
+// lines
+func (p *Plan) Compiled() (packages, files, lines int) {
+	for _, pkg := range p.Packages {
+		if !pkg.Cached {
+			packages++
+			files += pkg.Files
+			lines += pkg.Lines
+		}
+	}
+	return packages, files, lines
+}
+
+// plannedListing is the part of `go list -deps -json` output PlanCompile
+// reads
+type plannedListing struct {
+	ImportPath  string
+	Dir         string
+	GoFiles     []string
+	CgoFiles    []string
+	DepOnly     bool
+	Stale       bool
+	StaleReason string
+	Error       *struct{ Err string }
+}
+
+// PlanCompile returns the plan of Compile for the same patterns and
+// gcflags. It asks the go command which packages the patterns select and
+// which of them are stale with gcflags, which hashes their inputs but
+// compiles nothing. Packages the go command cannot load, and those build
+// constraints exclude, are listed as excluded.
+func PlanCompile(ctx context.Context, patterns []string, gcflags string) (*Plan, error) {
+	args := append([]string{"list", "-e", "-deps", "-json=ImportPath,Dir,GoFiles,CgoFiles,DepOnly,Stale,StaleReason,Error", "-gcflags=" + gcflags}, patterns...)
+	cmd := exec.CommandContext(ctx, "go", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	logging.Logger().Debug("running go", "args", strings.Join(args, " "), "GOFLAGS", os.Getenv("GOFLAGS"), "GOWORK", os.Getenv("GOWORK"))
+	if err := cmd.Run(); err != nil && stdout.Len() == 0 {
+		return nil, fmt.Errorf("go list failed: %w\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	plan := &Plan{Packages: []PlannedPackage{}}
+	var selected []listedPackage
+	for _, p := range decodePlanned(stdout.Bytes()) {
+		if p.DepOnly {
+			plan.Dependencies++
+			if p.Stale {
+				plan.StaleDependencies++
+			}
+			continue
+		}
+		selected = append(selected, listedPackage{ImportPath: p.ImportPath, Dir: p.Dir})
+		if p.Error != nil {
+			plan.Excluded = append(plan.Excluded, SkippedPackage{Package: p.ImportPath, Reason: FirstLine(p.Error.Err)})
+			continue
+		}
+		planned := PlannedPackage{
+			Package: p.ImportPath,
+			Dir:     p.Dir,
+			Files:   len(p.GoFiles) + len(p.CgoFiles),
+			Cached:  !p.Stale,
+			Reason:  p.StaleReason,
+		}
+		for _, f := range append(p.GoFiles, p.CgoFiles...) {
+			planned.Lines += countLines(filepath.Join(p.Dir, f))
+		}
+		plan.Packages = append(plan.Packages, planned)
+	}
+	// Like Compile, fail only when there is no package to analyze
+	if len(plan.Packages) == 0 {
+		if unmatched := unmatchedPatterns(stderr.String()); len(unmatched) > 0 {
+			return nil, &NoPackagesError{Patterns: unmatched}
+		}
+		if len(plan.Excluded) > 0 {
+			return nil, fmt.Errorf("%s: %s", plan.Excluded[0].Package, plan.Excluded[0].Reason)
+		}
+	}
+	plan.Excluded = append(plan.Excluded, excludedPackages(ctx, patterns, selected)...)
+	return plan, nil
+}
+
+// decodePlanned decodes the packages of `go list -json` output for
+// PlanCompile, as far as it is well-formed
+func decodePlanned(data []byte) []plannedListing {
+	var listed []plannedListing
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var p plannedListing
+		if err := dec.Decode(&p); err != nil {
+			return listed
+		}
+		listed = append(listed, p)
+	}
+}
+
+// countLines returns the number of lines of a file, 0 if it cannot be
+// read
+func countLines(path string) int {
+	data, err := buildctx.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return 0
+	}
+	n := bytes.Count(data, []byte("\n"))
+	if data[len(data)-1] != '\n' {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanCompile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":          "module plan\n\ngo 1.22\n",
+		"a/a.go":          "package a\n\nimport \"plan/b\"\n\nfunc New() *int {\n\tx := b.N\n\treturn &x\n}\n",
+		"b/b.go":          "package b\n\nconst N = 1\n",
+		"tagged/never.go": "//go:build never\n\npackage tagged\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("GOFLAGS", "")
+	t.Setenv("GOWORK", "off")
+
+	ctx := context.Background()
+	if _, _, err := Compile(ctx, []string{"./..."}, "-m=2", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a", "a.go"), []byte(files["a/a.go"]+"\nvar V = 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := PlanCompile(ctx, []string{"./a", "./b", "./tagged/..."}, "-m=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]PlannedPackage)
+	for _, p := range plan.Packages {
+		got[p.Package] = p
+	}
+	if a := got["plan/a"]; a.Cached || a.Reason == "" || a.Files != 1 || a.Lines != 10 {
+		t.Errorf("plan/a = %+v, want an edited package to compile, with 1 file of 10 lines", a)
+	}
+	if b := got["plan/b"]; !b.Cached {
+		t.Errorf("plan/b = %+v, want the compiled package cached", b)
+	}
+	if len(plan.Packages) != 2 {
+		t.Errorf("packages = %+v, want plan/a and plan/b", plan.Packages)
+	}
+	if len(plan.Excluded) != 1 || plan.Excluded[0].Package != "plan/tagged" || !plan.Excluded[0].Excluded {
+		t.Errorf("excluded = %+v, want plan/tagged, which build constraints exclude", plan.Excluded)
+	}
+	if packages, files, lines := plan.Compiled(); packages != 1 || files != 1 || lines != 10 {
+		t.Errorf("Compiled() = %d, %d, %d, want plan/a alone", packages, files, lines)
+	}
+
+	if _, err := PlanCompile(ctx, []string{"./missing"}, "-m=2"); err == nil {
+		t.Error("PlanCompile() of a missing directory succeeded")
+	}
+}
@@ -0,0 +1,243 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/logging"
+)
+
+// SkippedPackage is a package the analysis left out: one that failed to
+// compile, e.g. on a syntax error or a missing C header, or whose files
+// build constraints exclude, which have Excluded set. With PureGo set,
+// cgo failed to build it but its Go files were analyzed without the cgo
+// ones.
+type SkippedPackage struct {
+	Package  string `json:"package"`
+	Reason   string `json:"reason"`
+	PureGo   bool   `json:"pureGo,omitempty"`
+	Excluded bool   `json:"excluded,omitempty"`
+}
+
+// runtimeCgo fails to build when the C compiler is missing, and with it
+// every package using cgo, without blocks of their own
+const runtimeCgo = "runtime/cgo"
+
+// dependencyFailed is the reason of a package that did not compile
+// because a package it imports did not
+const dependencyFailed = "depends on %s, which failed to build"
+
+// notAnalyzed returns the packages of a compile that failed to build,
+// with the first error of each. If any of them uses cgo, they are first
+// compiled again with CGO_ENABLED=0, so that a cgo build error does not
+// hide the escapes of the Go files around it: packages using cgo lose
+// their cgo files, and packages importing them build against the Go
+// files alone. The failed packages' blocks in output are then replaced
+// by the retry's. No linking flags are needed: `go list -export` never
+// links, so cgo only has to compile.
+func notAnalyzed(ctx context.Context, output string, listed []listedPackage, gcflags string, progress io.Writer) (string, []SkippedPackage, error) {
+	var failed []listedPackage
+	isFailed := map[string]bool{}
+	usesCgo := false
+	for _, p := range listed {
+		if p.Export == "" {
+			failed = append(failed, p)
+			isFailed[p.ImportPath] = true
+			usesCgo = usesCgo || len(p.CgoFiles) > 0
+		}
+	}
+	if len(failed) == 0 {
+		return output, nil, nil
+	}
+
+	reasons := blockReasons(output)
+	reasonOf := func(p listedPackage) string {
+		if reason := reasons[p.ImportPath]; reason != "" {
+			return reason
+		}
+		for _, dep := range p.Deps {
+			if isFailed[dep] {
+				return fmt.Sprintf(dependencyFailed, dep)
+			}
+		}
+		if reason := reasons[runtimeCgo]; reason != "" && slices.Contains(p.Deps, runtimeCgo) {
+			return reason
+		}
+		if p.Error != nil {
+			return FirstLine(p.Error.Err)
+		}
+		return "failed to build"
+	}
+
+	compiled := make(map[string]bool)
+	if usesCgo {
+		patterns := make([]string, len(failed))
+		for i, p := range failed {
+			patterns[i] = p.ImportPath
+		}
+		logging.Logger().Debug("retrying without cgo", "packages", strings.Join(patterns, " "))
+		retried, relisted, err := compile(ctx, patterns, gcflags, progress, true)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", nil, err
+			}
+			logging.Logger().Debug("retry without cgo failed", "err", err)
+		}
+		for _, p := range relisted {
+			compiled[p.ImportPath] = p.Export != ""
+		}
+		output = dropBlocks(output, func(pkg string) bool {
+			return compiled[pkg] || pkg == runtimeCgo
+		}) + retried
+	}
+
+	var skipped []SkippedPackage
+	for _, p := range failed {
+		switch {
+		case !compiled[p.ImportPath]:
+			skipped = append(skipped, SkippedPackage{Package: p.ImportPath, Reason: reasonOf(p)})
+		case len(p.CgoFiles) > 0:
+			skipped = append(skipped, SkippedPackage{Package: p.ImportPath, Reason: reasonOf(p), PureGo: true})
+		}
+	}
+	return output, skipped, nil
+}
+
+// blockReasons returns the first line of each package block in output,
+// the error of a package that failed to build
+func blockReasons(output string) map[string]string {
+	reasons := make(map[string]string)
+	pw := NewPackageWriter(func(pkg, block string) {
+		if pkg != "" && reasons[pkg] == "" {
+			_, rest, _ := strings.Cut(block, "\n")
+			reasons[pkg] = FirstLine(rest)
+		}
+	})
+	io.WriteString(pw, output)
+	pw.Close()
+	return reasons
+}
+
+// dropBlocks returns output without the blocks of the packages drop
+// reports
+func dropBlocks(output string, drop func(pkg string) bool) string {
+	var kept strings.Builder
+	pw := NewPackageWriter(func(pkg, block string) {
+		if pkg == "" || !drop(pkg) {
+			kept.WriteString(block)
+		}
+	})
+	io.WriteString(pw, output)
+	pw.Close()
+	return kept.String()
+}
+
+// FirstLine returns the first line of a multi-line message, such as an
+// error of the go command, without surrounding space
+func FirstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
+
+// excludedPackages returns the packages under the directory trees of
+// local "/..." patterns whose Go files build constraints all exclude.
+// The go command leaves them out of such patterns without a word, so
+// they are found by looking for directories with Go files that listed
+// does not cover, and asking the go command why.
+func excludedPackages(ctx context.Context, patterns []string, listed []listedPackage) []SkippedPackage {
+	covered := make(map[string]bool)
+	for _, p := range listed {
+		covered[p.Dir] = true
+	}
+
+	var dirs []string
+	for _, pattern := range patterns {
+		root, ok := strings.CutSuffix(pattern, "/...")
+		if !ok || !(filepath.IsAbs(root) || root == "." || strings.HasPrefix(root, "./") || strings.HasPrefix(root, "../")) {
+			continue
+		}
+		root, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			if path != root && skipDir(path, d.Name()) {
+				return filepath.SkipDir
+			}
+			if !covered[path] && hasGoFiles(path) {
+				covered[path] = true
+				dirs = append(dirs, path)
+			}
+			return nil
+		})
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+
+	cmd := exec.CommandContext(ctx, "go", append([]string{"list", "-e", "-json=ImportPath,Error"}, dirs...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		logging.Logger().Debug("listing excluded packages failed", "err", err)
+		return nil
+	}
+	var skipped []SkippedPackage
+	for _, p := range decodeListed(out) {
+		if p.Error != nil {
+			skipped = append(skipped, SkippedPackage{Package: p.ImportPath, Reason: FirstLine(p.Error.Err), Excluded: true})
+		}
+	}
+	return skipped
+}
+
+// skipDir reports whether the go command's "..." patterns leave out a
+// directory: testdata, vendor, names starting with . or _, and nested
+// modules
+func skipDir(path, name string) bool {
+	if name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(path, "go.mod"))
+	return err == nil
+}
+
+// hasGoFiles reports whether dir has Go files other than tests
+func hasGoFiles(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() && strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeListed decodes the packages of `go list -json` output, as far
+// as it is well-formed
+func decodeListed(data []byte) []listedPackage {
+	var listed []listedPackage
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var p listedPackage
+		if err := dec.Decode(&p); err != nil {
+			return listed
+		}
+		listed = append(listed, p)
+	}
+}
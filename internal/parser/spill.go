@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// bufferLimit is how many bytes of compiler output are held in memory
+// while the go command runs, 0 for no limit
+var bufferLimit atomic.Int64
+
+// SetBufferLimit caps the compiler output held in memory while the go
+// command runs at n bytes. Output beyond it goes to a temporary file,
+// read back into one string of its exact size when the command exits,
+// rather than into a buffer that grows by doubling and is then copied.
+// 0 removes the cap.
+func SetBufferLimit(n int64) {
+	bufferLimit.Store(n)
+}
+
+// spillBuffer collects output in memory up to limit bytes, then moves it
+// to a temporary file
+type spillBuffer struct {
+	limit int64
+	mem   bytes.Buffer
+	file  *os.File
+	n     int64
+	err   error // the first error writing the file
+}
+
+func newSpillBuffer() *spillBuffer {
+	return &spillBuffer{limit: bufferLimit.Load()}
+}
+
+// Write never fails, so that the go command's output is never cut short;
+// an error writing the temporary file is returned by String
+func (b *spillBuffer) Write(p []byte) (int, error) {
+	b.n += int64(len(p))
+	if b.err != nil {
+		return len(p), nil
+	}
+	if b.file == nil && (b.limit <= 0 || int64(b.mem.Len()+len(p)) <= b.limit) {
+		return b.mem.Write(p)
+	}
+	if b.file == nil {
+		f, err := os.CreateTemp("", "heapcheck-output-*")
+		if err != nil {
+			b.err = err
+			return len(p), nil
+		}
+		b.file = f
+		if _, err := b.mem.WriteTo(f); err != nil {
+			b.err = err
+			return len(p), nil
+		}
+		b.mem = bytes.Buffer{}
+	}
+	if _, err := b.file.Write(p); err != nil {
+		b.err = err
+	}
+	return len(p), nil
+}
+
+// Len returns how many bytes were written
+func (b *spillBuffer) Len() int {
+	return int(b.n)
+}
+
+// String returns everything written, reading it back from the temporary
+// file if the output spilled
+func (b *spillBuffer) String() (string, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+	if b.file == nil {
+		return b.mem.String(), nil
+	}
+	if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.Grow(int(b.n))
+	if _, err := io.Copy(&sb, b.file); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// Close removes the temporary file, if any
+func (b *spillBuffer) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	b.file.Close()
+	return os.Remove(b.file.Name())
+}
@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSpillBuffer(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int64
+		writes  []string
+		spilled bool
+	}{
+		{"no limit", 0, []string{"a\n", "bb\n", "ccc\n"}, false},
+		{"under limit", 64, []string{"a\n", "bb\n"}, false},
+		{"at limit", 5, []string{"a\n", "bb\n"}, false},
+		{"over limit", 4, []string{"a\n", "bb\n", "ccc\n"}, true},
+		{"first write over limit", 1, []string{"long line\n"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &spillBuffer{limit: tt.limit}
+			for _, w := range tt.writes {
+				if n, err := b.Write([]byte(w)); n != len(w) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v", w, n, err)
+				}
+			}
+			if got := b.file != nil; got != tt.spilled {
+				t.Errorf("spilled = %v, want %v", got, tt.spilled)
+			}
+			want := strings.Join(tt.writes, "")
+			if b.Len() != len(want) {
+				t.Errorf("Len() = %d, want %d", b.Len(), len(want))
+			}
+			got, err := b.String()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("String() = %q, want %q", got, want)
+			}
+
+			var name string
+			if b.file != nil {
+				name = b.file.Name()
+			}
+			if err := b.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if name != "" {
+				if _, err := os.Stat(name); !os.IsNotExist(err) {
+					t.Errorf("temporary file %s not removed: %v", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSetBufferLimit(t *testing.T) {
+	defer SetBufferLimit(0)
+	SetBufferLimit(10)
+	if got := newSpillBuffer().limit; got != 10 {
+		t.Errorf("limit = %d, want 10", got)
+	}
+}
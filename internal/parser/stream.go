@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// PackageWriter splits compiler output written to it into the blocks the
+// go command prints per package, each starting with a "# pkg" header, and
+// calls fn with each block once it is complete: when the next header
+// arrives, or on Close. Output before the first header, or without any,
+// is passed with an empty package.
+type PackageWriter struct {
+	fn func(pkg, output string)
+
+	mu      sync.Mutex
+	partial []byte          // the last line, until its newline arrives
+	pkg     string          // package of the current block
+	block   strings.Builder // lines of the current block
+}
+
+// NewPackageWriter returns a writer that calls fn with each package block
+func NewPackageWriter(fn func(pkg, output string)) *PackageWriter {
+	return &PackageWriter{fn: fn}
+}
+
+// Write buffers p and reports the blocks it completes
+func (w *PackageWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		w.line(string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+}
+
+// Close reports the last block
+func (w *PackageWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) > 0 {
+		w.line(string(w.partial))
+		w.partial = nil
+	}
+	w.flush()
+	return nil
+}
+
+func (w *PackageWriter) line(line string) {
+	line = cleanLine(line)
+	if header, ok := strings.CutPrefix(line, "# "); ok {
+		w.flush()
+		w.pkg = packageFromHeader(header)
+	}
+	w.block.WriteString(line)
+	w.block.WriteByte('\n')
+}
+
+// flush reports the current block, unless it is empty
+func (w *PackageWriter) flush() {
+	if w.pkg != "" || strings.TrimSpace(w.block.String()) != "" {
+		w.fn(w.pkg, w.block.String())
+	}
+	w.pkg = ""
+	w.block.Reset()
+}
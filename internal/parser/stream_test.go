@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPackageWriter(t *testing.T) {
+	type block struct{ pkg, output string }
+	var got []block
+	w := NewPackageWriter(func(pkg, output string) {
+		got = append(got, block{pkg, output})
+	})
+
+	// Writes split lines and headers at arbitrary points
+	for _, chunk := range []string{
+		"go: downloading example.com/dep v1.0.0\n# exam",
+		"ple.com/a\n./a.go:3:2: moved to heap: x\n",
+		"# example.com/b [example.com/b.test]\n./b.go:5:6: y escapes to heap",
+	} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(got) != 2 {
+		t.Fatalf("before Close: got %d block(s), want 2 complete ones", len(got))
+	}
+	w.Close()
+
+	want := []block{
+		{"", "go: downloading example.com/dep v1.0.0\n"},
+		{"example.com/a", "# example.com/a\n./a.go:3:2: moved to heap: x\n"},
+		{"example.com/b", "# example.com/b [example.com/b.test]\n./b.go:5:6: y escapes to heap\n"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("blocks = %q, want %q", got, want)
+	}
+}
+
+func TestPackageWriterCRLF(t *testing.T) {
+	var got []string
+	w := NewPackageWriter(func(pkg, output string) {
+		got = append(got, pkg, output)
+	})
+	// The "\r\n" is split across writes
+	w.Write([]byte("\ufeff# example.com/a\r"))
+	w.Write([]byte("\n./a.go:3:2: moved to heap: x\r\n"))
+	w.Close()
+
+	want := []string{"example.com/a", "# example.com/a\n./a.go:3:2: moved to heap: x\n"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("blocks = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// IsThirdParty reports whether path belongs to code the user does not
+// own: a vendor/ directory, the module cache, or a file generated by cgo
+func IsThirdParty(path string) bool {
+	slashed := "/" + filepath.ToSlash(path)
+
+	if strings.Contains(slashed, "/vendor/") {
+		return true
+	}
+	if inModCache(path) {
+		return true
+	}
+	return isCgoGenerated(slashed)
+}
+
+// inModCache reports whether path is under the module cache root. Only
+// the root counts: the user's own code may be in a pkg/mod directory.
+func inModCache(path string) bool {
+	cache := modCache()
+	if cache == "" {
+		return false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(abs, filepath.Clean(cache)+string(filepath.Separator))
+}
+
+// modCache returns the module cache root: $GOMODCACHE, or else what go env
+// reports, which defaults it to GOPATH/pkg/mod; "" when neither is known
+func modCache() string {
+	if cache := os.Getenv("GOMODCACHE"); cache != "" {
+		return cache
+	}
+	return goEnvModCache()
+}
+
+// goEnvModCache asks the go command for GOMODCACHE once
+var goEnvModCache = sync.OnceValue(func() string {
+	out, err := exec.Command("go", "env", "GOMODCACHE").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+})
+
+// isCgoGenerated matches files cgo writes into the build directory,
+// such as _cgo_gotypes.go and foo.cgo1.go
+func isCgoGenerated(slashed string) bool {
+	base := slashed[strings.LastIndex(slashed, "/")+1:]
+	return strings.HasPrefix(base, "_cgo_") ||
+		strings.HasSuffix(base, ".cgo1.go") ||
+		strings.Contains(slashed, "/go-build/")
+}
+
+// SkipThirdParty drops escapes located in third-party or generated code
+func SkipThirdParty(escapes []EscapeInfo) []EscapeInfo {
+	kept := escapes[:0]
+	for _, e := range escapes {
+		if !IsThirdParty(e.File) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
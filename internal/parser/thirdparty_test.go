@@ -0,0 +1,47 @@
+package parser
+
+import "testing"
+
+func TestIsThirdParty(t *testing.T) {
+	t.Setenv("GOMODCACHE", "/cache/mod")
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"main.go", false},
+		{"./pkg/server/handler.go", false},
+		{"internal/vendorlib/x.go", false},
+		{"vendor/github.com/pkg/errors/errors.go", true},
+		{"./vendor/golang.org/x/net/http2/frame.go", true},
+		{"services/api/vendor/example.com/lib/lib.go", true},
+		{"/cache/mod/example.com/lib@v1.0.0/lib.go", true},
+		{"/cache/mod/../own/main.go", false},
+		{"./pkg/mod/resolve.go", false},
+		{"/home/u/src/app/pkg/mod/resolve.go", false},
+		{"/cache/module.go", false},
+		{"_cgo_gotypes.go", true},
+		{"/tmp/go-build123/b001/_cgo_imports.go", true},
+		{"sqlite.cgo1.go", true},
+		{"cgo_helpers.go", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsThirdParty(tt.path); got != tt.want {
+			t.Errorf("IsThirdParty(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSkipThirdParty(t *testing.T) {
+	escapes := []EscapeInfo{
+		{File: "main.go", Variable: "a"},
+		{File: "vendor/lib/lib.go", Variable: "b"},
+		{File: "server.go", Variable: "c"},
+	}
+
+	got := SkipThirdParty(escapes)
+	if len(got) != 2 || got[0].Variable != "a" || got[1].Variable != "c" {
+		t.Errorf("SkipThirdParty() = %+v, want a and c", got)
+	}
+}
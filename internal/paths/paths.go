@@ -0,0 +1,162 @@
+// Package paths rewrites the file paths in results to a display style:
+// relative to the current directory for people, qualified by module path
+// for stable references, or absolute.
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// Path styles
+const (
+	Relative = "relative" // relative to the working directory, e.g. pkg/server/handler.go
+	Module   = "module"   // module path and directory, e.g. example.com/app/pkg/server/handler.go
+	Absolute = "absolute" // e.g. /home/me/app/pkg/server/handler.go
+)
+
+// Styles lists the path styles
+var Styles = []string{Relative, Module, Absolute}
+
+// Rewriter renders file paths in one style. Relative paths, as the
+// compiler prints them, are resolved against its working directory.
+type Rewriter struct {
+	style string
+	dir   string
+
+	// modules caches the module path and root of directories
+	modules map[string]module
+}
+
+type module struct {
+	path string // "" if the directory is not in a module
+	root string
+}
+
+// New returns a rewriter for style, resolving paths against dir
+func New(style, dir string) (*Rewriter, error) {
+	switch style {
+	case Relative, Module, Absolute:
+	default:
+		return nil, fmt.Errorf("invalid path style %q (want %s)", style, strings.Join(Styles, ", "))
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Rewriter{style: style, dir: dir, modules: make(map[string]module)}, nil
+}
+
+// Path renders file in the rewriter's style. Compiler labels such as
+// "<autogenerated>" are returned unchanged, and so are paths a style
+// cannot express: files outside the working directory stay absolute in
+// the relative style, and files outside any module in the module style.
+func (r *Rewriter) Path(file string) string {
+	if file == "" || parser.IsSynthetic(file) {
+		return file
+	}
+	abs := file
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(r.dir, file)
+	}
+
+	switch r.style {
+	case Relative:
+		rel, err := filepath.Rel(r.dir, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return abs
+		}
+		return filepath.ToSlash(rel)
+	case Module:
+		m := r.module(filepath.Dir(abs))
+		if m.path == "" {
+			return abs
+		}
+		rel, err := filepath.Rel(m.root, abs)
+		if err != nil {
+			return abs
+		}
+		if m.path == "std" {
+			// GOROOT/src is module std, whose import paths have no prefix
+			return filepath.ToSlash(rel)
+		}
+		return path.Join(m.path, filepath.ToSlash(rel))
+	default:
+		return abs
+	}
+}
+
+// module returns the module containing dir, from the nearest go.mod
+func (r *Rewriter) module(dir string) module {
+	if m, ok := r.modules[dir]; ok {
+		return m
+	}
+	var m module
+	if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+		m = module{path: modulePath(data), root: dir}
+	} else if parent := filepath.Dir(dir); parent != dir {
+		m = r.module(parent)
+	}
+	r.modules[dir] = m
+	return m
+}
+
+// modulePath returns the path of the module directive in a go.mod file
+func modulePath(gomod []byte) string {
+	for _, line := range strings.Split(string(gomod), "\n") {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
+
+// Apply rewrites every file path in results: escapes and their flow
+// steps, the per-file counts, suppressions, and the declaration positions
+// of interface parameters and goroutine groups. Apply it last, after
+// baselines, suppressions and filters that match on the compiler's paths.
+func (r *Rewriter) Apply(results *categorizer.Results) {
+	for i := range results.Escapes {
+		info := &results.Escapes[i].Info
+		info.File = r.Path(info.File)
+		for j := range info.Flows {
+			for k := range info.Flows[j].Steps {
+				step := &info.Flows[j].Steps[k]
+				step.File = r.Path(step.File)
+			}
+		}
+	}
+
+	if results.Summary.ByFile != nil {
+		byFile := make(map[string]int, len(results.Summary.ByFile))
+		for file, n := range results.Summary.ByFile {
+			byFile[r.Path(file)] += n
+		}
+		results.Summary.ByFile = byFile
+	}
+
+	for i := range results.Suppressions {
+		results.Suppressions[i].File = r.Path(results.Suppressions[i].File)
+	}
+	for i := range results.InterfaceParams {
+		results.InterfaceParams[i].Position = r.position(results.InterfaceParams[i].Position)
+	}
+	for i := range results.Goroutines {
+		results.Goroutines[i].Position = r.position(results.Goroutines[i].Position)
+	}
+}
+
+// position rewrites the file of a "file:line" position
+func (r *Rewriter) position(pos string) string {
+	i := strings.LastIndexByte(pos, ':')
+	if i < 0 {
+		return r.Path(pos)
+	}
+	return r.Path(pos[:i]) + pos[i:]
+}
@@ -0,0 +1,92 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func TestPath(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("// app\nmodule \"example.com/app\"\n\ngo 1.22\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(filepath.Dir(root), "other.go")
+	if err := os.MkdirAll(filepath.Join(root, "tools"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "tools", "go.mod"), []byte("module example.com/tools\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		style, file, want string
+	}{
+		{Relative, "./pkg/server/handler.go", "pkg/server/handler.go"},
+		{Relative, filepath.Join(root, "main.go"), "main.go"},
+		{Relative, outside, outside},
+		{Module, "./pkg/server/handler.go", "example.com/app/pkg/server/handler.go"},
+		{Module, "main.go", "example.com/app/main.go"},
+		{Module, "tools/gen/gen.go", "example.com/tools/gen/gen.go"},
+		{Absolute, "./pkg/server/handler.go", filepath.Join(root, "pkg", "server", "handler.go")},
+		{Absolute, "<autogenerated>", "<autogenerated>"},
+	}
+	for _, tt := range tests {
+		r, err := New(tt.style, root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.Path(tt.file); got != tt.want {
+			t.Errorf("%s Path(%q) = %q, want %q", tt.style, tt.file, got, tt.want)
+		}
+	}
+
+	if _, err := New("short", root); err == nil {
+		t.Error("New(short) should fail")
+	}
+}
+
+func TestApply(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	results := &categorizer.Results{
+		Summary: categorizer.Summary{ByFile: map[string]int{"./main.go": 1, "main.go": 2}},
+		Escapes: []categorizer.CategorizedEscape{{Info: parser.EscapeInfo{
+			File:  "./main.go",
+			Flows: []parser.Flow{{Steps: []parser.FlowStep{{File: "./util.go"}}}},
+		}}},
+		Suppressions:    []categorizer.SuppressionStatus{{File: "./main.go"}},
+		InterfaceParams: []categorizer.InterfaceParam{{Position: "./log.go:12"}},
+		Goroutines:      []categorizer.GoroutineGroup{{Position: "./pool.go:30"}},
+	}
+	r, err := New(Module, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Apply(results)
+
+	if got := results.Escapes[0].Info.File; got != "example.com/app/main.go" {
+		t.Errorf("escape file = %q", got)
+	}
+	if got := results.Escapes[0].Info.Flows[0].Steps[0].File; got != "example.com/app/util.go" {
+		t.Errorf("flow step file = %q", got)
+	}
+	if got := results.Summary.ByFile["example.com/app/main.go"]; got != 3 || len(results.Summary.ByFile) != 1 {
+		t.Errorf("ByFile = %v, want the counts merged", results.Summary.ByFile)
+	}
+	if got := results.Suppressions[0].File; got != "example.com/app/main.go" {
+		t.Errorf("suppression file = %q", got)
+	}
+	if got := results.InterfaceParams[0].Position; got != "example.com/app/log.go:12" {
+		t.Errorf("interface param position = %q", got)
+	}
+	if got := results.Goroutines[0].Position; got != "example.com/app/pool.go:30" {
+		t.Errorf("goroutine group position = %q", got)
+	}
+}
@@ -0,0 +1,123 @@
+// Package query selects escapes by how and where they escape, for the
+// --where CLI filter and the library API.
+//
+// A query is a comma-separated list of key=value conditions that must
+// all match. A value may list alternatives separated by "|":
+//
+//	sink=channel
+//	sink=interface|closure,category=fmt-call
+//	file=pkg/server/,sink=return
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// Keys lists the supported condition keys
+var Keys = []string{"sink", "category", "file"}
+
+type condition struct {
+	key    string
+	values []string
+}
+
+// Query is a parsed --where expression
+type Query struct {
+	conds []condition
+}
+
+// Parse parses a query expression
+func Parse(expr string) (*Query, error) {
+	q := &Query{}
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid condition %q, want key=value", part)
+		}
+		key = strings.TrimSpace(key)
+		values := strings.Split(strings.TrimSpace(value), "|")
+
+		switch key {
+		case "sink":
+			for _, v := range values {
+				if !isSink(v) {
+					return nil, fmt.Errorf("unknown sink %q (known: %s)", v, sinkNames())
+				}
+			}
+		case "category", "file":
+		default:
+			return nil, fmt.Errorf("unknown key %q (known: %s)", key, strings.Join(Keys, ", "))
+		}
+		q.conds = append(q.conds, condition{key: key, values: values})
+	}
+	if len(q.conds) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+	return q, nil
+}
+
+// Match reports whether e satisfies every condition
+func (q *Query) Match(e categorizer.CategorizedEscape) bool {
+	for _, c := range q.conds {
+		if !c.match(e) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c condition) match(e categorizer.CategorizedEscape) bool {
+	for _, v := range c.values {
+		switch c.key {
+		case "sink":
+			if e.Info.HasSink(parser.Sink(v)) {
+				return true
+			}
+		case "category":
+			if string(e.Category) == v {
+				return true
+			}
+		case "file":
+			if strings.HasPrefix(strings.TrimPrefix(e.Info.File, "./"), strings.TrimPrefix(v, "./")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Filter returns the escapes in results matching q
+func (q *Query) Filter(results *categorizer.Results) []categorizer.CategorizedEscape {
+	matched := make([]categorizer.CategorizedEscape, 0)
+	for _, e := range results.Escapes {
+		if q.Match(e) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+func isSink(name string) bool {
+	for _, s := range parser.Sinks {
+		if string(s) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func sinkNames() string {
+	names := make([]string, len(parser.Sinks))
+	for i, s := range parser.Sinks {
+		names[i] = string(s)
+	}
+	return strings.Join(names, ", ")
+}
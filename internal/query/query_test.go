@@ -0,0 +1,87 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func escape(file string, cat categorizer.Category, reasons ...string) categorizer.CategorizedEscape {
+	flow := parser.Flow{Dst: "x", Src: "y"}
+	for _, r := range reasons {
+		flow.Steps = append(flow.Steps, parser.FlowStep{Reason: r})
+	}
+	return categorizer.CategorizedEscape{
+		Info:     parser.EscapeInfo{File: file, Flows: []parser.Flow{flow}},
+		Category: cat,
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"sink=channel", false},
+		{"sink=interface|closure, category=fmt-call", false},
+		{"file=pkg/server/", false},
+		{"", true},
+		{"sink", true},
+		{"sink=", true},
+		{"sink=disk", true},
+		{"owner=me", true},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.expr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	sent := escape("pkg/worker/pool.go", categorizer.CategoryChannelSend, "send")
+	boxed := escape("./pkg/server/log.go", categorizer.CategoryFmtCall, "interface-converted", "call parameter")
+	captured := escape("pkg/server/mw.go", categorizer.CategoryClosureCapture, "captured by a closure")
+
+	tests := []struct {
+		expr string
+		e    categorizer.CategorizedEscape
+		want bool
+	}{
+		{"sink=channel", sent, true},
+		{"sink=channel", boxed, false},
+		{"sink=interface|closure", boxed, true},
+		{"sink=interface|closure", captured, true},
+		{"sink=interface,category=fmt-call", boxed, true},
+		{"sink=interface,category=reflection", boxed, false},
+		{"file=pkg/server/", boxed, true},
+		{"file=./pkg/server", captured, true},
+		{"file=pkg/server", sent, false},
+	}
+
+	for _, tt := range tests {
+		q, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", tt.expr, err)
+		}
+		if got := q.Match(tt.e); got != tt.want {
+			t.Errorf("Parse(%q).Match(%s) = %v, want %v", tt.expr, tt.e.Info.File, got, tt.want)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	results := &categorizer.Results{Escapes: []categorizer.CategorizedEscape{
+		escape("a.go", categorizer.CategoryChannelSend, "send"),
+		escape("b.go", categorizer.CategoryFmtCall, "call parameter"),
+	}}
+	q, _ := Parse("sink=channel")
+
+	got := q.Filter(results)
+	if len(got) != 1 || got[0].Info.File != "a.go" {
+		t.Errorf("Filter() = %+v, want a.go", got)
+	}
+}
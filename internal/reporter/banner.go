@@ -0,0 +1,133 @@
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+// gateCheck is one gate a run was checked against
+type gateCheck struct {
+	name   string // category, "trend" or "suppressions"
+	status string // categorizer.GatePass, GateWarn or GateFail
+	detail string // e.g. "42 escapes, 12 over fail>30"
+}
+
+// gateChecks returns the gates the run was checked against and the
+// overall status. It returns false when no gate applies: no category
+// rules, no trend check and no expired suppressions.
+func gateChecks(results *categorizer.Results, meta Metadata) ([]gateCheck, string, bool) {
+	var checks []gateCheck
+	if meta.Gate != nil {
+		for _, c := range meta.Gate.Categories {
+			detail := fmt.Sprintf("%d escapes (%s)", c.Count, c.Rule)
+			if c.Status != categorizer.GatePass {
+				detail = fmt.Sprintf("%d escapes, %d over %s", c.Count, c.Margin, c.Rule)
+			}
+			checks = append(checks, gateCheck{name: c.Name(), status: c.Status, detail: detail})
+		}
+	}
+	if t := meta.Trend; t != nil {
+		c := gateCheck{
+			name:   "trend",
+			status: categorizer.GatePass,
+			detail: fmt.Sprintf("%+.1f%% vs the rolling average (allowed +%g%%)", t.Change, t.Allowed),
+		}
+		if t.Exceeded {
+			c.status = categorizer.GateFail
+			c.detail = fmt.Sprintf("%+.1f%% vs the rolling average, %.1f points over +%g%%", t.Change, t.Change-t.Allowed, t.Allowed)
+		}
+		checks = append(checks, c)
+	}
+	expired := 0
+	for _, s := range results.Suppressions {
+		if s.Status == categorizer.SuppressionExpired {
+			expired++
+		}
+	}
+	if expired > 0 {
+		checks = append(checks, gateCheck{
+			name:   "suppressions",
+			status: categorizer.GateFail,
+			detail: fmt.Sprintf("%d expired, their escapes resurfaced", expired),
+		})
+	}
+	if len(checks) == 0 {
+		return nil, "", false
+	}
+
+	status := categorizer.GatePass
+	for _, c := range checks {
+		if c.status == categorizer.GateFail || (c.status == categorizer.GateWarn && status == categorizer.GatePass) {
+			status = c.status
+		}
+	}
+	return checks, status, true
+}
+
+// bannerHeadline summarizes the checks, e.g. "FAIL: 2 of 4 gate(s) failed"
+func bannerHeadline(checks []gateCheck, status string) string {
+	n := 0
+	for _, c := range checks {
+		if c.status == status {
+			n++
+		}
+	}
+	switch status {
+	case categorizer.GateFail:
+		return fmt.Sprintf("FAIL: %d of %d gate(s) failed", n, len(checks))
+	case categorizer.GateWarn:
+		return fmt.Sprintf("WARN: %d of %d gate(s) warned", n, len(checks))
+	default:
+		return fmt.Sprintf("PASS: all %d gate(s) passed", len(checks))
+	}
+}
+
+// printBanner ends the text report with the overall gate outcome and the
+// gates that did not pass, so CI logs can be scanned from the bottom
+func (r *TextReporter) printBanner(results *categorizer.Results, meta Metadata) {
+	checks, status, ok := gateChecks(results, meta)
+	if !ok {
+		return
+	}
+	w := r.w
+	rule := strings.Repeat("═", 50)
+
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, rule)
+	fmt.Fprintf(w, "%s %s\n", gateMarker(status), r.paint(ansiBold+gateColor(status), bannerHeadline(checks, status)))
+	for _, c := range checks {
+		if c.status != categorizer.GatePass {
+			fmt.Fprintf(w, "  %s %-20s %s\n", gateMarker(c.status), c.name, c.detail)
+		}
+	}
+	fmt.Fprintln(w, rule)
+}
+
+// WriteMarkdownSummary writes the gate outcome as Markdown, e.g. for a
+// CI job summary. It writes nothing when no gate applies.
+func WriteMarkdownSummary(w io.Writer, results *categorizer.Results, meta Metadata) error {
+	checks, status, ok := gateChecks(results, meta)
+	if !ok {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s heapcheck %s\n\n", gateMarker(status), bannerHeadline(checks, status))
+	b.WriteString("| | Gate | Result |\n|---|---|---|\n")
+	for _, c := range checks {
+		fmt.Fprintf(&b, "| %s | `%s` | %s |\n", gateMarker(c.status), c.name, c.detail)
+	}
+	b.WriteString("\n")
+	if skipped := results.Summary.Skipped; len(skipped) > 0 {
+		fmt.Fprintf(&b, "Not analyzed (%d package(s)):\n\n", len(skipped))
+		for _, p := range skipped {
+			fmt.Fprintf(&b, "- `%s` %s: %s\n", p.Package, skippedStatus(p), p.Reason)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,93 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/history"
+)
+
+func gatedMeta() Metadata {
+	return Metadata{
+		Gate: &categorizer.GateResult{
+			Status: categorizer.GateFail,
+			Categories: []categorizer.CategoryGate{
+				{Category: categorizer.CategoryFmtCall, Count: 3, Rule: "warn>10", Status: categorizer.GatePass, Threshold: 10, Margin: -7},
+				{Category: categorizer.CategoryInterfaceBoxing, Count: 42, Rule: "fail>30", Status: categorizer.GateFail, Threshold: 30, Margin: 12},
+			},
+		},
+		Trend: &history.Trend{Current: 150, Average: 140, Runs: 5, Change: 7.1, Allowed: 5, Exceeded: true},
+	}
+}
+
+func TestTextReporterBanner(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewTextReporter(&buf).Report(context.Background(), sampleResults(), gatedMeta()); err != nil {
+		t.Fatalf("Report() error: %v", err)
+	}
+	output := buf.String()
+
+	banner := output[strings.LastIndex(output, "❌ FAIL"):]
+	for _, want := range []string{
+		"FAIL: 2 of 3 gate(s) failed",
+		"interface-boxing     42 escapes, 12 over fail>30",
+		"trend                +7.1% vs the rolling average, 2.1 points over +5%",
+	} {
+		if !strings.Contains(banner, want) {
+			t.Errorf("banner missing %q:\n%s", want, banner)
+		}
+	}
+	if strings.Contains(banner, "fmt-call") {
+		t.Errorf("banner lists a passing gate:\n%s", banner)
+	}
+	if !strings.HasSuffix(output, strings.Repeat("═", 50)+"\n") {
+		t.Errorf("output does not end with the banner:\n%s", output)
+	}
+
+	buf.Reset()
+	if err := NewTextReporter(&buf).Report(context.Background(), sampleResults(), Metadata{}); err != nil {
+		t.Fatalf("Report() error: %v", err)
+	}
+	if strings.Contains(buf.String(), "═") {
+		t.Error("banner printed without gates")
+	}
+}
+
+func TestWriteMarkdownSummary(t *testing.T) {
+	results := sampleResults()
+	results.Suppressions = []categorizer.SuppressionStatus{{Status: categorizer.SuppressionExpired}}
+
+	var buf bytes.Buffer
+	if err := WriteMarkdownSummary(&buf, results, gatedMeta()); err != nil {
+		t.Fatal(err)
+	}
+	want := "### ❌ heapcheck FAIL: 3 of 4 gate(s) failed\n\n" +
+		"| | Gate | Result |\n|---|---|---|\n" +
+		"| ✅ | `fmt-call` | 3 escapes (warn>10) |\n" +
+		"| ❌ | `interface-boxing` | 42 escapes, 12 over fail>30 |\n" +
+		"| ❌ | `trend` | +7.1% vs the rolling average, 2.1 points over +5% |\n" +
+		"| ❌ | `suppressions` | 1 expired, their escapes resurfaced |\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteMarkdownSummary() =\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	meta := Metadata{Gate: &categorizer.GateResult{
+		Status:     categorizer.GatePass,
+		Categories: []categorizer.CategoryGate{{Category: categorizer.CategoryFmtCall, Count: 3, Rule: "warn>10", Status: categorizer.GatePass}},
+	}}
+	if err := WriteMarkdownSummary(&buf, sampleResults(), meta); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "### ✅ heapcheck PASS: all 1 gate(s) passed") {
+		t.Errorf("passing summary = %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteMarkdownSummary(&buf, sampleResults(), Metadata{}); err != nil || buf.Len() != 0 {
+		t.Errorf("WriteMarkdownSummary(no gates) = %q, %v, want nothing", buf.String(), err)
+	}
+}
@@ -0,0 +1,109 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+// Catalog translates the category names and suggestions of the HTML
+// report. Categories and fields it leaves out stay in English.
+type Catalog struct {
+	Lang        string                                          `json:"lang"` // BCP 47 tag, e.g. de or pt-BR
+	Categories  map[categorizer.Category]string                 `json:"categories"`
+	Suggestions map[categorizer.Category]categorizer.Suggestion `json:"suggestions"`
+}
+
+// LoadCatalog returns the catalog for lang: a built-in language (see
+// Languages), or the path of a JSON catalog file. "" and "en" return nil,
+// the English report.
+func LoadCatalog(lang string) (*Catalog, error) {
+	if lang == "" || lang == "en" {
+		return nil, nil
+	}
+	if c, ok := catalogs[lang]; ok {
+		return c, nil
+	}
+	if !strings.HasSuffix(lang, ".json") && !strings.ContainsRune(lang, filepath.Separator) {
+		return nil, fmt.Errorf("unknown language %q (built in: %s; or the path of a JSON catalog)", lang, strings.Join(Languages(), ", "))
+	}
+	data, err := os.ReadFile(lang)
+	if err != nil {
+		return nil, err
+	}
+	var c Catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing catalog %s: %w", lang, err)
+	}
+	if c.Lang == "" {
+		c.Lang = strings.TrimSuffix(filepath.Base(lang), ".json")
+	}
+	return &c, nil
+}
+
+// Languages returns the built-in languages of the HTML report
+func Languages() []string {
+	langs := []string{"en"}
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs[1:])
+	return langs
+}
+
+// lang returns the language of the report
+func (c *Catalog) lang() string {
+	if c == nil || c.Lang == "" {
+		return "en"
+	}
+	return c.Lang
+}
+
+// name returns the display name of cat
+func (c *Catalog) name(cat categorizer.Category) string {
+	if c != nil {
+		if name, ok := c.Categories[cat]; ok && name != "" {
+			return name
+		}
+	}
+	return string(cat)
+}
+
+// suggestion returns s translated. Only the built-in text is: text
+// overridden in the config is the codebase's own and kept as written.
+func (c *Catalog) suggestion(cat categorizer.Category, s categorizer.Suggestion) categorizer.Suggestion {
+	if c == nil {
+		return s
+	}
+	t, ok := c.Suggestions[cat]
+	if !ok {
+		return s
+	}
+	builtin := categorizer.GetSuggestion(cat)
+	if t.Short != "" && s.Short == builtin.Short {
+		s.Short = t.Short
+	}
+	if t.Details != "" && s.Details == builtin.Details {
+		s.Details = t.Details
+	}
+	return s
+}
+
+// withCatalog returns escapes with their suggestions translated, copying
+// the slice only when there is a catalog
+func withCatalog(escapes []categorizer.CategorizedEscape, c *Catalog) []categorizer.CategorizedEscape {
+	if c == nil {
+		return escapes
+	}
+	out := make([]categorizer.CategorizedEscape, len(escapes))
+	for i, e := range escapes {
+		e.Suggestion = c.suggestion(e.Category, e.Suggestion)
+		out[i] = e
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+func TestCatalogsComplete(t *testing.T) {
+	all := append(categorizer.Categories(), categorizer.Advisories()...)
+	for lang, c := range catalogs {
+		if c.Lang != lang {
+			t.Errorf("catalog %s: Lang = %q", lang, c.Lang)
+		}
+		for _, cat := range all {
+			if c.Categories[cat] == "" {
+				t.Errorf("catalog %s: no name for %s", lang, cat)
+			}
+			if c.Suggestions[cat].Short == "" {
+				t.Errorf("catalog %s: no suggestion for %s", lang, cat)
+			}
+		}
+	}
+}
+
+func TestLoadCatalog(t *testing.T) {
+	for _, lang := range []string{"", "en"} {
+		if c, err := LoadCatalog(lang); c != nil || err != nil {
+			t.Errorf("LoadCatalog(%q) = %v, %v, want nil", lang, c, err)
+		}
+	}
+	if c, err := LoadCatalog("de"); err != nil || c.Lang != "de" {
+		t.Errorf("LoadCatalog(de) = %v, %v", c, err)
+	}
+	if _, err := LoadCatalog("fr"); err == nil || !strings.Contains(err.Error(), "en, de, ja") {
+		t.Errorf("LoadCatalog(fr) error = %v, want the built-in languages", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "fr.json")
+	os.WriteFile(path, []byte(`{"categories": {"interface-boxing": "Conversion en interface"}}`), 0o644)
+	c, err := LoadCatalog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Lang != "fr" || c.name(categorizer.CategoryInterfaceBoxing) != "Conversion en interface" {
+		t.Errorf("catalog file loaded as %+v", c)
+	}
+	if got := c.name(categorizer.CategoryReturnPointer); got != "return-pointer" {
+		t.Errorf("untranslated category named %q, want the ID", got)
+	}
+
+	os.WriteFile(path, []byte(`{`), 0o644)
+	if _, err := LoadCatalog(path); err == nil {
+		t.Error("LoadCatalog of a malformed file succeeded")
+	}
+}
+
+func TestHTMLReporterCatalog(t *testing.T) {
+	results := sampleResults()
+	for i := range results.Escapes {
+		results.Escapes[i].Suggestion = categorizer.GetSuggestion(results.Escapes[i].Category)
+	}
+	c, _ := LoadCatalog("ja")
+	overrides := map[categorizer.Category]categorizer.Suggestion{
+		categorizer.CategoryInterfaceBoxing: {Short: "Follow the team's interface guide"},
+	}
+
+	var buf bytes.Buffer
+	rep := NewHTMLReporter(&buf, WithCatalog(c), WithSuggestions(overrides))
+	if err := rep.Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+	for _, want := range []string{
+		`<html lang="ja">`,
+		`title="return-pointer">ポインタの返却</span>`,
+		"64 バイト以下の構造体は値で返す",
+		"Follow the team&#39;s interface guide",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("HTML output missing %q", want)
+		}
+	}
+	if results.Escapes[0].Suggestion.Short != categorizer.GetSuggestion(results.Escapes[0].Category).Short {
+		t.Errorf("reporting modified the results: suggestion %q", results.Escapes[0].Suggestion.Short)
+	}
+}
@@ -0,0 +1,67 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/baseline"
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+// DeltaReporter outputs how the escapes differ from the baseline as JSON
+// Patch (RFC 6902) operations on the baseline file: applying them updates
+// the baseline. Bots can count the "add" operations to report new escapes.
+type DeltaReporter struct {
+	w io.Writer
+}
+
+// NewDeltaReporter creates a new delta reporter
+func NewDeltaReporter(w io.Writer) *DeltaReporter {
+	return &DeltaReporter{w: w}
+}
+
+// deltaOp is one JSON Patch operation. Removals carry the removed entry
+// as value too, which JSON Patch ignores, so they can be reported.
+type deltaOp struct {
+	Op    string         `json:"op"`
+	Path  string         `json:"path"`
+	Value baseline.Entry `json:"value"`
+}
+
+// Report writes one operation per line: removals by descending index, so
+// earlier ones do not shift later ones, then additions
+func (r *DeltaReporter) Report(ctx context.Context, results *categorizer.Results, meta Metadata) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if meta.Delta == nil {
+		return fmt.Errorf("delta format needs a baseline to compare with (--baseline)")
+	}
+
+	var ops []deltaOp
+	for i := len(meta.Delta.Removed) - 1; i >= 0; i-- {
+		rm := meta.Delta.Removed[i]
+		ops = append(ops, deltaOp{Op: "remove", Path: fmt.Sprintf("/entries/%d", rm.Index), Value: rm.Entry})
+	}
+	for _, e := range meta.Delta.Added {
+		ops = append(ops, deltaOp{Op: "add", Path: "/entries/-", Value: e})
+	}
+
+	lines := make([]string, 0, len(ops))
+	for _, op := range ops {
+		data, err := json.Marshal(op)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, "  "+string(data))
+	}
+	if len(lines) == 0 {
+		_, err := io.WriteString(r.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(r.w, "[\n"+strings.Join(lines, ",\n")+"\n]\n")
+	return err
+}
@@ -0,0 +1,66 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/baseline"
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+func TestDeltaReporter(t *testing.T) {
+	delta := &baseline.Delta{
+		Added: []baseline.Entry{
+			{File: "a.go", Line: 4, Variable: "x", Category: categorizer.CategorySpill},
+		},
+		Removed: []baseline.Removal{
+			{Index: 1, Entry: baseline.Entry{File: "b.go", Line: 2, Variable: "y"}},
+			{Index: 5, Entry: baseline.Entry{File: "c.go", Line: 9, Variable: "z"}},
+		},
+	}
+	var buf bytes.Buffer
+	if err := NewDeltaReporter(&buf).Report(context.Background(), sampleResults(), Metadata{Delta: delta}); err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+
+	var ops []struct {
+		Op    string         `json:"op"`
+		Path  string         `json:"path"`
+		Value baseline.Entry `json:"value"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &ops); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	want := []struct{ op, path, variable string }{
+		{"remove", "/entries/5", "z"},
+		{"remove", "/entries/1", "y"},
+		{"add", "/entries/-", "x"},
+	}
+	if len(ops) != len(want) {
+		t.Fatalf("got %d operations, want %d:\n%s", len(ops), len(want), buf.String())
+	}
+	for i, w := range want {
+		if ops[i].Op != w.op || ops[i].Path != w.path || ops[i].Value.Variable != w.variable {
+			t.Errorf("op %d = %s %s %s, want %s %s %s", i, ops[i].Op, ops[i].Path, ops[i].Value.Variable, w.op, w.path, w.variable)
+		}
+	}
+	if got := strings.Count(buf.String(), "\n"); got != len(want)+2 {
+		t.Errorf("output has %d lines, want one per operation plus brackets:\n%s", got, buf.String())
+	}
+}
+
+func TestDeltaReporterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewDeltaReporter(&buf).Report(context.Background(), sampleResults(), Metadata{Delta: &baseline.Delta{}}); err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("output = %q, want []", buf.String())
+	}
+	if err := NewDeltaReporter(&buf).Report(context.Background(), sampleResults(), Metadata{}); err == nil {
+		t.Error("Report without a delta: expected error")
+	}
+}
@@ -0,0 +1,170 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/diff"
+)
+
+// DiffReporter interface for rendering the difference between two runs
+type DiffReporter interface {
+	ReportDiff(d *diff.Diff) error
+}
+
+// NewDiffReporter returns the diff reporter for format (text, json, html)
+func NewDiffReporter(w io.Writer, format string) (DiffReporter, error) {
+	switch format {
+	case "text", "":
+		return &TextDiffReporter{w: w}, nil
+	case "json":
+		return &JSONDiffReporter{w: w}, nil
+	case "html":
+		return &HTMLDiffReporter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unsupported diff format %q (supported: text, json, html)", format)
+	}
+}
+
+// TextDiffReporter outputs a human-readable diff
+type TextDiffReporter struct {
+	w io.Writer
+}
+
+// ReportDiff generates a human-readable diff
+func (r *TextDiffReporter) ReportDiff(d *diff.Diff) error {
+	w := r.w
+
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "📊 heapcheck - Escape Diff")
+	fmt.Fprintln(w, strings.Repeat("─", 50))
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "  %s → %s\n", d.OldFile, d.NewFile)
+	fmt.Fprintf(w, "  Escapes:   %d → %d (%+d)\n", d.OldTotal, d.NewTotal, d.NewTotal-d.OldTotal)
+	fmt.Fprintf(w, "  New:       %d\n", len(d.Added))
+	fmt.Fprintf(w, "  Resolved:  %d\n", len(d.Resolved))
+	fmt.Fprintln(w, "")
+
+	if len(d.Categories) > 0 {
+		fmt.Fprintln(w, "By category:")
+		for _, c := range d.Categories {
+			fmt.Fprintf(w, "  %-20s %4d → %-4d (%+d)\n", c.Category, c.Old, c.New, c.Delta())
+		}
+		fmt.Fprintln(w, "")
+	}
+
+	if len(d.Added) > 0 {
+		fmt.Fprintln(w, "New escapes:")
+		for _, e := range d.Added {
+			fmt.Fprintf(w, "  + %s:%d  %s [%s]\n", e.Info.File, e.Info.Line, e.Info.Variable, e.Category)
+		}
+		fmt.Fprintln(w, "")
+	}
+	if len(d.Resolved) > 0 {
+		fmt.Fprintln(w, "Resolved escapes:")
+		for _, e := range d.Resolved {
+			fmt.Fprintf(w, "  - %s:%d  %s [%s]\n", e.Info.File, e.Info.Line, e.Info.Variable, e.Category)
+		}
+		fmt.Fprintln(w, "")
+	}
+	return nil
+}
+
+// JSONDiffReporter outputs the diff as JSON
+type JSONDiffReporter struct {
+	w io.Writer
+}
+
+// ReportDiff generates JSON output
+func (r *JSONDiffReporter) ReportDiff(d *diff.Diff) error {
+	encoder := json.NewEncoder(r.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
+
+// HTMLDiffReporter outputs a side-by-side HTML diff
+type HTMLDiffReporter struct {
+	w io.Writer
+}
+
+// ReportDiff generates the HTML diff page
+func (r *HTMLDiffReporter) ReportDiff(d *diff.Diff) error {
+	_, err := io.WriteString(r.w, generateDiffHTML(d))
+	return err
+}
+
+func generateDiffHTML(d *diff.Diff) string {
+	var sb strings.Builder
+
+	sb.WriteString(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>heapcheck Diff</title>
+    <style>
+` + htmlStyles + `        .diff-added td { background: #fef2f2; }
+        .diff-resolved td { background: #f0fdf4; }
+        .delta-up { color: #dc2626; font-weight: 600; }
+        .delta-down { color: #16a34a; font-weight: 600; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>📊 heapcheck Diff</h1>
+`)
+	sb.WriteString(fmt.Sprintf(`<p><span class="file-link">%s</span> → <span class="file-link">%s</span></p>`,
+		html.EscapeString(d.OldFile), html.EscapeString(d.NewFile)))
+
+	// Summary cards
+	sb.WriteString(`<div class="grid-3" style="margin-bottom: 24px;">`)
+	sb.WriteString(fmt.Sprintf(`<div class="stat-card info"><div class="stat-value">%d → %d</div><div class="stat-label">Heap Escapes</div><div class="stat-pct">%+d</div></div>`, d.OldTotal, d.NewTotal, d.NewTotal-d.OldTotal))
+	sb.WriteString(fmt.Sprintf(`<div class="stat-card danger"><div class="stat-value">%d</div><div class="stat-label">New</div></div>`, len(d.Added)))
+	sb.WriteString(fmt.Sprintf(`<div class="stat-card success"><div class="stat-value">%d</div><div class="stat-label">Resolved</div></div>`, len(d.Resolved)))
+	sb.WriteString(`</div>`)
+
+	// Category counts
+	sb.WriteString(`<div class="card"><h2>📋 By Category</h2>`)
+	sb.WriteString(`<table><tr><th>Category</th><th>Old</th><th>New</th><th>Change</th></tr>`)
+	for _, c := range d.Categories {
+		deltaClass := ""
+		switch {
+		case c.Delta() > 0:
+			deltaClass = "delta-up"
+		case c.Delta() < 0:
+			deltaClass = "delta-down"
+		}
+		sb.WriteString(fmt.Sprintf(`<tr><td><span class="category-badge %s">%s</span></td><td>%d</td><td>%d</td><td class="%s">%+d</td></tr>`,
+			getCategoryBadgeClass(c.Category), html.EscapeString(string(c.Category)), c.Old, c.New, deltaClass, c.Delta()))
+	}
+	sb.WriteString(`</table></div>`)
+
+	// Side by side: resolved (old only) and new (new only)
+	sb.WriteString(`<div class="grid-2">`)
+	writeDiffTable(&sb, "✅ Resolved", "diff-resolved", d.Resolved)
+	writeDiffTable(&sb, "🔴 New", "diff-added", d.Added)
+	sb.WriteString(`</div>`)
+
+	sb.WriteString(`<div class="footer">Generated by <strong>heapcheck</strong> • <a href="https://github.com/harshakonda/heapcheck" style="color: #6b7280;">github.com/harshakonda/heapcheck</a></div>`)
+	sb.WriteString(`</div></body></html>`)
+
+	return sb.String()
+}
+
+func writeDiffTable(sb *strings.Builder, title, rowClass string, escapes []categorizer.CategorizedEscape) {
+	sb.WriteString(fmt.Sprintf(`<div class="card"><h2>%s (%d)</h2>`, title, len(escapes)))
+	if len(escapes) == 0 {
+		sb.WriteString(`<p style="color: #6b7280;">None</p></div>`)
+		return
+	}
+	sb.WriteString(`<table><tr><th>Location</th><th>Variable</th><th>Category</th></tr>`)
+	for _, e := range escapes {
+		sb.WriteString(fmt.Sprintf(`<tr class="%s"><td><span class="file-link">%s:%d</span></td><td><span class="var-name">%s</span></td><td>%s</td></tr>`,
+			rowClass, html.EscapeString(e.Info.File), e.Info.Line, html.EscapeString(e.Info.Variable), html.EscapeString(string(e.Category))))
+	}
+	sb.WriteString(`</table></div>`)
+}
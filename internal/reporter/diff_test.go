@@ -0,0 +1,71 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/diff"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func sampleDiff() *diff.Diff {
+	return &diff.Diff{
+		OldFile:  "old.json",
+		NewFile:  "new.json",
+		OldTotal: 2,
+		NewTotal: 2,
+		Added: []categorizer.CategorizedEscape{
+			{Info: parser.EscapeInfo{File: "new.go", Line: 3, Variable: "<script>"}, Category: categorizer.CategoryFmtCall},
+		},
+		Resolved: []categorizer.CategorizedEscape{
+			{Info: parser.EscapeInfo{File: "old.go", Line: 7, Variable: "buf"}, Category: categorizer.CategorySliceGrow},
+		},
+		Unchanged: 1,
+		Categories: []diff.CategoryDelta{
+			{Category: categorizer.CategoryFmtCall, Old: 0, New: 1},
+			{Category: categorizer.CategorySliceGrow, Old: 1, New: 0},
+		},
+	}
+}
+
+func TestDiffReporters(t *testing.T) {
+	tests := []struct {
+		format string
+		checks []string
+	}{
+		{"text", []string{"Escape Diff", "+ new.go:3", "- old.go:7", "fmt-call"}},
+		{"html", []string{"<!DOCTYPE html>", "diff-added", "diff-resolved", "new.go:3", "old.go:7", "&lt;script&gt;"}},
+		{"json", []string{`"added"`, `"resolved"`, `"categories"`}},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		rep, err := NewDiffReporter(&buf, tt.format)
+		if err != nil {
+			t.Fatalf("NewDiffReporter(%q) error: %v", tt.format, err)
+		}
+		if err := rep.ReportDiff(sampleDiff()); err != nil {
+			t.Fatalf("%s diff reporter failed: %v", tt.format, err)
+		}
+
+		output := buf.String()
+		for _, check := range tt.checks {
+			if !strings.Contains(output, check) {
+				t.Errorf("%s diff output missing: %s", tt.format, check)
+			}
+		}
+		if tt.format == "html" && strings.Contains(output, "<script>") {
+			t.Error("HTML diff output contains unescaped variable name")
+		}
+		if tt.format == "json" && !json.Valid(buf.Bytes()) {
+			t.Error("JSON diff output is not valid JSON")
+		}
+	}
+
+	if _, err := NewDiffReporter(&bytes.Buffer{}, "sarif"); err == nil {
+		t.Error("NewDiffReporter(sarif) expected error")
+	}
+}
@@ -0,0 +1,186 @@
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// hotspotNode is a directory, package or file of the hotspot tree, with
+// the escapes in it and below it
+type hotspotNode struct {
+	Name     string // directory or file name; directories with a single subdirectory are joined, e.g. "internal/api"
+	Package  string // import path of a package directory, "" when unknown
+	IsPkg    bool   // a directory with files of its own
+	IsFile   bool
+	Count    int
+	Pct      float64 // of all escapes
+	Children []*hotspotNode
+
+	dirs map[string]*hotspotNode // subdirectories by name, while building
+}
+
+// hotspotTree rolls the escape counts of byFile up into a tree of
+// directories, packages and files, most escapes first at each level. The
+// packages of directories are taken from escapes. Compiler labels such as
+// "<autogenerated>" are files at the top.
+func hotspotTree(byFile map[string]int, escapes []categorizer.CategorizedEscape) *hotspotNode {
+	pkgOf := make(map[string]string)
+	for _, e := range escapes {
+		if e.Info.Package != "" && !parser.IsSynthetic(e.Info.File) {
+			pkgOf[filepath.Dir(e.Info.File)] = e.Info.Package
+		}
+	}
+
+	root := &hotspotNode{Name: "."}
+	for file, n := range byFile {
+		if parser.IsSynthetic(file) {
+			root.Children = append(root.Children, &hotspotNode{Name: file, IsFile: true, Count: n})
+			continue
+		}
+		dir := root
+		for _, name := range splitDir(filepath.Dir(file)) {
+			child := dir.dirs[name]
+			if child == nil {
+				child = &hotspotNode{Name: name}
+				if dir.dirs == nil {
+					dir.dirs = make(map[string]*hotspotNode)
+				}
+				dir.dirs[name] = child
+				dir.Children = append(dir.Children, child)
+			}
+			dir = child
+		}
+		dir.IsPkg = true
+		dir.Package = pkgOf[filepath.Dir(file)]
+		dir.Children = append(dir.Children, &hotspotNode{Name: filepath.Base(file), IsFile: true, Count: n})
+	}
+	root.finish(total(byFile))
+	return root
+}
+
+// splitDir splits a directory into its names, the first "/" for an
+// absolute path; "." has none
+func splitDir(dir string) []string {
+	dir = filepath.ToSlash(filepath.Clean(dir))
+	if dir == "." {
+		return nil
+	}
+	names := strings.Split(dir, "/")
+	if names[0] == "" {
+		names[0] = "/"
+	}
+	return names
+}
+
+// finish rolls the counts up, sorts the children and joins directories
+// holding nothing but a single subdirectory
+func (n *hotspotNode) finish(total int) {
+	n.dirs = nil
+	for _, c := range n.Children {
+		c.finish(total)
+		n.Count += c.Count
+	}
+	for i, c := range n.Children {
+		for !c.IsPkg && !c.IsFile && len(c.Children) == 1 && !c.Children[0].IsFile {
+			only := c.Children[0]
+			only.Name = strings.TrimSuffix(c.Name, "/") + "/" + only.Name
+			c = only
+		}
+		n.Children[i] = c
+	}
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		a, b := n.Children[i], n.Children[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Name < b.Name
+	})
+	if total > 0 {
+		n.Pct = float64(n.Count) / float64(total) * 100
+	}
+}
+
+// Top returns the nodes to show at the top of the tree: the root when it
+// is a package, else its children
+func (n *hotspotNode) Top() []*hotspotNode {
+	if n.IsPkg {
+		return []*hotspotNode{n}
+	}
+	return n.Children
+}
+
+// Label names the node: files by name and directories with a trailing
+// slash, followed by the import path of a package whose name it does not
+// end in, e.g. "./ (example.com/app)" or "v2/ (example.com/app/apiv2)"
+func (n *hotspotNode) Label() string {
+	switch {
+	case n.IsFile:
+		return n.Name
+	case n.Package != "" && path.Base(n.Package) != path.Base(n.Name):
+		return fmt.Sprintf("%s/ (%s)", n.Name, n.Package)
+	}
+	return n.Name + "/"
+}
+
+func total(counts map[string]int) int {
+	sum := 0
+	for _, n := range counts {
+		sum += n
+	}
+	return sum
+}
+
+// Without -v, the text report prints the hotspot tree in up to
+// hotspotLines lines, with the first hotspotTop nodes at the top and
+// hotspotChildren below each node
+const (
+	hotspotLines    = 20
+	hotspotTop      = 5
+	hotspotChildren = 3
+)
+
+// printHotspots prints the hotspot tree indented, cut to its largest
+// nodes without verbose
+func printHotspots(w io.Writer, root *hotspotNode, pw int, verbose bool) {
+	lines := 0
+	var print func(nodes []*hotspotNode, depth int) bool
+	print = func(nodes []*hotspotNode, depth int) bool {
+		limit := hotspotChildren
+		if depth == 0 {
+			limit = hotspotTop
+		}
+		shown := nodes
+		if !verbose && len(shown) > limit {
+			shown = shown[:limit]
+		}
+		for _, n := range shown {
+			if !verbose && lines == hotspotLines {
+				return false
+			}
+			indent := strings.Repeat("  ", depth)
+			fmt.Fprintf(w, "  %-*s %3d escapes\n", pw, indent+truncatePath(n.Label(), pw-len(indent)), n.Count)
+			lines++
+			if !print(n.Children, depth+1) {
+				return false
+			}
+		}
+		if rest := nodes[len(shown):]; len(rest) > 0 {
+			more := 0
+			for _, n := range rest {
+				more += n.Count
+			}
+			fmt.Fprintf(w, "  %s… %d more with %d escape(s)\n", strings.Repeat("  ", depth), len(rest), more)
+		}
+		return true
+	}
+	if !print(root.Top(), 0) {
+		fmt.Fprintln(w, "  … run with -v for the full tree")
+	}
+}
@@ -0,0 +1,109 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// outline renders the tree as "label count" lines indented by depth
+func outline(nodes []*hotspotNode, depth int) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		fmt.Fprintf(&b, "%s%s %d\n", strings.Repeat("  ", depth), n.Label(), n.Count)
+		b.WriteString(outline(n.Children, depth+1))
+	}
+	return b.String()
+}
+
+func TestHotspotTree(t *testing.T) {
+	byFile := map[string]int{
+		"./internal/api/handler.go":    5,
+		"./internal/api/routes.go":     2,
+		"./internal/api/v2/handler.go": 4,
+		"./internal/store/db.go":       3,
+		"./cmd/server/main.go":         1,
+	}
+	escapes := []categorizer.CategorizedEscape{
+		{Info: parser.EscapeInfo{Package: "example.com/app/internal/api", File: "./internal/api/handler.go"}},
+		{Info: parser.EscapeInfo{Package: "example.com/app/cmd/server", File: "./cmd/server/main.go"}},
+		{Info: parser.EscapeInfo{Package: "example.com/app/apiv2", File: "./internal/api/v2/handler.go"}},
+		{Info: parser.EscapeInfo{Package: "example.com/app", File: "<autogenerated>"}},
+	}
+
+	got := outline(hotspotTree(byFile, escapes).Top(), 0)
+	want := `internal/ 14
+  api/ 11
+    handler.go 5
+    v2/ (example.com/app/apiv2) 4
+      handler.go 4
+    routes.go 2
+  store/ 3
+    db.go 3
+cmd/server/ 1
+  main.go 1
+`
+	if got != want {
+		t.Errorf("hotspot tree:\n%s\nwant:\n%s", got, want)
+	}
+
+	root := hotspotTree(map[string]int{"./main.go": 2, "./util/util.go": 1}, []categorizer.CategorizedEscape{
+		{Info: parser.EscapeInfo{Package: "example.com/app", File: "./main.go"}},
+	})
+	if got := root.Label(); got != "./ (example.com/app)" {
+		t.Errorf("Label() of the root package = %q, want ./ (example.com/app)", got)
+	}
+	if top := root.Top(); len(top) != 1 || top[0] != root || root.Count != 3 {
+		t.Errorf("Top() of a root package = %v, want the root with 3 escapes", top)
+	}
+	if root.Children[0].Pct < 66 || root.Children[0].Pct > 67 {
+		t.Errorf("Pct of main.go = %.1f, want 66.7", root.Children[0].Pct)
+	}
+}
+
+func TestPrintHotspots(t *testing.T) {
+	byFile := make(map[string]int)
+	for i := 1; i <= 7; i++ {
+		byFile[fmt.Sprintf("pkg%d/file.go", i)] = i
+		byFile[fmt.Sprintf("pkg7/more%d.go", i)] = 1
+	}
+	root := hotspotTree(byFile, nil)
+
+	var short bytes.Buffer
+	printHotspots(&short, root, 30, false)
+	for _, want := range []string{"pkg7/", "    file.go", "… 5 more with 5 escape(s)", "… 2 more with 3 escape(s)"} {
+		if !strings.Contains(short.String(), want) {
+			t.Errorf("hotspots missing %q:\n%s", want, short.String())
+		}
+	}
+	if strings.Contains(short.String(), "pkg1/") {
+		t.Errorf("hotspots list more than %d top-level nodes:\n%s", hotspotTop, short.String())
+	}
+
+	var full bytes.Buffer
+	printHotspots(&full, root, 30, true)
+	if strings.Contains(full.String(), "more with") || !strings.Contains(full.String(), "pkg1/") {
+		t.Errorf("verbose hotspots are not the full tree:\n%s", full.String())
+	}
+}
+
+func TestHTMLReporterHotspotTree(t *testing.T) {
+	results := sampleResults()
+	results.Summary.ByFile = map[string]int{"internal/api/handler.go": 2, "internal/api/v2/routes.go": 1}
+	results.Escapes[0].Info.Package, results.Escapes[0].Info.File = "example.com/app/internal/api", "internal/api/handler.go"
+	var buf bytes.Buffer
+	if err := NewHTMLReporter(&buf).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatal(err)
+	}
+	html := buf.String()
+	for _, want := range []string{`<span title="example.com/app/internal/api">internal/api/</span>`, `<span>v2/</span>`, `<span class="file-link">routes.go</span>`, "<details open>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML hotspots missing %q", want)
+		}
+	}
+}
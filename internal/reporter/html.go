@@ -0,0 +1,758 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+// =============================================================================
+// HTML Reporter
+// =============================================================================
+
+// HTMLReporter outputs an HTML report
+type HTMLReporter struct {
+	w    io.Writer
+	opts options
+}
+
+// NewHTMLReporter creates a new HTML reporter
+func NewHTMLReporter(w io.Writer, opts ...Option) *HTMLReporter {
+	return &HTMLReporter{w: w, opts: newOptions(opts)}
+}
+
+// Report generates an HTML report
+func (r *HTMLReporter) Report(ctx context.Context, results *categorizer.Results, meta Metadata) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return htmlTemplate.Execute(r.w, newHTMLData(results, meta, r.opts))
+}
+
+// htmlData is the input of htmlTemplate. Everything taken from the
+// analyzed code (file paths, variable names, suggestions) is escaped by
+// html/template for the context it appears in.
+type htmlData struct {
+	Summary  categorizer.Summary
+	StackPct float64
+	HeapPct  float64
+	Expiring []categorizer.SuppressionStatus
+	Hotspots []*hotspotNode
+	Effort   []htmlEffort
+	Escapes  []categorizer.CategorizedEscape
+	More     int // escapes beyond the limit
+	Links    bool
+	Ages     bool // escapes carry first-seen dates
+	Now      time.Time
+	Version  string
+	Duration string
+	Columns  int // of the escapes table
+	Lang     string
+	Catalog  *Catalog       // translates category names, nil for English
+	Config   *AppliedConfig // configuration of the analysis, nil when unknown
+	Sample   *Sample        // the packages analyzed, nil unless sampled
+
+	// Accept is set when escapes can be accepted from the report
+	Accept *htmlAccept
+
+	// Chart is embedded as a JSON data island that the chart script reads
+	Chart htmlChart
+
+	// Paged is set instead of Escapes above htmlPagedEscapes escapes, for
+	// a script to render the table a page at a time
+	Paged *htmlPaged
+}
+
+type htmlEffort struct {
+	Effort categorizer.Effort
+	Count  int
+	Pct    float64 // of all escapes
+}
+
+// htmlPagedEscapes is the number of escapes above which the escapes
+// table is rendered in the browser a page at a time: browsers freeze
+// laying out a table of tens of thousands of rows
+const htmlPagedEscapes = 5000
+
+// htmlPaged is the escapes table as a JSON data island
+type htmlPaged struct {
+	Ages    bool         `json:"ages"`
+	Accept  *htmlAccept  `json:"accept,omitempty"`
+	Escapes []htmlEscape `json:"escapes"`
+}
+
+// htmlAccept is where the accept form of an escape posts to
+type htmlAccept struct {
+	URL     string   `json:"url"`
+	Token   string   `json:"token"`
+	Targets []string `json:"targets"`
+}
+
+// htmlAcceptRow is the accept form of the escape at Index
+type htmlAcceptRow struct {
+	*htmlAccept
+	Index    int
+	Variable string
+}
+
+// htmlEscape is a row of the paged escapes table, with the details a
+// click on the row expands
+type htmlEscape struct {
+	Index      int      `json:"index"`
+	Location   string   `json:"location"`
+	Variable   string   `json:"variable"`
+	Category   string   `json:"category"` // display name
+	ID         string   `json:"id"`
+	Badge      string   `json:"badge"`
+	Age        string   `json:"age,omitempty"`
+	FirstSeen  string   `json:"firstSeen,omitempty"`
+	Suggestion string   `json:"suggestion"`
+	DocLink    string   `json:"docLink,omitempty"`
+	Reason     string   `json:"reason,omitempty"`
+	Object     string   `json:"object,omitempty"`  // size and alignment the compiler states
+	Inlined    string   `json:"inlined,omitempty"` // calls inlined at the location
+	Flow       []string `json:"flow,omitempty"`
+	Details    string   `json:"details,omitempty"`
+	Fix        string   `json:"fix,omitempty"`
+}
+
+type htmlChart struct {
+	Allocation  []int    `json:"allocation"` // stack, heap
+	Groups      []string `json:"groups"`
+	GroupCounts []int    `json:"groupCounts"`
+	Categories  []string `json:"categories"`
+	Counts      []int    `json:"counts"`
+}
+
+func newHTMLData(results *categorizer.Results, meta Metadata, opts options) htmlData {
+	d := htmlData{
+		Summary:  results.Summary,
+		Expiring: expiringSuppressions(results.Suppressions),
+		Escapes:  withCatalog(withOverrides(results.Escapes, opts), opts.catalog),
+		Links:    opts.links,
+		Now:      meta.now(),
+		Version:  meta.Version,
+		Config:   meta.Config,
+		Sample:   meta.Sample,
+		Lang:     opts.catalog.lang(),
+		Catalog:  opts.catalog,
+	}
+	_, d.Ages = newThisWeek(results.Escapes, d.Now)
+	if opts.acceptURL != "" && len(opts.acceptTargets) > 0 {
+		d.Accept = &htmlAccept{URL: opts.acceptURL, Token: opts.acceptToken, Targets: opts.acceptTargets}
+	}
+	if total := results.Summary.TotalVariables; total > 0 {
+		d.StackPct = float64(results.Summary.StackAllocated) / float64(total) * 100
+		d.HeapPct = float64(results.Summary.HeapAllocated) / float64(total) * 100
+	}
+	if meta.Duration > 0 {
+		d.Duration = meta.Duration.Round(time.Millisecond).String()
+	}
+	if opts.limit > 0 && len(d.Escapes) > opts.limit {
+		d.Escapes = d.Escapes[:opts.limit]
+		d.More = len(results.Escapes) - opts.limit
+	}
+	if len(d.Escapes) > htmlPagedEscapes {
+		d.Paged = newHTMLPaged(d)
+		d.Escapes = nil
+	}
+	d.Columns = 4
+	if d.Ages {
+		d.Columns++
+	}
+	if d.Accept != nil {
+		d.Columns++
+	}
+
+	if len(results.Summary.ByFile) > 0 {
+		d.Hotspots = hotspotTree(results.Summary.ByFile, results.Escapes).Top()
+	}
+
+	byEffort := categorizer.CountByEffort(results.Escapes)
+	for _, e := range categorizer.Efforts() {
+		if n := byEffort[e]; n > 0 {
+			d.Effort = append(d.Effort, htmlEffort{Effort: e, Count: n, Pct: float64(n) / float64(len(results.Escapes)) * 100})
+		}
+	}
+
+	d.Chart = htmlChart{
+		Allocation:  []int{results.Summary.StackAllocated, results.Summary.HeapAllocated},
+		Groups:      []string{},
+		GroupCounts: []int{},
+		Categories:  []string{},
+		Counts:      []int{},
+	}
+	// Every group, so each keeps its color
+	byGroup := categorizer.CountByGroup(results.ByCategory)
+	for _, g := range categorizer.Groups() {
+		d.Chart.Groups = append(d.Chart.Groups, string(g))
+		d.Chart.GroupCounts = append(d.Chart.GroupCounts, byGroup[g])
+	}
+	for _, cat := range sortCategories(results.ByCategory) {
+		d.Chart.Categories = append(d.Chart.Categories, opts.catalog.name(cat))
+		d.Chart.Counts = append(d.Chart.Counts, results.ByCategory[cat])
+	}
+	return d
+}
+
+// newHTMLPaged returns the rows of the paged escapes table
+func newHTMLPaged(d htmlData) *htmlPaged {
+	p := &htmlPaged{Ages: d.Ages, Accept: d.Accept, Escapes: make([]htmlEscape, len(d.Escapes))}
+	for i, e := range d.Escapes {
+		row := htmlEscape{
+			Index:      i,
+			Location:   fmt.Sprintf("%s:%d", e.Info.File, e.Info.Line),
+			Variable:   e.Info.Variable,
+			Category:   d.Catalog.name(e.Category),
+			ID:         string(e.Category),
+			Badge:      getCategoryBadgeClass(e.Category),
+			Suggestion: e.Suggestion.Short,
+			Reason:     e.Info.Reason,
+			Object:     heapObject(e.Info),
+			Inlined:    e.Info.InlineStack(),
+			Flow:       e.Info.FlowInfo,
+			Details:    e.Suggestion.Details,
+			Fix:        e.Fix,
+		}
+		if e.Impact != nil {
+			row.Suggestion += " (" + e.Impact.String() + ")"
+		}
+		if d.Links {
+			row.DocLink = e.Suggestion.DocLink
+		}
+		if d.Ages {
+			row.Age = categorizer.AgeLabel(e.FirstSeen, d.Now)
+			row.FirstSeen = e.FirstSeen
+		}
+		p.Escapes[i] = row
+	}
+	return p
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"badge":         getCategoryBadgeClass,
+	"name":          (*Catalog).name,
+	"age":           categorizer.AgeLabel,
+	"skippedStatus": skippedStatus,
+	"acceptRow": func(a *htmlAccept, i int, variable string) htmlAcceptRow {
+		return htmlAcceptRow{htmlAccept: a, Index: i, Variable: variable}
+	},
+	"suppressionBadge": func(status string) string {
+		if status == categorizer.SuppressionExpired {
+			return "badge-red"
+		}
+		return "badge-yellow"
+	},
+	"settings": func(settings map[string]any) string {
+		var b strings.Builder
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false) // the template escapes it
+		enc.SetIndent("", "  ")
+		enc.Encode(settings)
+		return b.String()
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>heapcheck Report</title>
+    <script src="https://cdn.jsdelivr.net/npm/chart.js"></script>
+    <style>
+` + htmlStyles + `    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>📊 heapcheck Report</h1>
+{{- with .Sample}}
+<div class="card sampled"><strong>⚠️ Sampled:</strong> {{.}}. The counts below cover the sample only; estimated for all packages: ~{{.Estimate $.Summary.HeapAllocated}} heap allocated of ~{{.Estimate $.Summary.TotalVariables}} variables.</div>
+{{- end}}
+<div class="grid-3" style="margin-bottom: 24px;">
+<div class="stat-card info"><div class="stat-value">{{.Summary.TotalVariables}}</div><div class="stat-label">Total Variables</div></div>
+<div class="stat-card success"><div class="stat-value">{{.Summary.StackAllocated}}</div><div class="stat-label">Stack Allocated</div><div class="stat-pct">{{printf "%.1f" .StackPct}}% ✓</div></div>
+<div class="stat-card danger"><div class="stat-value">{{.Summary.HeapAllocated}}</div><div class="stat-label">Heap Allocated</div><div class="stat-pct">{{printf "%.1f" .HeapPct}}% ⚠</div></div>
+</div>
+{{- with .Summary.Inlining}}
+<div class="card"><h2>⚡ Inlining</h2>
+<table>
+<tr><td>Inlinable functions</td><td><strong>{{.Inlinable}}</strong></td></tr>
+<tr><td>Calls inlined</td><td><strong>{{.CallsInlined}}</strong></td></tr>
+<tr><td>Not inlinable</td><td><strong>{{.Failed}}</strong></td></tr>
+{{- range $reason, $n := .FailReasons}}
+<tr><td style="padding-left: 32px;">{{$reason}}</td><td>{{$n}}</td></tr>
+{{- end}}
+</table></div>
+{{- end}}
+{{- with .Expiring}}
+<div class="card"><h2>⏳ Suppressions Nearing Expiry</h2>
+<table><tr><th>Status</th><th>Expires</th><th>Location</th><th>Variable</th><th>Category</th><th>Owner</th></tr>
+{{- range .}}
+<tr>
+	<td><span class="category-badge {{suppressionBadge .Status}}">{{.Status}}</span></td>
+	<td>{{.Expires}}</td>
+	<td><span class="file-link">{{.File}}:{{.Line}}</span></td>
+	<td><span class="var-name">{{.Variable}}</span></td>
+	<td>{{.Category}}</td>
+	<td>{{.Owner}}</td>
+</tr>
+{{- end}}
+</table></div>
+{{- end}}
+{{- with .Summary.Skipped}}
+<div class="card"><h2>⚠️ Not Analyzed</h2>
+<table><tr><th>Package</th><th>Status</th><th>Reason</th></tr>
+{{- range .}}
+<tr>
+	<td><span class="file-link">{{.Package}}</span></td>
+	<td><span class="category-badge {{if .PureGo}}badge-yellow{{else}}badge-red{{end}}">{{skippedStatus .}}</span></td>
+	<td>{{.Reason}}</td>
+</tr>
+{{- end}}
+</table></div>
+{{- end}}
+{{- if eq .Summary.HeapAllocated 0}}
+<div class="card no-escapes">
+	<div class="no-escapes-icon">🎉</div>
+	<div class="no-escapes-text">No heap escapes found!</div>
+	<p style="color: #6b7280; margin-top: 10px;">Your code is well-optimized for stack allocation.</p>
+</div>
+{{- else}}
+<div class="grid-3">
+<div class="card">
+	<h2>Allocation Distribution</h2>
+	<div class="chart-container">
+		<canvas id="allocationChart"></canvas>
+	</div>
+</div>
+<div class="card">
+	<h2>Escape Groups</h2>
+	<div class="chart-container">
+		<canvas id="groupsChart"></canvas>
+	</div>
+</div>
+<div class="card">
+	<h2>Escape Categories</h2>
+	<div class="chart-container">
+		<canvas id="categoriesChart"></canvas>
+	</div>
+</div>
+</div>
+{{- with .Hotspots}}
+<div class="card"><h2>🔥 Hotspots</h2>
+<div class="hotspot-tree">
+{{- range .}}{{template "hotspot" .}}{{end}}
+</div></div>
+{{- end}}
+{{- with .Effort}}
+<div class="card"><h2>🛠 Estimated Effort</h2>
+<table><tr><th>Effort</th><th style="width: 50%;">Escapes</th><th style="width: 80px;">Count</th></tr>
+{{- range .}}
+<tr>
+	<td>{{.Effort}}</td>
+	<td><div class="hotspot-bar"><div class="hotspot-fill" style="width: {{printf "%.1f" .Pct}}%;"></div></div></td>
+	<td><strong>{{.Count}}</strong></td>
+</tr>
+{{- end}}
+</table></div>
+{{- end}}
+<div class="card"><h2>📋 All Escapes</h2>
+{{- with .Paged}}
+<table id="escapes-table"><thead><tr><th>Location</th><th>Variable</th><th>Category</th>{{if .Ages}}<th>Age</th>{{end}}<th>Suggestion</th></tr></thead><tbody></tbody></table>
+<div class="pager">
+	<button id="escapes-prev">‹ Prev</button>
+	Page <input id="escapes-page" type="number" min="1" value="1"> of <span id="escapes-pages"></span>
+	<button id="escapes-next">Next ›</button>
+	<span class="pager-hint">{{len .Escapes}} escapes • click a row for details</span>
+</div>
+<script type="application/json" id="heapcheck-escapes">{{.}}</script>
+<script>
+(function() {
+	const paged = JSON.parse(document.getElementById('heapcheck-escapes').textContent);
+	const body = document.querySelector('#escapes-table tbody');
+	const input = document.getElementById('escapes-page');
+	const prev = document.getElementById('escapes-prev');
+	const next = document.getElementById('escapes-next');
+	const pageSize = 100;
+	const pages = Math.max(1, Math.ceil(paged.escapes.length / pageSize));
+	const columns = paged.ages ? 5 : 4;
+	let page = 0;
+	document.getElementById('escapes-pages').textContent = pages;
+	input.max = pages;
+
+	function add(parent, tag, text, className) {
+		const el = document.createElement(tag);
+		el.textContent = text;
+		if (className) el.className = className;
+		parent.appendChild(el);
+		return el;
+	}
+
+	// Details rows are built when a row is first expanded
+	function details(e) {
+		const tr = document.createElement('tr');
+		tr.className = 'escape-details';
+		const td = tr.insertCell();
+		td.colSpan = columns;
+		if (e.reason) add(td, 'div', e.reason);
+		if (e.object) add(td, 'div', 'Heap object: ' + e.object);
+		if (e.inlined) add(td, 'div', 'Inlined: ' + e.inlined);
+		if (e.flow) add(td, 'pre', e.flow.join('\n'));
+		if (e.details) add(td, 'div', e.details, 'suggestion');
+		if (e.fix) add(td, 'pre', e.fix, 'fix');
+		if (paged.accept) td.appendChild(acceptForm(e));
+		return tr;
+	}
+
+	// The form of the accept template, for the escape e
+	function acceptForm(e) {
+		const form = document.createElement('form');
+		form.method = 'post';
+		form.action = paged.accept.url;
+		form.className = 'accept';
+		function input(name, type, value, placeholder) {
+			const el = document.createElement('input');
+			el.name = name;
+			el.type = type;
+			el.value = value;
+			if (placeholder) el.placeholder = placeholder;
+			form.appendChild(el);
+			return el;
+		}
+		input('token', 'hidden', paged.accept.token);
+		input('escape', 'hidden', e.index);
+		input('variable', 'hidden', e.variable);
+		input('reason', 'text', '', 'Reason').required = true;
+		input('owner', 'text', '', '@owner');
+		input('expires', 'date', '').title = 'Expires';
+		if (paged.accept.targets.length > 1) {
+			const target = document.createElement('select');
+			target.name = 'target';
+			for (const t of paged.accept.targets) add(target, 'option', t);
+			form.appendChild(target);
+		} else {
+			input('target', 'hidden', paged.accept.targets[0]);
+		}
+		add(form, 'button', 'Accept');
+		return form;
+	}
+
+	function render() {
+		body.replaceChildren();
+		for (const e of paged.escapes.slice(page * pageSize, (page + 1) * pageSize)) {
+			const tr = body.insertRow();
+			tr.className = 'escape-row';
+			add(tr.insertCell(), 'span', e.location, 'file-link');
+			add(tr.insertCell(), 'span', e.variable, 'var-name');
+			add(tr.insertCell(), 'span', e.category, 'category-badge ' + e.badge).title = e.id;
+			if (paged.ages) add(tr, 'td', e.age || '').title = e.firstSeen || '';
+			const suggestion = add(tr, 'td', e.suggestion, 'suggestion');
+			if (e.docLink) {
+				suggestion.append(' ');
+				add(suggestion, 'a', 'docs').href = e.docLink;
+			}
+			let open = null;
+			tr.addEventListener('click', function(ev) {
+				if (ev.target.tagName === 'A') return;
+				if (open) {
+					open.remove();
+					open = null;
+				} else {
+					open = details(e);
+					tr.after(open);
+				}
+			});
+		}
+		input.value = page + 1;
+		prev.disabled = page === 0;
+		next.disabled = page === pages - 1;
+	}
+
+	function go(p) {
+		page = Math.min(Math.max(p, 0), pages - 1);
+		render();
+	}
+	prev.addEventListener('click', function() { go(page - 1); });
+	next.addEventListener('click', function() { go(page + 1); });
+	input.addEventListener('change', function() { go(parseInt(input.value, 10) - 1 || 0); });
+	render();
+})();
+</script>
+{{- else}}
+<table><tr><th>Location</th><th>Variable</th><th>Category</th>{{if .Ages}}<th>Age</th>{{end}}<th>Suggestion</th>{{if .Accept}}<th></th>{{end}}</tr>
+{{- range $i, $e := .Escapes}}
+<tr>
+	<td><span class="file-link">{{.Info.File}}:{{.Info.Line}}</span>{{with .Info.InlineStack}}<br><small>inlined: {{.}}</small>{{end}}</td>
+	<td><span class="var-name">{{.Info.Variable}}</span></td>
+	<td><span class="category-badge {{badge .Category}}" title="{{.Category}}">{{name $.Catalog .Category}}</span></td>
+	{{- if $.Ages}}
+	<td title="{{.FirstSeen}}">{{age .FirstSeen $.Now}}</td>
+	{{- end}}
+	<td class="suggestion">{{.Suggestion.Short}}{{with .Impact}} ({{.}}){{end}}{{if and $.Links .Suggestion.DocLink}} <a href="{{.Suggestion.DocLink}}">docs</a>{{end}}</td>
+	{{- with $.Accept}}
+	<td>{{template "accept" acceptRow . $i $e.Info.Variable}}</td>
+	{{- end}}
+</tr>
+{{- with .Fix}}
+<tr class="escape-details"><td colspan="{{$.Columns}}"><pre class="fix">{{.}}</pre></td></tr>
+{{- end}}
+{{- end}}
+</table>
+{{- end}}
+{{- if .More}}
+<p style="color: #6b7280;">... and {{.More}} more</p>
+{{- end}}
+</div>
+<script type="application/json" id="heapcheck-data">{{.Chart}}</script>
+<script>
+const data = JSON.parse(document.getElementById('heapcheck-data').textContent);
+
+// Allocation Pie Chart
+new Chart(document.getElementById('allocationChart'), {
+	type: 'doughnut',
+	data: {
+		labels: ['Stack Allocated', 'Heap Allocated'],
+		datasets: [{
+			data: data.allocation,
+			backgroundColor: ['#22c55e', '#ef4444'],
+			borderWidth: 0,
+			hoverOffset: 4
+		}]
+	},
+	options: {
+		responsive: true,
+		maintainAspectRatio: false,
+		plugins: {
+			legend: { position: 'bottom' },
+			tooltip: {
+				callbacks: {
+					label: function(context) {
+						let total = context.dataset.data.reduce((a, b) => a + b, 0);
+						let pct = ((context.raw / total) * 100).toFixed(1);
+						return context.label + ': ' + context.raw + ' (' + pct + '%)';
+					}
+				}
+			}
+		}
+	}
+});
+
+// Groups Pie Chart
+new Chart(document.getElementById('groupsChart'), {
+	type: 'doughnut',
+	data: {
+		labels: data.groups,
+		datasets: [{
+			data: data.groupCounts,
+			backgroundColor: ['#ef4444', '#f97316', '#3b82f6', '#a855f7', '#9ca3af'],
+			borderWidth: 0,
+			hoverOffset: 4
+		}]
+	},
+	options: {
+		responsive: true,
+		maintainAspectRatio: false,
+		plugins: { legend: { position: 'bottom' } }
+	}
+});
+
+// Categories Bar Chart
+new Chart(document.getElementById('categoriesChart'), {
+	type: 'bar',
+	data: {
+		labels: data.categories,
+		datasets: [{
+			label: 'Count',
+			data: data.counts,
+			backgroundColor: [
+				'#ef4444', '#f97316', '#f59e0b', '#eab308', '#84cc16',
+				'#22c55e', '#14b8a6', '#06b6d4', '#0ea5e9', '#3b82f6',
+				'#6366f1', '#8b5cf6', '#a855f7', '#d946ef', '#ec4899'
+			],
+			borderRadius: 6
+		}]
+	},
+	options: {
+		responsive: true,
+		maintainAspectRatio: false,
+		indexAxis: 'y',
+		plugins: {
+			legend: { display: false }
+		},
+		scales: {
+			x: { beginAtZero: true, grid: { display: false } },
+			y: { grid: { display: false } }
+		}
+	}
+});
+</script>
+{{- end}}
+{{- with .Config}}
+<div class="card config"><h2>⚙️ Configuration</h2>
+<table>
+{{- with .Patterns}}<tr><td>Packages</td><td><span class="file-link">{{range $i, $p := .}}{{if $i}} {{end}}{{$p}}{{end}}</span></td></tr>{{end}}
+{{- range .Given}}<tr><td>--{{.}}</td><td><code>{{index $.Config.Flags .}}</code></td></tr>{{end}}
+{{- with .Ruleset}}<tr><td>Category rules</td><td><span class="file-link">{{.}}</span></td></tr>{{end}}
+</table>
+{{- range .Files}}
+<details><summary><span class="file-link">{{.Path}}</span></summary><pre>{{settings .Settings}}</pre></details>
+{{- end}}
+<details><summary>All flags, with defaults</summary><table>
+{{- range $name, $value := .Flags}}<tr><td>--{{$name}}</td><td><code>{{$value}}</code></td></tr>{{end}}
+</table></details>
+</div>
+{{- end}}
+<div class="footer">Generated by <strong>heapcheck</strong>{{with .Version}} {{.}}{{end}}{{with .Duration}} in {{.}}{{end}} • <a href="https://github.com/harshakonda/heapcheck" style="color: #6b7280;">github.com/harshakonda/heapcheck</a></div>
+</div></body></html>
+{{- define "accept"}}<details class="accept"><summary>Accept</summary><form method="post" action="{{.URL}}" class="accept">
+	<input type="hidden" name="token" value="{{.Token}}"><input type="hidden" name="escape" value="{{.Index}}"><input type="hidden" name="variable" value="{{.Variable}}">
+	<input name="reason" placeholder="Reason" required>
+	<input name="owner" placeholder="@owner">
+	<input name="expires" type="date" title="Expires">
+	{{- if gt (len .Targets) 1}}
+	<select name="target">{{range .Targets}}<option>{{.}}</option>{{end}}</select>
+	{{- else}}
+	<input type="hidden" name="target" value="{{index .Targets 0}}">
+	{{- end}}
+	<button>Accept</button>
+</form></details>
+{{- end}}
+{{- define "hotspot"}}
+{{- if .IsFile}}
+<div class="hotspot-row"><span class="file-link">{{.Label}}</span><div class="hotspot-bar"><div class="hotspot-fill" style="width: {{printf "%.1f" .Pct}}%;"></div></div><strong>{{.Count}}</strong></div>
+{{- else}}
+<details{{if ge .Pct 25.0}} open{{end}}><summary class="hotspot-row"><span{{with .Package}} title="{{.}}"{{end}}>{{.Label}}</span><div class="hotspot-bar"><div class="hotspot-fill" style="width: {{printf "%.1f" .Pct}}%;"></div></div><strong>{{.Count}}</strong></summary>
+{{- range .Children}}{{template "hotspot" .}}{{end}}
+</details>
+{{- end}}
+{{- end}}
+`))
+
+// htmlStyles is the stylesheet shared by the HTML report and diff pages
+const htmlStyles = `        * { box-sizing: border-box; }
+        body { 
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
+            margin: 0; padding: 20px; background: #f5f5f5;
+        }
+        .container { max-width: 1400px; margin: 0 auto; }
+        h1 { color: #333; margin-bottom: 30px; }
+        h2 { color: #444; margin-top: 0; margin-bottom: 20px; border-bottom: 2px solid #e5e7eb; padding-bottom: 10px; }
+        .card { 
+            background: white; border-radius: 12px; padding: 24px; 
+            margin-bottom: 24px; box-shadow: 0 4px 6px rgba(0,0,0,0.07);
+        }
+        .grid-2 { display: grid; grid-template-columns: 1fr 1fr; gap: 24px; }
+        .grid-3 { display: grid; grid-template-columns: repeat(auto-fit, minmax(200px, 1fr)); gap: 20px; }
+        @media (max-width: 768px) { .grid-2 { grid-template-columns: 1fr; } }
+        
+        .stat-card {
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            border-radius: 12px; padding: 24px; color: white; text-align: center;
+        }
+        .stat-card.success { background: linear-gradient(135deg, #11998e 0%, #38ef7d 100%); }
+        .stat-card.danger { background: linear-gradient(135deg, #eb3349 0%, #f45c43 100%); }
+        .stat-card.info { background: linear-gradient(135deg, #2196F3 0%, #21CBF3 100%); }
+        .stat-value { font-size: 3em; font-weight: bold; margin-bottom: 5px; }
+        .stat-label { font-size: 1em; opacity: 0.9; }
+        .stat-pct { font-size: 0.9em; opacity: 0.8; margin-top: 5px; }
+        
+        .chart-container { position: relative; height: 300px; }
+        .chart-container-sm { position: relative; height: 250px; }
+        
+        table { width: 100%; border-collapse: collapse; }
+        th, td { padding: 12px 16px; text-align: left; border-bottom: 1px solid #e5e7eb; }
+        th { background: #f9fafb; font-weight: 600; color: #374151; }
+        tr:hover { background: #f9fafb; }
+        
+        .category-badge {
+            display: inline-block; padding: 4px 12px; border-radius: 20px;
+            font-size: 0.85em; font-weight: 500;
+        }
+        .badge-red { background: #fee2e2; color: #dc2626; }
+        .badge-orange { background: #ffedd5; color: #ea580c; }
+        .badge-yellow { background: #fef3c7; color: #ca8a04; }
+        .badge-green { background: #dcfce7; color: #16a34a; }
+        .badge-blue { background: #dbeafe; color: #2563eb; }
+        .badge-purple { background: #f3e8ff; color: #9333ea; }
+        .badge-gray { background: #f3f4f6; color: #6b7280; }
+        
+        .suggestion { color: #059669; font-style: italic; font-size: 0.9em; }
+        .file-link { color: #2563eb; text-decoration: none; font-family: monospace; }
+        .file-link:hover { text-decoration: underline; }
+        .var-name { font-family: monospace; background: #f3f4f6; padding: 2px 6px; border-radius: 4px; }
+        
+        .hotspot-bar {
+            background: #e5e7eb; border-radius: 4px; height: 24px; position: relative; overflow: hidden;
+        }
+        .hotspot-fill {
+            background: linear-gradient(90deg, #ef4444 0%, #f97316 100%);
+            height: 100%; border-radius: 4px; transition: width 0.3s;
+        }
+        .hotspot-tree details { margin-left: 20px; }
+        .hotspot-tree > details { margin-left: 0; }
+        .hotspot-tree details > .hotspot-row:not(summary) { margin-left: 20px; }
+        .hotspot-row {
+            display: grid; grid-template-columns: minmax(200px, 1fr) 50% 60px; gap: 12px;
+            align-items: center; padding: 4px 0; cursor: default;
+        }
+        summary.hotspot-row { cursor: pointer; list-style: none; }
+        summary.hotspot-row::before { content: "▸ "; }
+        details[open] > summary.hotspot-row::before { content: "▾ "; }
+        .hotspot-label {
+            position: absolute; right: 8px; top: 50%; transform: translateY(-50%);
+            font-size: 0.8em; font-weight: 600; color: #374151;
+        }
+        
+        .legend-item { display: flex; align-items: center; margin-bottom: 8px; }
+        .legend-color { width: 16px; height: 16px; border-radius: 4px; margin-right: 10px; }
+        .legend-text { font-size: 0.9em; color: #4b5563; }
+        
+        .no-escapes {
+            text-align: center; padding: 60px 20px; color: #059669;
+        }
+        .no-escapes-icon { font-size: 4em; margin-bottom: 20px; }
+        .no-escapes-text { font-size: 1.5em; font-weight: 600; }
+        
+        .pager { display: flex; align-items: center; gap: 8px; margin-top: 16px; color: #374151; }
+        .pager input { width: 70px; padding: 4px; }
+        .pager button { padding: 4px 12px; border: 1px solid #d1d5db; border-radius: 6px; background: white; cursor: pointer; }
+        .pager button:disabled { opacity: 0.5; cursor: default; }
+        .pager-hint { color: #6b7280; margin-left: auto; }
+        .escape-row { cursor: pointer; }
+        .escape-details td { background: #f9fafb; color: #4b5563; font-size: 0.9em; }
+        .escape-details pre { margin: 8px 0; white-space: pre-wrap; }
+        .escape-details pre.fix { background: #f3f4f6; border-left: 3px solid #10b981; padding: 6px 10px; }
+        details.accept summary { cursor: pointer; color: #2563eb; white-space: nowrap; }
+        form.accept { display: flex; flex-direction: column; gap: 4px; margin-top: 6px; }
+        .escape-details form.accept { flex-direction: row; flex-wrap: wrap; align-items: center; }
+        form.accept input, form.accept select, form.accept button { padding: 4px; font-size: 0.9em; }
+        
+        .sampled { background: #fffbeb; border-left: 4px solid #f59e0b; color: #92400e; }
+        .config summary { cursor: pointer; margin-top: 12px; color: #374151; }
+        .config pre { background: #f9fafb; padding: 8px 12px; white-space: pre-wrap; }
+        
+        .footer { text-align: center; color: #9ca3af; font-size: 0.85em; margin-top: 40px; padding: 20px; }
+`
+
+// getCategoryBadgeClass returns the CSS class for a category badge
+func getCategoryBadgeClass(cat categorizer.Category) string {
+	switch cat {
+	case categorizer.CategoryReturnPointer, categorizer.CategoryInterfaceBoxing:
+		return "badge-red"
+	case categorizer.CategoryClosureCapture, categorizer.CategoryStoredClosure, categorizer.CategoryGoroutineEscape:
+		return "badge-orange"
+	case categorizer.CategorySliceGrow, categorizer.CategoryChannelSend:
+		return "badge-yellow"
+	case categorizer.CategoryFmtCall, categorizer.CategoryReflection, categorizer.CategoryPoolMisuse:
+		return "badge-blue"
+	case categorizer.CategoryUnknownSize, categorizer.CategoryTooLarge:
+		return "badge-purple"
+	default:
+		return "badge-gray"
+	}
+}
@@ -0,0 +1,127 @@
+package reporter
+
+import "github.com/harshakonda/heapcheck/internal/categorizer"
+
+// catalogs are the built-in translations of the HTML report. They
+// translate the category names and short suggestions; the details stay in
+// English.
+var catalogs = map[string]*Catalog{
+	"de": {
+		Lang: "de",
+		Categories: map[categorizer.Category]string{
+			categorizer.CategoryReturnPointer:    "Rückgabe eines Zeigers",
+			categorizer.CategoryInterfaceBoxing:  "Interface-Boxing",
+			categorizer.CategoryClosureCapture:   "Closure-Erfassung",
+			categorizer.CategoryStoredClosure:    "Gespeicherte Closure",
+			categorizer.CategoryGoroutineEscape:  "Goroutine-Escape",
+			categorizer.CategoryChannelSend:      "Channel-Senden",
+			categorizer.CategorySliceGrow:        "Slice-Wachstum",
+			categorizer.CategoryUnknownSize:      "Unbekannte Größe",
+			categorizer.CategoryTooLarge:         "Zu groß",
+			categorizer.CategoryFmtCall:          "fmt-Aufruf",
+			categorizer.CategoryReflection:       "Reflection",
+			categorizer.CategoryContextValue:     "Context-Wert",
+			categorizer.CategoryPoolMisuse:       "Pool-Fehlgebrauch",
+			categorizer.CategoryErrorWrapping:    "Fehler-Wrapping",
+			categorizer.CategoryLeakingParam:     "Entweichender Parameter",
+			categorizer.CategoryLeakingContent:   "Entweichender Parameterinhalt",
+			categorizer.CategoryCompilerForced:   "Vom Compiler erzwungen",
+			categorizer.CategoryStringConversion: "String-Konvertierung",
+			categorizer.CategorySpill:            "Spill",
+			categorizer.CategoryAssignment:       "Zuweisung",
+			categorizer.CategoryCallParameter:    "Aufrufparameter",
+			categorizer.CategoryMapAllocation:    "Map-Allokation",
+			categorizer.CategoryNewAllocation:    "new()-Allokation",
+			categorizer.CategoryCompositeLiteral: "Composite-Literal",
+			categorizer.CategoryUncategorized:    "Nicht kategorisiert",
+			categorizer.CategoryLargeValueCopy:   "Kopie großer Werte",
+		},
+		Suggestions: map[categorizer.Category]categorizer.Suggestion{
+			categorizer.CategoryReturnPointer:    {Short: "Bei Structs ≤ 64 Bytes als Wert zurückgeben"},
+			categorizer.CategoryInterfaceBoxing:  {Short: "In Hot Paths konkrete Typen verwenden"},
+			categorizer.CategoryClosureCapture:   {Short: "Variablen als Parameter übergeben statt sie zu erfassen"},
+			categorizer.CategoryStoredClosure:    {Short: "Nur den Zustand erfassen, den ein gespeicherter Callback braucht"},
+			categorizer.CategoryGoroutineEscape:  {Short: "Für häufig gestartete Goroutinen Worker-Pools erwägen"},
+			categorizer.CategoryChannelSend:      {Short: "Channels puffern oder sync.Pool für gesendete Werte verwenden"},
+			categorizer.CategorySliceGrow:        {Short: "Slice-Kapazität vorab reservieren"},
+			categorizer.CategoryUnknownSize:      {Short: "Arrays fester Größe verwenden, wenn die Länge bekannt ist"},
+			categorizer.CategoryTooLarge:         {Short: "Große Allokationen landen absichtlich auf dem Heap"},
+			categorizer.CategoryFmtCall:          {Short: "In Hot Paths strconv verwenden"},
+			categorizer.CategoryReflection:       {Short: "reflect in Hot Paths vermeiden"},
+			categorizer.CategoryContextValue:     {Short: "Typisierte Context-Keys und einen Zeigerwert verwenden"},
+			categorizer.CategoryPoolMisuse:       {Short: "Zeiger in sync.Pool legen und nach Put nicht mehr verwenden"},
+			categorizer.CategoryErrorWrapping:    {Short: "In Hot Paths Sentinel- oder typisierte Fehler zurückgeben"},
+			categorizer.CategoryLeakingParam:     {Short: "Parameter entweicht dem Funktionsbereich"},
+			categorizer.CategoryLeakingContent:   {Short: "Inhalt des Parameters entweicht: Behaltenes kopieren"},
+			categorizer.CategoryCompilerForced:   {Short: "Strukturell, kaum behebbar: von der Analyse des Compilers erzwungen"},
+			categorizer.CategoryStringConversion: {Short: "String-Konvertierung alloziert"},
+			categorizer.CategorySpill:            {Short: "Compiler hat den Wert auf den Heap ausgelagert"},
+			categorizer.CategoryAssignment:       {Short: "Wert wird einem entweichenden Ort zugewiesen"},
+			categorizer.CategoryCallParameter:    {Short: "Wert entweicht über einen Funktionsaufruf"},
+			categorizer.CategoryMapAllocation:    {Short: "Maps werden immer auf dem Heap alloziert"},
+			categorizer.CategoryNewAllocation:    {Short: "new() alloziert immer auf dem Heap"},
+			categorizer.CategoryCompositeLiteral: {Short: "Composite-Literal entweicht"},
+			categorizer.CategoryUncategorized:    {Short: "Details des Escape-Flows prüfen"},
+			categorizer.CategoryLargeValueCopy:   {Short: "Große Structs in Hot Paths hinter einem Zeiger halten"},
+		},
+	},
+	"ja": {
+		Lang: "ja",
+		Categories: map[categorizer.Category]string{
+			categorizer.CategoryReturnPointer:    "ポインタの返却",
+			categorizer.CategoryInterfaceBoxing:  "インターフェースへのボクシング",
+			categorizer.CategoryClosureCapture:   "クロージャによるキャプチャ",
+			categorizer.CategoryStoredClosure:    "保存されたクロージャ",
+			categorizer.CategoryGoroutineEscape:  "ゴルーチンへのエスケープ",
+			categorizer.CategoryChannelSend:      "チャネル送信",
+			categorizer.CategorySliceGrow:        "スライスの拡張",
+			categorizer.CategoryUnknownSize:      "サイズ不明",
+			categorizer.CategoryTooLarge:         "サイズ過大",
+			categorizer.CategoryFmtCall:          "fmt 呼び出し",
+			categorizer.CategoryReflection:       "リフレクション",
+			categorizer.CategoryContextValue:     "context の値",
+			categorizer.CategoryPoolMisuse:       "プールの誤用",
+			categorizer.CategoryErrorWrapping:    "エラーのラップ",
+			categorizer.CategoryLeakingParam:     "パラメータのリーク",
+			categorizer.CategoryLeakingContent:   "パラメータ内容のリーク",
+			categorizer.CategoryCompilerForced:   "コンパイラによる強制",
+			categorizer.CategoryStringConversion: "文字列変換",
+			categorizer.CategorySpill:            "スピル",
+			categorizer.CategoryAssignment:       "代入",
+			categorizer.CategoryCallParameter:    "呼び出し引数",
+			categorizer.CategoryMapAllocation:    "マップの割り当て",
+			categorizer.CategoryNewAllocation:    "new() による割り当て",
+			categorizer.CategoryCompositeLiteral: "複合リテラル",
+			categorizer.CategoryUncategorized:    "未分類",
+			categorizer.CategoryLargeValueCopy:   "大きな値のコピー",
+		},
+		Suggestions: map[categorizer.Category]categorizer.Suggestion{
+			categorizer.CategoryReturnPointer:    {Short: "64 バイト以下の構造体は値で返す"},
+			categorizer.CategoryInterfaceBoxing:  {Short: "ホットパスでは具象型を使う"},
+			categorizer.CategoryClosureCapture:   {Short: "変数をキャプチャせず引数として渡す"},
+			categorizer.CategoryStoredClosure:    {Short: "保存するコールバックには必要な状態だけをキャプチャする"},
+			categorizer.CategoryGoroutineEscape:  {Short: "頻繁に起動するゴルーチンにはワーカープールを検討する"},
+			categorizer.CategoryChannelSend:      {Short: "チャネルをバッファするか、送信する値に sync.Pool を使う"},
+			categorizer.CategorySliceGrow:        {Short: "スライスの容量を事前に確保する"},
+			categorizer.CategoryUnknownSize:      {Short: "長さが分かっている場合は固定長配列を使う"},
+			categorizer.CategoryTooLarge:         {Short: "大きな割り当ては設計上ヒープに置かれる"},
+			categorizer.CategoryFmtCall:          {Short: "ホットパスでは strconv を使う"},
+			categorizer.CategoryReflection:       {Short: "ホットパスでは reflect を避ける"},
+			categorizer.CategoryContextValue:     {Short: "型付きの context キーと単一のポインタ値を使う"},
+			categorizer.CategoryPoolMisuse:       {Short: "sync.Pool にはポインタを入れ、Put の後は使わない"},
+			categorizer.CategoryErrorWrapping:    {Short: "ホットパスではセンチネルエラーか型付きエラーを返す"},
+			categorizer.CategoryLeakingParam:     {Short: "パラメータが関数のスコープ外へエスケープする"},
+			categorizer.CategoryLeakingContent:   {Short: "パラメータの内容がエスケープする: 保持する分をコピーする"},
+			categorizer.CategoryCompilerForced:   {Short: "構造的な理由で修正は困難: コンパイラの解析により強制される"},
+			categorizer.CategoryStringConversion: {Short: "文字列変換で割り当てが発生する"},
+			categorizer.CategorySpill:            {Short: "コンパイラが値をヒープに退避した"},
+			categorizer.CategoryAssignment:       {Short: "エスケープする場所に値が代入されている"},
+			categorizer.CategoryCallParameter:    {Short: "関数呼び出しを通じて値がエスケープする"},
+			categorizer.CategoryMapAllocation:    {Short: "マップは常にヒープに割り当てられる"},
+			categorizer.CategoryNewAllocation:    {Short: "new() は常にヒープに割り当てる"},
+			categorizer.CategoryCompositeLiteral: {Short: "複合リテラルがエスケープする"},
+			categorizer.CategoryUncategorized:    {Short: "エスケープのフロー詳細を確認する"},
+			categorizer.CategoryLargeValueCopy:   {Short: "ホットパスでは大きな構造体をポインタ経由で扱う"},
+		},
+	},
+}
@@ -0,0 +1,142 @@
+package reporter
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+// =============================================================================
+// Matrix Reporter
+// =============================================================================
+
+// MatrixReporter outputs a package × category pivot table of escape
+// counts, as an aligned text table or as CSV for spreadsheets
+type MatrixReporter struct {
+	w   io.Writer
+	csv bool
+}
+
+// NewMatrixReporter creates a new matrix reporter
+func NewMatrixReporter(w io.Writer, csv bool) *MatrixReporter {
+	return &MatrixReporter{w: w, csv: csv}
+}
+
+// matrix holds escape counts per package and category
+type matrix struct {
+	packages   []string
+	categories []categorizer.Category
+	counts     map[string]map[categorizer.Category]int
+	pkgTotals  map[string]int
+	catTotals  map[categorizer.Category]int
+	total      int
+	skipped    []parser.SkippedPackage
+}
+
+// buildMatrix pivots escapes by package (the import path, or the file's
+// directory without package headers) and category. Rows and columns are
+// sorted by total count, largest first.
+func buildMatrix(results *categorizer.Results) *matrix {
+	m := &matrix{
+		counts:    make(map[string]map[categorizer.Category]int),
+		pkgTotals: make(map[string]int),
+		catTotals: make(map[categorizer.Category]int),
+		skipped:   results.Summary.Skipped,
+	}
+	for _, e := range results.Escapes {
+		pkg := e.Info.PackageOrDir()
+		if m.counts[pkg] == nil {
+			m.counts[pkg] = make(map[categorizer.Category]int)
+		}
+		m.counts[pkg][e.Category]++
+		m.pkgTotals[pkg]++
+		m.catTotals[e.Category]++
+		m.total++
+	}
+
+	for pkg := range m.pkgTotals {
+		m.packages = append(m.packages, pkg)
+	}
+	sort.Slice(m.packages, func(i, j int) bool {
+		a, b := m.packages[i], m.packages[j]
+		if m.pkgTotals[a] != m.pkgTotals[b] {
+			return m.pkgTotals[a] > m.pkgTotals[b]
+		}
+		return a < b
+	})
+	m.categories = sortCategories(m.catTotals)
+	return m
+}
+
+// rows returns the header, one row per package and a totals row
+func (m *matrix) rows() [][]string {
+	header := []string{"package"}
+	for _, cat := range m.categories {
+		header = append(header, string(cat))
+	}
+	header = append(header, "total")
+
+	rows := [][]string{header}
+	for _, pkg := range m.packages {
+		row := []string{pkg}
+		for _, cat := range m.categories {
+			row = append(row, strconv.Itoa(m.counts[pkg][cat]))
+		}
+		rows = append(rows, append(row, strconv.Itoa(m.pkgTotals[pkg])))
+	}
+
+	// Packages left out of the analysis have no counts at all
+	for _, p := range m.skipped {
+		if p.PureGo {
+			continue
+		}
+		row := []string{p.Package}
+		for range m.categories {
+			row = append(row, "-")
+		}
+		rows = append(rows, append(row, "-"))
+	}
+
+	totals := []string{"total"}
+	for _, cat := range m.categories {
+		totals = append(totals, strconv.Itoa(m.catTotals[cat]))
+	}
+	return append(rows, append(totals, strconv.Itoa(m.total)))
+}
+
+// Report generates the matrix
+func (r *MatrixReporter) Report(ctx context.Context, results *categorizer.Results, meta Metadata) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	rows := buildMatrix(results).rows()
+
+	if r.csv {
+		cw := csv.NewWriter(r.w)
+		if err := cw.WriteAll(rows); err != nil {
+			return fmt.Errorf("writing CSV: %w", err)
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(r.w, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if skipped := results.Summary.Skipped; len(skipped) > 0 {
+		fmt.Fprintln(r.w, "")
+		printNotAnalyzed(r.w, skipped, DefaultWidth)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func matrixResults() *categorizer.Results {
+	escape := func(file string, cat categorizer.Category) categorizer.CategorizedEscape {
+		return categorizer.CategorizedEscape{Info: parser.EscapeInfo{File: file}, Category: cat}
+	}
+	return &categorizer.Results{Escapes: []categorizer.CategorizedEscape{
+		escape("pkg/server/handler.go", categorizer.CategoryInterfaceBoxing),
+		escape("pkg/server/router.go", categorizer.CategoryInterfaceBoxing),
+		escape("pkg/server/router.go", categorizer.CategoryFmtCall),
+		escape("pkg/db/conn.go", categorizer.CategoryReturnPointer),
+	}}
+}
+
+func TestMatrixReporterCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewMatrixReporter(&buf, true).Report(context.Background(), matrixResults(), Metadata{}); err != nil {
+		t.Fatalf("Matrix reporter failed: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV output: %v", err)
+	}
+
+	want := [][]string{
+		{"package", "interface-boxing", "fmt-call", "return-pointer", "total"},
+		{"pkg/server", "2", "1", "0", "3"},
+		{"pkg/db", "0", "0", "1", "1"},
+		{"total", "2", "1", "1", "4"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("CSV rows = %d, want %d:\n%v", len(records), len(want), records)
+	}
+	for i := range want {
+		if strings.Join(records[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("row %d = %v, want %v", i, records[i], want[i])
+		}
+	}
+}
+
+func TestMatrixReporterText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewMatrixReporter(&buf, false).Report(context.Background(), matrixResults(), Metadata{}); err != nil {
+		t.Fatalf("Matrix reporter failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("text matrix lines = %d, want 4:\n%s", len(lines), buf.String())
+	}
+	if !strings.HasPrefix(lines[1], "pkg/server") || !strings.HasPrefix(lines[3], "total") {
+		t.Errorf("unexpected row order:\n%s", buf.String())
+	}
+	// Columns line up: every category header starts where its counts do
+	col := strings.Index(lines[0], "fmt-call")
+	if col < 0 || lines[1][col] != '1' {
+		t.Errorf("columns not aligned:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,177 @@
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+// PRCommentMarker starts every pull request comment body, so a bot can
+// find the comment of an earlier push and edit it instead of adding one
+const PRCommentMarker = "<!-- heapcheck:pr-comment -->"
+
+// PRCommentMaxLen is the longest comment body GitHub accepts, in
+// characters. Escape tables are cut short to fit.
+const PRCommentMaxLen = 65536
+
+// PRCommentReporter outputs a Markdown pull request comment: the escape
+// counts and changes from the baseline up front, the escapes in
+// collapsed sections
+type PRCommentReporter struct {
+	w      io.Writer
+	opts   options
+	maxLen int
+}
+
+// NewPRCommentReporter creates a new pull request comment reporter
+func NewPRCommentReporter(w io.Writer, opts ...Option) *PRCommentReporter {
+	return &PRCommentReporter{w: w, opts: newOptions(opts), maxLen: PRCommentMaxLen}
+}
+
+// Report writes the comment body
+func (r *PRCommentReporter) Report(ctx context.Context, results *categorizer.Results, meta Metadata) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	escapes := withOverrides(results.Escapes, r.opts)
+
+	var head strings.Builder
+	head.WriteString(PRCommentMarker + "\n")
+	switch d := meta.Delta; {
+	case d == nil:
+		fmt.Fprintf(&head, "## 📊 heapcheck: %d heap escape(s)\n\n", results.Summary.HeapAllocated)
+	case len(d.Added) == 0:
+		fmt.Fprintf(&head, "## ✅ heapcheck: no new heap escapes\n\n")
+	default:
+		fmt.Fprintf(&head, "## ⚠️ heapcheck: %d new heap escape(s)\n\n", len(d.Added))
+	}
+
+	s := results.Summary
+	head.WriteString("| | Count |\n|---|---:|\n")
+	fmt.Fprintf(&head, "| Heap escapes | %d |\n", s.HeapAllocated)
+	if d := meta.Delta; d != nil {
+		fmt.Fprintf(&head, "| New since the baseline | %d |\n", len(d.Added))
+		fmt.Fprintf(&head, "| Resolved since the baseline | %d |\n", len(d.Removed))
+	}
+	if s.TotalVariables > 0 {
+		fmt.Fprintf(&head, "| Stack allocated | %d (%.1f%%) |\n", s.StackAllocated, float64(s.StackAllocated)/float64(s.TotalVariables)*100)
+	}
+	if s.Suppressed > 0 {
+		fmt.Fprintf(&head, "| Suppressed | %d |\n", s.Suppressed)
+	}
+	head.WriteString("\n")
+	if err := WriteMarkdownSummary(&head, results, meta); err != nil {
+		return err
+	}
+
+	var tail strings.Builder
+	tail.WriteString("<sub>Generated by heapcheck")
+	if meta.Version != "" {
+		tail.WriteString(" " + meta.Version)
+	}
+	tail.WriteString("</sub>\n")
+
+	// The head, cut to whole lines, then sections in order of interest,
+	// each cut short to what is left of the limit after the ones before
+	// it and the footer
+	b := &commentBuilder{limit: r.maxLen - tail.Len()}
+	b.WriteString(cutLines(head.String(), b.limit))
+	if d := meta.Delta; d != nil && len(d.Added) > 0 {
+		rows := make([]string, len(d.Added))
+		for i, e := range d.Added {
+			rows[i] = fmt.Sprintf("| %s | %s | %s |", mdCode(fmt.Sprintf("%s:%d", e.File, e.Line)), mdCode(e.Variable), e.Category)
+		}
+		b.section(fmt.Sprintf("New escapes (%d)", len(d.Added)), "| Location | Variable | Category |\n|---|---|---|", rows, true)
+	}
+	if d := meta.Delta; d != nil && len(d.Removed) > 0 {
+		rows := make([]string, len(d.Removed))
+		for i, rm := range d.Removed {
+			rows[i] = fmt.Sprintf("| %s | %s | %s |", mdCode(rm.Entry.File), mdCode(rm.Entry.Variable), rm.Entry.Category)
+		}
+		b.section(fmt.Sprintf("Resolved escapes (%d)", len(d.Removed)), "| File | Variable | Category |\n|---|---|---|", rows, false)
+	}
+	if len(results.ByCategory) > 0 {
+		var rows []string
+		for _, cat := range sortCategories(results.ByCategory) {
+			rows = append(rows, fmt.Sprintf("| %s | %d |", cat, results.ByCategory[cat]))
+		}
+		b.section("Escapes by category", "| Category | Escapes |\n|---|---:|", rows, meta.Delta == nil)
+	}
+	if len(escapes) > 0 {
+		rows := make([]string, len(escapes))
+		for i, e := range escapes {
+			rows[i] = fmt.Sprintf("| %s | %s | %s | %s |", mdCode(fmt.Sprintf("%s:%d", e.Info.File, e.Info.Line)), mdCode(e.Info.Variable), e.Category, mdCell(e.Suggestion.Short))
+		}
+		b.section(fmt.Sprintf("All escapes (%d)", len(escapes)), "| Location | Variable | Category | Suggestion |\n|---|---|---|---|", rows, false)
+	}
+	b.WriteString(tail.String())
+
+	_, err := io.WriteString(r.w, b.String())
+	return err
+}
+
+// commentBuilder builds a comment body of at most limit bytes, and so
+// at most limit characters
+type commentBuilder struct {
+	strings.Builder
+	limit int
+}
+
+// section adds a collapsible table with as many rows as fit, noting how
+// many were left out. A section without room for its first row is left
+// out entirely.
+func (b *commentBuilder) section(summary, header string, rows []string, open bool) {
+	attr := ""
+	if open {
+		attr = " open"
+	}
+	start := fmt.Sprintf("<details%s><summary>%s</summary>\n\n%s\n", attr, summary, header)
+	const end = "\n</details>\n\n"
+	const moreReserve = len("\n_… and 1000000 more_\n")
+
+	room := b.limit - b.Len() - len(start) - len(end) - moreReserve
+	var body strings.Builder
+	shown := 0
+	for _, row := range rows {
+		if body.Len()+len(row)+1 > room {
+			break
+		}
+		body.WriteString(row + "\n")
+		shown++
+	}
+	if shown == 0 {
+		return
+	}
+	b.WriteString(start)
+	b.WriteString(body.String())
+	if shown < len(rows) {
+		fmt.Fprintf(b, "\n_… and %d more_\n", len(rows)-shown)
+	}
+	b.WriteString(end)
+}
+
+// cutLines returns the longest run of whole lines starting s that is at
+// most n bytes long
+func cutLines(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+	return s[:strings.LastIndexByte(s[:n], '\n')+1]
+}
+
+// mdCell makes s safe inside a Markdown table cell
+func mdCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// mdCode formats s as inline code inside a table cell
+func mdCode(s string) string {
+	return "`" + mdCell(strings.ReplaceAll(s, "`", "'")) + "`"
+}
@@ -0,0 +1,105 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/baseline"
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func TestPRCommentReporter(t *testing.T) {
+	results := sampleResults()
+	results.Escapes[1].Info.Variable = "a | b"
+	delta := &baseline.Delta{
+		Added:   []baseline.Entry{{File: "main.go", Line: 10, Variable: "x", Category: categorizer.CategoryReturnPointer}},
+		Removed: []baseline.Removal{{Index: 0, Entry: baseline.Entry{File: "old.go", Variable: "y", Category: categorizer.CategorySpill}}},
+	}
+	var buf bytes.Buffer
+	if err := NewPRCommentReporter(&buf).Report(context.Background(), results, Metadata{Version: "1.2.3", Delta: delta}); err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	out := buf.String()
+
+	if !strings.HasPrefix(out, PRCommentMarker+"\n") {
+		t.Errorf("comment does not start with the marker:\n%s", out)
+	}
+	for _, want := range []string{
+		"## ⚠️ heapcheck: 1 new heap escape(s)",
+		"| New since the baseline | 1 |",
+		"| Resolved since the baseline | 1 |",
+		"<details open><summary>New escapes (1)</summary>",
+		"| `main.go:10` | `x` | return-pointer |",
+		"<summary>Resolved escapes (1)</summary>",
+		"| `handler.go:25` | `a \\| b` | interface-boxing | Use concrete types |",
+		"<sub>Generated by heapcheck 1.2.3</sub>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("comment missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestPRCommentReporterWithoutBaseline(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewPRCommentReporter(&buf).Report(context.Background(), sampleResults(), Metadata{}); err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"## 📊 heapcheck: 2 heap escape(s)", "<details open><summary>Escapes by category</summary>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("comment missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "baseline") {
+		t.Errorf("comment mentions a baseline without one:\n%s", out)
+	}
+}
+
+func TestPRCommentReporterLimit(t *testing.T) {
+	results := sampleResults()
+	results.Escapes = nil
+	for i := 0; i < 5000; i++ {
+		results.Escapes = append(results.Escapes, categorizer.CategorizedEscape{
+			Info:     parser.EscapeInfo{File: fmt.Sprintf("pkg/file%d.go", i), Line: i, Variable: "buf"},
+			Category: categorizer.CategorySliceGrow,
+		})
+	}
+	results.Summary.HeapAllocated = len(results.Escapes)
+
+	var buf bytes.Buffer
+	r := NewPRCommentReporter(&buf)
+	r.maxLen = 4000
+	if err := r.Report(context.Background(), results, Metadata{Version: "1.2.3"}); err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	out := buf.String()
+	if len(out) > r.maxLen {
+		t.Errorf("comment is %d bytes, over the limit of %d", len(out), r.maxLen)
+	}
+	for _, want := range []string{"<summary>All escapes (5000)</summary>", "more_", "</details>", "<sub>Generated by heapcheck 1.2.3</sub>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("comment missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestPRCommentReporterTinyLimit(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewPRCommentReporter(&buf)
+	r.maxLen = 150
+	if err := r.Report(context.Background(), sampleResults(), Metadata{Version: "1.2.3"}); err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	out := buf.String()
+	if len(out) > r.maxLen {
+		t.Errorf("comment is %d bytes, over the limit of %d:\n%s", len(out), r.maxLen, out)
+	}
+	if !strings.HasPrefix(out, PRCommentMarker+"\n") || !strings.HasSuffix(out, "<sub>Generated by heapcheck 1.2.3</sub>\n") {
+		t.Errorf("comment lost its marker or footer:\n%s", out)
+	}
+}
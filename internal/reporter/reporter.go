@@ -2,47 +2,367 @@
 package reporter
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
+	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/harshakonda/heapcheck/internal/baseline"
 	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/history"
+	"github.com/harshakonda/heapcheck/internal/parser"
 )
 
 // Reporter interface for different output formats
 type Reporter interface {
-	Report(results *categorizer.Results) error
+	Report(ctx context.Context, results *categorizer.Results, meta Metadata) error
 }
 
+// Metadata describes the run that produced the results. The zero value
+// is valid, e.g. when rendering saved results.
+type Metadata struct {
+	Version  string                  // heapcheck version
+	Started  time.Time               // when the analysis started
+	Duration time.Duration           // how long the analysis took
+	Gate     *categorizer.GateResult // category gate outcome, nil without gate rules
+	Trend    *history.Trend          // escape trend check, nil without --fail-on-trend
+	Stats    *Stats                  // analysis statistics, nil unless requested
+	Delta    *baseline.Delta         // changes from the baseline, nil without --baseline
+	Config   *AppliedConfig          // configuration of the analysis, nil when unknown
+	Sample   *Sample                 // the packages analyzed, nil unless sampled
+}
+
+// Sample describes a sampled run, which analyzed a stable subset of the
+// packages its patterns select
+type Sample struct {
+	Percent  float64 `json:"percent"`       // of the packages, as requested
+	Packages int     `json:"packages"`      // analyzed
+	Total    int     `json:"totalPackages"` // selected by the patterns
+}
+
+// String describes the sample, e.g. "12 of 120 packages (10%)"
+func (s *Sample) String() string {
+	return fmt.Sprintf("%d of %d packages (%g%%)", s.Packages, s.Total, s.Percent)
+}
+
+// Estimate extrapolates a count over the sampled packages to all of them
+func (s *Sample) Estimate(n int) int {
+	if s.Packages == 0 {
+		return n
+	}
+	return int(math.Round(float64(n) * float64(s.Total) / float64(s.Packages)))
+}
+
+// jsonSample is Sample in JSON, with estimates for all packages
+type jsonSample struct {
+	*Sample
+	Estimated jsonEstimate `json:"estimated"`
+}
+
+// jsonEstimate are the totals extrapolated from a sample, estimates
+// rather than counts
+type jsonEstimate struct {
+	TotalVariables int `json:"totalVariables"`
+	HeapAllocated  int `json:"heapAllocated"`
+}
+
+func newJSONSample(s *Sample, summary categorizer.Summary) *jsonSample {
+	if s == nil {
+		return nil
+	}
+	return &jsonSample{Sample: s, Estimated: jsonEstimate{
+		TotalVariables: s.Estimate(summary.TotalVariables),
+		HeapAllocated:  s.Estimate(summary.HeapAllocated),
+	}}
+}
+
+// AppliedConfig is the configuration an analysis ran with, resolved from
+// its flags, config files and defaults, so that readers of a report can
+// tell which filters, thresholds and exclusions shaped its numbers
+type AppliedConfig struct {
+	// Flags maps every flag of the analysis to its value: the one given,
+	// or else its default
+	Flags map[string]string `json:"flags"`
+
+	// Given are the names of the flags given on the command line
+	Given []string `json:"given,omitempty"`
+
+	// Patterns are the packages analyzed
+	Patterns []string `json:"patterns,omitempty"`
+
+	// Files are the config files read, the current directory's first
+	Files []ConfigFile `json:"files,omitempty"`
+
+	// Ruleset is the file of category rules, from --ruleset or the
+	// config; empty for the built-in rules
+	Ruleset string `json:"ruleset,omitempty"`
+}
+
+// ConfigFile is a config file with its settings as parsed
+type ConfigFile struct {
+	Path     string         `json:"path"`
+	Settings map[string]any `json:"settings,omitempty"`
+}
+
+// Stats describe where an analysis spent its time and how much compiler
+// output it parsed
+type Stats struct {
+	Compile    time.Duration // running the compiler, zero when reading saved output
+	Parse      time.Duration
+	Categorize time.Duration
+	Output     parser.Stats
+}
+
+// now returns the time the run started, for describing escape ages
+func (m Metadata) now() time.Time {
+	if m.Started.IsZero() {
+		return time.Now()
+	}
+	return m.Started
+}
+
+// Option configures a reporter
+type Option func(*options)
+
+type options struct {
+	verbose     bool
+	color       bool
+	width       int
+	limit       int
+	links       bool
+	docLinks    map[categorizer.Category]string
+	suggestions map[categorizer.Category]categorizer.Suggestion
+	summaryOnly bool
+	catalog     *Catalog
+
+	// acceptURL, acceptToken and acceptTargets are set when escapes can
+	// be accepted from the HTML report
+	acceptURL     string
+	acceptToken   string
+	acceptTargets []string
+}
+
+func newOptions(opts []Option) options {
+	o := options{links: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.width <= 0 {
+		o.width = DefaultWidth
+	}
+	return o
+}
+
+// pathWidth is the width of path columns in lines width columns wide:
+// half the line, 40 columns at the default width
+func pathWidth(width int) int {
+	return min(max(width/2, 16), 80)
+}
+
+// WithVerbose shows every escape and interface parameter in text output
+func WithVerbose(verbose bool) Option {
+	return func(o *options) { o.verbose = verbose }
+}
+
+// WithColor enables ANSI colors in text output
+func WithColor(color bool) Option {
+	return func(o *options) { o.color = color }
+}
+
+// DefaultWidth is the text output width when none is set
+const DefaultWidth = 80
+
+// WithWidth lays out text output for a terminal n columns wide: path
+// columns are sized to it and long paths shortened in the middle, and
+// suggestions and flows are wrapped. 0 uses DefaultWidth.
+func WithWidth(n int) Option {
+	return func(o *options) { o.width = n }
+}
+
+// WithLimit caps how many escapes are listed in detail (0 for the
+// reporter's default)
+func WithLimit(n int) Option {
+	return func(o *options) { o.limit = n }
+}
+
+// WithLinks enables documentation links in suggestions (default on)
+func WithLinks(links bool) Option {
+	return func(o *options) { o.links = links }
+}
+
+// WithDocLinks overrides the documentation links of categories, e.g.
+// with pages in an internal wiki
+func WithDocLinks(links map[categorizer.Category]string) Option {
+	return func(o *options) { o.docLinks = links }
+}
+
+// WithSuggestions replaces the suggestion text of categories, e.g. with
+// advice specific to a codebase. Empty fields keep the built-in text.
+func WithSuggestions(suggestions map[categorizer.Category]categorizer.Suggestion) Option {
+	return func(o *options) { o.suggestions = suggestions }
+}
+
+// WithSummaryOnly leaves the escapes and other per-escape lists out of
+// JSON output, keeping the aggregates and the gate outcome
+func WithSummaryOnly(summaryOnly bool) Option {
+	return func(o *options) { o.summaryOnly = summaryOnly }
+}
+
+// WithCatalog translates the category names and suggestions of the HTML
+// report with c (nil for English)
+func WithCatalog(c *Catalog) Option {
+	return func(o *options) { o.catalog = c }
+}
+
+// WithAccept adds a form to each escape of the HTML report that posts to
+// url to accept the escape with a reason, recorded in one of targets
+// ("comment" or "baseline"). The form posts token as "token", for the
+// server to tell its own report's forms from forged ones, the escape's
+// index in the results as "escape", its variable as "variable", and the
+// "reason", "owner", "expires" and "target" entered.
+func WithAccept(url, token string, targets ...string) Option {
+	return func(o *options) { o.acceptURL, o.acceptToken, o.acceptTargets = url, token, targets }
+}
+
+// suggestion returns s with the overridden text of cat, if any
+func (o options) suggestion(cat categorizer.Category, s categorizer.Suggestion) categorizer.Suggestion {
+	if override, ok := o.suggestions[cat]; ok {
+		if override.Short != "" {
+			s.Short = override.Short
+		}
+		if override.Details != "" {
+			s.Details = override.Details
+		}
+	}
+	return s
+}
+
+// docLink returns the documentation link to show for a suggestion, or ""
+// when links are off
+func (o options) docLink(cat categorizer.Category, s categorizer.Suggestion) string {
+	if !o.links {
+		return ""
+	}
+	if link, ok := o.docLinks[cat]; ok {
+		return link
+	}
+	return s.DocLink
+}
+
+// overrides reports whether suggestions or documentation links are
+// overridden
+func (o options) overrides() bool {
+	return len(o.suggestions) > 0 || len(o.docLinks) > 0
+}
+
+// withOverrides returns escapes with the overridden suggestions and
+// documentation links applied, copying the slice only when there are
+// overrides
+func withOverrides(escapes []categorizer.CategorizedEscape, o options) []categorizer.CategorizedEscape {
+	if !o.overrides() {
+		return escapes
+	}
+	out := make([]categorizer.CategorizedEscape, len(escapes))
+	for i, e := range escapes {
+		out[i] = withOverride(e, o)
+	}
+	return out
+}
+
+// withOverride returns e with its overridden suggestion and documentation
+// link applied
+func withOverride(e categorizer.CategorizedEscape, o options) categorizer.CategorizedEscape {
+	e.Suggestion = o.suggestion(e.Category, e.Suggestion)
+	if link, ok := o.docLinks[e.Category]; ok {
+		e.Suggestion.DocLink = link
+	}
+	return e
+}
+
+// New returns the reporter for format (text, json, html, sarif, matrix,
+// matrix-csv)
+func New(w io.Writer, format string, opts ...Option) (Reporter, error) {
+	switch format {
+	case "text", "":
+		return NewTextReporter(w, opts...), nil
+	case "json":
+		return NewJSONReporter(w, opts...), nil
+	case "html":
+		return NewHTMLReporter(w, opts...), nil
+	case "sarif":
+		return NewSARIFReporter(w, opts...), nil
+	case "matrix":
+		return NewMatrixReporter(w, false), nil
+	case "matrix-csv":
+		return NewMatrixReporter(w, true), nil
+	case "delta":
+		return NewDeltaReporter(w), nil
+	case "pr-comment":
+		return NewPRCommentReporter(w, opts...), nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q (supported: text, json, html, sarif, matrix, matrix-csv, delta, pr-comment)", format)
+	}
+}
+
+// ANSI escape codes used by the text reporter
+const (
+	ansiBold   = "\033[1m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiReset  = "\033[0m"
+)
+
 // =============================================================================
 // Text Reporter
 // =============================================================================
 
 // TextReporter outputs human-readable text
 type TextReporter struct {
-	w       io.Writer
-	verbose bool
+	w    io.Writer
+	opts options
 }
 
 // NewTextReporter creates a new text reporter
-func NewTextReporter(w io.Writer, verbose bool) *TextReporter {
-	return &TextReporter{w: w, verbose: verbose}
+func NewTextReporter(w io.Writer, opts ...Option) *TextReporter {
+	return &TextReporter{w: w, opts: newOptions(opts)}
+}
+
+// paint wraps s in an ANSI color when colors are enabled
+func (r *TextReporter) paint(code, s string) string {
+	if !r.opts.color {
+		return s
+	}
+	return code + s + ansiReset
 }
 
 // Report generates a human-readable report
-func (r *TextReporter) Report(results *categorizer.Results) error {
+func (r *TextReporter) Report(ctx context.Context, results *categorizer.Results, meta Metadata) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	w := r.w
+	pw := pathWidth(r.opts.width)
 
 	// Header
 	fmt.Fprintln(w, "")
-	fmt.Fprintln(w, "📊 heapcheck - Escape Analysis Report")
-	fmt.Fprintln(w, strings.Repeat("─", 50))
+	fmt.Fprintln(w, r.paint(ansiBold, "📊 heapcheck - Escape Analysis Report"))
+	fmt.Fprintln(w, strings.Repeat("─", min(r.opts.width, 50)))
 	fmt.Fprintln(w, "")
+	if s := meta.Sample; s != nil {
+		fmt.Fprintln(w, r.paint(ansiYellow, fmt.Sprintf("⚠️  Sampled: %s; the counts cover the sample only", s)))
+		fmt.Fprintln(w, "")
+	}
 
 	// Summary
-	fmt.Fprintln(w, "Summary:")
+	fmt.Fprintln(w, r.paint(ansiBold, "Summary:"))
 	total := results.Summary.TotalVariables
 	stack := results.Summary.StackAllocated
 	heap := results.Summary.HeapAllocated
@@ -57,69 +377,567 @@ func (r *TextReporter) Report(results *categorizer.Results) error {
 
 	fmt.Fprintf(w, "  Total variables analyzed: %d\n", total)
 	fmt.Fprintf(w, "  Stack allocated:          %d (%.1f%%)\n", stack, stackPct)
-	fmt.Fprintf(w, "  Heap allocated:           %d (%.1f%%) ⚠️\n", heap, heapPct)
-	if inlined > 0 {
+	fmt.Fprintf(w, "  Heap allocated:           %s ⚠️\n", r.paint(ansiYellow, fmt.Sprintf("%d (%.1f%%)", heap, heapPct)))
+	if s := meta.Sample; s != nil {
+		fmt.Fprintf(w, "  Estimated, all packages:  ~%d heap allocated of ~%d variables (extrapolated)\n", s.Estimate(heap), s.Estimate(total))
+	}
+	if inlined > 0 && results.Summary.Inlining == nil {
 		fmt.Fprintf(w, "  Inlined calls:            %d\n", inlined)
 	}
+	if results.Summary.Suppressed > 0 {
+		fmt.Fprintf(w, "  Suppressed:               %d\n", results.Summary.Suppressed)
+	}
+	if results.Summary.Allowed > 0 {
+		fmt.Fprintf(w, "  Allowed:                  %d\n", results.Summary.Allowed)
+	}
+	if covered, uncovered := results.Summary.CoveredEscapes, results.Summary.UncoveredEscapes; covered+uncovered > 0 {
+		fmt.Fprintf(w, "  Covered by tests:         %d\n", covered)
+		fmt.Fprintf(w, "  Not covered by tests:     %d\n", uncovered)
+	}
+	if benched, unbenched := results.Summary.BenchmarkedEscapes, results.Summary.UnbenchmarkedEscapes; benched+unbenched > 0 {
+		fmt.Fprintf(w, "  Benchmarked:              %d\n", benched)
+		fmt.Fprintf(w, "  Not benchmarked:          %d\n", unbenched)
+	}
+	if n := categorizer.CountInit(results.Escapes); n > 0 {
+		fmt.Fprintf(w, "  At initialization:        %d (one-time)\n", n)
+	}
+	if findings := categorizer.CountFindings(results.Escapes); findings < len(results.Escapes) {
+		fmt.Fprintf(w, "  Findings:                 %d (escapes grouped by construct)\n", findings)
+	}
+	if n, ok := newThisWeek(results.Escapes, meta.now()); ok {
+		fmt.Fprintf(w, "  New this week:            %d\n", n)
+	}
+	if meta.Duration > 0 {
+		fmt.Fprintf(w, "  Analysis time:            %s\n", meta.Duration.Round(time.Millisecond))
+	}
 	fmt.Fprintln(w, "")
 
+	printNotAnalyzed(w, results.Summary.Skipped, r.opts.width)
+	r.printStats(meta.Stats)
+	r.printInlining(results.Summary.Inlining)
+	printExpiringSuppressions(w, results.Suppressions)
+	r.printGate(meta.Gate)
+
 	if heap == 0 {
-		fmt.Fprintln(w, "✅ No heap escapes found! Your code is well-optimized.")
+		fmt.Fprintln(w, r.paint(ansiGreen, "✅ No heap escapes found! Your code is well-optimized."))
+		r.printBanner(results, meta)
 		return nil
 	}
 
-	// Escapes by category
-	fmt.Fprintln(w, "Escape Causes:")
+	// Escapes by group, then by category, by estimated bytes when known
+	fmt.Fprintln(w, r.paint(ansiBold, "Escape Groups:"))
+	byGroup := categorizer.CountByGroup(results.ByCategory)
+	for _, g := range categorizer.Groups() {
+		if count := byGroup[g]; count > 0 {
+			fmt.Fprintf(w, "  %-23s %3d (%5.1f%%)\n", g, count, float64(count)/float64(heap)*100)
+		}
+	}
+	fmt.Fprintln(w, "")
+
+	fmt.Fprintln(w, r.paint(ansiBold, "Estimated Effort:"))
+	byEffort := categorizer.CountByEffort(results.Escapes)
+	for _, e := range categorizer.Efforts() {
+		if count := byEffort[e]; count > 0 {
+			fmt.Fprintf(w, "  %-23s %3d (%5.1f%%)\n", e, count, float64(count)/float64(len(results.Escapes))*100)
+		}
+	}
+	fmt.Fprintln(w, "")
+
+	fmt.Fprintln(w, r.paint(ansiBold, "Severity:"))
+	bySeverity := categorizer.CountBySeverity(results.Escapes)
+	severities := categorizer.Severities()
+	for i := len(severities) - 1; i >= 0; i-- {
+		if count := bySeverity[severities[i]]; count > 0 {
+			fmt.Fprintf(w, "  %-23s %3d (%5.1f%%)\n", severities[i], count, float64(count)/float64(len(results.Escapes))*100)
+		}
+	}
+	fmt.Fprintln(w, "")
+
+	fmt.Fprintln(w, r.paint(ansiBold, "Escape Causes:"))
+	bytes := impactByCategory(results.Escapes)
 	categories := sortCategories(results.ByCategory)
+	if len(bytes) > 0 {
+		categories = sortCategoriesByImpact(results.ByCategory, bytes)
+	}
 	for i, cat := range categories {
 		count := results.ByCategory[cat]
 		pct := float64(count) / float64(heap) * 100
-		fmt.Fprintf(w, "  %d. %-20s %3d (%5.1f%%)\n", i+1, cat, count, pct)
+		line := fmt.Sprintf("  %d. %-20s %3d (%5.1f%%)", i+1, cat, count, pct)
+		if n, ok := bytes[cat]; ok {
+			line += fmt.Sprintf("  ~%s per call", categorizer.FormatBytes(n))
+		}
+		fmt.Fprintln(w, line)
 	}
 	fmt.Fprintln(w, "")
 
-	// Hotspots (files with most escapes)
+	// Hotspots, rolled up by directory and package
 	if len(results.Summary.ByFile) > 0 {
-		fmt.Fprintln(w, "Hotspots (files with most escapes):")
-		files := sortFilesByCount(results.Summary.ByFile)
-		for i, f := range files {
-			if i >= 5 {
-				break
-			}
-			fmt.Fprintf(w, "  %-40s %3d escapes\n", truncatePath(f.name, 40), f.count)
+		fmt.Fprintln(w, r.paint(ansiBold, "Hotspots (escapes by directory, package and file):"))
+		printHotspots(w, hotspotTree(results.Summary.ByFile, results.Escapes), pw, r.opts.verbose)
+		fmt.Fprintln(w, "")
+	}
+
+	if len(results.Summary.ByOwner) > 0 {
+		fmt.Fprintln(w, r.paint(ansiBold, "By Owner:"))
+		for _, o := range sortFilesByCount(results.Summary.ByOwner) {
+			fmt.Fprintf(w, "  %-*s %3d escapes\n", pw, o.name, o.count)
 		}
 		fmt.Fprintln(w, "")
 	}
 
-	// Detailed escapes (if verbose or few escapes)
-	if r.verbose || len(results.Escapes) <= 10 {
-		fmt.Fprintln(w, "Details:")
-		fmt.Fprintln(w, strings.Repeat("─", 50))
+	if len(results.Summary.TooLargeBytes) > 0 {
+		fmt.Fprintln(w, r.paint(ansiBold, "Oversized Objects (too-large, by package):"))
+		for _, p := range sortBySize(results.Summary.TooLargeBytes) {
+			fmt.Fprintf(w, "  %-*s %8s\n", pw, truncatePath(p, pw), categorizer.FormatBytes(results.Summary.TooLargeBytes[p]))
+		}
+		fmt.Fprintln(w, "")
+	}
 
-		for _, e := range results.Escapes {
-			printEscapeDetail(w, e)
+	printUncoveredFiles(w, results.Escapes, pw)
+	printInterfaceParams(w, results.InterfaceParams, r.opts.verbose)
+	printGoroutines(w, results.Goroutines, r.opts.verbose)
+	printHandlers(w, results.Handlers, r.opts.verbose)
+	printLargeCopies(w, results.LargeCopies, r.opts.verbose)
+
+	// Detailed findings: all of them when verbose or few, up to the limit
+	// when one is set. Escapes of one construct are listed under it.
+	clusters := categorizer.Clusters(results.Escapes)
+	shown := clusters
+	if !r.opts.verbose && r.opts.limit > 0 && len(shown) > r.opts.limit {
+		shown = shown[:r.opts.limit]
+	}
+	if r.opts.verbose || r.opts.limit > 0 || len(clusters) <= 10 {
+		fmt.Fprintln(w, r.paint(ansiBold, "Details:"))
+		fmt.Fprintln(w, strings.Repeat("─", min(r.opts.width, 50)))
+
+		// Verbose output is a section per category; otherwise findings are
+		// listed in order
+		if r.opts.verbose {
+			if err := r.printCategorySections(ctx, results.Escapes, shown, meta.now()); err != nil {
+				return err
+			}
+		} else {
+			for _, c := range shown {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				r.printEscapeDetail(c.Root, meta.now())
+				r.printClusterMembers(c)
+			}
+		}
+		if n := len(clusters) - len(shown); n > 0 {
+			fmt.Fprintf(w, "\n... and %d more (use -v)\n", n)
 		}
 	} else {
 		fmt.Fprintf(w, "Run with -v for detailed breakdown of all %d escapes.\n", len(results.Escapes))
 	}
 
+	r.printBanner(results, meta)
 	return nil
 }
 
-func printEscapeDetail(w io.Writer, e categorizer.CategorizedEscape) {
+// printInlining prints the inlining decisions, with failures by reason
+func (r *TextReporter) printInlining(stats *categorizer.InliningStats) {
+	if stats == nil {
+		return
+	}
+	w := r.w
+	fmt.Fprintln(w, r.paint(ansiBold, "Inlining:"))
+	fmt.Fprintf(w, "  Inlinable functions:      %d\n", stats.Inlinable)
+	fmt.Fprintf(w, "  Calls inlined:            %d\n", stats.CallsInlined)
+	fmt.Fprintf(w, "  Not inlinable:            %d\n", stats.Failed)
+	for _, reason := range sortReasons(stats.FailReasons) {
+		fmt.Fprintf(w, "    %-22s %d\n", reason, stats.FailReasons[reason])
+	}
+	fmt.Fprintln(w, "")
+}
+
+// printStats prints the phase timings and parse counts
+func (r *TextReporter) printStats(stats *Stats) {
+	if stats == nil {
+		return
+	}
+	w := r.w
+	fmt.Fprintln(w, r.paint(ansiBold, "Statistics:"))
+	if stats.Compile > 0 {
+		fmt.Fprintf(w, "  Compile:                  %s\n", stats.Compile.Round(time.Millisecond))
+	}
+	fmt.Fprintf(w, "  Parse:                    %s\n", stats.Parse.Round(time.Millisecond))
+	fmt.Fprintf(w, "  Categorize:               %s\n", stats.Categorize.Round(time.Millisecond))
+	fmt.Fprintf(w, "  Packages:                 %d\n", stats.Output.Packages)
+	fmt.Fprintf(w, "  Lines parsed:             %d\n", stats.Output.Lines)
+	unrecognized := fmt.Sprintf("%d", stats.Output.Unrecognized)
+	if stats.Output.Lines > 0 {
+		unrecognized += fmt.Sprintf(" (%.1f%%)", float64(stats.Output.Unrecognized)/float64(stats.Output.Lines)*100)
+	}
+	fmt.Fprintf(w, "  Unrecognized lines:       %s\n", unrecognized)
+	fmt.Fprintln(w, "")
+}
+
+// printGate lists the status of each gated category and the overall result
+func (r *TextReporter) printGate(gate *categorizer.GateResult) {
+	if gate == nil {
+		return
+	}
+	w := r.w
+
+	nameWidth := 20
+	for _, c := range gate.Categories {
+		nameWidth = max(nameWidth, len(c.Name()))
+	}
+	fmt.Fprintln(w, r.paint(ansiBold, "Category Gates:"))
+	for _, c := range gate.Categories {
+		fmt.Fprintf(w, "  %s %-4s %-*s %3d  (%s)\n", gateMarker(c.Status), c.Status, nameWidth, c.Name(), c.Count, c.Rule)
+	}
+	fmt.Fprintf(w, "Gate: %s %s\n", gateMarker(gate.Status), r.paint(gateColor(gate.Status), strings.ToUpper(gate.Status)))
+	fmt.Fprintln(w, "")
+}
+
+func gateMarker(status string) string {
+	switch status {
+	case categorizer.GateFail:
+		return "❌"
+	case categorizer.GateWarn:
+		return "⚠️"
+	default:
+		return "✅"
+	}
+}
+
+func gateColor(status string) string {
+	switch status {
+	case categorizer.GateFail:
+		return ansiRed
+	case categorizer.GateWarn:
+		return ansiYellow
+	default:
+		return ansiGreen
+	}
+}
+
+// printExpiringSuppressions lists suppressions that expired or expire soon
+func printExpiringSuppressions(w io.Writer, statuses []categorizer.SuppressionStatus) {
+	expiring := expiringSuppressions(statuses)
+	if len(expiring) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "Suppressions nearing expiry:")
+	for _, s := range expiring {
+		marker := "⏳"
+		if s.Status == categorizer.SuppressionExpired {
+			marker = "❌"
+		}
+		owner := s.Owner
+		if owner == "" {
+			owner = "(no owner)"
+		}
+		fmt.Fprintf(w, "  %s %-8s %s  %s:%d %s [%s] %s\n",
+			marker, s.Status, s.Expires, s.File, s.Line, s.Variable, s.Category, owner)
+	}
+	fmt.Fprintln(w, "")
+}
+
+// printInterfaceParams lists the interface parameters that most call
+// sites box values into
+func printInterfaceParams(w io.Writer, params []categorizer.InterfaceParam, verbose bool) {
+	if len(params) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "Interface parameters causing boxing:")
+	for i, p := range params {
+		if i >= 10 && !verbose {
+			fmt.Fprintf(w, "  ... and %d more (use -v)\n", len(params)-i)
+			break
+		}
+		fmt.Fprintf(w, "  %3d call sites  %s(%s)\n", p.CallSites, p.Func, p.Param)
+		if verbose && p.Position != "" {
+			fmt.Fprintf(w, "                  %s\n", p.Position)
+		}
+	}
+	fmt.Fprintln(w, "")
+}
+
+// printGoroutines lists the functions with the most goroutine and
+// channel escapes, with their categories
+func printGoroutines(w io.Writer, groups []categorizer.GoroutineGroup, verbose bool) {
+	if len(groups) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "Goroutine and channel escapes by function (consider a worker pool):")
+	for i, g := range groups {
+		if i >= 10 && !verbose {
+			fmt.Fprintf(w, "  ... and %d more (use -v)\n", len(groups)-i)
+			break
+		}
+		name := g.Func
+		if name == "" {
+			name = g.Position
+		}
+		var cats []string
+		for _, cat := range sortCategories(g.ByCategory) {
+			cats = append(cats, fmt.Sprintf("%s %d", cat, g.ByCategory[cat]))
+		}
+		fmt.Fprintf(w, "  %3d escapes  %s (%s)\n", g.Escapes, name, strings.Join(cats, ", "))
+		if verbose && g.Func != "" {
+			fmt.Fprintf(w, "               %s\n", g.Position)
+		}
+	}
+	fmt.Fprintln(w, "")
+}
+
+// printHandlers lists the HTTP handlers with the most escapes, the
+// allocations each request to them pays for
+func printHandlers(w io.Writer, groups []categorizer.HandlerGroup, verbose bool) {
+	if len(groups) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "Escapes per HTTP handler (allocated on every request):")
+	for i, g := range groups {
+		if i >= 10 && !verbose {
+			fmt.Fprintf(w, "  ... and %d more (use -v)\n", len(groups)-i)
+			break
+		}
+		var cats []string
+		for _, cat := range sortCategories(g.ByCategory) {
+			cats = append(cats, fmt.Sprintf("%s %d", cat, g.ByCategory[cat]))
+		}
+		fmt.Fprintf(w, "  %3d escapes  %s (%s)\n", g.Escapes, g, strings.Join(cats, ", "))
+		if verbose {
+			fmt.Fprintf(w, "               %s\n", g.Position)
+		}
+	}
+	fmt.Fprintln(w, "")
+}
+
+// printLargeCopies lists the large values copied by value around the
+// escapes, most bytes copied first
+func printLargeCopies(w io.Writer, copies []categorizer.LargeCopy, verbose bool) {
+	if len(copies) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "Large value copies (%s, advisory):\n", categorizer.CategoryLargeValueCopy)
+	for i, c := range copies {
+		if i >= 10 && !verbose {
+			fmt.Fprintf(w, "  ... and %d more (use -v)\n", len(copies)-i)
+			break
+		}
+		fmt.Fprintf(w, "  %3d call sites  %8s  %s %s (%s)\n", c.CallSites, categorizer.FormatBytes(c.Size), c.Func, c.Value, c.Type)
+		if verbose && c.Position != "" {
+			fmt.Fprintf(w, "                  %s\n", c.Position)
+		}
+	}
+	fmt.Fprintln(w, "")
+}
+
+// printUncoveredFiles lists the files with the most escapes on lines no
+// test executes: optimizing them is risky without tests to catch regressions
+// printNotAnalyzed lists the packages the analysis left out, so that a
+// run missing some packages is not mistaken for a complete one
+func printNotAnalyzed(w io.Writer, skipped []parser.SkippedPackage, width int) {
+	if len(skipped) == 0 {
+		return
+	}
+	pw := pathWidth(width)
+	fmt.Fprintf(w, "⚠️  Not Analyzed (%d package(s)):\n", len(skipped))
+	for _, p := range skipped {
+		prefix := fmt.Sprintf("  %-*s ", pw, truncatePath(p.Package, pw))
+		printWrapped(w, width, prefix, skippedStatus(p)+": "+p.Reason)
+	}
+	fmt.Fprintln(w, "")
+}
+
+// skippedStatus says how much of a skipped package was analyzed
+func skippedStatus(p parser.SkippedPackage) string {
+	if p.PureGo {
+		return "Go files only"
+	}
+	return "skipped"
+}
+
+func printUncoveredFiles(w io.Writer, escapes []categorizer.CategorizedEscape, pw int) {
+	byFile := make(map[string]int)
+	for _, e := range escapes {
+		if e.Coverage == categorizer.CoverageUncovered {
+			byFile[e.Info.File]++
+		}
+	}
+	if len(byFile) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "Uncovered escape-heavy files (risky to refactor):")
+	for i, f := range sortFilesByCount(byFile) {
+		if i >= 5 {
+			break
+		}
+		fmt.Fprintf(w, "  %-*s %3d uncovered escapes\n", pw, truncatePath(f.name, pw), f.count)
+	}
+	fmt.Fprintln(w, "")
+}
+
+func (r *TextReporter) printEscapeDetail(e categorizer.CategorizedEscape, now time.Time) {
+	w := r.w
 	fmt.Fprintln(w, "")
 	fmt.Fprintf(w, "📍 %s:%d:%d\n", e.Info.File, e.Info.Line, e.Info.Column)
 	fmt.Fprintf(w, "   Variable: %s\n", e.Info.Variable)
 	fmt.Fprintf(w, "   Type:     %s\n", e.Info.EscapeType)
 	fmt.Fprintf(w, "   Category: %s\n", e.Category)
-	fmt.Fprintf(w, "   💡 %s\n", e.Suggestion.Short)
+	if stack := e.Info.InlineStack(); stack != "" {
+		fmt.Fprintf(w, "   Inlined:  %s\n", stack)
+	}
+	if e.Size > 0 {
+		fmt.Fprintf(w, "   Size:     %s\n", categorizer.FormatBytes(e.Size))
+	} else if size := heapObject(e.Info); size != "" {
+		fmt.Fprintf(w, "   Size:     %s\n", size)
+	}
+	if len(e.Owners) > 0 {
+		fmt.Fprintf(w, "   Owners:   %s\n", strings.Join(e.Owners, " "))
+	}
+	if e.Coverage != "" {
+		fmt.Fprintf(w, "   Coverage: %s\n", e.Coverage)
+	}
+	if e.Benchmarked != "" {
+		fmt.Fprintf(w, "   Benchmarked: %s\n", e.Benchmarked)
+	}
+	if e.Init {
+		fmt.Fprintln(w, "   Init:     one-time, at package initialization")
+	}
+	if e.Call != nil {
+		fmt.Fprintf(w, "   Call:     %s\n", e.Call)
+	}
+	if age := categorizer.AgeLabel(e.FirstSeen, now); age != "" {
+		fmt.Fprintf(w, "   Age:      %s (since %s)\n", age, e.FirstSeen)
+	}
+	suggestion := r.opts.suggestion(e.Category, e.Suggestion).Short
+	if e.Impact != nil {
+		suggestion += fmt.Sprintf(" (%s)", e.Impact)
+	}
+	printWrapped(w, r.opts.width, "   💡 ", suggestion)
+	if link := r.opts.docLink(e.Category, e.Suggestion); link != "" {
+		fmt.Fprintf(w, "   📖 %s\n", link)
+	}
+	if r.opts.verbose && e.Fix != "" {
+		fmt.Fprintln(w, "   Fix:")
+		for _, line := range strings.Split(e.Fix, "\n") {
+			fmt.Fprintf(w, "     %s\n", line)
+		}
+	}
 
 	if len(e.Info.FlowInfo) > 0 {
 		fmt.Fprintln(w, "   Flow:")
 		for _, flow := range e.Info.FlowInfo {
-			fmt.Fprintf(w, "     %s\n", flow)
+			printWrapped(w, r.opts.width, "     ", flow)
+		}
+	}
+}
+
+// printCategorySections prints the findings by the category of their
+// construct, most escapes first. Each section opens with the category's
+// escapes, how many files and functions they are spread over, and the
+// files and functions with the most of them.
+func (r *TextReporter) printCategorySections(ctx context.Context, escapes []categorizer.CategorizedEscape, clusters []categorizer.Cluster, now time.Time) error {
+	w := r.w
+	counts := make(map[categorizer.Category]int)
+	files := make(map[categorizer.Category]map[string]int)
+	funcs := make(map[categorizer.Category]map[string]int)
+	for _, e := range escapes {
+		counts[e.Category]++
+		if files[e.Category] == nil {
+			files[e.Category] = make(map[string]int)
+			funcs[e.Category] = make(map[string]int)
+		}
+		files[e.Category][e.Info.File]++
+		if e.Func != "" {
+			funcs[e.Category][e.Info.PackageOrDir()+"\x00"+e.Func]++
+		}
+	}
+	byCategory := make(map[categorizer.Category][]categorizer.Cluster)
+	for _, c := range clusters {
+		byCategory[c.Root.Category] = append(byCategory[c.Root.Category], c)
+	}
+
+	for _, cat := range sortCategories(counts) {
+		if len(byCategory[cat]) == 0 {
+			continue
+		}
+		fmt.Fprintln(w, "")
+		header := fmt.Sprintf("▸ %s: %d escape(s) in %d file(s)", cat, counts[cat], len(files[cat]))
+		if n := len(funcs[cat]); n > 0 {
+			header += fmt.Sprintf(", %d function(s)", n)
+		}
+		fmt.Fprintln(w, r.paint(ansiBold, header))
+		printWrapped(w, r.opts.width, "  Top files:     ", topCounts(files[cat], 3, func(file string) string { return file }))
+		if len(funcs[cat]) > 0 {
+			printWrapped(w, r.opts.width, "  Top functions: ", topCounts(funcs[cat], 3, func(key string) string {
+				pkg, fn, _ := strings.Cut(key, "\x00")
+				if base := filepath.Base(pkg); base != "." && base != string(filepath.Separator) {
+					return base + "." + fn
+				}
+				return fn
+			}))
+		}
+
+		for _, c := range byCategory[cat] {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			r.printEscapeDetail(c.Root, now)
+			r.printClusterMembers(c)
+		}
+	}
+	return nil
+}
+
+// topCounts formats the n names with the highest counts, ties by name,
+// as "a (5), b (3)"
+func topCounts(counts map[string]int, n int, label func(string) string) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, 0, n)
+	for i, name := range names {
+		if i >= n {
+			break
 		}
+		parts = append(parts, fmt.Sprintf("%s (%d)", label(name), counts[name]))
 	}
+	return strings.Join(parts, ", ")
+}
+
+// printClusterMembers lists the other escapes of a finding's construct,
+// once per value
+func (r *TextReporter) printClusterMembers(c categorizer.Cluster) {
+	seen := map[string]bool{clusterMemberKey(c.Root): true}
+	var lines []string
+	for _, e := range c.Members {
+		if key := clusterMemberKey(e); !seen[key] {
+			seen[key] = true
+			lines = append(lines, fmt.Sprintf("%s at %s:%d:%d (%s)", e.Info.Variable, e.Info.File, e.Info.Line, e.Info.Column, e.Category))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(r.w, "   Includes: %d more escape(s) of %s\n", len(lines), c.Key)
+	for _, line := range lines {
+		printWrapped(r.w, r.opts.width, "     ", line)
+	}
+}
+
+func clusterMemberKey(e categorizer.CategorizedEscape) string {
+	return fmt.Sprintf("%s:%d:%d %s", e.Info.File, e.Info.Line, e.Info.Column, e.Info.Variable)
 }
 
 // =============================================================================
@@ -128,340 +946,166 @@ func printEscapeDetail(w io.Writer, e categorizer.CategorizedEscape) {
 
 // JSONReporter outputs JSON format
 type JSONReporter struct {
-	w io.Writer
+	w    io.Writer
+	opts options
 }
 
 // NewJSONReporter creates a new JSON reporter
-func NewJSONReporter(w io.Writer) *JSONReporter {
-	return &JSONReporter{w: w}
+func NewJSONReporter(w io.Writer, opts ...Option) *JSONReporter {
+	return &JSONReporter{w: w, opts: newOptions(opts)}
 }
 
-// Report generates JSON output
-func (r *JSONReporter) Report(results *categorizer.Results) error {
-	encoder := json.NewEncoder(r.w)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(results)
+// jsonReport is the JSON output: the results, with the gate outcome and
+// run metadata alongside
+type jsonReport struct {
+	*categorizer.Results
+	ByGroup    map[categorizer.Group]int    `json:"byGroup"`
+	ByEffort   map[categorizer.Effort]int   `json:"byEffort"`
+	BySeverity map[categorizer.Severity]int `json:"bySeverity"`
+	Findings   int                          `json:"findings"` // escapes, counting each cluster once
+	Gate       *categorizer.GateResult      `json:"gate,omitempty"`
+	Metadata   jsonMetadata                 `json:"metadata"`
 }
 
-// =============================================================================
-// HTML Reporter
-// =============================================================================
-
-// HTMLReporter outputs an HTML report
-type HTMLReporter struct {
-	w io.Writer
+// jsonSummaryReport is the JSON output with WithSummaryOnly
+type jsonSummaryReport struct {
+	Summary    categorizer.Summary          `json:"summary"`
+	ByCategory map[categorizer.Category]int `json:"byCategory"`
+	ByGroup    map[categorizer.Group]int    `json:"byGroup"`
+	ByEffort   map[categorizer.Effort]int   `json:"byEffort"`
+	BySeverity map[categorizer.Severity]int `json:"bySeverity"`
+	Findings   int                          `json:"findings"`
+	Gate       *categorizer.GateResult      `json:"gate,omitempty"`
+	Metadata   jsonMetadata                 `json:"metadata"`
 }
 
-// NewHTMLReporter creates a new HTML reporter
-func NewHTMLReporter(w io.Writer) *HTMLReporter {
-	return &HTMLReporter{w: w}
+type jsonMetadata struct {
+	Version    string         `json:"version,omitempty"`
+	Started    string         `json:"started,omitempty"`
+	DurationMS float64        `json:"durationMs,omitempty"`
+	Stats      *jsonStats     `json:"stats,omitempty"`
+	Config     *AppliedConfig `json:"config,omitempty"`
+	Sample     *jsonSample    `json:"sample,omitempty"`
 }
 
-// Report generates an HTML report
-func (r *HTMLReporter) Report(results *categorizer.Results) error {
-	html := generateHTML(results)
-	_, err := r.w.Write([]byte(html))
-	return err
+type jsonStats struct {
+	CompileMS    float64 `json:"compileMs"`
+	ParseMS      float64 `json:"parseMs"`
+	CategorizeMS float64 `json:"categorizeMs"`
+	Packages     int     `json:"packages"`
+	Lines        int     `json:"lines"`
+	Unrecognized int     `json:"unrecognized"`
 }
 
-func generateHTML(results *categorizer.Results) string {
-	var sb strings.Builder
+// milliseconds returns d in fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
 
-	// Calculate percentages for charts
-	stackPct := float64(0)
-	heapPct := float64(0)
-	if results.Summary.TotalVariables > 0 {
-		stackPct = float64(results.Summary.StackAllocated) / float64(results.Summary.TotalVariables) * 100
-		heapPct = float64(results.Summary.HeapAllocated) / float64(results.Summary.TotalVariables) * 100
-	}
-
-	sb.WriteString(`<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>heapcheck Report</title>
-    <script src="https://cdn.jsdelivr.net/npm/chart.js"></script>
-    <style>
-        * { box-sizing: border-box; }
-        body { 
-            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
-            margin: 0; padding: 20px; background: #f5f5f5;
-        }
-        .container { max-width: 1400px; margin: 0 auto; }
-        h1 { color: #333; margin-bottom: 30px; }
-        h2 { color: #444; margin-top: 0; margin-bottom: 20px; border-bottom: 2px solid #e5e7eb; padding-bottom: 10px; }
-        .card { 
-            background: white; border-radius: 12px; padding: 24px; 
-            margin-bottom: 24px; box-shadow: 0 4px 6px rgba(0,0,0,0.07);
-        }
-        .grid-2 { display: grid; grid-template-columns: 1fr 1fr; gap: 24px; }
-        .grid-3 { display: grid; grid-template-columns: repeat(auto-fit, minmax(200px, 1fr)); gap: 20px; }
-        @media (max-width: 768px) { .grid-2 { grid-template-columns: 1fr; } }
-        
-        .stat-card {
-            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
-            border-radius: 12px; padding: 24px; color: white; text-align: center;
-        }
-        .stat-card.success { background: linear-gradient(135deg, #11998e 0%, #38ef7d 100%); }
-        .stat-card.danger { background: linear-gradient(135deg, #eb3349 0%, #f45c43 100%); }
-        .stat-card.info { background: linear-gradient(135deg, #2196F3 0%, #21CBF3 100%); }
-        .stat-value { font-size: 3em; font-weight: bold; margin-bottom: 5px; }
-        .stat-label { font-size: 1em; opacity: 0.9; }
-        .stat-pct { font-size: 0.9em; opacity: 0.8; margin-top: 5px; }
-        
-        .chart-container { position: relative; height: 300px; }
-        .chart-container-sm { position: relative; height: 250px; }
-        
-        table { width: 100%; border-collapse: collapse; }
-        th, td { padding: 12px 16px; text-align: left; border-bottom: 1px solid #e5e7eb; }
-        th { background: #f9fafb; font-weight: 600; color: #374151; }
-        tr:hover { background: #f9fafb; }
-        
-        .category-badge {
-            display: inline-block; padding: 4px 12px; border-radius: 20px;
-            font-size: 0.85em; font-weight: 500;
-        }
-        .badge-red { background: #fee2e2; color: #dc2626; }
-        .badge-orange { background: #ffedd5; color: #ea580c; }
-        .badge-yellow { background: #fef3c7; color: #ca8a04; }
-        .badge-green { background: #dcfce7; color: #16a34a; }
-        .badge-blue { background: #dbeafe; color: #2563eb; }
-        .badge-purple { background: #f3e8ff; color: #9333ea; }
-        .badge-gray { background: #f3f4f6; color: #6b7280; }
-        
-        .suggestion { color: #059669; font-style: italic; font-size: 0.9em; }
-        .file-link { color: #2563eb; text-decoration: none; font-family: monospace; }
-        .file-link:hover { text-decoration: underline; }
-        .var-name { font-family: monospace; background: #f3f4f6; padding: 2px 6px; border-radius: 4px; }
-        
-        .hotspot-bar {
-            background: #e5e7eb; border-radius: 4px; height: 24px; position: relative; overflow: hidden;
-        }
-        .hotspot-fill {
-            background: linear-gradient(90deg, #ef4444 0%, #f97316 100%);
-            height: 100%; border-radius: 4px; transition: width 0.3s;
-        }
-        .hotspot-label {
-            position: absolute; right: 8px; top: 50%; transform: translateY(-50%);
-            font-size: 0.8em; font-weight: 600; color: #374151;
-        }
-        
-        .legend-item { display: flex; align-items: center; margin-bottom: 8px; }
-        .legend-color { width: 16px; height: 16px; border-radius: 4px; margin-right: 10px; }
-        .legend-text { font-size: 0.9em; color: #4b5563; }
-        
-        .no-escapes {
-            text-align: center; padding: 60px 20px; color: #059669;
-        }
-        .no-escapes-icon { font-size: 4em; margin-bottom: 20px; }
-        .no-escapes-text { font-size: 1.5em; font-weight: 600; }
-        
-        .footer { text-align: center; color: #9ca3af; font-size: 0.85em; margin-top: 40px; padding: 20px; }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <h1>📊 heapcheck Report</h1>
-`)
-
-	// Summary cards
-	sb.WriteString(`<div class="grid-3" style="margin-bottom: 24px;">`)
-	sb.WriteString(fmt.Sprintf(`<div class="stat-card info"><div class="stat-value">%d</div><div class="stat-label">Total Variables</div></div>`, results.Summary.TotalVariables))
-	sb.WriteString(fmt.Sprintf(`<div class="stat-card success"><div class="stat-value">%d</div><div class="stat-label">Stack Allocated</div><div class="stat-pct">%.1f%% ✓</div></div>`, results.Summary.StackAllocated, stackPct))
-	sb.WriteString(fmt.Sprintf(`<div class="stat-card danger"><div class="stat-value">%d</div><div class="stat-label">Heap Allocated</div><div class="stat-pct">%.1f%% ⚠</div></div>`, results.Summary.HeapAllocated, heapPct))
-	sb.WriteString(`</div>`)
-
-	// Check if there are any escapes
-	if results.Summary.HeapAllocated == 0 {
-		sb.WriteString(`<div class="card no-escapes">
-			<div class="no-escapes-icon">🎉</div>
-			<div class="no-escapes-text">No heap escapes found!</div>
-			<p style="color: #6b7280; margin-top: 10px;">Your code is well-optimized for stack allocation.</p>
-		</div>`)
-	} else {
-		// Charts row
-		sb.WriteString(`<div class="grid-2">`)
-
-		// Allocation pie chart
-		sb.WriteString(`<div class="card">
-			<h2>Allocation Distribution</h2>
-			<div class="chart-container">
-				<canvas id="allocationChart"></canvas>
-			</div>
-		</div>`)
-
-		// Categories bar chart
-		sb.WriteString(`<div class="card">
-			<h2>Escape Categories</h2>
-			<div class="chart-container">
-				<canvas id="categoriesChart"></canvas>
-			</div>
-		</div>`)
-
-		sb.WriteString(`</div>`) // end grid-2
-
-		// Hotspots card
-		if len(results.Summary.ByFile) > 0 {
-			sb.WriteString(`<div class="card"><h2>🔥 Hotspots</h2>`)
-			
-			// Find max for scaling
-			maxEscapes := 0
-			for _, count := range results.Summary.ByFile {
-				if count > maxEscapes {
-					maxEscapes = count
-				}
-			}
-			
-			// Sort files by escape count
-			type fileCount struct {
-				file  string
-				count int
-			}
-			var files []fileCount
-			for f, c := range results.Summary.ByFile {
-				files = append(files, fileCount{f, c})
-			}
-			sort.Slice(files, func(i, j int) bool {
-				return files[i].count > files[j].count
-			})
-			
-			sb.WriteString(`<table><tr><th>File</th><th style="width: 50%;">Escapes</th><th style="width: 80px;">Count</th></tr>`)
-			for i, fc := range files {
-				if i >= 10 { // Show top 10 only
-					break
-				}
-				pct := float64(fc.count) / float64(maxEscapes) * 100
-				sb.WriteString(fmt.Sprintf(`<tr>
-					<td><span class="file-link">%s</span></td>
-					<td><div class="hotspot-bar"><div class="hotspot-fill" style="width: %.1f%%;"></div></div></td>
-					<td><strong>%d</strong></td>
-				</tr>`, fc.file, pct, fc.count))
-			}
-			sb.WriteString(`</table></div>`)
-		}
-
-		// Detailed escapes table
-		sb.WriteString(`<div class="card"><h2>📋 All Escapes</h2>`)
-		sb.WriteString(`<table><tr><th>Location</th><th>Variable</th><th>Category</th><th>Suggestion</th></tr>`)
-		for _, e := range results.Escapes {
-			badgeClass := getCategoryBadgeClass(e.Category)
-			sb.WriteString(fmt.Sprintf(`<tr>
-				<td><span class="file-link">%s:%d</span></td>
-				<td><span class="var-name">%s</span></td>
-				<td><span class="category-badge %s">%s</span></td>
-				<td class="suggestion">%s</td>
-			</tr>`, e.Info.File, e.Info.Line, e.Info.Variable, badgeClass, e.Category, e.Suggestion.Short))
-		}
-		sb.WriteString(`</table></div>`)
-
-		// Chart.js scripts
-		sb.WriteString(`<script>
-		// Allocation Pie Chart
-		new Chart(document.getElementById('allocationChart'), {
-			type: 'doughnut',
-			data: {
-				labels: ['Stack Allocated', 'Heap Allocated'],
-				datasets: [{
-					data: [`)
-		sb.WriteString(fmt.Sprintf("%d, %d", results.Summary.StackAllocated, results.Summary.HeapAllocated))
-		sb.WriteString(`],
-					backgroundColor: ['#22c55e', '#ef4444'],
-					borderWidth: 0,
-					hoverOffset: 4
-				}]
-			},
-			options: {
-				responsive: true,
-				maintainAspectRatio: false,
-				plugins: {
-					legend: { position: 'bottom' },
-					tooltip: {
-						callbacks: {
-							label: function(context) {
-								let total = context.dataset.data.reduce((a, b) => a + b, 0);
-								let pct = ((context.raw / total) * 100).toFixed(1);
-								return context.label + ': ' + context.raw + ' (' + pct + '%)';
-							}
-						}
-					}
-				}
-			}
-		});
-
-		// Categories Bar Chart
-		new Chart(document.getElementById('categoriesChart'), {
-			type: 'bar',
-			data: {
-				labels: [`)
-		
-		// Add category labels
-		categories := sortCategories(results.ByCategory)
-		for i, cat := range categories {
-			if i > 0 {
-				sb.WriteString(",")
-			}
-			sb.WriteString(fmt.Sprintf("'%s'", cat))
-		}
-		sb.WriteString(`],
-				datasets: [{
-					label: 'Count',
-					data: [`)
-		
-		// Add category counts
-		for i, cat := range categories {
-			if i > 0 {
-				sb.WriteString(",")
-			}
-			sb.WriteString(fmt.Sprintf("%d", results.ByCategory[cat]))
-		}
-		sb.WriteString(`],
-					backgroundColor: [
-						'#ef4444', '#f97316', '#f59e0b', '#eab308', '#84cc16',
-						'#22c55e', '#14b8a6', '#06b6d4', '#0ea5e9', '#3b82f6',
-						'#6366f1', '#8b5cf6', '#a855f7', '#d946ef', '#ec4899'
-					],
-					borderRadius: 6
-				}]
-			},
-			options: {
-				responsive: true,
-				maintainAspectRatio: false,
-				indexAxis: 'y',
-				plugins: {
-					legend: { display: false }
-				},
-				scales: {
-					x: { beginAtZero: true, grid: { display: false } },
-					y: { grid: { display: false } }
-				}
-			}
-		});
-		</script>`)
+// Report generates JSON output
+func (r *JSONReporter) Report(ctx context.Context, results *categorizer.Results, meta Metadata) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	report := jsonReport{
+		Results:    results,
+		ByGroup:    categorizer.CountByGroup(results.ByCategory),
+		ByEffort:   categorizer.CountByEffort(results.Escapes),
+		BySeverity: categorizer.CountBySeverity(results.Escapes),
+		Findings:   categorizer.CountFindings(results.Escapes),
+		Gate:       meta.Gate,
+		Metadata: jsonMetadata{
+			Version:    meta.Version,
+			DurationMS: milliseconds(meta.Duration),
+			Config:     meta.Config,
+			Sample:     newJSONSample(meta.Sample, results.Summary),
+		},
+	}
+	if !meta.Started.IsZero() {
+		report.Metadata.Started = meta.Started.UTC().Format(time.RFC3339)
+	}
+	if st := meta.Stats; st != nil {
+		report.Metadata.Stats = &jsonStats{
+			CompileMS:    milliseconds(st.Compile),
+			ParseMS:      milliseconds(st.Parse),
+			CategorizeMS: milliseconds(st.Categorize),
+			Packages:     st.Output.Packages,
+			Lines:        st.Output.Lines,
+			Unrecognized: st.Output.Unrecognized,
+		}
 	}
 
-	sb.WriteString(`<div class="footer">Generated by <strong>heapcheck</strong> • <a href="https://github.com/harshakonda/heapcheck" style="color: #6b7280;">github.com/harshakonda/heapcheck</a></div>`)
-	sb.WriteString(`</div></body></html>`)
-
-	return sb.String()
+	encoder := json.NewEncoder(r.w)
+	encoder.SetIndent("", "  ")
+	if r.opts.summaryOnly {
+		return encoder.Encode(jsonSummaryReport{
+			Summary:    results.Summary,
+			ByCategory: results.ByCategory,
+			ByGroup:    report.ByGroup,
+			ByEffort:   report.ByEffort,
+			BySeverity: report.BySeverity,
+			Findings:   report.Findings,
+			Gate:       report.Gate,
+			Metadata:   report.Metadata,
+		})
+	}
+	return r.stream(report)
 }
 
-// getCategoryBadgeClass returns the CSS class for a category badge
-func getCategoryBadgeClass(cat categorizer.Category) string {
-	switch cat {
-	case categorizer.CategoryReturnPointer, categorizer.CategoryInterfaceBoxing:
-		return "badge-red"
-	case categorizer.CategoryClosureCapture, categorizer.CategoryGoroutineEscape:
-		return "badge-orange"
-	case categorizer.CategorySliceGrow, categorizer.CategoryChannelSend:
-		return "badge-yellow"
-	case categorizer.CategoryFmtCall, categorizer.CategoryReflection:
-		return "badge-blue"
-	case categorizer.CategoryUnknownSize, categorizer.CategoryTooLarge:
-		return "badge-purple"
+// jsonEscapesField is the escapes array in the indented JSON report, set
+// to null, where stream writes the escapes. Only a field of the top-level
+// object is indented by two spaces, and JSON strings cannot contain a
+// newline, so it appears once.
+const jsonEscapesField = "\n  \"escapes\": null"
+
+// stream writes report as encoding it whole would, but encodes the
+// escapes one at a time, so that the output of a large analysis is never
+// held in memory at once; overrides are applied to each escape as it is
+// written rather than to a copy of them all
+func (r *JSONReporter) stream(report jsonReport) error {
+	escapes := report.Results.Escapes
+	rest := *report.Results
+	rest.Escapes = nil
+	report.Results = &rest
+	head, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	i := bytes.Index(head, []byte(jsonEscapesField))
+	if i < 0 {
+		return fmt.Errorf("escapes missing from the JSON report")
+	}
+	tail := head[i+len(jsonEscapesField):]
+	head = head[:i]
+
+	w := bufio.NewWriter(r.w)
+	w.Write(head)
+	switch {
+	case escapes == nil:
+		w.WriteString(jsonEscapesField)
+	case len(escapes) == 0:
+		w.WriteString("\n  \"escapes\": []")
 	default:
-		return "badge-gray"
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("    ", "  ")
+		w.WriteString("\n  \"escapes\": [")
+		for i, e := range escapes {
+			buf.Reset()
+			if err := encoder.Encode(withOverride(e, r.opts)); err != nil {
+				return err
+			}
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString("\n    ")
+			w.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+		}
+		w.WriteString("\n  ]")
 	}
+	w.Write(tail)
+	w.WriteByte('\n')
+	return w.Flush()
 }
 
 // =============================================================================
@@ -470,17 +1114,21 @@ func getCategoryBadgeClass(cat categorizer.Category) string {
 
 // SARIFReporter outputs SARIF format for GitHub integration
 type SARIFReporter struct {
-	w io.Writer
+	w    io.Writer
+	opts options
 }
 
 // NewSARIFReporter creates a new SARIF reporter
-func NewSARIFReporter(w io.Writer) *SARIFReporter {
-	return &SARIFReporter{w: w}
+func NewSARIFReporter(w io.Writer, opts ...Option) *SARIFReporter {
+	return &SARIFReporter{w: w, opts: newOptions(opts)}
 }
 
 // Report generates SARIF output
-func (r *SARIFReporter) Report(results *categorizer.Results) error {
-	sarif := generateSARIF(results)
+func (r *SARIFReporter) Report(ctx context.Context, results *categorizer.Results, meta Metadata) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	sarif := generateSARIF(results, meta, r.opts)
 	encoder := json.NewEncoder(r.w)
 	encoder.SetIndent("", "  ")
 	return encoder.Encode(sarif)
@@ -493,8 +1141,22 @@ type sarifReport struct {
 }
 
 type sarifRun struct {
-	Tool    sarifTool     `json:"tool"`
-	Results []sarifResult `json:"results"`
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations,omitempty"`
+	Results     []sarifResult     `json:"results"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful bool                `json:"executionSuccessful"`
+	StartTimeUTC        string              `json:"startTimeUtc,omitempty"`
+	EndTimeUTC          string              `json:"endTimeUtc,omitempty"`
+	Notifications       []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+// sarifNotification reports a package the analysis left out
+type sarifNotification struct {
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
 }
 
 type sarifTool struct {
@@ -508,17 +1170,33 @@ type sarifDriver struct {
 }
 
 type sarifRule struct {
-	ID               string       `json:"id"`
-	ShortDescription sarifMessage `json:"shortDescription"`
-	Help             sarifMessage `json:"help"`
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	FullDescription      sarifMessage       `json:"fullDescription"`
+	Help                 sarifMessage       `json:"help"`
+	HelpURI              string             `json:"helpUri,omitempty"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+	Properties           sarifProperties    `json:"properties"`
 }
 
 type sarifMessage struct {
-	Text string `json:"text"`
+	Text     string `json:"text"`
+	Markdown string `json:"markdown,omitempty"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifProperties struct {
+	Tags    []string `json:"tags"`
+	Escapes int      `json:"escapes"` // escapes of this category in the run
 }
 
 type sarifResult struct {
 	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
 	Level     string          `json:"level"`
 	Message   sarifMessage    `json:"message"`
 	Locations []sarifLocation `json:"locations"`
@@ -539,53 +1217,140 @@ type sarifArtifact struct {
 
 type sarifRegion struct {
 	StartLine   int `json:"startLine"`
-	StartColumn int `json:"startColumn"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifLevels are the SARIF levels of the severities
+var sarifLevels = map[categorizer.Severity]string{
+	categorizer.SeverityLow:    "note",
+	categorizer.SeverityMedium: "warning",
+	categorizer.SeverityHigh:   "error",
+}
+
+// sarifRuleFor describes a category as a SARIF rule, with markdown help
+// that includes the category's example
+func sarifRuleFor(cat categorizer.Category, s categorizer.Suggestion, count int, link string) sarifRule {
+	level := sarifLevels[categorizer.SeverityOf(cat)]
+
+	var md strings.Builder
+	fmt.Fprintf(&md, "**%s**\n\n%s\n", s.Short, s.Details)
+	if ex, ok := categorizer.GetExample(cat); ok {
+		fmt.Fprintf(&md, "\nEscapes:\n\n```go\n%s\n```\n\nInstead:\n\n```go\n%s\n```\n", ex.Escaping, ex.Fixed)
+	}
+
+	rule := sarifRule{
+		ID:                   string(cat),
+		Name:                 sarifRuleName(cat),
+		ShortDescription:     sarifMessage{Text: s.Short},
+		FullDescription:      sarifMessage{Text: s.Details},
+		Help:                 sarifMessage{Text: s.Details, Markdown: md.String()},
+		DefaultConfiguration: sarifConfiguration{Level: level},
+		Properties: sarifProperties{
+			Tags:    []string{"performance", "escape-analysis", string(categorizer.GroupOf(cat))},
+			Escapes: count,
+		},
+	}
+	if link != "" {
+		rule.HelpURI = link
+		fmt.Fprintf(&md, "\n[Documentation](%s)\n", link)
+		rule.Help.Markdown = md.String()
+	}
+	return rule
 }
 
-func generateSARIF(results *categorizer.Results) sarifReport {
-	// Build rules from categories
-	rules := make([]sarifRule, 0)
-	ruleSet := make(map[categorizer.Category]bool)
+// sarifRuleName turns a category into the PascalCase name SARIF viewers
+// expect, e.g. "return-pointer" becomes "ReturnPointer"
+func sarifRuleName(cat categorizer.Category) string {
+	var sb strings.Builder
+	for _, part := range strings.Split(string(cat), "-") {
+		if part != "" {
+			sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return sb.String()
+}
+
+func generateSARIF(results *categorizer.Results, meta Metadata, opts options) sarifReport {
+	// One rule per built-in category, present or not, so Code Scanning
+	// sees the same rule set on every run; custom categories follow
+	rules := make([]sarifRule, 0, len(categorizer.Categories()))
+	ruleIndex := make(map[categorizer.Category]int)
+	for _, cat := range categorizer.Categories() {
+		ruleIndex[cat] = len(rules)
+		s := opts.suggestion(cat, categorizer.GetSuggestion(cat))
+		rules = append(rules, sarifRuleFor(cat, s, results.ByCategory[cat], opts.docLink(cat, s)))
+	}
 	for _, e := range results.Escapes {
-		if !ruleSet[e.Category] {
-			ruleSet[e.Category] = true
-			rules = append(rules, sarifRule{
-				ID:               string(e.Category),
-				ShortDescription: sarifMessage{Text: e.Suggestion.Short},
-				Help:             sarifMessage{Text: e.Suggestion.Details},
-			})
+		if _, ok := ruleIndex[e.Category]; !ok {
+			ruleIndex[e.Category] = len(rules)
+			s := opts.suggestion(e.Category, e.Suggestion)
+			rules = append(rules, sarifRuleFor(e.Category, s, results.ByCategory[e.Category], opts.docLink(e.Category, s)))
 		}
 	}
 
 	// Build results
 	sarifResults := make([]sarifResult, 0, len(results.Escapes))
 	for _, e := range results.Escapes {
-		sarifResults = append(sarifResults, sarifResult{
-			RuleID:  string(e.Category),
-			Level:   "warning",
-			Message: sarifMessage{Text: fmt.Sprintf("%s escapes to heap: %s", e.Info.Variable, e.Suggestion.Short)},
-			Locations: []sarifLocation{{
+		// Compiler-generated code such as <autogenerated> has no source
+		// file to point at
+		locations := make([]sarifLocation, 0, 1)
+		if !parser.IsSynthetic(e.Info.File) {
+			locations = append(locations, sarifLocation{
 				PhysicalLocation: sarifPhysicalLocation{
 					ArtifactLocation: sarifArtifact{URI: e.Info.File},
 					Region:           sarifRegion{StartLine: e.Info.Line, StartColumn: e.Info.Column},
 				},
-			}},
+			})
+		}
+		// Results override their rule's level when in an exported API or
+		// internal code
+		index := ruleIndex[e.Category]
+		level := rules[index].DefaultConfiguration.Level
+		if l, ok := sarifLevels[e.Severity]; ok {
+			level = l
+		}
+		sarifResults = append(sarifResults, sarifResult{
+			RuleID:    string(e.Category),
+			RuleIndex: index,
+			Level:     level,
+			Message:   sarifMessage{Text: fmt.Sprintf("%s escapes to heap: %s", e.Info.Variable, opts.suggestion(e.Category, e.Suggestion).Short)},
+			Locations: locations,
 		})
 	}
 
+	version := meta.Version
+	if version == "" {
+		version = "1.0.0"
+	}
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:    "heapcheck",
+				Version: version,
+				Rules:   rules,
+			},
+		},
+		Results: sarifResults,
+	}
+	if !meta.Started.IsZero() || len(results.Summary.Skipped) > 0 {
+		inv := sarifInvocation{ExecutionSuccessful: true}
+		if !meta.Started.IsZero() {
+			inv.StartTimeUTC = meta.Started.UTC().Format(time.RFC3339)
+			inv.EndTimeUTC = meta.Started.Add(meta.Duration).UTC().Format(time.RFC3339)
+		}
+		for _, p := range results.Summary.Skipped {
+			inv.Notifications = append(inv.Notifications, sarifNotification{
+				Level:   "warning",
+				Message: sarifMessage{Text: fmt.Sprintf("Not analyzed: %s (%s): %s", p.Package, skippedStatus(p), p.Reason)},
+			})
+		}
+		run.Invocations = []sarifInvocation{inv}
+	}
+
 	return sarifReport{
 		Version: "2.1.0",
 		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
-		Runs: []sarifRun{{
-			Tool: sarifTool{
-				Driver: sarifDriver{
-					Name:    "heapcheck",
-					Version: "1.0.0",
-					Rules:   rules,
-				},
-			},
-			Results: sarifResults,
-		}},
+		Runs:    []sarifRun{run},
 	}
 }
 
@@ -615,14 +1380,172 @@ func sortCategories(m map[categorizer.Category]int) []categorizer.Category {
 		result = append(result, cat)
 	}
 	sort.Slice(result, func(i, j int) bool {
-		return m[result[i]] > m[result[j]]
+		if m[result[i]] != m[result[j]] {
+			return m[result[i]] > m[result[j]]
+		}
+		return result[i] < result[j]
+	})
+	return result
+}
+
+// impactByCategory sums the estimated bytes per call of each category's
+// escapes, for categories with at least one estimate
+func impactByCategory(escapes []categorizer.CategorizedEscape) map[categorizer.Category]int64 {
+	result := make(map[categorizer.Category]int64)
+	for _, e := range escapes {
+		if e.Impact != nil {
+			result[e.Category] += e.Impact.PerCall
+		}
+	}
+	return result
+}
+
+// sortBySize returns the keys of m, largest size first
+func sortBySize(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// sortCategoriesByImpact returns the categories, most estimated bytes
+// first, then by count
+func sortCategoriesByImpact(m map[categorizer.Category]int, bytes map[categorizer.Category]int64) []categorizer.Category {
+	result := sortCategories(m)
+	sort.SliceStable(result, func(i, j int) bool {
+		return bytes[result[i]] > bytes[result[j]]
+	})
+	return result
+}
+
+// sortReasons returns the inlining failure reasons, most frequent first
+func sortReasons(m map[string]int) []string {
+	result := make([]string, 0, len(m))
+	for reason := range m {
+		result = append(result, reason)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if m[result[i]] != m[result[j]] {
+			return m[result[i]] > m[result[j]]
+		}
+		return result[i] < result[j]
+	})
+	return result
+}
+
+// expiringSuppressions returns expired and expiring suppressions,
+// soonest expiry first
+func expiringSuppressions(statuses []categorizer.SuppressionStatus) []categorizer.SuppressionStatus {
+	var result []categorizer.SuppressionStatus
+	for _, s := range statuses {
+		if s.Status == categorizer.SuppressionExpiring || s.Status == categorizer.SuppressionExpired {
+			result = append(result, s)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Expires < result[j].Expires
 	})
 	return result
 }
 
+// newThisWeek counts escapes first seen in the last seven days. ok is
+// false when escapes carry no ages.
+func newThisWeek(escapes []categorizer.CategorizedEscape, now time.Time) (n int, ok bool) {
+	for _, e := range escapes {
+		if e.FirstSeen == "" {
+			continue
+		}
+		ok = true
+		if categorizer.AgeLabel(e.FirstSeen, now) == "new this week" {
+			n++
+		}
+	}
+	return n, ok
+}
+
+// heapObject describes the size and alignment the compiler states for
+// an escape's heap object, e.g. "64B, align 8", or "" when it states none
+func heapObject(e parser.EscapeInfo) string {
+	if e.Size <= 0 {
+		return ""
+	}
+	s := categorizer.FormatBytes(e.Size)
+	if e.Align > 0 {
+		s += fmt.Sprintf(", align %d", e.Align)
+	}
+	return s
+}
+
+// truncatePath shortens path to maxLen characters by eliding its middle
 func truncatePath(path string, maxLen int) string {
-	if len(path) <= maxLen {
+	runes := []rune(path)
+	if len(runes) <= maxLen {
 		return path
 	}
-	return "..." + path[len(path)-maxLen+3:]
+	// Keep more of the end, which has the file name
+	tail := (maxLen - 3) * 2 / 3
+	head := maxLen - 3 - tail
+	return string(runes[:head]) + "..." + string(runes[len(runes)-tail:])
+}
+
+// displayWidth approximates the columns s takes in a terminal: emoji
+// take two
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		switch {
+		case r == 0xFE0F:
+			n++ // emoji presentation of the symbol before it, e.g. ⚠️
+		case r >= 0x1F000:
+			n += 2
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// wrap splits text at spaces into lines of at most width columns,
+// keeping the spacing inside lines. Words longer than a line are not
+// broken.
+func wrap(text string, width int) []string {
+	var lines []string
+	for displayWidth(text) > width {
+		// Break at the last space that fits, or else the first one
+		cut := -1
+		for i, r := range text {
+			if r != ' ' || i == 0 {
+				continue
+			}
+			if cut > 0 && displayWidth(text[:i]) > width {
+				break
+			}
+			cut = i
+		}
+		line := strings.TrimRight(text[:max(cut, 0)], " ")
+		if cut < 0 || line == "" {
+			break
+		}
+		lines = append(lines, line)
+		text = strings.TrimLeft(text[cut:], " ")
+	}
+	return append(lines, text)
+}
+
+// printWrapped writes prefix and text, wrapped to width columns, with
+// continuation lines indented to start below the text
+func printWrapped(w io.Writer, width int, prefix, text string) {
+	indent := displayWidth(prefix)
+	lines := wrap(text, max(width-indent, 20))
+	fmt.Fprintf(w, "%s%s\n", prefix, lines[0])
+	for _, line := range lines[1:] {
+		fmt.Fprintf(w, "%s%s\n", strings.Repeat(" ", indent), line)
+	}
 }
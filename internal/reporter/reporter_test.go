@@ -2,9 +2,11 @@ package reporter
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/harshakonda/heapcheck/internal/categorizer"
 	"github.com/harshakonda/heapcheck/internal/parser"
@@ -65,8 +67,8 @@ func TestTextReporter(t *testing.T) {
 	results := sampleResults()
 	var buf bytes.Buffer
 
-	reporter := NewTextReporter(&buf, false)
-	err := reporter.Report(results)
+	reporter := NewTextReporter(&buf)
+	err := reporter.Report(context.Background(), results, Metadata{})
 	if err != nil {
 		t.Fatalf("Text reporter failed: %v", err)
 	}
@@ -91,12 +93,79 @@ func TestTextReporter(t *testing.T) {
 	}
 }
 
+func TestTextReporterOwners(t *testing.T) {
+	results := sampleResults()
+	results.Escapes[0].Owners = []string{"@org/api", "@org/core"}
+	results.Summary.ByOwner = map[string]int{"@org/api": 1, "@org/core": 1, "(unowned)": 1}
+	var buf bytes.Buffer
+
+	if err := NewTextReporter(&buf, WithVerbose(true)).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("Text reporter failed: %v", err)
+	}
+	output := buf.String()
+	for _, check := range []string{"By Owner:", "(unowned)", "Owners:   @org/api @org/core"} {
+		if !strings.Contains(output, check) {
+			t.Errorf("Text output missing: %s", check)
+		}
+	}
+}
+
+func TestTextReporterTooLargeBytes(t *testing.T) {
+	results := sampleResults()
+	results.Escapes[0].Size = 1 << 20
+	results.Summary.TooLargeBytes = map[string]int64{"example.com/app": 1 << 20, "example.com/lib": 4096}
+	var buf bytes.Buffer
+
+	if err := NewTextReporter(&buf, WithVerbose(true)).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("Text reporter failed: %v", err)
+	}
+	output := buf.String()
+	for _, check := range []string{"Oversized Objects", "example.com/app", "Size:     1.0MB"} {
+		if !strings.Contains(output, check) {
+			t.Errorf("Text output missing: %s", check)
+		}
+	}
+	if app, lib := strings.Index(output, "example.com/app"), strings.Index(output, "example.com/lib"); app > lib {
+		t.Errorf("packages not sorted by size, largest first")
+	}
+}
+
+func TestTextReporterHeapObject(t *testing.T) {
+	results := sampleResults()
+	results.Escapes[0].Info.Size = 64
+	results.Escapes[0].Info.Align = 8
+	var buf bytes.Buffer
+
+	if err := NewTextReporter(&buf, WithVerbose(true)).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("Text reporter failed: %v", err)
+	}
+	if output := buf.String(); !strings.Contains(output, "Size:     64B, align 8") {
+		t.Errorf("Text output missing the heap object size:\n%s", output)
+	}
+}
+
+func TestTextReporterInlined(t *testing.T) {
+	results := sampleResults()
+	results.Escapes[0].Info.InlinedFrom = []parser.InlineFrame{
+		{Func: "wrap", File: "./t.go", Line: 12, Column: 6},
+		{Func: "strings.Clone"},
+	}
+	var buf bytes.Buffer
+
+	if err := NewTextReporter(&buf, WithVerbose(true)).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("Text reporter failed: %v", err)
+	}
+	if output := buf.String(); !strings.Contains(output, "Inlined:  wrap (./t.go:12) → strings.Clone") {
+		t.Errorf("Text output missing the inlined calls:\n%s", output)
+	}
+}
+
 func TestTextReporterVerbose(t *testing.T) {
 	results := sampleResults()
 	var buf bytes.Buffer
 
-	reporter := NewTextReporter(&buf, true)
-	err := reporter.Report(results)
+	reporter := NewTextReporter(&buf, WithVerbose(true))
+	err := reporter.Report(context.Background(), results, Metadata{})
 	if err != nil {
 		t.Fatalf("Text reporter (verbose) failed: %v", err)
 	}
@@ -124,7 +193,7 @@ func TestJSONReporter(t *testing.T) {
 	var buf bytes.Buffer
 
 	reporter := NewJSONReporter(&buf)
-	err := reporter.Report(results)
+	err := reporter.Report(context.Background(), results, Metadata{})
 	if err != nil {
 		t.Fatalf("JSON reporter failed: %v", err)
 	}
@@ -147,12 +216,187 @@ func TestJSONReporter(t *testing.T) {
 	}
 }
 
+func TestJSONReporterStreamsLikeEncode(t *testing.T) {
+	html := sampleResults()
+	html.Escapes[0].Info.Variable = "<-ch & map[string]any{}"
+	html.Suppressions = []categorizer.SuppressionStatus{{File: "main.go", Line: 3}}
+	empty := sampleResults()
+	empty.Escapes = []categorizer.CategorizedEscape{}
+	none := sampleResults()
+	none.Escapes = nil
+
+	for name, results := range map[string]*categorizer.Results{
+		"escapes": sampleResults(),
+		"html":    html,
+		"empty":   empty,
+		"nil":     none,
+	} {
+		t.Run(name, func(t *testing.T) {
+			report := jsonReport{
+				Results:  results,
+				ByGroup:  categorizer.CountByGroup(results.ByCategory),
+				Findings: len(results.Escapes),
+				Gate:     &categorizer.GateResult{Status: categorizer.GateWarn},
+				Metadata: jsonMetadata{Version: "1.2.3"},
+			}
+			var want, got bytes.Buffer
+			encoder := json.NewEncoder(&want)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				t.Fatal(err)
+			}
+			if err := NewJSONReporter(&got).stream(report); err != nil {
+				t.Fatalf("stream: %v", err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("streamed JSON differs from encoding the report whole:\n%s\nwant:\n%s", got.String(), want.String())
+			}
+			if len(results.Escapes) > 0 && report.Results.Escapes == nil {
+				t.Error("stream modified the results")
+			}
+		})
+	}
+}
+
+func TestJSONReporterSummaryOnly(t *testing.T) {
+	meta := Metadata{Gate: &categorizer.GateResult{Status: categorizer.GateWarn}}
+	var buf bytes.Buffer
+	if err := NewJSONReporter(&buf, WithSummaryOnly(true)).Report(context.Background(), sampleResults(), meta); err != nil {
+		t.Fatalf("JSON reporter failed: %v", err)
+	}
+
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	for _, field := range []string{"summary", "byCategory", "gate", "metadata"} {
+		if _, ok := result[field]; !ok {
+			t.Errorf("JSON missing %q field", field)
+		}
+	}
+	if _, ok := result["escapes"]; ok {
+		t.Error("summary-only JSON has an 'escapes' field")
+	}
+	var summary categorizer.Summary
+	if err := json.Unmarshal(result["summary"], &summary); err != nil || summary.HeapAllocated != 2 || summary.ByFile["main.go"] != 1 {
+		t.Errorf("summary = %+v, %v", summary, err)
+	}
+}
+
+func TestReportersGroups(t *testing.T) {
+	results := sampleResults()
+	results.ByCategory[categorizer.CategoryGoroutineEscape] = 1
+
+	var text bytes.Buffer
+	if err := NewTextReporter(&text).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("text reporter failed: %v", err)
+	}
+	if !strings.Contains(text.String(), "Escape Groups:") || !strings.Contains(text.String(), "api-design") {
+		t.Errorf("text output missing escape groups:\n%s", text.String())
+	}
+
+	var out bytes.Buffer
+	if err := NewJSONReporter(&out).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("JSON reporter failed: %v", err)
+	}
+	var report struct {
+		ByGroup map[categorizer.Group]int `json:"byGroup"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if report.ByGroup[categorizer.GroupAPIDesign] != 2 || report.ByGroup[categorizer.GroupConcurrency] != 1 {
+		t.Errorf("byGroup = %v, want api-design 2 and concurrency 1", report.ByGroup)
+	}
+}
+
+func TestReportersEffort(t *testing.T) {
+	results := sampleResults()
+	results.Escapes[0].Effort = categorizer.EffortStructural
+
+	var text bytes.Buffer
+	if err := NewTextReporter(&text).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("text reporter failed: %v", err)
+	}
+	for _, want := range []string{"Estimated Effort:", "structural", "moderate"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text output missing %q:\n%s", want, text.String())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := NewJSONReporter(&out, WithSummaryOnly(true)).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("JSON reporter failed: %v", err)
+	}
+	var report struct {
+		ByEffort map[categorizer.Effort]int `json:"byEffort"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if report.ByEffort[categorizer.EffortStructural] != 1 || report.ByEffort[categorizer.EffortModerate] != 1 {
+		t.Errorf("byEffort = %v, want structural 1 and moderate 1", report.ByEffort)
+	}
+}
+
+func TestReportersClusters(t *testing.T) {
+	escape := func(line, col int, variable string, cat categorizer.Category) categorizer.CategorizedEscape {
+		return categorizer.CategorizedEscape{
+			Info:     parser.EscapeInfo{File: "./main.go", Line: line, Column: col, Variable: variable, EscapeType: parser.EscapesToHeap},
+			Category: cat,
+			Cluster:  "./main.go:13 &T{...}",
+		}
+	}
+	results := &categorizer.Results{
+		Summary:    categorizer.Summary{TotalVariables: 4, HeapAllocated: 4, ByFile: map[string]int{"./main.go": 4}},
+		ByCategory: map[categorizer.Category]int{categorizer.CategoryInterfaceBoxing: 2, categorizer.CategorySpill: 2},
+		Escapes: []categorizer.CategorizedEscape{
+			escape(13, 15, "x", categorizer.CategorySpill),
+			escape(13, 9, "&T{...}", categorizer.CategoryInterfaceBoxing),
+			escape(13, 21, "s", categorizer.CategorySpill),
+			{Info: parser.EscapeInfo{File: "./main.go", Line: 20, Column: 2, Variable: "v", EscapeType: parser.MovedToHeap}, Category: categorizer.CategoryInterfaceBoxing},
+		},
+	}
+
+	var text bytes.Buffer
+	if err := NewTextReporter(&text).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("text reporter failed: %v", err)
+	}
+	for _, want := range []string{
+		"Findings:                 2",
+		"📍 ./main.go:13:9",
+		"Includes: 2 more escape(s) of ./main.go:13 &T{...}",
+		"x at ./main.go:13:15 (spill)",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text output missing %q:\n%s", want, text.String())
+		}
+	}
+	if strings.Contains(text.String(), "📍 ./main.go:13:15") {
+		t.Errorf("cluster member reported as a finding of its own:\n%s", text.String())
+	}
+
+	var out bytes.Buffer
+	if err := NewJSONReporter(&out).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("JSON reporter failed: %v", err)
+	}
+	var report struct {
+		Findings int `json:"findings"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if report.Findings != 2 {
+		t.Errorf("findings = %d, want 2", report.Findings)
+	}
+}
+
 func TestHTMLReporter(t *testing.T) {
 	results := sampleResults()
 	var buf bytes.Buffer
 
 	reporter := NewHTMLReporter(&buf)
-	err := reporter.Report(results)
+	err := reporter.Report(context.Background(), results, Metadata{})
 	if err != nil {
 		t.Fatalf("HTML reporter failed: %v", err)
 	}
@@ -177,12 +421,332 @@ func TestHTMLReporter(t *testing.T) {
 	}
 }
 
+func TestInliningSummary(t *testing.T) {
+	results := sampleResults()
+	results.Summary.Inlined = 5
+	results.Summary.Inlining = &categorizer.InliningStats{
+		Inlinable:    2,
+		CallsInlined: 3,
+		Failed:       4,
+		FailReasons:  map[string]int{"function too complex": 3, "unhandled op DEFER": 1},
+	}
+
+	var text bytes.Buffer
+	if err := NewTextReporter(&text).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("Text reporter failed: %v", err)
+	}
+	for _, want := range []string{"Inlining:", "Calls inlined:            3", "Not inlinable:            4", "function too complex   3"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text output missing %q", want)
+		}
+	}
+	if strings.Contains(text.String(), "Inlined calls:") {
+		t.Errorf("text output repeats the inlined count next to the Inlining block")
+	}
+
+	var html bytes.Buffer
+	if err := NewHTMLReporter(&html).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("HTML reporter failed: %v", err)
+	}
+	for _, want := range []string{"Inlining</h2>", "unhandled op DEFER"} {
+		if !strings.Contains(html.String(), want) {
+			t.Errorf("HTML output missing %q", want)
+		}
+	}
+}
+
+func TestTextReporterGoroutines(t *testing.T) {
+	results := sampleResults()
+	results.Goroutines = []categorizer.GoroutineGroup{
+		{
+			Func:       "ProcessTasks",
+			Position:   "worker.go:28",
+			Escapes:    3,
+			ByCategory: map[categorizer.Category]int{categorizer.CategoryClosureCapture: 2, categorizer.CategoryChannelSend: 1},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewTextReporter(&buf).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("Text reporter failed: %v", err)
+	}
+	want := "3 escapes  ProcessTasks (closure-capture 2, channel-send 1)"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("text output missing %q:\n%s", want, buf.String())
+	}
+}
+
+func TestTextReporterHandlers(t *testing.T) {
+	results := sampleResults()
+	results.Handlers = []categorizer.HandlerGroup{
+		{
+			Handler:    "listUsers",
+			Routes:     []string{"GET /users"},
+			Position:   "api.go:12",
+			Escapes:    2,
+			ByCategory: map[categorizer.Category]int{categorizer.CategoryCompositeLiteral: 1, categorizer.CategoryInterfaceBoxing: 1},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewTextReporter(&buf).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("Text reporter failed: %v", err)
+	}
+	want := "2 escapes  GET /users (listUsers) (composite-literal 1, interface-boxing 1)"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("text output missing %q:\n%s", want, buf.String())
+	}
+}
+
+func TestTextReporterLargeCopies(t *testing.T) {
+	results := sampleResults()
+	results.LargeCopies = []categorizer.LargeCopy{
+		{Func: "app.Apply", Value: "param c", Type: "app.Config", Size: 4096, Position: "app.go:10", CallSites: 3},
+	}
+
+	var buf bytes.Buffer
+	if err := NewTextReporter(&buf).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("Text reporter failed: %v", err)
+	}
+	for _, want := range []string{"Large value copies (large-value-copy, advisory):", "3 call sites", "app.Apply param c (app.Config)"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("text output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestSARIFRules(t *testing.T) {
+	results := sampleResults()
+	results.Escapes[1].Category = "custom-pool"
+	results.ByCategory["custom-pool"] = 1
+	var buf bytes.Buffer
+	if err := NewSARIFReporter(&buf).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("SARIF reporter failed: %v", err)
+	}
+
+	var report sarifReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid SARIF JSON: %v", err)
+	}
+	run := report.Runs[0]
+	rules := run.Tool.Driver.Rules
+
+	// Every built-in category has a rule, present in the results or not
+	if want := len(categorizer.Categories()) + 1; len(rules) != want {
+		t.Fatalf("got %d rules, want %d", len(rules), want)
+	}
+	byID := make(map[string]sarifRule)
+	for _, r := range rules {
+		byID[r.ID] = r
+	}
+	for _, cat := range categorizer.Categories() {
+		if _, ok := byID[string(cat)]; !ok {
+			t.Errorf("missing rule for %s", cat)
+		}
+	}
+
+	rp := byID[string(categorizer.CategoryReturnPointer)]
+	if rp.Name != "ReturnPointer" || rp.DefaultConfiguration.Level != "warning" || rp.Properties.Escapes != 1 {
+		t.Errorf("return-pointer rule = %+v", rp)
+	}
+	if !strings.Contains(rp.Help.Markdown, "```go") || rp.HelpURI == "" {
+		t.Errorf("return-pointer help = %q, helpUri %q", rp.Help.Markdown, rp.HelpURI)
+	}
+	if spill := byID[string(categorizer.CategorySpill)]; spill.DefaultConfiguration.Level != "note" || spill.Properties.Escapes != 0 {
+		t.Errorf("spill rule = %+v", spill)
+	}
+	if custom := byID["custom-pool"]; custom.Name != "CustomPool" || custom.Properties.Escapes != 1 {
+		t.Errorf("custom rule = %+v", custom)
+	}
+
+	// Results point at their rule
+	for _, r := range run.Results {
+		if rules[r.RuleIndex].ID != r.RuleID || r.Level != rules[r.RuleIndex].DefaultConfiguration.Level {
+			t.Errorf("result %s has ruleIndex %d (%s), level %s", r.RuleID, r.RuleIndex, rules[r.RuleIndex].ID, r.Level)
+		}
+	}
+}
+
+func TestSARIFResultLevels(t *testing.T) {
+	results := sampleResults()
+	results.Escapes[0].Severity = categorizer.SeverityHigh
+	var buf bytes.Buffer
+	if err := NewSARIFReporter(&buf).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("SARIF reporter failed: %v", err)
+	}
+
+	var report sarifReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid SARIF JSON: %v", err)
+	}
+	run := report.Runs[0]
+	if got := run.Results[0].Level; got != "error" {
+		t.Errorf("level of a high severity result = %q, want error", got)
+	}
+	if got, want := run.Results[1].Level, run.Tool.Driver.Rules[run.Results[1].RuleIndex].DefaultConfiguration.Level; got != want {
+		t.Errorf("level of a result without severity = %q, want its rule's %q", got, want)
+	}
+}
+
+func TestSuggestionOverrides(t *testing.T) {
+	results := sampleResults()
+	opts := []Option{WithSuggestions(map[categorizer.Category]categorizer.Suggestion{
+		categorizer.CategoryReturnPointer: {Short: "Follow the team's value-return guide"},
+	})}
+
+	for _, format := range []string{"text", "json", "html", "sarif"} {
+		var buf bytes.Buffer
+		rep, err := New(&buf, format, append(opts, WithVerbose(true))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rep.Report(context.Background(), results, Metadata{}); err != nil {
+			t.Fatalf("%s reporter failed: %v", format, err)
+		}
+		output := buf.String()
+		if !strings.Contains(output, "value-return guide") {
+			t.Errorf("%s output missing the overridden suggestion", format)
+		}
+		if !strings.Contains(output, "Use concrete types") {
+			t.Errorf("%s output missing the suggestion of another category", format)
+		}
+	}
+	if results.Escapes[0].Suggestion.Short != "Return by value" {
+		t.Errorf("reporting modified the results: suggestion %q", results.Escapes[0].Suggestion.Short)
+	}
+}
+
+func TestHTMLReporterEscaping(t *testing.T) {
+	results := sampleResults()
+	results.Escapes[0].Info.Variable = `</script><script>alert("x")</script>`
+	results.Escapes[1].Info.File = `it's "quoted".go`
+	results.ByCategory = map[categorizer.Category]int{`</script>'];alert(1);//`: 2}
+	var buf bytes.Buffer
+
+	if err := NewHTMLReporter(&buf).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("HTML reporter failed: %v", err)
+	}
+	output := buf.String()
+
+	for _, bad := range []string{`<script>alert`, `it's "quoted".go`, `</script>'];alert`} {
+		if strings.Contains(output, bad) {
+			t.Errorf("HTML output contains unescaped %q", bad)
+		}
+	}
+	if !strings.Contains(output, "&lt;/script&gt;&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;") {
+		t.Error("HTML output missing escaped variable name")
+	}
+
+	// The chart data island is valid JSON carrying the raw category names
+	_, island, ok := strings.Cut(output, `<script type="application/json" id="heapcheck-data">`)
+	if !ok {
+		t.Fatal("HTML output missing chart data island")
+	}
+	island, _, _ = strings.Cut(island, "</script>")
+	var chart htmlChart
+	if err := json.Unmarshal([]byte(island), &chart); err != nil {
+		t.Fatalf("invalid chart data %q: %v", island, err)
+	}
+	if len(chart.Categories) != 1 || chart.Categories[0] != `</script>'];alert(1);//` || chart.Counts[0] != 2 {
+		t.Errorf("chart data = %+v", chart)
+	}
+}
+
+func TestHTMLReporterPaged(t *testing.T) {
+	results := sampleResults()
+	escape := results.Escapes[0]
+	escape.Info.Variable = `</script><script>alert("x")</script>`
+	escape.Info.FlowInfo = []string{"flow: ~r0 = &x:"}
+	escape.Fix = "--- main.go\n+++ main.go"
+	results.Escapes = nil
+	for i := 0; i <= htmlPagedEscapes; i++ {
+		escape.Info.Line = i + 1
+		results.Escapes = append(results.Escapes, escape)
+	}
+	results.Summary.HeapAllocated = len(results.Escapes)
+
+	var buf bytes.Buffer
+	if err := NewHTMLReporter(&buf).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("HTML reporter failed: %v", err)
+	}
+	output := buf.String()
+
+	if strings.Contains(output, `<script>alert`) {
+		t.Error("HTML output contains unescaped variable name")
+	}
+	if strings.Contains(output, `<td><span class="var-name">`) {
+		t.Error("HTML output renders escape rows instead of paging them")
+	}
+	_, island, ok := strings.Cut(output, `<script type="application/json" id="heapcheck-escapes">`)
+	if !ok {
+		t.Fatal("HTML output missing escapes data island")
+	}
+	island, _, _ = strings.Cut(island, "</script>")
+	var paged htmlPaged
+	if err := json.Unmarshal([]byte(island), &paged); err != nil {
+		t.Fatalf("invalid escapes data: %v", err)
+	}
+	if len(paged.Escapes) != len(results.Escapes) {
+		t.Fatalf("escapes data has %d rows, want %d", len(paged.Escapes), len(results.Escapes))
+	}
+	row := paged.Escapes[1]
+	if row.Location != "main.go:2" || row.Variable != escape.Info.Variable || row.Badge != "badge-red" || len(row.Flow) != 1 || row.Details != escape.Suggestion.Details || row.Fix != escape.Fix {
+		t.Errorf("escapes data row = %+v", row)
+	}
+
+	// Smaller reports keep the plain table
+	buf.Reset()
+	if err := NewHTMLReporter(&buf).Report(context.Background(), sampleResults(), Metadata{}); err != nil {
+		t.Fatalf("HTML reporter failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "heapcheck-escapes") {
+		t.Error("HTML output pages a small escapes table")
+	}
+}
+
+func TestHTMLReporterAccept(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewHTMLReporter(&buf).Report(context.Background(), sampleResults(), Metadata{}); err != nil {
+		t.Fatalf("HTML reporter failed: %v", err)
+	}
+	if strings.Contains(buf.String(), `action="/accept"`) {
+		t.Error("HTML output has accept forms without WithAccept")
+	}
+
+	buf.Reset()
+	rep := NewHTMLReporter(&buf, WithAccept("/accept", "f00d", "comment", "baseline"))
+	if err := rep.Report(context.Background(), sampleResults(), Metadata{}); err != nil {
+		t.Fatalf("HTML reporter failed: %v", err)
+	}
+	output := buf.String()
+	for _, check := range []string{
+		`<form method="post" action="/accept" class="accept">`,
+		`<input type="hidden" name="token" value="f00d">`,
+		`name="escape" value="1"><input type="hidden" name="variable" value="req">`,
+		`<option>comment</option><option>baseline</option>`,
+	} {
+		if !strings.Contains(output, check) {
+			t.Errorf("HTML output missing: %s", check)
+		}
+	}
+
+	// A single target needs no choice
+	buf.Reset()
+	rep = NewHTMLReporter(&buf, WithAccept("/accept", "f00d", "comment"))
+	if err := rep.Report(context.Background(), sampleResults(), Metadata{}); err != nil {
+		t.Fatalf("HTML reporter failed: %v", err)
+	}
+	if output := buf.String(); strings.Contains(output, "<select") || !strings.Contains(output, `name="target" value="comment"`) {
+		t.Error("HTML output offers a choice of a single target")
+	}
+}
+
 func TestSARIFReporter(t *testing.T) {
 	results := sampleResults()
 	var buf bytes.Buffer
 
 	reporter := NewSARIFReporter(&buf)
-	err := reporter.Report(results)
+	err := reporter.Report(context.Background(), results, Metadata{})
 	if err != nil {
 		t.Fatalf("SARIF reporter failed: %v", err)
 	}
@@ -216,8 +780,8 @@ func TestEmptyResults(t *testing.T) {
 
 	t.Run("Text", func(t *testing.T) {
 		var buf bytes.Buffer
-		reporter := NewTextReporter(&buf, false)
-		err := reporter.Report(results)
+		reporter := NewTextReporter(&buf)
+		err := reporter.Report(context.Background(), results, Metadata{})
 		if err != nil {
 			t.Errorf("Text failed with empty results: %v", err)
 		}
@@ -226,7 +790,7 @@ func TestEmptyResults(t *testing.T) {
 	t.Run("JSON", func(t *testing.T) {
 		var buf bytes.Buffer
 		reporter := NewJSONReporter(&buf)
-		err := reporter.Report(results)
+		err := reporter.Report(context.Background(), results, Metadata{})
 		if err != nil {
 			t.Errorf("JSON failed with empty results: %v", err)
 		}
@@ -235,7 +799,7 @@ func TestEmptyResults(t *testing.T) {
 	t.Run("HTML", func(t *testing.T) {
 		var buf bytes.Buffer
 		reporter := NewHTMLReporter(&buf)
-		err := reporter.Report(results)
+		err := reporter.Report(context.Background(), results, Metadata{})
 		if err != nil {
 			t.Errorf("HTML failed with empty results: %v", err)
 		}
@@ -244,9 +808,580 @@ func TestEmptyResults(t *testing.T) {
 	t.Run("SARIF", func(t *testing.T) {
 		var buf bytes.Buffer
 		reporter := NewSARIFReporter(&buf)
-		err := reporter.Report(results)
+		err := reporter.Report(context.Background(), results, Metadata{})
 		if err != nil {
 			t.Errorf("SARIF failed with empty results: %v", err)
 		}
 	})
 }
+
+func TestTextReporterExpiringSuppressions(t *testing.T) {
+	results := sampleResults()
+	results.Summary.Suppressed = 2
+	results.Suppressions = []categorizer.SuppressionStatus{
+		{File: "main.go", Line: 3, Variable: "a", Category: categorizer.CategoryReturnPointer, Owner: "@core", Expires: "2025-01-01", Status: categorizer.SuppressionExpired},
+		{File: "main.go", Line: 7, Variable: "b", Category: categorizer.CategoryReturnPointer, Owner: "@core", Expires: "2025-02-01", Status: categorizer.SuppressionExpiring},
+		{File: "main.go", Line: 9, Variable: "c", Category: categorizer.CategoryReturnPointer, Status: categorizer.SuppressionActive},
+	}
+	var buf bytes.Buffer
+
+	if err := NewTextReporter(&buf).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("Text reporter failed: %v", err)
+	}
+
+	output := buf.String()
+	checks := []string{
+		"Suppressed:",
+		"Suppressions nearing expiry",
+		"2025-01-01",
+		"2025-02-01",
+		"@core",
+	}
+	for _, check := range checks {
+		if !strings.Contains(output, check) {
+			t.Errorf("Text output missing: %s", check)
+		}
+	}
+	if strings.Contains(output, "main.go:9") {
+		t.Error("Text output lists an active suppression as expiring")
+	}
+}
+
+func TestTextReporterCoverage(t *testing.T) {
+	results := sampleResults()
+	results.Escapes[0].Coverage = categorizer.CoverageUncovered
+	results.Escapes[1].Coverage = categorizer.CoverageCovered
+	results.Summary.CoveredEscapes = 1
+	results.Summary.UncoveredEscapes = 1
+	var buf bytes.Buffer
+
+	if err := NewTextReporter(&buf).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("Text reporter failed: %v", err)
+	}
+
+	output := buf.String()
+	checks := []string{
+		"Covered by tests:",
+		"Not covered by tests:",
+		"Uncovered escape-heavy files",
+		"main.go                                    1 uncovered escapes",
+		"Coverage: uncovered",
+	}
+	for _, check := range checks {
+		if !strings.Contains(output, check) {
+			t.Errorf("Text output missing: %s", check)
+		}
+	}
+	if strings.Contains(output, "handler.go                                 1 uncovered") {
+		t.Error("Text output lists a covered file as uncovered")
+	}
+}
+
+func TestTextReporterInit(t *testing.T) {
+	results := sampleResults()
+	results.Escapes[0].Init = true
+	var buf bytes.Buffer
+
+	if err := NewTextReporter(&buf).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("Text reporter failed: %v", err)
+	}
+
+	output := buf.String()
+	for _, check := range []string{"At initialization:        1 (one-time)", "Init:     one-time, at package initialization"} {
+		if !strings.Contains(output, check) {
+			t.Errorf("Text output missing: %s", check)
+		}
+	}
+}
+
+func TestTextReporterInterfaceParams(t *testing.T) {
+	results := sampleResults()
+	results.InterfaceParams = []categorizer.InterfaceParam{
+		{Func: "fmt.Sprintf", Param: "a ...any", Position: "$GOROOT/src/fmt/print.go:229", CallSites: 12, Escapes: 20},
+		{Func: "example.com/app.Store", Param: "v any", Position: "store.go:9", CallSites: 3, Escapes: 3},
+	}
+	var buf bytes.Buffer
+
+	if err := NewTextReporter(&buf, WithVerbose(true)).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("Text reporter failed: %v", err)
+	}
+
+	output := buf.String()
+	checks := []string{
+		"Interface parameters causing boxing:",
+		" 12 call sites  fmt.Sprintf(a ...any)",
+		"  3 call sites  example.com/app.Store(v any)",
+		"store.go:9",
+	}
+	for _, check := range checks {
+		if !strings.Contains(output, check) {
+			t.Errorf("Text output missing: %s", check)
+		}
+	}
+}
+
+func TestTextReporterGate(t *testing.T) {
+	results := sampleResults()
+	meta := Metadata{Gate: &categorizer.GateResult{
+		Status: categorizer.GateFail,
+		Categories: []categorizer.CategoryGate{
+			{Category: categorizer.CategoryInterfaceBoxing, Count: 1, Rule: "warn", Status: categorizer.GateWarn},
+			{Category: categorizer.CategoryReturnPointer, Count: 1, Rule: "fail", Status: categorizer.GateFail},
+		},
+	}}
+	var buf bytes.Buffer
+
+	if err := NewTextReporter(&buf).Report(context.Background(), results, meta); err != nil {
+		t.Fatalf("Text reporter failed: %v", err)
+	}
+
+	output := buf.String()
+	checks := []string{
+		"Category Gates:",
+		"warn interface-boxing",
+		"fail return-pointer",
+		"Gate: ❌ FAIL",
+	}
+	for _, check := range checks {
+		if !strings.Contains(output, check) {
+			t.Errorf("Text output missing: %s", check)
+		}
+	}
+}
+
+func TestReporterOptions(t *testing.T) {
+	results := sampleResults()
+	results.Escapes[0].Suggestion.DocLink = "https://go.dev/doc/faq#stack_or_heap"
+
+	tests := []struct {
+		name   string
+		opts   []Option
+		want   []string
+		absent []string
+	}{
+		{
+			name:   "defaults",
+			want:   []string{"main.go:10:5", "handler.go:25:12", "📖 https://go.dev/doc/faq#stack_or_heap"},
+			absent: []string{"\033["},
+		},
+		{
+			name:   "limit",
+			opts:   []Option{WithLimit(1)},
+			want:   []string{"main.go:10:5", "... and 1 more"},
+			absent: []string{"handler.go:25:12"},
+		},
+		{
+			name:   "no links",
+			opts:   []Option{WithLinks(false)},
+			absent: []string{"📖"},
+		},
+		{
+			name:   "doc link override",
+			opts:   []Option{WithDocLinks(map[categorizer.Category]string{categorizer.CategoryReturnPointer: "https://wiki.example.com/heap"})},
+			want:   []string{"📖 https://wiki.example.com/heap"},
+			absent: []string{"go.dev/doc/faq"},
+		},
+		{
+			name: "color",
+			opts: []Option{WithColor(true)},
+			want: []string{ansiBold + "Summary:" + ansiReset},
+		},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := NewTextReporter(&buf, tt.opts...).Report(context.Background(), results, Metadata{}); err != nil {
+			t.Fatalf("%s: Report() error: %v", tt.name, err)
+		}
+		output := buf.String()
+		for _, want := range tt.want {
+			if !strings.Contains(output, want) {
+				t.Errorf("%s: output missing %q", tt.name, want)
+			}
+		}
+		for _, absent := range tt.absent {
+			if strings.Contains(output, absent) {
+				t.Errorf("%s: output contains %q", tt.name, absent)
+			}
+		}
+	}
+}
+
+func TestJSONReporterMetadata(t *testing.T) {
+	meta := Metadata{
+		Version:  "1.2.3",
+		Started:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Duration: 1500 * time.Millisecond,
+		Gate:     &categorizer.GateResult{Status: categorizer.GateWarn},
+		Stats: &Stats{
+			Compile: 1200 * time.Millisecond,
+			Parse:   40 * time.Millisecond,
+			Output:  parser.Stats{Lines: 900, Unrecognized: 3, Packages: 4},
+		},
+	}
+	var buf bytes.Buffer
+	if err := NewJSONReporter(&buf).Report(context.Background(), sampleResults(), meta); err != nil {
+		t.Fatalf("Report() error: %v", err)
+	}
+
+	var out struct {
+		categorizer.Results
+		Gate     *categorizer.GateResult `json:"gate"`
+		Metadata struct {
+			Version    string  `json:"version"`
+			Started    string  `json:"started"`
+			DurationMS float64 `json:"durationMs"`
+			Stats      struct {
+				CompileMS    float64 `json:"compileMs"`
+				Lines        int     `json:"lines"`
+				Unrecognized int     `json:"unrecognized"`
+			} `json:"stats"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if out.Summary.HeapAllocated != 2 {
+		t.Errorf("summary.heapAllocated = %d, want 2", out.Summary.HeapAllocated)
+	}
+	if out.Gate == nil || out.Gate.Status != categorizer.GateWarn {
+		t.Errorf("gate = %+v, want warn", out.Gate)
+	}
+	if out.Metadata.Version != "1.2.3" || out.Metadata.Started != "2024-05-01T12:00:00Z" || out.Metadata.DurationMS != 1500 {
+		t.Errorf("metadata = %+v", out.Metadata)
+	}
+	if st := out.Metadata.Stats; st.CompileMS != 1200 || st.Lines != 900 || st.Unrecognized != 3 {
+		t.Errorf("metadata.stats = %+v", st)
+	}
+}
+
+func TestReporterAppliedConfig(t *testing.T) {
+	meta := Metadata{Config: &AppliedConfig{
+		Flags:    map[string]string{"filter": "example.com/app/api", "format": "json", "limit": "0"},
+		Given:    []string{"filter", "format"},
+		Patterns: []string{"./..."},
+		Files: []ConfigFile{{
+			Path:     ".heapcheck.yaml",
+			Settings: map[string]any{"categories": map[string]any{"fmt-call": "fail>10"}},
+		}},
+		Ruleset: ".heapcheck-rules.yaml",
+	}}
+
+	var buf bytes.Buffer
+	if err := NewJSONReporter(&buf).Report(context.Background(), sampleResults(), meta); err != nil {
+		t.Fatalf("Report() error: %v", err)
+	}
+	var out struct {
+		Metadata struct {
+			Config AppliedConfig `json:"config"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got := out.Metadata.Config; got.Flags["filter"] != "example.com/app/api" || len(got.Given) != 2 || len(got.Files) != 1 || got.Ruleset != ".heapcheck-rules.yaml" {
+		t.Errorf("metadata.config = %+v", got)
+	}
+
+	buf.Reset()
+	if err := NewHTMLReporter(&buf).Report(context.Background(), sampleResults(), meta); err != nil {
+		t.Fatalf("Report() error: %v", err)
+	}
+	for _, want := range []string{"Configuration</h2>", "<td>--filter</td><td><code>example.com/app/api</code>", ".heapcheck-rules.yaml", "fail&gt;10", "<td>--limit</td>"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("HTML output missing %q", want)
+		}
+	}
+}
+
+func TestReporterSample(t *testing.T) {
+	meta := Metadata{Sample: &Sample{Percent: 10, Packages: 5, Total: 48}}
+
+	var buf bytes.Buffer
+	if err := NewTextReporter(&buf).Report(context.Background(), sampleResults(), meta); err != nil {
+		t.Fatalf("Report() error: %v", err)
+	}
+	for _, want := range []string{"Sampled: 5 of 48 packages (10%)", "Estimated, all packages:  ~19 heap allocated"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("text output missing %q", want)
+		}
+	}
+
+	buf.Reset()
+	if err := NewHTMLReporter(&buf).Report(context.Background(), sampleResults(), meta); err != nil {
+		t.Fatalf("Report() error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "5 of 48 packages (10%)") || !strings.Contains(buf.String(), "~19 heap allocated") {
+		t.Error("HTML output does not label the sample")
+	}
+
+	buf.Reset()
+	if err := NewTextReporter(&buf).Report(context.Background(), sampleResults(), Metadata{}); err != nil {
+		t.Fatalf("Report() error: %v", err)
+	}
+	if strings.Contains(buf.String(), "Sampled") || strings.Contains(buf.String(), "Estimated, all packages") {
+		t.Error("text output labels a full run as sampled")
+	}
+}
+
+func TestTextReporterStats(t *testing.T) {
+	meta := Metadata{Stats: &Stats{
+		Parse:      40 * time.Millisecond,
+		Categorize: 2 * time.Millisecond,
+		Output:     parser.Stats{Lines: 200, Unrecognized: 5, Packages: 2},
+	}}
+	var buf bytes.Buffer
+	if err := NewTextReporter(&buf).Report(context.Background(), sampleResults(), meta); err != nil {
+		t.Fatalf("Report() error: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{"Statistics:", "Parse:                    40ms", "Lines parsed:             200", "Unrecognized lines:       5 (2.5%)"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+	if strings.Contains(output, "Compile:") {
+		t.Error("output has a compile time without one")
+	}
+
+	buf.Reset()
+	if err := NewTextReporter(&buf).Report(context.Background(), sampleResults(), Metadata{}); err != nil {
+		t.Fatalf("Report() error: %v", err)
+	}
+	if strings.Contains(buf.String(), "Statistics:") {
+		t.Error("output has statistics without --stats")
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, format := range []string{"text", "json", "html", "sarif", "matrix", "matrix-csv"} {
+		if _, err := New(&bytes.Buffer{}, format); err != nil {
+			t.Errorf("New(%q) error: %v", format, err)
+		}
+	}
+	if _, err := New(&bytes.Buffer{}, "xml"); err == nil {
+		t.Error("New(xml) expected error")
+	}
+}
+
+func TestReportCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var buf bytes.Buffer
+	if err := NewTextReporter(&buf).Report(ctx, sampleResults(), Metadata{}); err != context.Canceled {
+		t.Errorf("Report() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestTextReporterAges(t *testing.T) {
+	results := sampleResults()
+	results.Escapes[0].FirstSeen = "2024-05-30"
+	results.Escapes[1].FirstSeen = "2023-12-01"
+	meta := Metadata{Started: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	var buf bytes.Buffer
+
+	if err := NewTextReporter(&buf).Report(context.Background(), results, meta); err != nil {
+		t.Fatalf("Text reporter failed: %v", err)
+	}
+
+	output := buf.String()
+	checks := []string{
+		"New this week:            1",
+		"Age:      new this week (since 2024-05-30)",
+		"Age:      6 months old (since 2023-12-01)",
+	}
+	for _, check := range checks {
+		if !strings.Contains(output, check) {
+			t.Errorf("Text output missing: %s", check)
+		}
+	}
+}
+
+func TestTextReporterImpact(t *testing.T) {
+	results := sampleResults()
+	results.Escapes[0].Impact = &categorizer.Impact{Size: 480, Iterations: 10, Assumed: true, PerCall: 4800}
+
+	var buf bytes.Buffer
+	if err := NewTextReporter(&buf, WithVerbose(true)).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("Report() error: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{
+		"1. return-pointer",
+		"~4.7KB per call",
+		"(~4.7KB per call (assuming 10 loop iterations))",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestReportersFix(t *testing.T) {
+	results := sampleResults()
+	results.Escapes[0].Fix = "--- main.go\n+++ main.go\n@@ -8 +8 @@\n-\tvar ids []int\n+\tids := make([]int, 0, len(items))"
+
+	var buf bytes.Buffer
+	if err := NewTextReporter(&buf).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("Report() error: %v", err)
+	}
+	if strings.Contains(buf.String(), "Fix:") {
+		t.Errorf("non-verbose output shows the fix:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := NewTextReporter(&buf, WithVerbose(true)).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("Report() error: %v", err)
+	}
+	want := "   Fix:\n     --- main.go\n     +++ main.go\n     @@ -8 +8 @@\n     -\tvar ids []int\n     +\tids := make([]int, 0, len(items))\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("verbose output missing fix:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := NewHTMLReporter(&buf).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("HTML reporter failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `<pre class="fix">--- main.go`) {
+		t.Error("HTML output missing fix")
+	}
+}
+
+func TestNotAnalyzed(t *testing.T) {
+	results := &categorizer.Results{
+		Summary: categorizer.Summary{
+			Skipped: []parser.SkippedPackage{
+				{Package: "example.com/app/sqlite", Reason: "sqlite.go:5:10: fatal error: sqlite3.h: No such file or directory", PureGo: true},
+				{Package: "example.com/app/broken", Reason: "broken.go:4:1: syntax error: unexpected EOF"},
+			},
+		},
+		ByCategory: map[categorizer.Category]int{},
+	}
+
+	for _, format := range []string{"text", "json", "html", "sarif", "matrix", "matrix-csv"} {
+		var buf bytes.Buffer
+		rep, err := New(&buf, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rep.Report(context.Background(), results, Metadata{}); err != nil {
+			t.Fatalf("%s reporter failed: %v", format, err)
+		}
+		if !strings.Contains(buf.String(), "example.com/app/broken") {
+			t.Errorf("%s output does not list the package not analyzed:\n%s", format, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := NewTextReporter(&buf).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, check := range []string{"Not Analyzed (2 package(s))", "Go files only: sqlite.go:5:10", "skipped: broken.go:4:1"} {
+		if !strings.Contains(buf.String(), check) {
+			t.Errorf("Text output missing: %s", check)
+		}
+	}
+}
+
+func TestTruncatePath(t *testing.T) {
+	tests := []struct {
+		path string
+		max  int
+		want string
+	}{
+		{"pkg/server/handler.go", 40, "pkg/server/handler.go"},
+		{"internal/reporter/some/deep/path/handler.go", 20, "intern.../handler.go"},
+		{"a/b/c/d/e/f/g/h/i/j/k/l/m/n/o/p/q/r/s/t.go", 16, "a/b/c...r/s/t.go"},
+	}
+	for _, tt := range tests {
+		got := truncatePath(tt.path, tt.max)
+		if got != tt.want {
+			t.Errorf("truncatePath(%q, %d) = %q, want %q", tt.path, tt.max, got, tt.want)
+		}
+		if len(got) > tt.max {
+			t.Errorf("truncatePath(%q, %d) is %d long", tt.path, tt.max, len(got))
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  []string
+	}{
+		{"fits on one line", 20, []string{"fits on one line"}},
+		{"Return by value if the struct is small", 16, []string{"Return by value", "if the struct is", "small"}},
+		{"./a.go:3:19:   flow: ~r0 ← &x: and more", 30, []string{"./a.go:3:19:   flow: ~r0 ← &x:", "and more"}},
+		{"averylongwordthatdoesnotfit here", 10, []string{"averylongwordthatdoesnotfit", "here"}},
+	}
+	for _, tt := range tests {
+		got := wrap(tt.text, tt.width)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("wrap(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestTextReporterWidth(t *testing.T) {
+	results := sampleResults()
+	results.Escapes[0].Suggestion.Short = "Return by value if the struct is at most 64 bytes, which keeps it on the stack of the caller"
+	results.Summary.ByFile["internal/service/handlers/authentication/middleware.go"] = 1
+	var buf bytes.Buffer
+
+	if err := NewTextReporter(&buf, WithVerbose(true), WithWidth(50)).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("Text reporter failed: %v", err)
+	}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if displayWidth(line) > 50 {
+			t.Errorf("line wider than 50 columns: %q", line)
+		}
+	}
+	// Its directories, joined in the hotspot tree, are shortened instead
+	if !strings.Contains(buf.String(), "interna...thentication/") {
+		t.Errorf("long path not shortened in the middle:\n%s", buf.String())
+	}
+}
+
+func TestTextReporterCategorySections(t *testing.T) {
+	escape := func(file string, line int, fn string, cat categorizer.Category) categorizer.CategorizedEscape {
+		return categorizer.CategorizedEscape{
+			Info:     parser.EscapeInfo{Package: "example.com/api", File: file, Line: line, Column: 2, Variable: "v", EscapeType: parser.EscapesToHeap},
+			Category: cat,
+			Func:     fn,
+		}
+	}
+	results := &categorizer.Results{
+		Summary: categorizer.Summary{TotalVariables: 4, HeapAllocated: 4},
+		ByCategory: map[categorizer.Category]int{
+			categorizer.CategoryFmtCall:       3,
+			categorizer.CategoryReturnPointer: 1,
+		},
+		Escapes: []categorizer.CategorizedEscape{
+			escape("user.go", 5, "(*Server).Get", categorizer.CategoryFmtCall),
+			escape("new.go", 3, "New", categorizer.CategoryReturnPointer),
+			escape("user.go", 9, "(*Server).Get", categorizer.CategoryFmtCall),
+			escape("log.go", 2, "", categorizer.CategoryFmtCall),
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewTextReporter(&buf, WithVerbose(true), WithColor(false)).Report(context.Background(), results, Metadata{}); err != nil {
+		t.Fatalf("text reporter failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"▸ fmt-call: 3 escape(s) in 2 file(s), 1 function(s)",
+		"Top files:     user.go (2), log.go (1)",
+		"Top functions: api.(*Server).Get (2)",
+		"▸ return-pointer: 1 escape(s) in 1 file(s), 1 function(s)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("verbose output missing %q:\n%s", want, out)
+		}
+	}
+	fmtSection := strings.Index(out, "▸ fmt-call")
+	pointerSection := strings.Index(out, "▸ return-pointer")
+	if fmtSection > pointerSection || strings.Index(out, "log.go:2:2") > pointerSection || strings.Index(out, "new.go:3:2") < pointerSection {
+		t.Errorf("escapes are not listed under their category, most escapes first:\n%s", out)
+	}
+}
@@ -0,0 +1,580 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/history"
+)
+
+// =============================================================================
+// Static Site
+// =============================================================================
+
+// SiteModule is the results of one module, a section of the site
+type SiteModule struct {
+	Name    string
+	Results *categorizer.Results
+}
+
+// WriteSite writes a static site of the modules' results to dir, for
+// publishing as a dashboard: an index of the modules and their packages,
+// a summary and the HTML report of each module, a page per package, and a
+// trend page of runs when there are any. Pages link to each other by relative paths, so
+// the site can be served from any directory.
+func WriteSite(dir string, modules []SiteModule, runs []history.Run, meta Metadata, opts ...Option) error {
+	for _, sub := range []string{"modules", "summaries", "packages"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return err
+		}
+	}
+
+	d := newSiteData(modules, runs, meta)
+	for i, m := range modules {
+		var buf bytes.Buffer
+		if err := NewHTMLReporter(&buf, opts...).Report(context.Background(), m.Results, meta); err != nil {
+			return err
+		}
+		if err := writeSiteFile(dir, d.Modules[i].Page, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	for _, s := range d.Summaries {
+		if err := renderSitePage(dir, s.SummaryPage, "summary", s); err != nil {
+			return err
+		}
+	}
+	for _, p := range d.Packages {
+		if err := renderSitePage(dir, p.Page, "package", p); err != nil {
+			return err
+		}
+	}
+	if d.Trend != nil {
+		if err := renderSitePage(dir, "trend.html", "trend", d); err != nil {
+			return err
+		}
+	}
+	return renderSitePage(dir, "index.html", "index", d)
+}
+
+// siteData is the input of the site templates
+type siteData struct {
+	Modules   []siteModule
+	Summaries []*siteSummary
+	Packages  []*sitePackage
+	Trend     *siteTrend
+	Now       time.Time
+	Version   string
+}
+
+type siteModule struct {
+	Name        string
+	Page        string // relative to the site root
+	SummaryPage string
+	Summary     categorizer.Summary
+	HeapPct     float64
+	Packages    int
+}
+
+// siteSummary is the landing page of a module, for planning work rather
+// than fixing escapes: its totals, the trend of the recorded runs and the
+// actions that avoid the most allocation for the least effort
+type siteSummary struct {
+	siteModule
+	Findings int
+	Recent   int  // escapes first seen in the last siteRecentDays days
+	HasAges  bool // whether escapes have a FirstSeen date
+	Trend    *siteChange
+	Shared   bool // whether the runs are of every module of the site
+	Actions  []siteAction
+	Version  string
+}
+
+// siteChange is the latest recorded run and how it compares to the
+// previous and the first one
+type siteChange struct {
+	Latest     history.Run
+	First      history.Run
+	Runs       int
+	Delta      int // since the previous run
+	SinceFirst int
+}
+
+// siteAction is a recommended change: avoiding the escapes of a category
+// in a package
+type siteAction struct {
+	Package    string
+	Page       string // the package page, relative to the site root
+	Category   categorizer.Category
+	Effort     categorizer.Effort
+	Findings   int
+	Bytes      int64 // estimated bytes per call, 0 without impact estimates
+	Suggestion string
+	score      float64
+}
+
+const (
+	siteRecentDays = 30
+	siteActions    = 5
+)
+
+// effortCost weighs the impact of an action against the work it takes
+var effortCost = map[categorizer.Effort]float64{
+	categorizer.EffortTrivial:    1,
+	categorizer.EffortModerate:   2,
+	categorizer.EffortStructural: 4,
+}
+
+// sitePackage is a package page: the package's escapes, by file and line
+type sitePackage struct {
+	Name       string
+	Module     string
+	Page       string
+	Escapes    []categorizer.CategorizedEscape
+	ByCategory []siteCount
+	Version    string
+}
+
+type siteCount struct {
+	Category categorizer.Category
+	Count    int
+}
+
+// siteTrend is the escape count of recorded runs, drawn as a line of
+// siteChartWidth by siteChartHeight
+type siteTrend struct {
+	Runs   []siteRun // newest first
+	Points string    // SVG polyline points, oldest run first
+	Max    int
+	Min    int
+}
+
+type siteRun struct {
+	history.Run
+	Delta    int
+	HasDelta bool
+}
+
+const (
+	siteChartWidth  = 800
+	siteChartHeight = 200
+)
+
+func newSiteData(modules []SiteModule, runs []history.Run, meta Metadata) siteData {
+	d := siteData{Now: meta.now(), Version: meta.Version}
+	pages := make(map[string]bool)
+	for _, m := range modules {
+		sm := siteModule{
+			Name:        m.Name,
+			Page:        sitePage("modules", m.Name, pages),
+			SummaryPage: sitePage("summaries", m.Name, pages),
+			Summary:     m.Results.Summary,
+		}
+		if total := m.Results.Summary.TotalVariables; total > 0 {
+			sm.HeapPct = float64(m.Results.Summary.HeapAllocated) / float64(total) * 100
+		}
+
+		byPackage := make(map[string]*sitePackage)
+		for _, e := range m.Results.Escapes {
+			name := e.Info.PackageOrDir()
+			p := byPackage[name]
+			if p == nil {
+				p = &sitePackage{Name: name, Module: m.Name, Version: meta.Version}
+				byPackage[name] = p
+			}
+			p.Escapes = append(p.Escapes, e)
+		}
+		names := make([]string, 0, len(byPackage))
+		for name := range byPackage {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			p := byPackage[name]
+			p.Page = sitePage("packages", name, pages)
+			p.ByCategory = countCategories(p.Escapes)
+			sort.SliceStable(p.Escapes, func(i, j int) bool {
+				a, b := p.Escapes[i].Info, p.Escapes[j].Info
+				if a.File != b.File {
+					return a.File < b.File
+				}
+				return a.Line < b.Line
+			})
+			d.Packages = append(d.Packages, p)
+		}
+		sm.Packages = len(names)
+		d.Modules = append(d.Modules, sm)
+		d.Summaries = append(d.Summaries, newSiteSummary(sm, m.Results, byPackage, runs, len(modules) > 1, meta))
+	}
+
+	// Most escapes first on the index
+	sort.SliceStable(d.Packages, func(i, j int) bool {
+		return len(d.Packages[i].Escapes) > len(d.Packages[j].Escapes)
+	})
+	d.Trend = newSiteTrend(runs)
+	return d
+}
+
+// newSiteSummary returns the summary page of a module whose packages are
+// byPackage. Runs are not recorded per module, so with shared the trend
+// is of the whole site.
+func newSiteSummary(m siteModule, results *categorizer.Results, byPackage map[string]*sitePackage, runs []history.Run, shared bool, meta Metadata) *siteSummary {
+	s := &siteSummary{
+		siteModule: m,
+		Findings:   categorizer.CountFindings(results.Escapes),
+		Shared:     shared,
+		Version:    meta.Version,
+	}
+	since := meta.now().AddDate(0, 0, -siteRecentDays).Format(time.DateOnly)
+	for _, e := range results.Escapes {
+		if e.FirstSeen != "" {
+			s.HasAges = true
+			if e.FirstSeen >= since {
+				s.Recent++
+			}
+		}
+	}
+	if n := len(runs); n > 0 {
+		s.Trend = &siteChange{
+			Latest:     runs[n-1],
+			First:      runs[0],
+			Runs:       n,
+			SinceFirst: runs[n-1].HeapAllocated - runs[0].HeapAllocated,
+		}
+		if n > 1 {
+			s.Trend.Delta = runs[n-1].HeapAllocated - runs[n-2].HeapAllocated
+		}
+	}
+	s.Actions = siteTopActions(byPackage)
+	return s
+}
+
+// siteTopActions returns the siteActions actions of the packages with the
+// most impact for their effort: the estimated bytes per call when any
+// escape has an estimate, the number of findings otherwise, divided by
+// the effort's cost. An action's effort is the largest of its escapes'.
+func siteTopActions(byPackage map[string]*sitePackage) []siteAction {
+	type key struct {
+		pkg string
+		cat categorizer.Category
+	}
+	groups := make(map[key][]categorizer.CategorizedEscape)
+	var keys []key
+	estimated := false
+	for _, p := range byPackage {
+		for _, e := range p.Escapes {
+			k := key{p.Name, e.Category}
+			if groups[k] == nil {
+				keys = append(keys, k)
+			}
+			groups[k] = append(groups[k], e)
+			estimated = estimated || e.Impact != nil
+		}
+	}
+
+	actions := make([]siteAction, 0, len(keys))
+	for _, k := range keys {
+		escapes := groups[k]
+		a := siteAction{
+			Package:    k.pkg,
+			Page:       byPackage[k.pkg].Page,
+			Category:   k.cat,
+			Effort:     categorizer.EffortTrivial,
+			Findings:   categorizer.CountFindings(escapes),
+			Suggestion: escapes[0].Suggestion.Short,
+		}
+		for _, e := range escapes {
+			effort := e.Effort
+			if effort == "" {
+				effort = categorizer.EffortOf(e.Category)
+			}
+			if effortCost[effort] > effortCost[a.Effort] {
+				a.Effort = effort
+			}
+			if e.Impact != nil {
+				a.Bytes += e.Impact.PerCall
+			}
+		}
+		impact := float64(a.Findings)
+		if estimated {
+			impact = float64(a.Bytes)
+		}
+		a.score = impact / effortCost[a.Effort]
+		actions = append(actions, a)
+	}
+	sort.Slice(actions, func(i, j int) bool {
+		a, b := actions[i], actions[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.Findings != b.Findings {
+			return a.Findings > b.Findings
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Category < b.Category
+	})
+	if len(actions) > siteActions {
+		actions = actions[:siteActions]
+	}
+	return actions
+}
+
+// countCategories counts escapes by category, most first
+func countCategories(escapes []categorizer.CategorizedEscape) []siteCount {
+	counts := make(map[categorizer.Category]int)
+	for _, e := range escapes {
+		counts[e.Category]++
+	}
+	result := make([]siteCount, 0, len(counts))
+	for _, cat := range sortCategories(counts) {
+		result = append(result, siteCount{Category: cat, Count: counts[cat]})
+	}
+	return result
+}
+
+// newSiteTrend returns the trend of runs, oldest first, or nil without
+// runs
+func newSiteTrend(runs []history.Run) *siteTrend {
+	if len(runs) == 0 {
+		return nil
+	}
+	t := &siteTrend{Min: runs[0].HeapAllocated, Max: runs[0].HeapAllocated}
+	for _, r := range runs {
+		t.Min = min(t.Min, r.HeapAllocated)
+		t.Max = max(t.Max, r.HeapAllocated)
+	}
+
+	points := make([]string, len(runs))
+	for i, r := range runs {
+		x := siteChartWidth / 2.0
+		if len(runs) > 1 {
+			x = float64(i) / float64(len(runs)-1) * siteChartWidth
+		}
+		y := siteChartHeight / 2.0
+		if t.Max > t.Min {
+			y = siteChartHeight - float64(r.HeapAllocated-t.Min)/float64(t.Max-t.Min)*siteChartHeight
+		}
+		points[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+	t.Points = strings.Join(points, " ")
+
+	for i := len(runs) - 1; i >= 0; i-- {
+		sr := siteRun{Run: runs[i]}
+		if i > 0 {
+			sr.Delta, sr.HasDelta = runs[i].HeapAllocated-runs[i-1].HeapAllocated, true
+		}
+		t.Runs = append(t.Runs, sr)
+	}
+	return t
+}
+
+// sitePage returns a page path under sub for name, unique among pages:
+// names such as import paths become file names with their separators
+// replaced
+func sitePage(sub, name string, pages map[string]bool) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, strings.Trim(name, "./"))
+	if slug == "" {
+		slug = "root"
+	}
+	page := sub + "/" + slug + ".html"
+	for i := 2; pages[page]; i++ {
+		page = fmt.Sprintf("%s/%s-%d.html", sub, slug, i)
+	}
+	pages[page] = true
+	return page
+}
+
+func renderSitePage(dir, page, name string, data any) error {
+	var buf bytes.Buffer
+	if err := siteTemplate.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+	return writeSiteFile(dir, page, buf.Bytes())
+}
+
+func writeSiteFile(dir, page string, data []byte) error {
+	return os.WriteFile(filepath.Join(dir, filepath.FromSlash(page)), data, 0o644)
+}
+
+var siteTemplate = template.Must(template.New("site").Funcs(template.FuncMap{
+	"badge": getCategoryBadgeClass,
+	"bytes": categorizer.FormatBytes,
+	"add":   func(a, b int) int { return a + b },
+	"short": func(commit string) string {
+		if len(commit) > 7 {
+			return commit[:7]
+		}
+		return commit
+	},
+}).Parse(`
+{{- define "head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.}}</title>
+    <style>
+` + htmlStyles + `        nav { margin-bottom: 20px; }
+        nav a { color: #2563eb; margin-right: 16px; }
+        .trend-line { fill: none; stroke: #ef4444; stroke-width: 2; }
+        .delta-up { color: #dc2626; font-weight: 600; }
+        .delta-down { color: #16a34a; font-weight: 600; }
+    </style>
+</head>
+<body>
+    <div class="container">
+{{- end}}
+
+{{- define "foot"}}
+<div class="footer">Generated by heapcheck{{with .}} {{.}}{{end}}</div>
+    </div>
+</body>
+</html>
+{{end}}
+
+{{- define "index"}}{{template "head" "heapcheck Allocation Dashboard"}}
+        <h1>📊 heapcheck Allocation Dashboard</h1>
+<nav><a href="index.html">Modules and packages</a>{{if .Trend}}<a href="trend.html">Trend</a>{{end}}</nav>
+<div class="card"><h2>Modules</h2>
+<table><tr><th>Module</th><th>Variables</th><th>Stack</th><th>Heap</th><th>Heap %</th><th>Packages</th><th>Report</th></tr>
+{{- range .Modules}}
+<tr>
+	<td><a class="file-link" href="{{.SummaryPage}}">{{.Name}}</a></td>
+	<td>{{.Summary.TotalVariables}}</td>
+	<td>{{.Summary.StackAllocated}}</td>
+	<td><strong>{{.Summary.HeapAllocated}}</strong></td>
+	<td>{{printf "%.1f" .HeapPct}}%</td>
+	<td>{{.Packages}}</td>
+	<td><a href="{{.Page}}">Details</a></td>
+</tr>
+{{- end}}
+</table></div>
+<div class="card"><h2>Packages</h2>
+{{- if .Packages}}
+<table><tr><th>Package</th><th>Module</th><th>Escapes</th><th>Top category</th></tr>
+{{- range .Packages}}
+<tr>
+	<td><a class="file-link" href="{{.Page}}">{{.Name}}</a></td>
+	<td>{{.Module}}</td>
+	<td><strong>{{len .Escapes}}</strong></td>
+	<td>{{with index .ByCategory 0}}<span class="category-badge {{badge .Category}}">{{.Category}}</span> {{.Count}}{{end}}</td>
+</tr>
+{{- end}}
+</table>
+{{- else}}
+<div class="no-escapes"><div class="no-escapes-icon">🎉</div><div class="no-escapes-text">No heap escapes found!</div></div>
+{{- end}}
+</div>
+<p style="color: #6b7280;">Updated {{.Now.UTC.Format "2006-01-02 15:04 MST"}}</p>
+{{- template "foot" .Version}}
+{{- end}}
+
+{{- define "summary"}}{{template "head" (printf "%s summary - heapcheck" .Name)}}
+        <h1>📊 {{.Name}}</h1>
+<nav><a href="../index.html">← Dashboard</a><a href="../{{.Page}}">Detailed report</a>{{if .Trend}}<a href="../trend.html">Trend</a>{{end}}</nav>
+<div class="grid-3" style="margin-bottom: 24px;">
+<div class="stat-card info"><div class="stat-value">{{.Summary.TotalVariables}}</div><div class="stat-label">Variables</div></div>
+<div class="stat-card danger"><div class="stat-value">{{.Summary.HeapAllocated}}</div><div class="stat-label">Heap allocated</div><div class="stat-pct">{{printf "%.1f" .HeapPct}}%</div></div>
+<div class="stat-card"><div class="stat-value">{{.Findings}}</div><div class="stat-label">Findings</div><div class="stat-pct">in {{.Packages}} package(s)</div></div>
+</div>
+<div class="card"><h2>📈 Trend</h2>
+{{- with .Trend}}
+<p>Latest run {{.Latest.Time.UTC.Format "2006-01-02"}}{{with .Latest.Commit}} at <span class="file-link">{{short .}}</span>{{end}}: <strong>{{.Latest.HeapAllocated}}</strong> heap escape(s){{if $.Shared}} across all modules{{end}}.</p>
+{{- if gt .Runs 1}}
+<p>{{template "change" .Delta}} since the previous run, {{template "change" .SinceFirst}} since {{.First.Time.UTC.Format "2006-01-02"}} ({{.Runs}} runs).</p>
+{{- end}}
+{{- else}}
+<p>No runs recorded yet.</p>
+{{- end}}
+{{- if .HasAges}}
+<p><strong>{{.Recent}}</strong> escape(s) first seen in the last 30 days.</p>
+{{- end}}
+</div>
+<div class="card"><h2>🎯 Top Actions</h2>
+{{- if .Actions}}
+<p>Ranked by estimated impact for the effort they take.</p>
+<table><tr><th>#</th><th>Action</th><th>Package</th><th>Findings</th><th>Effort</th></tr>
+{{- range $i, $a := .Actions}}
+<tr>
+	<td>{{add $i 1}}</td>
+	<td><span class="category-badge {{badge .Category}}">{{.Category}}</span> {{.Suggestion}}{{if .Bytes}} (~{{bytes .Bytes}} per call){{end}}</td>
+	<td><a class="file-link" href="../{{.Page}}">{{.Package}}</a></td>
+	<td><strong>{{.Findings}}</strong></td>
+	<td>{{.Effort}}</td>
+</tr>
+{{- end}}
+</table>
+{{- else}}
+<div class="no-escapes"><div class="no-escapes-icon">🎉</div><div class="no-escapes-text">Nothing to do: no heap escapes found!</div></div>
+{{- end}}
+</div>
+{{- template "foot" .Version}}
+{{- end}}
+
+{{- define "change"}}{{if gt . 0}}<span class="delta-up">+{{.}}</span>{{else if lt . 0}}<span class="delta-down">{{.}}</span>{{else}}no change{{end}}{{end}}
+
+{{- define "package"}}{{template "head" (printf "%s - heapcheck" .Name)}}
+        <h1>📦 {{.Name}}</h1>
+<nav><a href="../index.html">← Dashboard</a></nav>
+<p>Module {{.Module}} • {{len .Escapes}} escape(s)</p>
+<div class="card"><h2>Escape Categories</h2>
+<table><tr><th>Category</th><th>Escapes</th></tr>
+{{- range .ByCategory}}
+<tr><td><span class="category-badge {{badge .Category}}">{{.Category}}</span></td><td><strong>{{.Count}}</strong></td></tr>
+{{- end}}
+</table></div>
+<div class="card"><h2>📋 Escapes</h2>
+<table><tr><th>Location</th><th>Variable</th><th>Category</th><th>Suggestion</th></tr>
+{{- range .Escapes}}
+<tr>
+	<td><span class="file-link">{{.Info.File}}:{{.Info.Line}}</span></td>
+	<td><span class="var-name">{{.Info.Variable}}</span></td>
+	<td><span class="category-badge {{badge .Category}}">{{.Category}}</span></td>
+	<td><span class="suggestion">{{.Suggestion.Short}}</span></td>
+</tr>
+{{- end}}
+</table></div>
+{{- template "foot" .Version}}
+{{- end}}
+
+{{- define "trend"}}{{template "head" "heapcheck Trend"}}
+        <h1>📈 Escape Trend</h1>
+<nav><a href="index.html">← Dashboard</a></nav>
+{{- with .Trend}}
+<div class="card"><h2>Heap escapes per run ({{.Min}}–{{.Max}})</h2>
+<svg viewBox="-10 -10 820 220" width="100%" height="240" role="img" aria-label="Heap escapes per recorded run">
+	<polyline class="trend-line" points="{{.Points}}"/>
+</svg>
+</div>
+<div class="card"><h2>Runs</h2>
+<table><tr><th>Time</th><th>Branch</th><th>Commit</th><th>Heap escapes</th><th>Change</th></tr>
+{{- range .Runs}}
+<tr>
+	<td>{{.Time.UTC.Format "2006-01-02 15:04"}}</td>
+	<td>{{.Branch}}</td>
+	<td><span class="file-link">{{short .Commit}}</span></td>
+	<td><strong>{{.HeapAllocated}}</strong></td>
+	<td>{{if .HasDelta}}{{if gt .Delta 0}}<span class="delta-up">+{{.Delta}}</span>{{else if lt .Delta 0}}<span class="delta-down">{{.Delta}}</span>{{else}}0{{end}}{{end}}</td>
+</tr>
+{{- end}}
+</table></div>
+{{- end}}
+{{- template "foot" .Version}}
+{{- end}}
+`))
@@ -0,0 +1,168 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/history"
+)
+
+func TestWriteSite(t *testing.T) {
+	api := sampleResults()
+	api.Escapes[0].Info.Package = "example.com/api"
+	api.Escapes[1].Info.Package = "example.com/api/handlers"
+	worker := sampleResults()
+	worker.Escapes[0].Info.Package = "example.com/api" // the same path in another module
+	worker.Escapes = worker.Escapes[:1]
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	runs := []history.Run{
+		{Time: day, Commit: "0123456789abcdef", Branch: "main", HeapAllocated: 40},
+		{Time: day.Add(24 * time.Hour), Commit: "fedcba9876543210", Branch: "main", HeapAllocated: 35},
+	}
+
+	dir := t.TempDir()
+	modules := []SiteModule{{Name: "api", Results: api}, {Name: "worker", Results: worker}}
+	if err := WriteSite(dir, modules, runs, Metadata{Version: "1.2.3"}); err != nil {
+		t.Fatal(err)
+	}
+	read := func(page string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(page)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	index := read("index.html")
+	for _, want := range []string{
+		`href="modules/api.html"`,
+		`href="modules/worker.html"`,
+		`href="packages/example.com_api.html"`,
+		`href="packages/example.com_api-2.html"`,
+		`href="packages/example.com_api_handlers.html"`,
+		`href="trend.html"`,
+	} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index missing %s:\n%s", want, index)
+		}
+	}
+	if report := read("modules/api.html"); !strings.Contains(report, "heapcheck Report") {
+		t.Errorf("module page is not the HTML report:\n%s", report)
+	}
+	pkg := read("packages/example.com_api_handlers.html")
+	for _, want := range []string{"example.com/api/handlers", "handler.go:25", "interface-boxing", `href="../index.html"`} {
+		if !strings.Contains(pkg, want) {
+			t.Errorf("package page missing %s:\n%s", want, pkg)
+		}
+	}
+	if strings.Contains(pkg, "main.go:10") {
+		t.Errorf("package page lists another package's escape:\n%s", pkg)
+	}
+	summary := read("summaries/api.html")
+	for _, want := range []string{
+		"Findings",
+		`<strong>35</strong> heap escape(s) across all modules`,
+		`<span class="delta-down">-5</span> since the previous run`,
+		`href="../packages/example.com_api_handlers.html"`,
+		`href="../modules/api.html"`,
+		"Return by value",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary page missing %s:\n%s", want, summary)
+		}
+	}
+	if !strings.Contains(index, `href="summaries/api.html"`) {
+		t.Errorf("index does not link the module summary:\n%s", index)
+	}
+
+	trend := read("trend.html")
+	for _, want := range []string{"0123456", "fedcba9", `<span class="delta-down">-5</span>`, `points="0.0,0.0 800.0,200.0"`} {
+		if !strings.Contains(trend, want) {
+			t.Errorf("trend page missing %s:\n%s", want, trend)
+		}
+	}
+}
+
+func TestWriteSiteWithoutRuns(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteSite(dir, []SiteModule{{Name: "api", Results: sampleResults()}}, nil, Metadata{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "trend.html")); !os.IsNotExist(err) {
+		t.Errorf("trend page written without runs: %v", err)
+	}
+	index, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(index), "trend.html") {
+		t.Errorf("index links to a missing trend page:\n%s", index)
+	}
+}
+
+func TestSiteTopActions(t *testing.T) {
+	escape := func(cat categorizer.Category, effort categorizer.Effort, perCall int64) categorizer.CategorizedEscape {
+		e := categorizer.CategorizedEscape{Category: cat, Effort: effort}
+		if perCall > 0 {
+			e.Impact = &categorizer.Impact{Size: perCall, Iterations: 1, PerCall: perCall}
+		}
+		return e
+	}
+	tests := []struct {
+		name    string
+		escapes []categorizer.CategorizedEscape
+		want    []categorizer.Category
+	}{
+		{
+			name: "findings for the effort",
+			escapes: []categorizer.CategorizedEscape{
+				escape(categorizer.CategoryGoroutineEscape, categorizer.EffortStructural, 0),
+				escape(categorizer.CategoryGoroutineEscape, categorizer.EffortStructural, 0),
+				escape(categorizer.CategoryGoroutineEscape, categorizer.EffortStructural, 0),
+				escape(categorizer.CategoryFmtCall, categorizer.EffortTrivial, 0),
+				escape(categorizer.CategoryInterfaceBoxing, categorizer.EffortModerate, 0),
+				escape(categorizer.CategoryInterfaceBoxing, categorizer.EffortModerate, 0),
+				escape(categorizer.CategoryInterfaceBoxing, categorizer.EffortModerate, 0),
+			},
+			want: []categorizer.Category{categorizer.CategoryInterfaceBoxing, categorizer.CategoryFmtCall, categorizer.CategoryGoroutineEscape},
+		},
+		{
+			name: "estimated bytes for the effort",
+			escapes: []categorizer.CategorizedEscape{
+				escape(categorizer.CategoryFmtCall, categorizer.EffortTrivial, 64),
+				escape(categorizer.CategoryFmtCall, categorizer.EffortTrivial, 64),
+				escape(categorizer.CategorySliceGrow, categorizer.EffortTrivial, 4096),
+				escape(categorizer.CategoryGoroutineEscape, categorizer.EffortStructural, 1024),
+			},
+			want: []categorizer.Category{categorizer.CategorySliceGrow, categorizer.CategoryGoroutineEscape, categorizer.CategoryFmtCall},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			byPackage := map[string]*sitePackage{"app": {Name: "app", Page: "packages/app.html", Escapes: tt.escapes}}
+			var got []categorizer.Category
+			for _, a := range siteTopActions(byPackage) {
+				got = append(got, a.Category)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("actions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSiteTopActionsLimit(t *testing.T) {
+	byPackage := make(map[string]*sitePackage)
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		byPackage[name] = &sitePackage{Name: name, Escapes: []categorizer.CategorizedEscape{{Category: categorizer.CategoryFmtCall}}}
+	}
+	if got := siteTopActions(byPackage); len(got) != siteActions || got[0].Package != "a" {
+		t.Errorf("actions = %+v, want the first %d packages", got, siteActions)
+	}
+}
@@ -0,0 +1,61 @@
+// Package sample selects a stable subset of packages for sampled runs,
+// which give fast smoke signals on repositories too large to analyze in
+// full on every change.
+//
+// A package is selected by a hash of its import path, not at random, so
+// that consecutive runs at the same percentage analyze the same packages
+// and their counts are comparable. Adding or removing packages does not
+// change whether the others are selected, and a larger percentage selects
+// a superset of a smaller one.
+package sample
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// scale is the resolution of percentages: hundredths of a percent
+const scale = 10000
+
+// ParsePercent parses a percentage of packages, e.g. "10%" or "2.5",
+// greater than 0 and at most 100
+func ParsePercent(s string) (float64, error) {
+	p, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil || math.IsNaN(p) || p <= 0 || p > 100 {
+		return 0, fmt.Errorf("invalid sample %q (want a percentage of packages above 0 and up to 100, e.g. 10%%)", s)
+	}
+	return p, nil
+}
+
+// Select returns the packages of pkgs, import paths, selected at percent,
+// in their order. At least one package is selected from a non-empty
+// list: the one of the lowest hash, which is the first any percentage
+// selects.
+func Select(pkgs []string, percent float64) []string {
+	threshold := uint32(math.Round(percent * scale / 100))
+	var selected []string
+	lowest, lowestPkg := uint32(math.MaxUint32), ""
+	for _, pkg := range pkgs {
+		b := bucket(pkg)
+		if b < threshold {
+			selected = append(selected, pkg)
+		}
+		if b < lowest || (b == lowest && pkg < lowestPkg) {
+			lowest, lowestPkg = b, pkg
+		}
+	}
+	if len(selected) == 0 && len(pkgs) > 0 {
+		selected = []string{lowestPkg}
+	}
+	return selected
+}
+
+// bucket places an import path in one of scale buckets
+func bucket(pkg string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(pkg))
+	return h.Sum32() % scale
+}
@@ -0,0 +1,69 @@
+package sample
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestParsePercent(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{in: "10%", want: 10},
+		{in: "2.5", want: 2.5},
+		{in: " 100% ", want: 100},
+		{in: "0%", wantErr: true},
+		{in: "150%", wantErr: true},
+		{in: "-5%", wantErr: true},
+		{in: "ten", wantErr: true},
+		{in: "NaN", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePercent(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParsePercent(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	var pkgs []string
+	for i := 0; i < 1000; i++ {
+		pkgs = append(pkgs, fmt.Sprintf("example.com/mono/svc%d/handler", i))
+	}
+
+	ten := Select(pkgs, 10)
+	if len(ten) < 50 || len(ten) > 150 {
+		t.Errorf("Select(10%%) of 1000 packages selected %d", len(ten))
+	}
+	if again := Select(pkgs, 10); !slices.Equal(again, ten) {
+		t.Error("Select() is not deterministic")
+	}
+
+	// Selection does not depend on the other packages listed
+	if sub := Select(pkgs[:500], 10); !slices.Equal(sub, slices.DeleteFunc(slices.Clone(ten), func(p string) bool {
+		return !slices.Contains(pkgs[:500], p)
+	})) {
+		t.Error("Select() of a subset is not the subset of the selection")
+	}
+
+	// A larger sample is a superset
+	for _, p := range ten {
+		if !slices.Contains(Select(pkgs, 25), p) {
+			t.Errorf("Select(25%%) dropped %s, selected at 10%%", p)
+		}
+	}
+
+	if all := Select(pkgs, 100); len(all) != len(pkgs) {
+		t.Errorf("Select(100%%) selected %d of %d packages", len(all), len(pkgs))
+	}
+	if one := Select(pkgs[:3], 0.01); len(one) != 1 {
+		t.Errorf("Select(0.01%%) of 3 packages = %v, want one package", one)
+	}
+	if none := Select(nil, 10); len(none) != 0 {
+		t.Errorf("Select() of no packages = %v", none)
+	}
+}
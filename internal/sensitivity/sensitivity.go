@@ -0,0 +1,109 @@
+// Package sensitivity compares escape analysis results produced with and
+// without inlining, separating fundamental escapes from escapes that
+// depend on the compiler's inlining decisions.
+//
+// An escape that only appears with inlining disabled (-l) is currently
+// avoided because its function is inlined; a change that makes the
+// function too expensive to inline brings it back. An escape that only
+// appears with inlining enabled is introduced by an inlined callee.
+package sensitivity
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+// Report is the result of comparing two analysis runs. Escapes are
+// compared by source position; each position is listed once.
+type Report struct {
+	// Stable counts source positions that escape regardless of inlining
+	Stable int `json:"stable"`
+
+	// AvoidedByInlining escapes only appear with inlining disabled
+	AvoidedByInlining []categorizer.CategorizedEscape `json:"avoidedByInlining"`
+
+	// CausedByInlining escapes only appear with inlining enabled
+	CausedByInlining []categorizer.CategorizedEscape `json:"causedByInlining"`
+}
+
+// key identifies an escape across runs by source position. Expression
+// text is left out since inlining rewrites it (strconv.Itoa(n) becomes
+// ~r0), as is the escape type.
+func key(e categorizer.CategorizedEscape) string {
+	return fmt.Sprintf("%s:%d:%d", e.Info.File, e.Info.Line, e.Info.Column)
+}
+
+// Compare returns the escapes that differ between a default run and a
+// run with inlining disabled
+func Compare(inlined, noInline *categorizer.Results) *Report {
+	inSet := make(map[string]bool, len(inlined.Escapes))
+	for _, e := range inlined.Escapes {
+		inSet[key(e)] = true
+	}
+	noSet := make(map[string]bool, len(noInline.Escapes))
+	for _, e := range noInline.Escapes {
+		noSet[key(e)] = true
+	}
+
+	report := &Report{
+		AvoidedByInlining: make([]categorizer.CategorizedEscape, 0),
+		CausedByInlining:  make([]categorizer.CategorizedEscape, 0),
+	}
+	seen := make(map[string]bool)
+	for _, e := range inlined.Escapes {
+		k := key(e)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		if noSet[k] {
+			report.Stable++
+		} else {
+			report.CausedByInlining = append(report.CausedByInlining, e)
+		}
+	}
+	for _, e := range noInline.Escapes {
+		k := key(e)
+		if !inSet[k] && !seen[k] {
+			seen[k] = true
+			report.AvoidedByInlining = append(report.AvoidedByInlining, e)
+		}
+	}
+
+	categorizer.SortEscapes(report.AvoidedByInlining, categorizer.OrderPosition)
+	categorizer.SortEscapes(report.CausedByInlining, categorizer.OrderPosition)
+	return report
+}
+
+// WriteText writes a human-readable report
+func WriteText(w io.Writer, r *Report) {
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "📊 heapcheck - Inlining Sensitivity Report")
+	fmt.Fprintln(w, "──────────────────────────────────────────────────")
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "  Stable escapes:              %d\n", r.Stable)
+	fmt.Fprintf(w, "  Avoided only by inlining:    %d\n", len(r.AvoidedByInlining))
+	fmt.Fprintf(w, "  Caused only by inlining:     %d\n", len(r.CausedByInlining))
+	fmt.Fprintln(w, "")
+
+	if len(r.AvoidedByInlining) > 0 {
+		fmt.Fprintln(w, "Escapes that reappear if inlining is lost (-l):")
+		writeEscapes(w, r.AvoidedByInlining)
+	}
+	if len(r.CausedByInlining) > 0 {
+		fmt.Fprintln(w, "Escapes introduced by inlined callees:")
+		writeEscapes(w, r.CausedByInlining)
+	}
+	if len(r.AvoidedByInlining) == 0 && len(r.CausedByInlining) == 0 {
+		fmt.Fprintln(w, "✅ No escapes depend on inlining decisions.")
+	}
+}
+
+func writeEscapes(w io.Writer, escapes []categorizer.CategorizedEscape) {
+	for _, e := range escapes {
+		fmt.Fprintf(w, "  %s:%d  %s [%s]\n", e.Info.File, e.Info.Line, e.Info.Variable, e.Category)
+	}
+	fmt.Fprintln(w, "")
+}
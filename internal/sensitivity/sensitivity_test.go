@@ -0,0 +1,55 @@
+package sensitivity
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func TestCompare(t *testing.T) {
+	inlined := categorizer.Categorize([]parser.EscapeInfo{
+		{File: "a.go", Line: 3, Column: 2, Variable: "x", EscapeType: parser.MovedToHeap},
+		{File: "a.go", Line: 9, Column: 5, Variable: "buf", EscapeType: parser.EscapesToHeap},
+		{File: "a.go", Line: 12, Column: 8, Variable: `"id=" + ~r0`, EscapeType: parser.EscapesToHeap},
+		{File: "a.go", Line: 12, Column: 8, Variable: `"id=" + ~r0`, EscapeType: parser.EscapesToHeap},
+	})
+	noInline := categorizer.Categorize([]parser.EscapeInfo{
+		{File: "a.go", Line: 3, Column: 2, Variable: "x", EscapeType: parser.EscapesToHeap},
+		{File: "b.go", Line: 7, Column: 9, Variable: "&T{...}", EscapeType: parser.EscapesToHeap},
+		{File: "a.go", Line: 1, Column: 1, Variable: "y", EscapeType: parser.DoesNotEscape},
+		{File: "a.go", Line: 12, Column: 8, Variable: `"id=" + strconv.Itoa(n)`, EscapeType: parser.EscapesToHeap},
+	})
+
+	r := Compare(inlined, noInline)
+
+	if r.Stable != 2 {
+		t.Errorf("Stable = %d, want 2", r.Stable)
+	}
+	if len(r.AvoidedByInlining) != 1 || r.AvoidedByInlining[0].Info.File != "b.go" {
+		t.Errorf("AvoidedByInlining = %+v, want b.go escape", r.AvoidedByInlining)
+	}
+	if len(r.CausedByInlining) != 1 || r.CausedByInlining[0].Info.Variable != "buf" {
+		t.Errorf("CausedByInlining = %+v, want buf escape", r.CausedByInlining)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	WriteText(&buf, &Report{Stable: 4})
+	if !strings.Contains(buf.String(), "No escapes depend on inlining") {
+		t.Errorf("WriteText() output missing all-clear line:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	WriteText(&buf, &Report{AvoidedByInlining: []categorizer.CategorizedEscape{
+		{Info: parser.EscapeInfo{File: "b.go", Line: 7, Variable: "p"}, Category: categorizer.CategoryReturnPointer},
+	}})
+	for _, check := range []string{"Avoided only by inlining:    1", "b.go:7", "return-pointer"} {
+		if !strings.Contains(buf.String(), check) {
+			t.Errorf("WriteText() output missing: %s", check)
+		}
+	}
+}
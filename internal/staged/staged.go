@@ -0,0 +1,209 @@
+// Package staged reads the Go changes staged in git and widens them to
+// the functions they touch, so that a pre-commit hook can report just the
+// escapes of the code being committed.
+package staged
+
+import (
+	"bufio"
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/buildctx"
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/gitcmd"
+)
+
+// Range is an inclusive range of lines
+type Range struct {
+	Start, End int
+}
+
+// File is a changed file, slash-separated and relative to the directory
+// of the diff, and the lines of its new content the change touches
+type File struct {
+	Path  string
+	Lines []Range
+}
+
+// Changes are the changed files of a diff, resolved against Dir
+type Changes struct {
+	Dir   string
+	Files []File
+}
+
+// Diff returns the staged changes to the non-test Go files under dir, by
+// running `git diff --cached` there. Files the build configuration
+// excludes are left out.
+func Diff(dir string) (*Changes, error) {
+	out, err := gitcmd.Run(context.Background(), dir, "diff", "--cached", "--relative", "--unified=0", "--no-color", "--no-ext-diff", "--no-prefix", "--diff-filter=ACMR", "--", "*.go")
+	if err != nil {
+		return nil, err
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := ParseDiff(strings.NewReader(out))
+	kept := files[:0]
+	for _, f := range files {
+		if buildctx.Includes(filepath.Join(dir, filepath.FromSlash(f.Path))) {
+			kept = append(kept, f)
+		}
+	}
+	return &Changes{Dir: dir, Files: kept}, nil
+}
+
+// Unstaged returns the Go files under dir with changes that are not
+// staged: the compiler sees them, so line numbers there may not match
+// the staged content
+func Unstaged(dir string) ([]string, error) {
+	out, err := gitcmd.Run(context.Background(), dir, "diff", "--relative", "--name-only", "--no-ext-diff", "--", "*.go")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+// ParseDiff parses a unified diff made with --unified=0 and --no-prefix
+// into the lines each file gained. A hunk that only removes lines counts
+// as touching the line before the removal. Test files are left out, as
+// the analysis does not compile them.
+func ParseDiff(r io.Reader) []File {
+	var files []File
+	var cur *File
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			name := strings.TrimPrefix(line, "+++ ")
+			if unquoted, err := strconv.Unquote(name); err == nil {
+				name = unquoted
+			}
+			cur = nil
+			if name != "/dev/null" && strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") {
+				files = append(files, File{Path: name})
+				cur = &files[len(files)-1]
+			}
+		case strings.HasPrefix(line, "@@ ") && cur != nil:
+			if r, ok := parseHunk(line); ok {
+				cur.Lines = append(cur.Lines, r)
+			}
+		}
+	}
+	return files
+}
+
+// parseHunk returns the new-file lines of a hunk header such as
+// "@@ -10,2 +10,3 @@ func f() {"
+func parseHunk(header string) (Range, bool) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return Range{}, false
+	}
+	startText, countText, hasCount := strings.Cut(fields[2][1:], ",")
+	start, err := strconv.Atoi(startText)
+	if err != nil {
+		return Range{}, false
+	}
+	count := 1
+	if hasCount {
+		if count, err = strconv.Atoi(countText); err != nil {
+			return Range{}, false
+		}
+	}
+	if count == 0 {
+		return Range{Start: max(start, 1), End: max(start, 1)}, true
+	}
+	return Range{Start: start, End: start + count - 1}, true
+}
+
+// Widen extends the changed lines of each file to the functions around
+// them, as changing one line can make values elsewhere in its function
+// escape. Files that do not parse keep their lines.
+func (c *Changes) Widen() {
+	fset := token.NewFileSet()
+	for i := range c.Files {
+		f := &c.Files[i]
+		file, err := parser.ParseFile(fset, filepath.Join(c.Dir, filepath.FromSlash(f.Path)), nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		for j, r := range f.Lines {
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+				start, end := fset.Position(fn.Pos()).Line, fset.Position(fn.End()).Line
+				if start <= r.End && r.Start <= end {
+					r = Range{Start: min(r.Start, start), End: max(r.End, end)}
+				}
+			}
+			f.Lines[j] = r
+		}
+	}
+}
+
+// Packages returns the package patterns of the changed files' directories,
+// e.g. "./internal/server" or "."
+func (c *Changes) Packages() []string {
+	var patterns []string
+	seen := make(map[string]bool)
+	for _, f := range c.Files {
+		dir := path.Dir(f.Path)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		if dir == "." {
+			patterns = append(patterns, ".")
+		} else {
+			patterns = append(patterns, "./"+dir)
+		}
+	}
+	return patterns
+}
+
+// Contains reports whether the change touches line of file, a path as
+// the compiler prints it: relative to Dir or absolute
+func (c *Changes) Contains(file string, line int) bool {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(c.Dir, file)
+	}
+	rel, err := filepath.Rel(c.Dir, file)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, f := range c.Files {
+		if f.Path != rel {
+			continue
+		}
+		for _, r := range f.Lines {
+			if r.Start <= line && line <= r.End {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Filter returns the escapes of results the change touches
+func (c *Changes) Filter(escapes []categorizer.CategorizedEscape) []categorizer.CategorizedEscape {
+	kept := make([]categorizer.CategorizedEscape, 0)
+	for _, e := range escapes {
+		if c.Contains(e.Info.File, e.Info.Line) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
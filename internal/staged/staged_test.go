@@ -0,0 +1,88 @@
+package staged
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+const sampleDiff = `diff --git server/handler.go server/handler.go
+index 1111111..2222222 100644
+--- server/handler.go
++++ server/handler.go
+@@ -10,2 +10,3 @@ func handle() {
++	x := new(T)
+@@ -30 +31 @@ func other() {
+-	old()
++	new()
+@@ -40,3 +41,0 @@ func gone() {
+diff --git server/handler_test.go server/handler_test.go
+--- server/handler_test.go
++++ server/handler_test.go
+@@ -1 +1 @@
+diff --git "with space.go" "with space.go"
+new file mode 100644
+--- /dev/null
++++ "with space.go"
+@@ -0,0 +1,5 @@
+`
+
+func TestParseDiff(t *testing.T) {
+	got := ParseDiff(strings.NewReader(sampleDiff))
+	want := []File{
+		{Path: "server/handler.go", Lines: []Range{{10, 12}, {31, 31}, {41, 41}}},
+		{Path: "with space.go", Lines: []Range{{1, 5}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDiff() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWidenAndFilter(t *testing.T) {
+	dir := t.TempDir()
+	src := `package server
+
+var global = new(int)
+
+func handle() *int {
+	x := 1
+	return &x
+}
+
+func untouched() *int {
+	y := 2
+	return &y
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "handler.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	changes := &Changes{Dir: dir, Files: []File{{Path: "handler.go", Lines: []Range{{3, 3}, {6, 6}}}}}
+	changes.Widen()
+	if want := []Range{{3, 3}, {5, 8}}; !reflect.DeepEqual(changes.Files[0].Lines, want) {
+		t.Fatalf("Widen() lines = %+v, want %+v", changes.Files[0].Lines, want)
+	}
+
+	escape := func(file string, line int) categorizer.CategorizedEscape {
+		return categorizer.CategorizedEscape{Info: parser.EscapeInfo{File: file, Line: line}}
+	}
+	escapes := []categorizer.CategorizedEscape{
+		escape("handler.go", 3),
+		escape(filepath.Join(dir, "handler.go"), 6),
+		escape("handler.go", 11),
+		escape("other.go", 6),
+	}
+	got := changes.Filter(escapes)
+	if len(got) != 2 || got[0].Info.Line != 3 || got[1].Info.Line != 6 {
+		t.Errorf("Filter() = %+v, want the escapes on lines 3 and 6 of handler.go", got)
+	}
+
+	if got, want := changes.Packages(), []string{"."}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Packages() = %v, want %v", got, want)
+	}
+}
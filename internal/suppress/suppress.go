@@ -0,0 +1,280 @@
+// Package suppress hides accepted escapes from reports, based on
+// `//heapcheck:ignore` source comments and baseline entries.
+//
+// A suppression comment applies to escapes on its own line and on the
+// line below it. It may restrict the categories it hides and carry
+// ownership and expiry metadata:
+//
+//	//heapcheck:ignore interface-boxing owner=@platform-team expires=2025-06-01 -- logged once at startup
+//	logger.Info("starting", cfg)
+//
+// Once a suppression expires it no longer hides the escape; the escape
+// is reported again and the run fails until the suppression is renewed
+// or the escape is fixed.
+package suppress
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/baseline"
+	"github.com/harshakonda/heapcheck/internal/buildctx"
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+// Sources of a suppression
+const (
+	SourceComment  = "comment"
+	SourceBaseline = "baseline"
+)
+
+// DateLayout is the format of expiry dates
+const DateLayout = "2006-01-02"
+
+// Suppression hides matching escapes until an optional expiry date
+type Suppression struct {
+	File       string
+	Line       int
+	Categories []categorizer.Category
+	Source     string
+	Owner      string
+	Expires    string
+	Reason     string
+
+	// key matches baseline entries independent of line numbers
+	key string
+}
+
+// ignoreRe matches "//heapcheck:ignore ..." comments
+var ignoreRe = regexp.MustCompile(`//\s*heapcheck:ignore\b(.*)$`)
+
+// ParseComment parses the text of a single source line. It returns false
+// if the line has no suppression comment.
+func ParseComment(line string) (Suppression, bool) {
+	m := ignoreRe.FindStringSubmatch(line)
+	if m == nil {
+		return Suppression{}, false
+	}
+
+	s := Suppression{Source: SourceComment}
+	args := m[1]
+	if i := strings.Index(args, "--"); i >= 0 {
+		s.Reason = strings.TrimSpace(args[i+2:])
+		args = args[:i]
+	}
+
+	for _, field := range strings.Fields(args) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			for _, cat := range strings.Split(field, ",") {
+				if cat != "" {
+					s.Categories = append(s.Categories, categorizer.Category(cat))
+				}
+			}
+			continue
+		}
+		switch key {
+		case "owner":
+			s.Owner = value
+		case "expires":
+			s.Expires = value
+		case "reason":
+			s.Reason = value
+		}
+	}
+	return s, true
+}
+
+// FromComments scans files for suppression comments. Files the build
+// configuration excludes are skipped.
+func FromComments(files []string) ([]Suppression, error) {
+	var result []Suppression
+	for _, file := range files {
+		if !buildctx.Includes(file) {
+			continue
+		}
+		data, err := buildctx.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading suppressions: %w", err)
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		lineNum := 0
+		for scanner.Scan() {
+			lineNum++
+			if s, ok := ParseComment(scanner.Text()); ok {
+				s.File = file
+				s.Line = lineNum
+				result = append(result, s)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading suppressions from %s: %w", file, err)
+		}
+	}
+	return result, nil
+}
+
+// FromBaseline converts baseline entries into suppressions
+func FromBaseline(b *baseline.Baseline) []Suppression {
+	result := make([]Suppression, 0, len(b.Entries))
+	for _, e := range b.Entries {
+		result = append(result, Suppression{
+			File:       e.File,
+			Line:       e.Line,
+			Categories: []categorizer.Category{e.Category},
+			Source:     SourceBaseline,
+			Owner:      e.Owner,
+			Expires:    e.Expires,
+			Reason:     e.Reason,
+			key:        e.Key(),
+		})
+	}
+	return result
+}
+
+// Validate checks that expiry dates are well-formed
+func Validate(sups []Suppression) error {
+	for _, s := range sups {
+		if s.Expires == "" {
+			continue
+		}
+		if _, err := time.Parse(DateLayout, s.Expires); err != nil {
+			return fmt.Errorf("%s:%d: invalid expires=%q, want YYYY-MM-DD", s.File, s.Line, s.Expires)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether s applies to escape e
+func (s Suppression) Matches(e categorizer.CategorizedEscape) bool {
+	if s.key != "" {
+		return s.key == baseline.KeyOf(e)
+	}
+	if filepath.Clean(s.File) != filepath.Clean(e.Info.File) {
+		return false
+	}
+	if e.Info.Line != s.Line && e.Info.Line != s.Line+1 {
+		return false
+	}
+	if len(s.Categories) == 0 {
+		return true
+	}
+	for _, cat := range s.Categories {
+		if cat == e.Category {
+			return true
+		}
+	}
+	return false
+}
+
+// Status returns active, expiring (within window of now), or expired
+func (s Suppression) Status(now time.Time, window time.Duration) string {
+	if s.Expires == "" {
+		return categorizer.SuppressionActive
+	}
+	expires, err := time.Parse(DateLayout, s.Expires)
+	if err != nil {
+		return categorizer.SuppressionActive
+	}
+	// A suppression is valid through the end of its expiry day
+	end := expires.AddDate(0, 0, 1)
+	switch {
+	case !now.Before(end):
+		return categorizer.SuppressionExpired
+	case now.Add(window).After(end):
+		return categorizer.SuppressionExpiring
+	default:
+		return categorizer.SuppressionActive
+	}
+}
+
+// Apply removes escapes matched by unexpired suppressions from results
+// and records the status of every matching suppression. Escapes whose
+// suppression has expired stay in the report. It returns the number of
+// expired suppressions that matched.
+func Apply(results *categorizer.Results, sups []Suppression, now time.Time, window time.Duration) int {
+	if len(sups) == 0 {
+		return 0
+	}
+
+	expired := 0
+	kept := results.Escapes[:0]
+	for _, e := range results.Escapes {
+		s, ok := find(sups, e)
+		if !ok {
+			kept = append(kept, e)
+			continue
+		}
+
+		status := s.Status(now, window)
+		results.Suppressions = append(results.Suppressions, categorizer.SuppressionStatus{
+			File:     e.Info.File,
+			Line:     e.Info.Line,
+			Variable: e.Info.Variable,
+			Category: e.Category,
+			Source:   s.Source,
+			Owner:    s.Owner,
+			Expires:  s.Expires,
+			Status:   status,
+		})
+
+		if status == categorizer.SuppressionExpired {
+			expired++
+			kept = append(kept, e)
+			continue
+		}
+
+		results.Summary.HeapAllocated--
+		results.Summary.Suppressed++
+		decrement(results.Summary.ByFile, e.Info.File)
+		decrement(results.Summary.ByEscapeType, e.Info.EscapeType.String())
+		if results.ByCategory[e.Category]--; results.ByCategory[e.Category] <= 0 {
+			delete(results.ByCategory, e.Category)
+		}
+	}
+	results.Escapes = kept
+	return expired
+}
+
+func find(sups []Suppression, e categorizer.CategorizedEscape) (Suppression, bool) {
+	for _, s := range sups {
+		if s.Matches(e) {
+			return s, true
+		}
+	}
+	return Suppression{}, false
+}
+
+// decrement lowers a count, deleting it at zero; a nil map, as in
+// results saved before it was counted, is left alone
+func decrement(m map[string]int, key string) {
+	if m == nil {
+		return
+	}
+	if m[key]--; m[key] <= 0 {
+		delete(m, key)
+	}
+}
+
+// Files returns the distinct source files referenced by escapes
+func Files(results *categorizer.Results) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, e := range results.Escapes {
+		if !seen[e.Info.File] {
+			seen[e.Info.File] = true
+			files = append(files, e.Info.File)
+		}
+	}
+	return files
+}
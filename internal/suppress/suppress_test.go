@@ -0,0 +1,239 @@
+package suppress
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/harshakonda/heapcheck/internal/baseline"
+	"github.com/harshakonda/heapcheck/internal/buildctx"
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+	"github.com/harshakonda/heapcheck/internal/parser"
+)
+
+func escape(file string, line int, variable string, cat categorizer.Category) categorizer.CategorizedEscape {
+	return categorizer.CategorizedEscape{
+		Info: parser.EscapeInfo{
+			File:       file,
+			Line:       line,
+			Variable:   variable,
+			EscapeType: parser.EscapesToHeap,
+		},
+		Category: cat,
+	}
+}
+
+func TestParseComment(t *testing.T) {
+	tests := []struct {
+		line    string
+		ok      bool
+		cats    int
+		owner   string
+		expires string
+		reason  string
+	}{
+		{"x := 1", false, 0, "", "", ""},
+		{"//heapcheck:ignore", true, 0, "", "", ""},
+		{"\tfoo(x) // heapcheck:ignore interface-boxing", true, 1, "", "", ""},
+		{"//heapcheck:ignore interface-boxing,closure-capture owner=@team expires=2025-06-01", true, 2, "@team", "2025-06-01", ""},
+		{"//heapcheck:ignore owner=@team -- logged once at startup", true, 0, "@team", "", "logged once at startup"},
+		{"//heapcheck:ignore reason=hot-path", true, 0, "", "", "hot-path"},
+		{"//heapcheck:ignored", false, 0, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		s, ok := ParseComment(tt.line)
+		if ok != tt.ok {
+			t.Errorf("ParseComment(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if len(s.Categories) != tt.cats {
+			t.Errorf("ParseComment(%q) categories = %v, want %d", tt.line, s.Categories, tt.cats)
+		}
+		if s.Owner != tt.owner {
+			t.Errorf("ParseComment(%q) owner = %q, want %q", tt.line, s.Owner, tt.owner)
+		}
+		if s.Expires != tt.expires {
+			t.Errorf("ParseComment(%q) expires = %q, want %q", tt.line, s.Expires, tt.expires)
+		}
+		if s.Reason != tt.reason {
+			t.Errorf("ParseComment(%q) reason = %q, want %q", tt.line, s.Reason, tt.reason)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	s := Suppression{
+		File:       "pkg/a.go",
+		Line:       10,
+		Categories: []categorizer.Category{categorizer.CategoryInterfaceBoxing},
+	}
+
+	tests := []struct {
+		name string
+		e    categorizer.CategorizedEscape
+		want bool
+	}{
+		{"same line", escape("pkg/a.go", 10, "x", categorizer.CategoryInterfaceBoxing), true},
+		{"next line", escape("pkg/a.go", 11, "x", categorizer.CategoryInterfaceBoxing), true},
+		{"two lines below", escape("pkg/a.go", 12, "x", categorizer.CategoryInterfaceBoxing), false},
+		{"other category", escape("pkg/a.go", 11, "x", categorizer.CategoryClosureCapture), false},
+		{"other file", escape("pkg/b.go", 11, "x", categorizer.CategoryInterfaceBoxing), false},
+		{"uncleaned path", escape("pkg/./a.go", 11, "x", categorizer.CategoryInterfaceBoxing), true},
+	}
+
+	for _, tt := range tests {
+		if got := s.Matches(tt.e); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesBaseline(t *testing.T) {
+	b := &baseline.Baseline{Entries: []baseline.Entry{
+		{File: "a.go", Line: 5, Variable: "buf", Category: categorizer.CategorySliceGrow},
+	}}
+	s := FromBaseline(b)[0]
+
+	if !s.Matches(escape("a.go", 50, "buf", categorizer.CategorySliceGrow)) {
+		t.Error("baseline suppression should match regardless of line")
+	}
+	if s.Matches(escape("a.go", 5, "other", categorizer.CategorySliceGrow)) {
+		t.Error("baseline suppression should not match another variable")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	window := 14 * 24 * time.Hour
+
+	tests := []struct {
+		expires string
+		want    string
+	}{
+		{"", categorizer.SuppressionActive},
+		{"2025-12-31", categorizer.SuppressionActive},
+		{"2025-06-10", categorizer.SuppressionExpiring},
+		{"2025-06-01", categorizer.SuppressionExpiring},
+		{"2025-05-31", categorizer.SuppressionExpired},
+	}
+
+	for _, tt := range tests {
+		s := Suppression{Expires: tt.expires}
+		if got := s.Status(now, window); got != tt.want {
+			t.Errorf("Status(expires=%q) = %q, want %q", tt.expires, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate([]Suppression{{Expires: "2025-06-01"}, {}}); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+	if err := Validate([]Suppression{{File: "a.go", Line: 3, Expires: "June"}}); err == nil {
+		t.Error("Validate() expected error for malformed date")
+	}
+}
+
+func TestApply(t *testing.T) {
+	results := categorizer.Categorize(nil)
+	for _, e := range []categorizer.CategorizedEscape{
+		escape("a.go", 2, "x", categorizer.CategoryInterfaceBoxing),
+		escape("a.go", 5, "y", categorizer.CategoryInterfaceBoxing),
+		escape("b.go", 8, "z", categorizer.CategoryClosureCapture),
+	} {
+		results.Escapes = append(results.Escapes, e)
+		results.Summary.HeapAllocated++
+		results.Summary.ByFile[e.Info.File]++
+		results.Summary.ByEscapeType[e.Info.EscapeType.String()]++
+		results.ByCategory[e.Category]++
+	}
+
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	sups := []Suppression{
+		{File: "a.go", Line: 1, Owner: "@a", Expires: "2025-12-01"},
+		{File: "b.go", Line: 8, Owner: "@b", Expires: "2025-01-01"},
+	}
+
+	expired := Apply(results, sups, now, 14*24*time.Hour)
+
+	if expired != 1 {
+		t.Errorf("Apply() expired = %d, want 1", expired)
+	}
+	if len(results.Escapes) != 2 {
+		t.Fatalf("Escapes = %d, want 2", len(results.Escapes))
+	}
+	if results.Summary.HeapAllocated != 2 {
+		t.Errorf("HeapAllocated = %d, want 2", results.Summary.HeapAllocated)
+	}
+	if results.Summary.Suppressed != 1 {
+		t.Errorf("Suppressed = %d, want 1", results.Summary.Suppressed)
+	}
+	if got := results.Summary.ByEscapeType["escapes-to-heap"]; got != 2 {
+		t.Errorf("ByEscapeType[escapes-to-heap] = %d, want 2", got)
+	}
+	if results.ByCategory[categorizer.CategoryInterfaceBoxing] != 1 {
+		t.Errorf("ByCategory[interface-boxing] = %d, want 1", results.ByCategory[categorizer.CategoryInterfaceBoxing])
+	}
+	if len(results.Suppressions) != 2 {
+		t.Fatalf("Suppressions = %d, want 2", len(results.Suppressions))
+	}
+	if results.Suppressions[1].Status != categorizer.SuppressionExpired {
+		t.Errorf("Suppressions[1].Status = %q, want expired", results.Suppressions[1].Status)
+	}
+}
+
+func TestFromComments(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.go")
+	src := "package a\n\n//heapcheck:ignore owner=@a\nvar x = 1\n"
+	if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sups, err := FromComments([]string{file, filepath.Join(dir, "missing.go")})
+	if err != nil {
+		t.Fatalf("FromComments() error: %v", err)
+	}
+	if len(sups) != 1 {
+		t.Fatalf("FromComments() = %d suppressions, want 1", len(sups))
+	}
+	if sups[0].Line != 3 || sups[0].Owner != "@a" || sups[0].File != file {
+		t.Errorf("FromComments()[0] = %+v", sups[0])
+	}
+}
+
+func TestFromCommentsBuildConstraints(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":         "package a\n\n//heapcheck:ignore\nvar x = 1\n",
+		"a_windows.go": "package a\n\n//heapcheck:ignore\nvar y = 1\n",
+		"tagged.go":    "//go:build integration\n\npackage a\n\n//heapcheck:ignore\nvar z = 1\n",
+	}
+	var paths []string
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	ctx := build.Default
+	ctx.GOOS, ctx.BuildTags = "linux", nil
+	buildctx.Use(buildctx.New(ctx))
+	defer buildctx.Use(nil)
+
+	sups, err := FromComments(paths)
+	if err != nil {
+		t.Fatalf("FromComments() error: %v", err)
+	}
+	if len(sups) != 1 || filepath.Base(sups[0].File) != "a.go" {
+		t.Errorf("FromComments() = %+v, want only the suppression in a.go", sups)
+	}
+}
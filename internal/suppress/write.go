@@ -0,0 +1,100 @@
+package suppress
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Comment returns the suppression comment for s, the inverse of
+// ParseComment
+func (s Suppression) Comment() string {
+	var b strings.Builder
+	b.WriteString("//heapcheck:ignore")
+	if len(s.Categories) > 0 {
+		cats := make([]string, len(s.Categories))
+		for i, cat := range s.Categories {
+			cats[i] = string(cat)
+		}
+		b.WriteString(" " + strings.Join(cats, ","))
+	}
+	if s.Owner != "" {
+		b.WriteString(" owner=" + s.Owner)
+	}
+	if s.Expires != "" {
+		b.WriteString(" expires=" + s.Expires)
+	}
+	if s.Reason != "" {
+		b.WriteString(" -- " + s.Reason)
+	}
+	return b.String()
+}
+
+// check reports fields that Comment cannot write so that ParseComment
+// reads them back
+func (s Suppression) check() error {
+	for _, cat := range s.Categories {
+		if cat == "" || strings.ContainsAny(string(cat), ", \t\r\n=") || strings.Contains(string(cat), "--") {
+			return fmt.Errorf("invalid category %q", cat)
+		}
+	}
+	if strings.ContainsAny(s.Owner, " \t\r\n") || strings.Contains(s.Owner, "--") {
+		return fmt.Errorf("invalid owner %q: want a single word such as @team", s.Owner)
+	}
+	if s.Expires != "" {
+		if _, err := time.Parse(DateLayout, s.Expires); err != nil {
+			return fmt.Errorf("invalid expires %q, want YYYY-MM-DD", s.Expires)
+		}
+	}
+	if strings.ContainsAny(s.Reason, "\r\n") {
+		return fmt.Errorf("the reason must be a single line")
+	}
+	return nil
+}
+
+// AddComment writes the comment of s on a new line above s.Line of
+// s.File, indented as that line, so that it suppresses the escapes on
+// it. A line already below a suppression comment is left alone: the new
+// comment would take the place of that one above the line.
+func AddComment(s Suppression) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+	info, err := os.Stat(s.File)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(s.File)
+	if err != nil {
+		return err
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if s.Line < 1 || s.Line > len(lines) || s.Line == len(lines) && len(lines[s.Line-1]) == 0 {
+		return fmt.Errorf("%s has no line %d", s.File, s.Line)
+	}
+	if s.Line > 1 {
+		if _, ok := ParseComment(strings.TrimRight(string(lines[s.Line-2]), "\r\n")); ok {
+			return fmt.Errorf("%s:%d already has a suppression comment above it; edit that one", s.File, s.Line)
+		}
+	}
+
+	target := lines[s.Line-1]
+	indent := target[:len(target)-len(bytes.TrimLeft(target, " \t"))]
+	newline := "\n"
+	if bytes.HasSuffix(target, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+	var out bytes.Buffer
+	out.Grow(len(data) + len(indent) + 128)
+	for _, l := range lines[:s.Line-1] {
+		out.Write(l)
+	}
+	out.Write(indent)
+	out.WriteString(s.Comment() + newline)
+	for _, l := range lines[s.Line-1:] {
+		out.Write(l)
+	}
+	return os.WriteFile(s.File, out.Bytes(), info.Mode().Perm())
+}
@@ -0,0 +1,101 @@
+package suppress
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/internal/categorizer"
+)
+
+func TestCommentRoundTrip(t *testing.T) {
+	tests := []Suppression{
+		{},
+		{Categories: []categorizer.Category{categorizer.CategoryInterfaceBoxing}},
+		{Categories: []categorizer.Category{categorizer.CategoryInterfaceBoxing, categorizer.CategorySliceGrow}, Owner: "@a", Expires: "2025-06-01", Reason: "logged once at startup"},
+		{Reason: "needs -- dashes"},
+	}
+	for _, s := range tests {
+		s.Source = SourceComment
+		got, ok := ParseComment("\t" + s.Comment())
+		if !ok {
+			t.Errorf("ParseComment(%q) found no comment", s.Comment())
+			continue
+		}
+		if !reflect.DeepEqual(got, s) {
+			t.Errorf("ParseComment(%q) = %+v, want %+v", s.Comment(), got, s)
+		}
+	}
+}
+
+func TestAddComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		line    int
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "indented",
+			src:  "package a\n\nfunc f() {\n\tg(x)\n}\n",
+			line: 4,
+			want: "package a\n\nfunc f() {\n\t//heapcheck:ignore interface-boxing -- why\n\tg(x)\n}\n",
+		},
+		{
+			name: "crlf",
+			src:  "package a\r\nvar x = 1\r\n",
+			line: 2,
+			want: "package a\r\n//heapcheck:ignore interface-boxing -- why\r\nvar x = 1\r\n",
+		},
+		{
+			name: "last line without newline",
+			src:  "package a\nvar x = 1",
+			line: 2,
+			want: "package a\n//heapcheck:ignore interface-boxing -- why\nvar x = 1",
+		},
+		{name: "already suppressed", src: "package a\n//heapcheck:ignore slice-grow\nvar x = 1\n", line: 3, wantErr: true},
+		{name: "no such line", src: "package a\n", line: 2, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "a.go")
+			if err := os.WriteFile(file, []byte(tt.src), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			err := AddComment(Suppression{File: file, Line: tt.line, Categories: []categorizer.Category{categorizer.CategoryInterfaceBoxing}, Reason: "why"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AddComment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			data, _ := os.ReadFile(file)
+			if tt.wantErr {
+				tt.want = tt.src
+			}
+			if string(data) != tt.want {
+				t.Errorf("file = %q, want %q", data, tt.want)
+			}
+			if info, _ := os.Stat(file); info.Mode().Perm() != 0o600 {
+				t.Errorf("mode = %v, want 0600 kept", info.Mode().Perm())
+			}
+		})
+	}
+}
+
+func TestAddCommentInvalid(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.go")
+	if err := os.WriteFile(file, []byte("package a\nvar x = 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []Suppression{
+		{Owner: "two words"},
+		{Expires: "soon"},
+		{Reason: "two\nlines"},
+		{Categories: []categorizer.Category{"a,b"}},
+	} {
+		s.File, s.Line = file, 2
+		if err := AddComment(s); err == nil {
+			t.Errorf("AddComment(%+v) succeeded, want an error", s)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Package synth generates compiler escape analysis output of any size, as
+// go build -gcflags=-m=2 prints it, for benchmarks and load tests of the
+// parser and categorizer. The output is deterministic.
+package synth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// functionsPerFile and filesPerPackage shape the generated module
+const (
+	functionsPerFile = 40
+	filesPerPackage  = 8
+)
+
+// templates are the diagnostics of one function, by kind of escape. %[1]s
+// is the file, %[2]d the function's first line and %[3]s its name.
+var templates = []string{
+	// Arguments boxed by fmt.Println
+	`%[1]s:%[2]d:6: can inline %[3]s with cost 12 as: func(int, string) { fmt.Println(x, s) }
+%[1]s:%[2]d:13: inlining call to fmt.Println
+%[1]s:%[2]d:14: x escapes to heap in %[3]s:
+%[1]s:%[2]d:14:   flow: {storage for ... argument} ← &{storage for x}:
+%[1]s:%[2]d:14:     from x (spill) at %[1]s:%[2]d:14
+%[1]s:%[2]d:14:     from ... argument (slice-literal-element) at %[1]s:%[2]d:13
+%[1]s:%[2]d:14:   flow: fmt.a ← &{storage for ... argument}:
+%[1]s:%[2]d:14:     from ... argument (spill) at %[1]s:%[2]d:13
+%[1]s:%[2]d:14:     from fmt.a := ... argument (assign-pair) at %[1]s:%[2]d:13
+%[1]s:%[2]d:14:   flow: {heap} ← *fmt.a:
+%[1]s:%[2]d:14:     from fmt.Fprintln(os.Stdout, fmt.a...) (call parameter) at %[1]s:%[2]d:13
+%[1]s:%[2]d:17: s escapes to heap in %[3]s:
+%[1]s:%[2]d:17:   flow: {storage for ... argument} ← &{storage for s}:
+%[1]s:%[2]d:17:     from s (spill) at %[1]s:%[2]d:17
+%[1]s:%[2]d:17:     from ... argument (slice-literal-element) at %[1]s:%[2]d:13
+%[1]s:%[2]d:13: ... argument does not escape
+%[1]s:%[2]d:14: x escapes to heap
+%[1]s:%[2]d:17: s escapes to heap
+`,
+	// A composite literal and its fields
+	`%[1]s:%[2]d:9: &T{...} escapes to heap in %[3]s:
+%[1]s:%[2]d:9:   flow: {heap} ← &{storage for &T{...}}:
+%[1]s:%[2]d:9:     from &T{...} (spill) at %[1]s:%[2]d:9
+%[1]s:%[2]d:9:     from &T{...} (interface-converted) at %[1]s:%[2]d:9
+%[1]s:%[2]d:9:     from sink = &T{...} (assign) at %[1]s:%[2]d:7
+%[1]s:%[2]d:15: x escapes to heap in %[3]s:
+%[1]s:%[2]d:15:   flow: {storage for &T{...}} ← &{storage for x}:
+%[1]s:%[2]d:15:     from x (spill) at %[1]s:%[2]d:15
+%[1]s:%[2]d:15:     from T{...} (struct literal element) at %[1]s:%[2]d:11
+%[1]s:%[2]d:9: &T{...} escapes to heap
+%[1]s:%[2]d:15: x escapes to heap
+`,
+	// A pointer to a local returned
+	`%[1]s:%[2]d:2: u escapes to heap in %[3]s:
+%[1]s:%[2]d:2:   flow: ~r0 ← &u:
+%[1]s:%[2]d:2:     from &u (address-of) at %[1]s:%[2]d:9
+%[1]s:%[2]d:2:     from return &u (return) at %[1]s:%[2]d:2
+%[1]s:%[2]d:2: moved to heap: u
+`,
+	// A leaking parameter stored in a global
+	`%[1]s:%[2]d:12: parameter p leaks to {heap} with derefs=0:
+%[1]s:%[2]d:12:   flow: {heap} ← p:
+%[1]s:%[2]d:12:     from global = p (assign) at %[1]s:%[2]d:30
+%[1]s:%[2]d:12: leaking param: p
+%[1]s:%[2]d:6: can inline %[3]s with cost 4 as: func(*int) { global = p }
+`,
+	// A slice of unknown size
+	`%[1]s:%[2]d:13: make([]byte, n) escapes to heap in %[3]s:
+%[1]s:%[2]d:13:   flow: {heap} ← &{storage for make([]byte, n)}:
+%[1]s:%[2]d:13:     from make([]byte, n) (non-constant size) at %[1]s:%[2]d:13
+%[1]s:%[2]d:13: make([]byte, n) escapes to heap
+%[1]s:%[2]d:6: cannot inline %[3]s: function too complex: cost 96 exceeds budget 80
+`,
+	// Values that stay on the stack
+	`%[1]s:%[2]d:6: can inline %[3]s with cost 9 as: func() int { v := 1; return v }
+%[1]s:%[2]d:2: buf does not escape
+%[1]s:%[2]d:17: []int{...} does not escape
+`,
+}
+
+// CompilerOutput returns at least lines lines of compiler output, from
+// packages of filesPerPackage files with functionsPerFile functions each,
+// cycling through escapes of every kind
+func CompilerOutput(lines int) string {
+	var b strings.Builder
+	written, fn := 0, 0
+	for written < lines {
+		pkg, file := fn/(functionsPerFile*filesPerPackage), fn/functionsPerFile%filesPerPackage
+		if fn%(functionsPerFile*filesPerPackage) == 0 {
+			fmt.Fprintf(&b, "# example.com/synth/pkg%d\n", pkg)
+			written++
+		}
+		path := fmt.Sprintf("./pkg%d/file%d.go", pkg, file)
+		block := fmt.Sprintf(templates[fn%len(templates)], path, 10+fn%functionsPerFile*20, fmt.Sprintf("fn%d", fn))
+		b.WriteString(block)
+		written += strings.Count(block, "\n")
+		fn++
+	}
+	return b.String()
+}
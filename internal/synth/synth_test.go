@@ -0,0 +1,21 @@
+package synth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompilerOutput(t *testing.T) {
+	for _, lines := range []int{1, 1000, 100_000} {
+		out := CompilerOutput(lines)
+		if n := strings.Count(out, "\n"); n < lines || n > lines+len(strings.Split(templates[0], "\n")) {
+			t.Errorf("CompilerOutput(%d) has %d lines", lines, n)
+		}
+		if !strings.HasPrefix(out, "# example.com/synth/pkg0\n") {
+			t.Errorf("CompilerOutput(%d) does not start with a package header", lines)
+		}
+	}
+	if CompilerOutput(5000) != CompilerOutput(5000) {
+		t.Error("CompilerOutput is not deterministic")
+	}
+}
@@ -0,0 +1,213 @@
+// Package testreport joins the `go test -json` stream of a suite with the
+// growth guard measured in each test, to rank the tests by the heap and
+// goroutines they leave behind.
+package testreport
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Env names the environment variable testreport sets to a file, to which
+// guard appends a Growth line for every verification of a named test
+const Env = "HEAPCHECK_TEST_REPORT"
+
+// Growth is what one guard verification of a test measured
+type Growth struct {
+	Time            time.Time `json:"time"`
+	Package         string    `json:"package,omitempty"`
+	Test            string    `json:"test"`
+	Goroutines      int       `json:"goroutines"`
+	HeapGrowthBytes int64     `json:"heapGrowthBytes"`      // of HeapMetric
+	HeapMetric      string    `json:"heapMetric,omitempty"` // empty for HeapAlloc
+	HeapObjects     int64     `json:"heapObjects,omitempty"`
+	Mallocs         uint64    `json:"mallocs,omitempty"`
+	Exceeded        bool      `json:"exceeded,omitempty"`
+}
+
+// Test is what the suite measured in one test. A test verified more than
+// once, e.g. with Checkpoint, has the largest growth of each kind.
+type Test struct {
+	Package         string  `json:"package"`
+	Name            string  `json:"test"`
+	Status          string  `json:"status,omitempty"` // pass, fail or skip, per go test
+	Elapsed         float64 `json:"elapsedSeconds"`
+	Verifications   int     `json:"verifications"`
+	Goroutines      int     `json:"goroutines"`
+	HeapGrowthBytes int64   `json:"heapGrowthBytes"`
+	HeapMetric      string  `json:"heapMetric,omitempty"`
+	HeapObjects     int64   `json:"heapObjects,omitempty"`
+	Mallocs         uint64  `json:"mallocs,omitempty"`
+	Exceeded        bool    `json:"exceeded,omitempty"` // over a guard limit
+}
+
+// Report is the suite's tests that guard verified, and a count of those
+// it did not
+type Report struct {
+	Tests      []Test `json:"tests"`
+	Packages   int    `json:"packages"`
+	Unmeasured int    `json:"unmeasured"` // tests that ran without a guard verification
+}
+
+// Sort orders, by the growth they rank tests on
+var Sort = map[string]func(a, b Test) bool{
+	"heap":       func(a, b Test) bool { return a.HeapGrowthBytes > b.HeapGrowthBytes },
+	"goroutines": func(a, b Test) bool { return a.Goroutines > b.Goroutines },
+	"mallocs":    func(a, b Test) bool { return a.Mallocs > b.Mallocs },
+	"time":       func(a, b Test) bool { return a.Elapsed > b.Elapsed },
+}
+
+// event is the part of a test2json event the report reads
+type event struct {
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+}
+
+type key struct{ pkg, test string }
+
+// Build reads a `go test -json` stream and the Growth lines guard wrote
+// to $HEAPCHECK_TEST_REPORT during it, and returns the tests
+// sorted by order, one of the keys of Sort
+func Build(events, growth io.Reader, order string) (*Report, error) {
+	less, ok := Sort[order]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort order %q (want heap, goroutines, mallocs or time)", order)
+	}
+
+	type result struct {
+		status  string
+		elapsed float64
+	}
+	results := make(map[key]result)
+	packages := make(map[string]bool)
+	scanner := bufio.NewScanner(events)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e event
+		// go test interleaves build output that is not JSON
+		if json.Unmarshal(scanner.Bytes(), &e) != nil || e.Package == "" {
+			continue
+		}
+		packages[e.Package] = true
+		if e.Test == "" {
+			continue
+		}
+		switch e.Action {
+		case "pass", "fail", "skip":
+			results[key{e.Package, e.Test}] = result{status: e.Action, elapsed: e.Elapsed}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading go test output: %w", err)
+	}
+
+	measured := make(map[key]*Test)
+	dec := json.NewDecoder(growth)
+	for {
+		var g Growth
+		if err := dec.Decode(&g); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("reading $%s: %w", Env, err)
+		}
+		k := key{g.Package, g.Test}
+		t := measured[k]
+		if t == nil {
+			t = &Test{Package: g.Package, Name: g.Test, HeapMetric: g.HeapMetric}
+			measured[k] = t
+		}
+		t.Verifications++
+		t.Goroutines = max(t.Goroutines, g.Goroutines)
+		t.HeapGrowthBytes = max(t.HeapGrowthBytes, g.HeapGrowthBytes)
+		t.HeapObjects = max(t.HeapObjects, g.HeapObjects)
+		t.Mallocs = max(t.Mallocs, g.Mallocs)
+		t.Exceeded = t.Exceeded || g.Exceeded
+	}
+
+	r := &Report{Packages: len(packages)}
+	for k, t := range measured {
+		if res, ok := results[k]; ok {
+			t.Status, t.Elapsed = res.status, res.elapsed
+		}
+		r.Tests = append(r.Tests, *t)
+	}
+	for k := range results {
+		if measured[k] == nil {
+			r.Unmeasured++
+		}
+	}
+	sort.Slice(r.Tests, func(i, j int) bool {
+		a, b := r.Tests[i], r.Tests[j]
+		if less(a, b) != less(b, a) {
+			return less(a, b)
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Name < b.Name
+	})
+	return r, nil
+}
+
+// WriteText writes the top tests of the report as a ranked table, all of
+// them when top is 0
+func WriteText(w io.Writer, r *Report, top int) error {
+	if len(r.Tests) == 0 {
+		fmt.Fprintf(w, "No test verified with guard in %d package(s); %d test(s) ran without it.\n", r.Packages, r.Unmeasured)
+		fmt.Fprintln(w, "Add defer guard.VerifyNone(t) to the tests to measure.")
+		return nil
+	}
+	fmt.Fprintf(w, "%d test(s) verified with guard in %d package(s)", len(r.Tests), r.Packages)
+	if r.Unmeasured > 0 {
+		fmt.Fprintf(w, ", %d test(s) ran without it", r.Unmeasured)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RANK\tTEST\tPACKAGE\tHEAP\tGOROUTINES\tMALLOCS\tTIME\tSTATUS")
+	var total int64
+	for i, t := range r.Tests {
+		total += max(t.HeapGrowthBytes, 0)
+		if top > 0 && i >= top {
+			continue
+		}
+		status := t.Status
+		if t.Exceeded {
+			status += " (over limit)"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%+d\t%d\t%.2fs\t%s\n",
+			i+1, t.Name, t.Package, formatBytes(t.HeapGrowthBytes), t.Goroutines, t.Mallocs, t.Elapsed, strings.TrimSpace(status))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if top > 0 && len(r.Tests) > top {
+		fmt.Fprintf(w, "... and %d more\n", len(r.Tests)-top)
+	}
+	fmt.Fprintf(w, "\nTotal heap growth: %s across the verified tests\n", formatBytes(total))
+	return nil
+}
+
+// formatBytes renders a signed byte count in the largest fitting unit
+func formatBytes(n int64) string {
+	v, unit := float64(n), "B"
+	for _, u := range []string{"KB", "MB", "GB"} {
+		if v < 1024 && v > -1024 {
+			break
+		}
+		v, unit = v/1024, u
+	}
+	if unit == "B" {
+		return fmt.Sprintf("%+d B", n)
+	}
+	return fmt.Sprintf("%+.1f %s", v, unit)
+}
@@ -0,0 +1,105 @@
+package testreport
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const events = `# example.com/a
+{"Action":"run","Package":"example.com/a","Test":"TestSmall"}
+{"Action":"pass","Package":"example.com/a","Test":"TestSmall","Elapsed":0.01}
+{"Action":"pass","Package":"example.com/a","Test":"TestBig","Elapsed":1.5}
+{"Action":"fail","Package":"example.com/b","Test":"TestLeaky","Elapsed":0.2}
+{"Action":"pass","Package":"example.com/b","Test":"TestPlain","Elapsed":0.1}
+{"Action":"pass","Package":"example.com/b","Elapsed":2}
+`
+
+const growth = `{"package":"example.com/a","test":"TestSmall","goroutines":0,"heapGrowthBytes":2048}
+{"package":"example.com/a","test":"TestBig","goroutines":0,"heapGrowthBytes":1048576,"mallocs":500}
+{"package":"example.com/a","test":"TestBig","goroutines":0,"heapGrowthBytes":52428800,"mallocs":9000}
+{"package":"example.com/b","test":"TestLeaky","goroutines":3,"heapGrowthBytes":4096,"exceeded":true}
+`
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		order string
+		want  []string
+	}{
+		{"heap", []string{"TestBig", "TestLeaky", "TestSmall"}},
+		{"goroutines", []string{"TestLeaky", "TestBig", "TestSmall"}},
+		{"time", []string{"TestBig", "TestLeaky", "TestSmall"}},
+	}
+	for _, tt := range tests {
+		r, err := Build(strings.NewReader(events), strings.NewReader(growth), tt.order)
+		if err != nil {
+			t.Fatalf("Build(%s): %v", tt.order, err)
+		}
+		var got []string
+		for _, test := range r.Tests {
+			got = append(got, test.Name)
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("Build(%s) order = %v, want %v", tt.order, got, tt.want)
+		}
+		if r.Packages != 2 || r.Unmeasured != 1 {
+			t.Errorf("Packages, Unmeasured = %d, %d, want 2, 1", r.Packages, r.Unmeasured)
+		}
+	}
+
+	r, _ := Build(strings.NewReader(events), strings.NewReader(growth), "heap")
+	big := r.Tests[0]
+	if big.Verifications != 2 || big.HeapGrowthBytes != 52428800 || big.Mallocs != 9000 || big.Status != "pass" || big.Elapsed != 1.5 {
+		t.Errorf("TestBig = %+v, want the largest of its two verifications", big)
+	}
+	if leaky := r.Tests[1]; !leaky.Exceeded || leaky.Status != "fail" {
+		t.Errorf("TestLeaky = %+v", leaky)
+	}
+
+	if _, err := Build(strings.NewReader(events), strings.NewReader(growth), "size"); err == nil {
+		t.Error("Build accepted an unknown sort order")
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	r, err := Build(strings.NewReader(events), strings.NewReader(growth), "heap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteText(&buf, r, 2); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"3 test(s) verified with guard in 2 package(s), 1 test(s) ran without it",
+		"+50.0 MB",
+		"fail (over limit)",
+		"... and 1 more",
+		"Total heap growth: +50.0 MB",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "TestSmall") {
+		t.Errorf("output lists more than the top 2:\n%s", out)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "+0 B"},
+		{512, "+512 B"},
+		{-2048, "-2.0 KB"},
+		{3 << 30, "+3.0 GB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
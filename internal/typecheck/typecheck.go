@@ -0,0 +1,223 @@
+// Package typecheck parses and type-checks the packages that escapes were
+// reported in, importing their dependencies from the export data `go list
+// -export` builds, so analyses can resolve the types behind an escape.
+package typecheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/harshakonda/heapcheck/internal/buildctx"
+)
+
+// Package is a parsed and type-checked package
+type Package struct {
+	Path  string
+	Files []*ast.File
+	Info  *types.Info
+}
+
+// listedPackage is the subset of `go list -json` output used here
+type listedPackage struct {
+	ImportPath string
+	Dir        string
+	GoFiles    []string
+	Export     string
+	DepOnly    bool
+}
+
+// Check type-checks the packages matching patterns that contain at least
+// one of files (absolute paths). Type errors are tolerated: partial
+// information still resolves most expressions.
+func Check(fset *token.FileSet, patterns []string, files map[string]bool) ([]*Package, error) {
+	listed, err := listPackages(patterns)
+	if err != nil {
+		return nil, err
+	}
+	exports := make(map[string]string)
+	for _, p := range listed {
+		exports[p.ImportPath] = p.Export
+	}
+	imp := importer.ForCompiler(fset, "gc", func(path string) (io.ReadCloser, error) {
+		export, ok := exports[path]
+		if !ok || export == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(export)
+	})
+
+	var pkgs []*Package
+	for _, p := range listed {
+		if p.DepOnly || !hasFiles(p, files) {
+			continue
+		}
+		pkgs = append(pkgs, checkPackage(fset, p, imp))
+	}
+	return pkgs, nil
+}
+
+// Memo, when set, runs the go list commands of Check, so a long-running
+// process can reuse their output while the sources are unchanged
+var Memo func(args []string, run func() ([]byte, error)) ([]byte, error)
+
+// listPackages runs `go list -export -deps -json` for patterns
+func listPackages(patterns []string) ([]listedPackage, error) {
+	args := append([]string{"list", "-export", "-deps", "-json"}, patterns...)
+	run := func() ([]byte, error) {
+		cmd := exec.Command("go", args...)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("go list: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+		}
+		return stdout.Bytes(), nil
+	}
+	var out []byte
+	var err error
+	if Memo != nil {
+		out, err = Memo(args, run)
+	} else {
+		out, err = run()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []listedPackage
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for dec.More() {
+		var p listedPackage
+		if err := dec.Decode(&p); err != nil {
+			return nil, fmt.Errorf("decoding go list output: %w", err)
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
+
+func hasFiles(p listedPackage, files map[string]bool) bool {
+	for _, name := range p.GoFiles {
+		if files[filepath.Join(p.Dir, name)] {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPackage parses and type-checks one package, skipping files that
+// do not parse
+func checkPackage(fset *token.FileSet, p listedPackage, imp types.Importer) *Package {
+	pkg := &Package{
+		Path: p.ImportPath,
+		Info: &types.Info{
+			Types: make(map[ast.Expr]types.TypeAndValue),
+			Defs:  make(map[*ast.Ident]types.Object),
+			Uses:  make(map[*ast.Ident]types.Object),
+
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		},
+	}
+	for _, name := range p.GoFiles {
+		path := filepath.Join(p.Dir, name)
+		src, err := buildctx.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+		if err == nil {
+			pkg.Files = append(pkg.Files, f)
+		}
+	}
+	conf := types.Config{Importer: imp, Error: func(error) {}}
+	conf.Check(p.ImportPath, fset, pkg.Files, pkg.Info)
+	return pkg
+}
+
+// Contains reports whether line:col falls within [start, end)
+func Contains(start, end token.Position, line, col int) bool {
+	if line < start.Line || line > end.Line {
+		return false
+	}
+	if line == start.Line && col < start.Column {
+		return false
+	}
+	if line == end.Line && col >= end.Column {
+		return false
+	}
+	return true
+}
+
+// Pos returns the position of line:col in f, parsed into fset, or
+// token.NoPos when it is outside the file
+func Pos(fset *token.FileSet, f *ast.File, line, col int) token.Pos {
+	tf := fset.File(f.Pos())
+	if tf == nil || line < 1 || line > tf.LineCount() || col < 1 {
+		return token.NoPos
+	}
+	p := tf.LineStart(line) + token.Pos(col-1)
+	if int(p) > tf.Base()+tf.Size() {
+		return token.NoPos
+	}
+	return p
+}
+
+// EnclosingFunc returns the function declaration of f containing p, or
+// nil outside functions
+func EnclosingFunc(f *ast.File, p token.Pos) *ast.FuncDecl {
+	for _, d := range f.Decls {
+		if fd, ok := d.(*ast.FuncDecl); ok && fd.Pos() <= p && p < fd.End() {
+			return fd
+		}
+	}
+	return nil
+}
+
+// FuncName names a function declaration the way the compiler does, e.g.
+// "Run", "(*Pool).Start" or "Pool.Len", without type parameters
+func FuncName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return decl.Name.Name
+	}
+	recv, ptr := decl.Recv.List[0].Type, false
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv, ptr = star.X, true
+	}
+	switch t := recv.(type) {
+	case *ast.IndexExpr:
+		recv = t.X
+	case *ast.IndexListExpr:
+		recv = t.X
+	}
+	if ptr {
+		return fmt.Sprintf("(*%s).%s", types.ExprString(recv), decl.Name.Name)
+	}
+	return fmt.Sprintf("%s.%s", types.ExprString(recv), decl.Name.Name)
+}
+
+// Callee resolves the function or method a call expression refers to
+func Callee(info *types.Info, fun ast.Expr) *types.Func {
+	var obj types.Object
+	switch f := ast.Unparen(fun).(type) {
+	case *ast.Ident:
+		obj = info.Uses[f]
+	case *ast.SelectorExpr:
+		obj = info.Uses[f.Sel]
+	case *ast.IndexExpr:
+		return Callee(info, f.X)
+	case *ast.IndexListExpr:
+		return Callee(info, f.X)
+	}
+	fn, _ := obj.(*types.Func)
+	return fn
+}
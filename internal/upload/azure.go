@@ -0,0 +1,62 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// AzureSASEnv holds the shared access signature that authorizes writes
+// to the container, the query string of a SAS URL
+const AzureSASEnv = "AZURE_STORAGE_SAS_TOKEN"
+
+// azureUploader puts block blobs with the Blob Storage REST API
+type azureUploader struct {
+	account   string
+	container string
+	prefix    string
+	sas       string
+	endpoint  string // https://<account>.blob.core.windows.net
+	client    *http.Client
+}
+
+// newAzure returns the uploader for azblob://account/container/prefix,
+// authorized with the SAS token in $AZURE_STORAGE_SAS_TOKEN
+func newAzure(dest *url.URL) (Uploader, error) {
+	container, prefix, _ := strings.Cut(strings.TrimPrefix(dest.Path, "/"), "/")
+	if container == "" {
+		return nil, fmt.Errorf("invalid upload destination %s (want azblob://account/container/path)", dest)
+	}
+	sas := strings.TrimPrefix(os.Getenv(AzureSASEnv), "?")
+	if sas == "" {
+		return nil, fmt.Errorf("uploading to %s needs a SAS token in $%s", dest, AzureSASEnv)
+	}
+	return &azureUploader{
+		account:   dest.Host,
+		container: container,
+		prefix:    prefix,
+		sas:       sas,
+		endpoint:  "https://" + dest.Host + ".blob.core.windows.net",
+		client:    &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (a *azureUploader) Put(ctx context.Context, name string, body []byte, contentType string, metadata map[string]string) error {
+	key := join(a.prefix, name)
+	target := fmt.Sprintf("%s/%s/%s?%s", a.endpoint, a.container, uriEncode(key), a.sas)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Ms-Blob-Type", "BlockBlob")
+	req.Header.Set("X-Ms-Version", "2021-08-06")
+	for k, v := range metadata {
+		req.Header.Set("X-Ms-Meta-"+k, v)
+	}
+	return send(a.client, req, "azblob://"+a.account+"/"+a.container+"/"+key)
+}
@@ -0,0 +1,58 @@
+package upload
+
+import (
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/internal/checkrun"
+	"github.com/harshakonda/heapcheck/internal/gitcmd"
+)
+
+// CurrentCommit returns the commit checked out in the current directory.
+// In GitHub Actions, whose checkouts are detached, it takes the
+// repository, commit and branch from the job's environment, with the
+// head of the pull request as the commit, as check runs do; elsewhere
+// from git, with the repository named by the origin remote.
+func CurrentCommit() Commit {
+	c := Commit{Branch: firstEnv("GITHUB_HEAD_REF", "GITHUB_REF_NAME")}
+	if sha, err := checkrun.HeadSHA(); err == nil {
+		c.SHA = sha
+	}
+	if repo := os.Getenv("GITHUB_REPOSITORY"); repo != "" {
+		host := "github.com"
+		if u, err := url.Parse(os.Getenv("GITHUB_SERVER_URL")); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		c.Repository = host + "/" + repo
+	}
+	if c.SHA == "" {
+		c.SHA = gitcmd.Output("rev-parse", "HEAD")
+	}
+	if c.Branch == "" {
+		c.Branch = gitcmd.Branch()
+	}
+	if c.Repository == "" {
+		c.Repository = repository(gitcmd.Output("remote", "get-url", "origin"))
+	}
+	return c
+}
+
+// repository names a repository by its remote URL without scheme, user
+// and .git suffix, e.g. github.com/org/repo for both
+// git@github.com:org/repo.git and https://github.com/org/repo
+func repository(remote string) string {
+	if remote == "" {
+		return ""
+	}
+	if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		remote = u.Host + u.Path
+	} else if host, path, ok := strings.Cut(remote, ":"); ok {
+		// scp-like syntax, user@host:path
+		if _, h, ok := strings.Cut(host, "@"); ok {
+			host = h
+		}
+		remote = host + "/" + strings.TrimPrefix(path, "/")
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(remote, "/"), ".git")
+}
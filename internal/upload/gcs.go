@@ -0,0 +1,54 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// GCSTokenEnv holds the OAuth 2.0 access token for Google Cloud Storage,
+// e.g. from `gcloud auth print-access-token`
+const GCSTokenEnv = "GOOGLE_OAUTH_ACCESS_TOKEN"
+
+// gcsUploader puts objects with the Cloud Storage XML API
+type gcsUploader struct {
+	bucket   string
+	prefix   string
+	token    string
+	endpoint string
+	client   *http.Client
+}
+
+// newGCS returns the uploader for gs://bucket/prefix, authorized with
+// the token in $GOOGLE_OAUTH_ACCESS_TOKEN
+func newGCS(dest *url.URL) (Uploader, error) {
+	token := os.Getenv(GCSTokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("uploading to %s needs an access token in $%s, e.g. from gcloud auth print-access-token", dest, GCSTokenEnv)
+	}
+	return &gcsUploader{
+		bucket:   dest.Host,
+		prefix:   dest.Path,
+		token:    token,
+		endpoint: "https://storage.googleapis.com",
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (g *gcsUploader) Put(ctx context.Context, name string, body []byte, contentType string, metadata map[string]string) error {
+	key := join(g.prefix, name)
+	target := fmt.Sprintf("%s/%s/%s", g.endpoint, g.bucket, uriEncode(key))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range metadata {
+		req.Header.Set("X-Goog-Meta-"+k, v)
+	}
+	return send(g.client, req, "gs://"+g.bucket+"/"+key)
+}
@@ -0,0 +1,180 @@
+// Package upload copies the artifacts of a run to cloud object storage:
+// Amazon S3 (s3://bucket/path), Google Cloud Storage (gs://bucket/path)
+// and Azure Blob Storage (azblob://account/container/path).
+//
+// Requests go to the storages' REST APIs with credentials from the usual
+// environment variables, so no cloud SDK is needed. Other schemes are
+// handed to an executable named heapcheck-upload-<scheme> on $PATH.
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Object is a file to upload, named relative to the destination
+type Object struct {
+	Name        string
+	Body        []byte
+	ContentType string
+}
+
+// Commit identifies the code a run analyzed. It is attached to every
+// object as metadata and fills the {repo}, {commit} and {branch}
+// placeholders of a destination.
+type Commit struct {
+	Repository string `json:"repository,omitempty"` // e.g. github.com/org/repo
+	SHA        string `json:"commit,omitempty"`
+	Branch     string `json:"branch,omitempty"`
+}
+
+// metadata returns the commit as object metadata, leaving out unknowns
+func (c Commit) metadata() map[string]string {
+	m := make(map[string]string)
+	for k, v := range map[string]string{"repository": c.Repository, "commit": c.SHA, "branch": c.Branch} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// Uploader stores objects under a destination
+type Uploader interface {
+	// Put stores body as name, relative to the destination, with the
+	// content type and metadata given
+	Put(ctx context.Context, name string, body []byte, contentType string, metadata map[string]string) error
+}
+
+// Factory returns the uploader for a destination URL of its scheme, or
+// an error when it is malformed or credentials are missing
+type Factory func(dest *url.URL) (Uploader, error)
+
+var factories = map[string]Factory{
+	"s3":     newS3,
+	"gs":     newGCS,
+	"azblob": newAzure,
+}
+
+// Register makes New use f for destinations of scheme, replacing the
+// built-in uploader or the executable for it
+func Register(scheme string, f Factory) {
+	factories[scheme] = f
+}
+
+// pluginPrefix names the executables that upload to other schemes
+const pluginPrefix = "heapcheck-upload-"
+
+// timeout bounds each request to a storage
+const timeout = time.Minute
+
+// New returns the uploader for dest, e.g. s3://bucket/heapcheck/{repo},
+// with the placeholders of dest filled from commit
+func New(dest string, commit Commit) (Uploader, error) {
+	dest = Expand(dest, commit)
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid upload destination %q (want e.g. s3://bucket/path, gs://bucket/path or azblob://account/container/path)", dest)
+	}
+	if f, ok := factories[u.Scheme]; ok {
+		return f(u)
+	}
+	plugin, err := exec.LookPath(pluginPrefix + u.Scheme)
+	if err != nil {
+		return nil, fmt.Errorf("no uploader for %s:// destinations (built in: s3, gs, azblob; or put %s%s on $PATH)", u.Scheme, pluginPrefix, u.Scheme)
+	}
+	return &execUploader{path: plugin, dest: u}, nil
+}
+
+// Expand fills the {repo}, {commit} and {branch} placeholders of dest.
+// Unknown values expand to "unknown", so objects do not land in an empty
+// path segment.
+func Expand(dest string, commit Commit) string {
+	or := func(s string) string {
+		if s == "" {
+			return "unknown"
+		}
+		return s
+	}
+	return strings.NewReplacer(
+		"{repo}", or(commit.Repository),
+		"{commit}", or(commit.SHA),
+		"{branch}", or(commit.Branch),
+	).Replace(dest)
+}
+
+// Upload stores objects under dest, each with the commit as metadata
+func Upload(ctx context.Context, dest string, commit Commit, objects []Object) error {
+	u, err := New(dest, commit)
+	if err != nil {
+		return err
+	}
+	metadata := commit.metadata()
+	for _, obj := range objects {
+		if err := u.Put(ctx, obj.Name, obj.Body, obj.ContentType, metadata); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// join appends name to the key prefix of a destination path
+func join(prefix, name string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return name
+	}
+	return prefix + "/" + name
+}
+
+// send performs a request and turns a non-2xx response into an error
+func send(client *http.Client, req *http.Request, dest string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("uploading to %s: %w", dest, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("uploading to %s: %s: %s", dest, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// execUploader hands each object to a heapcheck-upload-<scheme>
+// executable, as `heapcheck-upload-<scheme> <object URL>` with the body on
+// stdin, the content type in $HEAPCHECK_UPLOAD_CONTENT_TYPE and each
+// metadata key in $HEAPCHECK_UPLOAD_META_<KEY>
+type execUploader struct {
+	path string
+	dest *url.URL
+}
+
+func (e *execUploader) Put(ctx context.Context, name string, body []byte, contentType string, metadata map[string]string) error {
+	target := *e.dest
+	target.Path = "/" + join(e.dest.Path, name)
+	cmd := exec.CommandContext(ctx, e.path, target.String())
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(), "HEAPCHECK_UPLOAD_CONTENT_TYPE="+contentType)
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cmd.Env = append(cmd.Env, "HEAPCHECK_UPLOAD_META_"+strings.ToUpper(k)+"="+metadata[k])
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("uploading to %s: %s: %w\n%s", target.String(), e.path, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// debugServerFrame is in the stack of the goroutine serving a
+// DebugServer and of those running its handlers
+const debugServerFrame = "heapcheck/runtime.(*DebugServer)"
+
+// DebugServer serves the runtime state of a test over HTTP while it runs,
+// for attaching to a long or hanging test: the diff against a snapshot,
+// with the stacks of leaked goroutines, a fresh snapshot, and the
+// net/http/pprof endpoints.
+//
+//	srv, err := runtime.ServeDebug("localhost:6060")
+//	if err != nil {
+//	    t.Fatal(err)
+//	}
+//	defer srv.Close()
+//	t.Logf("debug endpoints at http://%s/debug/heapcheck/", srv.Addr())
+type DebugServer struct {
+	srv  *http.Server
+	addr net.Addr
+	done chan struct{}
+
+	mu       sync.Mutex
+	snapshot *Snapshot
+}
+
+// ServeDebug takes a snapshot and serves the debug endpoints on addr,
+// e.g. "localhost:6060", or "localhost:0" for a free port, until Close:
+//
+//	/debug/heapcheck/diff      the diff against the snapshot, as Diff.Format(true)
+//	/debug/heapcheck/snapshot  the current goroutine and heap counts, as JSON
+//	/debug/pprof/              the net/http/pprof profiles
+//
+// The server's own goroutines, and those serving its connections, are left
+// out of its diffs.
+func ServeDebug(addr string) (*DebugServer, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("serving debug endpoints: %w", err)
+	}
+	s := &DebugServer{
+		addr:     l.Addr(),
+		done:     make(chan struct{}),
+		snapshot: TakeSnapshot(),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/heapcheck/", s.index)
+	mux.HandleFunc("/debug/heapcheck/diff", s.diff)
+	mux.HandleFunc("/debug/heapcheck/snapshot", s.current)
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	s.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	// Connections close after each request, so that no idle connection
+	// goroutine outlives it
+	s.srv.SetKeepAlivesEnabled(false)
+
+	go s.serve(l)
+	return s, nil
+}
+
+// serve runs in its own goroutine, whose stack has debugServerFrame
+func (s *DebugServer) serve(l net.Listener) {
+	defer close(s.done)
+	if err := s.srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "heapcheck: debug server: %v\n", err)
+	}
+}
+
+// Addr returns the address the server listens on, with the port chosen
+// for a ":0" address
+func (s *DebugServer) Addr() string {
+	return s.addr.String()
+}
+
+// Watch makes the diff endpoint compare against snapshot, e.g. one taken
+// at the start of the phase of the test under inspection
+func (s *DebugServer) Watch(snapshot *Snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshot = snapshot
+}
+
+// Close stops the server and waits for its goroutines to exit, so that
+// they are not reported as leaks of the test
+func (s *DebugServer) Close() error {
+	err := s.srv.Close()
+	<-s.done
+	return err
+}
+
+func (s *DebugServer) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/debug/heapcheck/" {
+		http.NotFound(w, r)
+		return
+	}
+	fmt.Fprint(w, `heapcheck debug endpoints
+
+/debug/heapcheck/diff      goroutines, heap and allocations since the snapshot, with leaked goroutine stacks
+/debug/heapcheck/snapshot  current goroutine and heap counts, as JSON
+/debug/pprof/              profiles: goroutine, heap, allocs, block, mutex, CPU and trace
+`)
+}
+
+func (s *DebugServer) diff(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	snapshot := s.snapshot
+	s.mu.Unlock()
+
+	d := snapshot.CompareWith(nil)
+	own := serverGoroutines(captureGoroutines())
+	leaked := d.LeakedGoroutines[:0]
+	for _, g := range d.LeakedGoroutines {
+		if !own[g.ID] {
+			leaked = append(leaked, g)
+		}
+	}
+	d.LeakedGoroutines, d.ByState = leaked, CountByState(leaked)
+	for id := range own {
+		if !snapshot.GoroutineIDs[id] {
+			d.GoroutineGrowth--
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, d.Format(true))
+}
+
+// serverGoroutines returns the IDs of the goroutines of debug servers:
+// those with debugServerFrame in their stack, and those they started,
+// such as connections and their background reads
+func serverGoroutines(goroutines []GoroutineInfo) map[int]bool {
+	own := make(map[int]bool)
+	for _, g := range goroutines {
+		if strings.Contains(g.Stack, debugServerFrame) {
+			own[g.ID] = true
+		}
+	}
+	for grew := true; grew; {
+		grew = false
+		for _, g := range goroutines {
+			if !own[g.ID] && own[g.CreatorID] {
+				own[g.ID] = true
+				grew = true
+			}
+		}
+	}
+	return own
+}
+
+// debugSnapshot is the JSON of the snapshot endpoint
+type debugSnapshot struct {
+	Time          time.Time `json:"time"`
+	Goroutines    int       `json:"goroutines"`
+	HeapAllocated uint64    `json:"heapAllocated"`
+	HeapObjects   uint64    `json:"heapObjects"`
+	Mallocs       uint64    `json:"mallocs"`
+	Frees         uint64    `json:"frees"`
+	CgoCalls      int64     `json:"cgoCalls"`
+}
+
+func (s *DebugServer) current(w http.ResponseWriter, r *http.Request) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(debugSnapshot{
+		Time:          time.Now(),
+		Goroutines:    runtime.NumGoroutine(),
+		HeapAllocated: m.HeapAlloc,
+		HeapObjects:   m.HeapObjects,
+		Mallocs:       m.Mallocs,
+		Frees:         m.Frees,
+		CgoCalls:      runtime.NumCgoCall(),
+	})
+}
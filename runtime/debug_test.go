@@ -0,0 +1,80 @@
+package runtime_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harshakonda/heapcheck/runtime"
+)
+
+func get(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: %s\n%s", url, resp.Status, body)
+	}
+	return string(body)
+}
+
+func blockForever(ch chan struct{}) {
+	<-ch
+}
+
+func TestServeDebug(t *testing.T) {
+	before := runtime.TakeSnapshot()
+	srv, err := runtime.ServeDebug("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := "http://" + srv.Addr()
+
+	stop := make(chan struct{})
+	go blockForever(stop)
+
+	// The client's connection goroutines of this test are in the diffs;
+	// those of the server are not
+	diff := get(t, base+"/debug/heapcheck/diff")
+	if !strings.Contains(diff, "chan receive=1") || !strings.Contains(diff, "blockForever") {
+		t.Errorf("diff does not show the blocked goroutine:\n%s", diff)
+	}
+	if strings.Contains(diff, "net/http.(*conn).serve") || strings.Contains(diff, "created by net/http.(*Server).Serve") {
+		t.Errorf("diff lists the debug server's goroutines:\n%s", diff)
+	}
+
+	srv.Watch(runtime.TakeSnapshot())
+	if diff := get(t, base+"/debug/heapcheck/diff"); strings.Contains(diff, "blockForever") {
+		t.Errorf("diff against a later snapshot lists the blocked goroutine:\n%s", diff)
+	}
+
+	var snapshot struct {
+		Goroutines int `json:"goroutines"`
+	}
+	if err := json.Unmarshal([]byte(get(t, base+"/debug/heapcheck/snapshot")), &snapshot); err != nil || snapshot.Goroutines == 0 {
+		t.Errorf("snapshot = %+v, %v", snapshot, err)
+	}
+	if profile := get(t, base+"/debug/pprof/goroutine?debug=1"); !strings.Contains(profile, "blockForever") {
+		t.Errorf("goroutine profile does not list the blocked goroutine:\n%s", profile)
+	}
+
+	close(stop)
+	if err := srv.Close(); err != nil {
+		t.Fatal(err)
+	}
+	http.DefaultClient.CloseIdleConnections()
+	time.Sleep(50 * time.Millisecond)
+	if d := before.Compare(); len(d.LeakedGoroutines) > 0 {
+		t.Errorf("goroutines left after Close:\n%s", d.Format(true))
+	}
+}
@@ -21,29 +21,40 @@
 //	func TestNoGoroutineLeak(t *testing.T) {
 //	    snapshot := runtime.Snapshot()
 //	    defer snapshot.AssertNoLeak(t)
-//	
+//
 //	    // ... your test code that spawns goroutines ...
 //	}
 package runtime
 
 import (
 	"fmt"
-	"regexp"
+	"math"
 	"runtime"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
+
+	"github.com/harshakonda/heapcheck/runtime/stackparse"
 )
 
 // Snapshot captures the current runtime state for later comparison.
 // Use this at the beginning of a test to establish a baseline.
 type Snapshot struct {
-	Goroutines    int
-	HeapAllocated uint64
-	HeapObjects   uint64
-	Timestamp     time.Time
-	GoroutineIDs  map[int]bool
+	Goroutines     int
+	HeapAllocated  uint64
+	HeapObjects    uint64
+	HeapInuse      uint64 // bytes in in-use heap spans (MemStats.HeapInuse)
+	Sys            uint64 // bytes obtained from the OS (MemStats.Sys)
+	HeapLive       uint64 // bytes the last GC marked live
+	Mallocs        uint64 // cumulative heap allocations (MemStats.Mallocs)
+	Frees          uint64 // cumulative heap frees (MemStats.Frees)
+	CgoCalls       int64  // cumulative cgo calls by the process
+	Threads        int    // OS threads of the process
+	ThreadsCreated int    // cumulative OS threads the Go runtime created
+	Timestamp      time.Time
+	GoroutineIDs   map[int]bool
+
+	trackSeq uint64
 }
 
 // TakeSnapshot captures current runtime state.
@@ -52,13 +63,25 @@ func TakeSnapshot() *Snapshot {
 	var memStats runtime.MemStats
 	runtime.ReadMemStats(&memStats)
 
-	return &Snapshot{
+	s := &Snapshot{
 		Goroutines:    runtime.NumGoroutine(),
 		HeapAllocated: memStats.HeapAlloc,
 		HeapObjects:   memStats.HeapObjects,
+		HeapInuse:     memStats.HeapInuse,
+		Sys:           memStats.Sys,
+		HeapLive:      heapLive(),
 		Timestamp:     time.Now(),
 		GoroutineIDs:  captureGoroutineIDs(),
+		trackSeq:      trackerSeq(),
 	}
+
+	// Count allocations from after the goroutine capture, so its stack
+	// buffer is not attributed to the code under test
+	runtime.ReadMemStats(&memStats)
+	s.Mallocs, s.Frees = memStats.Mallocs, memStats.Frees
+	s.CgoCalls = runtime.NumCgoCall()
+	s.Threads, s.ThreadsCreated = osThreads(), threadsCreated()
+	return s
 }
 
 // Diff represents the difference between two snapshots
@@ -66,36 +89,81 @@ type Diff struct {
 	GoroutineGrowth   int
 	HeapGrowthBytes   int64
 	HeapGrowthObjects int64
-	Duration          time.Duration
-	LeakedGoroutines  []GoroutineInfo
-}
 
-// GoroutineInfo contains information about a goroutine
-type GoroutineInfo struct {
-	ID    int
-	State string
-	Stack string
+	// Growth of the other statistics a HeapMetric can select; see
+	// HeapGrowth
+	HeapInuseGrowthBytes int64
+	SysGrowthBytes       int64
+	HeapLiveGrowthBytes  int64
+
+	// Mallocs and Frees count heap allocations and frees since the
+	// snapshot. Many of both with little heap growth is object churn.
+	Mallocs uint64
+	Frees   uint64
+
+	// CgoCalls counts calls into C since the snapshot. Memory C code
+	// allocates is invisible to the Go heap statistics.
+	CgoCalls int64
+
+	// ThreadGrowth is the change in OS threads since the snapshot, and
+	// ThreadsCreated the threads the Go runtime created meanwhile. Threads
+	// stay behind when goroutines locked to them with runtime.LockOSThread
+	// keep running, or when C code starts them; goroutine counts miss
+	// both. The Go runtime also keeps idle threads for reuse, so some
+	// growth is normal.
+	ThreadGrowth   int
+	ThreadsCreated int
+
+	Duration         time.Duration
+	LeakedGoroutines []GoroutineInfo
+
+	// ByState counts leaked goroutines per state ("chan receive",
+	// "select", "IO wait", "sleep", ...)
+	ByState map[string]int
+
+	// UncollectedObjects lists objects registered with TrackObject after
+	// the snapshot was taken that were still alive at Compare time.
+	UncollectedObjects []TrackedObject
 }
 
+// GoroutineInfo contains information about a goroutine.
+// See the stackparse package for the parsed fields.
+type GoroutineInfo = stackparse.GoroutineInfo
+
 // Compare compares current state against the snapshot.
 // Call this at the end of your test to detect leaks.
 func (s *Snapshot) Compare() *Diff {
-	// Force GC to get accurate heap stats
-	runtime.GC()
-	time.Sleep(10 * time.Millisecond)
+	return s.CompareWith(nil)
+}
 
-	var memStats runtime.MemStats
-	runtime.ReadMemStats(&memStats)
+// CompareWith is like Compare but uses filter to decide which new
+// goroutines are expected. A nil filter uses the built-in patterns.
+func (s *Snapshot) CompareWith(filter *GoroutineFilter) *Diff {
+	// Collect until the heap settles, to get accurate heap stats
+	memStats := StableHeap()
 
-	currentIDs := captureGoroutineIDs()
-	leakedGoroutines := findLeakedGoroutines(s.GoroutineIDs, currentIDs)
+	leakedGoroutines := findLeakedGoroutines(s.GoroutineIDs, captureGoroutines(), filter)
+	attachLabels(leakedGoroutines)
 
 	return &Diff{
 		GoroutineGrowth:   runtime.NumGoroutine() - s.Goroutines,
 		HeapGrowthBytes:   int64(memStats.HeapAlloc) - int64(s.HeapAllocated),
 		HeapGrowthObjects: int64(memStats.HeapObjects) - int64(s.HeapObjects),
-		Duration:          time.Since(s.Timestamp),
-		LeakedGoroutines:  leakedGoroutines,
+		Mallocs:           memStats.Mallocs - s.Mallocs,
+		Frees:             memStats.Frees - s.Frees,
+		CgoCalls:          runtime.NumCgoCall() - s.CgoCalls,
+		ThreadGrowth:      osThreads() - s.Threads,
+		ThreadsCreated:    threadsCreated() - s.ThreadsCreated,
+
+		HeapInuseGrowthBytes: int64(memStats.HeapInuse) - int64(s.HeapInuse),
+		SysGrowthBytes:       int64(memStats.Sys) - int64(s.Sys),
+		HeapLiveGrowthBytes:  int64(heapLive()) - int64(s.HeapLive),
+
+		Duration:         time.Since(s.Timestamp),
+		LeakedGoroutines: leakedGoroutines,
+		ByState:          CountByState(leakedGoroutines),
+
+		UncollectedObjects: uncollectedSince(s.trackSeq),
 	}
 }
 
@@ -121,12 +189,35 @@ func (s *Snapshot) AssertNoLeak(t TestingT) {
 
 // Options configures leak detection behavior
 type Options struct {
-	MaxGoroutineGrowth int           // Maximum allowed goroutine growth (default: 0)
-	MaxHeapGrowthMB    int           // Maximum allowed heap growth in MB (default: 0 = unlimited)
-	SettleTime         time.Duration // Time to wait for goroutines to settle (default: 100ms)
-	RetryCount         int           // Number of retries before failing (default: 3)
+	MaxGoroutineGrowth  int           // Maximum allowed goroutine growth (default: 0)
+	MaxHeapGrowthMB     int           // Maximum allowed heap growth in MB (default: 0 = unlimited)
+	MaxHeapObjectGrowth int           // Maximum allowed growth in live heap objects, for leaks of many small ones (default: 0 = unlimited)
+	HeapMetric          HeapMetric    // Statistic MaxHeapGrowthMB is checked against (default: HeapMetricAlloc)
+	SettleTime          time.Duration // Time to wait for goroutines to settle (default: 100ms)
+	RetryCount          int           // Number of retries before failing (default: 3)
+
+	// ExpectedGoroutines filters goroutines that are not reported as
+	// leaks (default: nil, which uses DefaultGoroutineFilter patterns)
+	ExpectedGoroutines *GoroutineFilter
+
+	// In binaries built with -race, SettleTime is multiplied by
+	// RaceSettleMultiplier, and MaxHeapGrowthMB and MaxHeapObjectGrowth by
+	// RaceHeapMultiplier, as race builds schedule slower and allocate more
+	// (default: 0, which uses DefaultRaceSettleMultiplier and
+	// DefaultRaceHeapMultiplier)
+	RaceSettleMultiplier float64
+	RaceHeapMultiplier   float64
 }
 
+// Default multipliers of settle times and heap limits under -race. The
+// race detector makes programs 2-20x slower; its own memory is outside
+// the Go heap, but sync.Pool drops items at random under it, so code
+// that relies on pooling allocates more.
+const (
+	DefaultRaceSettleMultiplier = 5
+	DefaultRaceHeapMultiplier   = 2
+)
+
 // DefaultOptions returns sensible defaults
 func DefaultOptions() Options {
 	return Options{
@@ -137,9 +228,40 @@ func DefaultOptions() Options {
 	}
 }
 
-// AssertNoLeakWithOptions checks for leaks with custom options
+// ForRace returns the options relaxed for a -race build when race is
+// true, and unchanged otherwise
+func (o Options) ForRace(race bool) Options {
+	if !race {
+		return o
+	}
+	settle, heap := o.RaceSettleMultiplier, o.RaceHeapMultiplier
+	if settle == 0 {
+		settle = DefaultRaceSettleMultiplier
+	}
+	if heap == 0 {
+		heap = DefaultRaceHeapMultiplier
+	}
+	o.SettleTime = ScaleDuration(o.SettleTime, settle)
+	o.MaxHeapGrowthMB = ScaleLimit(o.MaxHeapGrowthMB, heap)
+	o.MaxHeapObjectGrowth = ScaleLimit(o.MaxHeapObjectGrowth, heap)
+	return o
+}
+
+// ScaleDuration multiplies d by f
+func ScaleDuration(d time.Duration, f float64) time.Duration {
+	return time.Duration(float64(d) * f)
+}
+
+// ScaleLimit multiplies a limit by f, rounding up; 0 (unlimited) stays 0
+func ScaleLimit(n int, f float64) int {
+	return int(math.Ceil(float64(n) * f))
+}
+
+// AssertNoLeakWithOptions checks for leaks with custom options, relaxed
+// per Options.ForRace in -race builds
 func (s *Snapshot) AssertNoLeakWithOptions(t TestingT, opts Options) {
 	t.Helper()
+	opts = opts.ForRace(RaceEnabled)
 
 	var diff *Diff
 
@@ -148,11 +270,12 @@ func (s *Snapshot) AssertNoLeakWithOptions(t TestingT, opts Options) {
 		runtime.GC()
 		time.Sleep(opts.SettleTime)
 
-		diff = s.Compare()
+		diff = s.CompareWith(opts.ExpectedGoroutines)
 
 		// Check if within thresholds
-		if diff.GoroutineGrowth <= opts.MaxGoroutineGrowth {
-			if opts.MaxHeapGrowthMB == 0 || diff.HeapGrowthBytes <= int64(opts.MaxHeapGrowthMB)*1024*1024 {
+		if diff.GoroutineGrowth <= opts.MaxGoroutineGrowth && len(diff.UncollectedObjects) == 0 {
+			if (opts.MaxHeapGrowthMB == 0 || diff.HeapGrowth(opts.HeapMetric) <= int64(opts.MaxHeapGrowthMB)*1024*1024) &&
+				(opts.MaxHeapObjectGrowth == 0 || diff.HeapGrowthObjects <= int64(opts.MaxHeapObjectGrowth)) {
 				return // No leak detected
 			}
 		}
@@ -161,56 +284,56 @@ func (s *Snapshot) AssertNoLeakWithOptions(t TestingT, opts Options) {
 	// Still have leaks after retries
 	if diff.GoroutineGrowth > opts.MaxGoroutineGrowth {
 		t.Errorf("goroutine leak detected: grew by %d (max allowed: %d)\n%s",
-			diff.GoroutineGrowth, opts.MaxGoroutineGrowth, formatLeakedGoroutines(diff.LeakedGoroutines))
+			diff.GoroutineGrowth, opts.MaxGoroutineGrowth, diff.Format(true))
 	}
 
-	if opts.MaxHeapGrowthMB > 0 && diff.HeapGrowthBytes > int64(opts.MaxHeapGrowthMB)*1024*1024 {
-		t.Errorf("heap leak detected: grew by %.2f MB (max allowed: %d MB)",
-			float64(diff.HeapGrowthBytes)/1024/1024, opts.MaxHeapGrowthMB)
+	if growth := diff.HeapGrowth(opts.HeapMetric); opts.MaxHeapGrowthMB > 0 && growth > int64(opts.MaxHeapGrowthMB)*1024*1024 {
+		t.Errorf("heap leak detected: %s grew by %.2f MB (max allowed: %d MB)",
+			opts.HeapMetric, float64(growth)/1024/1024, opts.MaxHeapGrowthMB)
 	}
-}
-
-// captureGoroutineIDs returns a set of current goroutine IDs
-func captureGoroutineIDs() map[int]bool {
-	ids := make(map[int]bool)
 
-	buf := make([]byte, 1<<20) // 1MB buffer
-	n := runtime.Stack(buf, true)
-	stackDump := string(buf[:n])
+	if opts.MaxHeapObjectGrowth > 0 && diff.HeapGrowthObjects > int64(opts.MaxHeapObjectGrowth) {
+		t.Errorf("heap object leak detected: grew by %d objects (max allowed: %d), %.2f MB",
+			diff.HeapGrowthObjects, opts.MaxHeapObjectGrowth, float64(diff.HeapGrowthBytes)/1024/1024)
+	}
 
-	// Parse goroutine IDs from stack dump
-	// Format: "goroutine 1 [running]:"
-	pattern := regexp.MustCompile(`goroutine\s+(\d+)\s+\[([^\]]+)\]`)
-	matches := pattern.FindAllStringSubmatch(stackDump, -1)
+	if len(diff.UncollectedObjects) > 0 {
+		t.Errorf("tracked objects not collected (%d):%s",
+			len(diff.UncollectedObjects), FormatUncollected(diff.UncollectedObjects))
+	}
+}
 
-	for _, match := range matches {
-		if id, err := strconv.Atoi(match[1]); err == nil {
-			ids[id] = true
+// captureGoroutines returns the parsed stacks of all current goroutines
+func captureGoroutines() []GoroutineInfo {
+	buf := make([]byte, 1<<20) // 1MB buffer
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return stackparse.ParseStacks(buf[:n])
 		}
+		buf = make([]byte, 2*len(buf))
 	}
+}
 
+// captureGoroutineIDs returns a set of current goroutine IDs
+func captureGoroutineIDs() map[int]bool {
+	ids := make(map[int]bool)
+	for _, g := range captureGoroutines() {
+		ids[g.ID] = true
+	}
 	return ids
 }
 
 // findLeakedGoroutines identifies goroutines that exist now but didn't before
-func findLeakedGoroutines(before, after map[int]bool) []GoroutineInfo {
+func findLeakedGoroutines(before map[int]bool, current []GoroutineInfo, filter *GoroutineFilter) []GoroutineInfo {
 	var leaked []GoroutineInfo
 
-	buf := make([]byte, 1<<20)
-	n := runtime.Stack(buf, true)
-	stackDump := string(buf[:n])
-
-	// Split into individual goroutine stacks
-	stacks := splitGoroutineStacks(stackDump)
-
-	for id := range after {
-		if !before[id] {
+	for _, g := range current {
+		if !before[g.ID] {
 			// This is a new goroutine - potential leak
-			if info := findGoroutineInfo(stacks, id); info != nil {
-				// Filter out expected goroutines
-				if !isExpectedGoroutine(info.Stack) {
-					leaked = append(leaked, *info)
-				}
+			// Filter out expected goroutines
+			if !filter.IsExpected(g.Stack) {
+				leaked = append(leaked, g)
 			}
 		}
 	}
@@ -223,122 +346,59 @@ func findLeakedGoroutines(before, after map[int]bool) []GoroutineInfo {
 	return leaked
 }
 
-// splitGoroutineStacks splits a stack dump into individual goroutine stacks
-func splitGoroutineStacks(dump string) map[int]string {
-	stacks := make(map[int]string)
-	pattern := regexp.MustCompile(`goroutine\s+(\d+)\s+\[([^\]]+)\]`)
-
-	// Find all goroutine headers
-	indices := pattern.FindAllStringSubmatchIndex(dump, -1)
-
-	for i, match := range indices {
-		idStr := dump[match[2]:match[3]]
-		id, _ := strconv.Atoi(idStr)
-
-		// Get stack content until next goroutine or end
-		start := match[0]
-		end := len(dump)
-		if i+1 < len(indices) {
-			end = indices[i+1][0]
-		}
-
-		stacks[id] = dump[start:end]
-	}
-
-	return stacks
-}
-
-// findGoroutineInfo extracts info for a specific goroutine ID
-func findGoroutineInfo(stacks map[int]string, id int) *GoroutineInfo {
-	stack, ok := stacks[id]
-	if !ok {
-		return nil
-	}
-
-	// Extract state from header
-	pattern := regexp.MustCompile(`goroutine\s+\d+\s+\[([^\]]+)\]`)
-	match := pattern.FindStringSubmatch(stack)
-
-	state := "unknown"
-	if match != nil {
-		state = match[1]
-	}
-
-	return &GoroutineInfo{
-		ID:    id,
-		State: state,
-		Stack: stack,
+// CountByState counts goroutines per state
+func CountByState(goroutines []GoroutineInfo) map[string]int {
+	counts := make(map[string]int)
+	for _, g := range goroutines {
+		counts[g.State]++
 	}
+	return counts
 }
 
-// isExpectedGoroutine checks if a goroutine is expected (runtime, testing, etc.)
-func isExpectedGoroutine(stack string) bool {
-	expectedPatterns := []string{
-		"runtime.gopark",
-		"runtime.chanrecv",
-		"runtime.chansend",
-		"testing.(*T).Run",
-		"testing.tRunner",
-		"runtime.main",
-		"runtime.gcBgMarkWorker",
-		"runtime.bgsweep",
-		"runtime.bgscavenge",
-		"runtime.forcegchelper",
-		"runtime.timerproc",
-		"signal.signal_recv",
-		"os/signal.loop",
-		"runtime.runfinq",
-		"runtime.goexit",
+// FormatByState renders state counts as "chan receive=2, select=1",
+// most frequent state first
+func FormatByState(counts map[string]int) string {
+	states := make([]string, 0, len(counts))
+	for state := range counts {
+		states = append(states, state)
 	}
-
-	stackLower := strings.ToLower(stack)
-	for _, pattern := range expectedPatterns {
-		if strings.Contains(stackLower, strings.ToLower(pattern)) {
-			return true
+	sort.Slice(states, func(i, j int) bool {
+		if counts[states[i]] != counts[states[j]] {
+			return counts[states[i]] > counts[states[j]]
 		}
-	}
-
-	return false
-}
-
-// formatLeakedGoroutines formats leaked goroutines for error output
-func formatLeakedGoroutines(leaked []GoroutineInfo) string {
-	if len(leaked) == 0 {
-		return ""
-	}
-
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("\nLeaked goroutines (%d):\n", len(leaked)))
+		return states[i] < states[j]
+	})
 
-	for _, g := range leaked {
-		sb.WriteString(fmt.Sprintf("\n--- Goroutine %d [%s] ---\n", g.ID, g.State))
-		// Truncate stack to first 10 lines for readability
-		lines := strings.Split(g.Stack, "\n")
-		if len(lines) > 12 {
-			lines = append(lines[:12], "    ...")
-		}
-		sb.WriteString(strings.Join(lines, "\n"))
-		sb.WriteString("\n")
+	parts := make([]string, 0, len(states))
+	for _, state := range states {
+		parts = append(parts, fmt.Sprintf("%s=%d", state, counts[state]))
 	}
-
-	return sb.String()
+	return strings.Join(parts, ", ")
 }
 
 // Result holds the complete runtime analysis result
 type Result struct {
-	GoroutineStart int           `json:"goroutineStart"`
-	GoroutineEnd   int           `json:"goroutineEnd"`
-	GoroutineGrowth int          `json:"goroutineGrowth"`
-	GoroutineLeak  bool          `json:"goroutineLeak"`
-	HeapStartBytes uint64        `json:"heapStartBytes"`
-	HeapEndBytes   uint64        `json:"heapEndBytes"`
-	HeapGrowthBytes int64        `json:"heapGrowthBytes"`
-	HeapLeak       bool          `json:"heapLeak"`
-	Duration       time.Duration `json:"duration"`
-	LeakedCount    int           `json:"leakedCount"`
+	GoroutineStart  int            `json:"goroutineStart"`
+	GoroutineEnd    int            `json:"goroutineEnd"`
+	GoroutineGrowth int            `json:"goroutineGrowth"`
+	GoroutineLeak   bool           `json:"goroutineLeak"`
+	HeapStartBytes  uint64         `json:"heapStartBytes"`
+	HeapEndBytes    uint64         `json:"heapEndBytes"`
+	HeapGrowthBytes int64          `json:"heapGrowthBytes"`
+	HeapLeak        bool           `json:"heapLeak"`
+	Mallocs         uint64         `json:"mallocs"`
+	Duration        time.Duration  `json:"duration"`
+	LeakedCount     int            `json:"leakedCount"`
+	ByState         map[string]int `json:"byState,omitempty"`
 }
 
-// Analyze runs a function and returns runtime analysis
+// heapLeakBytes is the heap growth Analyze reports as a leak
+const heapLeakBytes = 10 << 20
+
+// Analyze runs a function once and returns runtime analysis. The first
+// run of code pays for one-time allocations, such as lazily initialized
+// package state and grown pools, that a leak check should not count; use
+// AnalyzeN to measure runs after a warm-up.
 func Analyze(fn func()) *Result {
 	snapshot := TakeSnapshot()
 
@@ -354,8 +414,10 @@ func Analyze(fn func()) *Result {
 		HeapStartBytes:  snapshot.HeapAllocated,
 		HeapEndBytes:    uint64(int64(snapshot.HeapAllocated) + diff.HeapGrowthBytes),
 		HeapGrowthBytes: diff.HeapGrowthBytes,
-		HeapLeak:        diff.HeapGrowthBytes > 10*1024*1024, // >10MB growth
+		HeapLeak:        diff.HeapGrowthBytes > heapLeakBytes,
+		Mallocs:         diff.Mallocs,
 		Duration:        diff.Duration,
 		LeakedCount:     len(diff.LeakedGoroutines),
+		ByState:         diff.ByState,
 	}
 }
@@ -1,6 +1,9 @@
 package runtime_test
 
 import (
+	goruntime "runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -53,6 +56,63 @@ func TestSnapshot_Compare_GoroutineLeak(t *testing.T) {
 	close(leakChan)
 }
 
+var churnSink []byte
+
+func TestSnapshot_Compare_Mallocs(t *testing.T) {
+	snapshot := runtime.TakeSnapshot()
+	for i := 0; i < 1000; i++ {
+		churnSink = make([]byte, 64)
+	}
+	diff := snapshot.Compare()
+
+	if diff.Mallocs < 1000 {
+		t.Errorf("Mallocs = %d, want at least 1000", diff.Mallocs)
+	}
+	if diff.Frees > diff.Mallocs+1000 {
+		t.Errorf("Frees = %d, implausibly above Mallocs = %d", diff.Frees, diff.Mallocs)
+	}
+	if diff.CgoCalls < 0 {
+		t.Errorf("CgoCalls = %d, want >= 0", diff.CgoCalls)
+	}
+}
+
+func TestSnapshot_Compare_Threads(t *testing.T) {
+	snapshot := runtime.TakeSnapshot()
+	if snapshot.Threads < 1 || snapshot.ThreadsCreated < 1 {
+		t.Fatalf("Threads = %d, ThreadsCreated = %d, want at least one each", snapshot.Threads, snapshot.ThreadsCreated)
+	}
+
+	done := make(chan struct{})
+	var started, exited sync.WaitGroup
+	started.Add(64)
+	exited.Add(64)
+	for i := 0; i < 64; i++ {
+		go func() {
+			defer exited.Done()
+			goruntime.LockOSThread()
+			started.Done()
+			<-done
+		}()
+	}
+	started.Wait()
+	diff := snapshot.Compare()
+	close(done)
+	exited.Wait()
+	// Goroutines that exit locked end their threads, after exited.Done
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if runtime.TakeSnapshot().Threads <= snapshot.Threads+32 {
+			break
+		}
+	}
+
+	if diff.ThreadGrowth <= 0 {
+		t.Errorf("ThreadGrowth = %d with 64 goroutines locked to threads, want growth", diff.ThreadGrowth)
+	}
+	if diff.ThreadsCreated < 0 {
+		t.Errorf("ThreadsCreated = %d, want >= 0", diff.ThreadsCreated)
+	}
+}
+
 func TestAnalyze(t *testing.T) {
 	result := runtime.Analyze(func() {
 		// Simple function
@@ -86,6 +146,24 @@ func TestDefaultOptions(t *testing.T) {
 	}
 }
 
+func TestOptionsForRace(t *testing.T) {
+	opts := runtime.DefaultOptions()
+	opts.MaxHeapGrowthMB = 3
+
+	if got := opts.ForRace(false); got.SettleTime != opts.SettleTime || got.MaxHeapGrowthMB != 3 {
+		t.Errorf("ForRace(false) = %+v, want the options unchanged", got)
+	}
+	got := opts.ForRace(true)
+	if got.SettleTime != 500*time.Millisecond || got.MaxHeapGrowthMB != 6 {
+		t.Errorf("ForRace(true) = settle %v, heap %d MB; want 500ms, 6 MB", got.SettleTime, got.MaxHeapGrowthMB)
+	}
+
+	opts.RaceSettleMultiplier, opts.RaceHeapMultiplier = 1, 1.5
+	if got := opts.ForRace(true); got.SettleTime != 100*time.Millisecond || got.MaxHeapGrowthMB != 5 {
+		t.Errorf("ForRace(true) with multipliers 1, 1.5 = settle %v, heap %d MB; want 100ms, 5 MB", got.SettleTime, got.MaxHeapGrowthMB)
+	}
+}
+
 // MockT implements TestingT for testing
 type MockT struct {
 	errors []string
@@ -102,6 +180,56 @@ func (m *MockT) Logf(format string, args ...interface{}) {
 
 func (m *MockT) Helper() {}
 
+var smallObjects []*int
+
+func TestSnapshot_AssertNoLeak_HeapObjects(t *testing.T) {
+	mockT := &MockT{}
+	snapshot := runtime.TakeSnapshot()
+	for i := 0; i < 20000; i++ {
+		smallObjects = append(smallObjects, new(int))
+	}
+	opts := runtime.DefaultOptions()
+	opts.MaxHeapGrowthMB = 1
+	opts.MaxHeapObjectGrowth = 5000
+	opts.SettleTime, opts.RetryCount = 0, 1
+	snapshot.AssertNoLeakWithOptions(mockT, opts)
+	smallObjects = nil
+
+	if len(mockT.errors) != 1 || !strings.Contains(mockT.errors[0], "heap object leak") {
+		t.Errorf("expected only a heap object leak, got %v", mockT.errors)
+	}
+}
+
+var retainedHeap []byte
+
+func TestDiffHeapGrowth(t *testing.T) {
+	goruntime.GC()
+	snapshot := runtime.TakeSnapshot()
+	retainedHeap = make([]byte, 8<<20)
+	diff := snapshot.Compare()
+	defer func() { retainedHeap = nil }()
+
+	// Sys is left out: memory the runtime already holds is reused
+	for _, m := range []runtime.HeapMetric{runtime.HeapMetricAlloc, runtime.HeapMetricInuse, runtime.HeapMetricLive} {
+		if got := diff.HeapGrowth(m); got < 7<<20 {
+			t.Errorf("HeapGrowth(%s) = %d, want at least the 8 MB retained", m, got)
+		}
+	}
+	if diff.HeapGrowth(runtime.HeapMetricAlloc) != diff.HeapGrowthBytes {
+		t.Errorf("HeapGrowth(HeapMetricAlloc) = %d, want HeapGrowthBytes %d", diff.HeapGrowth(runtime.HeapMetricAlloc), diff.HeapGrowthBytes)
+	}
+
+	mockT := &MockT{}
+	opts := runtime.DefaultOptions()
+	opts.MaxHeapGrowthMB = 1
+	opts.HeapMetric = runtime.HeapMetricInuse
+	opts.SettleTime, opts.RetryCount = 0, 1
+	snapshot.AssertNoLeakWithOptions(mockT, opts)
+	if len(mockT.errors) != 1 || !strings.Contains(mockT.errors[0], "heap leak detected") {
+		t.Errorf("expected a HeapInuse leak, got %v", mockT.errors)
+	}
+}
+
 func TestSnapshot_AssertNoLeak_Pass(t *testing.T) {
 	mockT := &MockT{}
 	snapshot := runtime.TakeSnapshot()
@@ -146,3 +274,103 @@ func ExampleAnalyze() {
 		// Handle heap leak
 	}
 }
+
+func TestGoroutineFilter(t *testing.T) {
+	stack := "goroutine 7 [chan receive]:\nmain.worker()\ncreated by testing.tRunner"
+
+	f := runtime.DefaultGoroutineFilter()
+	if !f.IsExpected(stack) {
+		t.Error("default filter should treat tRunner stacks as expected")
+	}
+
+	f.Remove("testing.tRunner")
+	if f.IsExpected(stack) {
+		t.Error("filter should report stack after removing testing.tRunner")
+	}
+
+	f.Add("main.worker")
+	if !f.IsExpected(stack) {
+		t.Error("filter should match added pattern")
+	}
+
+	f.Disable()
+	if f.IsExpected(stack) {
+		t.Error("disabled filter should match nothing")
+	}
+}
+
+func TestGoroutineFilter_RuntimeFrames(t *testing.T) {
+	// a goroutine blocked on a channel, as printed with GOTRACEBACK=system
+	stack := `goroutine 7 gp=0xc000003180 m=nil [chan receive]:
+runtime.gopark(0x0?, 0x0?, 0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/proc.go:402 +0xce fp=0xc00004e718 sp=0xc00004e6f8 pc=0x43a42e
+runtime.chanrecv(0xc000020120, 0x0, 0x1)
+	/usr/local/go/src/runtime/chan.go:583 +0x3bf fp=0xc00004e790 sp=0xc00004e718 pc=0x40707f
+runtime.chanrecv1(0x0?, 0x0?)
+	/usr/local/go/src/runtime/chan.go:442 +0x12 fp=0xc00004e7b8 sp=0xc00004e790 pc=0x406c92
+main.worker()
+	/app/main.go:12 +0x25 fp=0xc00004e7e0 sp=0xc00004e7b8 pc=0x47b2a5
+runtime.goexit({})
+	/usr/local/go/src/runtime/asm_amd64.s:1695 +0x1 fp=0xc00004e7e8 sp=0xc00004e7e0 pc=0x4700a1
+created by main.main in goroutine 1
+	/app/main.go:10 +0x1a`
+
+	if runtime.DefaultGoroutineFilter().IsExpected(stack) {
+		t.Error("default filter should report a goroutine blocked on a channel")
+	}
+}
+
+func TestSnapshot_CompareWith_Filter(t *testing.T) {
+	snapshot := runtime.TakeSnapshot()
+
+	leakChan := make(chan struct{})
+	defer close(leakChan)
+	go func() {
+		<-leakChan
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	if diff := snapshot.Compare(); len(diff.LeakedGoroutines) == 0 {
+		t.Error("default filter should report blocked goroutine")
+	}
+
+	filter := runtime.DefaultGoroutineFilter().Add("TestSnapshot_CompareWith")
+	if diff := snapshot.CompareWith(filter); len(diff.LeakedGoroutines) != 0 {
+		t.Errorf("added pattern should hide goroutine, got %d", len(diff.LeakedGoroutines))
+	}
+}
+
+func TestCountByState(t *testing.T) {
+	goroutines := []runtime.GoroutineInfo{
+		{ID: 1, State: "chan receive"},
+		{ID: 2, State: "select"},
+		{ID: 3, State: "chan receive"},
+	}
+
+	counts := runtime.CountByState(goroutines)
+	if counts["chan receive"] != 2 || counts["select"] != 1 {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+
+	if got := runtime.FormatByState(counts); got != "chan receive=2, select=1" {
+		t.Errorf("FormatByState() = %q", got)
+	}
+}
+
+func TestSnapshot_Compare_ByState(t *testing.T) {
+	snapshot := runtime.TakeSnapshot()
+
+	leakChan := make(chan struct{})
+	defer close(leakChan)
+	for i := 0; i < 2; i++ {
+		go func() {
+			<-leakChan
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	diff := snapshot.Compare()
+	if diff.ByState["chan receive"] < 2 {
+		t.Errorf("expected at least 2 goroutines in 'chan receive', got %v", diff.ByState)
+	}
+}
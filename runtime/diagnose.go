@@ -0,0 +1,94 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diagnosis kinds
+const (
+	DiagnosisWaitGroup   = "waitgroup"
+	DiagnosisChanSend    = "chan-send"
+	DiagnosisChanReceive = "chan-receive"
+	DiagnosisNilChan     = "nil-chan"
+)
+
+// Diagnosis explains why a leaked goroutine is probably stuck. Without
+// one, a goroutine blocked in WaitGroup.Wait shows up as a generic
+// "semacquire" stack on older Go releases.
+type Diagnosis struct {
+	Kind       string
+	Message    string // what the goroutine is blocked on, and where
+	Suggestion string // the likely fix
+}
+
+// String renders the diagnosis as "message; suggestion"
+func (d Diagnosis) String() string {
+	return d.Message + "; " + d.Suggestion
+}
+
+// Diagnose recognizes goroutines stuck in sync.WaitGroup.Wait or on a
+// channel operation that will never complete. It returns nil for other
+// goroutines. Call it on goroutines still blocked after the settle
+// window, such as Diff.LeakedGoroutines.
+func Diagnose(g GoroutineInfo) *Diagnosis {
+	at := blockedAt(g)
+	switch {
+	case hasFrame(g, "sync.(*WaitGroup).Wait"):
+		return &Diagnosis{
+			Kind:       DiagnosisWaitGroup,
+			Message:    "blocked in sync.WaitGroup.Wait" + at + ": the counter never reached zero",
+			Suggestion: "a goroutine counted by Add likely never calls Done; use defer wg.Done() at the start of each one",
+		}
+	case strings.HasSuffix(g.State, "(nil chan)"):
+		return &Diagnosis{
+			Kind:       DiagnosisNilChan,
+			Message:    "blocked on a nil channel" + at + ": this never completes",
+			Suggestion: "the channel was likely never created with make",
+		}
+	case g.State == "chan send":
+		return &Diagnosis{
+			Kind:       DiagnosisChanSend,
+			Message:    "blocked sending" + at + ": the channel is full or no one reads it",
+			Suggestion: "the receiver likely returned early; give the send a buffer or select on ctx.Done()",
+		}
+	case g.State == "chan receive":
+		return &Diagnosis{
+			Kind:       DiagnosisChanReceive,
+			Message:    "blocked receiving" + at + ": no one sends and the channel is never closed",
+			Suggestion: "the sender likely never calls close(ch) when done",
+		}
+	}
+	return nil
+}
+
+// hasFrame reports whether fn is on g's stack
+func hasFrame(g GoroutineInfo, fn string) bool {
+	for _, f := range g.Frames {
+		if f.Function == fn {
+			return true
+		}
+	}
+	return false
+}
+
+// blockedAt returns " at file:line" of the innermost frame outside the
+// runtime and sync packages, or "" when there is none
+func blockedAt(g GoroutineInfo) string {
+	for _, f := range g.Frames {
+		if isRuntimeFrame(f.Function) {
+			continue
+		}
+		return fmt.Sprintf(" at %s:%d", f.File, f.Line)
+	}
+	return ""
+}
+
+func isRuntimeFrame(fn string) bool {
+	for _, prefix := range []string{"runtime.", "sync.", "internal/"} {
+		if strings.HasPrefix(fn, prefix) {
+			return true
+		}
+	}
+	return false
+}
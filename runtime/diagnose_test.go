@@ -0,0 +1,94 @@
+package runtime_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/harshakonda/heapcheck/runtime"
+	"github.com/harshakonda/heapcheck/runtime/stackparse"
+)
+
+func TestDiagnose(t *testing.T) {
+	snapshot := runtime.TakeSnapshot()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	unbuffered := make(chan int)
+	var nilChan chan int
+	release := make(chan struct{})
+
+	go func() { wg.Wait() }()
+	go func() { unbuffered <- 1 }()
+	go func() { <-release }()
+	go func() {
+		select {
+		case nilChan <- 1:
+		case <-release:
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	kinds := make(map[string]int)
+	for _, g := range snapshot.Compare().LeakedGoroutines {
+		d := runtime.Diagnose(g)
+		if d == nil {
+			continue
+		}
+		kinds[d.Kind]++
+		if !strings.Contains(d.Message, "diagnose_test.go:") {
+			t.Errorf("%s message %q does not point at the test", d.Kind, d.Message)
+		}
+	}
+
+	wg.Done()
+	<-unbuffered
+	close(release)
+
+	for _, kind := range []string{runtime.DiagnosisWaitGroup, runtime.DiagnosisChanSend, runtime.DiagnosisChanReceive} {
+		if kinds[kind] != 1 {
+			t.Errorf("%s diagnoses = %d, want 1 (all: %v)", kind, kinds[kind], kinds)
+		}
+	}
+	if len(kinds) != 3 {
+		t.Errorf("a goroutine blocked in select was diagnosed: %v", kinds)
+	}
+}
+
+func TestDiagnoseStacks(t *testing.T) {
+	dump := `goroutine 7 [semacquire, 2 minutes]:
+sync.runtime_Semacquire(0xc000012345?)
+	/usr/local/go/src/runtime/sema.go:62 +0x25
+sync.(*WaitGroup).Wait(0xc000012340?)
+	/usr/local/go/src/sync/waitgroup.go:116 +0x48
+example.com/app.(*Pool).Close(...)
+	/src/app/pool.go:88
+created by example.com/app.NewPool in goroutine 1
+	/src/app/pool.go:30 +0x65
+
+goroutine 9 [chan send (nil chan)]:
+example.com/app.publish()
+	/src/app/events.go:12 +0x1e
+
+goroutine 10 [IO wait]:
+internal/poll.runtime_pollWait(0x7f, 0x72)
+	/usr/local/go/src/runtime/netpoll.go:351 +0x85
+`
+	gs := stackparse.ParseStacks([]byte(dump))
+	if len(gs) != 3 {
+		t.Fatalf("parsed %d goroutines, want 3", len(gs))
+	}
+
+	d := runtime.Diagnose(gs[0])
+	if d == nil || d.Kind != runtime.DiagnosisWaitGroup || !strings.Contains(d.Message, "/src/app/pool.go:88") || !strings.Contains(d.Suggestion, "Done") {
+		t.Errorf("semacquire in WaitGroup.Wait: %+v", d)
+	}
+	d = runtime.Diagnose(gs[1])
+	if d == nil || d.Kind != runtime.DiagnosisNilChan || !strings.Contains(d.Message, "/src/app/events.go:12") {
+		t.Errorf("nil channel send: %+v", d)
+	}
+	if d := runtime.Diagnose(gs[2]); d != nil {
+		t.Errorf("IO wait diagnosed as %+v", d)
+	}
+}
@@ -0,0 +1,115 @@
+package runtime
+
+import "strings"
+
+// defaultExpectedPatterns are stack substrings of goroutines that live as
+// long as the process: those of the Go runtime, the testing and os/signal
+// packages, and the background workers common libraries start on first
+// use and never stop. Blocking frames such as runtime.gopark or
+// runtime.chanrecv are not among them: they appear in the stack of every
+// parked goroutine when GOTRACEBACK=system, leaked ones included.
+var defaultExpectedPatterns = []string{
+	"testing.(*T).Run",
+	"testing.tRunner",
+	"runtime.main",
+	"runtime.gcBgMarkWorker",
+	"runtime.bgsweep",
+	"runtime.bgscavenge",
+	"runtime.forcegchelper",
+	"runtime.timerproc",
+	"signal.signal_recv",
+	"os/signal.loop",
+	"runtime.runfinq",
+	"go.opencensus.io/stats/view.(*worker).start",
+	"github.com/golang/glog.(*loggingT).flushDaemon",
+	"github.com/golang/glog.(*fileSink).flushDaemon",
+	"k8s.io/klog.(*loggingT).flushDaemon",
+	"k8s.io/klog/v2.(*flushDaemon).run",
+	"github.com/rcrowley/go-metrics.(*meterArbiter).tick",
+}
+
+// GoroutineFilter decides which new goroutines are expected and therefore
+// not reported in Diff.LeakedGoroutines. A goroutine is expected when its
+// stack contains any of the filter's patterns (case-insensitive).
+//
+// Patterns are matched against the whole stack, including the "created by"
+// frame, so a broad pattern such as "testing.tRunner" can hide goroutines
+// the code under test started. Tighten the list with Remove, or extend it
+// for pool goroutines with Add:
+//
+//	filter := runtime.DefaultGoroutineFilter().
+//	    Remove("testing.tRunner").
+//	    Add("github.com/example/pool.(*Pool).worker")
+type GoroutineFilter struct {
+	patterns []string
+	disabled bool
+}
+
+// DefaultGoroutineFilter returns a new filter with the built-in patterns
+func DefaultGoroutineFilter() *GoroutineFilter {
+	return &GoroutineFilter{
+		patterns: append([]string(nil), defaultExpectedPatterns...),
+	}
+}
+
+// Add appends patterns to the filter
+func (f *GoroutineFilter) Add(patterns ...string) *GoroutineFilter {
+	f.patterns = append(f.patterns, patterns...)
+	return f
+}
+
+// Remove deletes patterns from the filter
+func (f *GoroutineFilter) Remove(patterns ...string) *GoroutineFilter {
+	kept := f.patterns[:0]
+	for _, p := range f.patterns {
+		if !containsFold(patterns, p) {
+			kept = append(kept, p)
+		}
+	}
+	f.patterns = kept
+	return f
+}
+
+// Disable turns the filter off so every new goroutine is reported
+func (f *GoroutineFilter) Disable() *GoroutineFilter {
+	f.disabled = true
+	return f
+}
+
+// Patterns returns the current patterns
+func (f *GoroutineFilter) Patterns() []string {
+	return append([]string(nil), f.patterns...)
+}
+
+// IsExpected reports whether a goroutine stack matches the filter
+func (f *GoroutineFilter) IsExpected(stack string) bool {
+	if f == nil {
+		return isExpectedGoroutine(stack)
+	}
+	if f.disabled {
+		return false
+	}
+
+	stackLower := strings.ToLower(stack)
+	for _, pattern := range f.patterns {
+		if strings.Contains(stackLower, strings.ToLower(pattern)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isExpectedGoroutine checks if a goroutine is expected (runtime, testing, etc.)
+func isExpectedGoroutine(stack string) bool {
+	return (&GoroutineFilter{patterns: defaultExpectedPatterns}).IsExpected(stack)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
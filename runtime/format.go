@@ -0,0 +1,125 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// topCreators is how many goroutine creators Format lists
+const topCreators = 5
+
+// String renders the diff as Format(false) does
+func (d *Diff) String() string {
+	return d.Format(false)
+}
+
+// Format renders the diff as a human-readable breakdown: goroutine growth
+// with leaked goroutines per state and the go statements that started the
+// most of them, heap growth, allocations, cgo calls, OS threads and
+// uncollected objects. Verbose adds each leaked goroutine's stack, with a hint when
+// Diagnose recognizes why it is stuck. Lines are separated by "\n"
+// without a trailing newline:
+//
+//	Goroutines: +2, 2 leaked (chan receive=2)
+//	  Created by:
+//	    2  example.com/app.startWorker at /src/app/worker.go:12
+//	Heap: +1.25 MB, +1200 objects
+//	Allocations: 5000 mallocs, 3800 frees
+//	Duration: 120ms
+func (d *Diff) Format(verbose bool) string {
+	var lines []string
+
+	goroutines := fmt.Sprintf("Goroutines: %+d", d.GoroutineGrowth)
+	if len(d.LeakedGoroutines) > 0 {
+		goroutines += fmt.Sprintf(", %d leaked (%s)", len(d.LeakedGoroutines), FormatByState(CountByState(d.LeakedGoroutines)))
+	}
+	lines = append(lines, goroutines)
+	if creators := formatCreators(d.LeakedGoroutines); len(creators) > 0 {
+		lines = append(lines, "  Created by:")
+		lines = append(lines, creators...)
+	}
+
+	lines = append(lines,
+		fmt.Sprintf("Heap: %+.2f MB, %+d objects", float64(d.HeapGrowthBytes)/1024/1024, d.HeapGrowthObjects),
+		fmt.Sprintf("Allocations: %d mallocs, %d frees", d.Mallocs, d.Frees))
+	if d.CgoCalls != 0 {
+		lines = append(lines, fmt.Sprintf("Cgo calls: %d", d.CgoCalls))
+	}
+	if d.ThreadGrowth != 0 || d.ThreadsCreated != 0 {
+		lines = append(lines, fmt.Sprintf("OS threads: %+d, %d created", d.ThreadGrowth, d.ThreadsCreated))
+	}
+	if len(d.UncollectedObjects) > 0 {
+		lines = append(lines, fmt.Sprintf("Uncollected objects (%d):%s", len(d.UncollectedObjects), FormatUncollected(d.UncollectedObjects)))
+	}
+	lines = append(lines, fmt.Sprintf("Duration: %s", d.Duration.Round(time.Millisecond)))
+
+	out := strings.Join(lines, "\n")
+	if verbose {
+		out += strings.TrimRight(formatLeakedGoroutines(d.LeakedGoroutines), "\n")
+	}
+	return out
+}
+
+// formatCreators lists the go statements that started the most of the
+// goroutines, most first
+func formatCreators(goroutines []GoroutineInfo) []string {
+	counts := make(map[string]int)
+	for _, g := range goroutines {
+		if g.CreatedBy == nil {
+			continue
+		}
+		counts[fmt.Sprintf("%s at %s:%d", g.CreatedBy.Function, g.CreatedBy.File, g.CreatedBy.Line)]++
+	}
+
+	creators := make([]string, 0, len(counts))
+	for c := range counts {
+		creators = append(creators, c)
+	}
+	sort.Slice(creators, func(i, j int) bool {
+		if counts[creators[i]] != counts[creators[j]] {
+			return counts[creators[i]] > counts[creators[j]]
+		}
+		return creators[i] < creators[j]
+	})
+
+	var lines []string
+	for i, c := range creators {
+		if i >= topCreators {
+			lines = append(lines, fmt.Sprintf("    ... and %d more", len(creators)-topCreators))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("    %d  %s", counts[c], c))
+	}
+	return lines
+}
+
+// formatLeakedGoroutines formats leaked goroutines for error output
+func formatLeakedGoroutines(leaked []GoroutineInfo) string {
+	if len(leaked) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\nLeaked goroutines (%d): %s\n", len(leaked), FormatByState(CountByState(leaked))))
+
+	for _, g := range leaked {
+		sb.WriteString(fmt.Sprintf("\n--- Goroutine %d [%s] ---\n", g.ID, g.State))
+		if labels := g.LabelString(); labels != "" {
+			sb.WriteString("Labels: " + labels + "\n")
+		}
+		if d := Diagnose(g); d != nil {
+			sb.WriteString("Hint: " + d.String() + "\n")
+		}
+		// Truncate stack to first 10 lines for readability
+		lines := strings.Split(g.Stack, "\n")
+		if len(lines) > 12 {
+			lines = append(lines[:12], "    ...")
+		}
+		sb.WriteString(strings.Join(lines, "\n"))
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
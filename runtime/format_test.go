@@ -0,0 +1,63 @@
+package runtime_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harshakonda/heapcheck/runtime"
+	"github.com/harshakonda/heapcheck/runtime/stackparse"
+)
+
+func TestDiff_Format(t *testing.T) {
+	worker := &stackparse.Frame{Function: "example.com/app.startWorker", File: "/src/app/worker.go", Line: 12}
+	poller := &stackparse.Frame{Function: "example.com/app.poll", File: "/src/app/poll.go", Line: 30}
+	diff := &runtime.Diff{
+		GoroutineGrowth:   3,
+		HeapGrowthBytes:   1536 * 1024,
+		HeapGrowthObjects: 1200,
+		Mallocs:           5000,
+		Frees:             3800,
+		Duration:          120 * time.Millisecond,
+		LeakedGoroutines: []runtime.GoroutineInfo{
+			{ID: 7, State: "chan receive", CreatedBy: worker, Stack: "goroutine 7 [chan receive]:"},
+			{ID: 8, State: "chan receive", CreatedBy: worker, Stack: "goroutine 8 [chan receive]:"},
+			{ID: 9, State: "select", CreatedBy: poller, Stack: "goroutine 9 [select]:"},
+		},
+	}
+
+	want := strings.Join([]string{
+		"Goroutines: +3, 3 leaked (chan receive=2, select=1)",
+		"  Created by:",
+		"    2  example.com/app.startWorker at /src/app/worker.go:12",
+		"    1  example.com/app.poll at /src/app/poll.go:30",
+		"Heap: +1.50 MB, +1200 objects",
+		"Allocations: 5000 mallocs, 3800 frees",
+		"Duration: 120ms",
+	}, "\n")
+	if got := diff.Format(false); got != want {
+		t.Errorf("Format(false) =\n%s\nwant\n%s", got, want)
+	}
+	if got := diff.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+
+	verbose := diff.Format(true)
+	if !strings.HasPrefix(verbose, want+"\n") {
+		t.Errorf("Format(true) should start with the summary, got\n%s", verbose)
+	}
+	for _, id := range []string{"--- Goroutine 7 [chan receive] ---", "--- Goroutine 9 [select] ---"} {
+		if !strings.Contains(verbose, id) {
+			t.Errorf("Format(true) missing %q", id)
+		}
+	}
+}
+
+func TestDiff_Format_Quiet(t *testing.T) {
+	diff := &runtime.Diff{CgoCalls: 4, Duration: time.Second}
+
+	want := "Goroutines: +0\nHeap: +0.00 MB, +0 objects\nAllocations: 0 mallocs, 0 frees\nCgo calls: 4\nDuration: 1s"
+	if got := diff.Format(true); got != want {
+		t.Errorf("Format(true) =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"fmt"
+	"runtime/metrics"
+)
+
+// HeapMetric selects the memory statistic that heap-growth limits, such
+// as Options.MaxHeapGrowthMB, are checked against
+type HeapMetric int
+
+const (
+	// HeapMetricAlloc is the bytes of allocated heap objects
+	// (MemStats.HeapAlloc), the default. It follows allocations closely,
+	// and so also garbage a GC cycle has not swept yet.
+	HeapMetricAlloc HeapMetric = iota
+
+	// HeapMetricInuse is the bytes in in-use heap spans
+	// (MemStats.HeapInuse): the resident heap, fragmentation included
+	HeapMetricInuse
+
+	// HeapMetricSys is all memory obtained from the OS (MemStats.Sys):
+	// the heap, goroutine stacks and the runtime's own structures
+	HeapMetricSys
+
+	// HeapMetricLive is the bytes of objects the last GC cycle marked
+	// live (/gc/heap/live:bytes). It does not move between cycles, so
+	// for an exact baseline take the snapshot after a runtime.GC().
+	HeapMetricLive
+)
+
+// String names the metric after the statistic it reads, e.g. "HeapInuse"
+func (m HeapMetric) String() string {
+	switch m {
+	case HeapMetricAlloc:
+		return "HeapAlloc"
+	case HeapMetricInuse:
+		return "HeapInuse"
+	case HeapMetricSys:
+		return "Sys"
+	case HeapMetricLive:
+		return "HeapLive"
+	default:
+		return fmt.Sprintf("HeapMetric(%d)", int(m))
+	}
+}
+
+// HeapGrowth returns the growth in bytes of the statistic m reads
+func (d *Diff) HeapGrowth(m HeapMetric) int64 {
+	switch m {
+	case HeapMetricInuse:
+		return d.HeapInuseGrowthBytes
+	case HeapMetricSys:
+		return d.SysGrowthBytes
+	case HeapMetricLive:
+		return d.HeapLiveGrowthBytes
+	default:
+		return d.HeapGrowthBytes
+	}
+}
+
+// heapLive returns the bytes the last GC cycle marked live
+func heapLive() uint64 {
+	sample := []metrics.Sample{{Name: "/gc/heap/live:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,104 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"runtime/pprof"
+	"strings"
+
+	"github.com/harshakonda/heapcheck/runtime/stackparse"
+)
+
+// attachLabels sets the pprof labels of leaked goroutines whose traceback
+// carried none, which is the case before Go 1.26 and with
+// GODEBUG=tracebacklabels=0, the default for main modules declaring a go
+// version before 1.27. They are read from the goroutine profile,
+// which records labels per stack rather than per goroutine: a goroutine
+// gets the labels of the profile records with its stack when those all
+// carry the same labels.
+func attachLabels(leaked []GoroutineInfo) {
+	unlabeled := false
+	for _, g := range leaked {
+		if g.Labels == nil {
+			unlabeled = true
+			break
+		}
+	}
+	if !unlabeled {
+		return
+	}
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return
+	}
+	byStack := profileLabels(buf.Bytes())
+	for i, g := range leaked {
+		if g.Labels != nil {
+			continue
+		}
+		if sets, ok := byStack[stackKey(g.Frames)]; ok && len(sets) == 1 {
+			for _, labels := range sets {
+				leaked[i].Labels = labels
+			}
+		}
+	}
+}
+
+// profileLabels returns the label sets of the records of a debug=1
+// goroutine profile, by stack, keyed by their LabelString. Records
+// without labels count as the empty set, so a stack some goroutines run
+// without labels is ambiguous.
+func profileLabels(profile []byte) map[string]map[string]map[string]string {
+	byStack := make(map[string]map[string]map[string]string)
+	var labels map[string]string
+	var frames []stackparse.Frame
+	inRecord := false
+	flush := func() {
+		if inRecord {
+			key := stackKey(frames)
+			if byStack[key] == nil {
+				byStack[key] = make(map[string]map[string]string)
+			}
+			byStack[key][stackparse.GoroutineInfo{Labels: labels}.LabelString()] = labels
+		}
+		labels, frames, inRecord = nil, nil, false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(profile))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(profile)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "# labels: {"):
+			labels = stackparse.ParseLabels(strings.TrimSuffix(strings.TrimPrefix(line, "# labels: {"), "}"), ":")
+		case strings.HasPrefix(line, "#\t"):
+			// #	0x4e14f8	main.worker+0x18	/app/main.go:11
+			fields := strings.Split(strings.TrimPrefix(line, "#\t"), "\t")
+			if len(fields) >= 2 {
+				fn, _, _ := strings.Cut(strings.TrimSpace(fields[1]), "+0x")
+				frames = append(frames, stackparse.Frame{Function: fn})
+			}
+		case strings.Contains(line, " @ "):
+			flush()
+			inRecord = true
+		}
+	}
+	flush()
+	return byStack
+}
+
+// stackKey identifies a stack by its functions outside the runtime, which
+// both tracebacks and goroutine profiles show
+func stackKey(frames []stackparse.Frame) string {
+	var b strings.Builder
+	for _, f := range frames {
+		if strings.HasPrefix(f.Function, "runtime.") {
+			continue
+		}
+		b.WriteString(f.Function)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
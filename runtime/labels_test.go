@@ -0,0 +1,64 @@
+package runtime
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+	"time"
+
+	"github.com/harshakonda/heapcheck/runtime/stackparse"
+)
+
+const sampleProfile = `goroutine profile: total 4
+1 @ 0x47d82a 0x41512e 0x414c72 0x4e14f9 0x4835c1
+#	0x4e14f8	main.worker+0x18	/app/main.go:11
+
+1 @ 0x47d82a 0x41512e 0x414c72 0x4e15b9 0x4835c1
+# labels: {"component":"billing", "req":"42"}
+#	0x4e15b8	main.worker+0x18	/app/main.go:11
+
+2 @ 0x47d82a 0x41512e 0x414c72 0x4e16b9 0x4835c1
+# labels: {"component":"search"}
+#	0x4e16b8	main.indexer+0x18	/app/index.go:7
+#	0x4e16c8	main.run+0x20		/app/index.go:3
+`
+
+func TestProfileLabels(t *testing.T) {
+	byStack := profileLabels([]byte(sampleProfile))
+
+	// Labeled and unlabeled goroutines share the worker's stack
+	worker := byStack[stackKey([]stackparse.Frame{{Function: "main.worker"}})]
+	if len(worker) != 2 {
+		t.Errorf("worker label sets = %v, want the labeled and the empty one", worker)
+	}
+	indexer := byStack[stackKey([]stackparse.Frame{{Function: "runtime.gopark"}, {Function: "main.indexer"}, {Function: "main.run"}})]
+	if len(indexer) != 1 || indexer["component=search"]["component"] != "search" {
+		t.Errorf("indexer label sets = %v, want component=search", indexer)
+	}
+}
+
+// labeledLeak blocks until stop is closed, on a stack of its own
+func labeledLeak(stop chan struct{}) {
+	<-stop
+}
+
+func TestAttachLabels(t *testing.T) {
+	snapshot := TakeSnapshot()
+	stop := make(chan struct{})
+	defer close(stop)
+	pprof.Do(context.Background(), pprof.Labels("component", "billing"), func(context.Context) {
+		go labeledLeak(stop)
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	diff := snapshot.Compare()
+	for _, g := range diff.LeakedGoroutines {
+		if g.TopFunction() == "github.com/harshakonda/heapcheck/runtime.labeledLeak" {
+			if g.Labels["component"] != "billing" {
+				t.Errorf("Labels = %v, want component=billing", g.Labels)
+			}
+			return
+		}
+	}
+	t.Fatalf("labeled goroutine not among the leaked: %+v", diff.LeakedGoroutines)
+}
@@ -0,0 +1,112 @@
+package runtime
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultWarmup is how many runs AnalyzeN makes before measuring
+const DefaultWarmup = 3
+
+// Percentiles summarizes a measurement over the runs of AnalyzeN
+type Percentiles struct {
+	Min    int64 `json:"min"`
+	Median int64 `json:"median"`
+	P95    int64 `json:"p95"`
+	Max    int64 `json:"max"`
+}
+
+// percentiles returns the nearest-rank percentiles of values, which it
+// sorts
+func percentiles(values []int64) Percentiles {
+	if len(values) == 0 {
+		return Percentiles{}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	rank := func(p float64) int64 {
+		return values[int(math.Ceil(p*float64(len(values))))-1]
+	}
+	return Percentiles{Min: values[0], Median: rank(0.5), P95: rank(0.95), Max: values[len(values)-1]}
+}
+
+// SampleResult summarizes the measured runs of AnalyzeN, each a Result
+// of Analyze
+type SampleResult struct {
+	Warmup int `json:"warmup"`
+	Runs   int `json:"runs"`
+
+	HeapGrowthBytes Percentiles `json:"heapGrowthBytes"`
+	Mallocs         Percentiles `json:"mallocs"`
+	GoroutineGrowth Percentiles `json:"goroutineGrowth"`
+	Duration        Percentiles `json:"duration"` // nanoseconds
+
+	// GoroutineLeak is set when most runs leaked goroutines, and
+	// HeapLeak when the heap grew in most runs by more than Analyze's
+	// threshold in all. A leak in a single run, such as a cache filled
+	// once, is not one.
+	GoroutineLeak bool `json:"goroutineLeak"`
+	HeapLeak      bool `json:"heapLeak"`
+
+	Samples []*Result `json:"samples"`
+}
+
+// AnalyzeN runs fn DefaultWarmup times unmeasured, then n times with
+// Analyze, and summarizes the measured runs. The warm-up runs absorb
+// one-time allocations, such as lazily initialized package state and
+// pools being filled, which dominate a single measured run. n less than
+// 1 is 1.
+func AnalyzeN(fn func(), n int) *SampleResult {
+	return AnalyzeNWarmup(fn, DefaultWarmup, n)
+}
+
+// AnalyzeNWarmup is AnalyzeN with warmup unmeasured runs
+func AnalyzeNWarmup(fn func(), warmup, n int) *SampleResult {
+	n, warmup = max(n, 1), max(warmup, 0)
+	for i := 0; i < warmup; i++ {
+		fn()
+	}
+
+	s := &SampleResult{Warmup: warmup, Runs: n, Samples: make([]*Result, n)}
+	heap := make([]int64, n)
+	mallocs := make([]int64, n)
+	goroutines := make([]int64, n)
+	durations := make([]int64, n)
+	var leaked, grew int
+	var total int64
+	for i := range s.Samples {
+		r := Analyze(fn)
+		s.Samples[i] = r
+		heap[i], mallocs[i] = r.HeapGrowthBytes, int64(r.Mallocs)
+		goroutines[i], durations[i] = int64(r.GoroutineGrowth), int64(r.Duration)
+		if r.GoroutineLeak {
+			leaked++
+		}
+		if r.HeapGrowthBytes > 0 {
+			grew++
+		}
+		total += r.HeapGrowthBytes
+	}
+	s.HeapGrowthBytes = percentiles(heap)
+	s.Mallocs = percentiles(mallocs)
+	s.GoroutineGrowth = percentiles(goroutines)
+	s.Duration = percentiles(durations)
+	s.GoroutineLeak = leaked*2 > n
+	s.HeapLeak = grew*2 > n && total > heapLeakBytes
+	return s
+}
+
+// String renders the median and p95 of each measurement:
+//
+//	20 runs after 3 warm-up: heap growth median +1.2 KB, p95 +4.0 KB; 35 mallocs median, 41 p95; goroutines median +0, p95 +0; duration median 1ms, p95 3ms
+func (s *SampleResult) String() string {
+	kb := func(b int64) string { return fmt.Sprintf("%+.1f KB", float64(b)/1024) }
+	d := func(ns int64) time.Duration { return time.Duration(ns).Round(time.Microsecond) }
+	return fmt.Sprintf("%d runs after %d warm-up: heap growth median %s, p95 %s; %d mallocs median, %d p95; goroutines median %+d, p95 %+d; duration median %s, p95 %s",
+		s.Runs, s.Warmup,
+		kb(s.HeapGrowthBytes.Median), kb(s.HeapGrowthBytes.P95),
+		s.Mallocs.Median, s.Mallocs.P95,
+		s.GoroutineGrowth.Median, s.GoroutineGrowth.P95,
+		d(s.Duration.Median), d(s.Duration.P95))
+}
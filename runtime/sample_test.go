@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPercentiles(t *testing.T) {
+	tests := []struct {
+		values []int64
+		want   Percentiles
+	}{
+		{nil, Percentiles{}},
+		{[]int64{7}, Percentiles{Min: 7, Median: 7, P95: 7, Max: 7}},
+		{[]int64{4, 1, 3, 2}, Percentiles{Min: 1, Median: 2, P95: 4, Max: 4}},
+		{[]int64{20, 19, 18, 17, 16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, Percentiles{Min: 1, Median: 10, P95: 19, Max: 20}},
+	}
+	for _, tt := range tests {
+		if got := percentiles(tt.values); got != tt.want {
+			t.Errorf("percentiles(%v) = %+v, want %+v", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeNWarmup(t *testing.T) {
+	calls := 0
+	var once sync.Once
+	var table []byte
+	s := AnalyzeNWarmup(func() {
+		calls++
+		// A one-time allocation, as of lazily initialized state
+		once.Do(func() { table = make([]byte, 4<<20) })
+		time.Sleep(time.Millisecond)
+	}, 2, 5)
+	_ = table
+
+	if calls != 7 {
+		t.Errorf("fn ran %d times, want 2 warm-up and 5 measured", calls)
+	}
+	if s.Warmup != 2 || s.Runs != 5 || len(s.Samples) != 5 {
+		t.Errorf("AnalyzeNWarmup() = %d warm-up, %d runs, %d samples", s.Warmup, s.Runs, len(s.Samples))
+	}
+	if s.HeapGrowthBytes.Max > 1<<20 {
+		t.Errorf("heap growth max = %d, want the one-time allocation left to the warm-up", s.HeapGrowthBytes.Max)
+	}
+	if s.Duration.Min < int64(time.Millisecond) {
+		t.Errorf("duration min = %v, want at least 1ms", time.Duration(s.Duration.Min))
+	}
+	if s.GoroutineLeak || s.HeapLeak {
+		t.Errorf("AnalyzeNWarmup() reports a leak: %s", s)
+	}
+	if !strings.HasPrefix(s.String(), "5 runs after 2 warm-up: heap growth median") {
+		t.Errorf("String() = %q", s.String())
+	}
+}
+
+func TestAnalyzeNGoroutineLeak(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	s := AnalyzeN(func() {
+		go func() { <-stop }()
+	}, 3)
+	if !s.GoroutineLeak || s.GoroutineGrowth.Median != 1 {
+		t.Errorf("AnalyzeN() = leak %v, growth median %d, want a leak of one goroutine per run", s.GoroutineLeak, s.GoroutineGrowth.Median)
+	}
+}
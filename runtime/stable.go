@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"runtime"
+	"time"
+)
+
+// StableHeap bounds: HeapAlloc has converged when two consecutive GC
+// cycles leave it within stableHeapEpsilon of each other, or within
+// stableHeapMinBytes for small heaps, where a few allocations by other
+// goroutines would exceed any ratio
+const (
+	stableHeapCycles   = 10
+	stableHeapEpsilon  = 0.01
+	stableHeapMinBytes = 16 << 10
+)
+
+// StableHeap runs GC cycles until HeapAlloc converges across consecutive
+// cycles, and returns the memory statistics after the last one. A single
+// cycle leaves objects kept alive by finalizers, and garbage allocated
+// while it ran, in the heap, which makes heap deltas flaky. It gives up
+// after 10 cycles, returning the statistics as they are.
+func StableHeap() runtime.MemStats {
+	var prev, cur runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&prev)
+	for i := 1; i < stableHeapCycles; i++ {
+		// Let the finalizer goroutine run, so what it releases is
+		// collected by the next cycle
+		time.Sleep(time.Millisecond)
+		runtime.GC()
+		runtime.ReadMemStats(&cur)
+		if converged(prev.HeapAlloc, cur.HeapAlloc) {
+			return cur
+		}
+		prev = cur
+	}
+	return prev
+}
+
+// converged reports whether two heap sizes are within the StableHeap bounds
+func converged(a, b uint64) bool {
+	diff := a - b
+	if b > a {
+		diff = b - a
+	}
+	return diff <= max(stableHeapMinBytes, uint64(float64(max(a, b))*stableHeapEpsilon))
+}
@@ -0,0 +1,46 @@
+package runtime
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestConverged(t *testing.T) {
+	tests := []struct {
+		a, b uint64
+		want bool
+	}{
+		{a: 1 << 20, b: 1 << 20, want: true},
+		{a: 100 << 10, b: 110 << 10, want: true},         // small heap, within 16 KB
+		{a: 100 << 20, b: 100<<20 + 512<<10, want: true}, // within 1%
+		{a: 100 << 20, b: 102 << 20, want: false},
+		{a: 102 << 20, b: 100 << 20, want: false},
+	}
+	for _, tt := range tests {
+		if got := converged(tt.a, tt.b); got != tt.want {
+			t.Errorf("converged(%d, %d) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+var stableSink []byte
+
+func TestStableHeap(t *testing.T) {
+	before := StableHeap()
+	for i := 0; i < 100; i++ {
+		stableSink = make([]byte, 64<<10)
+	}
+	stableSink = nil
+
+	after := StableHeap()
+	if growth := int64(after.HeapAlloc) - int64(before.HeapAlloc); growth > 1<<20 {
+		t.Errorf("StableHeap() left %d bytes of garbage in the heap", growth)
+	}
+
+	var check runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&check)
+	if !converged(after.HeapAlloc, check.HeapAlloc) {
+		t.Errorf("HeapAlloc moved from %d to %d after StableHeap()", after.HeapAlloc, check.HeapAlloc)
+	}
+}
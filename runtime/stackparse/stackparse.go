@@ -0,0 +1,250 @@
+// Package stackparse parses goroutine stack dumps as produced by
+// runtime.Stack(buf, true) or a panic/SIGQUIT traceback into structured
+// goroutine records.
+//
+// Example:
+//
+//	buf := make([]byte, 1<<20)
+//	n := runtime.Stack(buf, true)
+//	for _, g := range stackparse.ParseStacks(buf[:n]) {
+//	    fmt.Println(g.ID, g.State, g.WaitDuration, g.TopFunction())
+//	}
+package stackparse
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frame is a single function call in a goroutine stack
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// GoroutineInfo contains information about a goroutine
+type GoroutineInfo struct {
+	ID    int
+	State string
+	Stack string
+
+	// WaitDuration is how long the goroutine has been blocked, as reported
+	// by the runtime. The runtime only reports waits of a minute or more,
+	// so shorter waits are zero.
+	WaitDuration time.Duration
+
+	// LockedToThread is set when the goroutine called runtime.LockOSThread
+	LockedToThread bool
+
+	// Frames is the call stack, innermost call first
+	Frames []Frame
+
+	// CreatedBy is the frame of the go statement that started the
+	// goroutine. It is nil for the main goroutine and runtime goroutines
+	// without creator information.
+	CreatedBy *Frame
+
+	// CreatorID is the ID of the goroutine that executed the go statement,
+	// or 0 if the runtime did not report it (Go < 1.21).
+	CreatorID int
+
+	// Labels are the pprof labels of the goroutine, e.g. set with
+	// pprof.Do, from the braces the header ends with in
+	// `goroutine 18 [select] {component: billing, "request id": 42}:`,
+	// keys and values quoted only when they need it. The runtime prints
+	// them from Go 1.26 with GODEBUG=tracebacklabels=1, which is the
+	// default from Go 1.27 for main modules declaring go 1.27 or later;
+	// nil when the header has none.
+	Labels map[string]string
+}
+
+// TopFunction returns the innermost function of the stack, or "" if the
+// stack has no frames.
+func (g GoroutineInfo) TopFunction() string {
+	if len(g.Frames) == 0 {
+		return ""
+	}
+	return g.Frames[0].Function
+}
+
+// LabelString returns the labels as "key=value" pairs sorted by key,
+// e.g. "component=billing, request=42", or "" without labels
+func (g GoroutineInfo) LabelString() string {
+	keys := make([]string, 0, len(g.Labels))
+	for k := range g.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + g.Labels[k]
+	}
+	return strings.Join(pairs, ", ")
+}
+
+var (
+	// goroutine 18 [chan receive, 5 minutes]:
+	// goroutine 18 gp=0xc000007a40 m=nil [select]:
+	// goroutine 18 [select] {component: billing, "request id": 42}:
+	headerRe = regexp.MustCompile(`^goroutine (\d+)(?: [^\[]*)? \[([^\]]+)\](?: \{(.*)\})?:?$`)
+
+	// 	/path/to/file.go:42 +0x1d
+	locationRe = regexp.MustCompile(`^\s+(.+):(\d+)(?: \+0x[0-9a-f]+)?$`)
+
+	// created by main.main in goroutine 1
+	createdByRe = regexp.MustCompile(`^created by (\S+)(?: in goroutine (\d+))?$`)
+
+	// 5 minutes
+	waitRe = regexp.MustCompile(`^(\d+) minutes?$`)
+
+	// main.worker(0xc000012345, 0x1)
+	argsRe = regexp.MustCompile(`\([^()]*\)$`)
+)
+
+// ParseStacks parses a full goroutine dump into one GoroutineInfo per
+// goroutine, in the order they appear in the dump.
+func ParseStacks(dump []byte) []GoroutineInfo {
+	var result []GoroutineInfo
+	var current *GoroutineInfo
+	var stack strings.Builder
+	var pendingFunc string
+	inCreatedBy := false
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Stack = strings.TrimRight(stack.String(), "\n")
+		result = append(result, *current)
+		current = nil
+		stack.Reset()
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(dump))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(dump)+1)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if m := headerRe.FindStringSubmatch(line); m != nil {
+			flush()
+			id, _ := strconv.Atoi(m[1])
+			current = &GoroutineInfo{ID: id}
+			parseState(current, m[2])
+			if m[3] != "" {
+				current.Labels = ParseLabels(m[3], ": ")
+			}
+			stack.WriteString(line)
+			stack.WriteString("\n")
+			pendingFunc = ""
+			inCreatedBy = false
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+
+		stack.WriteString(line)
+		stack.WriteString("\n")
+
+		if m := createdByRe.FindStringSubmatch(line); m != nil {
+			pendingFunc = m[1]
+			inCreatedBy = true
+			if m[2] != "" {
+				current.CreatorID, _ = strconv.Atoi(m[2])
+			}
+			continue
+		}
+
+		if m := locationRe.FindStringSubmatch(line); m != nil && pendingFunc != "" {
+			lineNum, _ := strconv.Atoi(m[2])
+			frame := Frame{Function: pendingFunc, File: m[1], Line: lineNum}
+			if inCreatedBy {
+				current.CreatedBy = &frame
+			} else {
+				current.Frames = append(current.Frames, frame)
+			}
+			pendingFunc = ""
+			continue
+		}
+
+		// Function line, e.g. "main.worker(...)"; its location follows
+		if !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, "...") {
+			pendingFunc = argsRe.ReplaceAllString(line, "")
+		}
+	}
+	flush()
+
+	return result
+}
+
+// parseState splits the bracketed header, e.g. "chan receive, 5 minutes,
+// locked to thread", into state, wait duration, and thread lock.
+func parseState(g *GoroutineInfo, header string) {
+	parts := strings.Split(header, ", ")
+	g.State = parts[0]
+	for _, p := range parts[1:] {
+		if m := waitRe.FindStringSubmatch(p); m != nil {
+			minutes, _ := strconv.Atoi(m[1])
+			g.WaitDuration = time.Duration(minutes) * time.Minute
+			continue
+		}
+		if p == "locked to thread" {
+			g.LockedToThread = true
+		}
+	}
+}
+
+// ParseLabels parses a list of labels: pairs separated by ", ", each a
+// key and value separated by sep, either of which may be a quoted Go
+// string. That is the form of traceback headers, with ": ", and of the
+// "# labels:" lines of goroutine profiles, with ":" and quoted strings.
+// Malformed pairs end the list.
+func ParseLabels(list, sep string) map[string]string {
+	labels := make(map[string]string)
+	for list != "" {
+		key, rest, ok := labelToken(list, sep)
+		if !ok || !strings.HasPrefix(rest, sep) {
+			break
+		}
+		value, rest, ok := labelToken(rest[len(sep):], ", ")
+		if !ok {
+			break
+		}
+		labels[key] = value
+		list = strings.TrimLeft(strings.TrimPrefix(rest, ","), " ")
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+// labelToken returns the key or value at the start of s, unquoted, and
+// the rest of s, which starts at end when the token is not quoted
+func labelToken(s, end string) (string, string, bool) {
+	if strings.HasPrefix(s, `"`) {
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return "", "", false
+		}
+		token, err := strconv.Unquote(quoted)
+		return token, s[len(quoted):], err == nil
+	}
+	if i := strings.Index(s, end); i >= 0 {
+		return s[:i], s[i:], true
+	}
+	return s, "", true
+}
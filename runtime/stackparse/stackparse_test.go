@@ -0,0 +1,147 @@
+package stackparse
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+const sampleDump = `goroutine 1 [running]:
+main.main()
+	/app/main.go:10 +0x1d
+
+goroutine 18 [chan receive, 5 minutes]:
+main.worker(0xc000012345)
+	/app/worker.go:42 +0x25
+created by main.startWorkers in goroutine 1
+	/app/worker.go:20 +0x45
+
+goroutine 7 [select, locked to thread]:
+runtime.gopark(0x0?, 0x0?, 0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/proc.go:424 +0xce
+main.(*Server).loop(...)
+	/app/server.go:88
+created by main.(*Server).Start
+	/app/server.go:30 +0x8a
+`
+
+func TestParseStacks(t *testing.T) {
+	got := ParseStacks([]byte(sampleDump))
+	if len(got) != 3 {
+		t.Fatalf("ParseStacks() got %d goroutines, want 3", len(got))
+	}
+
+	main := got[0]
+	if main.ID != 1 || main.State != "running" {
+		t.Errorf("goroutine 0 = %d [%s], want 1 [running]", main.ID, main.State)
+	}
+	if main.TopFunction() != "main.main" {
+		t.Errorf("TopFunction() = %q, want main.main", main.TopFunction())
+	}
+	if main.CreatedBy != nil {
+		t.Errorf("main goroutine should have no creator, got %+v", main.CreatedBy)
+	}
+
+	worker := got[1]
+	if worker.State != "chan receive" {
+		t.Errorf("State = %q, want 'chan receive'", worker.State)
+	}
+	if worker.WaitDuration != 5*time.Minute {
+		t.Errorf("WaitDuration = %v, want 5m", worker.WaitDuration)
+	}
+	if len(worker.Frames) != 1 || worker.Frames[0].Function != "main.worker" {
+		t.Errorf("Frames = %+v, want [main.worker]", worker.Frames)
+	}
+	if worker.Frames[0].File != "/app/worker.go" || worker.Frames[0].Line != 42 {
+		t.Errorf("Frame location = %s:%d, want /app/worker.go:42", worker.Frames[0].File, worker.Frames[0].Line)
+	}
+	if worker.CreatedBy == nil || worker.CreatedBy.Function != "main.startWorkers" {
+		t.Fatalf("CreatedBy = %+v, want main.startWorkers", worker.CreatedBy)
+	}
+	if worker.CreatorID != 1 {
+		t.Errorf("CreatorID = %d, want 1", worker.CreatorID)
+	}
+
+	server := got[2]
+	if !server.LockedToThread {
+		t.Error("expected LockedToThread")
+	}
+	if len(server.Frames) != 2 || server.Frames[1].Function != "main.(*Server).loop" {
+		t.Errorf("Frames = %+v, want [runtime.gopark main.(*Server).loop]", server.Frames)
+	}
+	if server.CreatorID != 0 {
+		t.Errorf("CreatorID = %d, want 0 when not reported", server.CreatorID)
+	}
+}
+
+func TestParseStacksLive(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		<-done
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	buf := make([]byte, 1<<20)
+	n := runtime.Stack(buf, true)
+	got := ParseStacks(buf[:n])
+
+	found := false
+	for _, g := range got {
+		if g.State == "chan receive" && g.CreatedBy != nil {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a blocked goroutine with a creator in %d parsed goroutines", len(got))
+	}
+}
+
+func TestParseStacksEmpty(t *testing.T) {
+	if got := ParseStacks(nil); len(got) != 0 {
+		t.Errorf("ParseStacks(nil) = %v, want empty", got)
+	}
+}
+
+func TestParseStacksLabels(t *testing.T) {
+	dump := `goroutine 18 [chan receive] {component: billing, "request id": "42 \"a\""}:
+main.worker(...)
+	/app/worker.go:42
+`
+	got := ParseStacks([]byte(dump))
+	if len(got) != 1 || got[0].State != "chan receive" {
+		t.Fatalf("ParseStacks() = %+v, want one goroutine in chan receive", got)
+	}
+	if want := `component=billing, request id=42 "a"`; got[0].LabelString() != want {
+		t.Errorf("LabelString() = %q, want %q", got[0].LabelString(), want)
+	}
+	if (GoroutineInfo{}).LabelString() != "" {
+		t.Error("LabelString() of a goroutine without labels is not empty")
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		list, sep string
+		want      map[string]string
+	}{
+		{"component: billing", ": ", map[string]string{"component": "billing"}},
+		{"a: 1, b: x/y.z", ": ", map[string]string{"a": "1", "b": "x/y.z"}},
+		{`"k 1": "v, 2", k2: v3`, ": ", map[string]string{"k 1": "v, 2", "k2": "v3"}},
+		{`"component":"billing", "req":"42"`, ":", map[string]string{"component": "billing", "req": "42"}},
+		{`a: 1, "broken`, ": ", map[string]string{"a": "1"}},
+		{"", ": ", nil},
+	}
+	for _, tt := range tests {
+		got := ParseLabels(tt.list, tt.sep)
+		if len(got) != len(tt.want) {
+			t.Errorf("ParseLabels(%q) = %v, want %v", tt.list, got, tt.want)
+			continue
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("ParseLabels(%q)[%q] = %q, want %q", tt.list, k, got[k], v)
+			}
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package runtime
+
+import (
+	"os"
+	"runtime"
+)
+
+// osThreads returns the number of OS threads of the process. On Linux it
+// counts the tasks in /proc/self/task, which include the threads C
+// libraries start; elsewhere it falls back to threadsCreated, in which
+// threads that exited still count.
+func osThreads() int {
+	if tasks, err := os.ReadDir("/proc/self/task"); err == nil {
+		return len(tasks)
+	}
+	return threadsCreated()
+}
+
+// threadsCreated returns how many OS threads the Go runtime has created
+func threadsCreated() int {
+	n, _ := runtime.ThreadCreateProfile(nil)
+	return n
+}
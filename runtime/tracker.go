@@ -0,0 +1,109 @@
+package runtime
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TrackedObject describes an object registered with TrackObject
+type TrackedObject struct {
+	Label     string
+	Type      string
+	TrackedAt time.Time
+	seq       uint64
+}
+
+// tracker holds objects registered via TrackObject that have not yet
+// been collected by the garbage collector.
+var tracker = struct {
+	sync.Mutex
+	seq     uint64
+	pending map[uint64]TrackedObject
+}{pending: make(map[uint64]TrackedObject)}
+
+// TrackObject registers ptr so that a later Compare reports it if it was
+// never garbage collected. Use it to assert that caches, buffers, or
+// connections are actually released once the code under test is done.
+//
+//	snapshot := runtime.TakeSnapshot()
+//	cache := NewCache()
+//	runtime.TrackObject(cache, "cache")
+//	cache.Close()
+//	cache = nil
+//
+//	diff := snapshot.Compare()
+//	// diff.UncollectedObjects contains "cache" if it is still reachable
+//
+// ptr must be a pointer to the beginning of an allocated object that has
+// no finalizer set. Tiny pointer-free objects (<16 bytes) may be batched
+// by the allocator and never finalized, so track the enclosing struct.
+func TrackObject(ptr interface{}, label string) {
+	tracker.Lock()
+	tracker.seq++
+	seq := tracker.seq
+	tracker.pending[seq] = TrackedObject{
+		Label:     label,
+		Type:      fmt.Sprintf("%T", ptr),
+		TrackedAt: time.Now(),
+		seq:       seq,
+	}
+	tracker.Unlock()
+
+	runtime.SetFinalizer(ptr, func(interface{}) {
+		tracker.Lock()
+		delete(tracker.pending, seq)
+		tracker.Unlock()
+	})
+}
+
+// trackerSeq returns the sequence number of the most recently tracked object
+func trackerSeq() uint64 {
+	tracker.Lock()
+	defer tracker.Unlock()
+	return tracker.seq
+}
+
+// uncollectedSince returns objects tracked after seq that are still alive.
+// The caller is expected to have run a GC cycle; finalizers run on a
+// separate goroutine, so a few extra cycles are given to drain them.
+func uncollectedSince(seq uint64) []TrackedObject {
+	for i := 0; i < 3; i++ {
+		if len(pendingSince(seq)) == 0 {
+			return nil
+		}
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	return pendingSince(seq)
+}
+
+func pendingSince(seq uint64) []TrackedObject {
+	tracker.Lock()
+	defer tracker.Unlock()
+
+	var objs []TrackedObject
+	for s, obj := range tracker.pending {
+		if s > seq {
+			objs = append(objs, obj)
+		}
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		return objs[i].seq < objs[j].seq
+	})
+	return objs
+}
+
+// FormatUncollected formats tracked objects that were not collected for
+// error output, each on a line of its own after a newline, indented
+func FormatUncollected(objs []TrackedObject) string {
+	var sb strings.Builder
+	for _, obj := range objs {
+		sb.WriteString(fmt.Sprintf("\n  %s (%s, tracked %s ago)",
+			obj.Label, obj.Type, time.Since(obj.TrackedAt).Round(time.Millisecond)))
+	}
+	return sb.String()
+}
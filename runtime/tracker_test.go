@@ -0,0 +1,63 @@
+package runtime_test
+
+import (
+	"testing"
+
+	"github.com/harshakonda/heapcheck/runtime"
+)
+
+type trackedBuffer struct {
+	data []byte
+}
+
+var retained *trackedBuffer
+
+func TestTrackObject_Collected(t *testing.T) {
+	snapshot := runtime.TakeSnapshot()
+
+	buf := &trackedBuffer{data: make([]byte, 1024)}
+	runtime.TrackObject(buf, "buffer")
+	buf = nil
+	_ = buf
+
+	diff := snapshot.Compare()
+	if len(diff.UncollectedObjects) != 0 {
+		t.Errorf("expected tracked object to be collected, got %v", diff.UncollectedObjects)
+	}
+}
+
+func TestTrackObject_Retained(t *testing.T) {
+	snapshot := runtime.TakeSnapshot()
+
+	retained = &trackedBuffer{data: make([]byte, 1024)}
+	runtime.TrackObject(retained, "cache")
+
+	diff := snapshot.Compare()
+	if len(diff.UncollectedObjects) != 1 {
+		t.Fatalf("expected 1 uncollected object, got %d", len(diff.UncollectedObjects))
+	}
+	if diff.UncollectedObjects[0].Label != "cache" {
+		t.Errorf("expected label 'cache', got %q", diff.UncollectedObjects[0].Label)
+	}
+
+	mockT := &MockT{}
+	snapshot.AssertNoLeakWithOptions(mockT, runtime.Options{RetryCount: 1})
+	if len(mockT.errors) == 0 {
+		t.Error("expected AssertNoLeak to report the retained object")
+	}
+
+	retained = nil
+}
+
+func TestTrackObject_BeforeSnapshotIgnored(t *testing.T) {
+	retained = &trackedBuffer{data: make([]byte, 1024)}
+	runtime.TrackObject(retained, "long-lived")
+
+	snapshot := runtime.TakeSnapshot()
+	diff := snapshot.Compare()
+	if len(diff.UncollectedObjects) != 0 {
+		t.Errorf("objects tracked before the snapshot should be ignored, got %v", diff.UncollectedObjects)
+	}
+
+	retained = nil
+}
@@ -0,0 +1,74 @@
+package runtime
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/harshakonda/heapcheck/runtime/stackparse"
+)
+
+// waitInterval is how often the wait functions check the goroutines
+const waitInterval = 5 * time.Millisecond
+
+// WaitForGoroutinesBelow waits until fewer than n goroutines are running,
+// for at most timeout. Call it before a leak check, after closing what
+// the test started, to wait for asynchronous teardown instead of sleeping
+// for a fixed settle time. It returns nil as soon as the count drops, and
+// an error with the count once the timeout has passed.
+func WaitForGoroutinesBelow(n int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		count := runtime.NumGoroutine()
+		if count < n {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("%d goroutine(s) still running after %v, want fewer than %d", count, timeout, n)
+		}
+		time.Sleep(waitInterval)
+	}
+}
+
+// WaitUntilNoneMatching waits until no goroutine's stack contains pattern
+// (case-insensitive, as GoroutineFilter patterns are), for at most
+// timeout. The pattern is usually a function of the goroutines to wait
+// for, e.g. "(*Pool).worker"; the calling goroutine is not matched. It
+// returns nil as soon as none match, and an error listing the matching
+// goroutines once the timeout has passed.
+func WaitUntilNoneMatching(pattern string, timeout time.Duration) error {
+	self := currentGoroutineID()
+	lower := strings.ToLower(pattern)
+	deadline := time.Now().Add(timeout)
+	for {
+		var matching []GoroutineInfo
+		for _, g := range captureGoroutines() {
+			if g.ID != self && strings.Contains(strings.ToLower(g.Stack), lower) {
+				matching = append(matching, g)
+			}
+		}
+		if len(matching) == 0 {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			var b strings.Builder
+			for _, g := range matching {
+				fmt.Fprintf(&b, "\n  goroutine %d [%s] %s", g.ID, g.State, g.TopFunction())
+			}
+			return fmt.Errorf("%d goroutine(s) matching %q still running after %v:%s", len(matching), pattern, timeout, b.String())
+		}
+		time.Sleep(waitInterval)
+	}
+}
+
+// currentGoroutineID returns the ID of the calling goroutine, or 0 if its
+// stack cannot be parsed
+func currentGoroutineID() int {
+	buf := make([]byte, 64<<10)
+	n := runtime.Stack(buf, false)
+	if gs := stackparse.ParseStacks(buf[:n]); len(gs) > 0 {
+		return gs[0].ID
+	}
+	return 0
+}
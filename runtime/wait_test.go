@@ -0,0 +1,59 @@
+package runtime
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startWaitWorkers starts n goroutines that block until done is closed,
+// and end a little later
+func startWaitWorkers(n int, done chan struct{}) {
+	var started sync.WaitGroup
+	started.Add(n)
+	for i := 0; i < n; i++ {
+		go waitWorker(&started, done)
+	}
+	started.Wait()
+}
+
+func waitWorker(started *sync.WaitGroup, done chan struct{}) {
+	started.Done()
+	<-done
+	time.Sleep(20 * time.Millisecond)
+}
+
+func TestWaitForGoroutinesBelow(t *testing.T) {
+	before := runtime.NumGoroutine()
+	done := make(chan struct{})
+	startWaitWorkers(5, done)
+
+	if err := WaitForGoroutinesBelow(before+1, 20*time.Millisecond); err == nil || !strings.Contains(err.Error(), "want fewer than") {
+		t.Errorf("WaitForGoroutinesBelow() with the workers running = %v, want a timeout", err)
+	}
+	close(done)
+	if err := WaitForGoroutinesBelow(before+1, 5*time.Second); err != nil {
+		t.Errorf("WaitForGoroutinesBelow() after the workers were released = %v", err)
+	}
+}
+
+func TestWaitUntilNoneMatching(t *testing.T) {
+	// The caller's own stack, which has the test's name, is not matched
+	if err := WaitUntilNoneMatching("TestWaitUntilNoneMatching", 0); err != nil {
+		t.Errorf("WaitUntilNoneMatching() matched the calling goroutine: %v", err)
+	}
+
+	done := make(chan struct{})
+	startWaitWorkers(3, done)
+
+	err := WaitUntilNoneMatching("runtime.WAITWORKER", 20*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "3 goroutine(s) matching") {
+		t.Errorf("WaitUntilNoneMatching() with the workers running = %v, want a timeout naming 3 goroutines", err)
+	}
+	close(done)
+	if err := WaitUntilNoneMatching("runtime.waitWorker", 5*time.Second); err != nil {
+		t.Errorf("WaitUntilNoneMatching() after the workers were released = %v", err)
+	}
+}
@@ -2,11 +2,22 @@ package integration
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // getHeapcheckBinary builds and returns path to heapcheck binary
@@ -57,8 +68,8 @@ func TestHeapcheckOnExamples(t *testing.T) {
 		name string
 		path string
 	}{
-		{"basic-patterns", "examples/basic-patterns/..."},
-		{"testdata", "testdata/..."},
+		{"basic-patterns", "./examples/basic-patterns/..."},
+		{"testdata", "./testdata"},
 	}
 
 	for _, ex := range examples {
@@ -138,7 +149,7 @@ func TestHeapcheckFormats(t *testing.T) {
 
 	for _, f := range formats {
 		t.Run(f.name, func(t *testing.T) {
-			cmd := exec.Command(binary, "--format="+f.flag, "./testdata/...")
+			cmd := exec.Command(binary, "--format="+f.flag, "./testdata")
 			cmd.Dir = projectRoot
 
 			output, err := cmd.CombinedOutput()
@@ -177,7 +188,7 @@ func TestHeapcheckEscapesOnly(t *testing.T) {
 	binary := getHeapcheckBinary(t)
 	projectRoot := getProjectRoot(t)
 
-	cmd := exec.Command(binary, "--escapes-only", "--format=json", "./testdata/...")
+	cmd := exec.Command(binary, "--escapes-only", "--format=json", "./testdata")
 	cmd.Dir = projectRoot
 
 	output, err := cmd.CombinedOutput()
@@ -205,6 +216,30 @@ func TestHeapcheckVersion(t *testing.T) {
 	}
 }
 
+func TestHeapcheckVersionJSON(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+
+	for _, args := range [][]string{
+		{"--version", "--format=json"},
+		{"render", "--version", "--format=json"},
+	} {
+		output, err := exec.Command(binary, args...).Output()
+		if err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+		var v struct {
+			Version   string `json:"version"`
+			GoVersion string `json:"goVersion"`
+		}
+		if err := json.Unmarshal(output, &v); err != nil {
+			t.Fatalf("%v: invalid JSON: %v\n%s", args, err, output)
+		}
+		if v.Version == "" || !strings.HasPrefix(v.GoVersion, "go") {
+			t.Errorf("%v: version = %q, goVersion = %q", args, v.Version, v.GoVersion)
+		}
+	}
+}
+
 func TestHeapcheckHelp(t *testing.T) {
 	binary := getHeapcheckBinary(t)
 
@@ -259,9 +294,2241 @@ func TestHeapcheckOnItself(t *testing.T) {
 	}
 }
 
+func TestHeapcheckLeaks(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	projectRoot := getProjectRoot(t)
+
+	cmd := exec.Command(binary, "leaks", "--format=json", "./examples/...")
+	cmd.Dir = projectRoot
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("leaks failed: %v\n%s", err, output)
+	}
+
+	for _, check := range []string{`"summary"`, `"escapes"`} {
+		if !strings.Contains(string(output), check) {
+			t.Errorf("leaks JSON missing: %s", check)
+		}
+	}
+}
+
+func TestHeapcheckGoListQuery(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	projectRoot := getProjectRoot(t)
+
+	cmd := exec.Command(binary, "-v", "--go-list-query=deps(./examples/basic-patterns)")
+	cmd.Dir = projectRoot
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("--go-list-query failed: %v\n%s", err, output)
+	}
+	if !strings.Contains(string(output), "basic-patterns") {
+		t.Errorf("--go-list-query output missing basic-patterns escapes")
+	}
+	if strings.Contains(string(output), "worker-pool") {
+		t.Errorf("--go-list-query analyzed packages outside the dependency closure")
+	}
+}
+
+func TestHeapcheckRenderDiff(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	projectRoot := getProjectRoot(t)
+	dir := t.TempDir()
+
+	results := map[string]string{
+		"old.json": "./examples/json-processor",
+		"new.json": "./examples/basic-patterns",
+	}
+	for name, pkg := range results {
+		cmd := exec.Command(binary, "--format=json", pkg)
+		cmd.Dir = projectRoot
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("heapcheck %s failed: %v", pkg, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), output, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(binary, "render", "--diff", filepath.Join(dir, "old.json"), filepath.Join(dir, "new.json"), "--format=html")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("render --diff failed: %v\n%s", err, output)
+	}
+	for _, check := range []string{"heapcheck Diff", "diff-added", "diff-resolved", "patterns.go", "processor.go"} {
+		if !strings.Contains(string(output), check) {
+			t.Errorf("render --diff output missing: %s", check)
+		}
+	}
+}
+
+func TestHeapcheckSite(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	projectRoot := getProjectRoot(t)
+	dir := t.TempDir()
+
+	cmd := exec.Command(binary, "--format=json", "./examples/basic-patterns")
+	cmd.Dir = projectRoot
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("heapcheck failed: %v", err)
+	}
+	results := filepath.Join(dir, "results.json")
+	if err := os.WriteFile(results, output, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	public := filepath.Join(dir, "public")
+	cmd = exec.Command(binary, "site", "-o", public, "basics="+results)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("site failed: %v\n%s", err, output)
+	}
+	index, err := os.ReadFile(filepath.Join(public, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, check := range []string{`href="modules/basics.html"`, "examples/basic-patterns"} {
+		if !strings.Contains(string(index), check) {
+			t.Errorf("site index missing: %s", check)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(public, "modules", "basics.html")); err != nil {
+		t.Errorf("module report not written: %v", err)
+	}
+}
+
+func TestHeapcheckSaveRawInput(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	projectRoot := getProjectRoot(t)
+	raw := filepath.Join(t.TempDir(), "raw.txt")
+
+	cmd := exec.Command(binary, "--format=json", "--save-raw="+raw, "./examples/basic-patterns")
+	cmd.Dir = projectRoot
+	live, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("heapcheck --save-raw failed: %v", err)
+	}
+
+	cmd = exec.Command(binary, "--format=json", "--input="+raw)
+	cmd.Dir = projectRoot
+	replayed, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("heapcheck --input failed: %v", err)
+	}
+	// Run metadata (start time, duration) differs between the runs
+	if !reflect.DeepEqual(withoutMetadata(t, live), withoutMetadata(t, replayed)) {
+		t.Error("--input output differs from the run that saved it")
+	}
+}
+
+func TestHeapcheckModuleSettings(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+
+	// A module whose vendor/ directory is out of sync with go.mod
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":       "module vm\n\ngo 1.22\n\nrequire example.com/dep v0.0.0\n\nreplace example.com/dep => ./dep\n",
+		"vm.go":        "package vm\n\nimport \"example.com/dep\"\n\nvar V = dep.P()\n",
+		"dep/go.mod":   "module example.com/dep\n\ngo 1.22\n",
+		"dep/dep.go":   "package dep\n\nfunc P() *int { x := 1; return &x }\n",
+		"vendor/.keep": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env := append(os.Environ(), "GOFLAGS=", "GOWORK=off")
+
+	cmd := exec.Command(binary, "./...")
+	cmd.Dir = dir
+	cmd.Env = env
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("heapcheck succeeded with inconsistent vendoring:\n%s", output)
+	}
+	for _, want := range []string{"inconsistent vendoring", "--mod"} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("error output missing %q:\n%s", want, output)
+		}
+	}
+
+	cmd = exec.Command(binary, "--mod=mod", "./...")
+	cmd.Dir = dir
+	cmd.Env = env
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("heapcheck --mod=mod failed: %v\n%s", err, output)
+	}
+}
+
+func TestHeapcheckEmptyPatterns(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	projectRoot := getProjectRoot(t)
+
+	// docs/ holds no Go packages: go only warns, exiting 0
+	cmd := exec.Command(binary, "./docs/...")
+	cmd.Dir = projectRoot
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("heapcheck succeeded on a pattern matching no packages:\n%s", output)
+	}
+	if !strings.Contains(string(output), "no Go packages matched ./docs/...") {
+		t.Errorf("error output missing the unmatched pattern:\n%s", output)
+	}
+
+	// A package that compiles without any escape analysis output
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module empty\n\ngo 1.22\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "empty.go"), []byte("package empty\n\nconst N = 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmd = exec.Command(binary, "./...")
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("heapcheck failed on a package without escapes: %v\n%s", err, output)
+	}
+
+	cmd = exec.Command(binary, "--strict-empty", "./...")
+	cmd.Dir = dir
+	output, err = cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("heapcheck --strict-empty succeeded without results:\n%s", output)
+	}
+	if !strings.Contains(string(output), "--strict-empty") {
+		t.Errorf("error output does not mention --strict-empty:\n%s", output)
+	}
+}
+
+func TestHeapcheckExitCodes(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":          "module codes\n\ngo 1.22\n",
+		"ok/ok.go":        "package ok\n\nfunc New() *int { x := 1; return &x }\n",
+		"broken/b.go":     "package broken\n\nfunc F() *int { z := 3; return &z\n",
+		"gate.yaml":       "categories:\n  return-pointer: fail\n",
+		"other/go.mod":    "module other\n\ngo 1.22\n",
+		"other/other.go":  "package other\n\nimport \"example.com/missing\"\n\nvar _ = missing.X\n",
+		"tagged/never.go": "//go:build never\n\npackage tagged\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		args []string
+		want int
+	}{
+		{"clean", dir, []string{"./ok", "./tagged/..."}, 0},
+		{"invalid flag", dir, []string{"--no-such-flag", "./ok"}, 1},
+		{"unknown format", dir, []string{"--format=xml", "./ok"}, 1},
+		{"gate failure", dir, []string{"--config=gate.yaml", "./ok"}, 2},
+		{"build failure", filepath.Join(dir, "other"), []string{"--mod=readonly", "./..."}, 3},
+		{"partial analysis", dir, []string{"./ok", "./broken"}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(binary, tt.args...)
+			cmd.Dir = tt.dir
+			cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off", "HEAPCHECK_DAEMON=off")
+			out, err := cmd.CombinedOutput()
+			code := 0
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if code != tt.want {
+				t.Errorf("heapcheck %s exited with %d, want %d\n%s", strings.Join(tt.args, " "), code, tt.want, out)
+			}
+		})
+	}
+}
+
+func TestHeapcheckOutcomeLine(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":    "module outcome\n\ngo 1.22\n",
+		"ok/ok.go":  "package ok\n\nfunc New() *int { x := 1; return &x }\n",
+		"gate.yaml": "categories:\n  return-pointer: fail\n",
+		"warn.yaml": "categories:\n  return-pointer: warn\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"text", []string{"./ok"}, `^heapcheck: total=\d+ heap=\d+ stack=\d+ gates=pass$`},
+		{"json", []string{"--format=json", "./ok"}, `^heapcheck: total=\d+ heap=\d+ stack=\d+ gates=pass$`},
+		{"warning gate", []string{"--config=warn.yaml", "./ok"}, `^heapcheck: total=\d+ heap=\d+ stack=\d+ gates=warn$`},
+		{"failing gate", []string{"--config=gate.yaml", "--format=sarif", "./ok"}, `^heapcheck: total=\d+ heap=\d+ stack=\d+ gates=fail$`},
+		{"unreadable input", []string{"--input=missing.txt"}, `^heapcheck: total=0 heap=0 stack=0 gates=error$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(binary, tt.args...)
+			cmd.Dir = dir
+			cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off", "HEAPCHECK_DAEMON=off")
+			var stderr bytes.Buffer
+			cmd.Stdout, cmd.Stderr = io.Discard, &stderr
+			_ = cmd.Run()
+			lines := strings.Split(strings.TrimRight(stderr.String(), "\n"), "\n")
+			if last := lines[len(lines)-1]; !regexp.MustCompile(tt.want).MatchString(last) {
+				t.Errorf("last line on stderr = %q, want %s\n%s", last, tt.want, stderr.String())
+			}
+		})
+	}
+}
+
+func TestHeapcheckSample(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	files := map[string]string{"go.mod": "module sampled\n\ngo 1.22\n"}
+	for i := 0; i < 20; i++ {
+		files[fmt.Sprintf("p%d/p.go", i)] = fmt.Sprintf("package p%d\n\nfunc New() *int { x := %d; return &x }\n", i, i)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run := func() []byte {
+		cmd := exec.Command(binary, "--format=json", "--sample=30%", "./...")
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off", "HEAPCHECK_DAEMON=off")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("heapcheck --sample failed: %v", err)
+		}
+		return out
+	}
+	var report struct {
+		Summary struct {
+			HeapAllocated int `json:"heapAllocated"`
+		} `json:"summary"`
+		Metadata struct {
+			Sample struct {
+				Packages      int `json:"packages"`
+				TotalPackages int `json:"totalPackages"`
+				Estimated     struct {
+					HeapAllocated int `json:"heapAllocated"`
+				} `json:"estimated"`
+			} `json:"sample"`
+		} `json:"metadata"`
+	}
+	out := run()
+	if err := json.Unmarshal(out, &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	s := report.Metadata.Sample
+	if s.TotalPackages != 20 || s.Packages == 0 || s.Packages >= 20 {
+		t.Fatalf("metadata.sample = %+v, want a subset of 20 packages", s)
+	}
+	// Every package has the same escapes, so the estimate is exact
+	perPackage := report.Summary.HeapAllocated / s.Packages
+	if perPackage == 0 || report.Summary.HeapAllocated%s.Packages != 0 {
+		t.Errorf("summary.heapAllocated = %d, want the escapes of %d packages", report.Summary.HeapAllocated, s.Packages)
+	}
+	if s.Estimated.HeapAllocated != 20*perPackage {
+		t.Errorf("estimated heapAllocated = %d, want %d", s.Estimated.HeapAllocated, 20*perPackage)
+	}
+	if again := run(); string(again) == "" || !bytes.Contains(again, []byte(fmt.Sprintf(`"packages": %d`, s.Packages))) {
+		t.Error("a second run sampled other packages")
+	}
+}
+
+func TestHeapcheckPlan(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":               "module planapp\n\ngo 1.22\n",
+		"internal/a/a.go":      "package a\n\nfunc New() *int { x := 1; return &x }\n",
+		"cmd/tool/main.go":     "package main\n\nfunc main() {}\n",
+		"windows/only.go":      "//go:build never\n\npackage windows\n",
+		"internal/a/a_test.go": "package a\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(binary, "--plan", "--format=json", "--filter=planapp/internal", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("heapcheck --plan failed: %v\n%s", err, out)
+	}
+	var plan struct {
+		Packages []struct {
+			Package string `json:"package"`
+			Files   int    `json:"files"`
+			Lines   int    `json:"lines"`
+		} `json:"packages"`
+		Excluded []struct {
+			Package string `json:"package"`
+			Reason  string `json:"reason"`
+		} `json:"excluded"`
+	}
+	if err := json.Unmarshal(out, &plan); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out)
+	}
+	if len(plan.Packages) != 1 || plan.Packages[0].Package != "planapp/internal/a" || plan.Packages[0].Files != 1 || plan.Packages[0].Lines != 3 {
+		t.Errorf("packages = %+v, want planapp/internal/a alone", plan.Packages)
+	}
+	reasons := make(map[string]string)
+	for _, p := range plan.Excluded {
+		reasons[p.Package] = p.Reason
+	}
+	if !strings.Contains(reasons["planapp/cmd/tool"], "--filter") {
+		t.Errorf("planapp/cmd/tool excluded for %q, want --filter", reasons["planapp/cmd/tool"])
+	}
+	if !strings.Contains(reasons["planapp/windows"], "build constraints") {
+		t.Errorf("planapp/windows excluded for %q, want build constraints", reasons["planapp/windows"])
+	}
+
+	cmd = exec.Command(binary, "--plan", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off")
+	out, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("heapcheck --plan failed: %v\n%s", err, out)
+	}
+	for _, want := range []string{"Plan: 2 package(s) to analyze", "planapp/internal/a", "Excluded (1 package(s)):"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("text plan missing %q:\n%s", want, out)
+		}
+	}
+}
+
+// withoutMetadata decodes JSON results and drops the run metadata
+func withoutMetadata(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	delete(m, "metadata")
+	return m
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
 	}
 	return b
 }
+
+func TestHeapcheckStats(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	projectRoot := getProjectRoot(t)
+
+	cmd := exec.Command(binary, "--stats", "--format=json", "./examples/basic-patterns")
+	cmd.Dir = projectRoot
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("heapcheck --stats failed: %v", err)
+	}
+	var report struct {
+		Metadata struct {
+			Stats *struct {
+				CompileMS    float64 `json:"compileMs"`
+				Packages     int     `json:"packages"`
+				Lines        int     `json:"lines"`
+				Unrecognized int     `json:"unrecognized"`
+			} `json:"stats"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(output, &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	stats := report.Metadata.Stats
+	if stats == nil {
+		t.Fatal("metadata has no stats")
+	}
+	if stats.CompileMS <= 0 || stats.Packages != 1 || stats.Lines == 0 {
+		t.Errorf("stats = %+v", *stats)
+	}
+	// Many unrecognized lines mean this Go version's output needs parser
+	// support
+	if stats.Unrecognized*20 > stats.Lines {
+		t.Errorf("%d of %d compiler lines unrecognized", stats.Unrecognized, stats.Lines)
+	}
+}
+
+func TestHeapcheckCaptureUnparsed(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "raw.txt")
+	unparsed := filepath.Join(dir, "unparsed.txt")
+
+	output := "# example.com/app\n" +
+		"./main.go:12:2: moved to heap: x\n" +
+		"./main.go:14:2: x future diagnostic\n"
+	if err := os.WriteFile(raw, []byte(output), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(binary, "--input="+raw, "--capture-unparsed="+unparsed)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("heapcheck --capture-unparsed failed: %v\n%s", err, stderr.String())
+	}
+	data, err := os.ReadFile(unparsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "./main.go:14:2: x future diagnostic\n"; got != want {
+		t.Errorf("unparsed lines = %q, want %q", got, want)
+	}
+	if !strings.Contains(stderr.String(), "1 unrecognized compiler line(s)") {
+		t.Errorf("stderr missing the unrecognized count:\n%s", stderr.String())
+	}
+}
+
+func TestHeapcheckGateBanner(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	projectRoot := getProjectRoot(t)
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "heapcheck.yaml")
+	summary := filepath.Join(dir, "summary.md")
+	if err := os.WriteFile(configFile, []byte("categories:\n  return-pointer: fail\n  reflection: warn>100\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(binary, "--config="+configFile, "--summary-markdown="+summary, "./examples/basic-patterns")
+	cmd.Dir = projectRoot
+	output, err := cmd.Output()
+	if err == nil {
+		t.Fatal("heapcheck succeeded with a failing gate")
+	}
+	text := strings.TrimRight(string(output), "\n")
+	lines := strings.Split(text, "\n")
+	if len(lines) < 3 || !strings.Contains(lines[len(lines)-3], "FAIL: 1 of 2 gate(s) failed") ||
+		!strings.Contains(lines[len(lines)-2], "return-pointer") {
+		t.Errorf("output does not end with the gate banner:\n%s", text)
+	}
+
+	md, err := os.ReadFile(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"### ❌ heapcheck FAIL: 1 of 2 gate(s) failed", "| ✅ | `reflection` |", "| ❌ | `return-pointer` |"} {
+		if !strings.Contains(string(md), want) {
+			t.Errorf("markdown summary missing %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestHeapcheckMaxUncategorizedPct(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "raw.txt")
+
+	// Two of the three escapes match no rule
+	output := "# example.com/app\n" +
+		"./main.go:12:2: moved to heap: x\n" +
+		"./main.go:14:2: y escapes to heap\n" +
+		"./main.go:15:9: &T{} escapes to heap\n"
+	if err := os.WriteFile(raw, []byte(output), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(binary, "--input="+raw, "--max-uncategorized-pct=20")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("heapcheck --max-uncategorized-pct=20 succeeded with 2 of 3 escapes uncategorized:\n%s", out)
+	}
+	if !strings.Contains(string(out), "66.7% of escapes are uncategorized (max 20%)") {
+		t.Errorf("error output does not report the uncategorized percentage:\n%s", out)
+	}
+
+	cmd = exec.Command(binary, "--input="+raw, "--max-uncategorized-pct=70")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("heapcheck --max-uncategorized-pct=70 failed: %v\n%s", err, out)
+	}
+}
+
+func TestHeapcheckOTLP(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "raw.txt")
+	if err := os.WriteFile(raw, []byte("# example.com/app\n./main.go:12:2: moved to heap: x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan string, 2)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r.URL.Path + " " + string(body)
+	}))
+	defer collector.Close()
+
+	cmd := exec.Command(binary, "--input="+raw, "--otlp-endpoint="+collector.URL)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("heapcheck --otlp-endpoint failed: %v\n%s", err, out)
+	}
+	close(received)
+	var paths []string
+	for r := range received {
+		path, body, _ := strings.Cut(r, " ")
+		paths = append(paths, path)
+		if path == "/v1/metrics" && !strings.Contains(body, `"heapcheck.escapes"`) {
+			t.Errorf("metrics request lacks heapcheck.escapes:\n%s", body)
+		}
+	}
+	if want := []string{"/v1/metrics", "/v1/traces"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("collector received %v, want %v", paths, want)
+	}
+
+	// A collector that is down is reported, not fatal
+	collector.Close()
+	cmd = exec.Command(binary, "--input="+raw, "--otlp-endpoint="+collector.URL)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil || !strings.Contains(string(out), "exporting to") {
+		t.Errorf("heapcheck with the collector down: err = %v, output:\n%s", err, out)
+	}
+}
+
+func TestHeapcheckUpload(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "raw.txt")
+	if err := os.WriteFile(raw, []byte("# example.com/app\n./main.go:12:2: moved to heap: x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	received := make(map[string]string)
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received[r.URL.Path] = r.Header.Get("X-Amz-Meta-Commit") + " " + string(body)
+		mu.Unlock()
+	}))
+	defer storage.Close()
+
+	cmd := exec.Command(binary, "--input="+raw, "--upload=s3://bucket/runs/{commit}")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "HEAPCHECK_DAEMON=off", "GITHUB_SHA=abc123",
+		"AWS_ACCESS_KEY_ID=key", "AWS_SECRET_ACCESS_KEY=secret", "AWS_ENDPOINT_URL_S3="+storage.URL)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("heapcheck --upload failed: %v\n%s", err, out)
+	}
+	for _, name := range []string{"heapcheck.json", "heapcheck.html", "metadata.json"} {
+		got, ok := received["/bucket/runs/abc123/"+name]
+		if !ok {
+			t.Errorf("%s was not uploaded, got %v", name, received)
+			continue
+		}
+		if !strings.HasPrefix(got, "abc123 ") {
+			t.Errorf("%s lacks the commit metadata: %.80s", name, got)
+		}
+	}
+	if got := received["/bucket/runs/abc123/metadata.json"]; !strings.Contains(got, `"commit": "abc123"`) || !strings.Contains(got, `"escapes": 1`) {
+		t.Errorf("metadata.json = %s", got)
+	}
+
+	// Missing credentials are caught before the analysis
+	cmd = exec.Command(binary, "--input="+raw, "--upload=gs://bucket")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "HEAPCHECK_DAEMON=off", "GOOGLE_OAUTH_ACCESS_TOKEN=")
+	out, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(out), "GOOGLE_OAUTH_ACCESS_TOKEN") {
+		t.Errorf("heapcheck --upload without a token: err = %v, output:\n%s", err, out)
+	}
+}
+
+func TestHeapcheckTestReport(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	root := getProjectRoot(t)
+	sum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A suite with a test that retains 8 MB and one that retains nothing,
+	// both verified with guard, and one that is not
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module tr\n\ngo 1.22\n\nrequire github.com/harshakonda/heapcheck v0.0.0\n\nreplace github.com/harshakonda/heapcheck => " + root + "\n",
+		"go.sum": string(sum),
+		"tr_test.go": `package tr
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/harshakonda/heapcheck/guard"
+)
+
+var kept [][]byte
+
+func TestHungry(t *testing.T) {
+	runtime.GC()
+	guard.VerifyNone(t)
+	kept = append(kept, make([]byte, 8<<20))
+}
+
+func TestLean(t *testing.T) {
+	runtime.GC()
+	guard.VerifyNone(t)
+}
+
+func TestPlain(t *testing.T) {}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(binary, "testreport", "--format=json", "./...", "--", "-count=1")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off", "GOPROXY=off")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("heapcheck testreport failed: %v\n%s", err, stderr.String())
+	}
+	var report struct {
+		Tests []struct {
+			Test            string `json:"test"`
+			Status          string `json:"status"`
+			HeapGrowthBytes int64  `json:"heapGrowthBytes"`
+		} `json:"tests"`
+		Unmeasured int `json:"unmeasured"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if len(report.Tests) != 2 || report.Unmeasured != 1 {
+		t.Fatalf("report = %+v, want 2 measured tests and 1 unmeasured", report)
+	}
+	if first := report.Tests[0]; first.Test != "TestHungry" || first.Status != "pass" || first.HeapGrowthBytes < 4<<20 {
+		t.Errorf("first test = %+v, want TestHungry with about 8 MB", first)
+	}
+}
+
+func TestHeapcheckOwners(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "raw.txt")
+	codeowners := filepath.Join(dir, "OWNERS")
+
+	output := "# example.com/app\n" +
+		"./api/server.go:12:2: moved to heap: req\n" +
+		"./api/server.go:20:2: moved to heap: resp\n" +
+		"./store/db.go:8:2: moved to heap: conn\n"
+	if err := os.WriteFile(raw, []byte(output), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(codeowners, []byte("* @org/core\n/api/ @org/api-team\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(binary, "--input="+raw, "--owners-file="+codeowners, "--owner=@org/api-team", "--format=json")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("heapcheck --owner failed: %v", err)
+	}
+	var result struct {
+		Summary struct {
+			ByOwner map[string]int `json:"byOwner"`
+		} `json:"summary"`
+		Escapes []struct {
+			Owners []string `json:"owners"`
+		} `json:"escapes"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got := result.Summary.ByOwner; got["@org/api-team"] != 2 || got["@org/core"] != 1 {
+		t.Errorf("byOwner = %v, want @org/api-team: 2, @org/core: 1", got)
+	}
+	if len(result.Escapes) != 2 {
+		t.Fatalf("got %d escapes, want the 2 owned by @org/api-team", len(result.Escapes))
+	}
+	for _, e := range result.Escapes {
+		if len(e.Owners) != 1 || e.Owners[0] != "@org/api-team" {
+			t.Errorf("escape owners = %v, want [@org/api-team]", e.Owners)
+		}
+	}
+
+	cmd = exec.Command(binary, "--input="+raw, "--owner=@org/api-team")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(out), "CODEOWNERS") {
+		t.Errorf("--owner without an owners file: err = %v, output:\n%s", err, out)
+	}
+}
+
+func TestHeapcheckDelta(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "raw.txt")
+	baselineFile := filepath.Join(dir, "baseline.json")
+
+	write := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(raw, "# example.com/app\n./main.go:12:2: moved to heap: x\n")
+	cmd := exec.Command(binary, "--input="+raw, "--write-baseline="+baselineFile)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("heapcheck --write-baseline failed: %v\n%s", err, out)
+	}
+
+	// x moved to another file, y is new
+	write(raw, "# example.com/app\n./run.go:3:2: moved to heap: x\n./run.go:8:2: moved to heap: y\n")
+	cmd = exec.Command(binary, "--input="+raw, "--baseline="+baselineFile, "--format=delta")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("heapcheck --format=delta failed: %v", err)
+	}
+	var ops []struct {
+		Op    string `json:"op"`
+		Path  string `json:"path"`
+		Value struct {
+			File     string `json:"file"`
+			Line     int    `json:"line"`
+			Variable string `json:"variable"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(out, &ops); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out)
+	}
+	if len(ops) != 3 || ops[0].Op != "remove" || ops[0].Path != "/entries/0" || ops[1].Op != "add" || ops[2].Op != "add" {
+		t.Fatalf("operations = %+v, want one remove and two adds", ops)
+	}
+	if ops[2].Value.File != "./run.go" || ops[2].Value.Line != 8 || ops[2].Value.Variable != "y" {
+		t.Errorf("second add = %+v, want ./run.go:8 y", ops[2].Value)
+	}
+
+	cmd = exec.Command(binary, "--input="+raw, "--format=delta")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(out), "--baseline") {
+		t.Errorf("--format=delta without a baseline: err = %v, output:\n%s", err, out)
+	}
+}
+
+func TestHeapcheckSubcommands(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "raw.txt")
+	if err := os.WriteFile(raw, []byte("# example.com/app\n./main.go:12:2: moved to heap: x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command(binary, args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("heapcheck %s failed: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return string(out)
+	}
+
+	// Global flags go before or after the command
+	if out := run("--debug", "baseline", "--input="+raw); !strings.Contains(out, "wrote 1 escape(s) to heapcheck-baseline.json") {
+		t.Errorf("baseline output unexpected:\n%s", out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "heapcheck-baseline.json")); err != nil {
+		t.Errorf("baseline did not write the default file: %v", err)
+	}
+
+	// The analysis is the default command and can be named
+	for _, args := range [][]string{
+		{"--input=" + raw, "--format=json"},
+		{"analyze", "--input=" + raw, "--format=json", "--mod=mod"},
+	} {
+		if out := run(args...); !strings.Contains(out, `"variable": "x"`) {
+			t.Errorf("heapcheck %s output missing the escape:\n%s", strings.Join(args, " "), out)
+		}
+	}
+
+	old := filepath.Join(dir, "old.json")
+	cmd := exec.Command(binary, "--input="+raw, "--format=json")
+	cmd.Dir = dir
+	report, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(old, report, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out := run("diff", old, old); !strings.Contains(out, "heapcheck") {
+		t.Errorf("diff output unexpected:\n%s", out)
+	}
+
+	if out := run("explain"); !strings.Contains(out, "interface-boxing") {
+		t.Errorf("explain should list the categories, got:\n%s", out)
+	}
+	if out := run("explain", "return-pointer"); !strings.Contains(out, "Escapes:") || !strings.Contains(out, "Instead:") {
+		t.Errorf("explain return-pointer should show the example, got:\n%s", out)
+	}
+
+	if out := run("help"); !strings.Contains(out, "baseline") || !strings.Contains(out, "web") {
+		t.Errorf("help should list the commands, got:\n%s", out)
+	}
+
+	cmd = exec.Command(binary, "explain", "no-such-category")
+	if out, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(out), "unknown category") {
+		t.Errorf("explain of an unknown category: err = %v, output:\n%s", err, out)
+	}
+}
+
+func TestHeapcheckJSONEvents(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "raw.txt")
+	content := "# example.com/a\n./a.go:3:2: moved to heap: x\n./a.go:4:2: y does not escape\n" +
+		"# example.com/b\n./b.go:5:6: z escapes to heap\n./b.go:6:2: moved to heap: w\n"
+	if err := os.WriteFile(raw, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(binary, "--input="+raw, "--json-events", "--format=json")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("heapcheck --json-events failed: %v\n%s", err, stderr.String())
+	}
+
+	type event struct {
+		Event         string `json:"event"`
+		Package       string `json:"package"`
+		Escapes       int    `json:"escapes"`
+		TotalPackages int    `json:"totalPackages"`
+		TotalEscapes  int    `json:"totalEscapes"`
+	}
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	if last := lines[len(lines)-1]; !regexp.MustCompile(`^heapcheck: total=\d+ heap=\d+ stack=\d+ gates=pass$`).MatchString(last) {
+		t.Errorf("last line on stderr = %q, want the outcome line after the events", last)
+	}
+	var events []event
+	for _, line := range lines[:len(lines)-1] {
+		var e event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid event line %q: %v", line, err)
+		}
+		events = append(events, e)
+	}
+
+	want := []event{
+		{Event: "start"},
+		{Event: "package", Package: "example.com/a", Escapes: 1, TotalPackages: 1, TotalEscapes: 1},
+		{Event: "package", Package: "example.com/b", Escapes: 2, TotalPackages: 2, TotalEscapes: 3},
+		{Event: "done", TotalPackages: 2, TotalEscapes: 3},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %+v, want %+v", events, want)
+	}
+}
+
+func TestHeapcheckPathStyle(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	raw := filepath.Join(dir, "raw.txt")
+	if err := os.WriteFile(raw, []byte("# example.com/app/api\n./api/server.go:12:2: moved to heap: x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for style, want := range map[string]string{
+		"relative": "api/server.go",
+		"module":   "example.com/app/api/server.go",
+		"absolute": filepath.Join(dir, "api", "server.go"),
+	} {
+		cmd := exec.Command(binary, "--input="+raw, "--format=sarif", "--path-style="+style)
+		cmd.Dir = dir
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("--path-style=%s failed: %v", style, err)
+		}
+		if !strings.Contains(string(out), `"uri": "`+want+`"`) {
+			t.Errorf("--path-style=%s: SARIF output missing uri %q:\n%s", style, want, out)
+		}
+	}
+
+	cmd := exec.Command(binary, "--input="+raw, "--path-style=short")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(out), "--path-style") {
+		t.Errorf("invalid --path-style: err = %v, output:\n%s", err, out)
+	}
+}
+
+func TestHeapcheckNotAnalyzed(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":       "module cgoapp\n\ngo 1.22\n",
+		"mixed/c.go":   "package mixed\n\n// int one(void) { return 1; }\nimport \"C\"\n\nfunc One() int { return int(C.one()) }\n",
+		"mixed/go.go":  "package mixed\n\nfunc New() *int { x := 1; return &x }\n",
+		"onlyc/c.go":   "package onlyc\n\n// int two(void) { return 2; }\nimport \"C\"\n\nfunc Two() int { return int(C.two()) }\n",
+		"pure/pure.go": "package pure\n\nfunc New() *int { y := 2; return &y }\n",
+		"broken/b.go":  "package broken\n\nfunc F() *int { z := 3; return &z\n",
+		"tagged/t.go":  "//go:build never\n\npackage tagged\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Without a C compiler cgo fails to build mixed and onlyc
+	cmd := exec.Command(binary, "--format=json", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=1", "CC=heapcheck-no-such-cc", "GOFLAGS=", "GOWORK=off")
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Fatalf("heapcheck on cgo packages: err = %v, want exit code 4 for a partial analysis\n%s", err, out)
+	}
+
+	var results struct {
+		Summary struct {
+			ByFile  map[string]int `json:"byFile"`
+			Skipped []struct {
+				Package string `json:"package"`
+				Reason  string `json:"reason"`
+				PureGo  bool   `json:"pureGo"`
+			} `json:"skipped"`
+		} `json:"summary"`
+	}
+	if err := json.Unmarshal(out, &results); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out)
+	}
+	for _, file := range []string{"mixed/go.go", "pure/pure.go"} {
+		if results.Summary.ByFile[file] == 0 {
+			t.Errorf("no escapes in %s: byFile = %v", file, results.Summary.ByFile)
+		}
+	}
+	skipped := make(map[string]bool)
+	for _, p := range results.Summary.Skipped {
+		skipped[p.Package] = p.PureGo
+		if (p.Package == "cgoapp/mixed" || p.Package == "cgoapp/onlyc") && !strings.Contains(p.Reason, "heapcheck-no-such-cc") {
+			t.Errorf("skipped %s: reason %q does not name the C compiler", p.Package, p.Reason)
+		}
+	}
+	if pureGo, ok := skipped["cgoapp/mixed"]; !ok || !pureGo {
+		t.Errorf("cgoapp/mixed not marked as analyzed without cgo: %+v", results.Summary.Skipped)
+	}
+	if pureGo, ok := skipped["cgoapp/onlyc"]; !ok || pureGo {
+		t.Errorf("cgoapp/onlyc not marked as skipped: %+v", results.Summary.Skipped)
+	}
+	for pkg, reason := range map[string]string{"cgoapp/broken": "syntax error", "cgoapp/tagged": "build constraints exclude all Go files"} {
+		if pureGo, ok := skipped[pkg]; !ok || pureGo {
+			t.Errorf("%s not marked as skipped: %+v", pkg, results.Summary.Skipped)
+		}
+		for _, p := range results.Summary.Skipped {
+			if p.Package == pkg && !strings.Contains(p.Reason, reason) {
+				t.Errorf("skipped %s: reason %q, want %q", pkg, p.Reason, reason)
+			}
+		}
+	}
+	if _, ok := skipped["cgoapp/pure"]; ok {
+		t.Errorf("cgoapp/pure marked as skipped: %+v", results.Summary.Skipped)
+	}
+}
+
+func TestHeapcheckRemap(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	projectRoot := getProjectRoot(t)
+	configFile := filepath.Join(t.TempDir(), "heapcheck.yaml")
+	config := "remap:\n  return-pointer: alloc/return\n  fmt-call: interface-boxing\n" +
+		"suggestions:\n  alloc/return: See the value-return guide\n"
+	if err := os.WriteFile(configFile, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(binary, "--config="+configFile, "--format=json", "./examples/basic-patterns")
+	cmd.Dir = projectRoot
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("heapcheck failed: %v", err)
+	}
+	var results struct {
+		ByCategory map[string]int `json:"byCategory"`
+		Escapes    []struct {
+			Category   string `json:"category"`
+			Suggestion struct {
+				Short string `json:"short"`
+			} `json:"suggestion"`
+		} `json:"escapes"`
+	}
+	if err := json.Unmarshal(out, &results); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	for _, cat := range []string{"return-pointer", "fmt-call"} {
+		if n, ok := results.ByCategory[cat]; ok {
+			t.Errorf("byCategory still has %s: %d", cat, n)
+		}
+	}
+	if results.ByCategory["alloc/return"] == 0 {
+		t.Fatalf("byCategory has no alloc/return: %v", results.ByCategory)
+	}
+	for _, e := range results.Escapes {
+		if e.Category == "alloc/return" && e.Suggestion.Short != "See the value-return guide" {
+			t.Errorf("alloc/return suggestion = %q, want the configured one", e.Suggestion.Short)
+		}
+	}
+}
+
+func TestHeapcheckNestedConfig(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                 "module example.com/nested\n\ngo 1.22\n",
+		"main.go":                "package main\n\nimport \"example.com/nested/legacy\"\n\nfunc main() { _ = legacy.New() }\n",
+		"legacy/legacy.go":       "package legacy\n\ntype T struct{ n [4]int }\n\n//go:noinline\nfunc New() *T { return &T{} }\n",
+		".heapcheck.yaml":        "categories:\n  return-pointer: fail\n",
+		"legacy/.heapcheck.yaml": "categories:\n  return-pointer: warn>5\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The legacy tree's looser budget covers its escape
+	cmd := exec.Command(binary, "--format=json", "--summary-only", "./...")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("heapcheck failed with a looser nested budget: %v\n%s", err, out)
+	}
+	var report struct {
+		Gate struct {
+			Status     string `json:"status"`
+			Categories []struct {
+				Category string `json:"category"`
+				Dir      string `json:"dir"`
+				Count    int    `json:"count"`
+			} `json:"categories"`
+		} `json:"gate"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if report.Gate.Status != "pass" || len(report.Gate.Categories) != 2 {
+		t.Fatalf("gate = %+v, want a pass with root and legacy rules", report.Gate)
+	}
+	if c := report.Gate.Categories[1]; c.Dir != "legacy" || c.Count != 1 {
+		t.Errorf("legacy gate = %+v, want its return-pointer escape", c)
+	}
+
+	// Without it, the root rule fails the run
+	if err := os.Remove(filepath.Join(dir, "legacy", ".heapcheck.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	cmd = exec.Command(binary, "./...")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err == nil {
+		t.Errorf("heapcheck passed without the nested config:\n%s", out)
+	}
+}
+
+func TestHeapcheckInit(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":           "module example.com/starter\n\ngo 1.22\n",
+		"main.go":          "package main\n\nimport \"example.com/starter/legacy\"\n\nfunc main() { _ = legacy.New() }\n",
+		"legacy/legacy.go": "package legacy\n\ntype T struct{ n [4]int }\n\n//go:noinline\nfunc New() *T { return &T{} }\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run := func(args ...string) (string, error) {
+		cmd := exec.Command(binary, args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+
+	if out, err := run("init", "--min-package-escapes=1", "./..."); err != nil {
+		t.Fatalf("heapcheck init failed: %v\n%s", err, out)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ".heapcheck.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "packages:\n  legacy:\n") || !strings.Contains(string(data), "    return-pointer: fail>2  # 1 now\n") {
+		t.Errorf("starter config missing the legacy budget:\n%s", data)
+	}
+
+	// The budgets pass today's escapes
+	if out, err := run("./..."); err != nil {
+		t.Errorf("heapcheck failed with the starter config: %v\n%s", err, out)
+	}
+	if out, err := run("init", "./..."); err == nil || !strings.Contains(out, "--force") {
+		t.Errorf("init overwrote the config without --force:\n%s", out)
+	}
+	if out, err := run("init", "--force", "./..."); err != nil {
+		t.Errorf("init --force failed: %v\n%s", err, out)
+	}
+}
+
+func TestHeapcheckBulk(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	binary := getHeapcheckBinary(t)
+	origin := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module example.com/app\n\ngo 1.22\n",
+		"main.go": "package main\n\ntype T struct{ n [4]int }\n\n//go:noinline\nfunc New() *T { return &T{} }\n\nfunc main() { _ = New() }\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(origin, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{{"init", "--quiet"}, {"add", "."}, {"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "init"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = origin
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", args[0], err, out)
+		}
+	}
+
+	dir := t.TempDir()
+	manifest := "repos:\n  - url: file://" + origin + "\n    name: app\n  - url: file://" + filepath.Join(origin, "missing") + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "repos.yaml"), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(binary, "bulk", "--format=json", "--results-dir=results")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) || exit.ExitCode() != 4 {
+		t.Fatalf("bulk with a missing repository: %v, want exit code 4\n%s", err, stderr.String())
+	}
+	var report struct {
+		Repos []struct {
+			Name          string `json:"name"`
+			Commit        string `json:"commit"`
+			HeapAllocated int    `json:"heapAllocated"`
+			Error         string `json:"error"`
+		} `json:"repos"`
+		Analyzed   int            `json:"analyzed"`
+		ByCategory map[string]int `json:"byCategory"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out)
+	}
+	if report.Analyzed != 1 || len(report.Repos) != 2 {
+		t.Fatalf("report = %+v, want one of two repositories analyzed", report)
+	}
+	if app := report.Repos[0]; app.Name != "app" || app.Commit == "" || app.HeapAllocated == 0 || report.ByCategory["return-pointer"] != 1 {
+		t.Errorf("app result = %+v, by category %v", app, report.ByCategory)
+	}
+	if missing := report.Repos[1]; missing.Name != "missing" || missing.Error == "" {
+		t.Errorf("missing result = %+v, want its error", missing)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "results", "app.json")); err != nil {
+		t.Errorf("app results not written: %v", err)
+	}
+
+	// Without fetching, the clone is analyzed as it is
+	if err := os.WriteFile(filepath.Join(dir, "repos.yaml"), []byte("repos:\n  - url: file://"+origin+"\n    name: app\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmd = exec.Command(binary, "bulk", "--no-fetch")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil || !strings.Contains(string(out), "1 of 1 repo(s) analyzed") {
+		t.Errorf("bulk --no-fetch: %v\n%s", err, out)
+	}
+}
+
+func TestHeapcheckHandlers(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/web\n\ngo 1.22\n",
+		"main.go": `package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func hello(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, "hello %s", r.URL.Path)
+}
+
+func main() {
+	http.HandleFunc("GET /hello/", hello)
+	http.ListenAndServe(":8080", nil)
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(binary, "--handlers", "--format=json", "./...")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("heapcheck --handlers failed: %v\n%s", err, out)
+	}
+	var report struct {
+		Handlers []struct {
+			Handler string   `json:"handler"`
+			Routes  []string `json:"routes"`
+			Escapes int      `json:"escapes"`
+		} `json:"handlers"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(report.Handlers) != 1 {
+		t.Fatalf("handlers = %+v, want hello", report.Handlers)
+	}
+	h := report.Handlers[0]
+	if h.Handler != "hello" || !reflect.DeepEqual(h.Routes, []string{"GET /hello/"}) || h.Escapes == 0 {
+		t.Errorf("handler = %+v, want hello on GET /hello/ with its escapes", h)
+	}
+}
+
+func TestHeapcheckEntrypoint(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	newT := "package %s\n\ntype T struct{ n [4]int }\n\n//go:noinline\nfunc New() *T { return &T{} }\n"
+	files := map[string]string{
+		"go.mod":               "module example.com/svc\n\ngo 1.22\n",
+		"cmd/api/main.go":      "package main\n\nimport \"example.com/svc/store\"\n\nfunc main() { _ = store.New() }\n",
+		"store/store.go":       fmt.Sprintf(newT, "store"),
+		"tools/gen/main.go":    "package main\n\nimport \"example.com/svc/testutil\"\n\nfunc main() { _ = testutil.New() }\n",
+		"testutil/testutil.go": fmt.Sprintf(newT, "testutil"),
+		".heapcheck.yaml":      "categories:\n  return-pointer: fail>1\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The tool's helper package is over the budget for the whole module
+	cmd := exec.Command(binary, "./...")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err == nil {
+		t.Fatalf("heapcheck ./... passed with two return-pointer escapes:\n%s", out)
+	}
+
+	// The API binary links only its store
+	cmd = exec.Command(binary, "--entrypoint=./cmd/api", "--format=json")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("heapcheck --entrypoint=./cmd/api failed: %v\n%s", err, out)
+	}
+	var report struct {
+		Escapes []struct {
+			Info struct {
+				File string `json:"file"`
+			} `json:"info"`
+		} `json:"escapes"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(report.Escapes) == 0 {
+		t.Fatal("no escapes reported for ./cmd/api")
+	}
+	for _, e := range report.Escapes {
+		if !strings.HasPrefix(e.Info.File, "store/") {
+			t.Errorf("escape in %s, want only escapes in store/", e.Info.File)
+		}
+	}
+
+	// Patterns narrow further, and libraries are no entrypoints
+	cmd = exec.Command(binary, "--entrypoint=./cmd/api", "./tools/...")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(out), "is linked into ./cmd/api") {
+		t.Errorf("heapcheck --entrypoint=./cmd/api ./tools/...: err = %v, output:\n%s", err, out)
+	}
+	cmd = exec.Command(binary, "--entrypoint=./store")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(out), "not a main package") {
+		t.Errorf("heapcheck --entrypoint=./store: err = %v, output:\n%s", err, out)
+	}
+}
+
+func TestHeapcheckStdinPackages(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	newT := "package %s\n\ntype T struct{ n [4]int }\n\n//go:noinline\nfunc New() *T { return &T{} }\n"
+	files := map[string]string{
+		"go.mod":         "module example.com/svc\n\ngo 1.22\n",
+		"store/store.go": fmt.Sprintf(newT, "store"),
+		"cache/cache.go": fmt.Sprintf(newT, "cache"),
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A build system lists the affected targets itself
+	cmd := exec.Command(binary, "--format=json", "-")
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader("# affected\nexample.com/svc/store\n\n")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("heapcheck - failed: %v\n%s", err, out)
+	}
+	var report struct {
+		Escapes []struct {
+			Info struct {
+				File string `json:"file"`
+			} `json:"info"`
+		} `json:"escapes"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(report.Escapes) == 0 {
+		t.Fatal("no escapes reported for the package read from stdin")
+	}
+	for _, e := range report.Escapes {
+		if !strings.Contains(e.Info.File, "store/") {
+			t.Errorf("escape in %s, want only escapes in store/", e.Info.File)
+		}
+	}
+
+	cmd = exec.Command(binary, "-")
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader("\n")
+	if out, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(out), "no packages listed on stdin") {
+		t.Errorf("heapcheck - with empty stdin: err = %v, output:\n%s", err, out)
+	}
+	cmd = exec.Command(binary, "--input=-", "-")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(out), "cannot both read stdin") {
+		t.Errorf("heapcheck --input=- -: err = %v, output:\n%s", err, out)
+	}
+}
+
+func TestHeapcheckDaemon(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":         "module example.com/svc\n\ngo 1.22\n",
+		"store/store.go": "package store\n\ntype T struct{ n [4]int }\n\n//go:noinline\nfunc New() *T { return &T{} }\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logPath := filepath.Join(t.TempDir(), "daemon.log")
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logFile.Close()
+	daemon := exec.Command(binary, "--debug", "daemon")
+	daemon.Dir = dir
+	daemon.Stderr = logFile
+	if err := daemon.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		daemon.Wait()
+		close(exited)
+	}()
+	t.Cleanup(func() {
+		daemon.Process.Kill()
+		<-exited
+	})
+	daemonLog := func() string {
+		data, _ := os.ReadFile(logPath)
+		return string(data)
+	}
+	for !strings.Contains(daemonLog(), "daemon serving") {
+		select {
+		case <-exited:
+			t.Fatalf("daemon exited:\n%s", daemonLog())
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	analyze := func(env ...string) []byte {
+		t.Helper()
+		cmd := exec.Command(binary, "--format=json", "./...")
+		cmd.Dir = filepath.Join(dir, "store")
+		cmd.Env = append(os.Environ(), env...)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("heapcheck %v failed: %v\n%s", env, err, out)
+		}
+		return out
+	}
+
+	// The second run reuses the compiler output of the first
+	local := analyze("HEAPCHECK_DAEMON=off")
+	first := analyze()
+	analyze()
+	if !strings.Contains(daemonLog(), "reusing go command output") {
+		t.Errorf("daemon did not reuse the compiler output:\n%s", daemonLog())
+	}
+	if !reflect.DeepEqual(withoutMetadata(t, local), withoutMetadata(t, first)) {
+		t.Errorf("daemon output differs from an analysis in process:\n%s\n%s", local, first)
+	}
+
+	// The daemon reports in the language of the client's --lang
+	html := exec.Command(binary, "--format=html", "--lang=de", "./...")
+	html.Dir = filepath.Join(dir, "store")
+	if out, err := html.Output(); err != nil || !strings.Contains(string(out), "Rückgabe eines Zeigers") {
+		t.Errorf("daemon report with --lang=de: err = %v, no German category name", err)
+	}
+
+	// Edits invalidate it
+	edited := files["store/store.go"] + "\n//go:noinline\nfunc Other() *T { return &T{} }\n"
+	if err := os.WriteFile(filepath.Join(dir, "store/store.go"), []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var before, after struct {
+		Escapes []any `json:"escapes"`
+	}
+	if err := json.Unmarshal(first, &before); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(analyze(), &after); err != nil {
+		t.Fatal(err)
+	}
+	if len(after.Escapes) <= len(before.Escapes) {
+		t.Errorf("after an edit: %d escape(s), want more than %d", len(after.Escapes), len(before.Escapes))
+	}
+
+	stop := exec.Command(binary, "daemon", "--stop")
+	stop.Dir = dir
+	if out, err := stop.CombinedOutput(); err != nil {
+		t.Fatalf("heapcheck daemon --stop failed: %v\n%s", err, out)
+	}
+	select {
+	case <-exited:
+	case <-time.After(10 * time.Second):
+		t.Fatal("daemon did not exit after --stop")
+	}
+}
+
+func TestHeapcheckPrecommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write("go.mod", "module example.com/precommit\n\ngo 1.22\n")
+	write("main.go", "package main\n\nimport \"example.com/precommit/store\"\n\nfunc main() { _ = store.Old() }\n")
+	write("store/store.go", "package store\n\ntype T struct{ n [4]int }\n\n//go:noinline\nfunc Old() *T { return &T{} }\n")
+	write("other/other.go", "package other\n\ntype U struct{ n int }\n\n//go:noinline\nfunc Other() *U { return &U{} }\n")
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+
+	// Stage a new escaping function; Old and other/ are unchanged
+	write("store/store.go", "package store\n\ntype T struct{ n [4]int }\n\n//go:noinline\nfunc Old() *T { return &T{} }\n\n//go:noinline\nfunc New() *T {\n\tt := T{}\n\treturn &t\n}\n")
+	git("add", "store/store.go")
+
+	cmd := exec.Command(binary, "precommit", "--format=json")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("heapcheck precommit failed: %v\n%s", err, out)
+	}
+	var results struct {
+		Escapes []struct {
+			Info struct {
+				File string `json:"file"`
+				Line int    `json:"line"`
+			} `json:"info"`
+		} `json:"escapes"`
+	}
+	if err := json.Unmarshal(out, &results); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(results.Escapes) == 0 {
+		t.Fatal("precommit reported no escapes for the staged function")
+	}
+	for _, e := range results.Escapes {
+		if e.Info.File != "store/store.go" || e.Info.Line < 9 {
+			t.Errorf("precommit reported an escape outside the staged change: %s:%d", e.Info.File, e.Info.Line)
+		}
+	}
+
+	// --fail blocks the commit
+	cmd = exec.Command(binary, "precommit", "--fail")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err == nil {
+		t.Errorf("heapcheck precommit --fail passed with escapes staged:\n%s", out)
+	}
+
+	// Changes in files the build configuration excludes are not analyzed
+	git("commit", "-q", "-m", "add New")
+	write("store/store_plan9.go", "package store\n\n//go:noinline\nfunc Plan9() *T {\n\tt := T{}\n\treturn &t\n}\n")
+	git("add", "store/store_plan9.go")
+	cmd = exec.Command(binary, "precommit", "--fail")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil || !strings.Contains(string(out), "no staged Go changes") {
+		t.Errorf("heapcheck precommit with only a plan9 file staged: %v\n%s", err, out)
+	}
+
+	// Nothing staged, nothing to do
+	git("commit", "-q", "-m", "add Plan9")
+	cmd = exec.Command(binary, "precommit", "--fail")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil || !strings.Contains(string(out), "no staged Go changes") {
+		t.Errorf("heapcheck precommit with nothing staged: %v\n%s", err, out)
+	}
+}
+
+func TestHeapcheckProfileSelf(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "raw.txt")
+	if err := os.WriteFile(raw, []byte("# example.com/app\n./main.go:12:2: moved to heap: x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles := filepath.Join(dir, "profiles")
+	cmd := exec.Command(binary, "--profile-self="+profiles, "--input="+raw, "--format=json")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("heapcheck --profile-self failed: %v\n%s", err, out)
+	}
+	for _, name := range []string{"cpu.pprof", "heap.pprof"} {
+		info, err := os.Stat(filepath.Join(profiles, name))
+		if err != nil {
+			t.Errorf("profile not written: %v", err)
+		} else if info.Size() == 0 {
+			t.Errorf("%s is empty", name)
+		}
+	}
+}
+
+func TestHeapcheckHistoryPrune(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	now := time.Now().UTC()
+	noon := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, time.UTC)
+	type run struct {
+		Time          time.Time `json:"time"`
+		Branch        string    `json:"branch"`
+		HeapAllocated int       `json:"heapAllocated"`
+	}
+	runs := []run{
+		{Time: noon.AddDate(0, 0, -200), Branch: "main", HeapAllocated: 1},
+		{Time: noon.AddDate(0, 0, -60).Add(-2 * time.Hour), Branch: "main", HeapAllocated: 2},
+		{Time: noon.AddDate(0, 0, -60).Add(-time.Hour), Branch: "main", HeapAllocated: 3},
+		{Time: noon.AddDate(0, 0, -1), Branch: "main", HeapAllocated: 4},
+	}
+	data, err := json.Marshal(map[string]any{"runs": runs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, ".heapcheck-history.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config := "history:\n  file: .heapcheck-history.json\n  keep: 90d\n"
+	if err := os.WriteFile(filepath.Join(dir, ".heapcheck.yaml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	prune := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command(binary, append([]string{"history", "prune"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("heapcheck history prune %v failed: %v\n%s", args, err, out)
+		}
+		return string(out)
+	}
+
+	if out := prune("--compact=30d", "--dry-run"); !strings.Contains(out, "would drop 1 run(s) past the retention and 1 by compaction, 2 run(s) left") {
+		t.Errorf("dry run output = %q", out)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, data) {
+		t.Error("--dry-run changed the history file")
+	}
+
+	prune("--compact=30d")
+	var h struct{ Runs []run }
+	after, _ := os.ReadFile(path)
+	if err := json.Unmarshal(after, &h); err != nil {
+		t.Fatalf("invalid history: %v\n%s", err, after)
+	}
+	if len(h.Runs) != 2 || h.Runs[0].HeapAllocated != 3 || h.Runs[1].HeapAllocated != 4 {
+		t.Errorf("runs = %+v, want the last run 60 days ago and yesterday's", h.Runs)
+	}
+
+	if out := prune("--keep=1"); !strings.Contains(out, "dropped 1 run(s)") {
+		t.Errorf("--keep=1 output = %q", out)
+	}
+
+	cmd := exec.Command(binary, "history", "prune", "--keep=soon")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(out), "invalid retention") {
+		t.Errorf("--keep=soon: err = %v, output %q, want an invalid retention", err, out)
+	}
+}
+
+func TestHeapcheckOverlay(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":       "module overlayapp\n\ngo 1.22\n",
+		"app/app.go":   "package app\n\nfunc Use() *int { return nil }\n",
+		"gen/app.go":   "package app\n\nfunc Use() *int {\n\tx := 1\n\treturn &x\n}\n",
+		"gen/extra.go": "package app\n\nfunc Extra() *string {\n\ts := \"a\"\n\treturn &s\n}\n",
+		"overlay.json": `{"Replace": {"app/app.go": "gen/app.go", "app/extra.go": "gen/extra.go"}}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(binary, "--overlay=overlay.json", "--format=json", "./app")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("heapcheck --overlay failed: %v\n%s", err, out)
+	}
+	var results struct {
+		Escapes []struct {
+			Info struct {
+				File string `json:"file"`
+				Line int    `json:"line"`
+			} `json:"info"`
+			Category string `json:"category"`
+			Func     string `json:"func"`
+		} `json:"escapes"`
+	}
+	if err := json.Unmarshal(out, &results); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out)
+	}
+	funcs := make(map[string]bool)
+	for _, e := range results.Escapes {
+		if e.Category == "return-pointer" {
+			funcs[fmt.Sprintf("%s:%d %s", filepath.Base(e.Info.File), e.Info.Line, e.Func)] = true
+		}
+	}
+	if !funcs["app.go:4 Use"] || !funcs["extra.go:4 Extra"] {
+		t.Errorf("return-pointer escapes = %v, want those of the overlay's files, at the paths they replace", funcs)
+	}
+
+	cmd = exec.Command(binary, "--overlay=missing.json", "./app")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(out), "invalid --overlay") {
+		t.Errorf("--overlay=missing.json: err = %v, output %q, want an invalid overlay", err, out)
+	}
+}
+
+func TestHeapcheckGCFlagsExtra(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module extraapp\n\ngo 1.22\n",
+		"app.go": "package app\n\nfunc square(x int) int { return x * x }\n\nfunc Use(n int) *int {\n\tv := square(n)\n\treturn &v\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inlined := func(args ...string) int {
+		t.Helper()
+		cmd := exec.Command(binary, append(args, "--format=json", ".")...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off", "HEAPCHECK_DAEMON=off")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("heapcheck %v failed: %v\n%s", args, err, out)
+		}
+		var results struct {
+			Summary struct {
+				Inlined       int `json:"inlined"`
+				HeapAllocated int `json:"heapAllocated"`
+			} `json:"summary"`
+		}
+		if err := json.Unmarshal(out, &results); err != nil {
+			t.Fatalf("invalid JSON: %v\n%s", err, out)
+		}
+		if results.Summary.HeapAllocated == 0 {
+			t.Errorf("heapcheck %v found no escapes", args)
+		}
+		return results.Summary.Inlined
+	}
+	if n := inlined(); n == 0 {
+		t.Error("no inlining decisions without extra flags")
+	}
+	if n := inlined("--gcflags-extra=-l"); n != 0 {
+		t.Errorf("--gcflags-extra=-l: %d inlining decisions, want none with inlining disabled", n)
+	}
+
+	cmd := exec.Command(binary, "--gcflags-extra=-l", "--input=raw.txt")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(out), "cannot be used with --input") {
+		t.Errorf("--gcflags-extra with --input: err = %v, output %q, want a usage error", err, out)
+	}
+}
+
+func TestHeapcheckLimits(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	projectRoot := getProjectRoot(t)
+
+	run := func(args ...string) ([]byte, error) {
+		cmd := exec.Command(binary, append(args, "--format=json", "./examples/basic-patterns")...)
+		cmd.Dir = projectRoot
+		cmd.Env = append(os.Environ(), "HEAPCHECK_DAEMON=off")
+		return cmd.Output()
+	}
+	want, err := run()
+	if err != nil {
+		t.Fatalf("heapcheck failed: %v", err)
+	}
+	// A buffer limit of a quarter of 1KB spills all compiler output
+	got, err := run("--concurrency=1", "--max-memory=1KB")
+	if err != nil {
+		t.Fatalf("heapcheck --concurrency=1 --max-memory=1KB failed: %v", err)
+	}
+	count := func(out []byte) int {
+		var results struct {
+			Summary struct {
+				HeapAllocated int `json:"heapAllocated"`
+			} `json:"summary"`
+		}
+		if err := json.Unmarshal(out, &results); err != nil {
+			t.Fatalf("invalid JSON: %v\n%s", err, out)
+		}
+		return results.Summary.HeapAllocated
+	}
+	if g, w := count(got), count(want); g != w || w == 0 {
+		t.Errorf("with limits: %d escape(s), without: %d", g, w)
+	}
+
+	for _, arg := range []string{"--concurrency=-1", "--max-memory=lots"} {
+		if out, err := run(arg); err == nil {
+			t.Errorf("heapcheck %s succeeded, want an error\n%s", arg, out)
+		}
+	}
+}
+
+func TestHeapcheckWebAccept(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/accept\n\ngo 1.22\n",
+		"main.go": `package main
+
+import "fmt"
+
+func main() {
+	x := 42
+	fmt.Println(x)
+}
+`,
+		"base.json": `{"version": 1, "entries": []}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(binary, "web", "--addr=127.0.0.1:0", "--baseline=base.json", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "HEAPCHECK_DAEMON=off")
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+	buf := make([]byte, 256)
+	n, _ := stderr.Read(buf)
+	_, addr, ok := strings.Cut(strings.TrimSpace(string(buf[:n])), "serving the report at ")
+	if !ok {
+		t.Fatalf("no address in %q", buf[:n])
+	}
+	go io.Copy(io.Discard, stderr)
+
+	resp, err := http.Get(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, check := range []string{`action="/accept"`, "<option>comment</option>", "<option>baseline</option>"} {
+		if !strings.Contains(string(page), check) {
+			t.Errorf("report missing: %s", check)
+		}
+	}
+
+	postAs := func(host string, form url.Values) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, addr+"accept", strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if host != "" {
+			req.Host = host
+		}
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	post := func(form url.Values) *http.Response {
+		t.Helper()
+		return postAs("", form)
+	}
+	field := func(name string) string {
+		_, rest, _ := strings.Cut(string(page), `name="`+name+`" value="`)
+		value, _, _ := strings.Cut(rest, `"`)
+		return value
+	}
+	token, variable := field("token"), field("variable")
+	if token == "" {
+		t.Fatal("report has no accept token")
+	}
+	if resp := post(url.Values{"token": {token}, "escape": {"0"}, "variable": {"nope"}, "reason": {"r"}, "target": {"comment"}}); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("stale escape: status %d, want 400", resp.StatusCode)
+	}
+	accepted := url.Values{"token": {token}, "escape": {"0"}, "variable": {variable}, "reason": {"printed once"}, "owner": {"@me"}, "target": {"comment"}}
+	forged := url.Values{"escape": {"0"}, "variable": {variable}, "reason": {"forged"}, "target": {"comment"}}
+	if resp := post(forged); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("post without the token: status %d, want 403", resp.StatusCode)
+	}
+	if resp := postAs("rebound.example:8080", accepted); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("post to another host: status %d, want 403", resp.StatusCode)
+	}
+	resp = post(accepted)
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("accept: status %d, want a redirect to the report", resp.StatusCode)
+	}
+	src, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), "\t//heapcheck:ignore fmt-call owner=@me -- printed once\n\tfmt.Println(x)") {
+		t.Errorf("no suppression comment written:\n%s", src)
+	}
+
+	resp, err = http.Get(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if strings.Contains(string(page), `name="escape"`) {
+		t.Errorf("accepted escape still reported")
+	}
+}
+
+func TestHeapcheckWatch(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/watch\n\ngo 1.22\n",
+		"store/store.go": `package store
+
+type T struct{ n int }
+
+//go:noinline
+func New() *T { return &T{} }
+`,
+		"other/other.go": `package other
+
+//go:noinline
+func Box(n int) any { return n }
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	var mu sync.Mutex
+	cmd := exec.Command(binary, "watch", "--interval=100ms", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "HEAPCHECK_DAEMON=off")
+	cmd.Stdout = &lockedWriter{mu: &mu, w: &stdout}
+	cmd.Stderr = &lockedWriter{mu: &mu, w: &stderr}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+	waitFor := func(buf *bytes.Buffer, want string) string {
+		t.Helper()
+		deadline := time.Now().Add(60 * time.Second)
+		for time.Now().Before(deadline) {
+			mu.Lock()
+			out := buf.String()
+			mu.Unlock()
+			if strings.Contains(out, want) {
+				return out
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		t.Fatalf("no %q in output:\n%s\nstderr:\n%s", want, stdout.String(), stderr.String())
+		return ""
+	}
+	waitFor(&stderr, "watching for changes")
+
+	edited := files["store/store.go"] + "\n//go:noinline\nfunc Other() *T { return &T{n: 1} }\n"
+	if err := os.WriteFile(filepath.Join(dir, "store/store.go"), []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := waitFor(&stdout, "unchanged in 1 other package(s)")
+	_, delta, _ := strings.Cut(out, "store.go changed")
+	for _, check := range []string{"example.com/watch/store: 2 new, 0 fixed", "+ store/store.go:9  &T{...} [return-pointer]", "💡 "} {
+		if !strings.Contains(delta, check) {
+			t.Errorf("delta missing %q:\n%s", check, delta)
+		}
+	}
+	if strings.Contains(delta, "example.com/watch/other:") {
+		t.Errorf("unchanged package detailed:\n%s", delta)
+	}
+}
+
+// lockedWriter serializes writes to w with the reads of a test
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+func TestHeapcheckSort(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module sorted\n\ngo 1.22\n",
+		"a.go":   "package sorted\n\nfunc New() *int { x := 1; return &x }\n",
+		"z.go":   "package sorted\n\nfunc Collect(items []string) []*string {\n\tvar out []*string\n\tfor _, item := range items {\n\t\ts := item\n\t\tout = append(out, &s)\n\t}\n\treturn out\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first := func(args ...string) (string, bool) {
+		t.Helper()
+		cmd := exec.Command(binary, append([]string{"--format=json"}, append(args, "./...")...)...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off", "HEAPCHECK_DAEMON=off")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("heapcheck %v failed: %v", args, err)
+		}
+		var results struct {
+			Escapes []struct {
+				Info struct {
+					File string `json:"file"`
+				} `json:"info"`
+				InLoop bool    `json:"inLoop"`
+				Score  float64 `json:"score"`
+			} `json:"escapes"`
+		}
+		if err := json.Unmarshal(out, &results); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if len(results.Escapes) == 0 {
+			t.Fatal("no escapes")
+		}
+		e := results.Escapes[0]
+		return filepath.Base(e.Info.File), e.InLoop && e.Score > 0
+	}
+
+	if file, inLoop := first(); file != "z.go" || !inLoop {
+		t.Errorf("by default, the first escape is in %s (scored in a loop: %v), want the loop in z.go", file, inLoop)
+	}
+	if file, _ := first("--sort=position"); file != "a.go" {
+		t.Errorf("with --sort=position, the first escape is in %s, want a.go", file)
+	}
+
+	cmd := exec.Command(binary, "--sort=bytes", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off", "HEAPCHECK_DAEMON=off")
+	if out, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(out), "--gc-impact") {
+		t.Errorf("--sort=bytes without --gc-impact: err = %v, output:\n%s", err, out)
+	}
+}
+
+func TestHeapcheckTee(t *testing.T) {
+	binary := getHeapcheckBinary(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module teed\n\ngo 1.22\n",
+		"a.go":   "package teed\n\nfunc New() *int { x := 1; return &x }\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, format := range []string{"json", "text"} {
+		t.Run(format, func(t *testing.T) {
+			report := filepath.Join(t.TempDir(), "report."+format)
+			cmd := exec.Command(binary, "--format="+format, "--color=always", "--tee="+report, "./...")
+			cmd.Dir = dir
+			cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off", "HEAPCHECK_DAEMON=off")
+			var stderr bytes.Buffer
+			cmd.Stderr = &stderr
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("heapcheck --tee failed: %v\n%s", err, stderr.Bytes())
+			}
+			teed, err := os.ReadFile(report)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(teed) == 0 || bytes.Contains(teed, []byte("\033[")) {
+				t.Fatalf("the --tee file is empty or has colors:\n%q", teed)
+			}
+			plain := regexp.MustCompile("\033\\[[0-9;]*m").ReplaceAll(out, nil)
+			if !bytes.Equal(teed, plain) {
+				t.Errorf("the --tee file differs from stdout:\n%s\nstdout:\n%s", teed, out)
+			}
+		})
+	}
+}